@@ -266,6 +266,7 @@ type TrustedAssetsInstallObserver struct {
 
 	dataEncryptionKey keys.EncryptionKey
 	saveEncryptionKey keys.EncryptionKey
+	usePassphrase     bool
 }
 
 // Observe observes the operation related to the content of a given gadget
@@ -345,6 +346,13 @@ func (o *TrustedAssetsInstallObserver) ChosenEncryptionKeys(key, saveKey keys.En
 	o.saveEncryptionKey = saveKey
 }
 
+// ChosenPassphraseProtection makes note that the encrypted partitions are
+// protected with a user passphrase, in which case the encryption keys are not
+// sealed.
+func (o *TrustedAssetsInstallObserver) ChosenPassphraseProtection() {
+	o.usePassphrase = true
+}
+
 // TrustedAssetsUpdateObserverForModel returns a new trusted assets observer for
 // tracking changes to the trusted boot assets and preserving managed assets,
 // provided the device model indicates this might be needed. Otherwise, nil and
@@ -394,6 +394,60 @@ func MarkBootSuccessful(dev snap.Device) error {
 	return nil
 }
 
+// MaxKernelBootTries is the largest number of attempts that the bootloader
+// can be configured to make to boot a try kernel.
+const MaxKernelBootTries = 5
+
+// SetKernelBootTries configures boot counting for kernel updates. With a
+// non-zero number of tries, the bootloader makes up to that many attempts
+// to boot a new kernel before reverting to the previous one by itself,
+// while zero restores the default of reverting after the first failed
+// attempt. Boot counting is reset by MarkBootSuccessful. Only systems with
+// a modeenv and a run mode bootloader which counts the attempts support boot
+// counting, which currently means grub with a snapd managed boot config.
+// Other bootloaders, notably u-boot, do not count the attempts and setting a
+// non-zero number of tries fails on them.
+func SetKernelBootTries(tries int) error {
+	if tries < 0 || tries > MaxKernelBootTries {
+		return fmt.Errorf("cannot use %d kernel boot tries, must be between 0 and %d", tries, MaxKernelBootTries)
+	}
+	m, err := loadModeenv()
+	if err != nil {
+		return err
+	}
+	if m.KernelBootTries == tries {
+		return nil
+	}
+	if tries > 0 {
+		if err := checkKernelBootTriesSupported(); err != nil {
+			return err
+		}
+	}
+	m.KernelBootTries = tries
+	return m.Write()
+}
+
+func checkKernelBootTriesSupported() error {
+	opts := &bootloader.Options{
+		Role: bootloader.RoleRunMode,
+	}
+	bl, err := bootloader.Find(InitramfsUbuntuBootDir, opts)
+	if err != nil {
+		return err
+	}
+	supported := false
+	if cbl, ok := bl.(bootloader.BootCountingBootloader); ok {
+		supported, err = cbl.SupportsKernelBootTries()
+		if err != nil {
+			return fmt.Errorf("cannot check boot counting support of bootloader %q: %v", bl.Name(), err)
+		}
+	}
+	if !supported {
+		return fmt.Errorf("cannot use kernel boot tries, bootloader %q does not support boot counting (only grub does)", bl.Name())
+	}
+	return nil
+}
+
 var ErrUnsupportedSystemMode = errors.New("system mode is unsupported")
 
 // SetRecoveryBootSystemAndMode configures the recovery bootloader to boot into
@@ -2069,6 +2069,144 @@ func (s *bootenv20Suite) TestMarkBootSuccessful20KernelUpdate(c *C) {
 	c.Assert(nDisableTryCalls, Equals, 2)
 }
 
+func (s *bootenv20Suite) TestMarkBootSuccessful20KernelUpdateWithBootCounting(c *C) {
+	// trying a kernel snap with boot counting
+	m := &boot.Modeenv{
+		Mode:            "run",
+		Base:            s.base1.Filename(),
+		CurrentKernels:  []string{s.kern1.Filename(), s.kern2.Filename()},
+		KernelBootTries: 3,
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.TryingStatus,
+		},
+	)
+	defer r()
+	// the bootloader used 2 attempts to boot the try kernel
+	c.Assert(s.bootloader.SetBootVars(map[string]string{"kernel_tries_left": "1"}), IsNil)
+
+	coreDev := boottest.MockUC20Device("", nil)
+	err := boot.MarkBootSuccessful(coreDev)
+	c.Assert(err, IsNil)
+
+	// the counter is reset together with the status
+	c.Assert(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"kernel_status":     boot.DefaultStatus,
+		"kernel_tries_left": "",
+	})
+	actual, _ := s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableKernel")
+	c.Assert(actual, DeepEquals, []snap.PlaceInfo{s.kern2})
+
+	// and the number of attempts is recorded
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernels, DeepEquals, []string{s.kern2.Filename()})
+	c.Check(m2.KernelBootTries, Equals, 3)
+	c.Check(m2.KernelBootAttempts, Equals, 2)
+}
+
+func (s *bootenv20EnvRefKernelSuite) TestMarkBootSuccessful20KernelUpdateWithBootCounting(c *C) {
+	m := &boot.Modeenv{
+		Mode:            "run",
+		Base:            s.base1.Filename(),
+		CurrentKernels:  []string{s.kern1.Filename(), s.kern2.Filename()},
+		KernelBootTries: 2,
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.TryingStatus,
+		},
+	)
+	defer r()
+	c.Assert(s.bootloader.SetBootVars(map[string]string{"kernel_tries_left": "1"}), IsNil)
+
+	coreDev := boottest.MockUC20Device("", nil)
+	err := boot.MarkBootSuccessful(coreDev)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"kernel_status":     boot.DefaultStatus,
+		"kernel_tries_left": "",
+		"snap_kernel":       s.kern2.Filename(),
+		"snap_try_kernel":   "",
+	})
+
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.KernelBootAttempts, Equals, 1)
+}
+
+func (s *bootenv20Suite) TestSetKernelBootTries(c *C) {
+	m := &boot.Modeenv{
+		Mode: "run",
+		Base: s.base1.Filename(),
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+	s.forceBootloader(bootloadertest.Mock("mock", c.MkDir()).WithBootCounting())
+
+	err := boot.SetKernelBootTries(3)
+	c.Assert(err, IsNil)
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.KernelBootTries, Equals, 3)
+
+	err = boot.SetKernelBootTries(0)
+	c.Assert(err, IsNil)
+	m2, err = boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.KernelBootTries, Equals, 0)
+
+	err = boot.SetKernelBootTries(6)
+	c.Check(err, ErrorMatches, "cannot use 6 kernel boot tries, must be between 0 and 5")
+}
+
+func (s *bootenv20Suite) TestSetKernelBootTriesUnsupported(c *C) {
+	m := &boot.Modeenv{
+		Mode: "run",
+		Base: s.base1.Filename(),
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+
+	// the bootloader does not count boot attempts at all
+	err := boot.SetKernelBootTries(3)
+	c.Assert(err, ErrorMatches, `cannot use kernel boot tries, bootloader "mock" does not support boot counting \(only grub does\)`)
+
+	// or its boot config does not
+	cbl := bootloadertest.Mock("mock", c.MkDir()).WithBootCounting()
+	cbl.KernelBootTriesSupported = false
+	s.forceBootloader(cbl)
+	err = boot.SetKernelBootTries(3)
+	c.Assert(err, ErrorMatches, `cannot use kernel boot tries, bootloader "mock" does not support boot counting \(only grub does\)`)
+
+	cbl.KernelBootTriesErr = errors.New("boom")
+	err = boot.SetKernelBootTries(3)
+	c.Assert(err, ErrorMatches, `cannot check boot counting support of bootloader "mock": boom`)
+
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.KernelBootTries, Equals, 0)
+
+	// disabling boot counting is always possible
+	m.KernelBootTries = 3
+	c.Assert(m.WriteTo(""), IsNil)
+	err = boot.SetKernelBootTries(0)
+	c.Assert(err, IsNil)
+	m2, err = boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.KernelBootTries, Equals, 0)
+}
+
 func (s *bootenv20Suite) TestMarkBootSuccessful20KernelUpdateWithReseal(c *C) {
 	// checked by resealKeyToModeenv
 	s.stampSealedKeys(c, dirs.GlobalRootDir)
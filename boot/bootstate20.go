@@ -88,6 +88,10 @@ type bootloaderKernelState20 interface {
 	kernel() snap.PlaceInfo
 	// kernel returns the current try kernel if it exists on the bootloader
 	tryKernel() (snap.PlaceInfo, error)
+	// kernelTriesLeft returns the number of attempts the bootloader has
+	// left to boot the try kernel, as kept in the kernel_tries_left
+	// bootenv, ok is false when boot counting is not in use
+	kernelTriesLeft() (left int, ok bool)
 
 	// setNextKernel marks the kernel as the next, if it's not the currently
 	// booted kernel, then the specified kernel is setup as a try-kernel
@@ -95,6 +99,9 @@ type bootloaderKernelState20 interface {
 	// markSuccessfulKernel marks the specified kernel as having booted
 	// successfully, whether that kernel is the current kernel or the try-kernel
 	markSuccessfulKernel(sn snap.PlaceInfo) error
+	// setKernelTriesLeft sets the number of attempts the bootloader makes
+	// to boot the try kernel, it shall be called before setNextKernel
+	setKernelTriesLeft(tries int) error
 	// setNextKernelNoTry changes boot configuration so the specified kernel will
 	// be the one used in next boot, without the "try" logic. This shall be
 	// used only when we have already booted to a new kernel but for some
@@ -321,6 +328,14 @@ func (ks20 *bootState20Kernel) markSuccessful(update bootStateUpdate) (bootState
 		// On commit, set CurrentKernels as just this kernel because that is the
 		// successful kernel we booted
 		u20.writeModeenv.CurrentKernels = []string{sn.Filename()}
+
+		// record how many attempts it took to boot a kernel that was
+		// tried with boot counting
+		if tries := u20.modeenv.KernelBootTries; tries > 0 && ks20.bks.kernelStatus() == TryingStatus {
+			if left, ok := ks20.bks.kernelTriesLeft(); ok && left < tries {
+				u20.writeModeenv.KernelBootAttempts = tries - left
+			}
+		}
 	}
 
 	return u20, nil
@@ -367,6 +382,17 @@ func (ks20 *bootState20Kernel) setNext(next snap.PlaceInfo, bootCtx NextBootCont
 		bootCtx.BootWithoutTry, u20.writeModeenv.CurrentKernels)
 
 	bootTask := func() error { return ks20.bks.setNextKernel(next, nextStatus) }
+	if tries := u20.modeenv.KernelBootTries; tries > 0 && nextStatus == TryStatus {
+		// with boot counting the bootloader makes the given number of
+		// attempts to boot the try kernel before reverting, the
+		// counter must be in place before the try status is set
+		bootTask = func() error {
+			if err := ks20.bks.setKernelTriesLeft(tries); err != nil {
+				return err
+			}
+			return ks20.bks.setNextKernel(next, nextStatus)
+		}
+	}
 	if bootCtx.BootWithoutTry {
 		// force revert to "next" kernel (actually it is the old one)
 		// and ignore the try status, that will be empty in this case.
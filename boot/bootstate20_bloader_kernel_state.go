@@ -21,6 +21,7 @@ package boot
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/snap"
@@ -33,18 +34,21 @@ type extractedRunKernelImageBootloaderKernelState struct {
 	ebl bootloader.ExtractedRunKernelImageBootloader
 	// the current kernel status as read by the bootloader's bootenv
 	currentKernelStatus string
+	// the current kernel_tries_left as read by the bootloader's bootenv
+	currentKernelTriesLeft string
 	// the current kernel on the bootloader (not the try-kernel)
 	currentKernel snap.PlaceInfo
 }
 
 func (bks *extractedRunKernelImageBootloaderKernelState) load() error {
-	// get the kernel_status
-	m, err := bks.ebl.GetBootVars("kernel_status")
+	// get the kernel_status and boot counting state
+	m, err := bks.ebl.GetBootVars("kernel_status", "kernel_tries_left")
 	if err != nil {
 		return err
 	}
 
 	bks.currentKernelStatus = m["kernel_status"]
+	bks.currentKernelTriesLeft = m["kernel_tries_left"]
 
 	// get the current kernel for this bootloader to compare during commit() for
 	// markSuccessful() if we booted the current kernel or not
@@ -70,6 +74,18 @@ func (bks *extractedRunKernelImageBootloaderKernelState) kernelStatus() string {
 	return bks.currentKernelStatus
 }
 
+func (bks *extractedRunKernelImageBootloaderKernelState) kernelTriesLeft() (int, bool) {
+	return parseKernelTriesLeft(bks.currentKernelTriesLeft)
+}
+
+func (bks *extractedRunKernelImageBootloaderKernelState) setKernelTriesLeft(tries int) error {
+	v := strconv.Itoa(tries)
+	if v == bks.currentKernelTriesLeft {
+		return nil
+	}
+	return bks.ebl.SetBootVars(map[string]string{"kernel_tries_left": v})
+}
+
 func (bks *extractedRunKernelImageBootloaderKernelState) markSuccessfulKernel(sn snap.PlaceInfo) error {
 	// set the boot vars first, then enable the successful kernel, then disable
 	// the old try-kernel, see the comment in bootState20MarkSuccessful.commit()
@@ -119,10 +135,14 @@ func (bks *extractedRunKernelImageBootloaderKernelState) markSuccessfulKernel(sn
 	// technically this boot wasn't "successful" - it was successful in the
 	// sense that we booted some combination of boot snaps and made it all the
 	// way to snapd in user space
-	if bks.currentKernelStatus != DefaultStatus {
+	// boot counting, if used, is reset together with the status
+	if bks.currentKernelStatus != DefaultStatus || bks.currentKernelTriesLeft != "" {
 		m := map[string]string{
 			"kernel_status": DefaultStatus,
 		}
+		if bks.currentKernelTriesLeft != "" {
+			m["kernel_tries_left"] = ""
+		}
 
 		// set the boot variables
 		err := bks.ebl.SetBootVars(m)
@@ -190,10 +210,13 @@ func (bks *extractedRunKernelImageBootloaderKernelState) setNextKernelNoTry(sn s
 	// we are undoing it might be there or not.
 	bks.ebl.DisableTryKernel()
 
-	if bks.currentKernelStatus != DefaultStatus {
+	if bks.currentKernelStatus != DefaultStatus || bks.currentKernelTriesLeft != "" {
 		m := map[string]string{
 			"kernel_status": DefaultStatus,
 		}
+		if bks.currentKernelTriesLeft != "" {
+			m["kernel_tries_left"] = ""
+		}
 
 		// set the boot variables
 		return bks.ebl.SetBootVars(m)
@@ -220,12 +243,16 @@ type envRefExtractedKernelBootloaderKernelState struct {
 }
 
 func (envbks *envRefExtractedKernelBootloaderKernelState) load() error {
-	// for uc20, we only care about kernel_status, snap_kernel, and
-	// snap_try_kernel
-	m, err := envbks.bl.GetBootVars("kernel_status", "snap_kernel", "snap_try_kernel")
+	// for uc20, we only care about kernel_status, snap_kernel,
+	// snap_try_kernel and kernel_tries_left
+	m, err := envbks.bl.GetBootVars("kernel_status", "snap_kernel", "snap_try_kernel", "kernel_tries_left")
 	if err != nil {
 		return err
 	}
+	// kernel_tries_left is only written back when boot counting is in use
+	if m["kernel_tries_left"] == "" {
+		delete(m, "kernel_tries_left")
+	}
 
 	// the default commit env is the same state as the current env
 	envbks.env = m
@@ -267,6 +294,20 @@ func (envbks *envRefExtractedKernelBootloaderKernelState) kernelStatus() string
 	return envbks.env["kernel_status"]
 }
 
+func (envbks *envRefExtractedKernelBootloaderKernelState) kernelTriesLeft() (int, bool) {
+	return parseKernelTriesLeft(envbks.env["kernel_tries_left"])
+}
+
+func (envbks *envRefExtractedKernelBootloaderKernelState) setKernelTriesLeft(tries int) error {
+	envbks.toCommit["kernel_tries_left"] = strconv.Itoa(tries)
+	if envbks.env["kernel_tries_left"] == envbks.toCommit["kernel_tries_left"] {
+		return nil
+	}
+	return envbks.bl.SetBootVars(map[string]string{
+		"kernel_tries_left": envbks.toCommit["kernel_tries_left"],
+	})
+}
+
 func (envbks *envRefExtractedKernelBootloaderKernelState) commonStateCommitUpdate(sn snap.PlaceInfo, bootvar string) bool {
 	envChanged := false
 
@@ -293,6 +334,12 @@ func (envbks *envRefExtractedKernelBootloaderKernelState) markSuccessfulKernel(s
 	envbks.toCommit["kernel_status"] = DefaultStatus
 	envChanged := envbks.commonStateCommitUpdate(sn, "snap_kernel")
 
+	// reset boot counting
+	if envbks.env["kernel_tries_left"] != "" {
+		envChanged = true
+		envbks.toCommit["kernel_tries_left"] = ""
+	}
+
 	// if the snap_try_kernel is set, we should unset that to both cleanup after
 	// a successful trying -> "" transition, but also to cleanup if we got
 	// rebooted during the process and have it leftover
@@ -322,6 +369,10 @@ func (envbks *envRefExtractedKernelBootloaderKernelState) setNextKernel(sn snap.
 func (envbks *envRefExtractedKernelBootloaderKernelState) setNextKernelNoTry(sn snap.PlaceInfo) error {
 	envbks.toCommit["kernel_status"] = ""
 	bootenvChanged := envbks.commonStateCommitUpdate(sn, "snap_kernel")
+	if envbks.env["kernel_tries_left"] != "" {
+		bootenvChanged = true
+		envbks.toCommit["kernel_tries_left"] = ""
+	}
 
 	if bootenvChanged {
 		return envbks.bl.SetBootVars(envbks.toCommit)
@@ -329,3 +380,16 @@ func (envbks *envRefExtractedKernelBootloaderKernelState) setNextKernelNoTry(sn
 
 	return nil
 }
+
+// parseKernelTriesLeft parses the value of kernel_tries_left bootenv, ok
+// is false if boot counting is not in use or the value is invalid.
+func parseKernelTriesLeft(v string) (left int, ok bool) {
+	if v == "" {
+		return 0, false
+	}
+	left, err := strconv.Atoi(v)
+	if err != nil || left < 0 {
+		return 0, false
+	}
+	return left, true
+}
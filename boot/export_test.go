@@ -179,6 +179,7 @@ var (
 	WriteBootChains                     = writeBootChains
 	ReadBootChains                      = readBootChains
 	IsResealNeeded                      = isResealNeeded
+	ResealObjectDryRun                  = resealObjectDryRun
 
 	SetImageBootFlags = setImageBootFlags
 	NextBootFlags     = nextBootFlags
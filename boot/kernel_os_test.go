@@ -190,6 +190,77 @@ func (s *bootenv20Suite) TestSetNextBoot20ForKernel(c *C) {
 	c.Assert(m2.CurrentKernels, DeepEquals, []string{s.kern1.Filename(), s.kern2.Filename()})
 }
 
+func (s *bootenv20Suite) TestSetNextBoot20ForKernelWithBootCounting(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	m := &boot.Modeenv{
+		Mode:            "run",
+		Base:            s.base1.Filename(),
+		CurrentKernels:  []string{s.kern1.Filename()},
+		KernelBootTries: 3,
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+
+	bs := boot.NewCoreBootParticipant(s.kern2, snap.TypeKernel, coreDev)
+	rebootInfo, err := bs.SetNextBoot(boot.NextBootContext{BootWithoutTry: false})
+	c.Assert(err, IsNil)
+	c.Check(rebootInfo, Equals, boot.RebootInfo{RebootRequired: true})
+
+	// the bootloader gets the number of attempts along with the try status
+	v, err := s.bootloader.GetBootVars("kernel_status", "kernel_tries_left")
+	c.Assert(err, IsNil)
+	c.Assert(v, DeepEquals, map[string]string{
+		"kernel_status":     boot.TryStatus,
+		"kernel_tries_left": "3",
+	})
+
+	actual, _ := s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableTryKernel")
+	c.Assert(actual, DeepEquals, []snap.PlaceInfo{s.kern2})
+}
+
+func (s *bootenv20EnvRefKernelSuite) TestSetNextBoot20ForKernelWithBootCounting(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	m := &boot.Modeenv{
+		Mode:            "run",
+		Base:            s.base1.Filename(),
+		CurrentKernels:  []string{s.kern1.Filename()},
+		KernelBootTries: 2,
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+
+	bs := boot.NewCoreBootParticipant(s.kern2, snap.TypeKernel, coreDev)
+	_, err := bs.SetNextBoot(boot.NextBootContext{BootWithoutTry: false})
+	c.Assert(err, IsNil)
+
+	c.Assert(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"kernel_status":     boot.TryStatus,
+		"kernel_tries_left": "2",
+		"snap_try_kernel":   s.kern2.Filename(),
+		"snap_kernel":       s.kern1.Filename(),
+	})
+}
+
 func (s *bootenv20EnvRefKernelSuite) TestSetNextBoot20ForKernel(c *C) {
 	coreDev := boottest.MockUC20Device("", nil)
 	c.Assert(coreDev.HasModeenv(), Equals, true)
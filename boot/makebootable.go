@@ -525,6 +525,7 @@ func makeRunnableSystem(model *asserts.Model, bootWith *BootableSet, sealer *Tru
 		flags := sealKeyToModeenvFlags{
 			HasFDESetupHook: hasHook,
 			FactoryReset:    makeOpts.AfterDataReset,
+			UsePassphrase:   sealer.usePassphrase,
 		}
 		if makeOpts.Standalone {
 			flags.SnapsDir = snapBlobDir
@@ -103,6 +103,13 @@ type Modeenv struct {
 	// update scenarios.
 	CurrentKernelCommandLines bootCommandLines `key:"current_kernel_command_lines"`
 	// TODO:UC20 add a per recovery system list of kernel command lines
+	// KernelBootTries is the number of attempts the bootloader makes to
+	// boot a try kernel before reverting to the previous one, zero
+	// disables boot counting.
+	KernelBootTries int `key:"kernel_boot_tries"`
+	// KernelBootAttempts is the number of attempts it took to boot the
+	// last kernel that was tried with boot counting enabled.
+	KernelBootAttempts int `key:"kernel_boot_attempts"`
 
 	// read is set to true when a modenv was read successfully
 	read bool
@@ -210,6 +217,8 @@ func ReadModeenv(rootdir string) (*Modeenv, error) {
 	unmarshalModeenvValueFromCfg(cfg, "current_trusted_boot_assets", &m.CurrentTrustedBootAssets)
 	unmarshalModeenvValueFromCfg(cfg, "current_trusted_recovery_boot_assets", &m.CurrentTrustedRecoveryBootAssets)
 	unmarshalModeenvValueFromCfg(cfg, "current_kernel_command_lines", &m.CurrentKernelCommandLines)
+	unmarshalModeenvValueFromCfg(cfg, "kernel_boot_tries", &m.KernelBootTries)
+	unmarshalModeenvValueFromCfg(cfg, "kernel_boot_attempts", &m.KernelBootAttempts)
 
 	// save all the rest of the keys we don't understand
 	keys, err := cfg.Options("")
@@ -329,6 +338,8 @@ func (m *Modeenv) WriteTo(rootdir string) error {
 	marshalModeenvEntryTo(buf, "current_trusted_boot_assets", m.CurrentTrustedBootAssets)
 	marshalModeenvEntryTo(buf, "current_trusted_recovery_boot_assets", m.CurrentTrustedRecoveryBootAssets)
 	marshalModeenvEntryTo(buf, "current_kernel_command_lines", m.CurrentKernelCommandLines)
+	marshalModeenvEntryTo(buf, "kernel_boot_tries", m.KernelBootTries)
+	marshalModeenvEntryTo(buf, "kernel_boot_attempts", m.KernelBootAttempts)
 
 	// write all the extra keys at the end
 	// sort them for test convenience
@@ -445,6 +456,11 @@ func marshalModeenvEntryTo(out io.Writer, key string, what interface{}) error {
 		asString = asModeenvStringList(v)
 	case bool:
 		asString = strconv.FormatBool(v)
+	case int:
+		if v == 0 {
+			return nil
+		}
+		asString = strconv.Itoa(v)
 	default:
 		if vm, ok := what.(modeenvValueMarshaller); ok {
 			marshalled, err := vm.MarshalModeenvValue()
@@ -494,6 +510,16 @@ func unmarshalModeenvValueFromCfg(cfg *goconfigparser.ConfigParser, key string,
 		if err != nil {
 			return fmt.Errorf("cannot parse modeenv value %q to bool: %v", kv, err)
 		}
+	case *int:
+		if kv == "" {
+			*v = 0
+			return nil
+		}
+		var err error
+		*v, err = strconv.Atoi(kv)
+		if err != nil {
+			return fmt.Errorf("cannot parse modeenv value %q to int: %v", kv, err)
+		}
 	default:
 		if vm, ok := v.(modeenvValueUnmarshaller); ok {
 			if err := vm.UnmarshalModeenvValue(kv); err != nil {
@@ -78,6 +78,8 @@ func (s *modeenvSuite) TestKnownKnown(c *C) {
 		"current_kernel_command_lines":         true,
 		"current_trusted_boot_assets":          true,
 		"current_trusted_recovery_boot_assets": true,
+		"kernel_boot_tries":                    true,
+		"kernel_boot_attempts":                 true,
 	})
 }
 
@@ -799,6 +801,34 @@ current_kernel_command_lines=["snapd_recovery_mode=run panic=-1 console=ttyS0,io
 	})
 }
 
+func (s *modeenvSuite) TestMarshalKernelBootTries(c *C) {
+	c.Assert(s.mockModeenvPath, testutil.FileAbsent)
+
+	modeenv := &boot.Modeenv{
+		Mode:               "run",
+		KernelBootTries:    3,
+		KernelBootAttempts: 2,
+	}
+	err := modeenv.WriteTo(s.tmpdir)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+kernel_boot_tries=3
+kernel_boot_attempts=2
+`)
+
+	modeenvRead, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.KernelBootTries, Equals, 3)
+	c.Check(modeenvRead.KernelBootAttempts, Equals, 2)
+
+	// zero values are omitted
+	modeenvRead.KernelBootTries = 0
+	modeenvRead.KernelBootAttempts = 0
+	c.Assert(modeenvRead.Write(), IsNil)
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, "mode=run\n")
+}
+
 func (s *modeenvSuite) TestModeenvWithModelGradeSignKeyID(c *C) {
 	s.makeMockModeenvFile(c, `mode=run
 model=canonical/ubuntu-core-20-amd64
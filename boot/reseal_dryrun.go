@@ -0,0 +1,333 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/device"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// ResealDryRunReport describes what resealing the encryption keys to the
+// current modeenv would do.
+type ResealDryRunReport struct {
+	// SealingMethod is the method the keys were sealed with, empty if
+	// there are no sealed keys.
+	SealingMethod string `json:"sealing-method,omitempty"`
+	// Objects describes the sealed key objects, it is only set for keys
+	// sealed with the TPM.
+	Objects []*ResealDryRunObject `json:"objects,omitempty"`
+}
+
+// ResealDryRunObject describes how a sealed key object would be resealed.
+type ResealDryRunObject struct {
+	// Name is "run" for the object used in run mode or "fallback" for the
+	// one used in recovery modes.
+	Name           string `json:"name"`
+	BootChainsFile string `json:"boot-chains-file"`
+	ResealCount    int    `json:"reseal-count"`
+	ResealNeeded   bool   `json:"reseal-needed"`
+	// Profile is the PCR protection profile the object would be resealed
+	// with.
+	Profile []*ResealProfileModel `json:"profile"`
+	// Differences lists how the boot chains differ from the ones the object
+	// is currently sealed to.
+	Differences []string `json:"differences,omitempty"`
+	// Problems lists issues that would make the reseal fail.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// ResealProfileModel is the part of a PCR protection profile bound to a
+// single model.
+type ResealProfileModel struct {
+	Model          string   `json:"model"`
+	Grade          string   `json:"grade"`
+	KernelCmdlines []string `json:"kernel-cmdlines"`
+	// LoadChains are the sequences of boot assets, each one with a
+	// specific hash, that are measured when booting.
+	LoadChains []string `json:"load-chains"`
+}
+
+// ResealKeyToModeenvDryRun computes the boot chains that the encryption keys
+// would be resealed to for the given modeenv and compares them with the boot
+// chains the keys are currently sealed to, as persisted under rootdir. The TPM
+// is not used.
+func ResealKeyToModeenvDryRun(rootdir string, modeenv *Modeenv) (*ResealDryRunReport, error) {
+	method, err := device.SealedKeysMethod(rootdir)
+	if err == device.ErrNoSealedKeys {
+		return &ResealDryRunReport{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	report := &ResealDryRunReport{SealingMethod: string(method)}
+	switch method {
+	case device.SealingMethodTPM, device.SealingMethodLegacyTPM:
+		// only keys sealed with the TPM are resealed
+	default:
+		return report, nil
+	}
+
+	pbc, rpbc, roleToBlName, err := bootChainsForReseal(modeenv)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range []struct {
+		name       string
+		bootChains predictableBootChains
+		file       string
+	}{
+		{"run", pbc, bootChainsFileUnder(rootdir)},
+		{"fallback", rpbc, recoveryBootChainsFileUnder(rootdir)},
+	} {
+		dryRun, err := resealObjectDryRun(obj.name, obj.bootChains, obj.file, roleToBlName)
+		if err != nil {
+			return nil, err
+		}
+		report.Objects = append(report.Objects, dryRun)
+	}
+	return report, nil
+}
+
+func resealObjectDryRun(name string, pbc predictableBootChains, bootChainsFile string, roleToBlName map[bootloader.Role]string) (*ResealDryRunObject, error) {
+	previousPbc, count, err := readBootChains(bootChainsFile)
+	if err != nil {
+		return nil, err
+	}
+	obj := &ResealDryRunObject{
+		Name:           name,
+		BootChainsFile: bootChainsFile,
+		ResealCount:    count,
+		Profile:        resealProfile(pbc),
+		Problems:       missingBootAssets(pbc, roleToBlName),
+	}
+	switch predictableBootChainsEqualForReseal(pbc, previousPbc) {
+	case bootChainEquivalent:
+	case bootChainUnrevisioned:
+		obj.ResealNeeded = true
+		obj.Differences = []string{"boot chains use unasserted kernels, they cannot be compared reliably"}
+	default:
+		obj.ResealNeeded = true
+		if previousPbc == nil {
+			obj.Differences = []string{"no boot chains were recorded for the sealed keys"}
+		} else {
+			obj.Differences = diffBootChains(previousPbc, pbc)
+		}
+	}
+	return obj, nil
+}
+
+// resealProfile summarizes the boot chains in the way they are grouped per
+// model when resealing, see sealKeyModelParams.
+func resealProfile(pbc predictableBootChains) []*ResealProfileModel {
+	var profile []*ResealProfileModel
+	byModel := make(map[string]*ResealProfileModel)
+	for _, bc := range pbc {
+		modelID := modelUniqueID(bc.modelForSealing())
+		loadChains := loadChainsDescriptions(&bc)
+		if pm, ok := byModel[modelID]; ok {
+			pm.KernelCmdlines = strutil.SortedListsUniqueMerge(pm.KernelCmdlines, bc.KernelCmdlines)
+			pm.LoadChains = append(pm.LoadChains, loadChains...)
+			continue
+		}
+		pm := &ResealProfileModel{
+			Model:          fmt.Sprintf("%s/%s", bc.BrandID, bc.Model),
+			Grade:          string(bc.Grade),
+			KernelCmdlines: bc.KernelCmdlines,
+			LoadChains:     loadChains,
+		}
+		profile = append(profile, pm)
+		byModel[modelID] = pm
+	}
+	return profile
+}
+
+// loadChainsDescriptions describes all the sequences of boot assets of the
+// boot chain, much like bootAssetsToLoadChains builds them.
+func loadChainsDescriptions(bc *bootChain) []string {
+	chains := []string{kernelDescription(bc)}
+	for i := len(bc.AssetChain) - 1; i >= 0; i-- {
+		asset := &bc.AssetChain[i]
+		var next []string
+		for _, hash := range asset.Hashes {
+			for _, chain := range chains {
+				next = append(next, fmt.Sprintf("%s:%s@%s -> %s", asset.Role, asset.Name, shortHash(hash), chain))
+			}
+		}
+		chains = next
+	}
+	return chains
+}
+
+func kernelDescription(bc *bootChain) string {
+	if bc.KernelRevision == "" {
+		return fmt.Sprintf("%s (unasserted)", bc.Kernel)
+	}
+	return fmt.Sprintf("%s (rev %s)", bc.Kernel, bc.KernelRevision)
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func shortHashes(hashes []string) string {
+	short := make([]string, len(hashes))
+	for i, hash := range hashes {
+		short[i] = shortHash(hash)
+	}
+	return "[" + strings.Join(short, " ") + "]"
+}
+
+// missingBootAssets lists the boot assets of the boot chains that are not
+// present in the boot assets cache, which would make the reseal fail.
+func missingBootAssets(pbc predictableBootChains, roleToBlName map[bootloader.Role]string) []string {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, bc := range pbc {
+		for _, asset := range bc.AssetChain {
+			for _, hash := range asset.Hashes {
+				p := filepath.Join(dirs.SnapBootAssetsDir,
+					trustedAssetCacheRelPath(roleToBlName[asset.Role], asset.Name, hash))
+				if seen[p] {
+					continue
+				}
+				seen[p] = true
+				if !osutil.FileExists(p) {
+					missing = append(missing, fmt.Sprintf("boot asset %s:%s@%s is missing from the boot assets cache",
+						asset.Role, asset.Name, shortHash(hash)))
+				}
+			}
+		}
+	}
+	return missing
+}
+
+func sameBootChainShape(bc1, bc2 *bootChain) bool {
+	if modelUniqueID(bc1.modelForSealing()) != modelUniqueID(bc2.modelForSealing()) {
+		return false
+	}
+	if len(bc1.AssetChain) != len(bc2.AssetChain) {
+		return false
+	}
+	for i := range bc1.AssetChain {
+		if bc1.AssetChain[i].Role != bc2.AssetChain[i].Role || bc1.AssetChain[i].Name != bc2.AssetChain[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+func bootChainDescription(bc *bootChain) string {
+	assets := make([]string, 0, len(bc.AssetChain))
+	for _, asset := range bc.AssetChain {
+		assets = append(assets, fmt.Sprintf("%s:%s", asset.Role, asset.Name))
+	}
+	assets = append(assets, kernelDescription(bc))
+	return fmt.Sprintf("%s/%s: %s", bc.BrandID, bc.Model, strings.Join(assets, " -> "))
+}
+
+// diffBootChains describes how the new boot chains differ from the old ones.
+// Chains that differ are paired up by model and sequence of boot assets so
+// that the specific asset, kernel or command line that changed is reported.
+func diffBootChains(oldPbc, newPbc predictableBootChains) []string {
+	var diffs []string
+	oldUnmatched := make([]*bootChain, 0, len(oldPbc))
+	for i := range oldPbc {
+		oldUnmatched = append(oldUnmatched, &oldPbc[i])
+	}
+	var newUnmatched []*bootChain
+	for i := range newPbc {
+		found := false
+		for j, oldBc := range oldUnmatched {
+			if predictableBootChainsEqualForReseal(predictableBootChains{*oldBc}, predictableBootChains{newPbc[i]}) != bootChainDifferent {
+				oldUnmatched = append(oldUnmatched[:j], oldUnmatched[j+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			newUnmatched = append(newUnmatched, &newPbc[i])
+		}
+	}
+
+	for _, newBc := range newUnmatched {
+		matched := -1
+		for j, oldBc := range oldUnmatched {
+			if sameBootChainShape(oldBc, newBc) {
+				matched = j
+				break
+			}
+		}
+		if matched == -1 {
+			diffs = append(diffs, fmt.Sprintf("new boot chain %s", bootChainDescription(newBc)))
+			continue
+		}
+		oldBc := oldUnmatched[matched]
+		oldUnmatched = append(oldUnmatched[:matched], oldUnmatched[matched+1:]...)
+		diffs = append(diffs, diffBootChain(oldBc, newBc)...)
+	}
+	for _, oldBc := range oldUnmatched {
+		diffs = append(diffs, fmt.Sprintf("boot chain %s is no longer used", bootChainDescription(oldBc)))
+	}
+	return diffs
+}
+
+func diffBootChain(oldBc, newBc *bootChain) []string {
+	var diffs []string
+	prefix := bootChainDescription(newBc)
+	for i := range newBc.AssetChain {
+		oldAsset, newAsset := &oldBc.AssetChain[i], &newBc.AssetChain[i]
+		if !stringListsEqual(oldAsset.Hashes, newAsset.Hashes) {
+			diffs = append(diffs, fmt.Sprintf("%s: boot asset %s:%s hashes changed from %s to %s",
+				prefix, newAsset.Role, newAsset.Name, shortHashes(oldAsset.Hashes), shortHashes(newAsset.Hashes)))
+		}
+	}
+	if oldBc.Kernel != newBc.Kernel || oldBc.KernelRevision != newBc.KernelRevision {
+		diffs = append(diffs, fmt.Sprintf("%s: kernel changed from %s to %s",
+			prefix, kernelDescription(oldBc), kernelDescription(newBc)))
+	}
+	if added := stringListsDifference(newBc.KernelCmdlines, oldBc.KernelCmdlines); len(added) > 0 {
+		diffs = append(diffs, fmt.Sprintf("%s: kernel command lines added: %s", prefix, strutil.Quoted(added)))
+	}
+	if removed := stringListsDifference(oldBc.KernelCmdlines, newBc.KernelCmdlines); len(removed) > 0 {
+		diffs = append(diffs, fmt.Sprintf("%s: kernel command lines removed: %s", prefix, strutil.Quoted(removed)))
+	}
+	return diffs
+}
+
+// stringListsDifference returns the elements of sl1 that are not in sl2.
+func stringListsDifference(sl1, sl2 []string) []string {
+	var diff []string
+	for _, s := range sl1 {
+		if !strutil.ListContains(sl2, s) {
+			diff = append(diff, s)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,177 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/device"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type resealDryRunSuite struct {
+	testutil.BaseTest
+
+	rootDir string
+}
+
+var _ = Suite(&resealDryRunSuite{})
+
+func (s *resealDryRunSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.rootDir = c.MkDir()
+	s.AddCleanup(func() { dirs.SetRootDir("/") })
+	dirs.SetRootDir(s.rootDir)
+}
+
+func (s *resealDryRunSuite) TestNoSealedKeys(c *C) {
+	report, err := boot.ResealKeyToModeenvDryRun(s.rootDir, &boot.Modeenv{Mode: "run"})
+	c.Assert(err, IsNil)
+	c.Check(report, DeepEquals, &boot.ResealDryRunReport{})
+}
+
+func (s *resealDryRunSuite) TestNotSealedWithTPM(c *C) {
+	for _, method := range []device.SealingMethod{device.SealingMethodFDESetupHook, device.SealingMethodPassphrase} {
+		c.Assert(device.StampSealedKeys(s.rootDir, method), IsNil)
+
+		report, err := boot.ResealKeyToModeenvDryRun(s.rootDir, &boot.Modeenv{Mode: "run"})
+		c.Assert(err, IsNil)
+		c.Check(report, DeepEquals, &boot.ResealDryRunReport{SealingMethod: string(method)})
+	}
+}
+
+func (s *resealDryRunSuite) mockBootChain(assetHash, kernelRev string, cmdlines ...string) boot.BootChain {
+	return boot.BootChain{
+		BrandID:        "mybrand",
+		Model:          "foo",
+		Grade:          asserts.ModelSigned,
+		ModelSignKeyID: "my-key-id",
+		AssetChain: []boot.BootAsset{
+			{Role: bootloader.RoleRecovery, Name: "shim", Hashes: []string{"5ac9a5e5a2ba4b6d0c4e0aa82b1e0b2c"}},
+			{Role: bootloader.RoleRunMode, Name: "loader", Hashes: []string{assetHash}},
+		},
+		Kernel:         "pc-kernel",
+		KernelRevision: kernelRev,
+		KernelCmdlines: cmdlines,
+	}
+}
+
+var mockRoleToBlName = map[bootloader.Role]string{
+	bootloader.RoleRecovery: "grub",
+	bootloader.RoleRunMode:  "grub",
+}
+
+func (s *resealDryRunSuite) mockCachedAsset(c *C, name, hash string) {
+	p := filepath.Join(dirs.SnapBootAssetsDir, "grub", name+"-"+hash)
+	c.Assert(os.MkdirAll(filepath.Dir(p), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(p, nil, 0644), IsNil)
+}
+
+func (s *resealDryRunSuite) TestResealObjectDryRunNotNeeded(c *C) {
+	bootChainsFile := filepath.Join(s.rootDir, "boot-chains")
+	pbc := boot.ToPredictableBootChains([]boot.BootChain{
+		s.mockBootChain("1234567890abcdef", "1", "snapd_recovery_mode=run"),
+	})
+	c.Assert(boot.WriteBootChains(pbc, bootChainsFile, 3), IsNil)
+	s.mockCachedAsset(c, "shim", "5ac9a5e5a2ba4b6d0c4e0aa82b1e0b2c")
+	s.mockCachedAsset(c, "loader", "1234567890abcdef")
+
+	obj, err := boot.ResealObjectDryRun("run", pbc, bootChainsFile, mockRoleToBlName)
+	c.Assert(err, IsNil)
+	c.Check(obj, DeepEquals, &boot.ResealDryRunObject{
+		Name:           "run",
+		BootChainsFile: bootChainsFile,
+		ResealCount:    3,
+		Profile: []*boot.ResealProfileModel{{
+			Model:          "mybrand/foo",
+			Grade:          "signed",
+			KernelCmdlines: []string{"snapd_recovery_mode=run"},
+			LoadChains: []string{
+				"recovery:shim@5ac9a5e5a2ba -> run-mode:loader@1234567890ab -> pc-kernel (rev 1)",
+			},
+		}},
+	})
+}
+
+func (s *resealDryRunSuite) TestResealObjectDryRunDifferences(c *C) {
+	bootChainsFile := filepath.Join(s.rootDir, "boot-chains")
+	oldPbc := boot.ToPredictableBootChains([]boot.BootChain{
+		s.mockBootChain("1234567890abcdef", "1", "snapd_recovery_mode=run"),
+	})
+	c.Assert(boot.WriteBootChains(oldPbc, bootChainsFile, 1), IsNil)
+	s.mockCachedAsset(c, "shim", "5ac9a5e5a2ba4b6d0c4e0aa82b1e0b2c")
+
+	newBc := s.mockBootChain("fedcba0987654321", "2", "snapd_recovery_mode=run console=ttyS0")
+	otherBc := s.mockBootChain("1234567890abcdef", "1", "snapd_recovery_mode=run")
+	otherBc.Model = "bar"
+	pbc := boot.ToPredictableBootChains([]boot.BootChain{newBc, otherBc})
+
+	obj, err := boot.ResealObjectDryRun("run", pbc, bootChainsFile, mockRoleToBlName)
+	c.Assert(err, IsNil)
+	c.Check(obj.ResealNeeded, Equals, true)
+	c.Check(obj.ResealCount, Equals, 1)
+	c.Check(obj.Differences, DeepEquals, []string{
+		`new boot chain mybrand/bar: recovery:shim -> run-mode:loader -> pc-kernel (rev 1)`,
+		`mybrand/foo: recovery:shim -> run-mode:loader -> pc-kernel (rev 2): boot asset run-mode:loader hashes changed from [1234567890ab] to [fedcba098765]`,
+		`mybrand/foo: recovery:shim -> run-mode:loader -> pc-kernel (rev 2): kernel changed from pc-kernel (rev 1) to pc-kernel (rev 2)`,
+		`mybrand/foo: recovery:shim -> run-mode:loader -> pc-kernel (rev 2): kernel command lines added: "snapd_recovery_mode=run console=ttyS0"`,
+		`mybrand/foo: recovery:shim -> run-mode:loader -> pc-kernel (rev 2): kernel command lines removed: "snapd_recovery_mode=run"`,
+	})
+	c.Check(obj.Problems, DeepEquals, []string{
+		"boot asset run-mode:loader@1234567890ab is missing from the boot assets cache",
+		"boot asset run-mode:loader@fedcba098765 is missing from the boot assets cache",
+	})
+	c.Check(obj.Profile, HasLen, 2)
+}
+
+func (s *resealDryRunSuite) TestResealObjectDryRunNoBootChains(c *C) {
+	pbc := boot.ToPredictableBootChains([]boot.BootChain{
+		s.mockBootChain("1234567890abcdef", "", "snapd_recovery_mode=run"),
+	})
+
+	obj, err := boot.ResealObjectDryRun("fallback", pbc, filepath.Join(s.rootDir, "missing"), mockRoleToBlName)
+	c.Assert(err, IsNil)
+	c.Check(obj.ResealNeeded, Equals, true)
+	c.Check(obj.Differences, DeepEquals, []string{"no boot chains were recorded for the sealed keys"})
+	c.Check(obj.Profile[0].LoadChains, DeepEquals, []string{
+		"recovery:shim@5ac9a5e5a2ba -> run-mode:loader@1234567890ab -> pc-kernel (unasserted)",
+	})
+}
+
+func (s *resealDryRunSuite) TestResealObjectDryRunUnrevisioned(c *C) {
+	bootChainsFile := filepath.Join(s.rootDir, "boot-chains")
+	pbc := boot.ToPredictableBootChains([]boot.BootChain{
+		s.mockBootChain("1234567890abcdef", "", "snapd_recovery_mode=run"),
+	})
+	c.Assert(boot.WriteBootChains(pbc, bootChainsFile, 1), IsNil)
+
+	obj, err := boot.ResealObjectDryRun("run", pbc, bootChainsFile, mockRoleToBlName)
+	c.Assert(err, IsNil)
+	c.Check(obj.ResealNeeded, Equals, true)
+	c.Check(obj.Differences, DeepEquals, []string{"boot chains use unasserted kernels, they cannot be compared reliably"})
+}
@@ -119,6 +119,9 @@ type sealKeyToModeenvFlags struct {
 	// SnapsDir is set to provide a non-default directory to find
 	// run mode snaps in.
 	SnapsDir string
+	// UsePassphrase is true if the encrypted partitions are unlocked with
+	// a user passphrase instead of sealed keys
+	UsePassphrase bool
 }
 
 // sealKeyToModeenvImpl seals the supplied keys to the parameters specified
@@ -138,6 +141,10 @@ func sealKeyToModeenvImpl(key, saveKey keys.EncryptionKey, model *asserts.Model,
 		}
 	}
 
+	if flags.UsePassphrase {
+		return sealKeyToModeenvUsingPassphrase(model)
+	}
+
 	if flags.HasFDESetupHook {
 		return sealKeyToModeenvUsingFDESetupHook(key, saveKey, model, modeenv, flags)
 	}
@@ -207,6 +214,18 @@ func sealKeyToModeenvUsingFDESetupHook(key, saveKey keys.EncryptionKey, model *a
 	return nil
 }
 
+// sealKeyToModeenvUsingPassphrase does not seal any keys, but leaves markers
+// for the initramfs to know that the user must be asked for the passphrase
+// to unlock the encrypted partitions.
+func sealKeyToModeenvUsingPassphrase(model *asserts.Model) error {
+	for _, dir := range []string{InitramfsBootEncryptionKeyDir, InitramfsSeedEncryptionKeyDir} {
+		if err := device.WritePassphraseMarker(dir); err != nil {
+			return fmt.Errorf("cannot write passphrase marker: %v", err)
+		}
+	}
+	return device.StampSealedKeys(InstallHostWritableDir(model), device.SealingMethodPassphrase)
+}
+
 func sealKeyToModeenvUsingSecboot(key, saveKey keys.EncryptionKey, model *asserts.Model, modeenv *Modeenv, flags sealKeyToModeenvFlags) error {
 	// build the recovery mode boot chain
 	rbl, err := bootloader.Find(InitramfsUbuntuSeedDir, &bootloader.Options{
@@ -422,6 +441,9 @@ func resealKeyToModeenvImpl(rootdir string, modeenv *Modeenv, expectReseal bool)
 		return resealKeyToModeenvUsingFDESetupHook(rootdir, modeenv, expectReseal)
 	case device.SealingMethodTPM, device.SealingMethodLegacyTPM:
 		return resealKeyToModeenvSecboot(rootdir, modeenv, expectReseal)
+	case device.SealingMethodPassphrase:
+		// no keys are sealed
+		return nil
 	default:
 		return fmt.Errorf("unknown key sealing method: %q", method)
 	}
@@ -452,17 +474,75 @@ func resealKeyToModeenvUsingFDESetupHookImpl(rootdir string, modeenv *Modeenv, e
 
 // TODO:UC20: allow more than one model to accommodate the remodel scenario
 func resealKeyToModeenvSecboot(rootdir string, modeenv *Modeenv, expectReseal bool) error {
+	pbc, rpbc, roleToBlName, err := bootChainsForReseal(modeenv)
+	if err != nil {
+		return err
+	}
+	saveFDEDir := dirs.SnapFDEDirUnderSave(dirs.SnapSaveDirUnder(rootdir))
+	authKeyFile := filepath.Join(saveFDEDir, "tpm-policy-auth-key")
+
+	// reseal the run object
+	needed, nextCount, err := isResealNeeded(pbc, bootChainsFileUnder(rootdir), expectReseal)
+	if err != nil {
+		return err
+	}
+	if needed {
+		pbcJSON, _ := json.Marshal(pbc)
+		logger.Debugf("resealing (%d) to boot chains: %s", nextCount, pbcJSON)
+
+		if err := resealRunObjectKeys(pbc, authKeyFile, roleToBlName); err != nil {
+			return err
+		}
+		logger.Debugf("resealing (%d) succeeded", nextCount)
+
+		bootChainsPath := bootChainsFileUnder(rootdir)
+		if err := writeBootChains(pbc, bootChainsPath, nextCount); err != nil {
+			return err
+		}
+	} else {
+		logger.Debugf("reseal not necessary")
+	}
+
+	// reseal the fallback object
+	var nextFallbackCount int
+	needed, nextFallbackCount, err = isResealNeeded(rpbc, recoveryBootChainsFileUnder(rootdir), expectReseal)
+	if err != nil {
+		return err
+	}
+	if needed {
+		rpbcJSON, _ := json.Marshal(rpbc)
+		logger.Debugf("resealing (%d) to recovery boot chains: %s", nextFallbackCount, rpbcJSON)
+
+		if err := resealFallbackObjectKeys(rpbc, authKeyFile, roleToBlName); err != nil {
+			return err
+		}
+		logger.Debugf("fallback resealing (%d) succeeded", nextFallbackCount)
+
+		recoveryBootChainsPath := recoveryBootChainsFileUnder(rootdir)
+		if err := writeBootChains(rpbc, recoveryBootChainsPath, nextFallbackCount); err != nil {
+			return err
+		}
+	} else {
+		logger.Debugf("fallback reseal not necessary")
+	}
+
+	return nil
+}
+
+// bootChainsForReseal composes the boot chains that the run and the fallback
+// objects are sealed to, as derived from the modeenv.
+func bootChainsForReseal(modeenv *Modeenv) (pbc, rpbc predictableBootChains, roleToBlName map[bootloader.Role]string, err error) {
 	// build the recovery mode boot chain
 	rbl, err := bootloader.Find(InitramfsUbuntuSeedDir, &bootloader.Options{
 		Role: bootloader.RoleRecovery,
 	})
 	if err != nil {
-		return fmt.Errorf("cannot find the recovery bootloader: %v", err)
+		return nil, nil, nil, fmt.Errorf("cannot find the recovery bootloader: %v", err)
 	}
 	tbl, ok := rbl.(bootloader.TrustedAssetsBootloader)
 	if !ok {
 		// TODO:UC20: later the exact kind of bootloaders we expect here might change
-		return fmt.Errorf("internal error: sealed keys but not a trusted assets bootloader")
+		return nil, nil, nil, fmt.Errorf("internal error: sealed keys but not a trusted assets bootloader")
 	}
 	// derive the allowed modes for each system mentioned in the modeenv
 	modes := modesForSystems(modeenv)
@@ -475,7 +555,7 @@ func resealKeyToModeenvSecboot(rootdir string, modeenv *Modeenv, expectReseal bo
 	recoveryBootChainsForRunKey, err := recoveryBootChainsForSystems(modeenv.CurrentRecoverySystems, modes, tbl,
 		modeenv, includeTryModel)
 	if err != nil {
-		return fmt.Errorf("cannot compose recovery boot chains for run key: %v", err)
+		return nil, nil, nil, fmt.Errorf("cannot compose recovery boot chains for run key: %v", err)
 	}
 
 	// the boot chains for recovery keys include only those system that were
@@ -493,7 +573,7 @@ func resealKeyToModeenvSecboot(rootdir string, modeenv *Modeenv, expectReseal bo
 	includeTryModel = false
 	recoveryBootChains, err := recoveryBootChainsForSystems(testedRecoverySystems, modes, tbl, modeenv, includeTryModel)
 	if err != nil {
-		return fmt.Errorf("cannot compose recovery boot chains: %v", err)
+		return nil, nil, nil, fmt.Errorf("cannot compose recovery boot chains: %v", err)
 	}
 
 	// build the run mode boot chains
@@ -502,74 +582,24 @@ func resealKeyToModeenvSecboot(rootdir string, modeenv *Modeenv, expectReseal bo
 		NoSlashBoot: true,
 	})
 	if err != nil {
-		return fmt.Errorf("cannot find the bootloader: %v", err)
+		return nil, nil, nil, fmt.Errorf("cannot find the bootloader: %v", err)
 	}
 	cmdlines, err := kernelCommandLinesForResealWithFallback(modeenv)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 	runModeBootChains, err := runModeBootChains(rbl, bl, modeenv, cmdlines, "")
 	if err != nil {
-		return fmt.Errorf("cannot compose run mode boot chains: %v", err)
+		return nil, nil, nil, fmt.Errorf("cannot compose run mode boot chains: %v", err)
 	}
 
-	roleToBlName := map[bootloader.Role]string{
+	roleToBlName = map[bootloader.Role]string{
 		bootloader.RoleRecovery: rbl.Name(),
 		bootloader.RoleRunMode:  bl.Name(),
 	}
-	saveFDEDir := dirs.SnapFDEDirUnderSave(dirs.SnapSaveDirUnder(rootdir))
-	authKeyFile := filepath.Join(saveFDEDir, "tpm-policy-auth-key")
-
-	// reseal the run object
-	pbc := toPredictableBootChains(append(runModeBootChains, recoveryBootChainsForRunKey...))
-
-	needed, nextCount, err := isResealNeeded(pbc, bootChainsFileUnder(rootdir), expectReseal)
-	if err != nil {
-		return err
-	}
-	if needed {
-		pbcJSON, _ := json.Marshal(pbc)
-		logger.Debugf("resealing (%d) to boot chains: %s", nextCount, pbcJSON)
-
-		if err := resealRunObjectKeys(pbc, authKeyFile, roleToBlName); err != nil {
-			return err
-		}
-		logger.Debugf("resealing (%d) succeeded", nextCount)
-
-		bootChainsPath := bootChainsFileUnder(rootdir)
-		if err := writeBootChains(pbc, bootChainsPath, nextCount); err != nil {
-			return err
-		}
-	} else {
-		logger.Debugf("reseal not necessary")
-	}
-
-	// reseal the fallback object
-	rpbc := toPredictableBootChains(recoveryBootChains)
-
-	var nextFallbackCount int
-	needed, nextFallbackCount, err = isResealNeeded(rpbc, recoveryBootChainsFileUnder(rootdir), expectReseal)
-	if err != nil {
-		return err
-	}
-	if needed {
-		rpbcJSON, _ := json.Marshal(rpbc)
-		logger.Debugf("resealing (%d) to recovery boot chains: %s", nextFallbackCount, rpbcJSON)
-
-		if err := resealFallbackObjectKeys(rpbc, authKeyFile, roleToBlName); err != nil {
-			return err
-		}
-		logger.Debugf("fallback resealing (%d) succeeded", nextFallbackCount)
-
-		recoveryBootChainsPath := recoveryBootChainsFileUnder(rootdir)
-		if err := writeBootChains(rpbc, recoveryBootChainsPath, nextFallbackCount); err != nil {
-			return err
-		}
-	} else {
-		logger.Debugf("fallback reseal not necessary")
-	}
-
-	return nil
+	pbc = toPredictableBootChains(append(runModeBootChains, recoveryBootChainsForRunKey...))
+	rpbc = toPredictableBootChains(recoveryBootChains)
+	return pbc, rpbc, roleToBlName, nil
 }
 
 func resealRunObjectKeys(pbc predictableBootChains, authKeyFile string, roleToBlName map[bootloader.Role]string) error {
@@ -37,6 +37,7 @@ import (
 	"github.com/snapcore/snapd/bootloader/assets"
 	"github.com/snapcore/snapd/bootloader/bootloadertest"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/device"
 	"github.com/snapcore/snapd/kernel/fde"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/secboot"
@@ -1688,6 +1689,40 @@ func (s *sealSuite) TestSealToModeenvWithFdeHookHappy(c *C) {
 	c.Check(marker, testutil.FileEquals, "fde-setup-hook")
 }
 
+func (s *sealSuite) TestSealToModeenvWithPassphraseHappy(c *C) {
+	rootdir := c.MkDir()
+	dirs.SetRootDir(rootdir)
+	defer dirs.SetRootDir("")
+	model := boottest.MakeMockUC20Model()
+
+	restore := boot.MockSecbootSealKeys(func(keys []secboot.SealKeyRequest, params *secboot.SealKeysParams) error {
+		c.Fatalf("unexpected call")
+		return nil
+	})
+	defer restore()
+
+	modeenv := &boot.Modeenv{
+		RecoverySystem: "20200825",
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	key := keys.EncryptionKey{1, 2, 3, 4}
+	saveKey := keys.EncryptionKey{5, 6, 7, 8}
+
+	err := boot.SealKeyToModeenv(key, saveKey, model, modeenv, boot.MockSealKeyToModeenvFlags{UsePassphrase: true})
+	c.Assert(err, IsNil)
+
+	// nothing is sealed, the initramfs is told to ask for a passphrase
+	c.Check(filepath.Join(boot.InitramfsBootEncryptionKeyDir, "ubuntu-data.sealed-key"), testutil.FileAbsent)
+	c.Check(device.HasPassphraseMarkerUnder(boot.InitramfsBootEncryptionKeyDir), Equals, true)
+	c.Check(device.HasPassphraseMarkerUnder(boot.InitramfsSeedEncryptionKeyDir), Equals, true)
+
+	marker := filepath.Join(dirs.SnapFDEDirUnder(filepath.Join(dirs.GlobalRootDir, "/run/mnt/ubuntu-data/system-data")), "sealed-keys")
+	c.Check(marker, testutil.FileEquals, "passphrase")
+}
+
 func (s *sealSuite) TestSealToModeenvWithFdeHookSad(c *C) {
 	rootdir := c.MkDir()
 	dirs.SetRootDir(rootdir)
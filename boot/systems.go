@@ -497,3 +497,34 @@ func MarkRecoveryCapableSystem(systemLabel string) error {
 		"snapd_good_recovery_systems": systemsForEnv,
 	})
 }
+
+// UnmarkRecoveryCapableSystem removes a given system from the list of systems
+// that we can recover from.
+func UnmarkRecoveryCapableSystem(systemLabel string) error {
+	opts := &bootloader.Options{
+		// setup the recovery bootloader
+		Role: bootloader.RoleRecovery,
+	}
+	bl, err := bootloader.Find(InitramfsUbuntuSeedDir, opts)
+	if err != nil {
+		return err
+	}
+	rbl, ok := bl.(bootloader.RecoveryAwareBootloader)
+	if !ok {
+		return nil
+	}
+	vars, err := rbl.GetBootVars("snapd_good_recovery_systems")
+	if err != nil {
+		return err
+	}
+	if vars["snapd_good_recovery_systems"] == "" {
+		return nil
+	}
+	systems, found := dropFromRecoverySystemsList(strings.Split(vars["snapd_good_recovery_systems"], ","), systemLabel)
+	if !found {
+		return nil
+	}
+	return rbl.SetBootVars(map[string]string{
+		"snapd_good_recovery_systems": strings.Join(systems, ","),
+	})
+}
@@ -1885,6 +1885,54 @@ func (s *systemsSuite) TestMarkRecoveryCapableSystemNonRecoveryAware(c *C) {
 	c.Check(bl.SetBootVarsCalls, Equals, 0)
 }
 
+func (s *systemsSuite) TestUnmarkRecoveryCapableSystemHappy(c *C) {
+	rbl := bootloadertest.Mock("recovery", c.MkDir()).RecoveryAware()
+	bootloader.Force(rbl)
+
+	// nothing to drop from
+	err := boot.UnmarkRecoveryCapableSystem("1234")
+	c.Assert(err, IsNil)
+	c.Check(rbl.SetBootVarsCalls, Equals, 0)
+
+	err = rbl.SetBootVars(map[string]string{
+		"snapd_good_recovery_systems": "1234,4567,9999",
+	})
+	c.Assert(err, IsNil)
+
+	err = boot.UnmarkRecoveryCapableSystem("4567")
+	c.Assert(err, IsNil)
+	vars, err := rbl.GetBootVars("snapd_good_recovery_systems")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"snapd_good_recovery_systems": "1234,9999",
+	})
+
+	// unknown system is a nop
+	rbl.SetBootVarsCalls = 0
+	err = boot.UnmarkRecoveryCapableSystem("4567")
+	c.Assert(err, IsNil)
+	c.Check(rbl.SetBootVarsCalls, Equals, 0)
+
+	err = boot.UnmarkRecoveryCapableSystem("1234")
+	c.Assert(err, IsNil)
+	err = boot.UnmarkRecoveryCapableSystem("9999")
+	c.Assert(err, IsNil)
+	vars, err = rbl.GetBootVars("snapd_good_recovery_systems")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"snapd_good_recovery_systems": "",
+	})
+}
+
+func (s *systemsSuite) TestUnmarkRecoveryCapableSystemNonRecoveryAware(c *C) {
+	bl := bootloadertest.Mock("recovery", c.MkDir())
+	bootloader.Force(bl)
+
+	err := boot.UnmarkRecoveryCapableSystem("1234")
+	c.Assert(err, IsNil)
+	c.Check(bl.SetBootVarsCalls, Equals, 0)
+}
+
 type initramfsMarkTryRecoverySystemSuite struct {
 	baseSystemsSuite
 
@@ -23,6 +23,8 @@ var (
 	RegisterInternal           = registerInternal
 	RegisterSnippetForEditions = registerSnippetForEditions
 	RegisterGrubSnippets       = registerGrubSnippets
+
+	RegisterSystemdBootSnippets = registerSystemdBootSnippets
 )
 
 func MockCleanState() (restore func()) {
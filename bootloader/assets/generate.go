@@ -21,3 +21,4 @@ package assets
 
 //go:generate go run $GOINVOKEFLAGS ./genasset/main.go -name grub.cfg -in ./data/grub.cfg -out ./grub_cfg_asset.go
 //go:generate go run $GOINVOKEFLAGS ./genasset/main.go -name grub-recovery.cfg -in ./data/grub-recovery.cfg -out ./grub_recovery_cfg_asset.go
+//go:generate go run $GOINVOKEFLAGS ./genasset/main.go -name systemd-boot-loader.conf -in ./data/systemd-boot-loader.conf -out ./systemd_boot_loader_conf_asset.go
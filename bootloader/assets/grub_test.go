@@ -69,7 +69,7 @@ func (s *grubAssetsTestSuite) testGrubConfigContains(c *C, name string, edition
 }
 
 func (s *grubAssetsTestSuite) TestGrubConf(c *C) {
-	s.testGrubConfigContains(c, "grub.cfg", 3,
+	s.testGrubConfigContains(c, "grub.cfg", 4,
 		"snapd_recovery_mode",
 		"set snapd_static_cmdline_args='console=ttyS0,115200n8 console=tty1 panic=-1'",
 	)
@@ -128,7 +128,7 @@ func (s *grubAssetsTestSuite) TestGrubCmdlineSnippetCrossCheck(c *C) {
 		pattern string
 	}{
 		{
-			asset: "grub.cfg", snippet: "grub.cfg:static-cmdline", edition: 3,
+			asset: "grub.cfg", snippet: "grub.cfg:static-cmdline", edition: 4,
 			content: []byte("console=ttyS0,115200n8 console=tty1 panic=-1"),
 			pattern: "set snapd_static_cmdline_args='%s'\n",
 		},
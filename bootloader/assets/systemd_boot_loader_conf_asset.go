@@ -0,0 +1,43 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2022 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assets
+
+// Code generated from ./data/systemd-boot-loader.conf DO NOT EDIT
+
+func init() {
+	registerInternal("systemd-boot-loader.conf", []byte{
+		0x23, 0x20, 0x53, 0x6e, 0x61, 0x70, 0x64, 0x2d, 0x42, 0x6f, 0x6f, 0x74, 0x2d, 0x43, 0x6f, 0x6e,
+		0x66, 0x69, 0x67, 0x2d, 0x45, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x3a, 0x20, 0x31, 0x0a, 0x23,
+		0x0a, 0x23, 0x20, 0x73, 0x79, 0x73, 0x74, 0x65, 0x6d, 0x64, 0x2d, 0x62, 0x6f, 0x6f, 0x74, 0x20,
+		0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x20, 0x6d, 0x61,
+		0x6e, 0x61, 0x67, 0x65, 0x64, 0x20, 0x62, 0x79, 0x20, 0x73, 0x6e, 0x61, 0x70, 0x64, 0x2c, 0x20,
+		0x74, 0x68, 0x65, 0x20, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x20, 0x65, 0x6e, 0x74, 0x72,
+		0x79, 0x20, 0x62, 0x6f, 0x6f, 0x74, 0x73, 0x0a, 0x23, 0x20, 0x74, 0x68, 0x65, 0x20, 0x63, 0x75,
+		0x72, 0x72, 0x65, 0x6e, 0x74, 0x20, 0x6b, 0x65, 0x72, 0x6e, 0x65, 0x6c, 0x20, 0x77, 0x68, 0x69,
+		0x6c, 0x65, 0x20, 0x61, 0x20, 0x6b, 0x65, 0x72, 0x6e, 0x65, 0x6c, 0x20, 0x62, 0x65, 0x69, 0x6e,
+		0x67, 0x20, 0x74, 0x72, 0x69, 0x65, 0x64, 0x20, 0x69, 0x73, 0x20, 0x62, 0x6f, 0x6f, 0x74, 0x65,
+		0x64, 0x20, 0x6f, 0x6e, 0x63, 0x65, 0x20, 0x77, 0x69, 0x74, 0x68, 0x0a, 0x23, 0x20, 0x74, 0x68,
+		0x65, 0x20, 0x4c, 0x6f, 0x61, 0x64, 0x65, 0x72, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x4f, 0x6e, 0x65,
+		0x53, 0x68, 0x6f, 0x74, 0x20, 0x45, 0x46, 0x49, 0x20, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c,
+		0x65, 0x0a, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x20, 0x73, 0x6e, 0x61, 0x70, 0x64, 0x2d,
+		0x72, 0x75, 0x6e, 0x2e, 0x63, 0x6f, 0x6e, 0x66, 0x0a, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74,
+		0x20, 0x33, 0x0a, 0x65, 0x64, 0x69, 0x74, 0x6f, 0x72, 0x20, 0x6e, 0x6f, 0x0a,
+	})
+}
@@ -0,0 +1,68 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assets_test
+
+import (
+	"bytes"
+	"io/ioutil"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/arch"
+	"github.com/snapcore/snapd/arch/archtest"
+	"github.com/snapcore/snapd/bootloader/assets"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type systemdBootAssetsTestSuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&systemdBootAssetsTestSuite{})
+
+func (s *systemdBootAssetsTestSuite) TestLoaderConf(c *C) {
+	a := assets.Internal("systemd-boot-loader.conf")
+	c.Assert(a, NotNil)
+	c.Check(bytes.HasPrefix(a, []byte("# Snapd-Boot-Config-Edition: 1\n")), Equals, true)
+	c.Check(string(a), testutil.Contains, "\ndefault snapd-run.conf\n")
+
+	data, err := ioutil.ReadFile("data/systemd-boot-loader.conf")
+	c.Assert(err, IsNil)
+	c.Check(a, DeepEquals, data, Commentf("asset has not been updated"))
+}
+
+func (s *systemdBootAssetsTestSuite) TestStaticCmdlineForArch(c *C) {
+	for _, t := range []struct {
+		arch    string
+		cmdline string
+	}{
+		{"amd64", "console=ttyS0 console=tty1 panic=-1"},
+		{"arm64", "panic=-1"},
+	} {
+		r := archtest.MockArchitecture(arch.ArchitectureType(t.arch))
+		defer r()
+		// make sure to revert later to the prev arch snippets
+		r = assets.MockCleanState()
+		defer r()
+		assets.RegisterSystemdBootSnippets()
+		snip := assets.SnippetForEdition("systemd-boot-loader.conf:static-cmdline", 1)
+		c.Check(string(snip), Equals, t.cmdline)
+	}
+}
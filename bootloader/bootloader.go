@@ -247,6 +247,17 @@ type RebootBootloader interface {
 	GetRebootArguments() (string, error)
 }
 
+// BootCountingBootloader can make several attempts at booting a try kernel,
+// counting them down in the kernel_tries_left boot variable, before it
+// reverts to the previous kernel.
+type BootCountingBootloader interface {
+	Bootloader
+
+	// SupportsKernelBootTries returns whether the boot config in use
+	// counts the attempts at booting a try kernel.
+	SupportsKernelBootTries() (bool, error)
+}
+
 func genericInstallBootConfig(gadgetFile, systemFile string) error {
 	if err := os.MkdirAll(filepath.Dir(systemFile), 0755); err != nil {
 		return err
@@ -316,6 +327,7 @@ var (
 		newAndroidBoot,
 		newLk,
 		newPiboot,
+		newSystemdBoot,
 	}
 )
 
@@ -317,6 +317,13 @@ func (s *bootenvTestSuite) TestBootloaderFind(c *C) {
 			name: "lk", sysFile: "/boot/lk/snapbootsel.bin",
 			expName: "lk", opts: &bootloader.Options{PrepareImageTime: true},
 		},
+		{name: "systemd-boot", sysFile: "/boot/efi/loader/loader.conf", expName: "systemd-boot"},
+		{
+			// native run partition layout
+			name: "systemd-boot", sysFile: "/loader/loader.conf",
+			opts:    &bootloader.Options{Role: bootloader.RoleRunMode, NoSlashBoot: true},
+			expName: "systemd-boot",
+		},
 	} {
 		c.Logf("tc: %v", tc.name)
 		rootDir := c.MkDir()
@@ -343,6 +350,7 @@ func (s *bootenvTestSuite) TestBootloaderForGadget(c *C) {
 		{name: "grub", gadgetFile: "grub.conf", opts: &bootloader.Options{Role: bootloader.RoleRecovery}, expName: "grub"},
 		{name: "uboot", gadgetFile: "uboot.conf", expName: "uboot"},
 		{name: "androidboot", gadgetFile: "androidboot.conf", expName: "androidboot"},
+		{name: "systemd-boot", gadgetFile: "systemd-boot.conf", opts: &bootloader.Options{Role: bootloader.RoleRunMode, NoSlashBoot: true}, expName: "systemd-boot"},
 		{name: "lk", gadgetFile: "lk.conf", expName: "lk"},
 	} {
 		c.Logf("tc: %v", tc.name)
@@ -597,3 +597,23 @@ func (b *MockBootloader) WithRebootBootloader() *MockRebootBootloader {
 		MockBootloader: b,
 	}
 }
+
+// MockBootCountingBootloader mocks a bootloader implementing the
+// bootloader.BootCountingBootloader interface.
+type MockBootCountingBootloader struct {
+	*MockBootloader
+
+	KernelBootTriesSupported bool
+	KernelBootTriesErr       error
+}
+
+func (b *MockBootCountingBootloader) SupportsKernelBootTries() (bool, error) {
+	return b.KernelBootTriesSupported, b.KernelBootTriesErr
+}
+
+func (b *MockBootloader) WithBootCounting() *MockBootCountingBootloader {
+	return &MockBootCountingBootloader{
+		MockBootloader:           b,
+		KernelBootTriesSupported: true,
+	}
+}
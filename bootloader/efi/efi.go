@@ -17,7 +17,7 @@
  *
  */
 
-// Package efi supports reading EFI variables.
+// Package efi supports reading and writing EFI variables.
 package efi
 
 import (
@@ -47,15 +47,19 @@ const (
 )
 
 var (
-	openEFIVar = openEFIVarImpl
+	openEFIVar   = openEFIVarImpl
+	writeEFIVar  = writeEFIVarImpl
+	deleteEFIVar = deleteEFIVarImpl
 )
 
 const expectedEFIvarfsDir = "/sys/firmware/efi/efivars"
 
-func openEFIVarImpl(name string) (r io.ReadCloser, attr VariableAttr, size int64, err error) {
+// efiVarPath returns the path of the given EFI variable in efivarfs,
+// or ErrNoEFISystem if efivarfs is not mounted.
+func efiVarPath(name string) (string, error) {
 	mounts, err := osutil.LoadMountInfo()
 	if err != nil {
-		return nil, 0, 0, err
+		return "", err
 	}
 	found := false
 	for _, mnt := range mounts {
@@ -67,9 +71,17 @@ func openEFIVarImpl(name string) (r io.ReadCloser, attr VariableAttr, size int64
 		}
 	}
 	if !found {
-		return nil, 0, 0, ErrNoEFISystem
+		return "", ErrNoEFISystem
+	}
+	return filepath.Join(dirs.GlobalRootDir, expectedEFIvarfsDir, name), nil
+}
+
+func openEFIVarImpl(name string) (r io.ReadCloser, attr VariableAttr, size int64, err error) {
+	p, err := efiVarPath(name)
+	if err != nil {
+		return nil, 0, 0, err
 	}
-	varf, err := os.Open(filepath.Join(dirs.GlobalRootDir, expectedEFIvarfsDir, name))
+	varf, err := os.Open(p)
 	if err != nil {
 		return nil, 0, 0, err
 	}
@@ -159,11 +171,127 @@ func ReadVarString(name string) (string, VariableAttr, error) {
 	return b.String(), attr, nil
 }
 
+// clearImmutable drops the immutable flag that efivarfs sets on most
+// variables, errors are ignored as the subsequent write or removal
+// reports any real problem.
+func clearImmutable(p string) {
+	f, err := os.Open(p)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	attr, err := osutil.GetAttr(f)
+	if err != nil || attr&osutil.FS_IMMUTABLE_FL == 0 {
+		return
+	}
+	osutil.SetAttr(f, attr&^osutil.FS_IMMUTABLE_FL)
+}
+
+func writeEFIVarImpl(name string, attr VariableAttr, data []byte) error {
+	p, err := efiVarPath(name)
+	if err != nil {
+		return err
+	}
+	clearImmutable(p)
+	// efivarfs requires the attribute and the value to be written
+	// with a single write call
+	buf := make([]byte, 4+len(data))
+	binary.LittleEndian.PutUint32(buf, uint32(attr))
+	copy(buf[4:], data)
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func deleteEFIVarImpl(name string) error {
+	p, err := efiVarPath(name)
+	if err != nil {
+		return err
+	}
+	clearImmutable(p)
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func cannotWriteError(name string, err error) error {
+	return fmt.Errorf("cannot write EFI var %q: %v", name, err)
+}
+
+// WriteVarBytes will attempt to set the value of the specified EFI
+// variable, specified by its full name composed of the variable name
+// and vendor ID, together with the given attributes. It expects to
+// use the efivars filesystem at /sys/firmware/efi/efivars.
+func WriteVarBytes(name string, attr VariableAttr, value []byte) error {
+	if err := writeEFIVar(name, attr, value); err != nil {
+		if err == ErrNoEFISystem {
+			return err
+		}
+		return cannotWriteError(name, err)
+	}
+	return nil
+}
+
+// WriteVarString will attempt to set the specified EFI variable to
+// the given string value, encoded as a NUL terminated UTF16 string as
+// expected by the firmware and boot loaders.
+func WriteVarString(name string, attr VariableAttr, value string) error {
+	r16 := utf16.Encode([]rune(value + "\x00"))
+	b := make([]byte, 2*len(r16))
+	for i, r := range r16 {
+		binary.LittleEndian.PutUint16(b[2*i:], r)
+	}
+	return WriteVarBytes(name, attr, b)
+}
+
+// DeleteVar will attempt to remove the specified EFI variable. It is
+// not an error if the variable does not exist.
+func DeleteVar(name string) error {
+	if err := deleteEFIVar(name); err != nil {
+		if err == ErrNoEFISystem {
+			return err
+		}
+		return fmt.Errorf("cannot delete EFI var %q: %v", name, err)
+	}
+	return nil
+}
+
 // MockVars mocks EFI variables as read by ReadVar*, only to be used
-// from tests. Set vars to nil to mock a non-EFI system.
+// from tests. Set vars to nil to mock a non-EFI system. Variables
+// written or deleted with WriteVar* and DeleteVar are updated in the
+// vars map, and in attrs if it is not nil.
 func MockVars(vars map[string][]byte, attrs map[string]VariableAttr) (restore func()) {
 	osutil.MustBeTestBinary("MockVars only to be used from tests")
 	old := openEFIVar
+	oldWrite := writeEFIVar
+	oldDelete := deleteEFIVar
+	writeEFIVar = func(name string, attr VariableAttr, data []byte) error {
+		if vars == nil {
+			return ErrNoEFISystem
+		}
+		vars[name] = data
+		if attrs != nil {
+			attrs[name] = attr
+		}
+		return nil
+	}
+	deleteEFIVar = func(name string) error {
+		if vars == nil {
+			return ErrNoEFISystem
+		}
+		delete(vars, name)
+		if attrs != nil {
+			delete(attrs, name)
+		}
+		return nil
+	}
 	openEFIVar = func(name string) (io.ReadCloser, VariableAttr, int64, error) {
 		if vars == nil {
 			return nil, 0, 0, ErrNoEFISystem
@@ -180,5 +308,7 @@ func MockVars(vars map[string][]byte, attrs map[string]VariableAttr) (restore fu
 
 	return func() {
 		openEFIVar = old
+		writeEFIVar = oldWrite
+		deleteEFIVar = oldDelete
 	}
 }
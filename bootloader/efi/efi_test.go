@@ -172,3 +172,66 @@ func (s *efiVarsSuite) TestStringOddSize(c *C) {
 	_, _, err := efi.ReadVarString("a")
 	c.Check(err, ErrorMatches, `EFI var "a" is not a valid UTF16 string, it has an extra byte`)
 }
+
+func (s *efiVarsSuite) TestWriteVarString(c *C) {
+	err := efi.WriteVarString("my-cool-efi-var", efi.VariableNonVolatile|efi.VariableBootServiceAccess|efi.VariableRuntimeAccess, "foo")
+	c.Assert(err, IsNil)
+
+	varPath := filepath.Join(s.rootdir, "/sys/firmware/efi/efivars", "my-cool-efi-var")
+	c.Check(varPath, testutil.FileEquals, "\x07\x00\x00\x00f\x00o\x00o\x00\x00\x00")
+
+	v, attr, err := efi.ReadVarString("my-cool-efi-var")
+	c.Assert(err, IsNil)
+	c.Check(attr, Equals, efi.VariableNonVolatile|efi.VariableBootServiceAccess|efi.VariableRuntimeAccess)
+	c.Check(v, Equals, "foo")
+
+	// overwriting works too
+	err = efi.WriteVarBytes("my-cool-efi-var", efi.VariableRuntimeAccess, []byte("\x01"))
+	c.Assert(err, IsNil)
+	c.Check(varPath, testutil.FileEquals, "\x04\x00\x00\x00\x01")
+}
+
+func (s *efiVarsSuite) TestDeleteVar(c *C) {
+	varPath := filepath.Join(s.rootdir, "/sys/firmware/efi/efivars", "my-cool-efi-var")
+	err := ioutil.WriteFile(varPath, []byte("\x06\x00\x00\x00\x01"), 0644)
+	c.Assert(err, IsNil)
+
+	err = efi.DeleteVar("my-cool-efi-var")
+	c.Assert(err, IsNil)
+	c.Check(varPath, testutil.FileAbsent)
+
+	// deleting a missing variable is fine
+	err = efi.DeleteVar("my-cool-efi-var")
+	c.Assert(err, IsNil)
+}
+
+func (s *efiVarsSuite) TestWriteNoEFISystem(c *C) {
+	// no efivarfs
+	osutil.MockMountInfo("")
+
+	err := efi.WriteVarString("my-cool-efi-var", efi.VariableRuntimeAccess, "foo")
+	c.Check(err, Equals, efi.ErrNoEFISystem)
+
+	err = efi.DeleteVar("my-cool-efi-var")
+	c.Check(err, Equals, efi.ErrNoEFISystem)
+}
+
+func (s *efiVarsSuite) TestMockVarsWrite(c *C) {
+	vars := map[string][]byte{
+		"a": []byte("\x01"),
+	}
+	restore := efi.MockVars(vars, nil)
+	defer restore()
+
+	err := efi.WriteVarString("b", efi.VariableRuntimeAccess, "foo")
+	c.Assert(err, IsNil)
+	c.Check(vars["b"], DeepEquals, bootloadertest.UTF16Bytes("foo"))
+
+	v, _, err := efi.ReadVarString("b")
+	c.Assert(err, IsNil)
+	c.Check(v, Equals, "foo")
+
+	err = efi.DeleteVar("a")
+	c.Assert(err, IsNil)
+	c.Check(vars, HasLen, 1)
+}
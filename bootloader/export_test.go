@@ -75,6 +75,19 @@ func MockGrubFiles(c *C, rootdir string) {
 	c.Assert(err, IsNil)
 }
 
+func NewSystemdBoot(rootdir string, opts *Options) RecoveryAwareBootloader {
+	return newSystemdBoot(rootdir, opts).(RecoveryAwareBootloader)
+}
+
+func MockSystemdBootFiles(c *C, rootdir string, opts *Options) {
+	b := newSystemdBoot(rootdir, opts).(*systemdBoot)
+	err := os.MkdirAll(filepath.Dir(b.loaderConf()), 0755)
+	c.Assert(err, IsNil)
+	// unmanaged loader config
+	err = ioutil.WriteFile(b.loaderConf(), []byte("timeout 3\n"), 0644)
+	c.Assert(err, IsNil)
+}
+
 func NewLk(rootdir string, opts *Options) ExtractedRecoveryKernelImageBootloader {
 	if opts == nil {
 		opts = &Options{
@@ -327,3 +340,11 @@ var (
 	ConfigAssetFrom                      = configAssetFrom
 	StaticCommandLineForGrubAssetEdition = staticCommandLineForGrubAssetEdition
 )
+
+func MockBootID(f func() (string, error)) (restore func()) {
+	old := osutilBootID
+	osutilBootID = f
+	return func() {
+		osutilBootID = old
+	}
+}
@@ -353,6 +353,29 @@ func (g *grub) UpdateBootConfig() (bool, error) {
 	return genericUpdateBootConfigFromAssets(currentBootConfig, bootScriptName)
 }
 
+// grubKernelBootTriesEdition is the first edition of the managed grub.cfg
+// which counts the attempts at booting a try kernel.
+const grubKernelBootTriesEdition = 4
+
+// SupportsKernelBootTries returns whether the grub boot config is managed by
+// snapd and recent enough to count the attempts at booting a try kernel.
+//
+// Implements BootCountingBootloader for the grub bootloader.
+func (g *grub) SupportsKernelBootTries() (bool, error) {
+	if g.recovery {
+		// the recovery bootloader does not boot try kernels
+		return false, nil
+	}
+	edition, err := editionFromDiskConfigAsset(filepath.Join(g.dir(), "grub.cfg"))
+	if err != nil {
+		if err == errNoEdition {
+			return false, nil
+		}
+		return false, err
+	}
+	return edition >= grubKernelBootTriesEdition, nil
+}
+
 // ManagedAssets returns a list relative paths to boot assets inside the root
 // directory of the filesystem.
 //
@@ -745,6 +745,34 @@ func (s *grubTestSuite) testBootUpdateBootConfigUpdates(c *C, oldConfig, newConf
 	}
 }
 
+func (s *grubTestSuite) TestSupportsKernelBootTries(c *C) {
+	opts := &bootloader.Options{Role: bootloader.RoleRunMode, NoSlashBoot: true}
+	g := bootloader.NewGrub(s.rootdir, opts)
+	cbl, ok := g.(bootloader.BootCountingBootloader)
+	c.Assert(ok, Equals, true)
+
+	for _, tc := range []struct {
+		config    string
+		supported bool
+	}{
+		{"not managed\n", false},
+		{"# Snapd-Boot-Config-Edition: 3\n", false},
+		{"# Snapd-Boot-Config-Edition: 4\n", true},
+		{"# Snapd-Boot-Config-Edition: 5\n", true},
+	} {
+		s.makeFakeGrubEFINativeEnv(c, []byte(tc.config))
+		supported, err := cbl.SupportsKernelBootTries()
+		c.Assert(err, IsNil)
+		c.Check(supported, Equals, tc.supported, Commentf("config %q", tc.config))
+	}
+
+	// the recovery bootloader does not count boot attempts
+	g = bootloader.NewGrub(s.rootdir, &bootloader.Options{Role: bootloader.RoleRecovery})
+	supported, err := g.(bootloader.BootCountingBootloader).SupportsKernelBootTries()
+	c.Assert(err, IsNil)
+	c.Check(supported, Equals, false)
+}
+
 func (s *grubTestSuite) TestNoSlashBootUpdateBootConfigNoUpdateWhenNotManaged(c *C) {
 	oldConfig := `not managed`
 	newConfig := `# Snapd-Boot-Config-Edition: 3
@@ -0,0 +1,606 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package bootloader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/arch"
+	"github.com/snapcore/snapd/bootloader/efi"
+	"github.com/snapcore/snapd/bootloader/grubenv"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+)
+
+// systemdBoot implements the required interfaces
+var (
+	_ Bootloader                        = (*systemdBoot)(nil)
+	_ RecoveryAwareBootloader           = (*systemdBoot)(nil)
+	_ ExtractedRunKernelImageBootloader = (*systemdBoot)(nil)
+	_ TrustedAssetsBootloader           = (*systemdBoot)(nil)
+)
+
+const (
+	// systemdBootVendorGUID is the vendor GUID of the variables used
+	// by systemd-boot, see the Boot Loader Interface specification
+	systemdBootVendorGUID = "4a67b082-0a4c-41cf-b6c7-440b29bb8c4f"
+
+	// systemdBootKernelsDir is where the kernel images are extracted
+	// to, relative to the root of the ESP
+	systemdBootKernelsDir = "EFI/snapd"
+
+	systemdBootRunEntry = "snapd-run.conf"
+	systemdBootTryEntry = "snapd-try.conf"
+
+	// systemdBootTryBootIDVar records the boot id of the boot in which
+	// kernel_status was set to "try"
+	systemdBootTryBootIDVar = "snapd_try_boot_id"
+
+	systemdBootVarAttrs = efi.VariableNonVolatile | efi.VariableBootServiceAccess | efi.VariableRuntimeAccess
+)
+
+var osutilBootID = osutil.BootID
+
+var (
+	loaderEntryDefaultVar  = "LoaderEntryDefault-" + systemdBootVendorGUID
+	loaderEntryOneShotVar  = "LoaderEntryOneShot-" + systemdBootVendorGUID
+	loaderEntrySelectedVar = "LoaderEntrySelected-" + systemdBootVendorGUID
+)
+
+// systemdBoot is a bootloader backed by systemd-boot, booting Unified
+// Kernel Images extracted from the kernel snaps. The run and the try
+// kernels are described by loader entries, the try kernel is booted
+// once with the LoaderEntryOneShot EFI variable that systemd-boot
+// clears before booting it, so that a failed boot falls back to the
+// run kernel. As systemd-boot cannot modify the boot variables, the
+// kernel_status "try" -> "trying" transition done by the grub script is
+// performed when reading the variables after the try entry was booted,
+// as reported by the LoaderEntrySelected EFI variable. As that variable
+// is kept for the whole boot, the boot in which kernel_status was set to
+// "try" is recorded too, and only a later boot can be trying the kernel.
+type systemdBoot struct {
+	rootdir string
+
+	basedir string
+
+	uefiRunKernelExtraction bool
+	recovery                bool
+	nativePartitionLayout   bool
+	prepareImageTime        bool
+}
+
+// newSystemdBoot creates a new systemd-boot bootloader object
+func newSystemdBoot(rootdir string, opts *Options) Bootloader {
+	b := &systemdBoot{rootdir: rootdir}
+	if opts != nil {
+		b.uefiRunKernelExtraction = opts.Role == RoleRunMode
+		b.recovery = opts.Role == RoleRecovery
+		b.nativePartitionLayout = opts.NoSlashBoot || b.recovery
+		b.prepareImageTime = opts.PrepareImageTime
+	}
+	if b.nativePartitionLayout {
+		// the rootdir is the ESP
+		b.basedir = ""
+	} else {
+		b.basedir = "boot/efi"
+	}
+	return b
+}
+
+func (b *systemdBoot) Name() string {
+	return "systemd-boot"
+}
+
+func (b *systemdBoot) dir() string {
+	if b.rootdir == "" {
+		panic("internal error: unset rootdir")
+	}
+	return filepath.Join(b.rootdir, b.basedir)
+}
+
+func (b *systemdBoot) loaderConf() string {
+	return filepath.Join(b.dir(), "loader/loader.conf")
+}
+
+func (b *systemdBoot) entriesDir() string {
+	return filepath.Join(b.dir(), "loader/entries")
+}
+
+func (b *systemdBoot) InstallBootConfig(gadgetDir string, opts *Options) error {
+	if opts != nil && (opts.Role == RoleRecovery || opts.Role == RoleRunMode) {
+		// install managed loader config
+		return genericSetBootConfigFromAsset(b.loaderConf(), "systemd-boot-loader.conf")
+	}
+
+	gadgetFile := filepath.Join(gadgetDir, b.Name()+".conf")
+	return genericInstallBootConfig(gadgetFile, b.loaderConf())
+}
+
+func (b *systemdBoot) SetRecoverySystemEnv(recoverySystemDir string, values map[string]string) error {
+	if recoverySystemDir == "" {
+		return fmt.Errorf("internal error: recoverySystemDir unset")
+	}
+	recoverySystemEnv := filepath.Join(b.rootdir, recoverySystemDir, "snapd.env")
+	if err := os.MkdirAll(filepath.Dir(recoverySystemEnv), 0755); err != nil {
+		return err
+	}
+	env := grubenv.NewEnv(recoverySystemEnv)
+	for k, v := range values {
+		env.Set(k, v)
+	}
+	return env.Save()
+}
+
+func (b *systemdBoot) GetRecoverySystemEnv(recoverySystemDir string, key string) (string, error) {
+	if recoverySystemDir == "" {
+		return "", fmt.Errorf("internal error: recoverySystemDir unset")
+	}
+	recoverySystemEnv := filepath.Join(b.rootdir, recoverySystemDir, "snapd.env")
+	env := grubenv.NewEnv(recoverySystemEnv)
+	if err := env.Load(); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return env.Get(key), nil
+}
+
+func (b *systemdBoot) Present() (bool, error) {
+	return osutil.FileExists(b.loaderConf()), nil
+}
+
+func (b *systemdBoot) envFile() string {
+	return filepath.Join(b.dir(), "loader/snapd.env")
+}
+
+func (b *systemdBoot) GetBootVars(names ...string) (map[string]string, error) {
+	out := make(map[string]string)
+
+	env := grubenv.NewEnv(b.envFile())
+	if err := env.Load(); err != nil {
+		return nil, err
+	}
+
+	if env.Get("kernel_status") == "try" && b.tryEntrySelected() && rebootedSince(env.Get(systemdBootTryBootIDVar)) {
+		// the try kernel has been booted, this is the equivalent of
+		// what the grub script does
+		env.Set("kernel_status", "trying")
+		env.Set(systemdBootTryBootIDVar, "")
+		if err := env.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range names {
+		out[name] = env.Get(name)
+	}
+
+	return out, nil
+}
+
+// tryEntrySelected returns whether systemd-boot booted the try loader
+// entry in the current boot.
+func (b *systemdBoot) tryEntrySelected() bool {
+	selected, _, err := efi.ReadVarString(loaderEntrySelectedVar)
+	if err != nil {
+		// not booted with systemd-boot or with a version not
+		// supporting the variable, so the try entry was not booted
+		return false
+	}
+	return selected == systemdBootTryEntry
+}
+
+// rebootedSince returns whether the system was rebooted since the boot
+// with the given boot id.
+func rebootedSince(bootID string) bool {
+	current, err := osutilBootID()
+	if err != nil {
+		// the try entry was selected, so assume it was booted
+		logger.Noticef("cannot get boot id: %v", err)
+		return true
+	}
+	return current != bootID
+}
+
+func (b *systemdBoot) SetBootVars(values map[string]string) error {
+	env := grubenv.NewEnv(b.envFile())
+	if err := env.Load(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for k, v := range values {
+		env.Set(k, v)
+	}
+	if status, ok := values["kernel_status"]; ok {
+		// record the boot in which the try kernel was set up, so
+		// that it is not mistaken for a boot of it
+		bootID := ""
+		if status == "try" {
+			var err error
+			bootID, err = osutilBootID()
+			if err != nil {
+				logger.Noticef("cannot get boot id: %v", err)
+			}
+		}
+		env.Set(systemdBootTryBootIDVar, bootID)
+	}
+	return env.Save()
+}
+
+func (b *systemdBoot) ExtractKernelAssets(s snap.PlaceInfo, snapf snap.Container) error {
+	// extraction can be forced through either a special file in the kernel snap
+	// or through an option in the bootloader
+	_, err := snapf.ReadFile("meta/force-kernel-extraction")
+	if b.uefiRunKernelExtraction || err == nil {
+		return extractKernelAssetsToBootDir(
+			filepath.Join(b.dir(), systemdBootKernelsDir, s.Filename()),
+			snapf,
+			[]string{"kernel.efi"},
+		)
+	}
+	return nil
+}
+
+func (b *systemdBoot) RemoveKernelAssets(s snap.PlaceInfo) error {
+	return removeKernelAssetsFromBootDir(filepath.Join(b.dir(), systemdBootKernelsDir), s)
+}
+
+// ExtractedRunKernelImageBootloader helper methods
+
+func (b *systemdBoot) runModeCommandLine() (string, error) {
+	pieces := CommandLineComponents{
+		ModeArg: "snapd_recovery_mode=run",
+	}
+	env := grubenv.NewEnv(b.envFile())
+	if err := env.Load(); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	pieces.ExtraArgs = env.Get("snapd_extra_cmdline_args")
+	pieces.FullArgs = env.Get("snapd_full_cmdline_args")
+	return b.CommandLine(pieces)
+}
+
+// writeLoaderEntry writes a loader entry with the given name booting the
+// extracted kernel image of the given kernel snap.
+func (b *systemdBoot) writeLoaderEntry(s snap.PlaceInfo, name string) error {
+	kernelEfi := filepath.Join(systemdBootKernelsDir, s.Filename(), "kernel.efi")
+	// check that the kernel snap has been extracted already so we don't
+	// inadvertently create a dangling entry
+	if !osutil.FileExists(filepath.Join(b.dir(), kernelEfi)) {
+		return fmt.Errorf("cannot enable %s at %s: %v", name, kernelEfi, os.ErrNotExist)
+	}
+	cmdline, err := b.runModeCommandLine()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "title %s\n", s.Filename())
+	fmt.Fprintf(&buf, "efi /%s\n", kernelEfi)
+	fmt.Fprintf(&buf, "options %s\n", cmdline)
+
+	if err := os.MkdirAll(b.entriesDir(), 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(filepath.Join(b.entriesDir(), name), buf.Bytes(), 0644, 0)
+}
+
+func (b *systemdBoot) readLoaderEntry(name string) (snap.PlaceInfo, error) {
+	entry := filepath.Join(b.entriesDir(), name)
+	f, err := os.Open(entry)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read loader entry %s: %v", name, err)
+	}
+	defer f.Close()
+
+	var kernelEfi string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "efi" {
+			kernelEfi = fields[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read loader entry %s: %v", name, err)
+	}
+	if kernelEfi == "" {
+		return nil, fmt.Errorf("cannot find kernel image in loader entry %s", name)
+	}
+	// check that the entry does not point to a missing kernel image
+	if !osutil.FileExists(filepath.Join(b.dir(), kernelEfi)) {
+		return nil, fmt.Errorf("cannot use loader entry %s: kernel image %s does not exist", name, kernelEfi)
+	}
+
+	kernelSnapFileName := filepath.Base(filepath.Dir(kernelEfi))
+	sn, err := snap.ParsePlaceInfoFromSnapFileName(kernelSnapFileName)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"cannot parse kernel snap file name from loader entry %q: %v",
+			kernelSnapFileName,
+			err,
+		)
+	}
+	return sn, nil
+}
+
+// actual ExtractedRunKernelImageBootloader methods
+
+// EnableKernel will write the run loader entry, pointing to the
+// referenced kernel snap, and make it the default entry. EnableKernel()
+// will fail if the referenced kernel snap has not been extracted.
+func (b *systemdBoot) EnableKernel(s snap.PlaceInfo) error {
+	if err := b.writeLoaderEntry(s, systemdBootRunEntry); err != nil {
+		return err
+	}
+	// loader.conf already points to the run entry, the variable
+	// overrides any default that was set by other means
+	err := efi.WriteVarString(loaderEntryDefaultVar, systemdBootVarAttrs, systemdBootRunEntry)
+	if err != nil && err != efi.ErrNoEFISystem {
+		return err
+	}
+	return nil
+}
+
+// EnableTryKernel will write the try loader entry, pointing to the
+// referenced kernel snap, and request systemd-boot to boot it once on
+// the next boot. EnableTryKernel() will fail if the referenced kernel
+// snap has not been extracted.
+func (b *systemdBoot) EnableTryKernel(s snap.PlaceInfo) error {
+	if err := b.writeLoaderEntry(s, systemdBootTryEntry); err != nil {
+		return err
+	}
+	// without EFI variables, as when preparing an image, the try entry
+	// cannot be booted and the run kernel keeps being used
+	err := efi.WriteVarString(loaderEntryOneShotVar, systemdBootVarAttrs, systemdBootTryEntry)
+	if err != nil && err != efi.ErrNoEFISystem {
+		return err
+	}
+	return nil
+}
+
+// DisableTryKernel will remove the try loader entry and the one shot
+// boot request if they exist.
+func (b *systemdBoot) DisableTryKernel() error {
+	err := os.Remove(filepath.Join(b.entriesDir(), systemdBootTryEntry))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = efi.DeleteVar(loaderEntryOneShotVar)
+	if err != nil && err != efi.ErrNoEFISystem {
+		return err
+	}
+	return nil
+}
+
+// Kernel will return the kernel snap currently installed in the bootloader
+// partition, pointed to by the run loader entry.
+func (b *systemdBoot) Kernel() (snap.PlaceInfo, error) {
+	return b.readLoaderEntry(systemdBootRunEntry)
+}
+
+// TryKernel will return the kernel snap currently being tried if it exists
+// and ErrNoTryKernelRef if there is no try loader entry. Note if the entry
+// exists but does not point to an existing kernel image an error will be
+// returned.
+func (b *systemdBoot) TryKernel() (snap.PlaceInfo, error) {
+	if !osutil.FileExists(filepath.Join(b.entriesDir(), systemdBootTryEntry)) {
+		return nil, ErrNoTryKernelRef
+	}
+	return b.readLoaderEntry(systemdBootTryEntry)
+}
+
+// UpdateBootConfig updates the loader config only if it is already managed
+// and has a lower edition.
+//
+// Implements TrustedAssetsBootloader for the systemd-boot bootloader.
+func (b *systemdBoot) UpdateBootConfig() (bool, error) {
+	return genericUpdateBootConfigFromAssets(b.loaderConf(), "systemd-boot-loader.conf")
+}
+
+// ManagedAssets returns a list relative paths to boot assets inside the root
+// directory of the filesystem.
+//
+// Implements TrustedAssetsBootloader for the systemd-boot bootloader.
+func (b *systemdBoot) ManagedAssets() []string {
+	return []string{
+		filepath.Join(b.basedir, "loader/loader.conf"),
+	}
+}
+
+func (b *systemdBoot) commandLineForEdition(edition uint, pieces CommandLineComponents) (string, error) {
+	if err := pieces.Validate(); err != nil {
+		return "", err
+	}
+
+	var nonSnapdCmdline string
+	if pieces.FullArgs == "" {
+		staticCmdline := staticCommandLineForGrubAssetEdition("systemd-boot-loader.conf", edition)
+		nonSnapdCmdline = staticCmdline + " " + pieces.ExtraArgs
+	} else {
+		nonSnapdCmdline = pieces.FullArgs
+	}
+	args, err := osutil.KernelCommandLineSplit(nonSnapdCmdline)
+	if err != nil {
+		return "", fmt.Errorf("cannot use badly formatted kernel command line: %v", err)
+	}
+	snapdArgs := make([]string, 0, 2)
+	if pieces.ModeArg != "" {
+		snapdArgs = append(snapdArgs, pieces.ModeArg)
+	}
+	if pieces.SystemArg != "" {
+		snapdArgs = append(snapdArgs, pieces.SystemArg)
+	}
+	return strings.Join(append(snapdArgs, args...), " "), nil
+}
+
+// CommandLine returns the kernel command line composed of mode and
+// system arguments, followed by either a built-in bootloader specific
+// static arguments corresponding to the on-disk boot asset edition, and
+// any extra arguments or a separate set of arguments provided in the
+// components.
+//
+// Implements TrustedAssetsBootloader for the systemd-boot bootloader.
+func (b *systemdBoot) CommandLine(pieces CommandLineComponents) (string, error) {
+	edition, err := editionFromDiskConfigAsset(b.loaderConf())
+	if err != nil {
+		if err != errNoEdition {
+			return "", fmt.Errorf("cannot obtain edition number of current boot config: %v", err)
+		}
+		// the loader config is not managed, use the initial edition
+		// of the internal asset
+		edition = 1
+	}
+	return b.commandLineForEdition(edition, pieces)
+}
+
+// CandidateCommandLine is similar to CommandLine, but uses the current
+// edition of managed built-in boot assets as reference.
+//
+// Implements TrustedAssetsBootloader for the systemd-boot bootloader.
+func (b *systemdBoot) CandidateCommandLine(pieces CommandLineComponents) (string, error) {
+	edition, err := editionFromInternalConfigAsset("systemd-boot-loader.conf")
+	if err != nil {
+		return "", err
+	}
+	return b.commandLineForEdition(edition, pieces)
+}
+
+// systemdBootAssetPath contains the paths for assets in the boot chain.
+type systemdBootAssetPath struct {
+	// fallbackBinary is systemd-boot installed at the removable media
+	// path of the seed partition
+	fallbackBinary string
+	// bootBinary is systemd-boot installed on the boot partition
+	bootBinary string
+}
+
+// systemdBootAssetsForArch contains the paths for assets for different
+// architectures in a map
+var systemdBootAssetsForArch = map[string]systemdBootAssetPath{
+	"amd64": {
+		fallbackBinary: filepath.Join("EFI/boot/", "bootx64.efi"),
+		bootBinary:     filepath.Join("EFI/systemd/", "systemd-bootx64.efi")},
+	"arm64": {
+		fallbackBinary: filepath.Join("EFI/boot/", "bootaa64.efi"),
+		bootBinary:     filepath.Join("EFI/systemd/", "systemd-bootaa64.efi")},
+}
+
+func (b *systemdBoot) bootAssetsForArch() (*systemdBootAssetPath, error) {
+	if b.prepareImageTime {
+		return nil, fmt.Errorf("internal error: retrieving boot assets at prepare image time")
+	}
+	archi := arch.DpkgArchitecture()
+	assets, ok := systemdBootAssetsForArch[archi]
+	if !ok {
+		return nil, fmt.Errorf("cannot find systemd-boot assets for %q", archi)
+	}
+	return &assets, nil
+}
+
+// recoveryModeTrustedAssets returns the assets for recovery mode, which
+// is systemd-boot from the seed partition.
+func (b *systemdBoot) recoveryModeTrustedAssets() ([]string, error) {
+	assets, err := b.bootAssetsForArch()
+	if err != nil {
+		return nil, err
+	}
+	return []string{assets.fallbackBinary}, nil
+}
+
+// runModeTrustedAssets returns the assets for run mode, which is
+// systemd-boot from the boot partition.
+func (b *systemdBoot) runModeTrustedAssets() ([]string, error) {
+	assets, err := b.bootAssetsForArch()
+	if err != nil {
+		return nil, err
+	}
+	return []string{assets.bootBinary}, nil
+}
+
+// TrustedAssets returns the list of relative paths to assets inside
+// the bootloader's rootdir that are measured in the boot process in the
+// order of loading during the boot.
+func (b *systemdBoot) TrustedAssets() ([]string, error) {
+	if !b.nativePartitionLayout {
+		return nil, fmt.Errorf("internal error: trusted assets called without native host-partition layout")
+	}
+	if b.recovery {
+		return b.recoveryModeTrustedAssets()
+	}
+	return b.runModeTrustedAssets()
+}
+
+// RecoveryBootChain returns the load chain for recovery modes.
+// It should be called on a RoleRecovery bootloader.
+func (b *systemdBoot) RecoveryBootChain(kernelPath string) ([]BootFile, error) {
+	if !b.recovery {
+		return nil, fmt.Errorf("not a recovery bootloader")
+	}
+
+	assets, err := b.recoveryModeTrustedAssets()
+	if err != nil {
+		return nil, err
+	}
+	chain := make([]BootFile, 0, len(assets)+1)
+	for _, ta := range assets {
+		chain = append(chain, NewBootFile("", ta, RoleRecovery))
+	}
+	chain = append(chain, NewBootFile(kernelPath, "kernel.efi", RoleRecovery))
+
+	return chain, nil
+}
+
+// BootChain returns the load chain for run mode.
+// It should be called on a RoleRecovery bootloader passing the
+// RoleRunMode bootloader.
+func (b *systemdBoot) BootChain(runBl Bootloader, kernelPath string) ([]BootFile, error) {
+	if !b.recovery {
+		return nil, fmt.Errorf("not a recovery bootloader")
+	}
+	if runBl.Name() != "systemd-boot" {
+		return nil, fmt.Errorf("run mode bootloader must be systemd-boot")
+	}
+
+	recoveryModeAssets, err := b.recoveryModeTrustedAssets()
+	if err != nil {
+		return nil, err
+	}
+	runModeAssets, err := b.runModeTrustedAssets()
+	if err != nil {
+		return nil, err
+	}
+	chain := make([]BootFile, 0, len(recoveryModeAssets)+len(runModeAssets)+1)
+	for _, ta := range recoveryModeAssets {
+		chain = append(chain, NewBootFile("", ta, RoleRecovery))
+	}
+	for _, ta := range runModeAssets {
+		chain = append(chain, NewBootFile("", ta, RoleRunMode))
+	}
+	chain = append(chain, NewBootFile(kernelPath, "kernel.efi", RoleRunMode))
+
+	return chain, nil
+}
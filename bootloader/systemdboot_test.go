@@ -0,0 +1,442 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package bootloader_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/arch/archtest"
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/bootloader/assets"
+	"github.com/snapcore/snapd/bootloader/efi"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snapdir"
+	"github.com/snapcore/snapd/testutil"
+)
+
+const (
+	loaderEntryDefaultVar  = "LoaderEntryDefault-4a67b082-0a4c-41cf-b6c7-440b29bb8c4f"
+	loaderEntryOneShotVar  = "LoaderEntryOneShot-4a67b082-0a4c-41cf-b6c7-440b29bb8c4f"
+	loaderEntrySelectedVar = "LoaderEntrySelected-4a67b082-0a4c-41cf-b6c7-440b29bb8c4f"
+)
+
+type systemdBootTestSuite struct {
+	baseBootenvTestSuite
+
+	espdir     string
+	efivarsdir string
+	runOpts    *bootloader.Options
+	bootID     string
+}
+
+var _ = Suite(&systemdBootTestSuite{})
+
+func (s *systemdBootTestSuite) SetUpTest(c *C) {
+	s.baseBootenvTestSuite.SetUpTest(c)
+	s.AddCleanup(archtest.MockArchitecture("amd64"))
+
+	// the ESP as mounted for run mode
+	s.espdir = c.MkDir()
+	s.runOpts = &bootloader.Options{Role: bootloader.RoleRunMode, NoSlashBoot: true}
+	bootloader.MockSystemdBootFiles(c, s.espdir, s.runOpts)
+
+	// fake efivarfs
+	s.efivarsdir = filepath.Join(s.rootdir, "/sys/firmware/efi/efivars")
+	c.Assert(os.MkdirAll(s.efivarsdir, 0755), IsNil)
+	efivarfsMount := `
+38 24 0:32 / /sys/firmware/efi/efivars rw,nosuid,nodev,noexec,relatime shared:13 - efivarfs efivarfs rw
+`
+	s.AddCleanup(osutil.MockMountInfo(strings.TrimSpace(efivarfsMount)))
+
+	s.bootID = "boot-1"
+	s.AddCleanup(bootloader.MockBootID(func() (string, error) {
+		return s.bootID, nil
+	}))
+}
+
+// kernelDir is a kernel snap unpacked in a directory
+type kernelDir struct {
+	*snapdir.SnapDir
+	path string
+}
+
+func (d *kernelDir) Unpack(src, dst string) error {
+	return osutil.CopyFile(filepath.Join(d.path, src), filepath.Join(dst, src), 0)
+}
+
+func (s *systemdBootTestSuite) readVar(c *C, name string) string {
+	v, _, err := efi.ReadVarString(name)
+	c.Assert(err, IsNil)
+	return v
+}
+
+func (s *systemdBootTestSuite) extractKernel(c *C, b bootloader.Bootloader, name string, rev int) snap.PlaceInfo {
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "kernel.efi"), []byte(name+" UKI"), 0644), IsNil)
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: name, Revision: snap.R(rev)}}
+	c.Assert(b.ExtractKernelAssets(info, &kernelDir{SnapDir: snapdir.New(dir), path: dir}), IsNil)
+	return info
+}
+
+func (s *systemdBootTestSuite) TestNewSystemdBoot(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	c.Assert(b, NotNil)
+	c.Check(b.Name(), Equals, "systemd-boot")
+	present, err := b.Present()
+	c.Assert(err, IsNil)
+	c.Check(present, Equals, true)
+
+	// the traditional layout keeps the ESP at /boot/efi
+	b = bootloader.NewSystemdBoot(c.MkDir(), nil)
+	present, err = b.Present()
+	c.Assert(err, IsNil)
+	c.Check(present, Equals, false)
+}
+
+func (s *systemdBootTestSuite) TestInstallBootConfig(c *C) {
+	rootdir := c.MkDir()
+	b := bootloader.NewSystemdBoot(rootdir, s.runOpts)
+	err := b.InstallBootConfig(c.MkDir(), s.runOpts)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(rootdir, "loader/loader.conf"), testutil.FileEquals,
+		string(assets.Internal("systemd-boot-loader.conf")))
+
+	// not managed, the gadget provides the config
+	gadgetDir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(gadgetDir, "systemd-boot.conf"), []byte("timeout 5\n"), 0644), IsNil)
+	rootdir = c.MkDir()
+	b = bootloader.NewSystemdBoot(rootdir, nil)
+	err = b.InstallBootConfig(gadgetDir, nil)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(rootdir, "boot/efi/loader/loader.conf"), testutil.FileEquals, "timeout 5\n")
+}
+
+func (s *systemdBootTestSuite) TestSetGetBootVars(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	err := b.SetBootVars(map[string]string{"snap_mode": "try", "kernel_status": "try"})
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(s.espdir, "loader/snapd.env"), testutil.FilePresent)
+
+	m, err := b.GetBootVars("snap_mode", "kernel_status", "other")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snap_mode":     "try",
+		"kernel_status": "try",
+		"other":         "",
+	})
+}
+
+func (s *systemdBootTestSuite) TestGetBootVarsTryEntryBooted(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	c.Assert(b.SetBootVars(map[string]string{"kernel_status": "try"}), IsNil)
+	// set by systemd-boot when booting the try entry after a reboot
+	s.bootID = "boot-2"
+	c.Assert(efi.WriteVarString(loaderEntrySelectedVar, 0, "snapd-try.conf"), IsNil)
+
+	m, err := b.GetBootVars("kernel_status")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{"kernel_status": "trying"})
+
+	// the transition is persisted
+	c.Assert(efi.WriteVarString(loaderEntrySelectedVar, 0, "snapd-run.conf"), IsNil)
+	m, err = b.GetBootVars("kernel_status")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{"kernel_status": "trying"})
+}
+
+func (s *systemdBootTestSuite) TestGetBootVarsTryEntryNotBooted(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	c.Assert(b.SetBootVars(map[string]string{"kernel_status": "try"}), IsNil)
+
+	// no entry reported as selected
+	m, err := b.GetBootVars("kernel_status")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{"kernel_status": "try"})
+
+	// the run entry was booted, eg. falling back after a failed boot
+	c.Assert(efi.WriteVarString(loaderEntrySelectedVar, 0, "snapd-run.conf"), IsNil)
+	m, err = b.GetBootVars("kernel_status")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{"kernel_status": "try"})
+
+	// not an EFI system
+	osutil.MockMountInfo("")
+	m, err = b.GetBootVars("kernel_status")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{"kernel_status": "try"})
+}
+
+func (s *systemdBootTestSuite) TestGetBootVarsTryEntryBootedBeforeTry(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	// the current boot is of a try entry, eg. of a previous kernel
+	// refresh that was committed since
+	c.Assert(efi.WriteVarString(loaderEntrySelectedVar, 0, "snapd-try.conf"), IsNil)
+
+	// another kernel refresh happens in the same boot
+	c.Assert(b.SetBootVars(map[string]string{"kernel_status": "try"}), IsNil)
+	m, err := b.GetBootVars("kernel_status")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{"kernel_status": "try"})
+
+	// until the system is rebooted into the try entry
+	s.bootID = "boot-2"
+	m, err = b.GetBootVars("kernel_status")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{"kernel_status": "trying"})
+}
+
+func (s *systemdBootTestSuite) TestGetBootVarsTryEntryBootedNotTrying(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	c.Assert(b.SetBootVars(map[string]string{"kernel_status": ""}), IsNil)
+	c.Assert(efi.WriteVarString(loaderEntrySelectedVar, 0, "snapd-try.conf"), IsNil)
+
+	m, err := b.GetBootVars("kernel_status")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{"kernel_status": ""})
+}
+
+func (s *systemdBootTestSuite) TestSetGetRecoverySystemEnv(c *C) {
+	seeddir := c.MkDir()
+	b := bootloader.NewSystemdBoot(seeddir, &bootloader.Options{Role: bootloader.RoleRecovery})
+
+	v, err := b.GetRecoverySystemEnv("/systems/20191209", "snapd_recovery_kernel")
+	c.Assert(err, IsNil)
+	c.Check(v, Equals, "")
+
+	err = b.SetRecoverySystemEnv("/systems/20191209", map[string]string{
+		"snapd_recovery_kernel": "/snaps/pc-kernel_1.snap",
+	})
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(seeddir, "/systems/20191209/snapd.env"), testutil.FilePresent)
+
+	v, err = b.GetRecoverySystemEnv("/systems/20191209", "snapd_recovery_kernel")
+	c.Assert(err, IsNil)
+	c.Check(v, Equals, "/snaps/pc-kernel_1.snap")
+
+	_, err = b.GetRecoverySystemEnv("", "snapd_recovery_kernel")
+	c.Check(err, ErrorMatches, "internal error: recoverySystemDir unset")
+}
+
+func (s *systemdBootTestSuite) TestExtractAndRemoveKernelAssets(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	info := s.extractKernel(c, b, "pc-kernel", 1)
+	kernelEfi := filepath.Join(s.espdir, "EFI/snapd/pc-kernel_1.snap/kernel.efi")
+	c.Check(kernelEfi, testutil.FileEquals, "pc-kernel UKI")
+
+	c.Assert(b.RemoveKernelAssets(info), IsNil)
+	c.Check(filepath.Dir(kernelEfi), testutil.FileAbsent)
+}
+
+func (s *systemdBootTestSuite) TestEnableKernel(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	c.Assert(b.SetBootVars(map[string]string{"snapd_extra_cmdline_args": "foo=bar"}), IsNil)
+	info := s.extractKernel(c, b, "pc-kernel", 1)
+
+	eb, ok := b.(bootloader.ExtractedRunKernelImageBootloader)
+	c.Assert(ok, Equals, true)
+
+	err := eb.EnableKernel(info)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(s.espdir, "loader/entries/snapd-run.conf"), testutil.FileEquals, `title pc-kernel_1.snap
+efi /EFI/snapd/pc-kernel_1.snap/kernel.efi
+options snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 foo=bar
+`)
+	c.Check(s.readVar(c, loaderEntryDefaultVar), Equals, "snapd-run.conf")
+
+	kernel, err := eb.Kernel()
+	c.Assert(err, IsNil)
+	c.Check(kernel.Filename(), Equals, "pc-kernel_1.snap")
+
+	_, err = eb.TryKernel()
+	c.Check(err, Equals, bootloader.ErrNoTryKernelRef)
+}
+
+func (s *systemdBootTestSuite) TestEnableKernelNotExtracted(c *C) {
+	eb := bootloader.NewSystemdBoot(s.espdir, s.runOpts).(bootloader.ExtractedRunKernelImageBootloader)
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "pc-kernel", Revision: snap.R(1)}}
+
+	err := eb.EnableKernel(info)
+	c.Check(err, ErrorMatches, "cannot enable snapd-run.conf at EFI/snapd/pc-kernel_1.snap/kernel.efi: file does not exist")
+	err = eb.EnableTryKernel(info)
+	c.Check(err, ErrorMatches, "cannot enable snapd-try.conf at EFI/snapd/pc-kernel_1.snap/kernel.efi: file does not exist")
+	c.Check(filepath.Join(s.efivarsdir, loaderEntryOneShotVar), testutil.FileAbsent)
+}
+
+func (s *systemdBootTestSuite) TestTryKernel(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	eb := b.(bootloader.ExtractedRunKernelImageBootloader)
+	info1 := s.extractKernel(c, b, "pc-kernel", 1)
+	info2 := s.extractKernel(c, b, "pc-kernel", 2)
+	c.Assert(eb.EnableKernel(info1), IsNil)
+
+	err := eb.EnableTryKernel(info2)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(s.espdir, "loader/entries/snapd-try.conf"), testutil.FileContains,
+		"efi /EFI/snapd/pc-kernel_2.snap/kernel.efi\n")
+	c.Check(s.readVar(c, loaderEntryOneShotVar), Equals, "snapd-try.conf")
+	// the default is unchanged
+	c.Check(s.readVar(c, loaderEntryDefaultVar), Equals, "snapd-run.conf")
+
+	tryKernel, err := eb.TryKernel()
+	c.Assert(err, IsNil)
+	c.Check(tryKernel.Filename(), Equals, "pc-kernel_2.snap")
+	kernel, err := eb.Kernel()
+	c.Assert(err, IsNil)
+	c.Check(kernel.Filename(), Equals, "pc-kernel_1.snap")
+
+	// successful boot of the try kernel
+	c.Assert(eb.EnableKernel(info2), IsNil)
+	c.Assert(eb.DisableTryKernel(), IsNil)
+	c.Check(filepath.Join(s.espdir, "loader/entries/snapd-try.conf"), testutil.FileAbsent)
+	c.Check(filepath.Join(s.efivarsdir, loaderEntryOneShotVar), testutil.FileAbsent)
+	_, err = eb.TryKernel()
+	c.Check(err, Equals, bootloader.ErrNoTryKernelRef)
+	kernel, err = eb.Kernel()
+	c.Assert(err, IsNil)
+	c.Check(kernel.Filename(), Equals, "pc-kernel_2.snap")
+
+	// disabling again is fine
+	c.Assert(eb.DisableTryKernel(), IsNil)
+}
+
+func (s *systemdBootTestSuite) TestTryKernelMissingImage(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	eb := b.(bootloader.ExtractedRunKernelImageBootloader)
+	info := s.extractKernel(c, b, "pc-kernel", 2)
+	c.Assert(eb.EnableTryKernel(info), IsNil)
+	c.Assert(b.RemoveKernelAssets(info), IsNil)
+
+	_, err := eb.TryKernel()
+	c.Check(err, ErrorMatches, "cannot use loader entry snapd-try.conf: kernel image /EFI/snapd/pc-kernel_2.snap/kernel.efi does not exist")
+}
+
+func (s *systemdBootTestSuite) TestEnableKernelNoEFISystem(c *C) {
+	osutil.MockMountInfo("")
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	eb := b.(bootloader.ExtractedRunKernelImageBootloader)
+	info := s.extractKernel(c, b, "pc-kernel", 1)
+
+	// the default entry is set in loader.conf already
+	c.Assert(eb.EnableKernel(info), IsNil)
+	// the try entry is written but cannot be booted without EFI
+	// variables
+	c.Assert(eb.EnableTryKernel(info), IsNil)
+	c.Check(filepath.Join(s.espdir, "loader/entries/snapd-try.conf"), testutil.FilePresent)
+	c.Check(filepath.Join(s.efivarsdir, loaderEntryOneShotVar), testutil.FileAbsent)
+}
+
+func (s *systemdBootTestSuite) TestCommandLine(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	tb, ok := b.(bootloader.TrustedAssetsBootloader)
+	c.Assert(ok, Equals, true)
+
+	cmdline, err := tb.CommandLine(bootloader.CommandLineComponents{
+		ModeArg:   "snapd_recovery_mode=run",
+		ExtraArgs: "extra=1",
+	})
+	c.Assert(err, IsNil)
+	c.Check(cmdline, Equals, "snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 extra=1")
+
+	cmdline, err = tb.CandidateCommandLine(bootloader.CommandLineComponents{
+		ModeArg:  "snapd_recovery_mode=run",
+		FullArgs: "full=1",
+	})
+	c.Assert(err, IsNil)
+	c.Check(cmdline, Equals, "snapd_recovery_mode=run full=1")
+
+	_, err = tb.CommandLine(bootloader.CommandLineComponents{
+		ExtraArgs: "extra=1",
+		FullArgs:  "full=1",
+	})
+	c.Check(err, ErrorMatches, "cannot use both full and extra components of command line")
+}
+
+func (s *systemdBootTestSuite) TestUpdateBootConfig(c *C) {
+	b := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	tb := b.(bootloader.TrustedAssetsBootloader)
+	c.Check(tb.ManagedAssets(), DeepEquals, []string{"loader/loader.conf"})
+
+	// unmanaged config is not updated
+	updated, err := tb.UpdateBootConfig()
+	c.Assert(err, IsNil)
+	c.Check(updated, Equals, false)
+
+	restore := assets.MockInternal("systemd-boot-loader.conf", []byte("# Snapd-Boot-Config-Edition: 2\ndefault snapd-run.conf\n"))
+	defer restore()
+	loaderConf := filepath.Join(s.espdir, "loader/loader.conf")
+	c.Assert(ioutil.WriteFile(loaderConf, []byte("# Snapd-Boot-Config-Edition: 1\n"), 0644), IsNil)
+
+	updated, err = tb.UpdateBootConfig()
+	c.Assert(err, IsNil)
+	c.Check(updated, Equals, true)
+	c.Check(loaderConf, testutil.FileEquals, "# Snapd-Boot-Config-Edition: 2\ndefault snapd-run.conf\n")
+}
+
+func (s *systemdBootTestSuite) TestTrustedAssetsAndBootChains(c *C) {
+	seeddir := c.MkDir()
+	recoveryBl := bootloader.NewSystemdBoot(seeddir, &bootloader.Options{Role: bootloader.RoleRecovery})
+	runBl := bootloader.NewSystemdBoot(s.espdir, s.runOpts)
+	tb := recoveryBl.(bootloader.TrustedAssetsBootloader)
+
+	ta, err := tb.TrustedAssets()
+	c.Assert(err, IsNil)
+	c.Check(ta, DeepEquals, []string{"EFI/boot/bootx64.efi"})
+	ta, err = runBl.(bootloader.TrustedAssetsBootloader).TrustedAssets()
+	c.Assert(err, IsNil)
+	c.Check(ta, DeepEquals, []string{"EFI/systemd/systemd-bootx64.efi"})
+
+	chain, err := tb.RecoveryBootChain("kernel.snap")
+	c.Assert(err, IsNil)
+	c.Check(chain, DeepEquals, []bootloader.BootFile{
+		{Path: "EFI/boot/bootx64.efi", Role: bootloader.RoleRecovery},
+		{Snap: "kernel.snap", Path: "kernel.efi", Role: bootloader.RoleRecovery},
+	})
+
+	chain, err = tb.BootChain(runBl, "kernel.snap")
+	c.Assert(err, IsNil)
+	c.Check(chain, DeepEquals, []bootloader.BootFile{
+		{Path: "EFI/boot/bootx64.efi", Role: bootloader.RoleRecovery},
+		{Path: "EFI/systemd/systemd-bootx64.efi", Role: bootloader.RoleRunMode},
+		{Snap: "kernel.snap", Path: "kernel.efi", Role: bootloader.RoleRunMode},
+	})
+
+	_, err = tb.BootChain(bootloader.NewGrub(c.MkDir(), s.runOpts), "kernel.snap")
+	c.Check(err, ErrorMatches, "run mode bootloader must be systemd-boot")
+	_, err = runBl.(bootloader.TrustedAssetsBootloader).RecoveryBootChain("kernel.snap")
+	c.Check(err, ErrorMatches, "not a recovery bootloader")
+
+	// without the native layout
+	_, err = bootloader.NewSystemdBoot(c.MkDir(), nil).(bootloader.TrustedAssetsBootloader).TrustedAssets()
+	c.Check(err, ErrorMatches, "internal error: trusted assets called without native host-partition layout")
+}
+
+func (s *systemdBootTestSuite) TestTrustedAssetsArm64(c *C) {
+	defer archtest.MockArchitecture("arm64")()
+	tb := bootloader.NewSystemdBoot(c.MkDir(), &bootloader.Options{Role: bootloader.RoleRecovery}).(bootloader.TrustedAssetsBootloader)
+	ta, err := tb.TrustedAssets()
+	c.Assert(err, IsNil)
+	c.Check(ta, DeepEquals, []string{"EFI/boot/bootaa64.efi"})
+}
@@ -27,11 +27,13 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -775,6 +777,93 @@ func (client *Client) DebugGet(aspect string, result interface{}, params map[str
 	return err
 }
 
+// DebugCheckPolicyOptions holds the options for checking the interface
+// policy for a snap file.
+type DebugCheckPolicyOptions struct {
+	// SnapDeclarationFile is the path to a file with a snap declaration
+	// used instead of the one of the snap known to the system
+	SnapDeclarationFile string
+	// ModelFile is the path to a file with a model used instead of the
+	// model of the device
+	ModelFile string
+	// With restricts the installed snaps checked for connections
+	With []string
+}
+
+// DebugCheckPolicy checks whether the installation of the given snap file,
+// and the connections of its plugs and slots with the installed snaps, are
+// allowed by the interface policy, without installing it.
+func (client *Client) DebugCheckPolicy(snapPath string, opts *DebugCheckPolicyOptions, result interface{}) error {
+	if opts == nil {
+		opts = &DebugCheckPolicyOptions{}
+	}
+
+	var files []*os.File
+	var fileFields []string
+	for _, f := range []struct{ field, path string }{
+		{"snap", snapPath},
+		{"snap-declaration", opts.SnapDeclarationFile},
+		{"model", opts.ModelFile},
+	} {
+		if f.path == "" {
+			continue
+		}
+		file, err := os.Open(f.path)
+		if err != nil {
+			for _, file := range files {
+				file.Close()
+			}
+			return fmt.Errorf("cannot open %q: %w", f.path, err)
+		}
+		files = append(files, file)
+		fileFields = append(fileFields, f.field)
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go sendDebugCheckPolicyFiles(opts, fileFields, files, pw, mw)
+
+	headers := map[string]string{
+		"Content-Type": mw.FormDataContentType(),
+	}
+	_, err := client.doSyncWithOpts("POST", "/v2/debug", nil, headers, pr, result, doNoTimeoutAndRetry)
+	return err
+}
+
+func sendDebugCheckPolicyFiles(opts *DebugCheckPolicyOptions, fileFields []string, files []*os.File, pw *io.PipeWriter, mw *multipart.Writer) {
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	if err := mw.WriteField("action", "check-policy"); err != nil {
+		pw.CloseWithError(err)
+		return
+	}
+	for _, snapName := range opts.With {
+		if err := mw.WriteField("with", snapName); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+	}
+
+	for i, file := range files {
+		fw, err := mw.CreateFormFile(fileFields[i], filepath.Base(file.Name()))
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(fw, file); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+	}
+
+	mw.Close()
+	pw.Close()
+}
+
 type SystemRecoveryKeysResponse struct {
 	RecoveryKey  string `json:"recovery-key"`
 	ReinstallKey string `json:"reinstall-key,omitempty"`
@@ -785,6 +874,55 @@ func (client *Client) SystemRecoveryKeys(result interface{}) error {
 	return err
 }
 
+// RecoveryKeyInfo describes a named recovery key of the encrypted volumes.
+type RecoveryKeyInfo struct {
+	Name string `json:"name"`
+	// Volumes are the names of the volumes the key can unlock
+	Volumes []string `json:"volumes,omitempty"`
+	// RecoveryKey is only set when the key has just been added
+	RecoveryKey string `json:"recovery-key,omitempty"`
+}
+
+// ListRecoveryKeys returns the named recovery keys of the encrypted volumes.
+func (client *Client) ListRecoveryKeys() ([]RecoveryKeyInfo, error) {
+	var infos []RecoveryKeyInfo
+	if err := client.postRecoveryKeysAction("list", "", &infos); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// AddRecoveryKey adds a new recovery key with the given name to the encrypted
+// volumes, the returned key is not kept by the system.
+func (client *Client) AddRecoveryKey(name string) (*RecoveryKeyInfo, error) {
+	var info RecoveryKeyInfo
+	if err := client.postRecoveryKeysAction("add", name, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// RemoveRecoveryKey removes the recovery key with the given name from the
+// encrypted volumes.
+func (client *Client) RemoveRecoveryKey(name string) error {
+	return client.postRecoveryKeysAction("remove", name, nil)
+}
+
+func (client *Client) postRecoveryKeysAction(action, name string, result interface{}) error {
+	body, err := json.Marshal(struct {
+		Action string `json:"action"`
+		Name   string `json:"name,omitempty"`
+	}{
+		Action: action,
+		Name:   name,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.doSync("POST", "/v2/system-recovery-keys", nil, nil, bytes.NewReader(body), result)
+	return err
+}
+
 func (c *Client) MigrateSnapHome(snaps []string) (changeID string, err error) {
 	body, err := json.Marshal(struct {
 		Action string   `json:"action"`
@@ -631,6 +631,37 @@ func (cs *clientSuite) TestDebugGet(c *C) {
 	c.Check(cs.reqs[0].URL.Query(), DeepEquals, url.Values{"aspect": []string{"do-something"}, "foo": []string{"bar"}})
 }
 
+func (cs *clientSuite) TestDebugCheckPolicy(c *C) {
+	cs.rsp = `{"type": "sync", "result": {"snap": "foo"}}`
+
+	dir := c.MkDir()
+	snapFile := filepath.Join(dir, "foo_1.snap")
+	c.Assert(ioutil.WriteFile(snapFile, []byte("snap-data"), 0644), IsNil)
+	declFile := filepath.Join(dir, "foo.decl")
+	c.Assert(ioutil.WriteFile(declFile, []byte("decl-data"), 0644), IsNil)
+
+	var result map[string]interface{}
+	err := cs.cli.DebugCheckPolicy(snapFile, &client.DebugCheckPolicyOptions{
+		SnapDeclarationFile: declFile,
+		With:                []string{"bar"},
+	}, &result)
+	c.Assert(err, IsNil)
+	c.Check(result, DeepEquals, map[string]interface{}{"snap": "foo"})
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/debug")
+	c.Check(cs.req.Header.Get("Content-Type"), Matches, "multipart/form-data; boundary=.*")
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, IsNil)
+	c.Check(string(body), Matches, `(?s).*name="action"\r\n\r\ncheck-policy\r\n.*name="with"\r\n\r\nbar\r\n.*name="snap"; filename="foo_1.snap".*snap-data.*name="snap-declaration"; filename="foo.decl".*decl-data.*`)
+	c.Check(string(body), Not(testutil.Contains), `name="model"`)
+}
+
+func (cs *clientSuite) TestDebugCheckPolicyCannotOpen(c *C) {
+	err := cs.cli.DebugCheckPolicy(filepath.Join(c.MkDir(), "missing.snap"), nil, nil)
+	c.Check(err, ErrorMatches, `cannot open ".*/missing.snap": .*`)
+	c.Check(cs.req, IsNil)
+}
+
 func (cs *clientSuite) TestDebugMigrateHome(c *C) {
 	cs.status = 202
 	cs.rsp = `{"type": "async", "status-code": 202, "change": "123"}`
@@ -714,3 +745,55 @@ func (cs *clientSuite) TestClientDebugEnvVar(c *check.C) {
 	c.Assert(buf.String(), testutil.Contains, "foo")
 	c.Assert(buf.String(), testutil.Contains, "bar")
 }
+
+func (cs *clientSuite) TestClientAddRecoveryKey(c *C) {
+	cs.rsp = `{"type":"sync", "result":{"name":"backup","recovery-key":"42","volumes":["ubuntu-data"]}}`
+
+	info, err := cs.cli.AddRecoveryKey("backup")
+	c.Assert(err, IsNil)
+	c.Check(cs.reqs, HasLen, 1)
+	c.Check(cs.reqs[0].Method, Equals, "POST")
+	c.Check(cs.reqs[0].URL.Path, Equals, "/v2/system-recovery-keys")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.reqs[0].Body).Decode(&body), IsNil)
+	c.Check(body, DeepEquals, map[string]interface{}{
+		"action": "add",
+		"name":   "backup",
+	})
+	c.Check(info, DeepEquals, &client.RecoveryKeyInfo{
+		Name:        "backup",
+		RecoveryKey: "42",
+		Volumes:     []string{"ubuntu-data"},
+	})
+}
+
+func (cs *clientSuite) TestClientRemoveRecoveryKey(c *C) {
+	cs.rsp = `{"type":"sync", "result":null}`
+
+	err := cs.cli.RemoveRecoveryKey("backup")
+	c.Assert(err, IsNil)
+	c.Check(cs.reqs, HasLen, 1)
+	c.Check(cs.reqs[0].Method, Equals, "POST")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.reqs[0].Body).Decode(&body), IsNil)
+	c.Check(body, DeepEquals, map[string]interface{}{
+		"action": "remove",
+		"name":   "backup",
+	})
+}
+
+func (cs *clientSuite) TestClientListRecoveryKeys(c *C) {
+	cs.rsp = `{"type":"sync", "result":[{"name":"default","volumes":["ubuntu-data","ubuntu-save"]}]}`
+
+	infos, err := cs.cli.ListRecoveryKeys()
+	c.Assert(err, IsNil)
+	c.Check(cs.reqs, HasLen, 1)
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.reqs[0].Body).Decode(&body), IsNil)
+	c.Check(body, DeepEquals, map[string]interface{}{
+		"action": "list",
+	})
+	c.Check(infos, DeepEquals, []client.RecoveryKeyInfo{
+		{Name: "default", Volumes: []string{"ubuntu-data", "ubuntu-save"}},
+	})
+}
@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // Plug represents the potential of a given snap to connect to a slot.
@@ -75,6 +76,9 @@ type InterfaceAction struct {
 	Forget bool   `json:"forget,omitempty"`
 	Plugs  []Plug `json:"plugs,omitempty"`
 	Slots  []Slot `json:"slots,omitempty"`
+	// For and UntilReboot make a connection temporary
+	For         string `json:"for,omitempty"`
+	UntilReboot bool   `json:"until-reboot,omitempty"`
 }
 
 // InterfaceOptions represents opt-in elements include in responses.
@@ -86,6 +90,16 @@ type InterfaceOptions struct {
 	Connected bool
 }
 
+// ConnectOptions represents extra options for connect op
+type ConnectOptions struct {
+	// For makes the connection be automatically removed after the given
+	// duration
+	For time.Duration
+	// UntilReboot makes the connection be automatically removed once the
+	// system has been rebooted
+	UntilReboot bool
+}
+
 // DisconnectOptions represents extra options for disconnect op
 type DisconnectOptions struct {
 	Forget bool
@@ -131,11 +145,25 @@ func (client *Client) performInterfaceAction(sa *InterfaceAction) (changeID stri
 // Connect establishes a connection between a plug and a slot.
 // The plug and the slot must have the same interface.
 func (client *Client) Connect(plugSnapName, plugName, slotSnapName, slotName string) (changeID string, err error) {
-	return client.performInterfaceAction(&InterfaceAction{
+	return client.ConnectWithOptions(plugSnapName, plugName, slotSnapName, slotName, nil)
+}
+
+// ConnectWithOptions establishes a connection between a plug and a slot,
+// possibly only temporarily. Connecting an already temporarily connected
+// plug and slot again updates when the connection is removed.
+func (client *Client) ConnectWithOptions(plugSnapName, plugName, slotSnapName, slotName string, opts *ConnectOptions) (changeID string, err error) {
+	action := &InterfaceAction{
 		Action: "connect",
 		Plugs:  []Plug{{Snap: plugSnapName, Name: plugName}},
 		Slots:  []Slot{{Snap: slotSnapName, Name: slotName}},
-	})
+	}
+	if opts != nil {
+		if opts.For != 0 {
+			action.For = opts.For.String()
+		}
+		action.UntilReboot = opts.UntilReboot
+	}
+	return client.performInterfaceAction(action)
 }
 
 // Disconnect breaks the connection between a plug and a slot.
@@ -147,3 +175,55 @@ func (client *Client) Disconnect(plugSnapName, plugName, slotSnapName, slotName
 		Slots:  []Slot{{Snap: slotSnapName, Name: slotName}},
 	})
 }
+
+// DeviceAccess describes a udev rule granting an application or hook of a
+// snap access to a device.
+type DeviceAccess struct {
+	Snap      string   `json:"snap"`
+	App       string   `json:"app,omitempty"`
+	Hook      string   `json:"hook,omitempty"`
+	Interface string   `json:"interface"`
+	Plug      *PlugRef `json:"plug,omitempty"`
+	Slot      *SlotRef `json:"slot,omitempty"`
+	Rule      string   `json:"rule"`
+	// Error is set when the rule could not be evaluated against the device.
+	Error string `json:"error,omitempty"`
+}
+
+// UnrestrictedDeviceAccess describes an application or hook of a snap whose
+// access to devices is not restricted.
+type UnrestrictedDeviceAccess struct {
+	Snap string `json:"snap"`
+	App  string `json:"app,omitempty"`
+	Hook string `json:"hook,omitempty"`
+	// ControlsDeviceCgroup is set when the snap controls its own device
+	// cgroup, otherwise no devices are tagged for the application or hook.
+	ControlsDeviceCgroup bool `json:"controls-device-cgroup,omitempty"`
+}
+
+// DeviceAccessInfo describes a device and the snaps granted access to it.
+type DeviceAccessInfo struct {
+	Path      string         `json:"path"`
+	DevPath   string         `json:"devpath"`
+	Subsystem string         `json:"subsystem,omitempty"`
+	Access    []DeviceAccess `json:"access"`
+	// Unevaluated lists the rules which could not be evaluated against the
+	// device, they may grant access to it.
+	Unevaluated []DeviceAccess `json:"unevaluated,omitempty"`
+	// Unrestricted lists the applications and hooks which can access any
+	// device.
+	Unrestricted []UnrestrictedDeviceAccess `json:"unrestricted,omitempty"`
+}
+
+// DeviceAccess returns the applications and hooks of snaps which are granted
+// access to the device with the given device node or sysfs path.
+func (client *Client) DeviceAccess(path string) (*DeviceAccessInfo, error) {
+	query := url.Values{}
+	query.Set("path", path)
+	var info DeviceAccessInfo
+	_, err := client.doSync("GET", "/v2/interfaces/devices", query, nil, nil, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
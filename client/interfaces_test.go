@@ -21,6 +21,7 @@ package client_test
 
 import (
 	"encoding/json"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -194,6 +195,43 @@ func (cs *clientSuite) TestClientConnect(c *check.C) {
 	})
 }
 
+func (cs *clientSuite) TestClientConnectTemporary(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"result": { },
+		"change": "foo"
+	}`
+	id, err := cs.cli.ConnectWithOptions("producer", "plug", "consumer", "slot", &client.ConnectOptions{
+		For:         2 * time.Hour,
+		UntilReboot: true,
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "foo")
+	var body map[string]interface{}
+	decoder := json.NewDecoder(cs.req.Body)
+	err = decoder.Decode(&body)
+	c.Check(err, check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"action": "connect",
+		"plugs": []interface{}{
+			map[string]interface{}{
+				"snap": "producer",
+				"plug": "plug",
+			},
+		},
+		"slots": []interface{}{
+			map[string]interface{}{
+				"snap": "consumer",
+				"slot": "slot",
+			},
+		},
+		"for":          "2h0m0s",
+		"until-reboot": true,
+	})
+}
+
 func (cs *clientSuite) TestClientDisconnectCallsEndpoint(c *check.C) {
 	cs.cli.Disconnect("producer", "plug", "consumer", "slot", nil)
 	c.Check(cs.req.Method, check.Equals, "POST")
@@ -266,3 +304,66 @@ func (cs *clientSuite) TestClientDisconnectForget(c *check.C) {
 		},
 	})
 }
+
+func (cs *clientSuite) TestClientDeviceAccess(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"path": "/dev/ttyUSB0",
+			"devpath": "/devices/pci0000:00/usb1/1-1/ttyUSB0/tty/ttyUSB0",
+			"subsystem": "tty",
+			"access": [
+				{
+					"snap": "consumer",
+					"app": "app",
+					"interface": "serial-port",
+					"plug": {"snap": "consumer", "plug": "serial"},
+					"slot": {"snap": "gadget", "slot": "serial"},
+					"rule": "SUBSYSTEM==\"tty\", KERNEL==\"ttyUSB0\""
+				}
+			],
+			"unevaluated": [
+				{
+					"snap": "consumer",
+					"hook": "configure",
+					"interface": "weird",
+					"rule": "PROGRAM==\"/bin/true\"",
+					"error": "unsupported udev rule key \"PROGRAM\""
+				}
+			],
+			"unrestricted": [
+				{"snap": "manager", "app": "daemon", "controls-device-cgroup": true},
+				{"snap": "plain", "app": "app"}
+			]
+		}
+	}`
+	info, err := cs.cli.DeviceAccess("/dev/ttyUSB0")
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/interfaces/devices")
+	c.Check(cs.req.URL.RawQuery, check.Equals, "path=%2Fdev%2FttyUSB0")
+	c.Assert(err, check.IsNil)
+	c.Check(info, check.DeepEquals, &client.DeviceAccessInfo{
+		Path:      "/dev/ttyUSB0",
+		DevPath:   "/devices/pci0000:00/usb1/1-1/ttyUSB0/tty/ttyUSB0",
+		Subsystem: "tty",
+		Access: []client.DeviceAccess{{
+			Snap:      "consumer",
+			App:       "app",
+			Interface: "serial-port",
+			Plug:      &client.PlugRef{Snap: "consumer", Name: "serial"},
+			Slot:      &client.SlotRef{Snap: "gadget", Name: "serial"},
+			Rule:      `SUBSYSTEM=="tty", KERNEL=="ttyUSB0"`,
+		}},
+		Unevaluated: []client.DeviceAccess{{
+			Snap:      "consumer",
+			Hook:      "configure",
+			Interface: "weird",
+			Rule:      `PROGRAM=="/bin/true"`,
+			Error:     `unsupported udev rule key "PROGRAM"`,
+		}},
+		Unrestricted: []client.UnrestrictedDeviceAccess{
+			{Snap: "manager", App: "daemon", ControlsDeviceCgroup: true},
+			{Snap: "plain", App: "app"},
+		},
+	})
+}
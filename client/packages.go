@@ -261,3 +261,31 @@ func (client *Client) Snap(name string) (*Snap, *ResultInfo, error) {
 	}
 	return snap, ri, nil
 }
+
+// SnapHistoryEntry records a single install, refresh, revert, removal,
+// hold or unhold of a snap.
+type SnapHistoryEntry struct {
+	Time         time.Time     `json:"time"`
+	Action       string        `json:"action"`
+	FromRevision snap.Revision `json:"from-revision"`
+	ToRevision   snap.Revision `json:"to-revision"`
+	Channel      string        `json:"channel,omitempty"`
+	// Trigger is one of "manual", "auto", "remodel" or "validation-set".
+	Trigger  string `json:"trigger"`
+	ChangeID string `json:"change-id,omitempty"`
+	// Outcome is either "done" or "undone".
+	Outcome   string     `json:"outcome"`
+	HeldBy    string     `json:"held-by,omitempty"`
+	HoldUntil *time.Time `json:"hold-until,omitempty"`
+}
+
+// SnapHistory returns the recorded history of the given snap, oldest
+// first. The history of a removed snap is kept for a limited time only.
+func (client *Client) SnapHistory(name string) ([]*SnapHistoryEntry, error) {
+	var history []*SnapHistoryEntry
+	path := fmt.Sprintf("/v2/snaps/%s/history", name)
+	if _, err := client.doSync("GET", path, nil, nil, nil, &history); err != nil {
+		return nil, xerrors.Errorf("cannot retrieve history of snap %q: %w", name, err)
+	}
+	return history, nil
+}
@@ -414,3 +414,44 @@ func (cs *clientSuite) TestClientFindFromPathErrIsWrapped(c *check.C) {
 	_, err = cs.cli.List([]string{"snap"}, nil)
 	c.Assert(xerrors.As(err, &e), check.Equals, true)
 }
+
+func (cs *clientSuite) TestClientSnapHistory(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [
+			{"time": "2023-01-02T03:04:05Z", "action": "refresh", "from-revision": "1", "to-revision": "2", "channel": "latest/stable", "trigger": "auto", "change-id": "42", "outcome": "done"},
+			{"time": "2023-01-03T03:04:05Z", "action": "hold", "from-revision": "unset", "to-revision": "unset", "trigger": "manual", "outcome": "done", "held-by": "system", "hold-until": "2023-02-03T03:04:05Z"}
+		]
+	}`
+	history, err := cs.cli.SnapHistory("foo")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/foo/history")
+	holdUntil := time.Date(2023, 2, 3, 3, 4, 5, 0, time.UTC)
+	c.Check(history, check.DeepEquals, []*client.SnapHistoryEntry{
+		{
+			Time:         time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+			Action:       "refresh",
+			FromRevision: snap.R(1),
+			ToRevision:   snap.R(2),
+			Channel:      "latest/stable",
+			Trigger:      "auto",
+			ChangeID:     "42",
+			Outcome:      "done",
+		}, {
+			Time:      time.Date(2023, 1, 3, 3, 4, 5, 0, time.UTC),
+			Action:    "hold",
+			Trigger:   "manual",
+			Outcome:   "done",
+			HeldBy:    "system",
+			HoldUntil: &holdUntil,
+		},
+	})
+}
+
+func (cs *clientSuite) TestClientSnapHistoryError(c *check.C) {
+	cs.status = 404
+	cs.rsp = `{"type": "error", "status-code": 404, "result": {"message": "snap \"foo\" is not installed", "kind": "snap-not-found", "value": "foo"}}`
+	_, err := cs.cli.SnapHistory("foo")
+	c.Assert(err, check.ErrorMatches, `cannot retrieve history of snap "foo": snap "foo" is not installed`)
+}
@@ -28,6 +28,9 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/snapcore/snapd/snap"
 )
 
 // TransactionType says whether we want to treat each snap separately
@@ -61,6 +64,12 @@ type SnapOptions struct {
 	ValidationSets   []string        `json:"validation-sets,omitempty"`
 	Time             string          `json:"time,omitempty"`
 	HoldLevel        string          `json:"hold-level,omitempty"`
+	// DeltaFrom is the installed revision a local delta file is to be
+	// applied to, see InstallPath.
+	DeltaFrom string `json:"delta-from,omitempty"`
+	// DeltaAssertions optionally holds the snap-revision assertion for
+	// the snap reconstructed from a local delta and its prerequisites.
+	DeltaAssertions []byte `json:"-"`
 
 	Users []string `json:"users,omitempty"`
 }
@@ -112,6 +121,16 @@ func (opts *SnapOptions) writeOptionFields(mw *multipart.Writer) error {
 			return err
 		}
 	}
+	if opts.DeltaFrom != "" {
+		if err := mw.WriteField("delta-from", opts.DeltaFrom); err != nil {
+			return err
+		}
+	}
+	if len(opts.DeltaAssertions) != 0 {
+		if err := mw.WriteField("assertion", string(opts.DeltaAssertions)); err != nil {
+			return err
+		}
+	}
 	return writeFields(mw, fields)
 }
 
@@ -132,6 +151,7 @@ type multiActionData struct {
 	ValidationSets []string        `json:"validation-sets,omitempty"`
 	Time           string          `json:"time,omitempty"`
 	HoldLevel      string          `json:"hold-level,omitempty"`
+	Plan           bool            `json:"plan,omitempty"`
 }
 
 // Install adds the snap with the given name from the given channel (or
@@ -163,6 +183,73 @@ func (client *Client) RefreshMany(names []string, options *SnapOptions) (changeI
 	return client.doMultiSnapAction("refresh", names, options)
 }
 
+// RefreshPlan describes what a refresh of many snaps would do.
+type RefreshPlan struct {
+	Refresh           []*RefreshPlanSnap        `json:"refresh,omitempty"`
+	Held              []*RefreshPlanHeldSnap    `json:"held,omitempty"`
+	Blocked           []*RefreshPlanBlockedSnap `json:"blocked,omitempty"`
+	Prerequisites     []string                  `json:"prerequisites,omitempty"`
+	RequiredSpace     uint64                    `json:"required-space"`
+	InsufficientSpace bool                      `json:"insufficient-space,omitempty"`
+	RebootRequired    bool                      `json:"reboot-required,omitempty"`
+}
+
+// RefreshPlanSnap describes a snap that would be refreshed.
+type RefreshPlanSnap struct {
+	Name            string        `json:"name"`
+	Type            snap.Type     `json:"type"`
+	Version         string        `json:"version"`
+	CurrentRevision snap.Revision `json:"current-revision"`
+	Revision        snap.Revision `json:"revision"`
+	Channel         string        `json:"channel,omitempty"`
+	DownloadSize    int64         `json:"download-size"`
+	Prerequisites   []string      `json:"prerequisites,omitempty"`
+	RebootRequired  bool          `json:"reboot-required,omitempty"`
+}
+
+// RefreshPlanHeldSnap describes a snap whose refresh is held, HeldBy maps
+// the holding snaps (or "system") to the end time of their hold.
+type RefreshPlanHeldSnap struct {
+	Name     string               `json:"name"`
+	Revision snap.Revision        `json:"revision"`
+	HeldBy   map[string]time.Time `json:"held-by"`
+}
+
+// RefreshPlanBlockedSnap describes a snap whose refresh is blocked by
+// validation sets.
+type RefreshPlanBlockedSnap struct {
+	Name     string        `json:"name"`
+	Revision snap.Revision `json:"revision"`
+	Reason   string        `json:"reason"`
+}
+
+// RefreshPlan computes what refreshing the given snaps (or all snaps if
+// none are given) would do, without performing the refresh.
+func (client *Client) RefreshPlan(names []string, options *SnapOptions) (*RefreshPlan, error) {
+	action := multiActionData{
+		Action: "refresh",
+		Snaps:  names,
+		Plan:   true,
+	}
+	if options != nil {
+		action.IgnoreRunning = options.IgnoreRunning
+	}
+	data, err := json.Marshal(&action)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal multi-snap action: %s", err)
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	var plan RefreshPlan
+	if _, err := client.doSync("POST", "/v2/snaps", nil, headers, bytes.NewBuffer(data), &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
 func (client *Client) HoldRefreshes(name string, options *SnapOptions) (changeID string, err error) {
 	return client.doSnapAction("hold", name, options)
 }
@@ -273,7 +360,9 @@ func (client *Client) doMultiSnapActionFull(actionName string, snaps []string, o
 }
 
 // InstallPath sideloads the snap with the given path under optional provided name,
-// returning the UUID of the background operation upon success.
+// returning the UUID of the background operation upon success. If
+// options.DeltaFrom is set the path is a delta to be applied to that
+// installed revision of the snap instead.
 func (client *Client) InstallPath(path, name string, options *SnapOptions) (changeID string, err error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -29,10 +29,12 @@ import (
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -253,6 +255,52 @@ func (cs *clientSuite) TestClientMultiOpSnapIgnoreRunning(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientRefreshPlan(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"refresh": [{"name": "pc-kernel", "type": "kernel", "version": "5.15", "current-revision": "1", "revision": "2", "channel": "22/stable", "download-size": 1000, "reboot-required": true}],
+			"held": [{"name": "foo", "revision": "7", "held-by": {"system": "2099-01-01T00:00:00Z"}}],
+			"required-space": 5000,
+			"reboot-required": true
+		}
+	}`
+	plan, err := cs.cli.RefreshPlan([]string{"pc-kernel", "foo"}, &client.SnapOptions{IgnoreRunning: true})
+	c.Assert(err, check.IsNil)
+	c.Check(plan, check.DeepEquals, &client.RefreshPlan{
+		Refresh: []*client.RefreshPlanSnap{{
+			Name:            "pc-kernel",
+			Type:            snap.TypeKernel,
+			Version:         "5.15",
+			CurrentRevision: snap.R(1),
+			Revision:        snap.R(2),
+			Channel:         "22/stable",
+			DownloadSize:    1000,
+			RebootRequired:  true,
+		}},
+		Held: []*client.RefreshPlanHeldSnap{{
+			Name:     "foo",
+			Revision: snap.R(7),
+			HeldBy:   map[string]time.Time{"system": time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)},
+		}},
+		RequiredSpace:  5000,
+		RebootRequired: true,
+	})
+
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var jsonBody map[string]interface{}
+	c.Assert(json.Unmarshal(body, &jsonBody), check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action":         "refresh",
+		"snaps":          []interface{}{"pc-kernel", "foo"},
+		"ignore-running": true,
+		"plan":           true,
+	})
+}
+
 func (cs *clientSuite) TestClientMultiSnapshot(c *check.C) {
 	// Note body is essentially the same as TestClientMultiOpSnap; keep in sync
 	cs.status = 202
@@ -369,6 +417,38 @@ func (cs *clientSuite) TestClientOpInstallPathInstance(c *check.C) {
 	c.Check(id, check.Equals, "66b3")
 }
 
+func (cs *clientSuite) TestClientOpInstallPathDelta(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"change": "66b3",
+		"status-code": 202,
+		"type": "async"
+	}`
+	bodyData := []byte("delta-data")
+
+	delta := filepath.Join(c.MkDir(), "foo.xdelta3")
+	err := ioutil.WriteFile(delta, bodyData, 0644)
+	c.Assert(err, check.IsNil)
+
+	id, err := cs.cli.InstallPath(delta, "foo", &client.SnapOptions{
+		DeltaFrom:       "x1",
+		DeltaAssertions: []byte("type: snap-revision\n"),
+	})
+	c.Assert(err, check.IsNil)
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+
+	c.Assert(string(body), check.Matches, "(?s).*\r\ndelta-data\r\n.*")
+	c.Assert(string(body), check.Matches, "(?s).*Content-Disposition: form-data; name=\"assertion\"\r\n\r\ntype: snap-revision\n\r\n.*")
+	c.Assert(string(body), check.Matches, "(?s).*Content-Disposition: form-data; name=\"name\"\r\n\r\nfoo\r\n.*")
+	c.Assert(string(body), check.Matches, "(?s).*Content-Disposition: form-data; name=\"delta-from\"\r\n\r\nx1\r\n.*")
+
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+	c.Check(id, check.Equals, "66b3")
+}
+
 func (cs *clientSuite) TestClientOpInstallPathMany(c *check.C) {
 	cs.status = 202
 	cs.rsp = `{
@@ -23,6 +23,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
 
 	"golang.org/x/xerrors"
 
@@ -47,12 +52,27 @@ type System struct {
 	Current bool `json:"current,omitempty"`
 	// Label of the recovery system
 	Label string `json:"label,omitempty"`
+	// DefaultRecoverySystem is true when the recovery system was marked as
+	// the default one
+	DefaultRecoverySystem bool `json:"default-recovery-system,omitempty"`
 	// Model information
 	Model SystemModelData `json:"model,omitempty"`
 	// Brand information
 	Brand snap.StoreAccount `json:"brand,omitempty"`
 	// Actions available for this system
 	Actions []SystemAction `json:"actions,omitempty"`
+	// DiskUsage of the recovery system, only set when requested
+	DiskUsage *SystemDiskUsage `json:"disk-usage,omitempty"`
+}
+
+// SystemDiskUsage holds the disk usage of a recovery system.
+type SystemDiskUsage struct {
+	// Size is the total size of the files used by the recovery system,
+	// including snaps shared with other recovery systems
+	Size int64 `json:"size"`
+	// Reclaimable is the size of the files which are removed together
+	// with the recovery system
+	Reclaimable int64 `json:"reclaimable"`
 }
 
 type SystemAction struct {
@@ -64,13 +84,29 @@ type SystemAction struct {
 
 // ListSystems list all systems available for seeding or recovery.
 func (client *Client) ListSystems() ([]System, error) {
+	return client.ListSystemsWithOptions(nil)
+}
+
+// ListSystemsOptions holds the options for listing systems.
+type ListSystemsOptions struct {
+	// DiskUsage requests the disk usage of each recovery system
+	DiskUsage bool
+}
+
+// ListSystemsWithOptions list all systems available for seeding or recovery,
+// using the given options.
+func (client *Client) ListSystemsWithOptions(opts *ListSystemsOptions) ([]System, error) {
 	type systemsResponse struct {
 		Systems []System `json:"systems,omitempty"`
 	}
 
 	var rsp systemsResponse
 
-	if _, err := client.doSync("GET", "/v2/systems", nil, nil, nil, &rsp); err != nil {
+	var query url.Values
+	if opts != nil && opts.DiskUsage {
+		query = url.Values{"disk-usage": []string{"true"}}
+	}
+	if _, err := client.doSync("GET", "/v2/systems", query, nil, nil, &rsp); err != nil {
 		return nil, xerrors.Errorf("cannot list recovery systems: %v", err)
 	}
 	return rsp.Systems, nil
@@ -218,6 +254,11 @@ type InstallSystemOptions struct {
 	// OnVolumes is the volume description of the volumes that the
 	// given step should operate on.
 	OnVolumes map[string]*gadget.Volume `json:"on-volumes,omitempty"`
+
+	// Passphrase is an optional user passphrase protecting the encrypted
+	// volumes instead of keys sealed to the TPM, only used with the
+	// "setup-storage-encryption" step.
+	Passphrase string `json:"passphrase,omitempty"`
 }
 
 // InstallSystem will perform the given install step for the given volumes
@@ -245,3 +286,135 @@ func (client *Client) InstallSystem(systemLabel string, opts *InstallSystemOptio
 	}
 	return chgID, nil
 }
+
+// CreateSystemOptions holds the options for creating a new recovery system.
+type CreateSystemOptions struct {
+	// SnapFiles are paths to local snap files used instead of the
+	// currently installed revisions of the respective snaps
+	SnapFiles []string
+	// AssertionFiles are paths to local files with assertions required
+	// to validate the snap files. The recovery system is always created
+	// for the current model, a model assertion among them must be the
+	// current one
+	AssertionFiles []string
+	// MarkDefault makes the new recovery system the default one once it
+	// has been successfully tested
+	MarkDefault bool
+	// Dangerous allows using unasserted snap files, only supported with
+	// models of grade dangerous
+	Dangerous bool
+}
+
+// CreateSystem creates a new recovery system with the given label, the
+// system is tested by rebooting into it before it is added to the list of
+// good recovery systems.
+func (client *Client) CreateSystem(systemLabel string, opts *CreateSystemOptions) (changeID string, err error) {
+	if systemLabel == "" {
+		return "", fmt.Errorf("cannot create a recovery system with an empty label")
+	}
+	if opts == nil {
+		opts = &CreateSystemOptions{}
+	}
+
+	var files []*os.File
+	var fileFields []string
+	openAll := func(field string, paths []string) error {
+		for _, path := range paths {
+			f, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("cannot open %q: %w", path, err)
+			}
+			files = append(files, f)
+			fileFields = append(fileFields, field)
+		}
+		return nil
+	}
+	err = openAll("assertion", opts.AssertionFiles)
+	if err == nil {
+		err = openAll("snap", opts.SnapFiles)
+	}
+	if err != nil {
+		for _, f := range files {
+			f.Close()
+		}
+		return "", err
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go sendSystemFiles(systemLabel, opts, fileFields, files, pw, mw)
+
+	headers := map[string]string{
+		"Content-Type": mw.FormDataContentType(),
+	}
+
+	_, changeID, err = client.doAsyncFull("POST", "/v2/systems", nil, headers, pr, doNoTimeoutAndRetry)
+	if err != nil {
+		return "", xerrors.Errorf("cannot create recovery system %q: %v", systemLabel, err)
+	}
+	return changeID, nil
+}
+
+func sendSystemFiles(systemLabel string, opts *CreateSystemOptions, fileFields []string, files []*os.File, pw *io.PipeWriter, mw *multipart.Writer) {
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	if err := mw.WriteField("action", "create"); err != nil {
+		pw.CloseWithError(err)
+		return
+	}
+	if err := mw.WriteField("label", systemLabel); err != nil {
+		pw.CloseWithError(err)
+		return
+	}
+	fields := []field{
+		{"mark-default", opts.MarkDefault},
+		{"dangerous", opts.Dangerous},
+	}
+	if err := writeFields(mw, fields); err != nil {
+		pw.CloseWithError(err)
+		return
+	}
+
+	for i, file := range files {
+		fw, err := mw.CreateFormFile(fileFields[i], filepath.Base(file.Name()))
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(fw, file); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+	}
+
+	mw.Close()
+	pw.Close()
+}
+
+// RemoveSystem removes the recovery system with the given label, along with
+// the seed snaps no longer used by any other recovery system.
+func (client *Client) RemoveSystem(systemLabel string) (changeID string, err error) {
+	if systemLabel == "" {
+		return "", fmt.Errorf("cannot remove a recovery system with an empty label")
+	}
+
+	req := struct {
+		Action string `json:"action"`
+	}{
+		Action: "remove",
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(&req); err != nil {
+		return "", err
+	}
+	chgID, err := client.doAsync("POST", "/v2/systems/"+systemLabel, nil, nil, &body)
+	if err != nil {
+		return "", xerrors.Errorf("cannot remove recovery system %q: %v", systemLabel, err)
+	}
+	return chgID, nil
+}
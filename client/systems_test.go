@@ -22,6 +22,7 @@ package client_test
 import (
 	"encoding/json"
 	"io/ioutil"
+	"path/filepath"
 
 	"gopkg.in/check.v1"
 
@@ -411,3 +412,118 @@ func (cs *clientSuite) TestRequestSystemInstallHappy(c *check.C) {
 		},
 	})
 }
+
+func (cs *clientSuite) TestCreateSystemEmptyLabel(c *check.C) {
+	_, err := cs.cli.CreateSystem("", nil)
+	c.Assert(err, check.ErrorMatches, `cannot create a recovery system with an empty label`)
+	// no request was performed
+	c.Check(cs.req, check.IsNil)
+}
+
+func (cs *clientSuite) TestCreateSystemMissingFile(c *check.C) {
+	_, err := cs.cli.CreateSystem("1234", &client.CreateSystemOptions{
+		SnapFiles: []string{filepath.Join(c.MkDir(), "missing.snap")},
+	})
+	c.Assert(err, check.ErrorMatches, `cannot open ".*/missing.snap": .*`)
+	c.Check(cs.req, check.IsNil)
+}
+
+func (cs *clientSuite) TestCreateSystemHappy(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"change": "42"
+	}`
+	snapFile := filepath.Join(c.MkDir(), "foo_1.snap")
+	c.Assert(ioutil.WriteFile(snapFile, []byte("snap-data"), 0644), check.IsNil)
+
+	chgID, err := cs.cli.CreateSystem("1234", &client.CreateSystemOptions{
+		SnapFiles:   []string{snapFile},
+		MarkDefault: true,
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(chgID, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/systems")
+	c.Check(cs.req.Header.Get("Content-Type"), check.Matches, "multipart/form-data; boundary=.*")
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Matches, `(?s).*name="action"\r\n\r\ncreate\r\n.*name="label"\r\n\r\n1234\r\n.*name="mark-default"\r\n\r\ntrue\r\n.*name="snap"; filename="foo_1.snap".*snap-data.*`)
+}
+
+func (cs *clientSuite) TestListSystemsWithDiskUsage(c *check.C) {
+	cs.rsp = `{
+	    "type": "sync",
+	    "status-code": 200,
+	    "result": {
+	        "systems": [
+	           {
+	                "current": true,
+	                "label": "20200101",
+	                "model": {"model": "this-is-model-id", "brand-id": "brand-id-1"},
+	                "brand": {"id": "brand-id-1", "username": "brand"},
+	                "disk-usage": {"size": 2048, "reclaimable": 1024}
+	           }
+	        ]
+	    }
+	}`
+	systems, err := cs.cli.ListSystemsWithOptions(&client.ListSystemsOptions{DiskUsage: true})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/systems")
+	c.Check(cs.req.URL.Query().Get("disk-usage"), check.Equals, "true")
+	c.Check(systems, check.DeepEquals, []client.System{
+		{
+			Current: true,
+			Label:   "20200101",
+			Model: client.SystemModelData{
+				Model:   "this-is-model-id",
+				BrandID: "brand-id-1",
+			},
+			Brand: snap.StoreAccount{
+				ID:       "brand-id-1",
+				Username: "brand",
+			},
+			DiskUsage: &client.SystemDiskUsage{Size: 2048, Reclaimable: 1024},
+		},
+	})
+}
+
+func (cs *clientSuite) TestRemoveSystemEmptyLabel(c *check.C) {
+	_, err := cs.cli.RemoveSystem("")
+	c.Assert(err, check.ErrorMatches, "cannot remove a recovery system with an empty label")
+}
+
+func (cs *clientSuite) TestRemoveSystemHappy(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+	    "type": "async",
+	    "status-code": 202,
+	    "result": {},
+	    "change": "chgid"
+	}`
+	chgID, err := cs.cli.RemoveSystem("1234")
+	c.Assert(err, check.IsNil)
+	c.Check(chgID, check.Equals, "chgid")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/systems/1234")
+
+	var body map[string]interface{}
+	err = json.NewDecoder(cs.req.Body).Decode(&body)
+	c.Assert(err, check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"action": "remove",
+	})
+}
+
+func (cs *clientSuite) TestRemoveSystemError(c *check.C) {
+	cs.status = 400
+	cs.rsp = `{
+	    "type": "error",
+	    "status-code": 400,
+	    "result": {"message": "cannot remove the current recovery system"}
+	}`
+	_, err := cs.cli.RemoveSystem("1234")
+	c.Assert(err, check.ErrorMatches, `cannot remove recovery system "1234": cannot remove the current recovery system`)
+}
@@ -726,7 +726,7 @@ func (m *recoverModeStateMachine) setUnlockStateWithFallbackKey(partName string,
 
 		// figure out which key/method we used to unlock the partition
 		switch unlockRes.UnlockMethod {
-		case secboot.UnlockedWithSealedKey:
+		case secboot.UnlockedWithSealedKey, secboot.UnlockedWithPassphrase:
 			part.UnlockKey = keyFallback
 		case secboot.UnlockedWithRecoveryKey:
 			part.UnlockKey = keyRecovery
@@ -880,6 +880,7 @@ func (m *recoverModeStateMachine) unlockDataRunKey() (stateFunc, error) {
 		// recovery key after we first try the fallback object
 		AllowRecoveryKey: false,
 		WhichModel:       m.whichModel,
+		UsePassphrase:    device.HasPassphraseMarkerUnder(boot.InitramfsBootEncryptionKeyDir),
 	}
 	unlockRes, unlockErr := secbootUnlockVolumeUsingSealedKeyIfEncrypted(m.disk, "ubuntu-data", runModeKey, unlockOpts)
 	if err := m.setUnlockStateWithRunKey("ubuntu-data", unlockRes, unlockErr); err != nil {
@@ -920,6 +921,7 @@ func (m *recoverModeStateMachine) unlockDataFallbackKey() (stateFunc, error) {
 		// to unlock data
 		AllowRecoveryKey: true,
 		WhichModel:       m.whichModel,
+		UsePassphrase:    device.HasPassphraseMarkerUnder(boot.InitramfsSeedEncryptionKeyDir),
 	}
 	// TODO: this prompts for a recovery key
 	// TODO: we should somehow customize the prompt to mention what key we need
@@ -1054,6 +1056,7 @@ func (m *recoverModeStateMachine) unlockEncryptedSaveFallbackKey() (stateFunc, e
 		// to unlock save
 		AllowRecoveryKey: true,
 		WhichModel:       m.whichModel,
+		UsePassphrase:    device.HasPassphraseMarkerUnder(boot.InitramfsSeedEncryptionKeyDir),
 	}
 	saveFallbackKey := device.FallbackSaveSealedKeyUnder(boot.InitramfsSeedEncryptionKeyDir)
 	// TODO: this prompts again for a recover key, but really this is the
@@ -1694,6 +1697,8 @@ func generateMountsModeRun(mst *initramfsMountsState) error {
 	opts := &secboot.UnlockVolumeUsingSealedKeyOptions{
 		AllowRecoveryKey: true,
 		WhichModel:       mst.UnverifiedBootModel,
+		// without a TPM the key is protected by a passphrase
+		UsePassphrase: device.HasPassphraseMarkerUnder(boot.InitramfsBootEncryptionKeyDir),
 	}
 	unlockRes, err := secbootUnlockVolumeUsingSealedKeyIfEncrypted(disk, "ubuntu-data", runModeKey, opts)
 	if err != nil {
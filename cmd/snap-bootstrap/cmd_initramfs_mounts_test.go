@@ -40,6 +40,7 @@ import (
 	main "github.com/snapcore/snapd/cmd/snap-bootstrap"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/gadget/device"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/osutil/disks"
@@ -2247,8 +2248,20 @@ func (s *initramfsMountsSuite) TestInitramfsMountsRunModeWithBootedKernelPartUUI
 }
 
 func (s *initramfsMountsSuite) TestInitramfsMountsRunModeEncryptedDataHappy(c *C) {
+	s.testInitramfsMountsRunModeEncryptedDataHappy(c, false)
+}
+
+func (s *initramfsMountsSuite) TestInitramfsMountsRunModeEncryptedDataPassphraseHappy(c *C) {
+	s.testInitramfsMountsRunModeEncryptedDataHappy(c, true)
+}
+
+func (s *initramfsMountsSuite) testInitramfsMountsRunModeEncryptedDataHappy(c *C, usePassphrase bool) {
 	s.mockProcCmdlineContent(c, "snapd_recovery_mode=run")
 
+	if usePassphrase {
+		c.Assert(device.WritePassphraseMarker(boot.InitramfsBootEncryptionKeyDir), IsNil)
+	}
+
 	// ensure that we check that access to sealed keys were locked
 	sealedKeysLocked := false
 	defer main.MockSecbootLockSealedKeys(func() error {
@@ -2300,6 +2313,7 @@ func (s *initramfsMountsSuite) TestInitramfsMountsRunModeEncryptedDataHappy(c *C
 		c.Assert(name, Equals, "ubuntu-data")
 		c.Assert(sealedEncryptionKeyFile, Equals, filepath.Join(s.tmpDir, "run/mnt/ubuntu-boot/device/fde/ubuntu-data.sealed-key"))
 		c.Assert(opts.AllowRecoveryKey, Equals, true)
+		c.Check(opts.UsePassphrase, Equals, usePassphrase)
 		c.Assert(opts.WhichModel, NotNil)
 		mod, err := opts.WhichModel()
 		c.Assert(err, IsNil)
@@ -2307,6 +2321,9 @@ func (s *initramfsMountsSuite) TestInitramfsMountsRunModeEncryptedDataHappy(c *C
 
 		dataActivated = true
 		// return true because we are using an encrypted device
+		if usePassphrase {
+			return happyUnlocked("ubuntu-data", secboot.UnlockedWithPassphrase), nil
+		}
 		return happyUnlocked("ubuntu-data", secboot.UnlockedWithSealedKey), nil
 	})
 	defer restore()
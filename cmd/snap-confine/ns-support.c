@@ -64,6 +64,16 @@
  **/
 static const char *sc_ns_dir = SC_NS_DIR;
 
+/**
+ * Directory where snapd keeps descriptions of isolated network namespaces.
+ **/
+#define SC_NETNS_CONF_DIR "/var/lib/snapd/netns"
+
+/**
+ * Directory where isolated network namespaces are kept, see ip-netns(8).
+ **/
+#define SC_NETNS_DIR "/run/netns"
+
 enum {
 	HELPER_CMD_EXIT,
 	HELPER_CMD_CAPTURE_MOUNT_NS,
@@ -995,3 +1005,28 @@ bool sc_is_mount_ns_in_use(const char *snap_instance)
 	}
 	return occupied;
 }
+
+void sc_join_network_ns(const char *snap_instance)
+{
+	char conf_path[PATH_MAX] = { 0 };
+	char ns_path[PATH_MAX] = { 0 };
+	sc_must_snprintf(conf_path, sizeof conf_path, "%s/snap.%s.conf",
+			 SC_NETNS_CONF_DIR, snap_instance);
+	sc_must_snprintf(ns_path, sizeof ns_path, "%s/snap.%s", SC_NETNS_DIR,
+			 snap_instance);
+	if (access(conf_path, F_OK) != 0) {
+		if (errno == ENOENT) {
+			return;
+		}
+		die("cannot check presence of %s", conf_path);
+	}
+	int ns_fd SC_CLEANUP(sc_cleanup_close) = -1;
+	ns_fd = open(ns_path, O_RDONLY | O_CLOEXEC | O_NOFOLLOW);
+	if (ns_fd < 0) {
+		die("cannot open network namespace %s", ns_path);
+	}
+	debug("joining network namespace %s", ns_path);
+	if (setns(ns_fd, CLONE_NEWNET) < 0) {
+		die("cannot join network namespace %s", ns_path);
+	}
+}
@@ -150,4 +150,17 @@ void sc_wait_for_helper(struct sc_mount_ns *group);
 
 void sc_store_ns_info(const sc_invocation * inv);
 
+/**
+ * Join the network namespace of the given snap, if it is isolated.
+ *
+ * Snaps using the network-isolation interface have their network namespace
+ * described by snapd in /var/lib/snapd/netns/snap.$SNAP_INSTANCE_NAME.conf
+ * and kept as /run/netns/snap.$SNAP_INSTANCE_NAME. If the description exists
+ * but the namespace does not, the process dies instead of running the snap
+ * with the network of the host.
+ *
+ * This function must be called while still in the mount namespace of pid 1.
+ **/
+void sc_join_network_ns(const char *snap_instance);
+
 #endif
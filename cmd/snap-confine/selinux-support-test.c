@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+#include <selinux/context.h>
+#include <selinux/selinux.h>
+
+#include <glib.h>
+#include <stdlib.h>
+#include <string.h>
+
+/* The parts of libselinux interacting with the system are mocked. */
+static int mock_selinux_enabled;
+static const char *mock_current_con;
+static const char *mock_valid_type;
+static char *mock_exec_con;
+
+static int mock_is_selinux_enabled(void) { return mock_selinux_enabled; }
+
+static int mock_getcon(char **con) {
+    *con = strdup(mock_current_con);
+    return 0;
+}
+
+static void mock_freecon(char *con) { free(con); }
+
+static int mock_security_check_context(const char *con) {
+    if (mock_valid_type != NULL && strstr(con, mock_valid_type) != NULL) {
+        return 0;
+    }
+    return -1;
+}
+
+static int mock_setexeccon(const char *con) {
+    g_free(mock_exec_con);
+    mock_exec_con = g_strdup(con);
+    return 0;
+}
+
+#define is_selinux_enabled mock_is_selinux_enabled
+#define getcon mock_getcon
+#define freecon mock_freecon
+#define security_check_context mock_security_check_context
+#define setexeccon mock_setexeccon
+
+#include "selinux-support.h"
+#include "selinux-support.c"
+
+static void mock_reset(void) {
+    mock_selinux_enabled = 1;
+    mock_current_con = "system_u:system_r:snappy_confine_t:s0";
+    mock_valid_type = NULL;
+    g_free(mock_exec_con);
+    mock_exec_con = NULL;
+}
+
+static void test_sc_selinux_domain_type(void) {
+    char buf[PATH_MAX] = {0};
+
+    sc_selinux_domain_type("snap.foo.app", buf, sizeof buf);
+    g_assert_cmpstr(buf, ==, "snap__foo__app_t");
+    sc_selinux_domain_type("snap.foo-bar_baz.hook.post-refresh", buf, sizeof buf);
+    g_assert_cmpstr(buf, ==, "snap__foo_bar___baz__hook__post_refresh_t");
+}
+
+static void test_sc_selinux_set_snap_execcon_domain(void) {
+    mock_reset();
+    g_test_queue_destroy((GDestroyNotify)mock_reset, NULL);
+    mock_valid_type = "snap__foo__app_t";
+
+    sc_selinux_set_snap_execcon("snap.foo.app", false);
+    g_assert_cmpstr(mock_exec_con, ==, "system_u:system_r:snap__foo__app_t:s0");
+}
+
+static void test_sc_selinux_set_snap_execcon_classic(void) {
+    mock_reset();
+    g_test_queue_destroy((GDestroyNotify)mock_reset, NULL);
+    mock_valid_type = "snap__foo__app_t";
+
+    sc_selinux_set_snap_execcon("snap.foo.app", true);
+    g_assert_cmpstr(mock_exec_con, ==, "system_u:system_r:unconfined_service_t:s0");
+}
+
+static void test_sc_selinux_set_snap_execcon_no_module(void) {
+    mock_reset();
+    g_test_queue_destroy((GDestroyNotify)mock_reset, NULL);
+
+    /* the domain is not declared by any loaded policy module */
+    sc_selinux_set_snap_execcon("snap.foo.app", false);
+    g_assert_cmpstr(mock_exec_con, ==, "system_u:system_r:unconfined_service_t:s0");
+}
+
+static void test_sc_selinux_set_snap_execcon_not_snappy_confine(void) {
+    mock_reset();
+    g_test_queue_destroy((GDestroyNotify)mock_reset, NULL);
+    mock_current_con = "unconfined_u:unconfined_r:unconfined_t:s0";
+
+    sc_selinux_set_snap_execcon("snap.foo.app", false);
+    g_assert_null(mock_exec_con);
+}
+
+static void test_sc_selinux_set_snap_execcon_disabled(void) {
+    mock_reset();
+    g_test_queue_destroy((GDestroyNotify)mock_reset, NULL);
+    mock_selinux_enabled = 0;
+
+    sc_selinux_set_snap_execcon("snap.foo.app", false);
+    g_assert_null(mock_exec_con);
+}
+
+static void __attribute__((constructor)) init(void) {
+    g_test_add_func("/selinux/domain_type", test_sc_selinux_domain_type);
+    g_test_add_func("/selinux/set_snap_execcon/domain", test_sc_selinux_set_snap_execcon_domain);
+    g_test_add_func("/selinux/set_snap_execcon/classic", test_sc_selinux_set_snap_execcon_classic);
+    g_test_add_func("/selinux/set_snap_execcon/no_module", test_sc_selinux_set_snap_execcon_no_module);
+    g_test_add_func("/selinux/set_snap_execcon/not_snappy_confine",
+                    test_sc_selinux_set_snap_execcon_not_snappy_confine);
+    g_test_add_func("/selinux/set_snap_execcon/disabled", test_sc_selinux_set_snap_execcon_disabled);
+}
@@ -17,6 +17,8 @@
 #include "selinux-support.h"
 #include "config.h"
 
+#include <limits.h>
+
 #include <selinux/context.h>
 #include <selinux/selinux.h>
 
@@ -38,12 +40,41 @@ static void sc_context_free(context_t *ctx) {
     }
 }
 
+/**
+ * Compute the SELinux domain type of the given security tag.
+ *
+ * This mirrors DomainType() from interfaces/selinux in snapd: "_" becomes
+ * "___", "." becomes "__", "-" becomes "_" and the "_t" suffix is appended.
+ **/
+static void sc_selinux_domain_type(const char *security_tag, char *buf, size_t buf_size) {
+    sc_must_snprintf(buf, buf_size, "%s", "");
+    for (const char *p = security_tag; *p != '\0'; p++) {
+        switch (*p) {
+            case '_':
+                sc_string_append(buf, buf_size, "___");
+                break;
+            case '.':
+                sc_string_append(buf, buf_size, "__");
+                break;
+            case '-':
+                sc_string_append(buf, buf_size, "_");
+                break;
+            default:
+                sc_string_append_char(buf, buf_size, *p);
+                break;
+        }
+    }
+    sc_string_append(buf, buf_size, "_t");
+}
+
 /**
  * Set security context for the snap.
  *
- * Sets up SELinux context transition to unconfined_service_t.
+ * Sets up SELinux context transition to the domain of the security tag, or
+ * to unconfined_service_t for classic snaps and when the policy module of
+ * the snap does not declare the domain.
  **/
-int sc_selinux_set_snap_execcon(void) {
+int sc_selinux_set_snap_execcon(const char *security_tag, bool classic) {
     if (is_selinux_enabled() < 1) {
         debug("SELinux not enabled");
         return 0;
@@ -67,30 +98,42 @@ int sc_selinux_set_snap_execcon(void) {
         die("cannot obtain type from SELinux context string %s", ctx_str);
     }
 
-    if (sc_streq(ctx_type, "snappy_confine_t")) {
-        /* We are running under a targeted policy which ended up transitioning
-         * to snappy_confine_t domain, at this point we are right before
-         * executing snap-exec. However we do not have a full SELinux support
-         * for services running in snaps, only the snapd bits and helpers are
-         * covered by the policy.
-         *
-         * At this point transition to the unconfined_service_t domain (allowed
-         * by snap_confine_t policy) upon the next exec() call.
-         */
+    if (!sc_streq(ctx_type, "snappy_confine_t")) {
+        return 0;
+    }
+
+    /* We are running under a targeted policy which ended up transitioning to
+     * snappy_confine_t domain, at this point we are right before executing
+     * snap-exec. Transition to the domain of the application or hook, as
+     * declared by the policy module snapd generated for the snap, upon the
+     * next exec() call. Classic snaps are not confined, neither are snaps
+     * whose module is not loaded, those transition to the
+     * unconfined_service_t domain (allowed by snappy_confine_t policy)
+     * instead. */
+    char domain[PATH_MAX] = {0};
+    sc_selinux_domain_type(security_tag, domain, sizeof domain);
+    if (context_type_set(ctx, domain) != 0) {
+        die("cannot update SELinux context %s type to %s", ctx_str, domain);
+    }
+    /* freed by context_free(ctx) */
+    const char *new_ctx_str = context_str(ctx);
+    if (new_ctx_str == NULL) {
+        die("cannot obtain updated SELinux context string");
+    }
+    if (classic || security_check_context(new_ctx_str) != 0) {
+        debug("SELinux domain %s not used", domain);
         if (context_type_set(ctx, "unconfined_service_t") != 0) {
             die("cannot update SELinux context %s type to unconfined_service_t", ctx_str);
         }
-
-        /* freed by context_free(ctx) */
-        const char *new_ctx_str = context_str(ctx);
+        new_ctx_str = context_str(ctx);
         if (new_ctx_str == NULL) {
             die("cannot obtain updated SELinux context string");
         }
-        if (setexeccon(new_ctx_str) < 0) {
-            die("cannot set SELinux exec context to %s", new_ctx_str);
-        }
-        debug("SELinux context after next exec: %s", new_ctx_str);
     }
+    if (setexeccon(new_ctx_str) < 0) {
+        die("cannot set SELinux exec context to %s", new_ctx_str);
+    }
+    debug("SELinux context after next exec: %s", new_ctx_str);
 
     return 0;
 }
@@ -17,11 +17,15 @@
 #ifndef SNAP_CONFINE_SELINUX_SUPPORT_H
 #define SNAP_CONFINE_SELINUX_SUPPORT_H
 
+#include <stdbool.h>
+
 /**
  * Set security context for the snap
  *
- * Sets up SELinux context transition to unconfined_service_t.
+ * Sets up SELinux context transition to the domain of the given security
+ * tag, or to unconfined_service_t for classic snaps and snaps without a
+ * loaded policy module.
  **/
-int sc_selinux_set_snap_execcon(void);
+int sc_selinux_set_snap_execcon(const char *security_tag, bool classic);
 
 #endif /* SNAP_CONFINE_SELINUX_SUPPORT_H */
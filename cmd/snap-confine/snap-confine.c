@@ -469,7 +469,8 @@ int main(int argc, char **argv)
 	sc_maybe_aa_change_onexec(&apparmor, invocation.security_tag);
 #ifdef HAVE_SELINUX
 	// For classic and confined snaps
-	sc_selinux_set_snap_execcon();
+	sc_selinux_set_snap_execcon(invocation.security_tag,
+				    invocation.classic_confinement);
 #endif
 	if (snap_context != NULL) {
 		setenv("SNAP_COOKIE", snap_context, 1);
@@ -650,6 +651,9 @@ static void enter_non_classic_execution_environment(sc_invocation * inv,
 	/** Conditionally create, populate and join the device cgroup. */
 	sc_setup_device_cgroup(inv->security_tag);
 
+	/** Join the network namespace of snaps isolated from the host network. */
+	sc_join_network_ns(inv->snap_instance);
+
 	/**
 	 * is_normal_mode controls if we should pivot into the base snap.
 	 *
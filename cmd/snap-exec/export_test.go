@@ -22,6 +22,7 @@ package main
 import (
 	"syscall"
 
+	"github.com/snapcore/snapd/sandbox/landlock"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -32,6 +33,8 @@ var (
 	Run              = run
 	ExecApp          = execApp
 	ExecHook         = execHook
+
+	ExpandLandlockPath = expandLandlockPath
 )
 
 func MockSyscallExec(f func(argv0 string, argv []string, envv []string) (err error)) func() {
@@ -70,3 +73,15 @@ func MockSyscallStat(f func(string, *syscall.Stat_t) (err error)) func() {
 	syscallStat = f
 	return r
 }
+
+func MockLandlockRestrictSelf(f func(rules []landlock.Rule) error) (restore func()) {
+	r := testutil.Backup(&landlockRestrictSelf)
+	landlockRestrictSelf = f
+	return r
+}
+
+func MockRuntimeLockOSThread(f func()) (restore func()) {
+	r := testutil.Backup(&runtimeLockOSThread)
+	runtimeLockOSThread = f
+	return r
+}
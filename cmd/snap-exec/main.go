@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"syscall"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/sandbox/landlock"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snapenv"
 )
@@ -39,6 +41,8 @@ import (
 var syscallExec = syscall.Exec
 var syscallStat = syscall.Stat
 var osReadlink = os.Readlink
+var landlockRestrictSelf = landlock.RestrictSelf
+var runtimeLockOSThread = runtime.LockOSThread
 
 // commandline args
 var opts struct {
@@ -247,6 +251,10 @@ func execApp(snapApp, revision, command string, args []string) error {
 
 	fullCmd = append(absoluteCommandChain(app.Snap, app.CommandChain), fullCmd...)
 
+	if err := applyLandlock(app.SecurityTag(), env); err != nil {
+		return err
+	}
+
 	logger.StartupStageTimestamp("snap-exec to app")
 	if err := syscallExec(fullCmd[0], fullCmd, env.ForExec()); err != nil {
 		return fmt.Errorf("cannot exec %q: %s", fullCmd[0], err)
@@ -285,7 +293,69 @@ func execHook(snapName, revision, hookName string) error {
 		env.ExtendWithExpanded(eenv)
 	}
 
+	if err := applyLandlock(hook.SecurityTag(), env); err != nil {
+		return err
+	}
+
 	// run the hook
 	cmd := append(absoluteCommandChain(hook.Snap, hook.CommandChain), filepath.Join(hook.Snap.HooksDir(), hook.Name))
 	return syscallExec(cmd[0], cmd, env.ForExec())
 }
+
+// applyLandlock confines the process with the landlock rules of the given
+// security tag. The rules are only written by snapd on systems where
+// AppArmor is not available.
+func applyLandlock(securityTag string, env osutil.Environment) error {
+	f, err := os.Open(filepath.Join(dirs.SnapLandlockDir, securityTag+".rules"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot open landlock rules: %v", err)
+	}
+	defer f.Close()
+
+	ruleset, err := landlock.ParseRuleset(f)
+	if err != nil {
+		return fmt.Errorf("cannot parse landlock rules of %q: %v", securityTag, err)
+	}
+	if !ruleset.Enforced() {
+		return nil
+	}
+	rules := make([]landlock.Rule, 0, len(ruleset.Rules))
+	for _, rule := range ruleset.Rules {
+		path, ok := expandLandlockPath(rule.Path, env)
+		if !ok {
+			logger.Debugf("skipping landlock rule %q with undefined variables", rule)
+			continue
+		}
+		rules = append(rules, landlock.Rule{Access: rule.Access, Path: path})
+	}
+	// landlock only restricts the calling thread, keep running on it so
+	// that it is the one executing the app or hook; the thread is never
+	// unlocked as the process is replaced or exits right after
+	runtimeLockOSThread()
+	if err := landlockRestrictSelf(rules); err != nil {
+		return fmt.Errorf("cannot apply landlock rules of %q: %v", securityTag, err)
+	}
+	return nil
+}
+
+// expandLandlockPath expands the variables in the path of a landlock rule.
+// $HOME refers to the real home directory of the user. The returned boolean
+// is false when a variable is not defined, so that the rule is not applied
+// to an unrelated path.
+func expandLandlockPath(path string, env osutil.Environment) (string, bool) {
+	defined := true
+	expanded := os.Expand(path, func(name string) string {
+		if name == "HOME" {
+			name = "SNAP_REAL_HOME"
+		}
+		value := env[name]
+		if value == "" {
+			defined = false
+		}
+		return value
+	})
+	return expanded, defined && filepath.IsAbs(expanded)
+}
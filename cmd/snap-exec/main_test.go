@@ -35,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/sandbox/landlock"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
@@ -43,14 +44,32 @@ import (
 // Hook up check.v1 into the "go test" runner
 func Test(t *testing.T) { TestingT(t) }
 
-type snapExecSuite struct{}
+type snapExecSuite struct {
+	testutil.BaseTest
+
+	threadLocked bool
+}
 
 var _ = Suite(&snapExecSuite{})
 
 func (s *snapExecSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
 	// clean previous parse runs
 	snapExec.SetOptsCommand("")
 	snapExec.SetOptsHook("")
+	// never confine the test process
+	s.AddCleanup(snapExec.MockLandlockRestrictSelf(func(rules []landlock.Rule) error {
+		c.Fatalf("unexpected call to landlock restrict self")
+		return nil
+	}))
+	s.AddCleanup(snapExec.MockRuntimeLockOSThread(func() {
+		s.threadLocked = true
+	}))
+	s.threadLocked = false
+}
+
+func (s *snapExecSuite) TearDownTest(c *C) {
+	s.BaseTest.TearDownTest(c)
 }
 
 func (s *snapExecSuite) TearDown(c *C) {
@@ -664,3 +683,128 @@ func (s *snapExecSuite) TestSnapExecCompleteClassicNoReexec(c *C) {
 	c.Check(execEnv, testutil.Contains, "SNAP_DATA=/var/snap/snapname/42")
 	c.Check(execEnv, testutil.Contains, "TMPDIR=/var/tmp99")
 }
+
+func (s *snapExecSuite) TestSnapExecAppLandlock(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("/")
+	snaptest.MockSnap(c, string(mockYaml), &snap.SideInfo{
+		Revision: snap.R("42"),
+	})
+	c.Assert(os.MkdirAll(dirs.SnapLandlockDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapLandlockDir, "snap.snapname.app.rules"), []byte(`
+rx /usr
+rw $SNAP_DATA
+rw $HOME/Documents
+rw $SNAP_UNDEFINED/foo
+`), 0644), IsNil)
+
+	os.Setenv("SNAP_DATA", "/var/snap/snapname/42")
+	defer os.Unsetenv("SNAP_DATA")
+	os.Setenv("SNAP_REAL_HOME", "/home/user")
+	defer os.Unsetenv("SNAP_REAL_HOME")
+
+	var calls [][]landlock.Rule
+	restore := snapExec.MockLandlockRestrictSelf(func(rules []landlock.Rule) error {
+		// the rules only apply to the calling thread, which must
+		// thus be the one executing the app
+		c.Check(s.threadLocked, Equals, true)
+		calls = append(calls, rules)
+		return nil
+	})
+	defer restore()
+	execCalled := 0
+	restore = snapExec.MockSyscallExec(func(argv0 string, argv []string, env []string) error {
+		// the rules are applied before executing the app
+		c.Check(calls, HasLen, 1)
+		execCalled++
+		return nil
+	})
+	defer restore()
+
+	err := snapExec.ExecApp("snapname.app", "42", "", nil)
+	c.Assert(err, IsNil)
+	c.Check(execCalled, Equals, 1)
+	rw := landlock.AccessRead | landlock.AccessWrite
+	c.Check(calls, DeepEquals, [][]landlock.Rule{{
+		{Access: landlock.AccessRead | landlock.AccessExecute, Path: "/usr"},
+		{Access: rw, Path: "/var/snap/snapname/42"},
+		{Access: rw, Path: "/home/user/Documents"},
+	}})
+}
+
+func (s *snapExecSuite) TestSnapExecHookLandlock(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("/")
+	snaptest.MockSnap(c, string(mockHookYaml), &snap.SideInfo{
+		Revision: snap.R("42"),
+	})
+	c.Assert(os.MkdirAll(dirs.SnapLandlockDir, 0755), IsNil)
+	rulesFile := filepath.Join(dirs.SnapLandlockDir, "snap.snapname.hook.configure.rules")
+	c.Assert(ioutil.WriteFile(rulesFile, []byte("r /etc\n"), 0644), IsNil)
+
+	var calls [][]landlock.Rule
+	restore := snapExec.MockLandlockRestrictSelf(func(rules []landlock.Rule) error {
+		c.Check(s.threadLocked, Equals, true)
+		calls = append(calls, rules)
+		return nil
+	})
+	defer restore()
+	restore = snapExec.MockSyscallExec(func(argv0 string, argv []string, env []string) error {
+		return nil
+	})
+	defer restore()
+
+	err := snapExec.ExecHook("snapname", "42", "configure")
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, [][]landlock.Rule{{
+		{Access: landlock.AccessRead, Path: "/etc"},
+	}})
+
+	// not applied in devmode or for classic snaps
+	calls = nil
+	for _, directive := range []string{"@complain", "@unrestricted"} {
+		c.Assert(ioutil.WriteFile(rulesFile, []byte(directive+"\nr /etc\n"), 0644), IsNil)
+		err := snapExec.ExecHook("snapname", "42", "configure")
+		c.Assert(err, IsNil)
+	}
+	c.Check(calls, HasLen, 0)
+
+	// errors are reported
+	restore = snapExec.MockLandlockRestrictSelf(func(rules []landlock.Rule) error {
+		return fmt.Errorf("boom")
+	})
+	defer restore()
+	c.Assert(ioutil.WriteFile(rulesFile, []byte("r /etc\n"), 0644), IsNil)
+	err = snapExec.ExecHook("snapname", "42", "configure")
+	c.Assert(err, ErrorMatches, `cannot apply landlock rules of "snap.snapname.hook.configure": boom`)
+
+	c.Assert(ioutil.WriteFile(rulesFile, []byte("bad\n"), 0644), IsNil)
+	err = snapExec.ExecHook("snapname", "42", "configure")
+	c.Assert(err, ErrorMatches, `cannot parse landlock rules of "snap.snapname.hook.configure": line 1: .*`)
+}
+
+func (s *snapExecSuite) TestExpandLandlockPath(c *C) {
+	env := osutil.Environment{
+		"HOME":           "/home/user/snap/foo/1",
+		"SNAP_REAL_HOME": "/home/user",
+		"SNAP_DATA":      "/var/snap/foo/1",
+		"RELATIVE":       "foo",
+	}
+	for _, tc := range []struct {
+		in, out string
+		ok      bool
+	}{
+		{"/etc", "/etc", true},
+		{"$HOME", "/home/user", true},
+		{"$HOME/.config/foo", "/home/user/.config/foo", true},
+		{"$SNAP_DATA/x", "/var/snap/foo/1/x", true},
+		{"$SNAP_COMMON", "", false},
+		{"$RELATIVE/bar", "foo/bar", false},
+	} {
+		out, ok := snapExec.ExpandLandlockPath(tc.in, env)
+		c.Check(ok, Equals, tc.ok, Commentf("%q", tc.in))
+		if tc.ok {
+			c.Check(out, Equals, tc.out, Commentf("%q", tc.in))
+		}
+	}
+}
@@ -21,6 +21,7 @@ package main
 import (
 	"io"
 
+	"github.com/snapcore/snapd/secboot/keymgr"
 	"github.com/snapcore/snapd/secboot/keys"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -68,3 +69,39 @@ func MockOsStdin(r io.Reader) (restore func()) {
 	osStdin = r
 	return restore
 }
+
+func MockAddNamedRecoveryKeyToLUKS(f func(name string, recoveryKey keys.RecoveryKey, dev string) error) (restore func()) {
+	restore = testutil.Backup(&keymgrAddNamedRecoveryKeyToLUKSDevice)
+	keymgrAddNamedRecoveryKeyToLUKSDevice = f
+	return restore
+}
+
+func MockAddNamedRecoveryKeyToLUKSUsingKey(f func(name string, recoveryKey keys.RecoveryKey, key keys.EncryptionKey, dev string) error) (restore func()) {
+	restore = testutil.Backup(&keymgrAddNamedRecoveryKeyToLUKSDeviceUsingKey)
+	keymgrAddNamedRecoveryKeyToLUKSDeviceUsingKey = f
+	return restore
+}
+
+func MockRemoveNamedRecoveryKeyFromLUKS(f func(name string, dev string) error) (restore func()) {
+	restore = testutil.Backup(&keymgrRemoveNamedRecoveryKeyFromLUKSDevice)
+	keymgrRemoveNamedRecoveryKeyFromLUKSDevice = f
+	return restore
+}
+
+func MockRemoveNamedRecoveryKeyFromLUKSUsingKey(f func(name string, key keys.EncryptionKey, dev string) error) (restore func()) {
+	restore = testutil.Backup(&keymgrRemoveNamedRecoveryKeyFromLUKSDeviceUsingKey)
+	keymgrRemoveNamedRecoveryKeyFromLUKSDeviceUsingKey = f
+	return restore
+}
+
+func MockListRecoveryKeysOnLUKS(f func(dev string) ([]keymgr.NamedRecoveryKey, error)) (restore func()) {
+	restore = testutil.Backup(&keymgrListRecoveryKeysOnLUKSDevice)
+	keymgrListRecoveryKeysOnLUKSDevice = f
+	return restore
+}
+
+func MockOsStdout(w io.Writer) (restore func()) {
+	restore = testutil.Backup(&osStdout)
+	osStdout = w
+	return restore
+}
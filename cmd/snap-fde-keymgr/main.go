@@ -34,7 +34,10 @@ import (
 	"github.com/snapcore/snapd/secboot/keys"
 )
 
-var osStdin io.Reader = os.Stdin
+var (
+	osStdin  io.Reader = os.Stdin
+	osStdout io.Writer = os.Stdout
+)
 
 type commonMultiDeviceMixin struct {
 	Devices        []string `long:"devices" description:"encrypted devices (can be more than one)" required:"yes"`
@@ -44,11 +47,17 @@ type commonMultiDeviceMixin struct {
 type cmdAddRecoveryKey struct {
 	commonMultiDeviceMixin
 	KeyFile string `long:"key-file" description:"path for generated recovery key file" required:"yes"`
+	Name    string `long:"name" description:"name of the recovery key, the default one when unset"`
 }
 
 type cmdRemoveRecoveryKey struct {
 	commonMultiDeviceMixin
-	KeyFiles []string `long:"key-files" description:"path to recovery key files to be removed" required:"yes"`
+	KeyFiles []string `long:"key-files" description:"path to recovery key files to be removed"`
+	Name     string   `long:"name" description:"name of the recovery key, the default one when unset"`
+}
+
+type cmdListRecoveryKeys struct {
+	Devices []string `long:"devices" description:"encrypted devices (can be more than one)" required:"yes"`
 }
 
 type cmdChangeEncryptionKey struct {
@@ -60,6 +69,7 @@ type cmdChangeEncryptionKey struct {
 type options struct {
 	CmdAddRecoveryKey      cmdAddRecoveryKey      `command:"add-recovery-key"`
 	CmdRemoveRecoveryKey   cmdRemoveRecoveryKey   `command:"remove-recovery-key"`
+	CmdListRecoveryKeys    cmdListRecoveryKeys    `command:"list-recovery-keys"`
 	CmdChangeEncryptionKey cmdChangeEncryptionKey `command:"change-encryption-key"`
 }
 
@@ -70,8 +80,20 @@ var (
 	keymgrRemoveRecoveryKeyFromLUKSDeviceUsingKey = keymgr.RemoveRecoveryKeyFromLUKSDeviceUsingKey
 	keymgrStageLUKSDeviceEncryptionKeyChange      = keymgr.StageLUKSDeviceEncryptionKeyChange
 	keymgrTransitionLUKSDeviceEncryptionKeyChange = keymgr.TransitionLUKSDeviceEncryptionKeyChange
+
+	keymgrAddNamedRecoveryKeyToLUKSDevice              = keymgr.AddNamedRecoveryKeyToLUKSDevice
+	keymgrAddNamedRecoveryKeyToLUKSDeviceUsingKey      = keymgr.AddNamedRecoveryKeyToLUKSDeviceUsingKey
+	keymgrRemoveNamedRecoveryKeyFromLUKSDevice         = keymgr.RemoveNamedRecoveryKeyFromLUKSDevice
+	keymgrRemoveNamedRecoveryKeyFromLUKSDeviceUsingKey = keymgr.RemoveNamedRecoveryKeyFromLUKSDeviceUsingKey
+	keymgrListRecoveryKeysOnLUKSDevice                 = keymgr.ListRecoveryKeysOnLUKSDevice
 )
 
+// isDefaultRecoveryKey returns true if the name refers to the recovery key
+// kept in its own key slot.
+func isDefaultRecoveryKey(name string) bool {
+	return name == "" || name == keys.DefaultRecoveryKeyName
+}
+
 func validateAuthorizations(authorizations []string) error {
 	for _, authz := range authorizations {
 		switch {
@@ -106,6 +128,11 @@ func writeIfNotExists(p string, data []byte) (alreadyExists bool, err error) {
 }
 
 func (c *cmdAddRecoveryKey) Execute(args []string) error {
+	if !isDefaultRecoveryKey(c.Name) {
+		if err := keys.ValidateRecoveryKeyName(c.Name); err != nil {
+			return fmt.Errorf("cannot add recovery keys: %v", err)
+		}
+	}
 	recoveryKey, err := keys.NewRecoveryKey()
 	if err != nil {
 		return fmt.Errorf("cannot create recovery key: %v", err)
@@ -135,16 +162,31 @@ func (c *cmdAddRecoveryKey) Execute(args []string) error {
 		}
 		copy(recoveryKey[:], maybeKey[:])
 	}
-	// add the recovery key to each device; keys are always added to the
-	// same keyslot, so when the key existed on disk, assume that the key
-	// was already added to the device in case we hit an error with keyslot
-	// being already used
+	addToLUKS := func(dev string) error {
+		return keymgrAddRecoveryKeyToLUKSDevice(recoveryKey, dev)
+	}
+	addToLUKSUsingKey := func(authzKey keys.EncryptionKey, dev string) error {
+		return keymgrAddRecoveryKeyToLUKSDeviceUsingKey(recoveryKey, authzKey, dev)
+	}
+	if !isDefaultRecoveryKey(c.Name) {
+		addToLUKS = func(dev string) error {
+			return keymgrAddNamedRecoveryKeyToLUKSDevice(c.Name, recoveryKey, dev)
+		}
+		addToLUKSUsingKey = func(authzKey keys.EncryptionKey, dev string) error {
+			return keymgrAddNamedRecoveryKeyToLUKSDeviceUsingKey(c.Name, recoveryKey, authzKey, dev)
+		}
+	}
+	// add the recovery key to each device; the default key is always
+	// added to the same keyslot, and named keys are recorded by name, so
+	// when the key existed on disk, assume that the key was already added
+	// to the device in case we hit an error with keyslot being already
+	// used or with the name being taken
 	for i, dev := range c.Devices {
 		authz := c.Authorizations[i]
 		switch {
 		case authz == "keyring":
-			if err := keymgrAddRecoveryKeyToLUKSDevice(recoveryKey, dev); err != nil {
-				if !alreadyExists || !keymgr.IsKeyslotAlreadyUsed(err) {
+			if err := addToLUKS(dev); err != nil {
+				if !alreadyExists || !isRecoveryKeyAlreadyAdded(err) {
 					return fmt.Errorf("cannot add recovery key to LUKS device: %v", err)
 				}
 			}
@@ -153,8 +195,8 @@ func (c *cmdAddRecoveryKey) Execute(args []string) error {
 			if err != nil {
 				return fmt.Errorf("cannot load authorization key: %v", err)
 			}
-			if err := keymgrAddRecoveryKeyToLUKSDeviceUsingKey(recoveryKey, authzKey, dev); err != nil {
-				if !alreadyExists || !keymgr.IsKeyslotAlreadyUsed(err) {
+			if err := addToLUKSUsingKey(authzKey, dev); err != nil {
+				if !alreadyExists || !isRecoveryKeyAlreadyAdded(err) {
 					return fmt.Errorf("cannot add recovery key to LUKS device using authorization key: %v", err)
 				}
 			}
@@ -163,6 +205,10 @@ func (c *cmdAddRecoveryKey) Execute(args []string) error {
 	return nil
 }
 
+func isRecoveryKeyAlreadyAdded(err error) bool {
+	return keymgr.IsKeyslotAlreadyUsed(err) || err == keymgr.ErrRecoveryKeyExists
+}
+
 func (c *cmdRemoveRecoveryKey) Execute(args []string) error {
 	if len(c.Authorizations) != len(c.Devices) {
 		return fmt.Errorf("cannot remove recovery keys: mismatch in the number of devices and authorizations")
@@ -170,11 +216,21 @@ func (c *cmdRemoveRecoveryKey) Execute(args []string) error {
 	if err := validateAuthorizations(c.Authorizations); err != nil {
 		return fmt.Errorf("cannot remove recovery keys with invalid authorizations: %v", err)
 	}
+	removeFromLUKS := keymgrRemoveRecoveryKeyFromLUKSDevice
+	removeFromLUKSUsingKey := keymgrRemoveRecoveryKeyFromLUKSDeviceUsingKey
+	if !isDefaultRecoveryKey(c.Name) {
+		removeFromLUKS = func(dev string) error {
+			return keymgrRemoveNamedRecoveryKeyFromLUKSDevice(c.Name, dev)
+		}
+		removeFromLUKSUsingKey = func(authzKey keys.EncryptionKey, dev string) error {
+			return keymgrRemoveNamedRecoveryKeyFromLUKSDeviceUsingKey(c.Name, authzKey, dev)
+		}
+	}
 	for i, dev := range c.Devices {
 		authz := c.Authorizations[i]
 		switch {
 		case authz == "keyring":
-			if err := keymgrRemoveRecoveryKeyFromLUKSDevice(dev); err != nil {
+			if err := removeFromLUKS(dev); err != nil {
 				return fmt.Errorf("cannot remove recovery key from LUKS device: %v", err)
 			}
 		case strings.HasPrefix(authz, "file:"):
@@ -182,7 +238,7 @@ func (c *cmdRemoveRecoveryKey) Execute(args []string) error {
 			if err != nil {
 				return fmt.Errorf("cannot load authorization key: %v", err)
 			}
-			if err := keymgrRemoveRecoveryKeyFromLUKSDeviceUsingKey(authzKey, dev); err != nil {
+			if err := removeFromLUKSUsingKey(authzKey, dev); err != nil {
 				return fmt.Errorf("cannot remove recovery key from device using authorization key: %v", err)
 			}
 		}
@@ -199,6 +255,21 @@ func (c *cmdRemoveRecoveryKey) Execute(args []string) error {
 	return nil
 }
 
+func (c *cmdListRecoveryKeys) Execute(args []string) error {
+	devKeys := make(map[string][]keymgr.NamedRecoveryKey, len(c.Devices))
+	for _, dev := range c.Devices {
+		rkeys, err := keymgrListRecoveryKeysOnLUKSDevice(dev)
+		if err != nil {
+			return fmt.Errorf("cannot list recovery keys of LUKS device: %v", err)
+		}
+		devKeys[dev] = rkeys
+	}
+	if err := json.NewEncoder(osStdout).Encode(devKeys); err != nil {
+		return fmt.Errorf("cannot encode recovery keys: %v", err)
+	}
+	return nil
+}
+
 type newKey struct {
 	Key []byte `json:"key"`
 }
@@ -29,6 +29,7 @@ import (
 	. "gopkg.in/check.v1"
 
 	main "github.com/snapcore/snapd/cmd/snap-fde-keymgr"
+	"github.com/snapcore/snapd/secboot/keymgr"
 	"github.com/snapcore/snapd/secboot/keys"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -338,6 +339,120 @@ func (s *mainSuite) TestRemoveKeyRequiresAuthz(c *C) {
 	c.Assert(err, ErrorMatches, `cannot remove recovery keys with invalid authorizations: authorization file .*/authz.key does not exist`)
 }
 
+func (s *mainSuite) TestAddNamedKey(c *C) {
+	d := c.MkDir()
+	defer main.MockAddRecoveryKeyToLUKS(func(recoveryKey keys.RecoveryKey, luksDev string) error {
+		c.Errorf("unexpected call")
+		return nil
+	})()
+	var calls []string
+	var rkey keys.RecoveryKey
+	defer main.MockAddNamedRecoveryKeyToLUKS(func(name string, recoveryKey keys.RecoveryKey, luksDev string) error {
+		calls = append(calls, fmt.Sprintf("keyring:%s:%s", name, luksDev))
+		rkey = recoveryKey
+		return nil
+	})()
+	defer main.MockAddNamedRecoveryKeyToLUKSUsingKey(func(name string, recoveryKey keys.RecoveryKey, key keys.EncryptionKey, luksDev string) error {
+		calls = append(calls, fmt.Sprintf("key-%s:%s:%s", key, name, luksDev))
+		// the key was added by an earlier interrupted attempt
+		return keymgr.ErrRecoveryKeyExists
+	})()
+	c.Assert(ioutil.WriteFile(filepath.Join(d, "authz.key"), []byte("authz"), 0644), IsNil)
+	// the key file exists, as if written by an interrupted attempt
+	existing := keys.RecoveryKey{'r', 'e', 'c', 'o', 'v', 'e', 'r', 'y'}
+	c.Assert(ioutil.WriteFile(filepath.Join(d, "recovery.key"), existing[:], 0600), IsNil)
+
+	err := main.Run([]string{
+		"add-recovery-key",
+		"--name", "usb-stick",
+		"--devices", "/dev/vda4",
+		"--authorizations", "keyring",
+		"--devices", "/dev/vda5",
+		"--authorizations", "file:" + filepath.Join(d, "authz.key"),
+		"--key-file", filepath.Join(d, "recovery.key"),
+	})
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, []string{"keyring:usb-stick:/dev/vda4", "key-authz:usb-stick:/dev/vda5"})
+	c.Check(rkey, DeepEquals, existing)
+
+	// but the name must be unique when adding a new key
+	calls = nil
+	err = main.Run([]string{
+		"add-recovery-key",
+		"--name", "usb-stick",
+		"--devices", "/dev/vda5",
+		"--authorizations", "file:" + filepath.Join(d, "authz.key"),
+		"--key-file", filepath.Join(d, "other.key"),
+	})
+	c.Assert(err, ErrorMatches, "cannot add recovery key to LUKS device using authorization key: recovery key already exists")
+
+	err = main.Run([]string{
+		"add-recovery-key",
+		"--name", "Invalid",
+		"--devices", "/dev/vda4",
+		"--authorizations", "keyring",
+		"--key-file", filepath.Join(d, "other.key"),
+	})
+	c.Assert(err, ErrorMatches, `cannot add recovery keys: invalid recovery key name "Invalid"`)
+}
+
+func (s *mainSuite) TestRemoveNamedKey(c *C) {
+	d := c.MkDir()
+	defer main.MockRemoveRecoveryKeyFromLUKS(func(luksDev string) error {
+		c.Errorf("unexpected call")
+		return nil
+	})()
+	var calls []string
+	defer main.MockRemoveNamedRecoveryKeyFromLUKS(func(name string, luksDev string) error {
+		calls = append(calls, fmt.Sprintf("keyring:%s:%s", name, luksDev))
+		return nil
+	})()
+	defer main.MockRemoveNamedRecoveryKeyFromLUKSUsingKey(func(name string, key keys.EncryptionKey, luksDev string) error {
+		calls = append(calls, fmt.Sprintf("key-%s:%s:%s", key, name, luksDev))
+		return nil
+	})()
+	c.Assert(ioutil.WriteFile(filepath.Join(d, "authz.key"), []byte("authz"), 0644), IsNil)
+
+	err := main.Run([]string{
+		"remove-recovery-key",
+		"--name", "usb-stick",
+		"--devices", "/dev/vda4",
+		"--authorizations", "keyring",
+		"--devices", "/dev/vda5",
+		"--authorizations", "file:" + filepath.Join(d, "authz.key"),
+	})
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, []string{"keyring:usb-stick:/dev/vda4", "key-authz:usb-stick:/dev/vda5"})
+}
+
+func (s *mainSuite) TestListKeys(c *C) {
+	defer main.MockListRecoveryKeysOnLUKS(func(luksDev string) ([]keymgr.NamedRecoveryKey, error) {
+		switch luksDev {
+		case "/dev/vda4":
+			return []keymgr.NamedRecoveryKey{{Name: "default", Keyslot: 1}, {Name: "usb-stick", Keyslot: 3}}, nil
+		case "/dev/vda5":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("mock error")
+	})()
+	var out bytes.Buffer
+	defer main.MockOsStdout(&out)()
+
+	err := main.Run([]string{
+		"list-recovery-keys",
+		"--devices", "/dev/vda4",
+		"--devices", "/dev/vda5",
+	})
+	c.Assert(err, IsNil)
+	c.Check(out.String(), Equals, `{"/dev/vda4":[{"name":"default","keyslot":1},{"name":"usb-stick","keyslot":3}],"/dev/vda5":null}`+"\n")
+
+	err = main.Run([]string{
+		"list-recovery-keys",
+		"--devices", "/dev/vda6",
+	})
+	c.Assert(err, ErrorMatches, "cannot list recovery keys of LUKS device: mock error")
+}
+
 // 1 in ASCII repeated 32 times
 const all1sKey = `{"key":"MTExMTExMTExMTExMTExMTExMTExMTExMTExMTExMTE="}`
 
@@ -20,13 +20,19 @@
 package main
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
 )
 
 type cmdConnect struct {
 	waitMixin
+	For         string `long:"for" value-name:"<duration>"`
+	UntilReboot bool   `long:"until-reboot"`
 	Positionals struct {
 		PlugSpec connectPlugSpec `required:"yes"`
 		SlotSpec connectSlotSpec
@@ -52,12 +58,22 @@ $ snap connect <snap>:<plug>
 
 Connects the provided plug to the slot in the core snap with a name matching
 the plug name.
+
+With --for or --until-reboot the connection is temporary, it is removed
+automatically once the given duration has elapsed or the system has been
+rebooted, whichever comes first. Connecting a temporarily connected plug
+again replaces when the connection is removed.
 `)
 
 func init() {
 	addCommand("connect", shortConnectHelp, longConnectHelp, func() flags.Commander {
 		return &cmdConnect{}
-	}, waitDescs, []argDesc{
+	}, waitDescs.also(map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"for": i18n.G("Remove the connection automatically after the given duration"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"until-reboot": i18n.G("Remove the connection automatically once the system is rebooted"),
+	}), []argDesc{
 		// TRANSLATORS: This needs to begin with < and end with >
 		{name: i18n.G("<snap>:<plug>")},
 		// TRANSLATORS: This needs to begin with < and end with >
@@ -77,7 +93,21 @@ func (x *cmdConnect) Execute(args []string) error {
 		x.Positionals.PlugSpec.Snap = ""
 	}
 
-	id, err := x.client.Connect(x.Positionals.PlugSpec.Snap, x.Positionals.PlugSpec.Name, x.Positionals.SlotSpec.Snap, x.Positionals.SlotSpec.Name)
+	opts := &client.ConnectOptions{
+		UntilReboot: x.UntilReboot,
+	}
+	if x.For != "" {
+		dur, err := time.ParseDuration(x.For)
+		if err != nil {
+			return fmt.Errorf(i18n.G("duration must be a number of hours, minutes or seconds: %v"), err)
+		}
+		if dur < time.Second {
+			return fmt.Errorf(i18n.G("cannot connect for less than a second: %s"), x.For)
+		}
+		opts.For = dur
+	}
+
+	id, err := x.client.ConnectWithOptions(x.Positionals.PlugSpec.Snap, x.Positionals.PlugSpec.Name, x.Positionals.SlotSpec.Snap, x.Positionals.SlotSpec.Name, opts)
 	if err != nil {
 		return err
 	}
@@ -53,9 +53,18 @@ $ snap connect <snap>:<plug>
 Connects the provided plug to the slot in the core snap with a name matching
 the plug name.
 
+With --for or --until-reboot the connection is temporary, it is removed
+automatically once the given duration has elapsed or the system has been
+rebooted, whichever comes first. Connecting a temporarily connected plug
+again replaces when the connection is removed.
+
 [connect command options]
-      --no-wait          Do not wait for the operation to finish but just print
-                         the change id.
+      --no-wait             Do not wait for the operation to finish but just
+                            print the change id.
+      --for=<duration>      Remove the connection automatically after the given
+                            duration
+      --until-reboot        Remove the connection automatically once the system
+                            is rebooted
 `
 	s.testSubCommandHelp(c, "connect", msg)
 }
@@ -94,6 +103,52 @@ func (s *SnapSuite) TestConnectExplicitEverything(c *C) {
 	c.Assert(rest, DeepEquals, []string{})
 }
 
+func (s *SnapSuite) TestConnectTemporary(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/interfaces":
+			c.Check(r.Method, Equals, "POST")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"action": "connect",
+				"plugs": []interface{}{
+					map[string]interface{}{
+						"snap": "producer",
+						"plug": "plug",
+					},
+				},
+				"slots": []interface{}{
+					map[string]interface{}{
+						"snap": "",
+						"slot": "",
+					},
+				},
+				"for":          "2h0m0s",
+				"until-reboot": true,
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "zzz"}`)
+		case "/v2/changes/zzz":
+			c.Check(r.Method, Equals, "GET")
+			fmt.Fprintln(w, `{"type":"sync", "result":{"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected path %q", r.URL.Path)
+		}
+	})
+	rest, err := Parser(Client()).ParseArgs([]string{"connect", "--for", "2h", "--until-reboot", "producer:plug"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+}
+
+func (s *SnapSuite) TestConnectTemporaryInvalidDuration(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to %q", r.URL.Path)
+	})
+	_, err := Parser(Client()).ParseArgs([]string{"connect", "--for", "foo", "producer:plug"})
+	c.Assert(err, ErrorMatches, `duration must be a number of hours, minutes or seconds: .*`)
+	_, err = Parser(Client()).ParseArgs([]string{"connect", "--for", "10ms", "producer:plug"})
+	c.Assert(err, ErrorMatches, `cannot connect for less than a second: 10ms`)
+}
+
 func (s *SnapSuite) TestConnectExplicitPlugImplicitSlot(c *C) {
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -84,6 +84,7 @@ type connection struct {
 	interfaceDeterminant string
 	manual               bool
 	gadget               bool
+	temporary            bool
 }
 
 func (cn connection) String() string {
@@ -94,6 +95,9 @@ func (cn connection) String() string {
 	if cn.gadget {
 		opts = append(opts, "gadget")
 	}
+	if cn.temporary {
+		opts = append(opts, "temporary")
+	}
 	if len(opts) == 0 {
 		return "-"
 	}
@@ -169,6 +173,7 @@ func (x *cmdConnections) Execute(args []string) error {
 			slot:                 endpoint(conn.Slot.Snap, conn.Slot.Name),
 			manual:               conn.Manual,
 			gadget:               conn.Gadget,
+			temporary:            !conn.Expiry.IsZero() || conn.UntilReboot,
 			interfaceName:        conn.Interface,
 			interfaceDeterminant: interfaceDeterminant(&conn),
 		})
@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -277,6 +278,76 @@ func (s *SnapSuite) TestConnectionsSomeConnected(c *C) {
 	c.Assert(s.Stderr(), Equals, "")
 }
 
+func (s *SnapSuite) TestConnectionsTemporary(c *C) {
+	result := client.Connections{
+		Established: []client.Connection{
+			{
+				Plug:      client.PlugRef{Snap: "keyboard-lights", Name: "capslock"},
+				Slot:      client.SlotRef{Snap: "leds-provider", Name: "capslock-led"},
+				Interface: "leds",
+				Manual:    true,
+				Expiry:    time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+			}, {
+				Plug:        client.PlugRef{Snap: "keyboard-lights", Name: "numlock"},
+				Slot:        client.SlotRef{Snap: "core", Name: "numlock-led"},
+				Interface:   "leds",
+				Manual:      true,
+				UntilReboot: true,
+			}, {
+				Plug:      client.PlugRef{Snap: "keyboard-lights", Name: "scrollock"},
+				Slot:      client.SlotRef{Snap: "core", Name: "scrollock-led"},
+				Interface: "leds",
+				Manual:    true,
+			},
+		},
+		Plugs: []client.Plug{
+			{
+				Snap:      "keyboard-lights",
+				Name:      "capslock",
+				Interface: "leds",
+				Connections: []client.SlotRef{{
+					Snap: "leds-provider",
+					Name: "capslock-led",
+				}},
+			}, {
+				Snap:      "keyboard-lights",
+				Name:      "numlock",
+				Interface: "leds",
+				Connections: []client.SlotRef{{
+					Snap: "core",
+					Name: "numlock-led",
+				}},
+			}, {
+				Snap:      "keyboard-lights",
+				Name:      "scrollock",
+				Interface: "leds",
+				Connections: []client.SlotRef{{
+					Snap: "core",
+					Name: "scrollock-led",
+				}},
+			},
+		},
+	}
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type":   "sync",
+			"result": result,
+		})
+	})
+	rest, err := Parser(Client()).ParseArgs([]string{"connections"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	expectedStdout := "" +
+		"Interface  Plug                       Slot                        Notes\n" +
+		"leds       keyboard-lights:capslock   leds-provider:capslock-led  manual,temporary\n" +
+		"leds       keyboard-lights:numlock    :numlock-led                manual,temporary\n" +
+		"leds       keyboard-lights:scrollock  :scrollock-led              manual\n"
+	c.Assert(s.Stdout(), Equals, expectedStdout)
+	c.Assert(s.Stderr(), Equals, "")
+}
+
 func (s *SnapSuite) TestConnectionsSomeDisconnected(c *C) {
 	result := client.Connections{
 		Established: []client.Connection{
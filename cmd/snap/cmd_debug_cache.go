@@ -0,0 +1,166 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+type cmdDebugCache struct {
+	clientMixin
+	timeMixin
+
+	Purge bool `long:"purge"`
+
+	Positional struct {
+		Digests []string `positional-arg-name:"<digest>"`
+	} `positional-args:"yes"`
+}
+
+var cmdDebugCacheShortHelp = i18n.G("Inspect and purge the snap download cache")
+var cmdDebugCacheLongHelp = i18n.G(`
+The cache command lists the snaps in the download cache, by their
+sha3-384 digest, along with the snap revision they are when known.
+Pinned entries are revisions of installed snaps, or are linked from
+outside the cache, and are kept when purging.
+
+With --purge, the given entries are removed from the cache, or all
+entries that are not pinned if none are given. Digests can be
+abbreviated to any unique prefix.
+`)
+
+func init() {
+	addDebugCommand("cache", cmdDebugCacheShortHelp, cmdDebugCacheLongHelp, func() flags.Commander {
+		return &cmdDebugCache{}
+	}, timeDescs.also(map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"purge": i18n.G("Remove the given entries, or all entries that are not pinned, from the cache"),
+	}), nil)
+}
+
+type debugCacheEntry struct {
+	Digest   string        `json:"digest"`
+	Size     int64         `json:"size"`
+	ModTime  time.Time     `json:"mtime"`
+	Pinned   bool          `json:"pinned"`
+	Name     string        `json:"name"`
+	Revision snap.Revision `json:"revision"`
+}
+
+func (x *cmdDebugCache) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if len(x.Positional.Digests) > 0 && !x.Purge {
+		return fmt.Errorf(i18n.G("cannot use digests without --purge"))
+	}
+
+	var entries []debugCacheEntry
+	if err := x.client.DebugGet("cache", &entries, nil); err != nil {
+		return err
+	}
+	if x.Purge {
+		return x.purge(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("The download cache is empty."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Digest\tSize\tModified\tSnap\tRev\tNotes"))
+	var total int64
+	for _, entry := range entries {
+		name := entry.Name
+		if name == "" {
+			name = "-"
+		}
+		var notes []string
+		if entry.Pinned {
+			notes = append(notes, i18n.G("pinned"))
+		} else {
+			total += entry.Size
+		}
+		fmt.Fprintf(w, "%.12s\t%s\t%s\t%s\t%s\t%s\n", entry.Digest, strutil.SizeToStr(entry.Size),
+			x.fmtTime(entry.ModTime), name, revisionOrDash(entry.Revision), noteOrDash(notes))
+	}
+	w.Flush()
+	// TRANSLATORS: %s is a size, like 120MB
+	fmt.Fprintf(Stdout, i18n.G("\nPurging the cache would free %s.\n"), strutil.SizeToStr(total))
+	return nil
+}
+
+// expandDigest returns the full digest of the cache entry the given
+// prefix identifies.
+func expandDigest(entries []debugCacheEntry, prefix string) (string, error) {
+	var found string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Digest, prefix) {
+			continue
+		}
+		if found != "" {
+			return "", fmt.Errorf(i18n.G("digest prefix %q is ambiguous"), prefix)
+		}
+		found = entry.Digest
+	}
+	if found == "" {
+		return "", fmt.Errorf(i18n.G("cannot find %q in the download cache"), prefix)
+	}
+	return found, nil
+}
+
+func (x *cmdDebugCache) purge(entries []debugCacheEntry) error {
+	var params struct {
+		Digests []string `json:"digests,omitempty"`
+	}
+	for _, prefix := range x.Positional.Digests {
+		digest, err := expandDigest(entries, prefix)
+		if err != nil {
+			return err
+		}
+		params.Digests = append(params.Digests, digest)
+	}
+
+	var removed []debugCacheEntry
+	if err := x.client.Debug("purge-cache", params, &removed); err != nil {
+		return err
+	}
+	var freed int64
+	for _, entry := range removed {
+		if !entry.Pinned {
+			freed += entry.Size
+		}
+	}
+	// TRANSLATORS: %d is a number of cache entries, %s is a size, like 120MB
+	fmt.Fprintf(Stdout, i18n.NG("Removed %d entry, freeing %s.\n", "Removed %d entries, freeing %s.\n", len(removed)),
+		len(removed), strutil.SizeToStr(freed))
+	return nil
+}
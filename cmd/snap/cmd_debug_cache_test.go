@@ -0,0 +1,112 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+var debugCacheEntriesJSON = `{"type": "sync", "result": [
+  {"digest": "aaaa1111aaaa1111aaaa", "size": 1000, "mtime": "2023-01-02T15:04:05Z", "pinned": true, "name": "foo", "revision": "10"},
+  {"digest": "aaaa2222aaaa2222aaaa", "size": 2000, "mtime": "2023-01-03T15:04:05Z"}
+]}`
+
+func (s *SnapSuite) TestDebugCache(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.RawQuery, check.Equals, "aspect=cache")
+			fmt.Fprintln(w, debugCacheEntriesJSON)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "cache", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `
+Digest        Size  Modified              Snap  Rev  Notes
+aaaa1111aaaa  1kB   2023-01-02T15:04:05Z  foo   10   pinned
+aaaa2222aaaa  2kB   2023-01-03T15:04:05Z  -     -    -
+
+Purging the cache would free 2kB.
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugCachePurge(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			fmt.Fprintln(w, debugCacheEntriesJSON)
+		case 1:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			var body map[string]interface{}
+			c.Assert(json.NewDecoder(r.Body).Decode(&body), check.IsNil)
+			c.Check(body, check.DeepEquals, map[string]interface{}{
+				"action": "purge-cache",
+				"params": map[string]interface{}{"digests": []interface{}{"aaaa2222aaaa2222aaaa"}},
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": [{"digest": "aaaa2222aaaa2222aaaa", "size": 2000}]}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "cache", "--purge", "aaaa2"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, "Removed 1 entry, freeing 2kB.\n")
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestDebugCacheErrors(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		fmt.Fprintln(w, debugCacheEntriesJSON)
+	})
+
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"aaaa"}, `cannot use digests without --purge`},
+		{[]string{"--purge", "aaaa"}, `digest prefix "aaaa" is ambiguous`},
+		{[]string{"--purge", "bbbb"}, `cannot find "bbbb" in the download cache`},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(append([]string{"debug", "cache"}, t.args...))
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf(strings.Join(t.args, " ")))
+	}
+}
@@ -0,0 +1,157 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugCheckPolicy struct {
+	clientMixin
+
+	SnapDeclaration flags.Filename `long:"snap-declaration" value-name:"<file>"`
+	Model           flags.Filename `long:"model" value-name:"<file>"`
+	With            []string       `long:"with" value-name:"<snap>"`
+
+	Positionals struct {
+		Snap flags.Filename `positional-arg-name:"<snap-file>"`
+	} `positional-args:"true" required:"true"`
+}
+
+var cmdDebugCheckPolicyShortHelp = i18n.G("Check the interface policy for a snap file")
+var cmdDebugCheckPolicyLongHelp = i18n.G(`
+The check-policy command evaluates the base-declaration and snap-declaration
+rules for the given snap file without installing it. It shows whether the
+installation of each plug and slot of the snap is allowed, and whether the
+connections with the plugs and slots of the installed snaps are allowed,
+manually and automatically, along with the rule deciding each outcome.
+
+A snap-declaration or a model from local files can be used instead of the
+ones known to the system, for instance to check a draft declaration. The
+assertions are not verified.
+`)
+
+func init() {
+	addDebugCommand("check-policy", cmdDebugCheckPolicyShortHelp, cmdDebugCheckPolicyLongHelp, func() flags.Commander {
+		return &cmdDebugCheckPolicy{}
+	}, map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"snap-declaration": i18n.G("Use the snap-declaration from the given file"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"model": i18n.G("Use the model from the given file"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"with": i18n.G("Check connections only with the given installed snap (can be repeated)"),
+	}, []argDesc{{
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<snap-file>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("The snap file to check"),
+	}})
+}
+
+type policyDecision struct {
+	Allowed bool   `json:"allowed"`
+	Rule    string `json:"rule"`
+	Reason  string `json:"reason"`
+}
+
+func (d *policyDecision) String() string {
+	var s string
+	switch {
+	case d.Allowed && d.Rule != "":
+		s = fmt.Sprintf(i18n.G("allowed by %s"), d.Rule)
+	case d.Allowed:
+		s = i18n.G("allowed, no rule applies")
+	case d.Rule != "":
+		s = fmt.Sprintf(i18n.G("not allowed by %s"), d.Rule)
+	default:
+		s = i18n.G("not allowed")
+	}
+	if d.Reason != "" {
+		s += fmt.Sprintf(" (%s)", d.Reason)
+	}
+	return s
+}
+
+type policyCheckReport struct {
+	Snap         string `json:"snap"`
+	Asserted     bool   `json:"asserted"`
+	Installation []struct {
+		Plug      string `json:"plug"`
+		Slot      string `json:"slot"`
+		Interface string `json:"interface"`
+		policyDecision
+	} `json:"installation"`
+	Connections []struct {
+		Plug        string         `json:"plug"`
+		Slot        string         `json:"slot"`
+		Interface   string         `json:"interface"`
+		Connect     policyDecision `json:"connect"`
+		AutoConnect policyDecision `json:"auto-connect"`
+	} `json:"connections"`
+}
+
+func (x *cmdDebugCheckPolicy) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	opts := &client.DebugCheckPolicyOptions{
+		SnapDeclarationFile: string(x.SnapDeclaration),
+		ModelFile:           string(x.Model),
+		With:                x.With,
+	}
+	var report policyCheckReport
+	if err := x.client.DebugCheckPolicy(string(x.Positionals.Snap), opts, &report); err != nil {
+		return err
+	}
+
+	if !report.Asserted {
+		fmt.Fprintf(Stderr, i18n.G("WARNING: no snap-declaration for snap %q, only the base-declaration applies\n"), report.Snap)
+	}
+
+	fmt.Fprintf(Stdout, "%s:\n", i18n.G("installation"))
+	if len(report.Installation) == 0 {
+		fmt.Fprintf(Stdout, "  %s\n", i18n.G("no plugs or slots"))
+	}
+	for _, check := range report.Installation {
+		side, name := "plug", check.Plug
+		if check.Slot != "" {
+			side, name = "slot", check.Slot
+		}
+		fmt.Fprintf(Stdout, "  %s %s (%s): %s\n", side, name, check.Interface, &check.policyDecision)
+	}
+
+	fmt.Fprintf(Stdout, "%s:\n", i18n.G("connections"))
+	if len(report.Connections) == 0 {
+		fmt.Fprintf(Stdout, "  %s\n", i18n.G("no candidate connections"))
+	}
+	for _, check := range report.Connections {
+		fmt.Fprintf(Stdout, "  %s %s (%s):\n", check.Plug, check.Slot, check.Interface)
+		fmt.Fprintf(Stdout, "    connect: %s\n", &check.Connect)
+		fmt.Fprintf(Stdout, "    auto-connect: %s\n", &check.AutoConnect)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const mockCheckPolicyResponse = `{"type": "sync", "result": {
+  "snap": "consumer",
+  "asserted": false,
+  "installation": [
+    {"plug": "otherplug", "interface": "test2", "allowed": false,
+     "rule": "plug rule of interface \"test2\" in the base-declaration",
+     "reason": "installation denied by \"otherplug\" plug rule of interface \"test2\""},
+    {"plug": "plug", "interface": "test", "allowed": true}
+  ],
+  "connections": [
+    {"plug": "consumer:plug", "slot": "producer:slot", "interface": "test",
+     "connect": {"allowed": true, "rule": "slot rule of interface \"test\" in the base-declaration"},
+     "auto-connect": {"allowed": false, "rule": "slot rule of interface \"test\" in the base-declaration",
+                      "reason": "auto-connection denied by slot rule of interface \"test\""}}
+  ]
+}}`
+
+func (s *SnapSuite) TestDebugCheckPolicy(c *check.C) {
+	dir := c.MkDir()
+	snapFile := filepath.Join(dir, "consumer_1_all.snap")
+	c.Assert(ioutil.WriteFile(snapFile, []byte("snap-data"), 0644), check.IsNil)
+	declFile := filepath.Join(dir, "consumer.decl")
+	c.Assert(ioutil.WriteFile(declFile, []byte("decl-data"), 0644), check.IsNil)
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			form, err := r.MultipartReader()
+			c.Assert(err, check.IsNil)
+			values := make(map[string][]string)
+			for {
+				part, err := form.NextPart()
+				if err != nil {
+					break
+				}
+				data, err := ioutil.ReadAll(part)
+				c.Assert(err, check.IsNil)
+				values[part.FormName()] = append(values[part.FormName()], string(data))
+			}
+			c.Check(values, check.DeepEquals, map[string][]string{
+				"action":           {"check-policy"},
+				"with":             {"producer", "other"},
+				"snap":             {"snap-data"},
+				"snap-declaration": {"decl-data"},
+			})
+			fmt.Fprintln(w, mockCheckPolicyResponse)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "check-policy", "--snap-declaration", declFile, "--with", "producer", "--with", "other", snapFile})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `
+installation:
+  plug otherplug (test2): not allowed by plug rule of interface "test2" in the base-declaration (installation denied by "otherplug" plug rule of interface "test2")
+  plug plug (test): allowed, no rule applies
+connections:
+  consumer:plug producer:slot (test):
+    connect: allowed by slot rule of interface "test" in the base-declaration
+    auto-connect: not allowed by slot rule of interface "test" in the base-declaration (auto-connection denied by slot rule of interface "test")
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "WARNING: no snap-declaration for snap \"consumer\", only the base-declaration applies\n")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugCheckPolicyNothingToCheck(c *check.C) {
+	snapFile := filepath.Join(c.MkDir(), "foo_1_all.snap")
+	c.Assert(ioutil.WriteFile(snapFile, []byte("snap-data"), 0644), check.IsNil)
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": {"snap": "foo", "asserted": true}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "check-policy", snapFile})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `
+installation:
+  no plugs or slots
+connections:
+  no candidate connections
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugCheckPolicyMissingSnap(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "check-policy"})
+	c.Assert(err, check.ErrorMatches, "the required argument `<snap-file>` was not provided")
+}
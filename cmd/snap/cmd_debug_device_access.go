@@ -0,0 +1,124 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugDeviceAccess struct {
+	clientMixin
+
+	Positionals struct {
+		Path string `positional-arg-name:"<path>"`
+	} `positional-args:"true" required:"true"`
+}
+
+var cmdDebugDeviceAccessShortHelp = i18n.G("Show which snaps can access a device")
+var cmdDebugDeviceAccessLongHelp = i18n.G(`
+The device-access command evaluates the udev rules generated for the
+interfaces of the installed snaps against the udev information of the given
+device node or sysfs path. It lists the applications and hooks of snaps which
+are granted access to the device, along with the interface and connection
+granting it. Rules which could not be evaluated, and applications and hooks
+whose device access is not restricted at all, are listed separately.
+`)
+
+func init() {
+	addDebugCommand("device-access", cmdDebugDeviceAccessShortHelp, cmdDebugDeviceAccessLongHelp, func() flags.Commander {
+		return &cmdDebugDeviceAccess{}
+	}, nil, []argDesc{{
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<path>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("The device node or sysfs path of the device"),
+	}})
+}
+
+func (x *cmdDebugDeviceAccess) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	info, err := x.client.DeviceAccess(x.Positionals.Path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, "device: %s\n", info.DevPath)
+	if info.Subsystem != "" {
+		fmt.Fprintf(Stdout, "subsystem: %s\n", info.Subsystem)
+	}
+	if len(info.Access) == 0 {
+		fmt.Fprintf(Stderr, i18n.G("No snaps are granted access to %s.\n"), info.Path)
+	} else {
+		w := tabWriter()
+		fmt.Fprintln(w, i18n.G("Snap\tApp\tInterface\tPlug\tSlot"))
+		for _, access := range info.Access {
+			plug, slot := "-", "-"
+			if access.Plug != nil {
+				plug = access.Plug.Snap + ":" + access.Plug.Name
+			}
+			if access.Slot != nil {
+				slot = access.Slot.Snap + ":" + access.Slot.Name
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", access.Snap, appOrHook(access.App, access.Hook), access.Interface, plug, slot)
+		}
+		w.Flush()
+	}
+
+	if len(info.Unevaluated) > 0 {
+		fmt.Fprintln(Stdout)
+		fmt.Fprintln(Stdout, i18n.G("The following rules could not be evaluated and may grant access:"))
+		w := tabWriter()
+		fmt.Fprintln(w, i18n.G("Snap\tApp\tInterface\tError"))
+		for _, access := range info.Unevaluated {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", access.Snap, appOrHook(access.App, access.Hook), access.Interface, access.Error)
+		}
+		w.Flush()
+	}
+
+	if len(info.Unrestricted) > 0 {
+		fmt.Fprintln(Stdout)
+		fmt.Fprintln(Stdout, i18n.G("The following applications and hooks have unrestricted device access:"))
+		w := tabWriter()
+		fmt.Fprintln(w, i18n.G("Snap\tApp\tReason"))
+		for _, access := range info.Unrestricted {
+			reason := i18n.G("no devices tagged")
+			if access.ControlsDeviceCgroup {
+				reason = i18n.G("snap controls device cgroup")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", access.Snap, appOrHook(access.App, access.Hook), reason)
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+func appOrHook(app, hook string) string {
+	if hook != "" {
+		return "hook." + hook
+	}
+	return app
+}
@@ -0,0 +1,131 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestDebugDeviceAccess(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/interfaces/devices")
+			c.Check(r.URL.Query().Get("path"), check.Equals, "/dev/ttyUSB0")
+			fmt.Fprintln(w, `{"type": "sync", "result": {
+  "path": "/dev/ttyUSB0",
+  "devpath": "/devices/usb1/1-1/ttyUSB0/tty/ttyUSB0",
+  "subsystem": "tty",
+  "access": [
+    {"snap": "consumer", "app": "app", "interface": "serial-port",
+     "plug": {"snap": "consumer", "plug": "serial"}, "slot": {"snap": "gadget", "slot": "serial"},
+     "rule": "SUBSYSTEM==\"tty\""},
+    {"snap": "gadget", "hook": "configure", "interface": "serial-port",
+     "slot": {"snap": "gadget", "slot": "serial"},
+     "rule": "SUBSYSTEM==\"tty\""}
+  ]
+}}`)
+		default:
+			c.Fatalf("expected to get 1 request, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "device-access", "/dev/ttyUSB0"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `device: /devices/usb1/1-1/ttyUSB0/tty/ttyUSB0
+subsystem: tty
+Snap      App             Interface    Plug             Slot
+consumer  app             serial-port  consumer:serial  gadget:serial
+gadget    hook.configure  serial-port  -                gadget:serial
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugDeviceAccessNone(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": {
+  "path": "/dev/null",
+  "devpath": "/devices/virtual/mem/null",
+  "subsystem": "mem",
+  "access": []
+}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "device-access", "/dev/null"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "device: /devices/virtual/mem/null\nsubsystem: mem\n")
+	c.Check(s.Stderr(), check.Equals, "No snaps are granted access to /dev/null.\n")
+}
+
+func (s *SnapSuite) TestDebugDeviceAccessUnevaluatedAndUnrestricted(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": {
+  "path": "/dev/ttyUSB0",
+  "devpath": "/devices/usb1/1-1/ttyUSB0/tty/ttyUSB0",
+  "subsystem": "tty",
+  "access": [],
+  "unevaluated": [
+    {"snap": "consumer", "app": "app", "interface": "weird",
+     "plug": {"snap": "consumer", "plug": "weird"}, "slot": {"snap": "gadget", "slot": "weird"},
+     "rule": "PROGRAM==\"/bin/true\"", "error": "unsupported key \"PROGRAM\""}
+  ],
+  "unrestricted": [
+    {"snap": "manager", "app": "daemon", "controls-device-cgroup": true},
+    {"snap": "plain", "hook": "install"}
+  ]
+}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "device-access", "/dev/ttyUSB0"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `device: /devices/usb1/1-1/ttyUSB0/tty/ttyUSB0
+subsystem: tty
+
+The following rules could not be evaluated and may grant access:
+Snap      App  Interface  Error
+consumer  app  weird      unsupported key "PROGRAM"
+
+The following applications and hooks have unrestricted device access:
+Snap     App           Reason
+manager  daemon        snap controls device cgroup
+plain    hook.install  no devices tagged
+`)
+	c.Check(s.Stderr(), check.Equals, "No snaps are granted access to /dev/ttyUSB0.\n")
+}
+
+func (s *SnapSuite) TestDebugDeviceAccessError(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		fmt.Fprintln(w, `{"type": "error", "result": {"message": "cannot audit device access: no device found"}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "device-access", "/dev/foo"})
+	c.Assert(err, check.ErrorMatches, "cannot audit device access: no device found")
+}
@@ -0,0 +1,106 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap"
+)
+
+type cmdDebugGadgetUpdateStatus struct {
+	clientMixin
+	timeMixin
+}
+
+var cmdDebugGadgetUpdateStatusShortHelp = i18n.G("Show the progress of gadget assets updates")
+var cmdDebugGadgetUpdateStatusLongHelp = i18n.G(`
+The gadget-update-status command shows the journals of gadget assets
+updates, listing the state of each updated structure. An update that is
+in progress when no change is running was interrupted; it is resumed or
+rolled back when its change runs again.
+`)
+
+func init() {
+	addDebugCommand("gadget-update-status", cmdDebugGadgetUpdateStatusShortHelp, cmdDebugGadgetUpdateStatusLongHelp, func() flags.Commander {
+		return &cmdDebugGadgetUpdateStatus{}
+	}, timeDescs, nil)
+}
+
+type gadgetUpdateJournalEntry struct {
+	Volume    string `json:"volume"`
+	Name      string `json:"name"`
+	YamlIndex int    `json:"yaml-index"`
+	State     string `json:"state"`
+}
+
+type gadgetUpdateStatus struct {
+	Snap       string                     `json:"snap"`
+	Revision   snap.Revision              `json:"revision"`
+	Status     string                     `json:"status"`
+	Attempts   int                        `json:"attempts"`
+	Modified   time.Time                  `json:"modified"`
+	Structures []gadgetUpdateJournalEntry `json:"structures"`
+}
+
+func (x *cmdDebugGadgetUpdateStatus) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var statuses []gadgetUpdateStatus
+	if err := x.client.DebugGet("gadget-update-status", &statuses, nil); err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No gadget assets updates recorded."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Snap\tRev\tStatus\tAttempts\tModified\tStructure\tState"))
+	for _, status := range statuses {
+		if len(status.Structures) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t-\t-\n", status.Snap, status.Revision,
+				status.Status, status.Attempts, x.fmtTime(status.Modified))
+			continue
+		}
+		for i, st := range status.Structures {
+			name := st.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", st.YamlIndex)
+			}
+			structure := fmt.Sprintf("%s/%s", st.Volume, name)
+			if i == 0 {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", status.Snap, status.Revision,
+					status.Status, status.Attempts, x.fmtTime(status.Modified), structure, st.State)
+			} else {
+				fmt.Fprintf(w, "\t\t\t\t\t%s\t%s\n", structure, st.State)
+			}
+		}
+	}
+	return nil
+}
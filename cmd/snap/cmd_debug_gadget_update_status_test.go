@@ -0,0 +1,72 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestDebugGadgetUpdateStatus(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.RawQuery, check.Equals, "aspect=gadget-update-status")
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"snap": "pc", "revision": "33", "status": "done", "attempts": 1, "modified": "2023-01-02T15:04:05Z"},
+  {"snap": "pc", "revision": "34", "status": "in-progress", "attempts": 2, "modified": "2023-01-03T15:04:05Z", "structures": [
+    {"volume": "pc", "name": "mbr", "yaml-index": 0, "state": "updated"},
+    {"volume": "pc", "yaml-index": 1, "state": "updating"}
+  ]}
+]}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "gadget-update-status", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `
+Snap  Rev  Status       Attempts  Modified              Structure  State
+pc    33   done         1         2023-01-02T15:04:05Z  -          -
+pc    34   in-progress  2         2023-01-03T15:04:05Z  pc/mbr     updated
+                                                        pc/#1      updating
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugGadgetUpdateStatusEmpty(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "gadget-update-status"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No gadget assets updates recorded.\n")
+}
@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugReseal struct {
+	clientMixin
+
+	DryRun  bool `long:"dry-run"`
+	Verbose bool `long:"verbose"`
+}
+
+var cmdDebugResealShortHelp = i18n.G("Diagnose resealing of the encryption keys")
+var cmdDebugResealLongHelp = i18n.G(`
+The reseal command computes the boot chains that the encryption keys
+would be resealed to, and compares them with the boot chains the keys
+are currently sealed to. For each sealed object it shows whether a
+reseal is needed, which boot chain, boot asset, kernel or kernel
+command line differs, and the boot assets missing from the cache that
+would make resealing fail.
+
+Only --dry-run is supported, the TPM is never used.
+`)
+
+func init() {
+	addDebugCommand("reseal", cmdDebugResealShortHelp, cmdDebugResealLongHelp, func() flags.Commander {
+		return &cmdDebugReseal{}
+	}, map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"dry-run": i18n.G("Only show what resealing would do"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"verbose": i18n.G("Also show the PCR protection profile of the sealed objects"),
+	}, nil)
+}
+
+type resealProfileModel struct {
+	Model          string   `json:"model"`
+	Grade          string   `json:"grade"`
+	KernelCmdlines []string `json:"kernel-cmdlines"`
+	LoadChains     []string `json:"load-chains"`
+}
+
+type resealDryRunObject struct {
+	Name           string                `json:"name"`
+	BootChainsFile string                `json:"boot-chains-file"`
+	ResealCount    int                   `json:"reseal-count"`
+	ResealNeeded   bool                  `json:"reseal-needed"`
+	Profile        []*resealProfileModel `json:"profile"`
+	Differences    []string              `json:"differences"`
+	Problems       []string              `json:"problems"`
+}
+
+type resealDryRunReport struct {
+	SealingMethod string                `json:"sealing-method"`
+	Objects       []*resealDryRunObject `json:"objects"`
+}
+
+func printResealList(title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(Stdout, "  %s:\n", title)
+	for _, item := range items {
+		fmt.Fprintf(Stdout, "    - %s\n", item)
+	}
+}
+
+func (x *cmdDebugReseal) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if !x.DryRun {
+		return fmt.Errorf(i18n.G("only --dry-run is supported"))
+	}
+
+	var report resealDryRunReport
+	if err := x.client.DebugGet("reseal-dry-run", &report, nil); err != nil {
+		return err
+	}
+	if report.SealingMethod == "" {
+		fmt.Fprintln(Stderr, i18n.G("No sealed encryption keys."))
+		return nil
+	}
+	if len(report.Objects) == 0 {
+		fmt.Fprintf(Stderr, i18n.G("Encryption keys sealed with method %q are not resealed.\n"), report.SealingMethod)
+		return nil
+	}
+
+	w := tabWriter()
+	fmt.Fprintf(w, i18n.G("Sealing method: %s\n"), report.SealingMethod)
+	fmt.Fprintln(w, i18n.G("Object\tReseal count\tReseal needed\tBoot chains file"))
+	for _, obj := range report.Objects {
+		needed := i18n.G("no")
+		if obj.ResealNeeded {
+			needed = i18n.G("yes")
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", obj.Name, obj.ResealCount, needed, obj.BootChainsFile)
+	}
+	w.Flush()
+
+	for _, obj := range report.Objects {
+		if len(obj.Differences) == 0 && len(obj.Problems) == 0 && !x.Verbose {
+			continue
+		}
+		fmt.Fprintf(Stdout, "\n%s:\n", obj.Name)
+		printResealList(i18n.G("differences"), obj.Differences)
+		printResealList(i18n.G("problems"), obj.Problems)
+		if !x.Verbose {
+			continue
+		}
+		fmt.Fprintf(Stdout, "  %s:\n", i18n.G("profile"))
+		for _, pm := range obj.Profile {
+			fmt.Fprintf(Stdout, "    - model: %s (%s)\n", pm.Model, pm.Grade)
+			fmt.Fprintf(Stdout, "      %s:\n", i18n.G("kernel command lines"))
+			for _, cmdline := range pm.KernelCmdlines {
+				fmt.Fprintf(Stdout, "        - %s\n", cmdline)
+			}
+			fmt.Fprintf(Stdout, "      %s:\n", i18n.G("load chains"))
+			for _, chain := range pm.LoadChains {
+				fmt.Fprintf(Stdout, "        - %s\n", chain)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const mockResealDryRunResponse = `{"type": "sync", "result": {
+  "sealing-method": "tpm",
+  "objects": [
+    {"name": "run", "boot-chains-file": "/var/lib/snapd/device/fde/boot-chains", "reseal-count": 3, "reseal-needed": true,
+     "differences": ["mybrand/foo: recovery:shim -> pc-kernel (rev 2): kernel changed from pc-kernel (rev 1) to pc-kernel (rev 2)"],
+     "problems": ["boot asset run-mode:grubx64.efi@1234567890ab is missing from the boot assets cache"],
+     "profile": [{"model": "mybrand/foo", "grade": "signed", "kernel-cmdlines": ["snapd_recovery_mode=run"], "load-chains": ["recovery:shim@5ac9a5e5a2ba -> pc-kernel (rev 2)"]}]},
+    {"name": "fallback", "boot-chains-file": "/var/lib/snapd/device/fde/recovery-boot-chains", "reseal-count": 1, "reseal-needed": false,
+     "profile": [{"model": "mybrand/foo", "grade": "signed", "kernel-cmdlines": ["snapd_recovery_mode=recover"], "load-chains": ["recovery:shim@5ac9a5e5a2ba -> pc-kernel (rev 1)"]}]}
+  ]
+}}`
+
+func (s *SnapSuite) TestDebugResealDryRun(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.RawQuery, check.Equals, "aspect=reseal-dry-run")
+			fmt.Fprintln(w, mockResealDryRunResponse)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "reseal", "--dry-run"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `
+Sealing method: tpm
+Object    Reseal count  Reseal needed  Boot chains file
+run       3             yes            /var/lib/snapd/device/fde/boot-chains
+fallback  1             no             /var/lib/snapd/device/fde/recovery-boot-chains
+
+run:
+  differences:
+    - mybrand/foo: recovery:shim -> pc-kernel (rev 2): kernel changed from pc-kernel (rev 1) to pc-kernel (rev 2)
+  problems:
+    - boot asset run-mode:grubx64.efi@1234567890ab is missing from the boot assets cache
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugResealDryRunVerbose(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, mockResealDryRunResponse)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "reseal", "--dry-run", "--verbose"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `
+Sealing method: tpm
+Object    Reseal count  Reseal needed  Boot chains file
+run       3             yes            /var/lib/snapd/device/fde/boot-chains
+fallback  1             no             /var/lib/snapd/device/fde/recovery-boot-chains
+
+run:
+  differences:
+    - mybrand/foo: recovery:shim -> pc-kernel (rev 2): kernel changed from pc-kernel (rev 1) to pc-kernel (rev 2)
+  problems:
+    - boot asset run-mode:grubx64.efi@1234567890ab is missing from the boot assets cache
+  profile:
+    - model: mybrand/foo (signed)
+      kernel command lines:
+        - snapd_recovery_mode=run
+      load chains:
+        - recovery:shim@5ac9a5e5a2ba -> pc-kernel (rev 2)
+
+fallback:
+  profile:
+    - model: mybrand/foo (signed)
+      kernel command lines:
+        - snapd_recovery_mode=recover
+      load chains:
+        - recovery:shim@5ac9a5e5a2ba -> pc-kernel (rev 1)
+`[1:])
+}
+
+func (s *SnapSuite) TestDebugResealNotSealedWithTPM(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": {"sealing-method": "fde-setup-hook"}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "reseal", "--dry-run"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "Encryption keys sealed with method \"fde-setup-hook\" are not resealed.\n")
+}
+
+func (s *SnapSuite) TestDebugResealNoSealedKeys(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": {}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "reseal", "--dry-run"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stderr(), check.Equals, "No sealed encryption keys.\n")
+}
+
+func (s *SnapSuite) TestDebugResealNoDryRun(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "reseal"})
+	c.Assert(err, check.ErrorMatches, "only --dry-run is supported")
+}
@@ -0,0 +1,180 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugSandboxDenials struct {
+	clientMixin
+	timeMixin
+
+	N    string `short:"n"`
+	Snap string `long:"snap" value-name:"<snap>"`
+}
+
+var cmdDebugSandboxDenialsShortHelp = i18n.G("Show the operations of snaps denied by the sandbox")
+var cmdDebugSandboxDenialsLongHelp = i18n.G(`
+The sandbox-denials command looks for AppArmor and seccomp denials in
+the kernel audit log of the system journal, and shows which snap, app
+or hook they come from. Identical denials are shown once, along with
+how many times they happened.
+
+The interfaces which would allow the operation are suggested, along
+with whether the snap has a plug for them and whether it is connected.
+Seccomp denials only carry the number of the system call, the
+interfaces allowing that system call are suggested regardless of the
+arguments it was made with.
+`)
+
+func init() {
+	addDebugCommand("sandbox-denials", cmdDebugSandboxDenialsShortHelp, cmdDebugSandboxDenialsLongHelp, func() flags.Commander {
+		return &cmdDebugSandboxDenials{}
+	}, timeDescs.also(map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"n": i18n.G("Look only at the given number of audit log records, or 'all'"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"snap": i18n.G("Show only the denials of the given snap"),
+	}), nil)
+}
+
+type sandboxDenialSuggestion struct {
+	Interface string `json:"interface"`
+	Plug      string `json:"plug"`
+	Connected bool   `json:"connected"`
+}
+
+type sandboxDenial struct {
+	Kind  string    `json:"kind"`
+	Snap  string    `json:"snap"`
+	App   string    `json:"app"`
+	Hook  string    `json:"hook"`
+	Time  time.Time `json:"time"`
+	Count int       `json:"count"`
+
+	Operation  string `json:"operation"`
+	Name       string `json:"name"`
+	DeniedMask string `json:"denied-mask"`
+	Capability string `json:"capability"`
+	Family     string `json:"family"`
+	SockType   string `json:"sock-type"`
+	Bus        string `json:"bus"`
+	Path       string `json:"path"`
+	Interface  string `json:"interface"`
+	Member     string `json:"member"`
+
+	Syscall int    `json:"syscall"`
+	Arch    string `json:"arch"`
+
+	Suggestions []sandboxDenialSuggestion `json:"suggestions"`
+}
+
+func (d *sandboxDenial) source() string {
+	if d.Hook != "" {
+		return fmt.Sprintf(i18n.G("%s (hook %s)"), d.Snap, d.Hook)
+	}
+	return d.Snap + "." + d.App
+}
+
+func (d *sandboxDenial) description() string {
+	if d.Kind == "seccomp" {
+		return fmt.Sprintf(i18n.G("seccomp: system call %d on %s"), d.Syscall, d.Arch)
+	}
+	var what []string
+	switch {
+	case d.Capability != "":
+		what = []string{"capability", d.Capability}
+	case d.Bus != "":
+		what = []string{d.Operation, d.Bus, d.Name, d.Path, strings.Trim(d.Interface+"."+d.Member, ".")}
+	case d.Family != "":
+		what = []string{d.Operation, d.Family, d.SockType}
+	default:
+		what = []string{d.Operation, d.Name}
+		if d.DeniedMask != "" {
+			what = append(what, fmt.Sprintf("(%s)", d.DeniedMask))
+		}
+	}
+	return "apparmor: " + strings.Join(strings.Fields(strings.Join(what, " ")), " ")
+}
+
+func (x *cmdDebugSandboxDenials) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	params := make(map[string]string)
+	switch x.N {
+	case "":
+	case "all":
+		params["n"] = "-1"
+	default:
+		n, err := strconv.ParseInt(x.N, 0, 32)
+		if n < 0 || err != nil {
+			return fmt.Errorf(i18n.G("invalid argument for flag ‘-n’: expected a non-negative integer argument, or “all”."))
+		}
+		params["n"] = strconv.Itoa(int(n))
+	}
+	if x.Snap != "" {
+		params["snap"] = x.Snap
+	}
+
+	var denials []*sandboxDenial
+	if err := x.client.DebugGet("sandbox-denials", &denials, params); err != nil {
+		return err
+	}
+	if len(denials) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No sandbox denials found."))
+		return nil
+	}
+
+	for i, d := range denials {
+		if i > 0 {
+			fmt.Fprintln(Stdout)
+		}
+		fmt.Fprintf(Stdout, i18n.NG("%s, %d time, last %s:\n", "%s, %d times, last %s:\n", d.Count),
+			d.source(), d.Count, x.fmtTime(d.Time))
+		fmt.Fprintf(Stdout, "  %s\n", d.description())
+		if len(d.Suggestions) == 0 {
+			continue
+		}
+		fmt.Fprintf(Stdout, "  %s:\n", i18n.G("suggested interfaces"))
+		for _, s := range d.Suggestions {
+			var status string
+			switch {
+			case s.Plug == "":
+				status = i18n.G("no plug")
+			case s.Connected:
+				status = fmt.Sprintf(i18n.G("plug %s, connected"), s.Plug)
+			default:
+				status = fmt.Sprintf(i18n.G("plug %s, not connected"), s.Plug)
+			}
+			fmt.Fprintf(Stdout, "    - %s (%s)\n", s.Interface, status)
+		}
+	}
+	return nil
+}
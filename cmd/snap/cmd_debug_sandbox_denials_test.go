@@ -0,0 +1,122 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const mockSandboxDenialsResponse = `{"type": "sync", "result": [
+  {"kind": "apparmor", "label": "snap.producer.app", "snap": "producer", "app": "app", "pid": 3, "comm": "app",
+   "operation": "open", "name": "/etc/shadow", "requested-mask": "r", "denied-mask": "r",
+   "time": "2019-04-11T16:26:42Z", "count": 1,
+   "suggestions": [{"interface": "system-backup", "connected": false}]},
+  {"kind": "apparmor", "label": "snap.consumer.app", "snap": "consumer", "app": "app", "pid": 2, "comm": "app",
+   "operation": "open", "name": "/dev/video0", "requested-mask": "wr", "denied-mask": "wr",
+   "time": "2019-04-11T16:26:43Z", "count": 2,
+   "suggestions": [{"interface": "camera", "plug": "camera", "connected": false},
+                   {"interface": "test", "plug": "plug", "connected": true}]},
+  {"kind": "apparmor", "label": "snap.consumer.hook.configure", "snap": "consumer", "hook": "configure",
+   "operation": "capable", "capability": "sys_admin", "time": "2019-04-11T16:26:44Z", "count": 1},
+  {"kind": "apparmor", "label": "snap.consumer.app", "snap": "consumer", "app": "app",
+   "operation": "dbus_method_call", "bus": "system", "name": "org.freedesktop.NetworkManager",
+   "path": "/org/freedesktop/NetworkManager", "interface": "org.freedesktop.DBus.Properties", "member": "GetAll",
+   "time": "2019-04-11T16:26:45Z", "count": 1},
+  {"kind": "seccomp", "label": "snap.consumer.app", "snap": "consumer", "app": "app", "pid": 4,
+   "syscall": 165, "arch": "amd64", "time": "2019-04-11T16:26:46Z", "count": 1}
+]}`
+
+func (s *SnapSuite) TestDebugSandboxDenials(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.RawQuery, check.Equals, "aspect=sandbox-denials")
+			fmt.Fprintln(w, mockSandboxDenialsResponse)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-denials", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `
+producer.app, 1 time, last 2019-04-11T16:26:42Z:
+  apparmor: open /etc/shadow (r)
+  suggested interfaces:
+    - system-backup (no plug)
+
+consumer.app, 2 times, last 2019-04-11T16:26:43Z:
+  apparmor: open /dev/video0 (wr)
+  suggested interfaces:
+    - camera (plug camera, not connected)
+    - test (plug plug, connected)
+
+consumer (hook configure), 1 time, last 2019-04-11T16:26:44Z:
+  apparmor: capability sys_admin
+
+consumer.app, 1 time, last 2019-04-11T16:26:45Z:
+  apparmor: dbus_method_call system org.freedesktop.NetworkManager /org/freedesktop/NetworkManager org.freedesktop.DBus.Properties.GetAll
+
+consumer.app, 1 time, last 2019-04-11T16:26:46Z:
+  seccomp: system call 165 on amd64
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugSandboxDenialsParams(c *check.C) {
+	for _, tc := range []struct {
+		args  []string
+		query string
+	}{
+		{[]string{"-n", "10"}, "aspect=sandbox-denials&n=10"},
+		{[]string{"-n", "all"}, "aspect=sandbox-denials&n=-1"},
+		{[]string{"--snap", "consumer"}, "aspect=sandbox-denials&snap=consumer"},
+	} {
+		s.ResetStdStreams()
+		s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+			c.Check(r.URL.RawQuery, check.Equals, tc.query)
+			fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+		})
+		_, err := snap.Parser(snap.Client()).ParseArgs(append([]string{"debug", "sandbox-denials"}, tc.args...))
+		c.Assert(err, check.IsNil)
+		c.Check(s.Stdout(), check.Equals, "")
+		c.Check(s.Stderr(), check.Equals, "No sandbox denials found.\n")
+	}
+}
+
+func (s *SnapSuite) TestDebugSandboxDenialsErrors(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-denials", "-n=foo"})
+	c.Check(err, check.ErrorMatches, "invalid argument for flag ‘-n’: expected a non-negative integer argument, or “all”.")
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-denials", "extra"})
+	c.Check(err, check.ErrorMatches, "too many arguments for command")
+}
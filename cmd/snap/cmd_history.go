@@ -0,0 +1,103 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap"
+)
+
+var shortHistoryHelp = i18n.G("List the install and refresh history of a snap")
+var longHistoryHelp = i18n.G(`
+The history command displays the installs, refreshes, reverts, removals
+and refresh holds recorded for the given snap, oldest first, including what
+triggered them and whether they were undone. The history is kept after the
+snap is removed.
+`)
+
+type cmdHistory struct {
+	clientMixin
+	timeMixin
+	Positional struct {
+		Snap installedSnapName `positional-arg-name:"<snap>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func init() {
+	addCommand("history", shortHistoryHelp, longHistoryHelp,
+		func() flags.Commander { return &cmdHistory{} }, timeDescs, nil)
+}
+
+func revisionOrDash(rev snap.Revision) string {
+	if rev.Unset() {
+		return "-"
+	}
+	return rev.String()
+}
+
+func (x *cmdHistory) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	name := string(x.Positional.Snap)
+	history, err := x.client.SnapHistory(name)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("no history found"))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Time\tAction\tFrom\tTo\tChannel\tTrigger\tChange\tOutcome\tNotes"))
+	for _, entry := range history {
+		var notes []string
+		if entry.HeldBy != "" {
+			// TRANSLATORS: %s is the name of the snap (or "system") holding the refresh
+			notes = append(notes, fmt.Sprintf(i18n.G("held by %s"), entry.HeldBy))
+		}
+		if entry.HoldUntil != nil {
+			// TRANSLATORS: %s is the time until which a refresh is held
+			notes = append(notes, fmt.Sprintf(i18n.G("until %s"), x.fmtTime(*entry.HoldUntil)))
+		}
+		channel := entry.Channel
+		if channel == "" {
+			channel = "-"
+		}
+		changeID := entry.ChangeID
+		if changeID == "" {
+			changeID = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			x.fmtTime(entry.Time), entry.Action,
+			revisionOrDash(entry.FromRevision), revisionOrDash(entry.ToRevision),
+			channel, entry.Trigger, changeID, entry.Outcome, noteOrDash(notes))
+	}
+
+	return nil
+}
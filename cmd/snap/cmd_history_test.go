@@ -0,0 +1,75 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestHistory(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo/history")
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"time": "2023-04-21T01:02:03Z", "action": "install", "from-revision": "unset", "to-revision": "1", "channel": "latest/stable", "trigger": "manual", "change-id": "1", "outcome": "done"},
+  {"time": "2023-04-22T01:02:03Z", "action": "refresh", "from-revision": "1", "to-revision": "2", "channel": "latest/stable", "trigger": "auto", "change-id": "2", "outcome": "undone"},
+  {"time": "2023-04-23T01:02:03Z", "action": "hold", "from-revision": "unset", "to-revision": "unset", "trigger": "manual", "outcome": "done", "held-by": "system", "hold-until": "2023-05-23T01:02:03Z"}
+]}`)
+		default:
+			c.Fatalf("expected to get 1 request, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"history", "--abs-time", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `Time                  Action   From  To   Channel        Trigger  Change  Outcome  Notes
+2023-04-21T01:02:03Z  install  -     1    latest/stable  manual   1       done     -
+2023-04-22T01:02:03Z  refresh  1     2    latest/stable  auto     2       undone   -
+2023-04-23T01:02:03Z  hold     -     -    -              manual   -       done     held by system,until 2023-05-23T01:02:03Z
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestHistoryEmpty(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo/history")
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"history", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "no history found\n")
+}
+
+func (s *SnapSuite) TestHistoryNeedsSnap(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"history"})
+	c.Assert(err, check.ErrorMatches, "the required argument `<snap>` was not provided")
+}
@@ -48,10 +48,11 @@ When called without a system label and without a mode it will just
 trigger a regular reboot.
 
 When called without a system label but with a mode it will use the
-current system to enter the given mode.
+recovery system marked as the default one to enter the "install", "recover"
+and "factory-reset" modes, and the current system otherwise.
 
 Note that "recover", "factory-reset" and "run" modes are only available for the
-current system.
+current system, and apart from "run", for the default recovery system.
 `)
 
 func init() {
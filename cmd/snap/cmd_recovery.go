@@ -28,13 +28,28 @@ import (
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/strutil"
 )
 
 type cmdRecovery struct {
-	clientMixin
+	waitMixin
 	colorMixin
 
-	ShowKeys bool `long:"show-keys"`
+	ShowKeys bool   `long:"show-keys"`
+	Keys     string `long:"keys" choice:"add" choice:"remove" choice:"list"`
+
+	Create     string   `long:"create" value-name:"<label>"`
+	Snaps      []string `long:"snap" value-name:"<snap-file>"`
+	Assertions []string `long:"assertion" value-name:"<assertion-file>"`
+	Default    bool     `long:"default"`
+	Dangerous  bool     `long:"dangerous"`
+
+	Remove    string `long:"remove" value-name:"<label>"`
+	DiskUsage bool   `long:"disk-usage"`
+
+	Positional struct {
+		KeyName string
+	} `positional-args:"yes"`
 }
 
 var shortRecoveryHelp = i18n.G("List available recovery systems")
@@ -42,24 +57,58 @@ var longRecoveryHelp = i18n.G(`
 The recovery command lists the available recovery systems.
 
 With --show-keys it displays recovery keys that can be used to unlock the encrypted partitions if the device-specific automatic unlocking does not work.
+
+With --keys=list it lists the named recovery keys of the encrypted partitions. With --keys=add <key-name> a new recovery key with the given name is added and displayed, the key is not stored on the device so it must be noted down. With --keys=remove <key-name> the named recovery key is removed. To rotate a recovery key add a new one and then remove the old one.
+
+With --create=<label> a new recovery system with the given label is created from the current model and the installed snaps. Snap files passed with --snap are used instead of the installed revisions of the respective snaps, the assertions needed to validate them can be passed with --assertion. Only recovery systems for the current model can be created, a model assertion passed with --assertion must be the current one. The device reboots into the new recovery system to test it, and once tested the system is added to the list of recovery systems. With --default the new system is also made the default recovery system.
+
+With --remove=<label> the recovery system with the given label is removed, along with the snaps no longer used by any other recovery system. The current and the default recovery systems cannot be removed. With --disk-usage the listing includes the size of each recovery system and how much space would be reclaimed by removing it.
 `)
 
 func init() {
 	addCommand("recovery", shortRecoveryHelp, longRecoveryHelp, func() flags.Commander {
 		// XXX: if we want more/nicer details we can add `snap recovery <system>` later
 		return &cmdRecovery{}
-	}, colorDescs.also(
+	}, colorDescs.also(waitDescs).also(
 		map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"show-keys": i18n.G("Show recovery keys (if available) to unlock encrypted partitions."),
-		}), nil)
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"keys": i18n.G("Add, remove or list named recovery keys of encrypted partitions."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"create": i18n.G("Create a new recovery system with the given label."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"snap": i18n.G("Use the given snap file for the new recovery system (can be repeated)."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"assertion": i18n.G("Add assertions from the given file to validate the snap files (can be repeated)."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"default": i18n.G("Make the new recovery system the default one once it was tested."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"dangerous": i18n.G("Allow unasserted snap files, only with models of grade dangerous."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"remove": i18n.G("Remove the recovery system with the given label."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"disk-usage": i18n.G("Show the disk usage of the recovery systems."),
+		}), []argDesc{{
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<key-name>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("Recovery key name for --keys=add and --keys=remove"),
+	}})
 }
 
 func notesForSystem(sys *client.System) string {
+	var notes []string
 	if sys.Current {
-		return "current"
+		notes = append(notes, "current")
+	}
+	if sys.DefaultRecoverySystem {
+		notes = append(notes, "default-recovery")
 	}
-	return "-"
+	if len(notes) == 0 {
+		return "-"
+	}
+	return strings.Join(notes, ",")
 }
 
 func (x *cmdRecovery) showKeys(w io.Writer) error {
@@ -75,10 +124,110 @@ func (x *cmdRecovery) showKeys(w io.Writer) error {
 	return nil
 }
 
+func (x *cmdRecovery) manageKeys(w io.Writer) error {
+	name := x.Positional.KeyName
+	switch x.Keys {
+	case "list":
+		if name != "" {
+			return ErrExtraArgs
+		}
+		infos, err := x.client.ListRecoveryKeys()
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			fmt.Fprintf(Stderr, i18n.G("No recovery keys available.\n"))
+			return nil
+		}
+		fmt.Fprintf(w, i18n.G("Name\tVolumes\n"))
+		for _, info := range infos {
+			fmt.Fprintf(w, "%s\t%s\n", info.Name, strings.Join(info.Volumes, ","))
+		}
+		return nil
+	case "add":
+		if name == "" {
+			return fmt.Errorf(i18n.G("the name of the recovery key to add is required"))
+		}
+		info, err := x.client.AddRecoveryKey(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s:\t%s\n", info.Name, info.RecoveryKey)
+		return nil
+	case "remove":
+		if name == "" {
+			return fmt.Errorf(i18n.G("the name of the recovery key to remove is required"))
+		}
+		return x.client.RemoveRecoveryKey(name)
+	}
+	return fmt.Errorf("internal error: unexpected recovery keys action %q", x.Keys)
+}
+
+func (x *cmdRecovery) createSystem() error {
+	opts := &client.CreateSystemOptions{
+		SnapFiles:      x.Snaps,
+		AssertionFiles: x.Assertions,
+		MarkDefault:    x.Default,
+		Dangerous:      x.Dangerous,
+	}
+	chgID, err := x.client.CreateSystem(x.Create, opts)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(chgID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+	fmt.Fprintf(Stdout, i18n.G("Created recovery system %q\n"), x.Create)
+	return nil
+}
+
+func (x *cmdRecovery) removeSystem() error {
+	chgID, err := x.client.RemoveSystem(x.Remove)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(chgID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+	fmt.Fprintf(Stdout, i18n.G("Removed recovery system %q\n"), x.Remove)
+	return nil
+}
+
 func (x *cmdRecovery) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
 	}
+	if x.ShowKeys && x.Keys != "" {
+		return fmt.Errorf(i18n.G("cannot use --show-keys and --keys together"))
+	}
+	if x.Create != "" && (x.ShowKeys || x.Keys != "") {
+		return fmt.Errorf(i18n.G("cannot use --create together with --show-keys or --keys"))
+	}
+	if x.Remove != "" && (x.ShowKeys || x.Keys != "" || x.Create != "") {
+		return fmt.Errorf(i18n.G("cannot use --remove together with --show-keys, --keys or --create"))
+	}
+	if x.DiskUsage && (x.ShowKeys || x.Keys != "" || x.Create != "" || x.Remove != "") {
+		return fmt.Errorf(i18n.G("--disk-usage can only be used when listing recovery systems"))
+	}
+	if x.Create == "" && (len(x.Snaps) > 0 || len(x.Assertions) > 0 || x.Default || x.Dangerous) {
+		return fmt.Errorf(i18n.G("--snap, --assertion, --default and --dangerous can only be used with --create"))
+	}
+	if x.Keys == "" && x.Positional.KeyName != "" {
+		return ErrExtraArgs
+	}
+
+	if x.Create != "" {
+		return x.createSystem()
+	}
+	if x.Remove != "" {
+		return x.removeSystem()
+	}
 
 	esc := x.getEscapes()
 	w := tabWriter()
@@ -87,8 +236,11 @@ func (x *cmdRecovery) Execute(args []string) error {
 	if x.ShowKeys {
 		return x.showKeys(w)
 	}
+	if x.Keys != "" {
+		return x.manageKeys(w)
+	}
 
-	systems, err := x.client.ListSystems()
+	systems, err := x.client.ListSystemsWithOptions(&client.ListSystemsOptions{DiskUsage: x.DiskUsage})
 	if err != nil {
 		return err
 	}
@@ -97,15 +249,27 @@ func (x *cmdRecovery) Execute(args []string) error {
 		return nil
 	}
 
-	fmt.Fprintf(w, i18n.G("Label\tBrand%s\tModel\tNotes\n"), fillerPublisher(esc))
+	if x.DiskUsage {
+		fmt.Fprintf(w, i18n.G("Label\tBrand%s\tModel\tSize\tReclaimable\tNotes\n"), fillerPublisher(esc))
+	} else {
+		fmt.Fprintf(w, i18n.G("Label\tBrand%s\tModel\tNotes\n"), fillerPublisher(esc))
+	}
 	for _, sys := range systems {
 		// doing it this way because otherwise it's a sea of %s\t%s\t%s
 		line := []string{
 			sys.Label,
 			shortPublisher(esc, &sys.Brand),
 			sys.Model.Model,
-			notesForSystem(&sys),
 		}
+		if x.DiskUsage {
+			size, reclaimable := "-", "-"
+			if sys.DiskUsage != nil {
+				size = strutil.SizeToStr(sys.DiskUsage.Size)
+				reclaimable = strutil.SizeToStr(sys.DiskUsage.Reclaimable)
+			}
+			line = append(line, size, reclaimable)
+		}
+		line = append(line, notesForSystem(&sys))
 		fmt.Fprintln(w, strings.Join(line, "\t"))
 	}
 
@@ -21,7 +21,10 @@ package main_test
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"path/filepath"
 
 	. "gopkg.in/check.v1"
 
@@ -31,20 +34,65 @@ import (
 
 func (s *SnapSuite) TestRecoveryHelp(c *C) {
 	msg := `Usage:
-  snap.test recovery [recovery-OPTIONS]
+  snap.test recovery [recovery-OPTIONS] [<key-name>]
 
 The recovery command lists the available recovery systems.
 
 With --show-keys it displays recovery keys that can be used to unlock the
 encrypted partitions if the device-specific automatic unlocking does not work.
 
+With --keys=list it lists the named recovery keys of the encrypted partitions.
+With --keys=add <key-name> a new recovery key with the given name is added and
+displayed, the key is not stored on the device so it must be noted down. With
+--keys=remove <key-name> the named recovery key is removed. To rotate a
+recovery key add a new one and then remove the old one.
+
+With --create=<label> a new recovery system with the given label is created
+from the current model and the installed snaps. Snap files passed with --snap
+are used instead of the installed revisions of the respective snaps, the
+assertions needed to validate them can be passed with --assertion. Only
+recovery systems for the current model can be created, a model assertion passed
+with --assertion must be the current one. The device reboots into the new
+recovery system to test it, and once tested the system is added to the list of
+recovery systems. With --default the new system is also made the default
+recovery system.
+
+With --remove=<label> the recovery system with the given label is removed,
+along with the snaps no longer used by any other recovery system. The current
+and the default recovery systems cannot be removed. With --disk-usage the
+listing includes the size of each recovery system and how much space would be
+reclaimed by removing it.
+
 [recovery command options]
-      --color=[auto|never|always]     Use a little bit of color to highlight
-                                      some things. (default: auto)
-      --unicode=[auto|never|always]   Use a little bit of Unicode to improve
-                                      legibility. (default: auto)
-      --show-keys                     Show recovery keys (if available) to
-                                      unlock encrypted partitions.
+      --no-wait                          Do not wait for the operation to
+                                         finish but just print the change id.
+      --color=[auto|never|always]        Use a little bit of color to highlight
+                                         some things. (default: auto)
+      --unicode=[auto|never|always]      Use a little bit of Unicode to improve
+                                         legibility. (default: auto)
+      --show-keys                        Show recovery keys (if available) to
+                                         unlock encrypted partitions.
+      --keys=[add|remove|list]           Add, remove or list named recovery
+                                         keys of encrypted partitions.
+      --create=<label>                   Create a new recovery system with the
+                                         given label.
+      --snap=<snap-file>                 Use the given snap file for the new
+                                         recovery system (can be repeated).
+      --assertion=<assertion-file>       Add assertions from the given file to
+                                         validate the snap files (can be
+                                         repeated).
+      --default                          Make the new recovery system the
+                                         default one once it was tested.
+      --dangerous                        Allow unasserted snap files, only with
+                                         models of grade dangerous.
+      --remove=<label>                   Remove the recovery system with the
+                                         given label.
+      --disk-usage                       Show the disk usage of the recovery
+                                         systems.
+
+[recovery command arguments]
+  <key-name>:                            Recovery key name for --keys=add and
+                                         --keys=remove
 `
 	s.testSubCommandHelp(c, "recovery", msg)
 }
@@ -79,6 +127,7 @@ func (s *SnapSuite) TestRecovery(c *C) {
            },
            {
                 "label": "20200802",
+                "default-recovery-system": true,
                 "model": {
                     "model": "model-id-2",
                     "brand-id": "brand-id-1",
@@ -108,7 +157,7 @@ func (s *SnapSuite) TestRecovery(c *C) {
 	c.Check(s.Stdout(), Equals, `
 Label     Brand    Model       Notes
 20200101  brand-1  model-id-1  current
-20200802  brand-2  model-id-2  -
+20200802  brand-2  model-id-2  default-recovery
 `[1:])
 	c.Check(s.Stderr(), Equals, "")
 }
@@ -208,3 +257,246 @@ func (s *SnapSuite) TestRecoveryShowRecoveryKeyAloneHappy(c *C) {
 	c.Check(s.Stderr(), Equals, "")
 	c.Check(n, Equals, 1)
 }
+
+func (s *SnapSuite) TestRecoveryKeysList(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/system-recovery-keys")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"action": "list",
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": [{"name": "backup", "volumes": ["ubuntu-data"]}, {"name": "default", "volumes": ["ubuntu-data", "ubuntu-save"]}]}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--keys=list"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, `Name     Volumes
+backup   ubuntu-data
+default  ubuntu-data,ubuntu-save
+`)
+	c.Check(s.Stderr(), Equals, "")
+	c.Check(n, Equals, 1)
+}
+
+func (s *SnapSuite) TestRecoveryKeysAdd(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/system-recovery-keys")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"action": "add",
+				"name":   "backup",
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": {"name": "backup", "recovery-key": "61665-00531-54469-09783-47273-19035-40077-28287", "volumes": ["ubuntu-data", "ubuntu-save"]}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--keys=add", "backup"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, `backup:  61665-00531-54469-09783-47273-19035-40077-28287
+`)
+	c.Check(s.Stderr(), Equals, "")
+	c.Check(n, Equals, 1)
+}
+
+func (s *SnapSuite) TestRecoveryKeysRemove(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/system-recovery-keys")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"action": "remove",
+				"name":   "backup",
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--keys=remove", "backup"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "")
+	c.Check(n, Equals, 1)
+}
+
+func (s *SnapSuite) TestRecoveryKeysErrors(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"recovery", "--keys=add"}, `the name of the recovery key to add is required`},
+		{[]string{"recovery", "--keys=remove"}, `the name of the recovery key to remove is required`},
+		{[]string{"recovery", "--keys=list", "foo"}, `too many arguments for command`},
+		{[]string{"recovery", "foo"}, `too many arguments for command`},
+		{[]string{"recovery", "--show-keys", "--keys=list"}, `cannot use --show-keys and --keys together`},
+		{[]string{"recovery", "--create=1234", "--show-keys"}, `cannot use --create together with --show-keys or --keys`},
+		{[]string{"recovery", "--default"}, `--snap, --assertion, --default and --dangerous can only be used with --create`},
+		{[]string{"recovery", "--snap=foo.snap"}, `--snap, --assertion, --default and --dangerous can only be used with --create`},
+		{[]string{"recovery", "--create=1234", "foo"}, `too many arguments for command`},
+		{[]string{"recovery", "--remove=1234", "--create=4567"}, `cannot use --remove together with --show-keys, --keys or --create`},
+		{[]string{"recovery", "--remove=1234", "--keys=list"}, `cannot use --remove together with --show-keys, --keys or --create`},
+		{[]string{"recovery", "--remove=1234", "--disk-usage"}, `--disk-usage can only be used when listing recovery systems`},
+		{[]string{"recovery", "--show-keys", "--disk-usage"}, `--disk-usage can only be used when listing recovery systems`},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(tc.args)
+		c.Check(err, ErrorMatches, tc.err, Commentf("%v", tc.args))
+	}
+}
+
+func (s *SnapSuite) TestRecoveryCreate(c *C) {
+	d := c.MkDir()
+	snapFile := filepath.Join(d, "pc-kernel_1.snap")
+	c.Assert(ioutil.WriteFile(snapFile, []byte("snap-data"), 0644), IsNil)
+	assertFile := filepath.Join(d, "pc-kernel.assert")
+	c.Assert(ioutil.WriteFile(assertFile, []byte("assertion-data"), 0644), IsNil)
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/systems")
+			form, err := r.MultipartReader()
+			c.Assert(err, IsNil)
+			values := make(map[string]string)
+			files := make(map[string]string)
+			for {
+				part, err := form.NextPart()
+				if err == io.EOF {
+					break
+				}
+				c.Assert(err, IsNil)
+				data, err := ioutil.ReadAll(part)
+				c.Assert(err, IsNil)
+				if part.FileName() != "" {
+					files[part.FormName()+":"+part.FileName()] = string(data)
+				} else {
+					values[part.FormName()] = string(data)
+				}
+			}
+			c.Check(values, DeepEquals, map[string]string{
+				"action":       "create",
+				"label":        "1234",
+				"mark-default": "true",
+			})
+			c.Check(files, DeepEquals, map[string]string{
+				"snap:pc-kernel_1.snap":      "snap-data",
+				"assertion:pc-kernel.assert": "assertion-data",
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type": "async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Path, Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--create=1234", "--snap", snapFile, "--assertion", assertFile, "--default"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, "Created recovery system \"1234\"\n")
+	c.Check(s.Stderr(), Equals, "")
+	c.Check(n, Equals, 2)
+}
+
+func (s *SnapSuite) TestRecoveryRemove(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/systems/1234")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"action": "remove",
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type": "async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Path, Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--remove=1234"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, "Removed recovery system \"1234\"\n")
+	c.Check(s.Stderr(), Equals, "")
+	c.Check(n, Equals, 2)
+}
+
+func (s *SnapSuite) TestRecoveryDiskUsage(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Path, Equals, "/v2/systems")
+			c.Check(r.URL.RawQuery, Equals, "disk-usage=true")
+			fmt.Fprintln(w, `{"type": "sync", "result": {
+        "systems": [
+           {
+                "current": true,
+                "label": "20200101",
+                "model": {"model": "model-id-1", "brand-id": "brand-id-1"},
+                "brand": {"id": "brand-id-1", "username": "brand-1"},
+                "disk-usage": {"size": 1073741824, "reclaimable": 0}
+           },
+           {
+                "label": "20200802",
+                "model": {"model": "model-id-1", "brand-id": "brand-id-1"},
+                "brand": {"id": "brand-id-1", "username": "brand-1"},
+                "disk-usage": {"size": 1073741824, "reclaimable": 52428800}
+           },
+           {
+                "label": "broken",
+                "model": {"model": "model-id-1", "brand-id": "brand-id-1"},
+                "brand": {"id": "brand-id-1", "username": "brand-1"}
+           }
+        ]
+}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--disk-usage"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, `
+Label     Brand    Model       Size  Reclaimable  Notes
+20200101  brand-1  model-id-1  1GB   0B           current
+20200802  brand-1  model-id-1  1GB   52MB         -
+broken    brand-1  model-id-1  -     -            -
+`[1:])
+	c.Check(s.Stderr(), Equals, "")
+}
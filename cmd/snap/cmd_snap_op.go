@@ -22,6 +22,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -67,6 +68,12 @@ Note that a later refresh will typically undo a revision override, taking the sn
 back to the current revision of the channel it's tracking.
 
 Use --name to set the instance name when installing from snap file.
+
+Use --delta-from to install a snap reconstructed by applying the given delta
+file to that revision of an installed snap. The resulting snap must match a
+snap-revision assertion, either provided with --delta-assertions or
+previously acknowledged. The snap is found from the revision unless --name
+is given.
 `)
 
 var longRemoveHelp = i18n.G(`
@@ -105,6 +112,10 @@ requests from 'snap refresh target-snap'.
 When snaps are specified --hold is effective on both their auto-refreshes
 and general refresh requests from 'snap refresh'. However, specific snap
 requests from 'snap refresh target-snap' remain unblocked and will proceed.
+
+Plan (--plan) shows what a refresh would do, including prerequisites, held and
+blocked snaps, the required disk space and whether a reboot would be needed,
+without refreshing anything.
 `)
 
 var longTryHelp = i18n.G(`
@@ -504,6 +515,8 @@ type cmdInstall struct {
 	IgnoreRunning    bool                   `long:"ignore-running" hidden:"yes"`
 	Transaction      client.TransactionType `long:"transaction" default:"per-snap" choice:"all-snaps" choice:"per-snap"`
 	QuotaGroupName   string                 `long:"quota-group"`
+	DeltaFrom        string                 `long:"delta-from"`
+	DeltaAssertions  flags.Filename         `long:"delta-assertions"`
 	Positional       struct {
 		Snaps []remoteSnapName `positional-arg-name:"<snap>" required:"1"`
 	} `positional-args:"yes" required:"yes"`
@@ -515,7 +528,7 @@ func (x *cmdInstall) installOne(nameOrPath, desiredName string, opts *client.Sna
 	var snapName string
 	var path string
 
-	if isLocalSnap(nameOrPath) {
+	if isLocalSnap(nameOrPath) || opts.DeltaFrom != "" {
 		// don't log the request's body because the encoded snap is large.
 		x.client.SetMayLogBody(false)
 		path = nameOrPath
@@ -654,6 +667,7 @@ func (x *cmdInstall) Execute([]string) error {
 		IgnoreRunning:    x.IgnoreRunning,
 		Transaction:      x.Transaction,
 		QuotaGroupName:   x.QuotaGroupName,
+		DeltaFrom:        x.DeltaFrom,
 	}
 	x.setModes(opts)
 
@@ -664,6 +678,20 @@ func (x *cmdInstall) Execute([]string) error {
 		}
 	}
 
+	if x.DeltaFrom != "" && dangerous {
+		return errors.New(i18n.G("cannot use --dangerous with --delta-from, the resulting snap must match a snap-revision assertion"))
+	}
+	if x.DeltaAssertions != "" {
+		if x.DeltaFrom == "" {
+			return errors.New(i18n.G("cannot use --delta-assertions without --delta-from"))
+		}
+		assertions, err := ioutil.ReadFile(string(x.DeltaAssertions))
+		if err != nil {
+			return err
+		}
+		opts.DeltaAssertions = assertions
+	}
+
 	if len(names) == 1 {
 		return x.installOne(names[0], x.Name, opts)
 	}
@@ -678,6 +706,9 @@ func (x *cmdInstall) Execute([]string) error {
 	if x.Name != "" {
 		return errors.New(i18n.G("cannot use instance name when installing multiple snaps"))
 	}
+	if x.DeltaFrom != "" {
+		return errors.New(i18n.G("a single delta file must be specified when using --delta-from"))
+	}
 	return x.installMany(names, opts)
 }
 
@@ -693,6 +724,7 @@ type cmdRefresh struct {
 	Cohort           string                 `long:"cohort"`
 	LeaveCohort      bool                   `long:"leave-cohort"`
 	List             bool                   `long:"list"`
+	Plan             bool                   `long:"plan"`
 	Time             bool                   `long:"time"`
 	IgnoreValidation bool                   `long:"ignore-validation"`
 	IgnoreRunning    bool                   `long:"ignore-running" hidden:"yes"`
@@ -836,6 +868,97 @@ func (x *cmdRefresh) listRefresh() error {
 	return nil
 }
 
+func (x *cmdRefresh) showRefreshPlan(names []string) error {
+	plan, err := x.client.RefreshPlan(names, &client.SnapOptions{
+		IgnoreRunning: x.IgnoreRunning,
+	})
+	if err != nil {
+		return err
+	}
+	if len(plan.Refresh) == 0 && len(plan.Held) == 0 && len(plan.Blocked) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("All snaps up to date."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	if len(plan.Refresh) > 0 {
+		fmt.Fprintln(w, i18n.G("Name\tFrom\tTo\tVersion\tTracking\tSize\tNotes"))
+		for _, sn := range plan.Refresh {
+			var notes []string
+			if sn.RebootRequired {
+				notes = append(notes, i18n.G("reboot"))
+			}
+			if len(sn.Prerequisites) > 0 {
+				// TRANSLATORS: the %s is a comma-separated list of snap names
+				notes = append(notes, fmt.Sprintf(i18n.G("needs %s"), strings.Join(sn.Prerequisites, ",")))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", sn.Name, sn.CurrentRevision, sn.Revision, sn.Version,
+				sn.Channel, strutil.SizeToStr(sn.DownloadSize), noteOrDash(notes))
+		}
+	}
+	if len(plan.Held) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, i18n.G("Held\tTo\tHeld by\tUntil"))
+		for _, sn := range plan.Held {
+			holders := make([]string, 0, len(sn.HeldBy))
+			for holder := range sn.HeldBy {
+				holders = append(holders, holder)
+			}
+			sort.Strings(holders)
+			for i, holder := range holders {
+				name, rev := sn.Name, sn.Revision.String()
+				if i > 0 {
+					name, rev = "", ""
+				}
+				until := sn.HeldBy[holder]
+				untilStr := x.fmtTime(until)
+				// like 'snap refresh --time', show long holds as "forever"
+				if until.After(timeNow().Add(100 * 365 * 24 * time.Hour)) {
+					untilStr = i18n.G("forever")
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, rev, holder, untilStr)
+			}
+		}
+	}
+	if len(plan.Blocked) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, i18n.G("Blocked\tTo\tReason"))
+		for _, sn := range plan.Blocked {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", sn.Name, sn.Revision, sn.Reason)
+		}
+	}
+	w.Flush()
+
+	if len(plan.Refresh) > 0 {
+		fmt.Fprintln(Stdout)
+		if len(plan.Prerequisites) > 0 {
+			fmt.Fprintf(Stdout, i18n.G("Prerequisites to install: %s\n"), strings.Join(plan.Prerequisites, ", "))
+		}
+		space := strutil.SizeToStr(int64(plan.RequiredSpace))
+		if plan.InsufficientSpace {
+			fmt.Fprintf(Stdout, i18n.G("Required disk space: %s (insufficient)\n"), space)
+		} else {
+			fmt.Fprintf(Stdout, i18n.G("Required disk space: %s\n"), space)
+		}
+		if plan.RebootRequired {
+			fmt.Fprintln(Stdout, i18n.G("Reboot required: yes"))
+		} else {
+			fmt.Fprintln(Stdout, i18n.G("Reboot required: no"))
+		}
+	}
+
+	return nil
+}
+
+func noteOrDash(notes []string) string {
+	if len(notes) == 0 {
+		return "-"
+	}
+	return strings.Join(notes, ",")
+}
+
 func (x *cmdRefresh) Execute([]string) error {
 	if err := x.setChannelFromCommandline(); err != nil {
 		return err
@@ -859,6 +982,15 @@ func (x *cmdRefresh) Execute([]string) error {
 		return x.listRefresh()
 	}
 
+	if x.Plan {
+		if x.asksForMode() || x.asksForChannel() || x.Amend || x.Revision != "" ||
+			x.Cohort != "" || x.LeaveCohort || x.IgnoreValidation ||
+			x.Hold != "" || x.Unhold || x.Transaction != client.TransactionPerSnap {
+			return errors.New(i18n.G("--plan does not take other flags"))
+		}
+		return x.showRefreshPlan(installedSnapNames(x.Positional.Snaps))
+	}
+
 	if len(x.Positional.Snaps) == 0 && os.Getenv("SNAP_REFRESH_FROM_TIMER") == "1" {
 		fmt.Fprintf(Stdout, "Ignoring `snap refresh` from the systemd timer")
 		return nil
@@ -1283,6 +1415,10 @@ func init() {
 			"transaction": i18n.G("Have one transaction per-snap or one for all the specified snaps"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"quota-group": i18n.G("Add the snap to a quota group on install"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"delta-from": i18n.G("Install the snap obtained by applying the given delta file to this installed revision"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"delta-assertions": i18n.G("Use the snap-revision assertion for the snap obtained from the delta in this file"),
 		}), nil)
 	addCommand("refresh", shortRefreshHelp, longRefreshHelp, func() flags.Commander { return &cmdRefresh{} },
 		colorDescs.also(waitDescs).also(channelDescs).also(modeDescs).also(timeDescs).also(map[string]string{
@@ -1293,6 +1429,8 @@ func init() {
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"list": i18n.G("Show the new versions of snaps that would be updated with the next refresh"),
 			// TRANSLATORS: This should not start with a lowercase letter.
+			"plan": i18n.G("Show what the refresh would do, including held snaps, disk space and reboots, without refreshing"),
+			// TRANSLATORS: This should not start with a lowercase letter.
 			"time": i18n.G("Show auto refresh information but do not perform a refresh"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"ignore-validation": i18n.G("Ignore validation by other snaps blocking the refresh"),
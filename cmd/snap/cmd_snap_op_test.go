@@ -1123,6 +1123,86 @@ func (s *SnapOpSuite) TestInstallPathInstance(c *check.C) {
 	c.Check(s.srv.n, check.Equals, s.srv.total)
 }
 
+func (s *SnapOpSuite) TestInstallPathDelta(c *check.C) {
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+
+		form := testForm(r, c)
+		defer form.RemoveAll()
+
+		c.Check(form.Value["action"], check.DeepEquals, []string{"install"})
+		c.Check(form.Value["delta-from"], check.DeepEquals, []string{"x1"})
+		c.Check(form.Value["snap-path"], check.DeepEquals, []string{"foo.xdelta3"})
+		c.Check(form.Value["name"], check.IsNil)
+		c.Check(form.Value["assertion"], check.IsNil)
+
+		name, _, body := formFile(form, c)
+		c.Check(name, check.Equals, "snap")
+		c.Check(string(body), check.Equals, "delta-data")
+	}
+
+	s.RedirectClientToTestServer(s.srv.handle)
+	dir := c.MkDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "foo.xdelta3"), []byte("delta-data"), 0644)
+	c.Assert(err, check.IsNil)
+	oldCwd, err := os.Getwd()
+	c.Assert(err, check.IsNil)
+	c.Assert(os.Chdir(dir), check.IsNil)
+	defer os.Chdir(oldCwd)
+
+	// the delta file is not mistaken for a store snap name
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"install", "--delta-from=x1", "foo.xdelta3"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?sm).*foo 1.0 from Bar installed`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.srv.n, check.Equals, s.srv.total)
+}
+
+func (s *SnapOpSuite) TestInstallPathDeltaWithAssertions(c *check.C) {
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+
+		form := testForm(r, c)
+		defer form.RemoveAll()
+
+		c.Check(form.Value["delta-from"], check.DeepEquals, []string{"x1"})
+		c.Check(form.Value["assertion"], check.DeepEquals, []string{"type: snap-revision\n"})
+	}
+
+	s.RedirectClientToTestServer(s.srv.handle)
+	dir := c.MkDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "foo.xdelta3"), []byte("delta-data"), 0644)
+	c.Assert(err, check.IsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, "foo.assert"), []byte("type: snap-revision\n"), 0644)
+	c.Assert(err, check.IsNil)
+	oldCwd, err := os.Getwd()
+	c.Assert(err, check.IsNil)
+	c.Assert(os.Chdir(dir), check.IsNil)
+	defer os.Chdir(oldCwd)
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"install", "--delta-from=x1", "--delta-assertions=foo.assert", "foo.xdelta3"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?sm).*foo 1.0 from Bar installed`)
+	c.Check(s.srv.n, check.Equals, s.srv.total)
+}
+
+func (s *SnapOpSuite) TestInstallPathDeltaErrors(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"install", "--delta-from=x1", "--dangerous", "foo.xdelta3"})
+	c.Check(err, check.ErrorMatches, "cannot use --dangerous with --delta-from, the resulting snap must match a snap-revision assertion")
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"install", "--delta-from=x1", "foo.xdelta3", "bar.xdelta3"})
+	c.Check(err, check.ErrorMatches, "a single delta file must be specified when using --delta-from")
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"install", "--delta-assertions=foo.assert", "foo.snap"})
+	c.Check(err, check.ErrorMatches, "cannot use --delta-assertions without --delta-from")
+}
+
 func (s *SnapOpSuite) TestInstallPathMany(c *check.C) {
 	snaps := []string{"foo.snap", "bar.snap"}
 	total := 4
@@ -1358,6 +1438,82 @@ foo +4.2update1 +17 +436MB +bar +-.*
 	c.Check(n, check.Equals, 1)
 }
 
+func (s *SnapSuite) TestRefreshPlan(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+				"action": "refresh",
+				"plan":   true,
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": {
+"refresh": [
+  {"name": "pc-kernel", "type": "kernel", "version": "5.15.0-1", "current-revision": "1", "revision": "2", "channel": "22/stable", "download-size": 123456789, "reboot-required": true},
+  {"name": "foo", "type": "app", "version": "1.0", "current-revision": "3", "revision": "4", "channel": "latest/stable", "download-size": 1000000, "prerequisites": ["core22"]}
+],
+"held": [{"name": "bar", "revision": "9", "held-by": {"system": "2999-01-01T00:00:00Z", "gating-snap": "2017-04-26T00:58:00+02:00"}}],
+"blocked": [{"name": "baz", "revision": "10", "reason": "validation failed"}],
+"prerequisites": ["core22"],
+"required-space": 160000000,
+"reboot-required": true
+}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--plan", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `Name       From  To   Version   Tracking       Size   Notes
+pc-kernel  1     2    5.15.0-1  22/stable      123MB  reboot
+foo        3     4    1.0       latest/stable  1MB    needs core22
+
+Held  To   Held by      Until
+bar   9    gating-snap  2017-04-26T00:58:00+02:00
+           system       forever
+
+Blocked  To   Reason
+baz      10   validation failed
+
+Prerequisites to install: core22
+Required disk space: 160MB
+Reboot required: yes
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestRefreshPlanUpToDate(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "refresh",
+			"snaps":  []interface{}{"foo"},
+			"plan":   true,
+		})
+		fmt.Fprintln(w, `{"type": "sync", "result": {"required-space": 0}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--plan", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "All snaps up to date.\n")
+}
+
+func (s *SnapSuite) TestRefreshPlanNoOtherFlags(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatal("expected to get 0 requests")
+	})
+
+	for _, flag := range []string{"--beta", "--classic", "--amend", "--revision=2", "--transaction=all-snaps", "--ignore-validation"} {
+		_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--plan", flag, "some-snap"})
+		c.Assert(err, check.ErrorMatches, "--plan does not take other flags")
+	}
+}
+
 func (s *SnapSuite) TestRefreshLegacyTime(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
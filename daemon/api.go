@@ -47,7 +47,9 @@ var api = []*Command{
 	snapFileCmd,
 	snapDownloadCmd,
 	snapConfCmd,
+	snapHistoryCmd,
 	interfacesCmd,
+	interfacesDevicesCmd,
 	assertsCmd,
 	assertsFindManyCmd,
 	stateChangeCmd,
@@ -134,11 +136,13 @@ func storeFrom(d *Daemon) snapstate.StoreService {
 var (
 	snapstateInstall                        = snapstate.Install
 	snapstateInstallPath                    = snapstate.InstallPath
+	snapstateApplyLocalDelta                = snapstate.ApplyLocalDelta
 	snapstateInstallPathMany                = snapstate.InstallPathMany
 	snapstateRefreshCandidates              = snapstate.RefreshCandidates
 	snapstateTryPath                        = snapstate.TryPath
 	snapstateUpdate                         = snapstate.Update
 	snapstateUpdateMany                     = snapstate.UpdateMany
+	snapstateUpdateManyPlan                 = snapstate.UpdateManyPlan
 	snapstateInstallMany                    = snapstate.InstallMany
 	snapstateRemoveMany                     = snapstate.RemoveMany
 	snapstateResolveValSetsEnforcementError = snapstate.ResolveValidationSetsEnforcementError
@@ -159,13 +159,18 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 		slotID := slotRef.String()
 
 		cj := connectionJSON{
-			Slot:      slotRef,
-			Plug:      plugRef,
-			Manual:    !cstate.Auto,
-			Gadget:    cstate.ByGadget,
-			Interface: cstate.Interface,
-			PlugAttrs: mergeAttrs(cstate.StaticPlugAttrs, cstate.DynamicPlugAttrs),
-			SlotAttrs: mergeAttrs(cstate.StaticSlotAttrs, cstate.DynamicSlotAttrs),
+			Slot:        slotRef,
+			Plug:        plugRef,
+			Manual:      !cstate.Auto,
+			Gadget:      cstate.ByGadget,
+			Interface:   cstate.Interface,
+			PlugAttrs:   mergeAttrs(cstate.StaticPlugAttrs, cstate.DynamicPlugAttrs),
+			SlotAttrs:   mergeAttrs(cstate.StaticSlotAttrs, cstate.DynamicSlotAttrs),
+			UntilReboot: cstate.UntilReboot,
+		}
+		if !cstate.Expiry.IsZero() {
+			expiry := cstate.Expiry
+			cj.Expiry = &expiry
 		}
 		if cstate.Undesired {
 			// explicitly disconnected are always manual
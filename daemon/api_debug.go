@@ -22,8 +22,10 @@ package daemon
 import (
 	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/snapcore/snapd/asserts"
@@ -55,6 +57,8 @@ type debugAction struct {
 		ChgID string `json:"chg-id"`
 
 		RecoverySystemLabel string `json:"recovery-system-label"`
+
+		Digests []string `json:"digests"`
 	} `json:"params"`
 	Snaps []string `json:"snaps"`
 }
@@ -302,7 +306,7 @@ func getGadgetDiskMapping(st *state.State) Response {
 		// no sealed keys, so no encryption
 	} else {
 		switch sealingMethod {
-		case device.SealingMethodLegacyTPM, device.SealingMethodTPM:
+		case device.SealingMethodLegacyTPM, device.SealingMethodTPM, device.SealingMethodPassphrase:
 			encType = secboot.EncryptionTypeLUKS
 		case device.SealingMethodFDESetupHook:
 			// TODO:ICE: device setup hook support goes away
@@ -355,11 +359,19 @@ func getDisks(st *state.State) Response {
 	return SyncResponse(vols)
 }
 
+func getGadgetUpdateStatus(st *state.State) Response {
+	statuses, err := devicestate.GadgetUpdateStatuses()
+	if err != nil {
+		return InternalError("cannot get gadget update status: %v", err)
+	}
+	return SyncResponse(statuses)
+}
+
 func createRecovery(st *state.State, label string) Response {
 	if label == "" {
 		return BadRequest("cannot create a recovery system with no label")
 	}
-	chg, err := devicestate.CreateRecoverySystem(st, label)
+	chg, err := devicestate.CreateRecoverySystem(st, label, devicestate.CreateRecoverySystemOptions{})
 	if err != nil {
 		return InternalError("cannot create recovery system %q: %v", label, err)
 	}
@@ -399,12 +411,29 @@ func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		return getGadgetDiskMapping(st)
 	case "disks":
 		return getDisks(st)
+	case "cache":
+		return getDebugCache(st)
+	case "gadget-update-status":
+		return getGadgetUpdateStatus(st)
+	case "reseal-dry-run":
+		return getResealDryRun(st)
+	case "sandbox-denials":
+		return getSandboxDenials(st, c.d.overlord.InterfaceManager().Repository(), query)
 	default:
 		return BadRequest("unknown debug aspect %q", aspect)
 	}
 }
 
 func postDebug(c *Command, r *http.Request, user *auth.UserState) Response {
+	contentType := r.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "multipart/") {
+		_, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return BadRequest("cannot parse content type: %v", err)
+		}
+		return postDebugFromForm(c, r, params["boundary"])
+	}
+
 	var a debugAction
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&a); err != nil {
@@ -440,6 +469,8 @@ func postDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		return createRecovery(st, a.Params.RecoverySystemLabel)
 	case "migrate-home":
 		return migrateHome(st, a.Snaps)
+	case "purge-cache":
+		return purgeDebugCache(st, a.Params.Digests)
 	default:
 		return BadRequest("unknown debug action: %v", a.Action)
 	}
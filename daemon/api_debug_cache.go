@@ -0,0 +1,183 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+)
+
+var newDownloadCache = func() *store.CacheManager {
+	return store.NewCacheManager(dirs.SnapDownloadCacheDir, 0)
+}
+
+type cacheEntry struct {
+	Digest  string    `json:"digest"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	// Pinned is true if the entry is also linked from outside the
+	// cache, or is a revision in the sequence of an installed snap,
+	// and so is not purged.
+	Pinned bool `json:"pinned,omitempty"`
+
+	// Name and Revision are set if the snap-revision assertion of
+	// the cached snap is known.
+	Name     string        `json:"name,omitempty"`
+	Revision snap.Revision `json:"revision,omitempty"`
+}
+
+// installedSnapDigests returns the hex encoded sha3-384 digests, which
+// key the download cache, of the revisions in the sequences of the
+// installed snaps.
+func installedSnapDigests(st *state.State) (map[string]bool, error) {
+	snapStates, err := snapstate.All(st)
+	if err != nil {
+		return nil, err
+	}
+	db := assertstate.DB(st)
+	digests := make(map[string]bool)
+	for _, snapst := range snapStates {
+		for _, si := range snapst.Sequence {
+			if si.SnapID == "" {
+				continue
+			}
+			as, err := db.FindMany(asserts.SnapRevisionType, map[string]string{
+				"snap-id":       si.SnapID,
+				"snap-revision": si.Revision.String(),
+			})
+			if errors.Is(err, &asserts.NotFoundError{}) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range as {
+				rawDigest, err := base64.RawURLEncoding.DecodeString(a.(*asserts.SnapRevision).SnapSHA3_384())
+				if err != nil {
+					continue
+				}
+				digests[hex.EncodeToString(rawDigest)] = true
+			}
+		}
+	}
+	return digests, nil
+}
+
+// describeCacheEntry fills in the snap the cached entry is a revision of,
+// looking up its digest in the assertion database.
+func describeCacheEntry(st *state.State, entry store.CacheEntry, installed map[string]bool) cacheEntry {
+	desc := cacheEntry{
+		Digest:  entry.Digest,
+		Size:    entry.Size,
+		ModTime: entry.ModTime,
+		Pinned:  entry.Pinned || installed[entry.Digest],
+	}
+	// the cache is keyed by the hex encoded digest
+	rawDigest, err := hex.DecodeString(entry.Digest)
+	if err != nil {
+		return desc
+	}
+	digest, err := asserts.EncodeDigest(crypto.SHA3_384, rawDigest)
+	if err != nil {
+		return desc
+	}
+	a, err := assertstate.DB(st).Find(asserts.SnapRevisionType, map[string]string{
+		"snap-sha3-384": digest,
+	})
+	if err != nil {
+		return desc
+	}
+	snapRev := a.(*asserts.SnapRevision)
+	desc.Revision = snap.R(snapRev.SnapRevision())
+	if decl, err := assertstate.SnapDeclaration(st, snapRev.SnapID()); err == nil {
+		desc.Name = decl.SnapName()
+	}
+	return desc
+}
+
+func getDebugCache(st *state.State) Response {
+	entries, err := newDownloadCache().Entries()
+	if err != nil {
+		return InternalError("cannot list download cache: %v", err)
+	}
+	installed, err := installedSnapDigests(st)
+	if err != nil {
+		return InternalError("cannot list installed snaps: %v", err)
+	}
+	descs := make([]cacheEntry, 0, len(entries))
+	for _, entry := range entries {
+		descs = append(descs, describeCacheEntry(st, entry, installed))
+	}
+	return SyncResponse(descs)
+}
+
+// purgeDebugCache removes the entries with the given digests from the
+// download cache, or all entries that are not pinned if none are given.
+func purgeDebugCache(st *state.State, digests []string) Response {
+	installed, err := installedSnapDigests(st)
+	if err != nil {
+		return InternalError("cannot list installed snaps: %v", err)
+	}
+	cache := newDownloadCache()
+	var removed []store.CacheEntry
+	if len(digests) == 0 {
+		removed, err = cache.Purge(func(digest string) bool { return installed[digest] })
+		if err != nil {
+			return InternalError("cannot purge download cache: %v", err)
+		}
+	} else {
+		entries, err := cache.Entries()
+		if err != nil {
+			return InternalError("cannot list download cache: %v", err)
+		}
+		byDigest := make(map[string]store.CacheEntry, len(entries))
+		for _, entry := range entries {
+			byDigest[entry.Digest] = entry
+		}
+		for _, digest := range digests {
+			entry, ok := byDigest[digest]
+			if !ok {
+				return BadRequest("cannot find %q in download cache", digest)
+			}
+			if err := cache.Remove(digest); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return InternalError("cannot remove %q from download cache: %v", digest, err)
+			}
+			removed = append(removed, entry)
+		}
+	}
+
+	descs := make([]cacheEntry, 0, len(removed))
+	for _, entry := range removed {
+		descs = append(descs, describeCacheEntry(st, entry, installed))
+	}
+	return SyncResponse(descs)
+}
@@ -0,0 +1,143 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/sha3"
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+var _ = check.Suite(&debugCacheSuite{})
+
+type debugCacheSuite struct {
+	apiBaseSuite
+}
+
+// mockCache puts an installed snap, which is thus pinned, and a snap
+// that is only in the cache into the download cache.
+func (s *debugCacheSuite) mockCache(c *check.C) (pinned, loose string) {
+	d := s.daemon(c)
+	info := s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	c.Assert(os.MkdirAll(dirs.SnapDownloadCacheDir, 0700), check.IsNil)
+	content, err := ioutil.ReadFile(info.MountFile())
+	c.Assert(err, check.IsNil)
+	pinned = fmt.Sprintf("%x", sha3.Sum384(content))
+	c.Assert(os.Link(info.MountFile(), filepath.Join(dirs.SnapDownloadCacheDir, pinned)), check.IsNil)
+
+	loose = fmt.Sprintf("%x", sha3.Sum384([]byte("loose")))
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapDownloadCacheDir, loose), []byte("loose"), 0644), check.IsNil)
+	return pinned, loose
+}
+
+func (s *debugCacheSuite) getCache(c *check.C) []daemon.DebugCacheEntry {
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=cache", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	return rsp.Result.([]daemon.DebugCacheEntry)
+}
+
+func (s *debugCacheSuite) purgeCache(c *check.C, digests ...string) []daemon.DebugCacheEntry {
+	body, err := json.Marshal(map[string]interface{}{
+		"action": "purge-cache",
+		"params": map[string]interface{}{"digests": digests},
+	})
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/debug", bytes.NewBuffer(body))
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	return rsp.Result.([]daemon.DebugCacheEntry)
+}
+
+func (s *debugCacheSuite) TestGetDebugCache(c *check.C) {
+	pinned, loose := s.mockCache(c)
+
+	entries := s.getCache(c)
+	c.Assert(entries, check.HasLen, 2)
+	byDigest := map[string]daemon.DebugCacheEntry{}
+	for _, entry := range entries {
+		byDigest[entry.Digest] = entry
+	}
+
+	c.Check(byDigest[pinned].Pinned, check.Equals, true)
+	c.Check(byDigest[pinned].Name, check.Equals, "foo")
+	c.Check(byDigest[pinned].Revision, check.Equals, snap.R(10))
+	c.Check(byDigest[loose].Pinned, check.Equals, false)
+	c.Check(byDigest[loose].Name, check.Equals, "")
+	c.Check(byDigest[loose].Size, check.Equals, int64(5))
+}
+
+func (s *debugCacheSuite) TestPostDebugPurgeCacheKeepsInstalledCopies(c *check.C) {
+	d := s.daemon(c)
+	info := s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	// the cached revision is a copy, not a link, of the installed one
+	c.Assert(os.MkdirAll(dirs.SnapDownloadCacheDir, 0700), check.IsNil)
+	content, err := ioutil.ReadFile(info.MountFile())
+	c.Assert(err, check.IsNil)
+	installed := fmt.Sprintf("%x", sha3.Sum384(content))
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapDownloadCacheDir, installed), content, 0644), check.IsNil)
+
+	entries := s.getCache(c)
+	c.Assert(entries, check.HasLen, 1)
+	c.Check(entries[0].Pinned, check.Equals, true)
+
+	s.expectRootAccess()
+	removed := s.purgeCache(c)
+	c.Check(removed, check.HasLen, 0)
+	c.Check(filepath.Join(dirs.SnapDownloadCacheDir, installed), testutil.FilePresent)
+}
+
+func (s *debugCacheSuite) TestPostDebugPurgeCache(c *check.C) {
+	pinned, loose := s.mockCache(c)
+	s.expectRootAccess()
+
+	removed := s.purgeCache(c)
+	c.Assert(removed, check.HasLen, 1)
+	c.Check(removed[0].Digest, check.Equals, loose)
+
+	c.Check(filepath.Join(dirs.SnapDownloadCacheDir, loose), testutil.FileAbsent)
+	c.Check(filepath.Join(dirs.SnapDownloadCacheDir, pinned), testutil.FilePresent)
+
+	// pinned entries are only removed when asked for explicitly
+	removed = s.purgeCache(c, pinned)
+	c.Assert(removed, check.HasLen, 1)
+	c.Check(removed[0].Name, check.Equals, "foo")
+	c.Check(filepath.Join(dirs.SnapDownloadCacheDir, pinned), testutil.FileAbsent)
+
+	req, err := http.NewRequest("POST", "/v2/debug", bytes.NewBufferString(fmt.Sprintf(`{"action": "purge-cache", "params": {"digests": [%q]}}`, pinned)))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, fmt.Sprintf("cannot find %q in download cache", pinned))
+}
@@ -0,0 +1,111 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+)
+
+var ifacestateCheckPolicy = ifacestate.CheckPolicy
+
+// postDebugFromForm handles the debug actions taking files uploaded as a
+// multipart form.
+func postDebugFromForm(c *Command, r *http.Request, boundary string) Response {
+	form, errRsp := readForm(multipart.NewReader(r.Body, boundary))
+	if errRsp != nil {
+		return errRsp
+	}
+	defer form.RemoveAllExcept(nil)
+
+	if len(form.Values["action"]) != 1 || form.Values["action"][0] != "check-policy" {
+		return BadRequest("unsupported multipart debug action, only %q is supported", "check-policy")
+	}
+	return checkPolicy(c, form)
+}
+
+// checkPolicy reports whether the installation of the uploaded snap, and the
+// connections of its plugs and slots with the installed snaps, are allowed
+// by the interface policy, optionally using the uploaded snap declaration
+// and model instead of the ones of the system.
+func checkPolicy(c *Command, form *Form) Response {
+	refs := form.FileRefs["snap"]
+	if len(refs) != 1 {
+		return BadRequest(`cannot check policy: expected exactly one "snap" file`)
+	}
+	info, err := unsafeReadSnapInfo(refs[0].TmpPath)
+	if err != nil {
+		return BadRequest("cannot read snap file: %v", err)
+	}
+
+	opts := &ifacestate.PolicyCheckOptions{
+		Snaps: form.Values["with"],
+	}
+	if a, errRsp := readAssertionFromForm(form, "snap-declaration", asserts.SnapDeclarationType); errRsp != nil {
+		return errRsp
+	} else if a != nil {
+		opts.SnapDeclaration = a.(*asserts.SnapDeclaration)
+	}
+	if a, errRsp := readAssertionFromForm(form, "model", asserts.ModelType); errRsp != nil {
+		return errRsp
+	} else if a != nil {
+		opts.Model = a.(*asserts.Model)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	report, err := ifacestateCheckPolicy(st, info, opts)
+	if err != nil {
+		return BadRequest("cannot check policy: %v", err)
+	}
+	return SyncResponse(report)
+}
+
+// readAssertionFromForm decodes the assertion of the given type uploaded in
+// the named file field of the form, if any. The assertion is not verified,
+// which allows checking draft declarations.
+func readAssertionFromForm(form *Form, field string, assertType *asserts.AssertionType) (asserts.Assertion, *apiError) {
+	refs := form.FileRefs[field]
+	switch len(refs) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, BadRequest("cannot use more than one %q file", field)
+	}
+	data, err := ioutil.ReadFile(refs[0].TmpPath)
+	if err != nil {
+		return nil, InternalError("cannot read uploaded %s: %v", field, err)
+	}
+	a, err := asserts.Decode(data)
+	if err != nil {
+		return nil, BadRequest("cannot decode %s: %v", field, err)
+	}
+	if a.Type() != assertType {
+		return nil, BadRequest("cannot use %q assertion as %s", a.Type().Name, field)
+	}
+	return a, nil
+}
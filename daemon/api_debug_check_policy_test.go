@@ -0,0 +1,172 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+var _ = check.Suite(&debugCheckPolicySuite{})
+
+type debugCheckPolicySuite struct {
+	apiBaseSuite
+}
+
+func (s *debugCheckPolicySuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.expectRootAccess()
+	s.AddCleanup(daemon.MockUnsafeReadSnapInfo(func(string) (*snap.Info, error) {
+		return &snap.Info{SuggestedName: "consumer"}, nil
+	}))
+}
+
+func checkPolicyRequest(c *check.C, parts []systemsFormPart) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, p := range parts {
+		var err error
+		if p.filename != "" {
+			var fw io.Writer
+			fw, err = mw.CreateFormFile(p.name, p.filename)
+			c.Assert(err, check.IsNil)
+			_, err = fw.Write([]byte(p.content))
+		} else {
+			err = mw.WriteField(p.name, p.content)
+		}
+		c.Assert(err, check.IsNil)
+	}
+	c.Assert(mw.Close(), check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/debug", &body)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func (s *debugCheckPolicySuite) TestCheckPolicyHappy(c *check.C) {
+	s.daemon(c)
+
+	snapDecl, err := s.StoreSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "consumer-id",
+		"snap-name":    "consumer",
+		"publisher-id": "can0nical",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+	model := s.Brands.Model("my-brand", "my-model", modelDefaults)
+
+	var gotInfo *snap.Info
+	var gotOpts *ifacestate.PolicyCheckOptions
+	restore := daemon.MockIfacestateCheckPolicy(func(st *state.State, info *snap.Info, opts *ifacestate.PolicyCheckOptions) (*ifacestate.PolicyCheckReport, error) {
+		gotInfo = info
+		gotOpts = opts
+		return &ifacestate.PolicyCheckReport{
+			Snap: "consumer",
+			Installation: []*ifacestate.InstallPolicyCheck{{
+				Plug:           "plug",
+				Interface:      "test",
+				PolicyDecision: ifacestate.PolicyDecision{Allowed: true},
+			}},
+		}, nil
+	})
+	defer restore()
+
+	req := checkPolicyRequest(c, []systemsFormPart{
+		{name: "action", content: "check-policy"},
+		{name: "with", content: "producer"},
+		{name: "snap", filename: "consumer_1_all.snap", content: "snap"},
+		{name: "snap-declaration", filename: "consumer.decl", content: string(asserts.Encode(snapDecl))},
+		{name: "model", filename: "model", content: string(asserts.Encode(model))},
+	})
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, &ifacestate.PolicyCheckReport{
+		Snap: "consumer",
+		Installation: []*ifacestate.InstallPolicyCheck{{
+			Plug:           "plug",
+			Interface:      "test",
+			PolicyDecision: ifacestate.PolicyDecision{Allowed: true},
+		}},
+	})
+
+	c.Check(gotInfo.SnapName(), check.Equals, "consumer")
+	c.Assert(gotOpts, check.NotNil)
+	c.Check(gotOpts.Snaps, check.DeepEquals, []string{"producer"})
+	c.Assert(gotOpts.SnapDeclaration, check.NotNil)
+	c.Check(gotOpts.SnapDeclaration.SnapID(), check.Equals, "consumer-id")
+	c.Assert(gotOpts.Model, check.NotNil)
+	c.Check(gotOpts.Model.Model(), check.Equals, "my-model")
+}
+
+func (s *debugCheckPolicySuite) TestCheckPolicyErrors(c *check.C) {
+	s.daemon(c)
+
+	model := s.Brands.Model("my-brand", "my-model", modelDefaults)
+
+	restore := daemon.MockIfacestateCheckPolicy(func(st *state.State, info *snap.Info, opts *ifacestate.PolicyCheckOptions) (*ifacestate.PolicyCheckReport, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+
+	snapPart := systemsFormPart{name: "snap", filename: "consumer_1_all.snap", content: "snap"}
+	for _, tc := range []struct {
+		parts []systemsFormPart
+		err   string
+	}{{
+		parts: []systemsFormPart{{name: "action", content: "foo"}, snapPart},
+		err:   `unsupported multipart debug action, only "check-policy" is supported`,
+	}, {
+		parts: []systemsFormPart{{name: "action", content: "check-policy"}},
+		err:   `cannot check policy: expected exactly one "snap" file`,
+	}, {
+		parts: []systemsFormPart{
+			{name: "action", content: "check-policy"}, snapPart,
+			{name: "snap-declaration", filename: "model", content: string(asserts.Encode(model))},
+		},
+		err: `cannot use "model" assertion as snap-declaration`,
+	}, {
+		parts: []systemsFormPart{
+			{name: "action", content: "check-policy"}, snapPart,
+			{name: "model", filename: "model", content: "garbage"},
+		},
+		err: `cannot decode model: .*`,
+	}, {
+		parts: []systemsFormPart{{name: "action", content: "check-policy"}, snapPart},
+		err:   `cannot check policy: boom`,
+	}} {
+		rspe := s.errorReq(c, checkPolicyRequest(c, tc.parts), nil)
+		c.Check(rspe.Status, check.Equals, 400)
+		c.Check(rspe.Message, check.Matches, tc.err)
+	}
+}
@@ -0,0 +1,48 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"os"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var bootResealKeyToModeenvDryRun = boot.ResealKeyToModeenvDryRun
+
+// getResealDryRun reports what resealing the encryption keys would do,
+// without touching the TPM. The state lock is held, so that no reseal
+// happens concurrently.
+func getResealDryRun(st *state.State) Response {
+	modeenv, err := boot.ReadModeenv("")
+	if os.IsNotExist(err) {
+		return BadRequest("cannot reseal: not a system with a modeenv")
+	}
+	if err != nil {
+		return InternalError("cannot read modeenv: %v", err)
+	}
+	report, err := bootResealKeyToModeenvDryRun(dirs.GlobalRootDir, modeenv)
+	if err != nil {
+		return InternalError("cannot compute reseal: %v", err)
+	}
+	return SyncResponse(report)
+}
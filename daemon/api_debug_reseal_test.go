@@ -0,0 +1,98 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"errors"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
+)
+
+var _ = check.Suite(&debugResealSuite{})
+
+type debugResealSuite struct {
+	apiBaseSuite
+}
+
+func (s *debugResealSuite) getResealDryRunReq(c *check.C) *http.Request {
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=reseal-dry-run", nil)
+	c.Assert(err, check.IsNil)
+	return req
+}
+
+func (s *debugResealSuite) TestResealDryRunHappy(c *check.C) {
+	s.daemon(c)
+
+	m := &boot.Modeenv{Mode: "run", RecoverySystem: "20230101"}
+	c.Assert(m.WriteTo(""), check.IsNil)
+
+	report := &boot.ResealDryRunReport{
+		SealingMethod: "tpm",
+		Objects: []*boot.ResealDryRunObject{{
+			Name:         "run",
+			ResealNeeded: true,
+			Differences:  []string{"some difference"},
+		}},
+	}
+	called := 0
+	s.AddCleanup(daemon.MockBootResealKeyToModeenvDryRun(func(rootdir string, modeenv *boot.Modeenv) (*boot.ResealDryRunReport, error) {
+		called++
+		c.Check(rootdir, check.Equals, dirs.GlobalRootDir)
+		c.Check(modeenv.RecoverySystem, check.Equals, "20230101")
+		return report, nil
+	}))
+
+	rsp := s.syncReq(c, s.getResealDryRunReq(c), nil)
+	c.Check(rsp.Result, check.DeepEquals, report)
+	c.Check(called, check.Equals, 1)
+}
+
+func (s *debugResealSuite) TestResealDryRunNoModeenv(c *check.C) {
+	s.daemon(c)
+
+	s.AddCleanup(daemon.MockBootResealKeyToModeenvDryRun(func(rootdir string, modeenv *boot.Modeenv) (*boot.ResealDryRunReport, error) {
+		c.Fatalf("unexpected call")
+		return nil, nil
+	}))
+
+	rsp := s.errorReq(c, s.getResealDryRunReq(c), nil)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Message, check.Equals, "cannot reseal: not a system with a modeenv")
+}
+
+func (s *debugResealSuite) TestResealDryRunError(c *check.C) {
+	s.daemon(c)
+
+	m := &boot.Modeenv{Mode: "run"}
+	c.Assert(m.WriteTo(""), check.IsNil)
+
+	s.AddCleanup(daemon.MockBootResealKeyToModeenvDryRun(func(rootdir string, modeenv *boot.Modeenv) (*boot.ResealDryRunReport, error) {
+		return nil, errors.New("cannot find the recovery bootloader")
+	}))
+
+	rsp := s.errorReq(c, s.getResealDryRunReq(c), nil)
+	c.Check(rsp.Status, check.Equals, 500)
+	c.Check(rsp.Message, check.Equals, "cannot compute reseal: cannot find the recovery bootloader")
+}
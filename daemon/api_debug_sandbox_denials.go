@@ -0,0 +1,144 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/audit"
+	"github.com/snapcore/snapd/systemd"
+)
+
+var (
+	systemdAuditLogReader          = systemd.AuditLogReader
+	builtinSuggestInterfaces       = builtin.SuggestInterfaces
+	defaultSandboxDenialsLogLength = 10000
+)
+
+type sandboxDenialSuggestion struct {
+	Interface string `json:"interface"`
+	// Plug is the plug of the snap for the interface, if any.
+	Plug      string `json:"plug,omitempty"`
+	Connected bool   `json:"connected"`
+}
+
+type sandboxDenial struct {
+	*audit.Denial
+	// Time is the time of the last occurrence of the denial.
+	Time        time.Time                 `json:"time"`
+	Count       int                       `json:"count"`
+	Suggestions []sandboxDenialSuggestion `json:"suggestions,omitempty"`
+}
+
+// getSandboxDenials reports the operations of snaps denied by the sandbox,
+// as found in the last records of the kernel audit log, along with the
+// interfaces which would allow them.
+func getSandboxDenials(st *state.State, repo *interfaces.Repository, query url.Values) Response {
+	n := defaultSandboxDenialsLogLength
+	if s := query.Get("n"); s != "" {
+		var err error
+		n, err = strconv.Atoi(s)
+		if err != nil {
+			return BadRequest("invalid value for n: %q", s)
+		}
+	}
+	snapName := query.Get("snap")
+
+	// reading the journal can take a while, the state is not needed
+	st.Unlock()
+	defer st.Lock()
+
+	reader, err := systemdAuditLogReader(n)
+	if err != nil {
+		return InternalError("cannot read the audit log: %v", err)
+	}
+	defer reader.Close()
+
+	var denials []*sandboxDenial
+	seen := make(map[audit.Denial]*sandboxDenial)
+	dec := json.NewDecoder(reader)
+	for {
+		var log systemd.Log
+		if err := dec.Decode(&log); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return InternalError("cannot decode the audit log: %v", err)
+		}
+		denial := audit.ParseDenial(log.Message())
+		if denial == nil || (snapName != "" && denial.Snap != snapName) {
+			continue
+		}
+		t, _ := log.Time()
+		// identical denials from different processes are reported once
+		key := *denial
+		key.Pid = 0
+		if d := seen[key]; d != nil {
+			d.Count++
+			d.Time = t
+			continue
+		}
+		d := &sandboxDenial{
+			Denial:      denial,
+			Time:        t,
+			Count:       1,
+			Suggestions: sandboxDenialSuggestions(repo, denial),
+		}
+		seen[key] = d
+		denials = append(denials, d)
+	}
+	sort.SliceStable(denials, func(i, j int) bool {
+		return denials[i].Time.Before(denials[j].Time)
+	})
+	return SyncResponse(denials)
+}
+
+func sandboxDenialSuggestions(repo *interfaces.Repository, denial *audit.Denial) []sandboxDenialSuggestion {
+	ifaceNames := builtinSuggestInterfaces(denial)
+	if len(ifaceNames) == 0 {
+		return nil
+	}
+	plugs := repo.Plugs(denial.Snap)
+	suggestions := make([]sandboxDenialSuggestion, 0, len(ifaceNames))
+	for _, ifaceName := range ifaceNames {
+		suggestion := sandboxDenialSuggestion{Interface: ifaceName}
+		for _, plug := range plugs {
+			if plug.Interface != ifaceName {
+				continue
+			}
+			suggestion.Plug = plug.Name
+			conns, err := repo.Connected(denial.Snap, plug.Name)
+			if err == nil && len(conns) > 0 {
+				suggestion.Connected = true
+				break
+			}
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
@@ -0,0 +1,180 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/sandbox/audit"
+	"github.com/snapcore/snapd/testutil"
+)
+
+var _ = check.Suite(&debugSandboxDenialsSuite{})
+
+type debugSandboxDenialsSuite struct {
+	apiBaseSuite
+}
+
+func (s *debugSandboxDenialsSuite) getSandboxDenialsReq(c *check.C, query string) *http.Request {
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=sandbox-denials"+query, nil)
+	c.Assert(err, check.IsNil)
+	return req
+}
+
+func mockAuditLog(records ...string) string {
+	var buf bytes.Buffer
+	for i, record := range records {
+		fmt.Fprintf(&buf, `{"__REALTIME_TIMESTAMP":"%d","MESSAGE":%q}`+"\n", 1555000000000000+i*1000000, record)
+	}
+	return buf.String()
+}
+
+const (
+	videoDenial   = `audit: type=1400 audit(1555000000.000:1): apparmor="DENIED" operation="open" profile="snap.consumer.app" name="/dev/video0" pid=%d comm="app" requested_mask="wr" denied_mask="wr" fsuid=1000 ouid=0`
+	otherDenial   = `audit: type=1400 audit(1555000000.000:2): apparmor="DENIED" operation="open" profile="snap.producer.app" name="/etc/shadow" pid=3 comm="app" requested_mask="r" denied_mask="r" fsuid=1000 ouid=0`
+	seccompDenial = `audit: type=1326 audit(1555000000.000:3): auid=1000 uid=1000 gid=1000 ses=3 subj=snap.consumer.app (enforce) pid=4 comm="app" exe="/snap/consumer/x1/app" sig=0 arch=c000003e syscall=165 compat=0 ip=0x7f0000000000 code=0x50000`
+)
+
+func (s *debugSandboxDenialsSuite) TestSandboxDenialsHappy(c *check.C) {
+	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
+	defer restore()
+
+	d := s.daemon(c)
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	repo := d.Overlord().InterfaceManager().Repository()
+	_, err := repo.Connect(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}, nil, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	s.AddCleanup(daemon.MockSystemdAuditLogReader(func(n int) (io.ReadCloser, error) {
+		c.Check(n, check.Equals, 10000)
+		return ioutil.NopCloser(strings.NewReader(mockAuditLog(
+			"some unrelated kernel message",
+			fmt.Sprintf(videoDenial, 1),
+			otherDenial,
+			fmt.Sprintf(videoDenial, 2),
+			seccompDenial,
+		))), nil
+	}))
+	s.AddCleanup(daemon.MockBuiltinSuggestInterfaces(func(denial *audit.Denial) []string {
+		if denial.Name == "/dev/video0" {
+			return []string{"camera", "test"}
+		}
+		return nil
+	}))
+
+	rsp := s.syncReq(c, s.getSandboxDenialsReq(c, ""), nil)
+	// the result is what is sent over the wire
+	var denials []map[string]interface{}
+	b, err := json.Marshal(rsp.Result)
+	c.Assert(err, check.IsNil)
+	c.Assert(json.Unmarshal(b, &denials), check.IsNil)
+	c.Assert(denials, check.HasLen, 3)
+
+	c.Check(denials[0], check.DeepEquals, map[string]interface{}{
+		"kind":      "apparmor",
+		"label":     "snap.producer.app",
+		"snap":      "producer",
+		"app":       "app",
+		"pid":       3.0,
+		"comm":      "app",
+		"operation": "open",
+		"name":      "/etc/shadow",
+
+		"requested-mask": "r",
+		"denied-mask":    "r",
+
+		"time":  time.Unix(1555000002, 0).UTC().Format(time.RFC3339),
+		"count": 1.0,
+	})
+	// duplicates are folded into the last occurrence
+	c.Check(denials[1]["label"], check.Equals, "snap.consumer.app")
+	c.Check(denials[1]["name"], check.Equals, "/dev/video0")
+	c.Check(denials[1]["count"], check.Equals, 2.0)
+	c.Check(denials[1]["time"], check.Equals, time.Unix(1555000003, 0).UTC().Format(time.RFC3339))
+	c.Check(denials[1]["suggestions"], check.DeepEquals, []interface{}{
+		map[string]interface{}{"interface": "camera", "connected": false},
+		map[string]interface{}{"interface": "test", "plug": "plug", "connected": true},
+	})
+	c.Check(denials[2]["kind"], check.Equals, "seccomp")
+	c.Check(denials[2]["syscall"], check.Equals, 165.0)
+	c.Check(denials[2]["arch"], check.Equals, "amd64")
+	c.Check(denials[2]["suggestions"], check.IsNil)
+}
+
+func (s *debugSandboxDenialsSuite) TestSandboxDenialsFiltered(c *check.C) {
+	s.daemon(c)
+
+	s.AddCleanup(daemon.MockSystemdAuditLogReader(func(n int) (io.ReadCloser, error) {
+		c.Check(n, check.Equals, 10)
+		return ioutil.NopCloser(strings.NewReader(mockAuditLog(
+			fmt.Sprintf(videoDenial, 1),
+			otherDenial,
+		))), nil
+	}))
+
+	rsp := s.syncReq(c, s.getSandboxDenialsReq(c, "&n=10&snap=producer"), nil)
+	denials, ok := rsp.Result.([]*daemon.SandboxDenial)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(denials, check.HasLen, 1)
+	c.Check(denials[0].Snap, check.Equals, "producer")
+	// the built-in interfaces are looked up
+	c.Check(denials[0].Suggestions, testutil.DeepContains, daemon.SandboxDenialSuggestion{Interface: "system-backup"})
+}
+
+func (s *debugSandboxDenialsSuite) TestSandboxDenialsErrors(c *check.C) {
+	s.daemon(c)
+
+	rsp := s.errorReq(c, s.getSandboxDenialsReq(c, "&n=foo"), nil)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Message, check.Equals, `invalid value for n: "foo"`)
+
+	restore := daemon.MockSystemdAuditLogReader(func(n int) (io.ReadCloser, error) {
+		return nil, errors.New("journalctl not found")
+	})
+	rsp = s.errorReq(c, s.getSandboxDenialsReq(c, ""), nil)
+	restore()
+	c.Check(rsp.Status, check.Equals, 500)
+	c.Check(rsp.Message, check.Equals, "cannot read the audit log: journalctl not found")
+
+	s.AddCleanup(daemon.MockSystemdAuditLogReader(func(n int) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("not json")), nil
+	}))
+	rsp = s.errorReq(c, s.getSandboxDenialsReq(c, ""), nil)
+	c.Check(rsp.Status, check.Equals, 500)
+	c.Check(rsp.Message, check.Matches, "cannot decode the audit log: .*")
+}
@@ -23,13 +23,19 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
@@ -111,6 +117,28 @@ func (s *postDebugSuite) TestGetDebugBaseDeclaration(c *check.C) {
 		testutil.Contains, "type: base-declaration")
 }
 
+func (s *postDebugSuite) TestGetDebugGadgetUpdateStatus(c *check.C) {
+	_ = s.daemon(c)
+
+	c.Assert(os.MkdirAll(dirs.SnapRollbackDir, 0755), check.IsNil)
+	err := ioutil.WriteFile(filepath.Join(dirs.SnapRollbackDir, "pc_34.journal"),
+		[]byte(`{"status":"in-progress","attempts":1,"structures":[{"volume":"pc","name":"mbr","yaml-index":0,"state":"updating"}]}`), 0644)
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=gadget-update-status", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	statuses := rsp.Result.([]*devicestate.GadgetUpdateStatus)
+	c.Assert(statuses, check.HasLen, 1)
+	c.Check(statuses[0].Snap, check.Equals, "pc")
+	c.Check(statuses[0].Revision, check.Equals, snap.R(34))
+	c.Check(statuses[0].Status, check.Equals, gadget.UpdateInProgress)
+	c.Check(statuses[0].Structures, check.DeepEquals, []*gadget.UpdateJournalEntry{
+		{Volume: "pc", Name: "mbr", YamlIndex: 0, State: gadget.StructureUpdating},
+	})
+}
+
 func mockDurationThreshold() func() {
 	oldDurationThreshold := timings.DurationThreshold
 	restore := func() {
@@ -26,12 +26,17 @@ import (
 	"net/http"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/naming"
 )
 
 var (
@@ -42,6 +47,17 @@ var (
 		ReadAccess:  openAccess{},
 		WriteAccess: authenticatedAccess{Polkit: polkitActionManageInterfaces},
 	}
+
+	interfacesDevicesCmd = &Command{
+		Path:       "/v2/interfaces/devices",
+		GET:        getDeviceAccess,
+		ReadAccess: openAccess{},
+	}
+)
+
+var (
+	udevQueryDevice = udev.QueryDevice
+	timeNow         = time.Now
 )
 
 // interfacesConnectionsMultiplexer multiplexes to either legacy (connection) or modern behavior (interfaces).
@@ -141,6 +157,20 @@ func changeInterfaces(c *Command, r *http.Request, user *auth.UserState) Respons
 	if len(a.Plugs) == 0 || len(a.Slots) == 0 {
 		return BadRequest("at least one plug and slot is required")
 	}
+	var connectOpts ifacestate.ConnectOptions
+	if a.For != "" || a.UntilReboot {
+		if a.Action != "connect" {
+			return BadRequest("temporary connections are only supported when connecting")
+		}
+		connectOpts.UntilReboot = a.UntilReboot
+	}
+	if a.For != "" {
+		duration, err := time.ParseDuration(a.For)
+		if err != nil || duration <= 0 {
+			return BadRequest("invalid duration for temporary connection: %q", a.For)
+		}
+		connectOpts.Expiry = timeNow().Add(duration)
+	}
 
 	var summary string
 	var err error
@@ -190,8 +220,15 @@ func changeInterfaces(c *Command, r *http.Request, user *auth.UserState) Respons
 			var ts *state.TaskSet
 			affected = snapNamesFromConns([]*interfaces.ConnRef{connRef})
 			summary = fmt.Sprintf("Connect %s:%s to %s:%s", connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name)
-			ts, err = ifacestate.Connect(st, connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name)
+			ts, err = ifacestate.ConnectWithOptions(st, connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name, connectOpts)
 			if _, ok := err.(*ifacestate.ErrAlreadyConnected); ok {
+				// connecting again only updates when a temporary
+				// connection is removed
+				if !connectOpts.Expiry.IsZero() || connectOpts.UntilReboot {
+					if err := ifacestate.UpdateTemporaryConnection(st, connRef, connectOpts); err != nil {
+						return errToResponse(err, nil, BadRequest, "%v")
+					}
+				}
 				change := newChange(st, a.Action+"-snap", summary, nil, affected)
 				change.SetStatus(state.DoneStatus)
 				return AsyncResponse(nil, change.ID())
@@ -254,3 +291,100 @@ func snapNamesFromConns(conns []*interfaces.ConnRef) []string {
 	sort.Strings(l)
 	return l
 }
+
+// getDeviceAccess reports which applications and hooks of snaps are granted
+// access to a given device by the udev rules generated for their interfaces.
+func getDeviceAccess(c *Command, r *http.Request, user *auth.UserState) Response {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		return BadRequest("cannot audit device access: missing path")
+	}
+	dev, err := udevQueryDevice(path)
+	if err != nil {
+		return BadRequest("cannot audit device access: %v", err)
+	}
+	st := c.d.overlord.State()
+	st.Lock()
+	snapStates, err := snapstate.All(st)
+	st.Unlock()
+	if err != nil {
+		return InternalError("cannot audit device access: %v", err)
+	}
+	infos := make([]*snap.Info, 0, len(snapStates))
+	for _, snapst := range snapStates {
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			return InternalError("cannot audit device access: %v", err)
+		}
+		infos = append(infos, info)
+	}
+	repo := c.d.overlord.InterfaceManager().Repository()
+	audit, err := udev.AuditDeviceAccess(repo, infos, dev)
+	if err != nil {
+		return InternalError("cannot audit device access: %v", err)
+	}
+	info := &client.DeviceAccessInfo{
+		Path:      path,
+		DevPath:   dev.DevPath,
+		Subsystem: dev.Subsystem,
+		Access:    make([]client.DeviceAccess, 0, len(audit.Rules)),
+	}
+	for _, rule := range audit.Rules {
+		access, err := deviceAccessFromRule(rule)
+		if err != nil {
+			return InternalError("cannot audit device access: %v", err)
+		}
+		info.Access = append(info.Access, *access)
+	}
+	for _, unevaluated := range audit.Unevaluated {
+		access, err := deviceAccessFromRule(unevaluated.DeviceRule)
+		if err != nil {
+			return InternalError("cannot audit device access: %v", err)
+		}
+		access.Error = unevaluated.Err.Error()
+		info.Unevaluated = append(info.Unevaluated, *access)
+	}
+	for _, unrestricted := range audit.Unrestricted {
+		tag, err := naming.ParseSecurityTag(unrestricted.SecurityTag)
+		if err != nil {
+			return InternalError("cannot audit device access: %v", err)
+		}
+		access := client.UnrestrictedDeviceAccess{
+			Snap:                 tag.InstanceName(),
+			ControlsDeviceCgroup: unrestricted.ControlsDeviceCgroup,
+		}
+		access.App, access.Hook = appAndHookFromTag(tag)
+		info.Unrestricted = append(info.Unrestricted, access)
+	}
+	return SyncResponse(info)
+}
+
+func deviceAccessFromRule(rule udev.DeviceRule) (*client.DeviceAccess, error) {
+	tag, err := naming.ParseSecurityTag(rule.SecurityTag)
+	if err != nil {
+		return nil, err
+	}
+	access := &client.DeviceAccess{
+		Snap:      tag.InstanceName(),
+		Interface: rule.Interface,
+		Rule:      rule.Rule,
+	}
+	access.App, access.Hook = appAndHookFromTag(tag)
+	if rule.Plug != nil {
+		access.Plug = &client.PlugRef{Snap: rule.Plug.Snap, Name: rule.Plug.Name}
+	}
+	if rule.Slot != nil {
+		access.Slot = &client.SlotRef{Snap: rule.Slot.Snap, Name: rule.Slot.Name}
+	}
+	return access, nil
+}
+
+func appAndHookFromTag(tag naming.SecurityTag) (app, hook string) {
+	switch t := tag.(type) {
+	case naming.AppSecurityTag:
+		return t.AppName(), ""
+	case naming.HookSecurityTag:
+		return "", t.HookName()
+	}
+	return "", ""
+}
@@ -26,6 +26,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -34,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/builtin"
 	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/state"
 )
@@ -450,6 +452,182 @@ func (s *interfacesSuite) TestConnectPlugChangeConflict(c *check.C) {
 		"type": "error"})
 }
 
+func (s *interfacesSuite) TestConnectTemporary(c *check.C) {
+	// the overlord loop removes connections which expired in real time
+	now := time.Now().UTC().Truncate(time.Second)
+	restore := daemon.MockTimeNow(func() time.Time { return now })
+	defer restore()
+	d := s.daemon(c)
+
+	mockIface(c, d, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	d.Overlord().Loop()
+	defer d.Overlord().Stop()
+
+	action := &client.InterfaceAction{
+		Action:      "connect",
+		Plugs:       []client.Plug{{Snap: "consumer", Name: "plug"}},
+		Slots:       []client.Slot{{Snap: "producer", Name: "slot"}},
+		For:         "2h",
+		UntilReboot: true,
+	}
+	text, err := json.Marshal(action)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/interfaces", bytes.NewBuffer(text))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil)
+
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	var connectTask *state.Task
+	for _, t := range chg.Tasks() {
+		if t.Kind() == "connect" {
+			connectTask = t
+		}
+	}
+	c.Assert(connectTask, check.NotNil)
+	var expiry time.Time
+	c.Assert(connectTask.Get("expiry", &expiry), check.IsNil)
+	c.Check(expiry.Equal(now.Add(2*time.Hour)), check.Equals, true)
+	var untilReboot bool
+	c.Assert(connectTask.Get("until-reboot", &untilReboot), check.IsNil)
+	c.Check(untilReboot, check.Equals, true)
+}
+
+func (s *interfacesSuite) TestConnectTemporaryAlreadyConnected(c *check.C) {
+	// the overlord loop removes connections which expired in real time
+	now := time.Now().UTC().Truncate(time.Second)
+	restore := daemon.MockTimeNow(func() time.Time { return now })
+	defer restore()
+	d := s.daemon(c)
+
+	mockIface(c, d, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	repo := d.Overlord().InterfaceManager().Repository()
+	connRef := &interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+
+	d.Overlord().Loop()
+	defer d.Overlord().Stop()
+
+	_, err := repo.Connect(connRef, nil, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+			"expiry":    now.Add(time.Hour).Format(time.RFC3339),
+		},
+	})
+	st.Unlock()
+
+	action := &client.InterfaceAction{
+		Action: "connect",
+		Plugs:  []client.Plug{{Snap: "consumer", Name: "plug"}},
+		Slots:  []client.Slot{{Snap: "producer", Name: "slot"}},
+		For:    "3h",
+	}
+	text, err := json.Marshal(action)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/interfaces", bytes.NewBuffer(text))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Tasks(), check.HasLen, 0)
+	c.Check(chg.Status(), check.Equals, state.DoneStatus)
+	// the expiry was updated
+	var conns map[string]interface{}
+	c.Assert(st.Get("conns", &conns), check.IsNil)
+	c.Check(conns["consumer:plug producer:slot"], check.DeepEquals, map[string]interface{}{
+		"interface": "test",
+		"expiry":    now.Add(3 * time.Hour).Format(time.RFC3339),
+	})
+}
+
+func (s *interfacesSuite) TestConnectTemporaryAlreadyConnectedPermanently(c *check.C) {
+	d := s.daemon(c)
+
+	mockIface(c, d, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	repo := d.Overlord().InterfaceManager().Repository()
+	connRef := &interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+	_, err := repo.Connect(connRef, nil, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+		},
+	})
+	st.Unlock()
+
+	action := &client.InterfaceAction{
+		Action:      "connect",
+		Plugs:       []client.Plug{{Snap: "consumer", Name: "plug"}},
+		Slots:       []client.Slot{{Snap: "producer", Name: "slot"}},
+		UntilReboot: true,
+	}
+	text, err := json.Marshal(action)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/interfaces", bytes.NewBuffer(text))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `cannot make existing permanent connection "consumer:plug producer:slot" temporary`)
+}
+
+func (s *interfacesSuite) TestConnectTemporaryErrors(c *check.C) {
+	d := s.daemon(c)
+
+	mockIface(c, d, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	for _, tc := range []struct {
+		action *client.InterfaceAction
+		err    string
+	}{{
+		action: &client.InterfaceAction{Action: "connect", For: "foo"},
+		err:    `invalid duration for temporary connection: "foo"`,
+	}, {
+		action: &client.InterfaceAction{Action: "connect", For: "-1h"},
+		err:    `invalid duration for temporary connection: "-1h"`,
+	}, {
+		action: &client.InterfaceAction{Action: "disconnect", UntilReboot: true},
+		err:    `temporary connections are only supported when connecting`,
+	}} {
+		tc.action.Plugs = []client.Plug{{Snap: "consumer", Name: "plug"}}
+		tc.action.Slots = []client.Slot{{Snap: "producer", Name: "slot"}}
+		text, err := json.Marshal(tc.action)
+		c.Assert(err, check.IsNil)
+		req, err := http.NewRequest("POST", "/v2/interfaces", bytes.NewBuffer(text))
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, 400)
+		c.Check(rspe.Message, check.Equals, tc.err)
+	}
+}
+
 func (s *interfacesSuite) TestConnectCoreSystemAlias(c *check.C) {
 	revert := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
 	defer revert()
@@ -1185,3 +1363,109 @@ func (s *interfacesSuite) TestInterfacesModern(c *check.C) {
 		"type":        "sync",
 	})
 }
+
+// Tests for GET /v2/interfaces/devices
+
+func (s *interfacesSuite) TestDeviceAccess(c *check.C) {
+	restore := builtin.MockInterface(&ifacetest.TestInterface{
+		InterfaceName: "test",
+		UDevConnectedPlugCallback: func(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.TagDevice(`SUBSYSTEM=="tty", KERNEL=="ttyUSB[0-9]*"`)
+			spec.TagDevice(`PROGRAM=="/bin/true"`)
+			return nil
+		},
+	})
+	defer restore()
+	restore = daemon.MockUdevQueryDevice(func(path string) (*udev.Device, error) {
+		c.Check(path, check.Equals, "/dev/ttyUSB0")
+		return &udev.Device{
+			DevPath:   "/devices/usb1/1-1/ttyUSB0/tty/ttyUSB0",
+			Kernel:    "ttyUSB0",
+			Subsystem: "tty",
+		}, nil
+	})
+	defer restore()
+
+	d := s.daemon(c)
+
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	repo := d.Overlord().InterfaceManager().Repository()
+	err := repo.AddBackend(&udev.Backend{})
+	c.Assert(err, check.IsNil)
+	connRef := &interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+	_, err = repo.Connect(connRef, nil, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/interfaces/devices?path=/dev/ttyUSB0", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, &client.DeviceAccessInfo{
+		Path:      "/dev/ttyUSB0",
+		DevPath:   "/devices/usb1/1-1/ttyUSB0/tty/ttyUSB0",
+		Subsystem: "tty",
+		Access: []client.DeviceAccess{{
+			Snap:      "consumer",
+			App:       "app",
+			Interface: "test",
+			Plug:      &client.PlugRef{Snap: "consumer", Name: "plug"},
+			Slot:      &client.SlotRef{Snap: "producer", Name: "slot"},
+			Rule:      `SUBSYSTEM=="tty", KERNEL=="ttyUSB[0-9]*"`,
+		}},
+		Unevaluated: []client.DeviceAccess{{
+			Snap:      "consumer",
+			App:       "app",
+			Interface: "test",
+			Plug:      &client.PlugRef{Snap: "consumer", Name: "plug"},
+			Slot:      &client.SlotRef{Snap: "producer", Name: "slot"},
+			Rule:      `PROGRAM=="/bin/true"`,
+			Error:     `unsupported udev rule key "PROGRAM"`,
+		}},
+		// no devices are tagged for the producer app
+		Unrestricted: []client.UnrestrictedDeviceAccess{
+			{Snap: "producer", App: "app"},
+		},
+	})
+}
+
+func (s *interfacesSuite) TestDeviceAccessNoMatch(c *check.C) {
+	restore := daemon.MockUdevQueryDevice(func(path string) (*udev.Device, error) {
+		return &udev.Device{DevPath: "/devices/virtual/mem/null", Kernel: "null", Subsystem: "mem"}, nil
+	})
+	defer restore()
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/interfaces/devices?path=/dev/null", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, &client.DeviceAccessInfo{
+		Path:      "/dev/null",
+		DevPath:   "/devices/virtual/mem/null",
+		Subsystem: "mem",
+		Access:    []client.DeviceAccess{},
+	})
+}
+
+func (s *interfacesSuite) TestDeviceAccessErrors(c *check.C) {
+	restore := daemon.MockUdevQueryDevice(func(path string) (*udev.Device, error) {
+		return nil, fmt.Errorf("cannot run udevadm: boom")
+	})
+	defer restore()
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/interfaces/devices", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, "cannot audit device access: missing path")
+
+	req, err = http.NewRequest("GET", "/v2/interfaces/devices?path=/dev/foo", nil)
+	c.Assert(err, check.IsNil)
+	rspe = s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, "cannot audit device access: cannot run udevadm: boom")
+}
@@ -20,6 +20,8 @@
 package daemon
 
 import (
+	"time"
+
 	"github.com/snapcore/snapd/interfaces"
 )
 
@@ -62,6 +64,9 @@ type interfaceAction struct {
 	Forget bool       `json:"forget,omitempty"`
 	Plugs  []plugJSON `json:"plugs,omitempty"`
 	Slots  []slotJSON `json:"slots,omitempty"`
+	// For and UntilReboot make a connection temporary
+	For         string `json:"for,omitempty"`
+	UntilReboot bool   `json:"until-reboot,omitempty"`
 }
 
 // connectionsJSON aids in marshalling information about a single connection
@@ -74,6 +79,9 @@ type connectionJSON struct {
 	Gadget    bool                   `json:"gadget,omitempty"`
 	SlotAttrs map[string]interface{} `json:"slot-attrs,omitempty"`
 	PlugAttrs map[string]interface{} `json:"plug-attrs,omitempty"`
+	// Expiry and UntilReboot are set for temporary connections
+	Expiry      *time.Time `json:"expiry,omitempty"`
+	UntilReboot bool       `json:"until-reboot,omitempty"`
 }
 
 // legacyConnectionsJSON aids in marshaling legacy connections into JSON.
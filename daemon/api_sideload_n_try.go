@@ -29,6 +29,8 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/snapasserts"
@@ -186,12 +188,36 @@ func sideloadOrTrySnap(c *Command, body io.ReadCloser, boundary string, user *au
 		return errRsp
 	}
 
+	var deltaFrom string
+	var deltaAsserts *asserts.Batch
+	if len(form.Values["delta-from"]) > 0 {
+		if len(snapFiles) != 1 {
+			return BadRequest("cannot install multiple snaps from deltas")
+		}
+		if sideloadFlags.dangerousOK {
+			return BadRequest("cannot install snap from delta in dangerous mode")
+		}
+		deltaFrom = form.Values["delta-from"][0]
+		// the snap-revision assertion (and its prerequisites) for the
+		// reconstructed snap can be provided together with the delta
+		if len(form.Values["assertion"]) > 0 {
+			deltaAsserts = asserts.NewBatch(nil)
+			for _, a := range form.Values["assertion"] {
+				if _, err := deltaAsserts.AddStream(strings.NewReader(a)); err != nil {
+					return BadRequest("cannot decode assertions provided with delta: %v", err)
+				}
+			}
+		}
+	}
+
 	st := c.d.overlord.State()
 	st.Lock()
 	defer st.Unlock()
 
 	var chg *state.Change
-	if len(snapFiles) > 1 {
+	if deltaFrom != "" {
+		chg, errRsp = sideloadSnapFromDelta(st, snapFiles[0], deltaFrom, deltaAsserts, sideloadFlags)
+	} else if len(snapFiles) > 1 {
 		chg, errRsp = sideloadManySnaps(st, snapFiles, sideloadFlags, user)
 	} else {
 		chg, errRsp = sideloadSnap(st, snapFiles[0], sideloadFlags)
@@ -295,6 +321,112 @@ func sideloadSnap(st *state.State, snapFile *uploadedSnap, flags sideloadFlags)
 	return chg, nil
 }
 
+// sideloadSnapFromDelta reconstructs a snap by applying the uploaded delta
+// to the given installed revision of a snap and installs the result, which
+// must match a snap-revision assertion either provided with the delta or
+// already in the database.
+func sideloadSnapFromDelta(st *state.State, snapFile *uploadedSnap, deltaFrom string, deltaAsserts *asserts.Batch, flags sideloadFlags) (*state.Change, *apiError) {
+	fromRev, err := snap.ParseRevision(deltaFrom)
+	if err != nil {
+		return nil, BadRequest("invalid revision to apply delta to: %v", err)
+	}
+
+	instanceName, apiErr := deltaSourceSnap(st, snapFile.instanceName, fromRev)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	deviceCtx, err := snapstate.DevicePastSeeding(st, nil)
+	if err != nil {
+		return nil, InternalError(err.Error())
+	}
+
+	if deltaAsserts != nil {
+		if err := assertstate.AddBatch(st, deltaAsserts, &asserts.CommitOptions{Precheck: true}); err != nil {
+			return nil, BadRequest("cannot add assertions provided with delta: %v", err)
+		}
+	}
+
+	// the reconstructed snap replaces the uploaded delta, which is
+	// removed together with the rest of the form
+	deltaPath := snapFile.tmpPath
+	targetPath := deltaPath + ".snap"
+	err = snapstateApplyLocalDelta(st, instanceName, fromRev, deltaPath, targetPath)
+	if err != nil {
+		return nil, BadRequest("cannot apply delta to revision %s of snap %q: %v", fromRev, instanceName, err)
+	}
+	removeTarget := true
+	defer func() {
+		if removeTarget {
+			os.Remove(targetPath)
+		}
+	}()
+
+	sideInfo, err := snapasserts.DeriveSideInfo(targetPath, deviceCtx.Model(), assertstate.DB(st))
+	if err != nil {
+		if errors.Is(err, &asserts.NotFoundError{}) {
+			return nil, BadRequest("cannot find snap-revision assertion for snap %q reconstructed from delta %q", instanceName, snapFile.filename)
+		}
+		return nil, BadRequest(err.Error())
+	}
+	if sideInfo.RealName != snap.InstanceSnap(instanceName) {
+		return nil, BadRequest("snap reconstructed from delta is %q, not %q", sideInfo.RealName, snap.InstanceSnap(instanceName))
+	}
+
+	tset, _, err := snapstateInstallPath(st, sideInfo, targetPath, instanceName, "", flags.Flags)
+	if err != nil {
+		return nil, errToResponse(err, []string{instanceName}, InternalError, "cannot install snap from delta: %v")
+	}
+
+	msg := fmt.Sprintf(i18n.G("Install %q snap from delta %q"), instanceName, snapFile.filename)
+	chg := newChange(st, "install-snap", msg, []*state.TaskSet{tset}, []string{instanceName})
+	chg.Set("api-data", map[string]string{"snap-name": instanceName})
+
+	// the change is now in charge of the reconstructed snap
+	removeTarget = false
+	snapFile.tmpPath = targetPath
+
+	return chg, nil
+}
+
+// deltaSourceSnap returns the name of the installed snap the delta applies
+// to, which must be given unless only one installed snap has the revision.
+func deltaSourceSnap(st *state.State, instanceName string, rev snap.Revision) (string, *apiError) {
+	if instanceName != "" {
+		if err := snap.ValidateInstanceName(instanceName); err != nil {
+			return "", BadRequest(err.Error())
+		}
+		var snapst snapstate.SnapState
+		if err := snapstate.Get(st, instanceName, &snapst); err != nil && !errors.Is(err, state.ErrNoState) {
+			return "", InternalError(err.Error())
+		}
+		if snapst.LastIndex(rev) < 0 {
+			return "", BadRequest("cannot apply delta: revision %s of snap %q is not installed", rev, instanceName)
+		}
+		return instanceName, nil
+	}
+
+	all, err := snapstate.All(st)
+	if err != nil {
+		return "", InternalError(err.Error())
+	}
+	var candidates []string
+	for name, snapst := range all {
+		if snapst.LastIndex(rev) >= 0 {
+			candidates = append(candidates, name)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return "", BadRequest("cannot apply delta: no installed snap has revision %s", rev)
+	case 1:
+		return candidates[0], nil
+	default:
+		sort.Strings(candidates)
+		return "", BadRequest("cannot apply delta: revision %s is installed for snaps %s, the snap name must be given", rev, strutil.Quoted(candidates))
+	}
+}
+
 func readSideInfo(st *state.State, tempPath string, origPath string, flags sideloadFlags, model *asserts.Model) (*snap.SideInfo, *apiError) {
 	var sideInfo *snap.SideInfo
 
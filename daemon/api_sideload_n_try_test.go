@@ -39,6 +39,7 @@ import (
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/assertstate/assertstatetest"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
@@ -987,6 +988,256 @@ version: 1`, snap), nil)
 	return snapData
 }
 
+type deltaApplier struct {
+	target []byte
+	calls  [][]string
+}
+
+func (da *deltaApplier) apply(st *state.State, instanceName string, fromRev snap.Revision, deltaPath, targetPath string) error {
+	da.calls = append(da.calls, []string{instanceName, fromRev.String(), deltaPath, targetPath})
+	if da.target == nil {
+		return errors.New("xdelta3 failed")
+	}
+	return ioutil.WriteFile(targetPath, da.target, 0600)
+}
+
+// setupDeltaInstall mocks installed snaps and the application of deltas
+// producing target, returning the assertions for the target snap, which
+// are added to the database if ack is set.
+func (s *sideloadSuite) setupDeltaInstall(c *check.C, target []byte, ack bool) (*state.State, *deltaApplier, []asserts.Assertion) {
+	d := s.daemonWithOverlordMockAndStore()
+	s.markSeeded(d)
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+
+	da := &deltaApplier{target: target}
+	s.AddCleanup(daemon.MockSnapstateApplyLocalDelta(da.apply))
+
+	for _, name := range []string{"foo", "bar"} {
+		snapstate.Set(st, name, &snapstate.SnapState{
+			Active: true,
+			Sequence: []*snap.SideInfo{
+				{RealName: name, SnapID: name + "-id", Revision: snap.R(40)},
+			},
+			Current: snap.R(40),
+		})
+	}
+
+	if target == nil {
+		return st, da, nil
+	}
+
+	digest, size, err := asserts.SnapFileSHA3_384(writeTempFile(c, target))
+	c.Assert(err, check.IsNil)
+	dev1Acct := assertstest.NewAccount(s.StoreSigning, "devel1", nil, "")
+	snapDecl, err := s.StoreSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "foo-id",
+		"snap-name":    "foo",
+		"publisher-id": dev1Acct.AccountID(),
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+	snapRev, err := s.StoreSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"snap-sha3-384": digest,
+		"snap-size":     fmt.Sprintf("%d", size),
+		"snap-id":       "foo-id",
+		"snap-revision": "41",
+		"developer-id":  dev1Acct.AccountID(),
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+	as := []asserts.Assertion{dev1Acct, snapDecl, snapRev}
+	assertstatetest.AddMany(st, s.StoreSigning.StoreAccountKey(""))
+	if ack {
+		assertstatetest.AddMany(st, as...)
+	}
+
+	return st, da, as
+}
+
+func writeTempFile(c *check.C, content []byte) string {
+	path := filepath.Join(c.MkDir(), "file")
+	c.Assert(ioutil.WriteFile(path, content, 0644), check.IsNil)
+	return path
+}
+
+func deltaInstallRequest(c *check.C, fields map[string]string) *http.Request {
+	body := "----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap\"; filename=\"foo.xdelta3\"\r\n" +
+		"\r\n" +
+		"delta\r\n"
+	for _, name := range []string{"name", "snap-path", "delta-from", "dangerous", "assertion"} {
+		if value, ok := fields[name]; ok {
+			body += "----hello--\r\n" +
+				fmt.Sprintf("Content-Disposition: form-data; name=%q\r\n", name) +
+				"\r\n" +
+				value + "\r\n"
+		}
+	}
+	body += "----hello--\r\n"
+	req, err := http.NewRequest("POST", "/v2/snaps", bytes.NewBufferString(body))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "multipart/thing; boundary=--hello--")
+	return req
+}
+
+func makeDeltaTarget(c *check.C) []byte {
+	fooSnap := snaptest.MakeTestSnapWithFiles(c, `name: foo
+version: 1`, nil)
+	target, err := ioutil.ReadFile(fooSnap)
+	c.Assert(err, check.IsNil)
+	return target
+}
+
+func (s *sideloadSuite) TestSideloadSnapFromDelta(c *check.C) {
+	target := makeDeltaTarget(c)
+	st, da, _ := s.setupDeltaInstall(c, target, true)
+
+	var installPath string
+	defer daemon.MockSnapstateInstallPath(func(s *state.State, si *snap.SideInfo, path, name, channel string, flags snapstate.Flags) (*state.TaskSet, *snap.Info, error) {
+		c.Check(si, check.DeepEquals, &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "foo-id",
+			Revision: snap.R(41),
+		})
+		c.Check(name, check.Equals, "foo")
+		c.Check(path, testutil.FileEquals, target)
+		installPath = path
+		return state.NewTaskSet(), &snap.Info{SuggestedName: "foo"}, nil
+	})()
+
+	req := deltaInstallRequest(c, map[string]string{
+		"name":       "foo",
+		"snap-path":  "foo.xdelta3",
+		"delta-from": "40",
+	})
+	rsp := s.asyncReq(c, req, nil)
+
+	c.Assert(da.calls, check.HasLen, 1)
+	c.Check(da.calls[0][0], check.Equals, "foo")
+	c.Check(da.calls[0][1], check.Equals, "40")
+	c.Check(da.calls[0][3], check.Equals, installPath)
+	// the delta was removed, the reconstructed snap was handed to the change
+	c.Check(da.calls[0][2], testutil.FileAbsent)
+	c.Check(installPath, testutil.FilePresent)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Summary(), check.Equals, `Install "foo" snap from delta "foo.xdelta3"`)
+}
+
+func (s *sideloadSuite) TestSideloadSnapFromDeltaFindsSnap(c *check.C) {
+	st, da, _ := s.setupDeltaInstall(c, makeDeltaTarget(c), true)
+	st.Lock()
+	snapstate.Set(st, "bar", nil)
+	st.Unlock()
+
+	defer daemon.MockSnapstateInstallPath(func(s *state.State, si *snap.SideInfo, path, name, channel string, flags snapstate.Flags) (*state.TaskSet, *snap.Info, error) {
+		c.Check(name, check.Equals, "foo")
+		return state.NewTaskSet(), &snap.Info{SuggestedName: "foo"}, nil
+	})()
+
+	req := deltaInstallRequest(c, map[string]string{"delta-from": "40"})
+	s.asyncReq(c, req, nil)
+	c.Assert(da.calls, check.HasLen, 1)
+	c.Check(da.calls[0][0], check.Equals, "foo")
+}
+
+func (s *sideloadSuite) TestSideloadSnapFromDeltaWithAssertions(c *check.C) {
+	target := makeDeltaTarget(c)
+	st, _, as := s.setupDeltaInstall(c, target, false)
+
+	defer daemon.MockSnapstateInstallPath(func(s *state.State, si *snap.SideInfo, path, name, channel string, flags snapstate.Flags) (*state.TaskSet, *snap.Info, error) {
+		c.Check(si.SnapID, check.Equals, "foo-id")
+		c.Check(si.Revision, check.Equals, snap.R(41))
+		return state.NewTaskSet(), &snap.Info{SuggestedName: "foo"}, nil
+	})()
+
+	buf := new(bytes.Buffer)
+	enc := asserts.NewEncoder(buf)
+	for _, a := range as {
+		c.Assert(enc.Encode(a), check.IsNil)
+	}
+	req := deltaInstallRequest(c, map[string]string{
+		"name":       "foo",
+		"snap-path":  "foo.xdelta3",
+		"delta-from": "40",
+		"assertion":  buf.String(),
+	})
+	s.asyncReq(c, req, nil)
+
+	// the provided assertions were added to the database
+	st.Lock()
+	defer st.Unlock()
+	_, err := assertstate.DB(st).Find(asserts.SnapRevisionType, map[string]string{
+		"snap-sha3-384": as[2].(*asserts.SnapRevision).SnapSHA3_384(),
+	})
+	c.Check(err, check.IsNil)
+}
+
+func (s *sideloadSuite) TestSideloadSnapFromDeltaInvalidAssertions(c *check.C) {
+	s.setupDeltaInstall(c, nil, false)
+
+	req := deltaInstallRequest(c, map[string]string{
+		"name":       "foo",
+		"snap-path":  "foo.xdelta3",
+		"delta-from": "40",
+		"assertion":  "type: snap-revision\n\nbogus",
+	})
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Matches, `cannot decode assertions provided with delta: .*`)
+}
+
+func (s *sideloadSuite) TestSideloadSnapFromDeltaErrors(c *check.C) {
+	s.setupDeltaInstall(c, nil, false)
+
+	for _, t := range []struct {
+		fields map[string]string
+		err    string
+	}{
+		{map[string]string{"delta-from": "40", "dangerous": "true"}, `cannot install snap from delta in dangerous mode`},
+		{map[string]string{"delta-from": "foo"}, `invalid revision to apply delta to: invalid snap revision: "foo"`},
+		{map[string]string{"delta-from": "40"}, `cannot apply delta: revision 40 is installed for snaps "bar", "foo", the snap name must be given`},
+		{map[string]string{"delta-from": "41"}, `cannot apply delta: no installed snap has revision 41`},
+		{map[string]string{"delta-from": "41", "name": "foo", "snap-path": "foo.xdelta3"}, `cannot apply delta: revision 41 of snap "foo" is not installed`},
+		{map[string]string{"delta-from": "40", "name": "foo", "snap-path": "foo.xdelta3"}, `cannot apply delta to revision 40 of snap "foo": xdelta3 failed`},
+	} {
+		rspe := s.errorReq(c, deltaInstallRequest(c, t.fields), nil)
+		c.Check(rspe.Status, check.Equals, 400)
+		c.Check(rspe.Message, check.Equals, t.err)
+	}
+}
+
+func (s *sideloadSuite) TestSideloadSnapFromDeltaNoAssertion(c *check.C) {
+	st, da, _ := s.setupDeltaInstall(c, nil, false)
+	da.target = []byte("unknown")
+
+	defer daemon.MockSnapstateInstallPath(func(s *state.State, si *snap.SideInfo, path, name, channel string, flags snapstate.Flags) (*state.TaskSet, *snap.Info, error) {
+		c.Fatalf("unexpected install")
+		return nil, nil, nil
+	})()
+
+	req := deltaInstallRequest(c, map[string]string{
+		"name":       "foo",
+		"snap-path":  "foo.xdelta3",
+		"delta-from": "40",
+	})
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Message, check.Equals, `cannot find snap-revision assertion for snap "foo" reconstructed from delta "foo.xdelta3"`)
+	// the reconstructed snap was removed
+	c.Assert(da.calls, check.HasLen, 1)
+	c.Check(da.calls[0][3], testutil.FileAbsent)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 0)
+}
+
 type trySuite struct {
 	apiBaseSuite
 }
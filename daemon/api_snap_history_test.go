@@ -0,0 +1,83 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/snapstate"
+)
+
+var _ = check.Suite(&snapHistorySuite{})
+
+type snapHistorySuite struct {
+	apiBaseSuite
+}
+
+func (s *snapHistorySuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.expectOpenAccess()
+}
+
+func (s *snapHistorySuite) TestGetSnapHistory(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, "name: foo\nversion: 1")
+
+	st := d.Overlord().State()
+	st.Lock()
+	err := snapstate.HoldRefreshesBySystem(st, snapstate.HoldGeneral, "forever", []string{"foo"})
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/history", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	history, ok := rsp.Result.([]*snapstate.HistoryEntry)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(history, check.HasLen, 1)
+	c.Check(history[0].Action, check.Equals, snapstate.HistoryHold)
+	c.Check(history[0].Trigger, check.Equals, snapstate.HistoryTriggerManual)
+	c.Check(history[0].HeldBy, check.Equals, "system")
+}
+
+func (s *snapHistorySuite) TestGetSnapHistoryEmpty(c *check.C) {
+	s.daemon(c)
+	s.mockSnap(c, "name: foo\nversion: 1")
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/history", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, []*snapstate.HistoryEntry{})
+}
+
+func (s *snapHistorySuite) TestGetSnapHistoryNotInstalled(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/history", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 404)
+	c.Check(rspe.Kind, check.Equals, client.ErrorKindSnapNotFound)
+	c.Check(rspe.Message, check.Equals, `snap "foo" is not installed`)
+}
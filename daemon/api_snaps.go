@@ -211,6 +211,7 @@ type snapInstruction struct {
 	QuotaGroupName         string                 `json:"quota-group"`
 	Time                   string                 `json:"time"`
 	HoldLevel              string                 `json:"hold-level"`
+	Plan                   bool                   `json:"plan"`
 
 	// The fields below should not be unmarshalled into. Do not export them.
 	userID int
@@ -308,6 +309,10 @@ func (inst *snapInstruction) validate() error {
 		}
 	}
 
+	if inst.Plan && (inst.Action != "refresh" || len(inst.ValidationSets) > 0) {
+		return errors.New(`plan can only be specified for the "refresh" action without validation sets`)
+	}
+
 	if inst.Action != "hold" {
 		if inst.Time != "" {
 			return errors.New(`time can only be specified for the "hold" action`)
@@ -599,6 +604,10 @@ func snapOpMany(c *Command, r *http.Request, user *auth.UserState) Response {
 		inst.userID = user.ID
 	}
 
+	if inst.Plan {
+		return snapRefreshPlan(&inst, st)
+	}
+
 	op := inst.dispatchForMany()
 	if op == nil {
 		return BadRequest("unsupported multi-snap operation %q", inst.Action)
@@ -731,6 +740,27 @@ func snapUpdateMany(inst *snapInstruction, st *state.State) (*snapInstructionRes
 	}, nil
 }
 
+// snapRefreshPlan computes what refreshing the given snaps (or all snaps)
+// would do, without performing the refresh.
+func snapRefreshPlan(inst *snapInstruction, st *state.State) Response {
+	// like an actual refresh the plan needs refreshed snap-declarations to
+	// take refresh-control into account; validation-set assertions are not
+	// updated (this is implied by passing nil opts) as that would change
+	// what is tracked
+	if err := assertstateRefreshSnapAssertions(st, inst.userID, nil); err != nil {
+		return inst.errToResponse(err)
+	}
+
+	// TODO: use a per-request context
+	plan, err := snapstateUpdateManyPlan(context.TODO(), st, inst.Snaps, inst.userID, &snapstate.Flags{
+		IgnoreRunning: inst.IgnoreRunning,
+	})
+	if err != nil {
+		return inst.errToResponse(err)
+	}
+	return SyncResponse(plan)
+}
+
 func snapEnforceValidationSets(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
 	if len(inst.ValidationSets) > 0 && len(inst.Snaps) != 0 {
 		return nil, fmt.Errorf("snap names cannot be specified with validation sets to enforce")
@@ -702,6 +702,95 @@ func (s *snapsSuite) TestRefreshMany(c *check.C) {
 	c.Check(refreshAssertionsOpts.IsRefreshOfAllSnaps, check.Equals, false)
 }
 
+func (s *snapsSuite) TestPostSnapsRefreshPlan(c *check.C) {
+	s.daemonWithOverlordMockAndStore()
+
+	refreshSnapAssertions := false
+	defer daemon.MockAssertstateRefreshSnapAssertions(func(s *state.State, userID int, opts *assertstate.RefreshAssertionsOptions) error {
+		refreshSnapAssertions = true
+		c.Check(opts, check.IsNil)
+		return nil
+	})()
+	defer daemon.MockSnapstateUpdateMany(func(_ context.Context, s *state.State, names []string, _ []*snapstate.RevisionOptions, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error) {
+		c.Fatalf("unexpected refresh")
+		return nil, nil, nil
+	})()
+
+	var calledNames []string
+	defer daemon.MockSnapstateUpdateManyPlan(func(_ context.Context, s *state.State, names []string, userID int, flags *snapstate.Flags) (*snapstate.RefreshPlan, error) {
+		calledNames = names
+		return &snapstate.RefreshPlan{
+			Refresh: []*snapstate.RefreshPlanSnap{{
+				InstanceName:    "foo",
+				Type:            snap.TypeKernel,
+				CurrentRevision: snap.R(1),
+				Revision:        snap.R(2),
+				RebootRequired:  true,
+			}},
+			RequiredSpace:  1234,
+			RebootRequired: true,
+		}, nil
+	})()
+
+	buf := strings.NewReader(`{"action": "refresh", "snaps": ["foo", "bar"], "plan": true}`)
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp := s.syncReq(c, req, nil)
+	c.Check(refreshSnapAssertions, check.Equals, true)
+	c.Check(calledNames, check.DeepEquals, []string{"foo", "bar"})
+	plan, ok := rsp.Result.(*snapstate.RefreshPlan)
+	c.Assert(ok, check.Equals, true)
+	c.Check(plan.RebootRequired, check.Equals, true)
+	c.Check(plan.RequiredSpace, check.Equals, uint64(1234))
+	c.Assert(plan.Refresh, check.HasLen, 1)
+	c.Check(plan.Refresh[0].InstanceName, check.Equals, "foo")
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 0)
+}
+
+func (s *snapsSuite) TestPostSnapsRefreshPlanAssertionsError(c *check.C) {
+	s.daemonWithOverlordMockAndStore()
+
+	defer daemon.MockAssertstateRefreshSnapAssertions(func(s *state.State, userID int, opts *assertstate.RefreshAssertionsOptions) error {
+		return errors.New("boom")
+	})()
+	defer daemon.MockSnapstateUpdateManyPlan(func(_ context.Context, s *state.State, names []string, userID int, flags *snapstate.Flags) (*snapstate.RefreshPlan, error) {
+		c.Fatalf("unexpected plan")
+		return nil, nil
+	})()
+
+	buf := strings.NewReader(`{"action": "refresh", "plan": true}`)
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, "cannot refresh: boom")
+}
+
+func (s *snapsSuite) TestPostSnapsPlanOnlyForRefresh(c *check.C) {
+	s.daemonWithOverlordMockAndStore()
+
+	for _, body := range []string{
+		`{"action": "install", "snaps": ["foo"], "plan": true}`,
+		`{"action": "refresh", "validation-sets": ["foo/bar"], "plan": true}`,
+	} {
+		req, err := http.NewRequest("POST", "/v2/snaps", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, 400)
+		c.Check(rspe.Message, testutil.Contains, `plan can only be specified for the "refresh" action without validation sets`)
+	}
+}
+
 func (s *snapsSuite) TestRefreshManyIgnoreRunning(c *check.C) {
 	defer daemon.MockAssertstateRefreshSnapAssertions(func(s *state.State, userID int, opts *assertstate.RefreshAssertionsOptions) error {
 		return nil
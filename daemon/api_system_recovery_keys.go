@@ -25,6 +25,7 @@ import (
 
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/secboot/keys"
 )
 
 var systemRecoveryKeysCmd = &Command{
@@ -48,10 +49,17 @@ func getSystemRecoveryKeys(c *Command, r *http.Request, user *auth.UserState) Re
 	return SyncResponse(keys)
 }
 
-var deviceManagerRemoveRecoveryKeys = (*devicestate.DeviceManager).RemoveRecoveryKeys
+var (
+	deviceManagerRemoveRecoveryKeys = (*devicestate.DeviceManager).RemoveRecoveryKeys
+	deviceManagerAddRecoveryKey     = (*devicestate.DeviceManager).AddRecoveryKey
+	deviceManagerRemoveRecoveryKey  = (*devicestate.DeviceManager).RemoveRecoveryKey
+	deviceManagerListRecoveryKeys   = (*devicestate.DeviceManager).ListRecoveryKeys
+)
 
 type postSystemRecoveryKeysData struct {
 	Action string `json:"action"`
+	// Name of the recovery key for the add action, optional for remove
+	Name string `json:"name,omitempty"`
 }
 
 func postSystemRecoveryKeys(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -70,14 +78,48 @@ func postSystemRecoveryKeys(c *Command, r *http.Request, user *auth.UserState) R
 		return BadRequest("missing recovery keys action")
 	default:
 		return BadRequest("unsupported recovery keys action %q", postData.Action)
+	case "add":
+		if postData.Name == "" {
+			return BadRequest("recovery key name is required for action %q", postData.Action)
+		}
+		if err := keys.ValidateRecoveryKeyName(postData.Name); err != nil {
+			return BadRequest(err.Error())
+		}
 	case "remove":
-		// only currently supported action
+		if postData.Name != "" {
+			if err := keys.ValidateRecoveryKeyName(postData.Name); err != nil {
+				return BadRequest(err.Error())
+			}
+		}
+	case "list":
 	}
 	st := c.d.overlord.State()
 	st.Lock()
 	defer st.Unlock()
 
-	err := deviceManagerRemoveRecoveryKeys(c.d.overlord.DeviceManager())
+	deviceMgr := c.d.overlord.DeviceManager()
+	switch postData.Action {
+	case "add":
+		info, err := deviceManagerAddRecoveryKey(deviceMgr, postData.Name)
+		if err != nil {
+			return InternalError(err.Error())
+		}
+		return SyncResponse(info)
+	case "list":
+		infos, err := deviceManagerListRecoveryKeys(deviceMgr)
+		if err != nil {
+			return InternalError(err.Error())
+		}
+		return SyncResponse(infos)
+	}
+
+	var err error
+	if postData.Name == "" {
+		// without a name all recovery keys are removed, as before
+		err = deviceManagerRemoveRecoveryKeys(deviceMgr)
+	} else {
+		err = deviceManagerRemoveRecoveryKey(deviceMgr, postData.Name)
+	}
 	if err != nil {
 		return InternalError(err.Error())
 	}
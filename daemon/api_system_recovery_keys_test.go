@@ -166,3 +166,94 @@ func (s *recoveryKeysSuite) TestPostSystemRecoveryKeysActionRemoveError(c *C) {
 	c.Check(rspe, DeepEquals, daemon.InternalError("boom"))
 	c.Check(called, Equals, 1)
 }
+
+func (s *recoveryKeysSuite) TestPostSystemRecoveryKeysActionAdd(c *C) {
+	s.daemon(c)
+
+	defer daemon.MockDeviceManagerAddRecoveryKey(func(name string) (*client.RecoveryKeyInfo, error) {
+		c.Check(name, Equals, "backup")
+		return &client.RecoveryKeyInfo{
+			Name:        name,
+			RecoveryKey: "61665-00531-54469-09783-47273-19035-40077-28287",
+			Volumes:     []string{"ubuntu-data", "ubuntu-save"},
+		}, nil
+	})()
+
+	buf := bytes.NewBufferString(`{"action":"add","name":"backup"}`)
+	req, err := http.NewRequest("POST", "/v2/system-recovery-keys", buf)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, &client.RecoveryKeyInfo{
+		Name:        "backup",
+		RecoveryKey: "61665-00531-54469-09783-47273-19035-40077-28287",
+		Volumes:     []string{"ubuntu-data", "ubuntu-save"},
+	})
+}
+
+func (s *recoveryKeysSuite) TestPostSystemRecoveryKeysActionAddErrors(c *C) {
+	s.daemon(c)
+
+	defer daemon.MockDeviceManagerAddRecoveryKey(func(name string) (*client.RecoveryKeyInfo, error) {
+		return nil, errors.New("boom")
+	})()
+
+	for _, tc := range []struct {
+		body string
+		err  *daemon.APIError
+	}{
+		{`{"action":"add"}`, daemon.BadRequest(`recovery key name is required for action "add"`)},
+		{`{"action":"add","name":"Bad Name"}`, daemon.BadRequest(`invalid recovery key name "Bad Name"`)},
+		{`{"action":"remove","name":"-bad"}`, daemon.BadRequest(`invalid recovery key name "-bad"`)},
+		{`{"action":"add","name":"backup"}`, daemon.InternalError("boom")},
+	} {
+		req, err := http.NewRequest("POST", "/v2/system-recovery-keys", bytes.NewBufferString(tc.body))
+		c.Assert(err, IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe, DeepEquals, tc.err, Commentf(tc.body))
+	}
+}
+
+func (s *recoveryKeysSuite) TestPostSystemRecoveryKeysActionRemoveNamed(c *C) {
+	s.daemon(c)
+
+	removedAll := 0
+	defer daemon.MockDeviceManagerRemoveRecoveryKeys(func() error {
+		removedAll++
+		return nil
+	})()
+	var removed []string
+	defer daemon.MockDeviceManagerRemoveRecoveryKey(func(name string) error {
+		removed = append(removed, name)
+		return nil
+	})()
+
+	buf := bytes.NewBufferString(`{"action":"remove","name":"backup"}`)
+	req, err := http.NewRequest("POST", "/v2/system-recovery-keys", buf)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(removed, DeepEquals, []string{"backup"})
+	c.Check(removedAll, Equals, 0)
+}
+
+func (s *recoveryKeysSuite) TestPostSystemRecoveryKeysActionList(c *C) {
+	s.daemon(c)
+
+	defer daemon.MockDeviceManagerListRecoveryKeys(func() ([]client.RecoveryKeyInfo, error) {
+		return []client.RecoveryKeyInfo{
+			{Name: "backup", Volumes: []string{"ubuntu-data"}},
+			{Name: "default", Volumes: []string{"ubuntu-data", "ubuntu-save"}},
+		}, nil
+	})()
+
+	buf := bytes.NewBufferString(`{"action":"list"}`)
+	req, err := http.NewRequest("POST", "/v2/system-recovery-keys", buf)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, []client.RecoveryKeyInfo{
+		{Name: "backup", Volumes: []string{"ubuntu-data"}},
+		{Name: "default", Volumes: []string{"ubuntu-data", "ubuntu-save"}},
+	})
+}
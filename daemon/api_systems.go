@@ -21,14 +21,21 @@ package daemon
 
 import (
 	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -71,6 +78,14 @@ func getAllSystems(c *Command, r *http.Request, user *auth.UserState) Response {
 		return InternalError(err.Error())
 	}
 
+	var diskUsage map[string]*devicestate.SystemDiskUsage
+	if r.URL.Query().Get("disk-usage") == "true" {
+		diskUsage, err = deviceManagerSystemsDiskUsage(c.d.overlord.DeviceManager())
+		if err != nil {
+			return InternalError("cannot compute disk usage of recovery systems: %v", err)
+		}
+	}
+
 	rsp.Systems = make([]client.System, 0, len(seedSystems))
 
 	for _, ss := range seedSystems {
@@ -85,8 +100,9 @@ func getAllSystems(c *Command, r *http.Request, user *auth.UserState) Response {
 		}
 
 		rsp.Systems = append(rsp.Systems, client.System{
-			Current: ss.Current,
-			Label:   ss.Label,
+			Current:               ss.Current,
+			Label:                 ss.Label,
+			DefaultRecoverySystem: ss.DefaultRecoverySystem,
 			Model: client.SystemModelData{
 				Model:       ss.Model.Model(),
 				BrandID:     ss.Model.BrandID(),
@@ -100,10 +116,21 @@ func getAllSystems(c *Command, r *http.Request, user *auth.UserState) Response {
 			},
 			Actions: actions,
 		})
+		if du := diskUsage[ss.Label]; du != nil {
+			rsp.Systems[len(rsp.Systems)-1].DiskUsage = &client.SystemDiskUsage{
+				Size:        du.Size,
+				Reclaimable: du.Reclaimable,
+			}
+		}
 	}
 	return SyncResponse(&rsp)
 }
 
+// wrapped for unit tests
+var deviceManagerSystemsDiskUsage = func(dm *devicestate.DeviceManager) (map[string]*devicestate.SystemDiskUsage, error) {
+	return dm.SystemsDiskUsage()
+}
+
 // wrapped for unit tests
 var deviceManagerSystemAndGadgetAndEncryptionInfo = func(dm *devicestate.DeviceManager, systemLabel string) (*devicestate.System, *gadget.Info, *devicestate.EncryptionSupportInfo, error) {
 	return dm.SystemAndGadgetAndEncryptionInfo(systemLabel)
@@ -137,6 +164,8 @@ func storageEncryption(encInfo *devicestate.EncryptionSupportInfo) *client.Stora
 var (
 	devicestateInstallFinish                 = devicestate.InstallFinish
 	devicestateInstallSetupStorageEncryption = devicestate.InstallSetupStorageEncryption
+	devicestateCreateRecoverySystem          = devicestate.CreateRecoverySystem
+	devicestateRemoveRecoverySystem          = devicestate.RemoveRecoverySystem
 )
 
 func getSystemDetails(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -184,6 +213,18 @@ func postSystemsAction(c *Command, r *http.Request, user *auth.UserState) Respon
 	var req systemActionRequest
 	systemLabel := muxVars(r)["label"]
 
+	contentType := r.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "multipart/") {
+		if systemLabel != "" {
+			return BadRequest("cannot create a recovery system through the path of an existing system")
+		}
+		_, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return BadRequest("cannot parse content type: %v", err)
+		}
+		return postSystemActionCreateFromForm(c, r, params["boundary"])
+	}
+
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&req); err != nil {
 		return BadRequest("cannot decode request body into system action: %v", err)
@@ -198,6 +239,8 @@ func postSystemsAction(c *Command, r *http.Request, user *auth.UserState) Respon
 		return postSystemActionReboot(c, systemLabel, &req)
 	case "install":
 		return postSystemActionInstall(c, systemLabel, &req)
+	case "remove":
+		return postSystemActionRemove(c, systemLabel)
 	default:
 		return BadRequest("unsupported action %q", req.Action)
 	}
@@ -253,13 +296,16 @@ func postSystemActionInstall(c *Command, systemLabel string, req *systemActionRe
 
 	switch req.Step {
 	case client.InstallStepSetupStorageEncryption:
-		chg, err := devicestateInstallSetupStorageEncryption(st, systemLabel, req.OnVolumes)
+		chg, err := devicestateInstallSetupStorageEncryption(st, systemLabel, req.OnVolumes, req.Passphrase)
 		if err != nil {
 			return BadRequest("cannot setup storage encryption for install from %q: %v", systemLabel, err)
 		}
 		ensureStateSoon(st)
 		return AsyncResponse(nil, chg.ID())
 	case client.InstallStepFinish:
+		if req.Passphrase != "" {
+			return BadRequest("passphrase can only be used with install step %q", client.InstallStepSetupStorageEncryption)
+		}
 		chg, err := devicestateInstallFinish(st, systemLabel, req.OnVolumes)
 		if err != nil {
 			return BadRequest("cannot finish install for %q: %v", systemLabel, err)
@@ -270,3 +316,147 @@ func postSystemActionInstall(c *Command, systemLabel string, req *systemActionRe
 		return BadRequest("unsupported install step %q", req.Step)
 	}
 }
+
+func postSystemActionRemove(c *Command, systemLabel string) Response {
+	if systemLabel == "" {
+		return BadRequest("system action requires the system label to be provided")
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	chg, err := devicestateRemoveRecoverySystem(st, systemLabel)
+	if err != nil {
+		if err == devicestate.ErrNoRecoverySystem {
+			return NotFound("requested seed system %q does not exist", systemLabel)
+		}
+		if cce, ok := err.(*snapstate.ChangeConflictError); ok {
+			return SnapChangeConflict(cce)
+		}
+		return BadRequest("cannot remove recovery system %q: %v", systemLabel, err)
+	}
+	ensureStateSoon(st)
+	return AsyncResponse(nil, chg.ID())
+}
+
+// postSystemActionCreateFromForm creates a new recovery system using snap and
+// assertion files uploaded as a multipart form. The recovery system is always
+// created for the current model, an uploaded model assertion is only accepted
+// if it is the current one; recovery systems for other models are created as
+// part of remodeling.
+func postSystemActionCreateFromForm(c *Command, r *http.Request, boundary string) Response {
+	form, errRsp := readForm(multipart.NewReader(r.Body, boundary))
+	if errRsp != nil {
+		return errRsp
+	}
+
+	// we are in charge of the temp files, until they're handed off to the change
+	var pathsToNotRemove []string
+	defer func() {
+		form.RemoveAllExcept(pathsToNotRemove)
+	}()
+
+	if len(form.Values["action"]) != 1 || form.Values["action"][0] != "create" {
+		return BadRequest("unsupported multipart system action, only %q is supported", "create")
+	}
+	if len(form.Values["label"]) != 1 || form.Values["label"][0] == "" {
+		return BadRequest("cannot create a recovery system with no label")
+	}
+	label := form.Values["label"][0]
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	deviceCtx, err := snapstate.DevicePastSeeding(st, nil)
+	if err != nil {
+		return BadRequest("cannot create recovery system %q: %v", label, err)
+	}
+	model := deviceCtx.Model()
+
+	if errRsp := addSystemAssertionsFromForm(st, form, model); errRsp != nil {
+		return errRsp
+	}
+
+	flags := sideloadFlags{dangerousOK: isTrue(form, "dangerous")}
+	if flags.dangerousOK && model.Grade() != asserts.ModelDangerous {
+		return BadRequest("cannot use unasserted snaps with a model of grade %q", model.Grade())
+	}
+	var localSnaps []devicestate.LocalSnap
+	for _, ref := range form.FileRefs["snap"] {
+		si, errRsp := readSideInfo(st, ref.TmpPath, ref.Filename, flags, model)
+		if errRsp != nil {
+			return errRsp
+		}
+		localSnaps = append(localSnaps, devicestate.LocalSnap{
+			SideInfo: si,
+			Path:     ref.TmpPath,
+		})
+	}
+
+	chg, err := devicestateCreateRecoverySystem(st, label, devicestate.CreateRecoverySystemOptions{
+		LocalSnaps:  localSnaps,
+		MarkDefault: isTrue(form, "mark-default"),
+	})
+	if err != nil {
+		return BadRequest("cannot create recovery system %q: %v", label, err)
+	}
+	ensureStateSoon(st)
+
+	for _, localSnap := range localSnaps {
+		pathsToNotRemove = append(pathsToNotRemove, localSnap.Path)
+	}
+
+	return AsyncResponse(nil, chg.ID())
+}
+
+func addSystemAssertionsFromForm(st *state.State, form *Form, model *asserts.Model) *apiError {
+	refs := form.FileRefs["assertion"]
+	if len(refs) == 0 {
+		return nil
+	}
+
+	batch := asserts.NewBatch(nil)
+	for _, ref := range refs {
+		f, err := os.Open(ref.TmpPath)
+		if err != nil {
+			return InternalError("cannot open uploaded assertions: %v", err)
+		}
+		errRsp := addSystemAssertionsFromFile(batch, f, ref.Filename, model)
+		f.Close()
+		if errRsp != nil {
+			return errRsp
+		}
+	}
+
+	if err := assertstate.AddBatch(st, batch, &asserts.CommitOptions{
+		Precheck: true,
+	}); err != nil {
+		return BadRequest("cannot add assertions: %v", err)
+	}
+	return nil
+}
+
+func addSystemAssertionsFromFile(batch *asserts.Batch, r io.Reader, filename string, model *asserts.Model) *apiError {
+	dec := asserts.NewDecoder(r)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return BadRequest("cannot decode assertions from %q: %v", filename, err)
+		}
+		if a.Type() == asserts.ModelType {
+			// only the current model is supported, creating recovery
+			// systems for other models is part of remodeling
+			if a.Revision() != model.Revision() || strings.Join(a.Ref().PrimaryKey, "/") != strings.Join(model.Ref().PrimaryKey, "/") {
+				return BadRequest("cannot create a recovery system for a model other than the current one: model assertion from %q is not the current model %s/%s at revision %d", filename, model.BrandID(), model.Model(), model.Revision())
+			}
+		}
+		if err := batch.Add(a); err != nil {
+			return BadRequest("cannot add assertion from %q: %v", filename, err)
+		}
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -48,6 +50,7 @@ import (
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/restart"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/secboot"
@@ -55,6 +58,7 @@ import (
 	"github.com/snapcore/snapd/seed/seedtest"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
 )
 
 var _ = check.Suite(&systemsSuite{})
@@ -259,6 +263,58 @@ func (s *systemsSuite) TestSystemsGetSome(c *check.C) {
 		}})
 }
 
+func (s *systemsSuite) TestSystemsGetWithDiskUsage(c *check.C) {
+	m := boot.Modeenv{
+		Mode: "run",
+	}
+	err := m.WriteTo("")
+	c.Assert(err, check.IsNil)
+
+	d := s.daemonWithOverlordMockAndStore()
+	hookMgr, err := hookstate.Manager(d.Overlord().State(), d.Overlord().TaskRunner())
+	c.Assert(err, check.IsNil)
+	mgr, err := devicestate.Manager(d.Overlord().State(), hookMgr, d.Overlord().TaskRunner(), nil)
+	c.Assert(err, check.IsNil)
+	d.Overlord().AddManager(mgr)
+
+	s.expectAuthenticatedAccess()
+
+	restore := s.mockSystemSeeds(c)
+	defer restore()
+
+	restore = daemon.MockDeviceManagerSystemsDiskUsage(func(*devicestate.DeviceManager) (map[string]*devicestate.SystemDiskUsage, error) {
+		return map[string]*devicestate.SystemDiskUsage{
+			"20191119": {Size: 2048, Reclaimable: 1024},
+		}, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/systems?disk-usage=true", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+
+	c.Assert(rsp.Status, check.Equals, 200)
+	sys := rsp.Result.(*daemon.SystemsResponse)
+	c.Assert(sys.Systems, check.HasLen, 2)
+	c.Check(sys.Systems[0].Label, check.Equals, "20191119")
+	c.Check(sys.Systems[0].DiskUsage, check.DeepEquals, &client.SystemDiskUsage{Size: 2048, Reclaimable: 1024})
+	c.Check(sys.Systems[1].Label, check.Equals, "20200318")
+	c.Check(sys.Systems[1].DiskUsage, check.IsNil)
+
+	// disk usage is only computed when asked for
+	restore = daemon.MockDeviceManagerSystemsDiskUsage(func(*devicestate.DeviceManager) (map[string]*devicestate.SystemDiskUsage, error) {
+		c.Fatalf("unexpected call")
+		return nil, nil
+	})
+	defer restore()
+	req, err = http.NewRequest("GET", "/v2/systems", nil)
+	c.Assert(err, check.IsNil)
+	rsp = s.syncReq(c, req, nil)
+	sys = rsp.Result.(*daemon.SystemsResponse)
+	c.Assert(sys.Systems, check.HasLen, 2)
+	c.Check(sys.Systems[0].DiskUsage, check.IsNil)
+}
+
 func (s *systemsSuite) TestSystemsGetNone(c *check.C) {
 	m := boot.Modeenv{
 		Mode: "run",
@@ -1052,7 +1108,68 @@ func (s *systemsSuite) TestSystemsGetSpecificLabelIntegration(c *check.C) {
 }
 
 func (s *systemsSuite) TestSystemInstallActionSetupStorageEncryptionCallsDevicestate(c *check.C) {
-	s.testSystemInstallActionCallsDevicestate(c, "setup-storage-encryption", daemon.MockDevicestateInstallSetupStorageEncryption)
+	mocker := func(f func(st *state.State, label string, onVolumes map[string]*gadget.Volume) (*state.Change, error)) (restore func()) {
+		return daemon.MockDevicestateInstallSetupStorageEncryption(func(st *state.State, label string, onVolumes map[string]*gadget.Volume, passphrase string) (*state.Change, error) {
+			c.Check(passphrase, check.Equals, "")
+			return f(st, label, onVolumes)
+		})
+	}
+	s.testSystemInstallActionCallsDevicestate(c, "setup-storage-encryption", mocker)
+}
+
+func (s *systemsSuite) TestSystemInstallActionSetupStorageEncryptionWithPassphrase(c *check.C) {
+	s.daemon(c)
+
+	_, restore := daemon.MockEnsureStateSoon(func(st *state.State) {})
+	defer restore()
+
+	var gotPassphrase string
+	r := daemon.MockDevicestateInstallSetupStorageEncryption(func(st *state.State, label string, onVolumes map[string]*gadget.Volume, passphrase string) (*state.Change, error) {
+		gotPassphrase = passphrase
+		return st.NewChange("foo", "..."), nil
+	})
+	defer r()
+
+	body := map[string]interface{}{
+		"action":     "install",
+		"step":       "setup-storage-encryption",
+		"passphrase": "my secret",
+		"on-volumes": map[string]interface{}{
+			"pc": map[string]interface{}{
+				"bootloader": "grub",
+			},
+		},
+	}
+	b, err := json.Marshal(body)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/systems/20191119", bytes.NewBuffer(b))
+	c.Assert(err, check.IsNil)
+
+	s.asyncReq(c, req, nil)
+	c.Check(gotPassphrase, check.Equals, "my secret")
+}
+
+func (s *systemsSuite) TestSystemInstallActionFinishWithPassphraseError(c *check.C) {
+	s.daemon(c)
+
+	body := map[string]interface{}{
+		"action":     "install",
+		"step":       "finish",
+		"passphrase": "my secret",
+		"on-volumes": map[string]interface{}{
+			"pc": map[string]interface{}{
+				"bootloader": "grub",
+			},
+		},
+	}
+	b, err := json.Marshal(body)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/systems/20191119", bytes.NewBuffer(b))
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `passphrase can only be used with install step "setup-storage-encryption"`)
 }
 
 func (s *systemsSuite) TestSystemInstallActionFinishCallsDevicestate(c *check.C) {
@@ -1202,3 +1319,227 @@ func (s *systemsSuite) TestSystemInstallActionError(c *check.C) {
 	rspe := s.errorReq(c, req, nil)
 	c.Check(rspe.Error(), check.Equals, `unsupported install step "unknown-install-step" (api)`)
 }
+
+func (s *systemsSuite) mockDeviceForCreate(c *check.C, grade string) (*state.State, *asserts.Model) {
+	d := s.daemon(c)
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+
+	model := s.Brands.Model("my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        grade,
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              snaptest.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              snaptest.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	assertstatetest.AddMany(st, s.StoreSigning.StoreAccountKey(""))
+	assertstatetest.AddMany(st, s.Brands.AccountsAndKeys("my-brand")...)
+	s.mockModel(st, model)
+	st.Set("seeded", true)
+	return st, model
+}
+
+type systemsFormPart struct {
+	name     string
+	filename string
+	content  string
+}
+
+func systemsCreateRequest(c *check.C, parts []systemsFormPart) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, p := range parts {
+		var err error
+		if p.filename != "" {
+			var fw io.Writer
+			fw, err = mw.CreateFormFile(p.name, p.filename)
+			c.Assert(err, check.IsNil)
+			_, err = fw.Write([]byte(p.content))
+		} else {
+			err = mw.WriteField(p.name, p.content)
+		}
+		c.Assert(err, check.IsNil)
+	}
+	c.Assert(mw.Close(), check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/systems", &body)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func (s *systemsSuite) TestSystemsCreateFromFormHappy(c *check.C) {
+	st, _ := s.mockDeviceForCreate(c, "dangerous")
+
+	_, restore := daemon.MockEnsureStateSoon(func(st *state.State) {})
+	defer restore()
+	restore = daemon.MockUnsafeReadSnapInfo(func(string) (*snap.Info, error) {
+		return &snap.Info{SuggestedName: "pc-kernel"}, nil
+	})
+	defer restore()
+
+	var gotLabel string
+	var gotOpts devicestate.CreateRecoverySystemOptions
+	restore = daemon.MockDevicestateCreateRecoverySystem(func(st *state.State, label string, opts devicestate.CreateRecoverySystemOptions) (*state.Change, error) {
+		gotLabel = label
+		gotOpts = opts
+		return st.NewChange("create-recovery-system", "..."), nil
+	})
+	defer restore()
+
+	req := systemsCreateRequest(c, []systemsFormPart{
+		{name: "action", content: "create"},
+		{name: "label", content: "1234"},
+		{name: "mark-default", content: "true"},
+		{name: "dangerous", content: "true"},
+		{name: "snap", filename: "pc-kernel_x1.snap", content: "snap-data"},
+	})
+	rsp := s.asyncReq(c, req, nil)
+
+	st.Lock()
+	chg := st.Change(rsp.Change)
+	st.Unlock()
+	c.Assert(chg, check.NotNil)
+	c.Check(gotLabel, check.Equals, "1234")
+	c.Check(gotOpts.MarkDefault, check.Equals, true)
+	c.Assert(gotOpts.LocalSnaps, check.HasLen, 1)
+	c.Check(gotOpts.LocalSnaps[0].SideInfo, check.DeepEquals, &snap.SideInfo{RealName: "pc-kernel"})
+	// the file was handed over to the change
+	c.Check(gotOpts.LocalSnaps[0].Path, testutil.FileEquals, "snap-data")
+}
+
+func (s *systemsSuite) TestSystemsCreateFromFormErrors(c *check.C) {
+	s.mockDeviceForCreate(c, "signed")
+
+	restore := daemon.MockDevicestateCreateRecoverySystem(func(st *state.State, label string, opts devicestate.CreateRecoverySystemOptions) (*state.Change, error) {
+		c.Fatalf("unexpected call")
+		return nil, nil
+	})
+	defer restore()
+
+	otherModel := s.Brands.Model("my-brand", "other-pc", map[string]interface{}{
+		"architecture": "amd64",
+		"gadget":       "pc",
+		"kernel":       "pc-kernel",
+	})
+
+	for _, tc := range []struct {
+		parts []systemsFormPart
+		err   string
+	}{{
+		parts: []systemsFormPart{{name: "action", content: "do"}},
+		err:   `unsupported multipart system action, only "create" is supported`,
+	}, {
+		parts: []systemsFormPart{{name: "action", content: "create"}},
+		err:   `cannot create a recovery system with no label`,
+	}, {
+		parts: []systemsFormPart{
+			{name: "action", content: "create"},
+			{name: "label", content: "1234"},
+			{name: "dangerous", content: "true"},
+		},
+		err: `cannot use unasserted snaps with a model of grade "signed"`,
+	}, {
+		parts: []systemsFormPart{
+			{name: "action", content: "create"},
+			{name: "label", content: "1234"},
+			{name: "assertion", filename: "model.assert", content: string(asserts.Encode(otherModel))},
+		},
+		err: `cannot create a recovery system for a model other than the current one: model assertion from "model.assert" is not the current model my-brand/pc at revision 0`,
+	}, {
+		parts: []systemsFormPart{
+			{name: "action", content: "create"},
+			{name: "label", content: "1234"},
+			{name: "snap", filename: "pc-kernel_1.snap", content: "snap-data"},
+		},
+		err: `cannot find signatures with metadata for snap "pc-kernel_1.snap"`,
+	}} {
+		req := systemsCreateRequest(c, tc.parts)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, 400)
+		c.Check(rspe.Message, check.Equals, tc.err)
+	}
+
+	// no uploaded files were left behind
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapBlobDir, dirs.LocalInstallBlobTempPrefix+"*"))
+	c.Assert(err, check.IsNil)
+	c.Check(matches, check.HasLen, 0)
+}
+
+func (s *systemsSuite) postSystemsRemoveRequest(c *check.C, label string) *http.Request {
+	b, err := json.Marshal(map[string]string{"action": "remove"})
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/systems/"+label, bytes.NewBuffer(b))
+	c.Assert(err, check.IsNil)
+	return req
+}
+
+func (s *systemsSuite) TestSystemsRemoveHappy(c *check.C) {
+	d := s.daemon(c)
+	st := d.Overlord().State()
+
+	_, restore := daemon.MockEnsureStateSoon(func(st *state.State) {})
+	defer restore()
+
+	var gotLabel string
+	restore = daemon.MockDevicestateRemoveRecoverySystem(func(st *state.State, label string) (*state.Change, error) {
+		gotLabel = label
+		return st.NewChange("remove-recovery-system", "..."), nil
+	})
+	defer restore()
+
+	rsp := s.asyncReq(c, s.postSystemsRemoveRequest(c, "1234"), nil)
+
+	st.Lock()
+	chg := st.Change(rsp.Change)
+	st.Unlock()
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "remove-recovery-system")
+	c.Check(gotLabel, check.Equals, "1234")
+}
+
+func (s *systemsSuite) TestSystemsRemoveErrors(c *check.C) {
+	s.daemon(c)
+
+	for _, tc := range []struct {
+		err     error
+		status  int
+		kind    client.ErrorKind
+		message string
+	}{{
+		err:     devicestate.ErrNoRecoverySystem,
+		status:  404,
+		message: `requested seed system "1234" does not exist`,
+	}, {
+		err:     &snapstate.ChangeConflictError{ChangeKind: "create-recovery-system", Message: "creating recovery system in progress"},
+		status:  409,
+		kind:    client.ErrorKindSnapChangeConflict,
+		message: `creating recovery system in progress`,
+	}, {
+		err:     errors.New(`cannot remove the default recovery system "1234"`),
+		status:  400,
+		message: `cannot remove recovery system "1234": cannot remove the default recovery system "1234"`,
+	}} {
+		restore := daemon.MockDevicestateRemoveRecoverySystem(func(st *state.State, label string) (*state.Change, error) {
+			return nil, tc.err
+		})
+		rspe := s.errorReq(c, s.postSystemsRemoveRequest(c, "1234"), nil)
+		restore()
+		c.Check(rspe.Status, check.Equals, tc.status)
+		c.Check(rspe.Kind, check.Equals, tc.kind)
+		c.Check(rspe.Message, check.Equals, tc.message)
+	}
+}
@@ -75,6 +75,7 @@ type Daemon struct {
 	tomb            tomb.Tomb
 	router          *mux.Router
 	standbyOpinions *standby.StandbyOpinions
+	storeMirror     *storeMirror
 
 	// set to what kind of restart was requested if any
 	requestedRestart restart.RestartType
@@ -314,6 +315,9 @@ func (d *Daemon) initStandbyHandling() {
 	d.standbyOpinions.AddOpinion(d.overlord)
 	d.standbyOpinions.AddOpinion(d.overlord.SnapManager())
 	d.standbyOpinions.AddOpinion(d.overlord.DeviceManager())
+	if d.storeMirror != nil {
+		d.standbyOpinions.AddOpinion(d.storeMirror)
+	}
 	d.standbyOpinions.Start()
 }
 
@@ -351,6 +355,13 @@ func (d *Daemon) Start() error {
 		ConnState: d.connTracker.trackConn,
 	}
 
+	// serving a store mirror is best-effort, do not fail startup over it
+	mirror, err := newStoreMirror(d.state)
+	if err != nil {
+		logger.Noticef("%v", err)
+	}
+	d.storeMirror = mirror
+
 	// enable standby handling
 	d.initStandbyHandling()
 
@@ -365,6 +376,16 @@ func (d *Daemon) Start() error {
 	d.overlord.Loop()
 
 	d.tomb.Go(func() error {
+		if d.storeMirror != nil {
+			d.tomb.Go(func() error {
+				if err := d.storeMirror.serve(); err != nil && d.tomb.Err() == tomb.ErrStillAlive {
+					return err
+				}
+
+				return nil
+			})
+		}
+
 		if d.snapListener != nil {
 			d.tomb.Go(func() error {
 				if err := d.serve.Serve(d.snapListener); err != http.ErrServerClosed && d.tomb.Err() == tomb.ErrStillAlive {
@@ -509,6 +530,9 @@ func (d *Daemon) Stop(sigCh chan<- os.Signal) error {
 
 	d.snapdListener.Close()
 	d.standbyOpinions.Stop()
+	if d.storeMirror != nil {
+		d.storeMirror.stop()
+	}
 
 	if d.snapListener != nil {
 		// stop running hooks first
@@ -42,6 +42,7 @@ import (
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/patch"
@@ -662,6 +663,37 @@ version: 1`, si)
 	c.Check(s.notified, check.DeepEquals, []string{extendedTimeoutUSec, "READY=1", "STOPPING=1"})
 }
 
+func (s *daemonSuite) TestStoreMirror(c *check.C) {
+	d := s.newTestDaemon(c)
+	st := d.overlord.State()
+
+	m, err := newStoreMirror(st)
+	c.Assert(err, check.IsNil)
+	c.Check(m, check.IsNil)
+
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "store.mirror-listen", "127.0.0.1:0")
+	tr.Commit()
+	st.Unlock()
+
+	m, err = newStoreMirror(st)
+	c.Assert(err, check.IsNil)
+	c.Assert(m, check.NotNil)
+	c.Check(m.CanStandby(), check.Equals, false)
+
+	done := make(chan error)
+	go func() { done <- m.serve() }()
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/v2/mirror/snaps/%096d", m.listener.Addr(), 0))
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Check(resp.StatusCode, check.Equals, 404)
+
+	m.stop()
+	c.Check(<-done, check.IsNil)
+}
+
 func (s *daemonSuite) TestRestartWiring(c *check.C) {
 	d := s.newTestDaemon(c)
 	// mark as already seeded
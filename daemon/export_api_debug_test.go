@@ -19,10 +19,50 @@
 
 package daemon
 
+import (
+	"io"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/audit"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
 type (
-	ConnectivityStatus = connectivityStatus
+	ConnectivityStatus      = connectivityStatus
+	DebugCacheEntry         = cacheEntry
+	SandboxDenial           = sandboxDenial
+	SandboxDenialSuggestion = sandboxDenialSuggestion
 )
 
 var (
 	MinLane = minLane
 )
+
+func MockBootResealKeyToModeenvDryRun(f func(rootdir string, modeenv *boot.Modeenv) (*boot.ResealDryRunReport, error)) (restore func()) {
+	old := bootResealKeyToModeenvDryRun
+	bootResealKeyToModeenvDryRun = f
+	return func() {
+		bootResealKeyToModeenvDryRun = old
+	}
+}
+
+func MockSystemdAuditLogReader(f func(n int) (io.ReadCloser, error)) (restore func()) {
+	restore = testutil.Backup(&systemdAuditLogReader)
+	systemdAuditLogReader = f
+	return restore
+}
+
+func MockBuiltinSuggestInterfaces(f func(denial *audit.Denial) []string) (restore func()) {
+	restore = testutil.Backup(&builtinSuggestInterfaces)
+	builtinSuggestInterfaces = f
+	return restore
+}
+
+func MockIfacestateCheckPolicy(f func(st *state.State, info *snap.Info, opts *ifacestate.PolicyCheckOptions) (*ifacestate.PolicyCheckReport, error)) (restore func()) {
+	restore = testutil.Backup(&ifacestateCheckPolicy)
+	ifacestateCheckPolicy = f
+	return restore
+}
@@ -20,6 +20,7 @@
 package daemon
 
 import (
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -31,3 +32,27 @@ func MockDeviceManagerRemoveRecoveryKeys(f func() error) (restore func()) {
 	}
 	return restore
 }
+
+func MockDeviceManagerAddRecoveryKey(f func(name string) (*client.RecoveryKeyInfo, error)) (restore func()) {
+	restore = testutil.Backup(&deviceManagerAddRecoveryKey)
+	deviceManagerAddRecoveryKey = func(_ *devicestate.DeviceManager, name string) (*client.RecoveryKeyInfo, error) {
+		return f(name)
+	}
+	return restore
+}
+
+func MockDeviceManagerRemoveRecoveryKey(f func(name string) error) (restore func()) {
+	restore = testutil.Backup(&deviceManagerRemoveRecoveryKey)
+	deviceManagerRemoveRecoveryKey = func(_ *devicestate.DeviceManager, name string) error {
+		return f(name)
+	}
+	return restore
+}
+
+func MockDeviceManagerListRecoveryKeys(f func() ([]client.RecoveryKeyInfo, error)) (restore func()) {
+	restore = testutil.Backup(&deviceManagerListRecoveryKeys)
+	deviceManagerListRecoveryKeys = func(_ *devicestate.DeviceManager) ([]client.RecoveryKeyInfo, error) {
+		return f()
+	}
+	return restore
+}
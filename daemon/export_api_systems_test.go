@@ -50,8 +50,26 @@ func MockDevicestateInstallFinish(f func(*state.State, string, map[string]*gadge
 	return restore
 }
 
-func MockDevicestateInstallSetupStorageEncryption(f func(*state.State, string, map[string]*gadget.Volume) (*state.Change, error)) (restore func()) {
+func MockDevicestateInstallSetupStorageEncryption(f func(*state.State, string, map[string]*gadget.Volume, string) (*state.Change, error)) (restore func()) {
 	restore = testutil.Backup(&devicestateInstallSetupStorageEncryption)
 	devicestateInstallSetupStorageEncryption = f
 	return restore
 }
+
+func MockDevicestateCreateRecoverySystem(f func(*state.State, string, devicestate.CreateRecoverySystemOptions) (*state.Change, error)) (restore func()) {
+	restore = testutil.Backup(&devicestateCreateRecoverySystem)
+	devicestateCreateRecoverySystem = f
+	return restore
+}
+
+func MockDevicestateRemoveRecoverySystem(f func(*state.State, string) (*state.Change, error)) (restore func()) {
+	restore = testutil.Backup(&devicestateRemoveRecoverySystem)
+	devicestateRemoveRecoverySystem = f
+	return restore
+}
+
+func MockDeviceManagerSystemsDiskUsage(f func(*devicestate.DeviceManager) (map[string]*devicestate.SystemDiskUsage, error)) (restore func()) {
+	restore = testutil.Backup(&deviceManagerSystemsDiskUsage)
+	deviceManagerSystemsDiskUsage = f
+	return restore
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/restart"
@@ -112,6 +113,18 @@ func MockUnsafeReadSnapInfo(mock func(string) (*snap.Info, error)) (restore func
 	}
 }
 
+func MockUdevQueryDevice(mock func(string) (*udev.Device, error)) (restore func()) {
+	restore = testutil.Backup(&udevQueryDevice)
+	udevQueryDevice = mock
+	return restore
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	restore = testutil.Backup(&timeNow)
+	timeNow = f
+	return restore
+}
+
 func MockAssertstateRefreshSnapAssertions(mock func(*state.State, int, *assertstate.RefreshAssertionsOptions) error) (restore func()) {
 	oldAssertstateRefreshSnapAssertions := assertstateRefreshSnapAssertions
 	assertstateRefreshSnapAssertions = mock
@@ -142,6 +155,14 @@ func MockSnapstateInstallPath(mock func(*state.State, *snap.SideInfo, string, st
 	}
 }
 
+func MockSnapstateApplyLocalDelta(mock func(*state.State, string, snap.Revision, string, string) error) (restore func()) {
+	oldSnapstateApplyLocalDelta := snapstateApplyLocalDelta
+	snapstateApplyLocalDelta = mock
+	return func() {
+		snapstateApplyLocalDelta = oldSnapstateApplyLocalDelta
+	}
+}
+
 func MockSnapstateUpdate(mock func(*state.State, string, *snapstate.RevisionOptions, int, snapstate.Flags) (*state.TaskSet, error)) (restore func()) {
 	oldSnapstateUpdate := snapstateUpdate
 	snapstateUpdate = mock
@@ -198,6 +219,14 @@ func MockSnapstateUpdateMany(mock func(context.Context, *state.State, []string,
 	}
 }
 
+func MockSnapstateUpdateManyPlan(mock func(context.Context, *state.State, []string, int, *snapstate.Flags) (*snapstate.RefreshPlan, error)) (restore func()) {
+	oldSnapstateUpdateManyPlan := snapstateUpdateManyPlan
+	snapstateUpdateManyPlan = mock
+	return func() {
+		snapstateUpdateManyPlan = oldSnapstateUpdateManyPlan
+	}
+}
+
 func MockSnapstateRemoveMany(mock func(*state.State, []string, *snapstate.RemoveFlags) ([]string, []*state.TaskSet, error)) (restore func()) {
 	oldSnapstateRemoveMany := snapstateRemoveMany
 	snapstateRemoveMany = mock
@@ -0,0 +1,89 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/store"
+)
+
+// storeMirror serves the download cache and the assertions of the
+// device to LAN peers as set up with the store.mirror-listen option.
+type storeMirror struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+// newStoreMirror returns the store mirror to serve, or nil if serving
+// one is not enabled.
+func newStoreMirror(st *state.State) (*storeMirror, error) {
+	st.Lock()
+	tr := config.NewTransaction(st)
+	var listen string
+	err := tr.GetMaybe("core", "store.mirror-listen", &listen)
+	st.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if listen == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, fmt.Errorf("cannot serve store mirror: %v", err)
+	}
+
+	cache := store.NewCacheManager(dirs.SnapDownloadCacheDir, 0)
+	findAssertion := func(assertType *asserts.AssertionType, headers map[string]string) (asserts.Assertion, error) {
+		st.Lock()
+		defer st.Unlock()
+		return assertstate.DB(st).Find(assertType, headers)
+	}
+	return &storeMirror{
+		listener: listener,
+		server:   &http.Server{Handler: store.NewMirrorHandler(cache, findAssertion)},
+	}, nil
+}
+
+func (m *storeMirror) serve() error {
+	if err := m.server.Serve(m.listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (m *storeMirror) stop() {
+	m.server.Close()
+}
+
+// CanStandby implements standby.Opinionator, the mirror must stay
+// reachable even if no local clients are connected.
+func (m *storeMirror) CanStandby() bool {
+	return false
+}
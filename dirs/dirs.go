@@ -101,6 +101,9 @@ var (
 	SnapDesktopFilesDir    string
 	SnapDesktopIconsDir    string
 	SnapPolkitPolicyDir    string
+	SnapSELinuxDir         string
+	SnapLandlockDir        string
+	SnapNetNSDir           string
 	SnapSystemdDir         string
 	SnapSystemdRunDir      string
 
@@ -440,6 +443,9 @@ func SetRootDir(rootdir string) {
 	SnapDBusSystemServicesDir = filepath.Join(rootdir, snappyDir, "dbus-1", "system-services")
 
 	SnapPolkitPolicyDir = filepath.Join(rootdir, "/usr/share/polkit-1/actions")
+	SnapSELinuxDir = filepath.Join(rootdir, snappyDir, "selinux")
+	SnapLandlockDir = filepath.Join(rootdir, snappyDir, "landlock")
+	SnapNetNSDir = filepath.Join(rootdir, snappyDir, "netns")
 
 	CloudInstanceDataFile = filepath.Join(rootdir, "/run/cloud-init/instance-data.json")
 
@@ -66,6 +66,27 @@ func WriteEncryptionMarkers(dataFDEDir, saveFDEDir string, markerSecret []byte)
 	return osutil.AtomicWriteFile(encryptionMarkerUnder(saveFDEDir), markerSecret, 0600, 0)
 }
 
+// passphraseMarkerUnder returns the path of the marker indicating that the
+// encrypted partitions are protected with a user passphrase rather than
+// sealed keys.
+func passphraseMarkerUnder(deviceFDEDir string) string {
+	return filepath.Join(deviceFDEDir, "passphrase")
+}
+
+// HasPassphraseMarkerUnder returns true when there is a passphrase marker in a
+// given directory.
+func HasPassphraseMarkerUnder(deviceFDEDir string) bool {
+	return osutil.FileExists(passphraseMarkerUnder(deviceFDEDir))
+}
+
+// WritePassphraseMarker writes the passphrase marker in a given directory.
+func WritePassphraseMarker(deviceFDEDir string) error {
+	if err := os.MkdirAll(deviceFDEDir, 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(passphraseMarkerUnder(deviceFDEDir), nil, 0644, 0)
+}
+
 // DataSealedKeyUnder returns the path of the sealed key for ubuntu-data.
 func DataSealedKeyUnder(deviceFDEDir string) string {
 	return filepath.Join(deviceFDEDir, "ubuntu-data.sealed-key")
@@ -81,6 +102,12 @@ func RecoveryKeyUnder(deviceFDEDir string) string {
 	return filepath.Join(deviceFDEDir, "recovery.key")
 }
 
+// NamedRecoveryKeyUnder returns the path of a named recovery key while it
+// is being added.
+func NamedRecoveryKeyUnder(deviceFDEDir, name string) string {
+	return filepath.Join(deviceFDEDir, fmt.Sprintf("recovery-%s.key", name))
+}
+
 // FallbackDataSealedKeyUnder returns the path of a fallback ubuntu data key.
 func FallbackDataSealedKeyUnder(seedDeviceFDEDir string) string {
 	return filepath.Join(seedDeviceFDEDir, "ubuntu-data.recovery.sealed-key")
@@ -112,6 +139,9 @@ const (
 	SealingMethodLegacyTPM    = SealingMethod("")
 	SealingMethodTPM          = SealingMethod("tpm")
 	SealingMethodFDESetupHook = SealingMethod("fde-setup-hook")
+	// SealingMethodPassphrase indicates that no keys are sealed and the
+	// encrypted partitions are unlocked with a user passphrase
+	SealingMethodPassphrase = SealingMethod("passphrase")
 )
 
 // StampSealedKeys writes what sealing method was used for key sealing
@@ -106,6 +106,16 @@ func (s *deviceSuite) TestLocations(c *C) {
 		"/var/lib/snapd/save/device/fde/tpm-lockout-auth")
 }
 
+func (s *deviceSuite) TestPassphraseMarker(c *C) {
+	d := filepath.Join(c.MkDir(), "device/fde")
+	c.Check(device.HasPassphraseMarkerUnder(d), Equals, false)
+
+	err := device.WritePassphraseMarker(d)
+	c.Assert(err, IsNil)
+	c.Check(device.HasPassphraseMarkerUnder(d), Equals, true)
+	c.Check(filepath.Join(d, "passphrase"), testutil.FilePresent)
+}
+
 func (s *deviceSuite) TestStampSealedKeysRunthrough(c *C) {
 	root := c.MkDir()
 
@@ -116,6 +126,7 @@ func (s *deviceSuite) TestStampSealedKeysRunthrough(c *C) {
 		{device.SealingMethodLegacyTPM, ""},
 		{device.SealingMethodTPM, "tpm"},
 		{device.SealingMethodFDESetupHook, "fde-setup-hook"},
+		{device.SealingMethodPassphrase, "passphrase"},
 	} {
 		err := device.StampSealedKeys(root, tc.mth)
 		c.Assert(err, IsNil)
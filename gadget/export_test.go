@@ -19,6 +19,10 @@
 
 package gadget
 
+import (
+	"github.com/snapcore/snapd/gadget/quantity"
+)
+
 type (
 	MountedFilesystemUpdater = mountedFilesystemUpdater
 	RawStructureUpdater      = rawStructureUpdater
@@ -65,6 +69,9 @@ var (
 	OnDiskStructureIsLikelyImplicitSystemDataRole = onDiskStructureIsLikelyImplicitSystemDataRole
 
 	SearchForVolumeWithTraits = searchForVolumeWithTraits
+
+	NewPartitionUpdater = newPartitionUpdater
+	CurrentLayout       = currentLayout
 )
 
 func MockEvalSymlinks(mock func(path string) (string, error)) (restore func()) {
@@ -75,6 +82,22 @@ func MockEvalSymlinks(mock func(path string) (string, error)) (restore func()) {
 	}
 }
 
+func MockOnDiskVolumeFromDevice(mock func(device string) (*OnDiskVolume, error)) (restore func()) {
+	old := onDiskVolumeFromDevice
+	onDiskVolumeFromDevice = mock
+	return func() {
+		onDiskVolumeFromDevice = old
+	}
+}
+
+func MockMkfsMakeWithContent(mock func(typ, img, label, contentRootDir string, deviceSize, sectorSize quantity.Size) error) (restore func()) {
+	old := mkfsMakeWithContent
+	mkfsMakeWithContent = mock
+	return func() {
+		mkfsMakeWithContent = old
+	}
+}
+
 func (m *MountedFilesystemWriter) WriteDirectory(volumeRoot, src, dst string, preserveInDst []string) error {
 	return m.writeDirectory(volumeRoot, src, dst, preserveInDst)
 }
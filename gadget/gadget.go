@@ -114,6 +114,26 @@ type Info struct {
 	Connections []Connection `yaml:"connections"`
 
 	KernelCmdline KernelCmdline `yaml:"kernel-cmdline"`
+
+	FactoryReset FactoryReset `yaml:"factory-reset"`
+}
+
+// FactoryReset describes how the data of the system is treated on factory
+// reset.
+type FactoryReset struct {
+	// Preserve lists the paths, relative to the root of the system data,
+	// whose content is carried over a factory reset.
+	Preserve []FactoryResetPreservedPath `yaml:"preserve"`
+}
+
+// FactoryResetPreservedPath is a file or directory of the system data that is
+// preserved on factory reset.
+type FactoryResetPreservedPath struct {
+	Path string `yaml:"path"`
+	// MaxSize is the maximum size of the content under the path, content
+	// that is larger is not preserved. Defaults to
+	// DefaultFactoryResetPreservedMaxSize.
+	MaxSize quantity.Size `yaml:"max-size"`
 }
 
 // Volume defines the structure and content for the image to be written into a
@@ -593,6 +613,10 @@ func InfoFromGadgetYaml(gadgetYaml []byte, model Model) (*Info, error) {
 		}
 	}
 
+	if err := validateFactoryReset(&gi.FactoryReset); err != nil {
+		return nil, fmt.Errorf("invalid factory-reset stanza: %v", err)
+	}
+
 	if len(gi.Volumes) == 0 && classicOrUndetermined(model) {
 		// volumes can be left out on classic
 		// can still specify defaults though
@@ -827,6 +851,48 @@ func (b byStructureOffset) Len() int           { return len(b) }
 func (b byStructureOffset) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
 func (b byStructureOffset) Less(i, j int) bool { return *(b[i].Offset) < *(b[j].Offset) }
 
+const (
+	// DefaultFactoryResetPreservedMaxSize is the maximum size of a path
+	// preserved on factory reset when none is declared.
+	DefaultFactoryResetPreservedMaxSize = 1 * quantity.SizeMiB
+	// MaxFactoryResetPreservedSize is the limit for the sum of the maximum
+	// sizes of all the paths preserved on factory reset, the data is
+	// kept in ubuntu-save which is small.
+	MaxFactoryResetPreservedSize = 8 * quantity.SizeMiB
+)
+
+func validateFactoryReset(fr *FactoryReset) error {
+	seen := make(map[string]bool, len(fr.Preserve))
+	var total quantity.Size
+	for i := range fr.Preserve {
+		p := &fr.Preserve[i]
+		if p.Path == "" {
+			return errors.New("preserved path cannot be empty")
+		}
+		if filepath.IsAbs(p.Path) || filepath.Clean(p.Path) != p.Path || p.Path == "." ||
+			p.Path == ".." || strings.HasPrefix(p.Path, "../") {
+			return fmt.Errorf("preserved path %q must be a clean path relative to the system data", p.Path)
+		}
+		// the state of snapd is recreated on factory reset
+		if p.Path == "var/lib/snapd" || strings.HasPrefix(p.Path, "var/lib/snapd/") {
+			return fmt.Errorf("preserved path %q cannot be within var/lib/snapd", p.Path)
+		}
+		if seen[p.Path] {
+			return fmt.Errorf("preserved path %q is listed more than once", p.Path)
+		}
+		seen[p.Path] = true
+		if p.MaxSize == 0 {
+			p.MaxSize = DefaultFactoryResetPreservedMaxSize
+		}
+		total += p.MaxSize
+	}
+	if total > MaxFactoryResetPreservedSize {
+		return fmt.Errorf("preserved paths maximum size %s exceeds the limit of %s",
+			total.IECString(), MaxFactoryResetPreservedSize.IECString())
+	}
+	return nil
+}
+
 func validateVolume(vol *Volume) error {
 	if !validVolumeName.MatchString(vol.Name) {
 		return errors.New("invalid name")
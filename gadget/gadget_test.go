@@ -3852,3 +3852,45 @@ kernel-cmdline:
 		}
 	}
 }
+
+func (s *gadgetYamlTestSuite) TestFactoryResetPreserve(c *C) {
+	gi, err := gadget.InfoFromGadgetYaml([]byte(`
+volumes:
+  pc:
+    bootloader: grub
+factory-reset:
+  preserve:
+    - path: var/lib/kiosk/calibration
+      max-size: 2M
+    - path: etc/kiosk.conf
+`), uc20Mod)
+	c.Assert(err, IsNil)
+	c.Check(gi.FactoryReset.Preserve, DeepEquals, []gadget.FactoryResetPreservedPath{
+		{Path: "var/lib/kiosk/calibration", MaxSize: 2 * quantity.SizeMiB},
+		{Path: "etc/kiosk.conf", MaxSize: gadget.DefaultFactoryResetPreservedMaxSize},
+	})
+}
+
+func (s *gadgetYamlTestSuite) TestFactoryResetPreserveErrors(c *C) {
+	for _, tc := range []struct {
+		preserve string
+		err      string
+	}{
+		{"- max-size: 1M", `invalid factory-reset stanza: preserved path cannot be empty`},
+		{"- path: /etc/foo", `invalid factory-reset stanza: preserved path "/etc/foo" must be a clean path relative to the system data`},
+		{"- path: etc/../foo", `invalid factory-reset stanza: preserved path "etc/../foo" must be a clean path relative to the system data`},
+		{"- path: ../foo", `invalid factory-reset stanza: preserved path "../foo" must be a clean path relative to the system data`},
+		{"- path: var/lib/snapd/foo", `invalid factory-reset stanza: preserved path "var/lib/snapd/foo" cannot be within var/lib/snapd`},
+		{"- path: foo\n    - path: foo", `invalid factory-reset stanza: preserved path "foo" is listed more than once`},
+		{"- path: foo\n      max-size: 6M\n    - path: bar\n      max-size: 3M", `invalid factory-reset stanza: preserved paths maximum size 9 MiB exceeds the limit of 8 MiB`},
+	} {
+		_, err := gadget.InfoFromGadgetYaml([]byte(`
+volumes:
+  pc:
+    bootloader: grub
+factory-reset:
+  preserve:
+    `+tc.preserve+"\n"), uc20Mod)
+		c.Check(err, ErrorMatches, tc.err, Commentf("preserve: %s", tc.preserve))
+	}
+}
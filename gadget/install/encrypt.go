@@ -37,6 +37,7 @@ import (
 
 var (
 	secbootFormatEncryptedDevice = secboot.FormatEncryptedDevice
+	secbootAddPassphrase         = secboot.AddPassphrase
 )
 
 // encryptedDeviceCryptsetup represents a encrypted block device.
@@ -96,7 +96,7 @@ func saveStorageTraits(mod gadget.Model, allLaidOutVols map[string]*gadget.LaidO
 	return nil
 }
 
-func maybeEncryptPartition(laidOut *gadget.LaidOutStructure, encryptionType secboot.EncryptionType, sectorSize quantity.Size, perfTimings timings.Measurer) (fsParams *mkfsParams, encryptionKey keys.EncryptionKey, err error) {
+func maybeEncryptPartition(laidOut *gadget.LaidOutStructure, encryptionType secboot.EncryptionType, passphrase string, sectorSize quantity.Size, perfTimings timings.Measurer) (fsParams *mkfsParams, encryptionKey keys.EncryptionKey, err error) {
 	mustEncrypt := (encryptionType != secboot.EncryptionTypeNone)
 	// fsParams.Device is the kernel device that carries the
 	// filesystem, which is either the raw /dev/<partition>, or
@@ -136,9 +136,22 @@ func maybeEncryptPartition(laidOut *gadget.LaidOutStructure, encryptionType secb
 			if err != nil {
 				return nil, nil, err
 			}
+			if passphrase != "" {
+				timings.Run(perfTimings, fmt.Sprintf("add-passphrase[%s]", laidOut.Role()),
+					fmt.Sprintf("Add passphrase for %s", laidOut.Role()),
+					func(timings.Measurer) {
+						err = secbootAddPassphrase(encryptionKey, passphrase, laidOut.Node)
+					})
+				if err != nil {
+					return nil, nil, fmt.Errorf("cannot add passphrase to encrypted device: %v", err)
+				}
+			}
 
 			//TODO:ICE: device-setup hook support goes away
 		case secboot.EncryptionTypeDeviceSetupHook:
+			if passphrase != "" {
+				return nil, nil, fmt.Errorf("cannot use a passphrase with %q encryption", encryptionType)
+			}
 			timings.Run(perfTimings, fmt.Sprintf("new-encrypted-device-setup-hook[%s]", laidOut.Role()),
 				fmt.Sprintf("Create encryption device for %s using device-setup-hook", laidOut.Role()),
 				func(timings.Measurer) {
@@ -194,7 +207,7 @@ func writePartitionContent(laidOut *gadget.LaidOutStructure, fsDevice string, ob
 func installOnePartition(laidOut *gadget.LaidOutStructure, encryptionType secboot.EncryptionType, sectorSize quantity.Size, observer gadget.ContentObserver, perfTimings timings.Measurer) (fsDevice string, encryptionKey keys.EncryptionKey, err error) {
 	// 1. Encrypt
 	role := laidOut.Role()
-	fsParams, encryptionKey, err := maybeEncryptPartition(laidOut, encryptionType, sectorSize, perfTimings)
+	fsParams, encryptionKey, err := maybeEncryptPartition(laidOut, encryptionType, "", sectorSize, perfTimings)
 	if err != nil {
 		return "", nil, fmt.Errorf("cannot encrypt partition %s: %v", role, err)
 	}
@@ -621,7 +634,10 @@ func SaveStorageTraits(model gadget.Model, allLaidOutVols map[string]*gadget.Lai
 	return nil
 }
 
-func EncryptPartitions(onVolumes map[string]*gadget.Volume, encryptionType secboot.EncryptionType, model *asserts.Model, gadgetRoot, kernelRoot string, perfTimings timings.Measurer) (*EncryptionSetupData, error) {
+// EncryptPartitions encrypts the ubuntu-save and ubuntu-data partitions of the
+// given volumes. When a passphrase is provided, it is added to the encrypted
+// partitions so that they can be unlocked without a sealed key.
+func EncryptPartitions(onVolumes map[string]*gadget.Volume, encryptionType secboot.EncryptionType, passphrase string, model *asserts.Model, gadgetRoot, kernelRoot string, perfTimings timings.Measurer) (*EncryptionSetupData, error) {
 
 	// TODO for partial gadgets we should use the data from onVolumes instead of
 	// what using only what comes from gadget.yaml.
@@ -632,7 +648,8 @@ func EncryptPartitions(onVolumes map[string]*gadget.Volume, encryptionType secbo
 	}
 
 	setupData := &EncryptionSetupData{
-		parts: make(map[string]partEncryptionData),
+		parts:          make(map[string]partEncryptionData),
+		withPassphrase: passphrase != "",
 	}
 	for volName, vol := range onVolumes {
 		var onDiskVol *gadget.OnDiskVolume
@@ -667,7 +684,7 @@ func EncryptPartitions(onVolumes map[string]*gadget.Volume, encryptionType secbo
 			logger.Debugf("encrypting partition %s", device)
 
 			fsParams, encryptionKey, err :=
-				maybeEncryptPartition(laidOut, encryptionType, onDiskVol.SectorSize, perfTimings)
+				maybeEncryptPartition(laidOut, encryptionType, passphrase, onDiskVol.SectorSize, perfTimings)
 			if err != nil {
 				return nil, fmt.Errorf("cannot encrypt %q: %v", device, err)
 			}
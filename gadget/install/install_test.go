@@ -1005,6 +1005,7 @@ func (s *installSuite) TestInstallWriteContentDeviceNotFound(c *C) {
 
 type encryptPartitionsOpts struct {
 	encryptType secboot.EncryptionType
+	passphrase  string
 }
 
 func (s *installSuite) testEncryptPartitions(c *C, opts encryptPartitionsOpts) {
@@ -1035,9 +1036,24 @@ func (s *installSuite) testEncryptPartitions(c *C, opts encryptPartitionsOpts) {
 		ginfo.Volumes["pc"].Structure[i].Device = "/dev/vda" + strconv.Itoa(partIdx)
 		partIdx++
 	}
-	encryptSetup, err := install.EncryptPartitions(ginfo.Volumes, opts.encryptType, model, gadgetRoot, "", timings.New(nil))
+	var passphraseNodes []string
+	restore = install.MockSecbootAddPassphrase(func(key keys.EncryptionKey, passphrase, node string) error {
+		c.Check(passphrase, Equals, opts.passphrase)
+		passphraseNodes = append(passphraseNodes, node)
+		return nil
+	})
+	defer restore()
+
+	encryptSetup, err := install.EncryptPartitions(ginfo.Volumes, opts.encryptType, opts.passphrase, model, gadgetRoot, "", timings.New(nil))
 	c.Assert(err, IsNil)
 	c.Assert(encryptSetup, NotNil)
+	if opts.passphrase != "" {
+		c.Check(encryptSetup.UsesPassphrase(), Equals, true)
+		c.Check(passphraseNodes, DeepEquals, []string{"/dev/vda4", "/dev/vda5"})
+	} else {
+		c.Check(encryptSetup.UsesPassphrase(), Equals, false)
+		c.Check(passphraseNodes, HasLen, 0)
+	}
 	err = install.CheckEncryptionSetupData(encryptSetup, map[string]string{
 		"ubuntu-save": "/dev/mapper/ubuntu-save",
 		"ubuntu-data": "/dev/mapper/ubuntu-data",
@@ -1060,6 +1076,13 @@ func (s *installSuite) TestInstallEncryptPartitionsLUKSHappy(c *C) {
 	})
 }
 
+func (s *installSuite) TestInstallEncryptPartitionsLUKSWithPassphraseHappy(c *C) {
+	s.testEncryptPartitions(c, encryptPartitionsOpts{
+		encryptType: secboot.EncryptionTypeLUKS,
+		passphrase:  "my secret",
+	})
+}
+
 func (s *installSuite) TestInstallEncryptPartitionsNoDeviceSet(c *C) {
 	vdaSysPath := "/sys/devices/pci0000:00/0000:00:03.0/virtio1/block/vda"
 	restore := install.MockSysfsPathForBlockDevice(func(device string) (string, error) {
@@ -1073,7 +1096,7 @@ func (s *installSuite) TestInstallEncryptPartitionsNoDeviceSet(c *C) {
 	c.Assert(err, IsNil)
 	defer restore()
 
-	encryptSetup, err := install.EncryptPartitions(ginfo.Volumes, secboot.EncryptionTypeLUKS, model, gadgetRoot, "", timings.New(nil))
+	encryptSetup, err := install.EncryptPartitions(ginfo.Volumes, secboot.EncryptionTypeLUKS, "", model, gadgetRoot, "", timings.New(nil))
 
 	c.Check(err, ErrorMatches, "device field for volume struct .* cannot be empty")
 	c.Check(encryptSetup, IsNil)
@@ -62,6 +62,15 @@ type partEncryptionData struct {
 type EncryptionSetupData struct {
 	// maps from partition label to data
 	parts map[string]partEncryptionData
+	// withPassphrase is set when the partitions can be unlocked with a
+	// user passphrase
+	withPassphrase bool
+}
+
+// UsesPassphrase returns true if the encrypted partitions are protected with
+// a user passphrase.
+func (esd *EncryptionSetupData) UsesPassphrase() bool {
+	return esd.withPassphrase
 }
 
 // EncryptedDevices returns a map partition role -> LUKS mapper device.
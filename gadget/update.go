@@ -968,7 +968,9 @@ func buildVolumeStructureToLocation(mod Model,
 			ExpectedStructureEncryption: diskDeviceTraits.StructureEncryption,
 		}
 
-		disk, err := searchForVolumeWithTraits(laidOutVol, diskDeviceTraits, validateOpts)
+		// the disk is searched for using the layout it has before the
+		// update, that is without any structures that get added or grown
+		disk, err := searchForVolumeWithTraits(currentLayout(laidOutVol, vol), diskDeviceTraits, validateOpts)
 		if err != nil {
 			dieErr := fmt.Errorf("could not map volume %s from gadget.yaml to any physical disk: %v", volName, err)
 			return nil, maybeFatalError(dieErr)
@@ -1018,6 +1020,9 @@ func buildVolumeStructureToLocation(mod Model,
 				// to find the decrypted mapper device for the encrypted device
 				// node and then find the root mount point of the mapper device
 				if _, ok := diskDeviceTraits.StructureEncryption[volStruct.Name]; ok {
+					if laidOutVol.LaidOutStructure[volYamlIndex].VolumeStructure.Size != volStruct.Size {
+						return nil, fmt.Errorf("cannot grow encrypted structure %s on volume %s", volStruct.Name, volName)
+					}
 					logger.Noticef("gadget asset update for assets on encrypted partition %s unsupported", volStruct.Name)
 
 					// leaving this structure as an empty location will
@@ -1049,6 +1054,11 @@ func buildVolumeStructureToLocation(mod Model,
 					mountpt = mountpts[0]
 				}
 				loc.RootMountPoint = mountpt
+				if laidOutVol.LaidOutStructure[volYamlIndex].VolumeStructure.Size != volStruct.Size {
+					// the partition is grown, which needs the disk
+					loc.Device = disk.KernelDeviceNode()
+					loc.Offset = structStartOffset
+				}
 			} else {
 				// no filesystem, the device for this one is just the device
 				// for the disk itself
@@ -1058,11 +1068,60 @@ func buildVolumeStructureToLocation(mod Model,
 
 			volumeStructureToLocation[volName][volYamlIndex] = loc
 		}
+
+		// structures added by the update do not exist yet, they will be
+		// created on the disk at their start offset
+		for _, ps := range laidOutVol.LaidOutStructure {
+			if ps.YamlIndex < len(vol.Structure) {
+				continue
+			}
+			volumeStructureToLocation[volName][ps.YamlIndex] = StructureLocation{
+				Device: disk.KernelDeviceNode(),
+				Offset: ps.StartOffset,
+			}
+		}
 	}
 
 	return volumeStructureToLocation, nil
 }
 
+// currentLayout returns the laid out volume as it is on the disk before an
+// update to it, leaving out the structures that get added and using the
+// current size of the structures that get grown.
+func currentLayout(laidOutVol *LaidOutVolume, current *Volume) *LaidOutVolume {
+	changed := len(laidOutVol.LaidOutStructure) != len(current.Structure)
+	for _, ps := range laidOutVol.LaidOutStructure {
+		if ps.YamlIndex < len(current.Structure) && ps.VolumeStructure.Size != current.Structure[ps.YamlIndex].Size {
+			changed = true
+		}
+	}
+	if !changed {
+		return laidOutVol
+	}
+
+	vol := *laidOutVol.Volume
+	if len(vol.Structure) > len(current.Structure) {
+		vol.Structure = vol.Structure[:len(current.Structure)]
+	}
+	layout := &LaidOutVolume{
+		Volume: &vol,
+		Size:   laidOutVol.Size,
+	}
+	for _, ps := range laidOutVol.LaidOutStructure {
+		if ps.YamlIndex >= len(current.Structure) {
+			continue
+		}
+		if size := current.Structure[ps.YamlIndex].Size; ps.VolumeStructure.Size != size {
+			vs := *ps.VolumeStructure
+			vs.Size = size
+			ps.VolumeStructure = &vs
+			ps.Size = size
+		}
+		layout.LaidOutStructure = append(layout.LaidOutStructure, ps)
+	}
+	return layout
+}
+
 func MockVolumeStructureToLocationMap(f func(_ GadgetData, _ Model, _ map[string]*LaidOutVolume) (map[string]map[int]StructureLocation, error)) (restore func()) {
 	old := volumeStructureToLocationMap
 	volumeStructureToLocationMap = f
@@ -1172,7 +1231,94 @@ func volumeStructureToLocationMapImpl(old GadgetData, mod Model, laidOutVols map
 // kernel (rule 1)
 // d. After step (c) is completed the kernel refresh will now also work (no more
 // violation of rule 1)
+//
+// The progress of the update is recorded in a journal next to the rollback
+// directory, see UpdateJournalPath. When the journal shows that a previous
+// update using the same rollback directory was interrupted, the update is
+// resumed, reusing the backups taken then.
 func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePolicy UpdatePolicyFunc, observer ContentUpdateObserver) error {
+	structureLocations, allUpdates, err := planUpdate(model, old, new, updatePolicy)
+	if err != nil {
+		if err == errSkipUpdateProceedRefresh {
+			// we couldn't successfully build a map for the structure locations,
+			// but for various reasons this isn't considered a fatal error for
+			// the gadget refresh, so just return nil instead, a message should
+			// already have been logged
+			return nil
+		}
+		return err
+	}
+
+	// apply all updates at once
+	if err := applyUpdates(structureLocations, new, allUpdates, rollbackDirPath, observer); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RollbackUpdate restores the structures that an interrupted update, as
+// recorded in its journal, may have modified from the backups kept in the
+// rollback directory. The arguments must be the same as those of the
+// interrupted Update call. ErrNoUpdate is returned if there is no interrupted
+// update to roll back.
+func RollbackUpdate(model Model, old, new GadgetData, rollbackDirPath string, updatePolicy UpdatePolicyFunc, observer ContentUpdateObserver) error {
+	journal, err := ReadUpdateJournal(rollbackDirPath)
+	if err != nil {
+		return err
+	}
+	if !journal.Interrupted() {
+		return ErrNoUpdate
+	}
+
+	structureLocations, allUpdates, err := planUpdate(model, old, new, updatePolicy)
+	if err != nil {
+		if err == errSkipUpdateProceedRefresh {
+			err = fmt.Errorf("cannot locate structures on disk")
+		}
+		return fmt.Errorf("cannot prepare gadget update rollback: %v", err)
+	}
+	updaters, err := updatersForUpdates(structureLocations, new, allUpdates, rollbackDirPath, observer)
+	if err != nil {
+		return err
+	}
+
+	var rollbackErr error
+	for i := len(updaters) - 1; i >= 0; i-- {
+		e := journal.entry(allUpdates[i].volume.Name, allUpdates[i].to.YamlIndex)
+		if e == nil || (e.State != StructureUpdating && e.State != StructureUpdated) {
+			// never written to
+			continue
+		}
+		if err := updaters[i].Rollback(); err != nil {
+			logger.Noticef("cannot rollback volume structure %v update on volume %s: %v", allUpdates[i].to, allUpdates[i].volume.Name, err)
+			if rollbackErr == nil {
+				rollbackErr = fmt.Errorf("cannot rollback volume structure %v update on volume %s: %v", allUpdates[i].to, allUpdates[i].volume.Name, err)
+			}
+			continue
+		}
+		if err := journal.setState(e, StructureRolledBack); err != nil {
+			return err
+		}
+	}
+	if rollbackErr != nil {
+		return rollbackErr
+	}
+	if err := journal.setStatus(UpdateRolledBack); err != nil {
+		return err
+	}
+
+	if observer != nil {
+		if err := observer.Canceled(); err != nil {
+			logger.Noticef("cannot observe canceled update: %v", err)
+		}
+	}
+	return nil
+}
+
+// planUpdate validates the update from the old to the new gadget and
+// returns the structures to update along with their locations.
+func planUpdate(model Model, old, new GadgetData, updatePolicy UpdatePolicyFunc) (map[string]map[int]StructureLocation, []updatePair, error) {
 	// if the volumes from the old and the new gadgets do not match, then fail -
 	// we don't support adding or removing volumes from the gadget.yaml
 	newVolumes := make([]string, 0, len(new.Info.Volumes))
@@ -1188,15 +1334,15 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 	switch {
 	case len(common) != len(newVolumes) && len(common) != len(oldVolumes):
 		// there are both volumes removed from old and volumes added to new
-		return fmt.Errorf("cannot update gadget assets: volumes were both added and removed")
+		return nil, nil, fmt.Errorf("cannot update gadget assets: volumes were both added and removed")
 	case len(common) != len(newVolumes):
 		// then there are volumes in old that are not in new, i.e. a volume
 		// was removed
-		return fmt.Errorf("cannot update gadget assets: volumes were removed")
+		return nil, nil, fmt.Errorf("cannot update gadget assets: volumes were removed")
 	case len(common) != len(oldVolumes):
 		// then there are volumes in new that are not in old, i.e. a volume
 		// was added
-		return fmt.Errorf("cannot update gadget assets: volumes were added")
+		return nil, nil, fmt.Errorf("cannot update gadget assets: volumes were added")
 	}
 
 	if updatePolicy == nil {
@@ -1220,7 +1366,7 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 	// ensure all required kernel assets are found in the gadget
 	kernelInfo, err := kernel.ReadInfo(new.KernelRootDir)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	allKernelAssets := []string{}
@@ -1246,25 +1392,25 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 		newVol := new.Info.Volumes[volName]
 
 		if oldVol.Schema == "" || newVol.Schema == "" {
-			return fmt.Errorf("internal error: unset volume schemas: old: %q new: %q", oldVol.Schema, newVol.Schema)
+			return nil, nil, fmt.Errorf("internal error: unset volume schemas: old: %q new: %q", oldVol.Schema, newVol.Schema)
 		}
 
 		// layout old partially, without going deep into the layout of structure
 		// content
 		pOld, err := LayoutVolumePartially(oldVol)
 		if err != nil {
-			return fmt.Errorf("cannot lay out the old volume %s: %v", volName, err)
+			return nil, nil, fmt.Errorf("cannot lay out the old volume %s: %v", volName, err)
 		}
 
 		pNew, err := LayoutVolume(newVol, opts)
 		if err != nil {
-			return fmt.Errorf("cannot lay out the new volume %s: %v", volName, err)
+			return nil, nil, fmt.Errorf("cannot lay out the new volume %s: %v", volName, err)
 		}
 
 		laidOutVols[volName] = pNew
 
 		if err := canUpdateVolume(pOld, pNew); err != nil {
-			return fmt.Errorf("cannot apply update to volume %s: %v", volName, err)
+			return nil, nil, fmt.Errorf("cannot apply update to volume %s: %v", volName, err)
 		}
 
 		// if we haven't consumed any kernel assets yet check if this volume
@@ -1273,7 +1419,7 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 		if !atLeastOneKernelAssetConsumed {
 			consumed, err := gadgetVolumeKernelUpdateAssetsConsumed(pNew.Volume, kernelInfo)
 			if err != nil {
-				return err
+				return nil, nil, err
 			}
 			atLeastOneKernelAssetConsumed = consumed
 		}
@@ -1281,13 +1427,17 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 		// now we know which structure is which, find which ones need an update
 		updates, err := resolveUpdate(pOld, pNew, updatePolicy, new.RootDir, new.KernelRootDir, kernelInfo)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 
 		// can update old layout to new layout
 		for _, update := range updates {
+			if update.from == nil {
+				// added structure, checked with the volume
+				continue
+			}
 			if err := canUpdateStructure(update.from, update.to, pNew.Schema); err != nil {
-				return fmt.Errorf("cannot update volume structure %v for volume %s: %v", update.to, volName, err)
+				return nil, nil, fmt.Errorf("cannot update volume structure %v for volume %s: %v", update.to, volName, err)
 			}
 		}
 
@@ -1300,12 +1450,12 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 	// any of the volumes
 	if len(allKernelAssets) != 0 && !atLeastOneKernelAssetConsumed {
 		sort.Strings(allKernelAssets)
-		return fmt.Errorf("gadget does not consume any of the kernel assets needing synced update %s", strutil.Quoted(allKernelAssets))
+		return nil, nil, fmt.Errorf("gadget does not consume any of the kernel assets needing synced update %s", strutil.Quoted(allKernelAssets))
 	}
 
 	if len(allUpdates) == 0 {
 		// nothing to update
-		return ErrNoUpdate
+		return nil, nil, ErrNoUpdate
 	}
 
 	// build the map of volume structure locations where the first key is the
@@ -1314,14 +1464,7 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 	// hat can actually be used to perform the lookup/update in applyUpdates
 	structureLocations, err := volumeStructureToLocationMap(old, model, laidOutVols)
 	if err != nil {
-		if err == errSkipUpdateProceedRefresh {
-			// we couldn't successfully build a map for the structure locations,
-			// but for various reasons this isn't considered a fatal error for
-			// the gadget refresh, so just return nil instead, a message should
-			// already have been logged
-			return nil
-		}
-		return err
+		return nil, nil, err
 	}
 
 	if len(new.Info.Volumes) != 1 {
@@ -1350,12 +1493,7 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 		}
 	}
 
-	// apply all updates at once
-	if err := applyUpdates(structureLocations, new, allUpdates, rollbackDirPath, observer); err != nil {
-		return err
-	}
-
-	return nil
+	return structureLocations, allUpdates, nil
 }
 
 func resolveVolume(old *Info, new *Info) (oldVol, newVol *Volume, err error) {
@@ -1410,7 +1548,7 @@ func canUpdateStructure(from *LaidOutStructure, to *LaidOutStructure, schema str
 		// partition names are only effective when GPT is used
 		return fmt.Errorf("cannot change structure name from %q to %q", from.Name(), to.Name())
 	}
-	if from.VolumeStructure.Size != to.VolumeStructure.Size {
+	if from.VolumeStructure.Size != to.VolumeStructure.Size && !canGrowStructure(from, to) {
 		return fmt.Errorf("cannot change structure size from %v to %v", from.VolumeStructure.Size, to.VolumeStructure.Size)
 	}
 	if !isSameOffset(from.VolumeStructure.Offset, to.VolumeStructure.Offset) {
@@ -1462,13 +1600,89 @@ func canUpdateVolume(from *PartiallyLaidOutVolume, to *LaidOutVolume) error {
 	if from.Schema != to.Schema {
 		return fmt.Errorf("cannot change volume schema from %q to %q", from.Schema, to.Schema)
 	}
-	if len(from.LaidOutStructure) != len(to.LaidOutStructure) {
+	if len(from.LaidOutStructure) > len(to.LaidOutStructure) {
 		return fmt.Errorf("cannot change the number of structures within volume from %v to %v", len(from.LaidOutStructure), len(to.LaidOutStructure))
 	}
+	if len(from.LaidOutStructure) == 0 {
+		return nil
+	}
+
+	// only the last structure can be grown, growing any other one would
+	// move the ones after it
+	last := len(from.LaidOutStructure) - 1
+	for i := 0; i < last; i++ {
+		if to.LaidOutStructure[i].VolumeStructure.Size > from.LaidOutStructure[i].VolumeStructure.Size {
+			return fmt.Errorf("cannot grow structure %v, only the last structure of a volume can be grown", to.LaidOutStructure[i])
+		}
+	}
+
+	// new structures can be added in the free space after the existing
+	// ones
+	end := from.LaidOutStructure[last].StartOffset + quantity.Offset(to.LaidOutStructure[last].VolumeStructure.Size)
+	for i := len(from.LaidOutStructure); i < len(to.LaidOutStructure); i++ {
+		if err := canAddStructure(&to.LaidOutStructure[i], len(from.Volume.Structure), end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canGrowStructure returns true if the given structure can be grown in
+// place, which is supported for partitions with no filesystem or with an ext4
+// one. The system-data and system-save structures, which may be encrypted,
+// cannot be grown. Like for content, the update must be requested by bumping
+// the update edition of the structure.
+func canGrowStructure(from *LaidOutStructure, to *LaidOutStructure) bool {
+	if to.VolumeStructure.Size < from.VolumeStructure.Size {
+		return false
+	}
+	if !to.IsPartition() {
+		return false
+	}
+	switch to.Role() {
+	case SystemData, SystemSave:
+		return false
+	}
+	if to.VolumeStructure.Update.Edition <= from.VolumeStructure.Update.Edition {
+		return false
+	}
+	switch to.Filesystem() {
+	case "", "ext4":
+		return true
+	}
+	return false
+}
+
+// canAddStructure checks whether the given structure, not present in the
+// volume on the disk yet, can be created during an update. Structures are
+// created as partitions after the existing ones, when their update edition is
+// set.
+func canAddStructure(ps *LaidOutStructure, existingStructures int, freeSpaceStart quantity.Offset) error {
+	if ps.YamlIndex < existingStructures {
+		return fmt.Errorf("cannot add structure %v before existing structures", ps)
+	}
+	if ps.StartOffset < freeSpaceStart {
+		return fmt.Errorf("cannot add structure %v, it overlaps with existing structures", ps)
+	}
+	if !ps.IsPartition() {
+		return fmt.Errorf("cannot add structure %v, only partitions can be added", ps)
+	}
+	if ps.Role() != "" {
+		return fmt.Errorf("cannot add structure %v with role %q", ps, ps.Role())
+	}
+	if ps.VolumeStructure.Update.Edition == 0 {
+		return fmt.Errorf("cannot add structure %v without an update edition", ps)
+	}
+	switch ps.Filesystem() {
+	case "", "ext4", "vfat":
+	default:
+		return fmt.Errorf("cannot add structure %v with filesystem %q", ps, ps.Filesystem())
+	}
 	return nil
 }
 
 type updatePair struct {
+	// from is nil for structures that are added by the update
 	from   *LaidOutStructure
 	to     *LaidOutStructure
 	volume *Volume
@@ -1511,11 +1725,19 @@ func KernelUpdatePolicy(from, to *LaidOutStructure) (bool, ResolvedContentFilter
 }
 
 func resolveUpdate(oldVol *PartiallyLaidOutVolume, newVol *LaidOutVolume, policy UpdatePolicyFunc, newGadgetRootDir, newKernelRootDir string, kernelInfo *kernel.Info) (updates []updatePair, err error) {
-	if len(oldVol.LaidOutStructure) != len(newVol.LaidOutStructure) {
+	if len(oldVol.LaidOutStructure) > len(newVol.LaidOutStructure) {
 		return nil, errors.New("internal error: the number of structures in new and old volume definitions is different")
 	}
-	for j, oldStruct := range oldVol.LaidOutStructure {
-		newStruct := newVol.LaidOutStructure[j]
+	for j, newStruct := range newVol.LaidOutStructure {
+		// structures added by the new gadget are compared against
+		// an empty one, canUpdateVolume made sure they have an
+		// update edition set so that they get created
+		var from *LaidOutStructure
+		oldStruct := LaidOutStructure{VolumeStructure: &VolumeStructure{}}
+		if j < len(oldVol.LaidOutStructure) {
+			from = &oldVol.LaidOutStructure[j]
+			oldStruct = *from
+		}
 		// update only when the policy says so; boot assets
 		// are assumed to be backwards compatible, once
 		// deployed they are not rolled back or replaced unless
@@ -1535,7 +1757,7 @@ func resolveUpdate(oldVol *PartiallyLaidOutVolume, newVol *LaidOutVolume, policy
 
 			// and add to updates
 			updates = append(updates, updatePair{
-				from:   &oldVol.LaidOutStructure[j],
+				from:   from,
 				to:     &newVol.LaidOutStructure[j],
 				volume: newVol.Volume,
 			})
@@ -1556,6 +1778,14 @@ type Updater interface {
 	Rollback() error
 }
 
+// updateFinalizer is implemented by updaters with changes that cannot be
+// rolled back, which are applied once all structures have been updated.
+type updateFinalizer interface {
+	// Finalize applies the remaining changes, it is called again when
+	// an interrupted update is resumed.
+	Finalize() error
+}
+
 func updateLocationForStructure(structureLocations map[string]map[int]StructureLocation, ps *LaidOutStructure) (loc StructureLocation, err error) {
 	loc, ok := structureLocations[ps.VolumeStructure.VolumeName][ps.YamlIndex]
 	if !ok {
@@ -1581,20 +1811,56 @@ func updateLocationForStructure(structureLocations map[string]map[int]StructureL
 	}
 }
 
-func applyUpdates(structureLocations map[string]map[int]StructureLocation, new GadgetData, updates []updatePair, rollbackDir string, observer ContentUpdateObserver) error {
+func updatersForUpdates(structureLocations map[string]map[int]StructureLocation, new GadgetData, updates []updatePair, rollbackDir string, observer ContentUpdateObserver) ([]Updater, error) {
 	updaters := make([]Updater, len(updates))
 
 	for i, one := range updates {
+		if one.from == nil {
+			// added structure, its partition is created along with
+			// the content
+			loc := structureLocations[one.to.VolumeStructure.VolumeName][one.to.YamlIndex]
+			up, err := partitionUpdaterForStructure(loc, nil, one.to, new.RootDir, rollbackDir, nil)
+			if err != nil {
+				return nil, fmt.Errorf("cannot prepare update for volume structure %v on volume %s: %v", one.to, one.volume.Name, err)
+			}
+			updaters[i] = up
+			continue
+		}
 		loc, err := updateLocationForStructure(structureLocations, one.to)
 		if err != nil {
-			return fmt.Errorf("cannot prepare update for volume structure %v on volume %s: %v", one.to, one.volume.Name, err)
+			return nil, fmt.Errorf("cannot prepare update for volume structure %v on volume %s: %v", one.to, one.volume.Name, err)
 		}
 		up, err := updaterForStructure(loc, one.to, new.RootDir, rollbackDir, observer)
 		if err != nil {
-			return fmt.Errorf("cannot prepare update for volume structure %v on volume %s: %v", one.to, one.volume.Name, err)
+			return nil, fmt.Errorf("cannot prepare update for volume structure %v on volume %s: %v", one.to, one.volume.Name, err)
+		}
+		if one.from.VolumeStructure.Size != one.to.VolumeStructure.Size {
+			// grown structure, its partition is grown before the
+			// content is updated
+			up, err = partitionUpdaterForStructure(loc, one.from, one.to, new.RootDir, rollbackDir, up)
+			if err != nil {
+				return nil, fmt.Errorf("cannot prepare update for volume structure %v on volume %s: %v", one.to, one.volume.Name, err)
+			}
 		}
 		updaters[i] = up
 	}
+	return updaters, nil
+}
+
+func applyUpdates(structureLocations map[string]map[int]StructureLocation, new GadgetData, updates []updatePair, rollbackDir string, observer ContentUpdateObserver) error {
+	updaters, err := updatersForUpdates(structureLocations, new, updates, rollbackDir, observer)
+	if err != nil {
+		return err
+	}
+
+	journal, err := startUpdateJournal(rollbackDir, updates)
+	if err != nil {
+		return err
+	}
+	if journal.Attempts > 1 {
+		logger.Noticef("resuming interrupted gadget update (attempt %d)", journal.Attempts)
+	}
+	entries := journal.Structures
 
 	var backupErr error
 	for i, one := range updaters {
@@ -1602,6 +1868,15 @@ func applyUpdates(structureLocations map[string]map[int]StructureLocation, new G
 			backupErr = fmt.Errorf("cannot backup volume structure %v on volume %s: %v", updates[i].to, updates[i].volume.Name, err)
 			break
 		}
+		// structures that may have been written by an interrupted
+		// attempt keep their state, backups of those were taken
+		// before and are reused
+		if entries[i].State == StructurePending {
+			if err := journal.setState(entries[i], StructureBackedUp); err != nil {
+				backupErr = err
+				break
+			}
+		}
 	}
 	if backupErr != nil {
 		if observer != nil {
@@ -1609,6 +1884,9 @@ func applyUpdates(structureLocations map[string]map[int]StructureLocation, new G
 				logger.Noticef("cannot observe canceled prepare update: %v", err)
 			}
 		}
+		if err := journal.setStatus(UpdateCanceled); err != nil {
+			logger.Noticef("%v", err)
+		}
 		return backupErr
 	}
 	if observer != nil {
@@ -1622,14 +1900,41 @@ func applyUpdates(structureLocations map[string]map[int]StructureLocation, new G
 	var skipped int
 	for i, one := range updaters {
 		updateLastAttempted = i
+		if err := journal.setState(entries[i], StructureUpdating); err != nil {
+			updateErr = err
+			break
+		}
 		if err := one.Update(); err != nil {
 			if err == ErrNoUpdate {
 				skipped++
+				if err := journal.setState(entries[i], StructureUnchanged); err != nil {
+					updateErr = err
+					break
+				}
 				continue
 			}
 			updateErr = fmt.Errorf("cannot update volume structure %v on volume %s: %v", updates[i].to, updates[i].volume.Name, err)
 			break
 		}
+		if err := journal.setState(entries[i], StructureUpdated); err != nil {
+			updateErr = err
+			break
+		}
+	}
+	if updateErr == nil {
+		if err := journal.setStatus(UpdateDone); err != nil {
+			return err
+		}
+		// the changes that cannot be rolled back are applied last
+		for i, one := range updaters {
+			f, ok := one.(updateFinalizer)
+			if !ok {
+				continue
+			}
+			if err := f.Finalize(); err != nil {
+				logger.Noticef("cannot finalize update of volume structure %v on volume %s: %v", updates[i].to, updates[i].volume.Name, err)
+			}
+		}
 	}
 	if skipped == len(updaters) {
 		// all updates were a noop
@@ -1648,8 +1953,15 @@ func applyUpdates(structureLocations map[string]map[int]StructureLocation, new G
 		if err := one.Rollback(); err != nil {
 			// TODO: log errors to oplog
 			logger.Noticef("cannot rollback volume structure %v update on volume %s: %v", updates[i].to, updates[i].volume.Name, err)
+			continue
+		}
+		if err := journal.setState(entries[i], StructureRolledBack); err != nil {
+			logger.Noticef("%v", err)
 		}
 	}
+	if err := journal.setStatus(UpdateRolledBack); err != nil {
+		logger.Noticef("%v", err)
+	}
 
 	if observer != nil {
 		if err := observer.Canceled(); err != nil {
@@ -1678,6 +1990,22 @@ func updaterForStructureImpl(loc StructureLocation, ps *LaidOutStructure, newRoo
 	}
 }
 
+var partitionUpdaterForStructure = partitionUpdaterForStructureImpl
+
+func partitionUpdaterForStructureImpl(loc StructureLocation, from, to *LaidOutStructure, newRootDir, rollbackDir string, contentUpdater Updater) (Updater, error) {
+	return newPartitionUpdater(loc, from, to, newRootDir, rollbackDir, contentUpdater)
+}
+
+// MockPartitionUpdaterForStructure replaces the internal call creating
+// updaters changing the partition table, for use in tests only
+func MockPartitionUpdaterForStructure(mock func(loc StructureLocation, from, to *LaidOutStructure, rootDir, rollbackDir string, contentUpdater Updater) (Updater, error)) (restore func()) {
+	old := partitionUpdaterForStructure
+	partitionUpdaterForStructure = mock
+	return func() {
+		partitionUpdaterForStructure = old
+	}
+}
+
 // MockUpdaterForStructure replace internal call with a mocked one, for use in tests only
 func MockUpdaterForStructure(mock func(loc StructureLocation, ps *LaidOutStructure, rootDir, rollbackDir string, observer ContentUpdateObserver) (Updater, error)) (restore func()) {
 	old := updaterForStructure
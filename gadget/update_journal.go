@@ -0,0 +1,211 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gadget
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/snapcore/snapd/osutil"
+)
+
+// UpdateJournalStatus is the overall status of a gadget update recorded in
+// the update journal.
+type UpdateJournalStatus string
+
+const (
+	// UpdateInProgress is the status of an update that has not finished,
+	// if found when no update is running the update was interrupted.
+	UpdateInProgress UpdateJournalStatus = "in-progress"
+	// UpdateDone is the status of an update that was fully applied.
+	UpdateDone UpdateJournalStatus = "done"
+	// UpdateRolledBack is the status of an update that failed or was
+	// interrupted and whose structures were restored from backups.
+	UpdateRolledBack UpdateJournalStatus = "rolled-back"
+	// UpdateCanceled is the status of an update that was abandoned
+	// before any structure was modified.
+	UpdateCanceled UpdateJournalStatus = "canceled"
+)
+
+// StructureUpdateState is the progress of the update of a single structure.
+type StructureUpdateState string
+
+const (
+	StructurePending    StructureUpdateState = "pending"
+	StructureBackedUp   StructureUpdateState = "backed-up"
+	StructureUpdating   StructureUpdateState = "updating"
+	StructureUpdated    StructureUpdateState = "updated"
+	StructureUnchanged  StructureUpdateState = "unchanged"
+	StructureRolledBack StructureUpdateState = "rolled-back"
+)
+
+// UpdateJournalEntry records the progress of the update of one structure.
+type UpdateJournalEntry struct {
+	Volume    string               `json:"volume"`
+	Name      string               `json:"name,omitempty"`
+	YamlIndex int                  `json:"yaml-index"`
+	State     StructureUpdateState `json:"state"`
+}
+
+// UpdateJournal records the progress of a gadget update, structure by
+// structure, so that an update interrupted by e.g. a power loss can be
+// resumed or rolled back on the next boot.
+type UpdateJournal struct {
+	Status UpdateJournalStatus `json:"status"`
+	// Attempts is the number of times the update was started, it is
+	// larger than 1 when an interrupted update was resumed.
+	Attempts   int                   `json:"attempts"`
+	Started    time.Time             `json:"started"`
+	Modified   time.Time             `json:"modified"`
+	Structures []*UpdateJournalEntry `json:"structures"`
+
+	path string
+}
+
+// UpdateJournalPath returns the path of the update journal kept next to the
+// given rollback directory.
+func UpdateJournalPath(rollbackDir string) string {
+	return filepath.Clean(rollbackDir) + ".journal"
+}
+
+var timeNow = time.Now
+
+// ReadUpdateJournal reads the update journal of the update using the given
+// rollback directory. If there is no journal, nil is returned.
+func ReadUpdateJournal(rollbackDir string) (*UpdateJournal, error) {
+	path := UpdateJournalPath(rollbackDir)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var j UpdateJournal
+	if err := json.Unmarshal(b, &j); err != nil {
+		return nil, fmt.Errorf("cannot decode gadget update journal %q: %v", path, err)
+	}
+	j.path = path
+	return &j, nil
+}
+
+// Interrupted returns true if the journal is of an update that has not
+// finished.
+func (j *UpdateJournal) Interrupted() bool {
+	return j != nil && j.Status == UpdateInProgress
+}
+
+func (j *UpdateJournal) entry(volume string, yamlIndex int) *UpdateJournalEntry {
+	for _, e := range j.Structures {
+		if e.Volume == volume && e.YamlIndex == yamlIndex {
+			return e
+		}
+	}
+	return nil
+}
+
+func (j *UpdateJournal) save() error {
+	if j.path == "" {
+		// not persisted
+		return nil
+	}
+	j.Modified = timeNow()
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(j.path, b, 0644, 0)
+}
+
+// setState updates the state of the given structure and persists the
+// journal.
+func (j *UpdateJournal) setState(e *UpdateJournalEntry, state StructureUpdateState) error {
+	e.State = state
+	if err := j.save(); err != nil {
+		return fmt.Errorf("cannot update gadget update journal: %v", err)
+	}
+	return nil
+}
+
+// setStatus updates the overall status of the update and persists the
+// journal.
+func (j *UpdateJournal) setStatus(status UpdateJournalStatus) error {
+	j.Status = status
+	if err := j.save(); err != nil {
+		return fmt.Errorf("cannot update gadget update journal: %v", err)
+	}
+	return nil
+}
+
+// startUpdateJournal starts the journal for an update of the given
+// structures. An existing journal of an interrupted update is carried over.
+// The journal is only kept in memory when there is no rollback directory.
+func startUpdateJournal(rollbackDir string, updates []updatePair) (*UpdateJournal, error) {
+	var prev *UpdateJournal
+	if rollbackDir != "" {
+		var err error
+		prev, err = ReadUpdateJournal(rollbackDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	j := &UpdateJournal{
+		Status:     UpdateInProgress,
+		Attempts:   1,
+		Started:    timeNow(),
+		Structures: make([]*UpdateJournalEntry, len(updates)),
+	}
+	if rollbackDir != "" {
+		j.path = UpdateJournalPath(rollbackDir)
+	}
+	if prev.Interrupted() {
+		j.Attempts = prev.Attempts + 1
+		j.Started = prev.Started
+	}
+	for i, one := range updates {
+		state := StructurePending
+		if prev.Interrupted() {
+			// keep the progress of structures that may have been
+			// written already, so that a rollback knows about them
+			if e := prev.entry(one.volume.Name, one.to.YamlIndex); e != nil && (e.State == StructureUpdating || e.State == StructureUpdated) {
+				state = e.State
+			}
+		}
+		j.Structures[i] = &UpdateJournalEntry{
+			Volume:    one.volume.Name,
+			Name:      one.to.Name(),
+			YamlIndex: one.to.YamlIndex,
+			State:     state,
+		}
+	}
+	if j.path != "" {
+		if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+			return nil, err
+		}
+	}
+	if err := j.save(); err != nil {
+		return nil, fmt.Errorf("cannot write gadget update journal: %v", err)
+	}
+	return j, nil
+}
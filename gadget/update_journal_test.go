@@ -0,0 +1,234 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gadget_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/gadget"
+)
+
+// mockJournaledUpdaters mocks updaters for all structures, recording the
+// calls made to them in calls, update of the structure named failUpdate
+// fails.
+func (u *updateTestSuite) mockJournaledUpdaters(c *C, calls *[]string, failUpdate string) {
+	restore := gadget.MockUpdaterForStructure(func(loc gadget.StructureLocation, ps *gadget.LaidOutStructure, psRootDir, psRollbackDir string, observer gadget.ContentUpdateObserver) (gadget.Updater, error) {
+		return &mockUpdater{
+			backupCb: func() error {
+				*calls = append(*calls, "backup:"+ps.Name())
+				return nil
+			},
+			updateCb: func() error {
+				*calls = append(*calls, "update:"+ps.Name())
+				if ps.Name() == failUpdate {
+					return errors.New("failed")
+				}
+				return nil
+			},
+			rollbackCb: func() error {
+				*calls = append(*calls, "rollback:"+ps.Name())
+				return nil
+			},
+		}, nil
+	})
+	u.AddCleanup(restore)
+}
+
+func (u *updateTestSuite) writeInterruptedJournal(c *C, rollbackDir string) {
+	err := ioutil.WriteFile(gadget.UpdateJournalPath(rollbackDir), []byte(`{
+"status": "in-progress",
+"attempts": 1,
+"started": "2023-01-02T03:04:05Z",
+"modified": "2023-01-02T03:04:06Z",
+"structures": [
+  {"volume": "foo", "name": "first", "yaml-index": 0, "state": "updated"},
+  {"volume": "foo", "name": "second", "yaml-index": 1, "state": "updating"},
+  {"volume": "foo", "name": "third", "yaml-index": 2, "state": "backed-up"}
+]}`), 0644)
+	c.Assert(err, IsNil)
+}
+
+func (u *updateTestSuite) TestUpdateJournalPath(c *C) {
+	c.Check(gadget.UpdateJournalPath("/var/lib/snapd/rollback/pc_1/"), Equals, "/var/lib/snapd/rollback/pc_1.journal")
+}
+
+func (u *updateTestSuite) TestUpdateJournalHappy(c *C) {
+	oldData, newData, rollbackDir := u.updateDataSet(c)
+	newData.Info.Volumes["foo"].Structure[0].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[1].Update.Edition = 1
+
+	var calls []string
+	u.mockJournaledUpdaters(c, &calls, "")
+
+	j, err := gadget.ReadUpdateJournal(rollbackDir)
+	c.Assert(err, IsNil)
+	c.Check(j, IsNil)
+
+	err = gadget.Update(uc16Model, oldData, newData, rollbackDir, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, []string{"backup:first", "backup:second", "update:first", "update:second"})
+
+	j, err = gadget.ReadUpdateJournal(rollbackDir)
+	c.Assert(err, IsNil)
+	c.Check(j.Status, Equals, gadget.UpdateDone)
+	c.Check(j.Interrupted(), Equals, false)
+	c.Check(j.Attempts, Equals, 1)
+	c.Check(j.Structures, DeepEquals, []*gadget.UpdateJournalEntry{
+		{Volume: "foo", Name: "first", YamlIndex: 0, State: gadget.StructureUpdated},
+		{Volume: "foo", Name: "second", YamlIndex: 1, State: gadget.StructureUpdated},
+	})
+}
+
+func (u *updateTestSuite) TestUpdateJournalUpdateFailsThenRollback(c *C) {
+	oldData, newData, rollbackDir := u.updateDataSet(c)
+	newData.Info.Volumes["foo"].Structure[0].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[1].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[2].Update.Edition = 1
+
+	var calls []string
+	u.mockJournaledUpdaters(c, &calls, "second")
+
+	err := gadget.Update(uc16Model, oldData, newData, rollbackDir, nil, nil)
+	c.Assert(err, ErrorMatches, `cannot update volume structure #1 \("second"\) on volume foo: failed`)
+
+	j, err := gadget.ReadUpdateJournal(rollbackDir)
+	c.Assert(err, IsNil)
+	c.Check(j.Status, Equals, gadget.UpdateRolledBack)
+	c.Check(j.Structures, DeepEquals, []*gadget.UpdateJournalEntry{
+		{Volume: "foo", Name: "first", YamlIndex: 0, State: gadget.StructureRolledBack},
+		{Volume: "foo", Name: "second", YamlIndex: 1, State: gadget.StructureRolledBack},
+		{Volume: "foo", Name: "third", YamlIndex: 2, State: gadget.StructureBackedUp},
+	})
+}
+
+func (u *updateTestSuite) TestUpdateJournalResumesInterrupted(c *C) {
+	oldData, newData, rollbackDir := u.updateDataSet(c)
+	newData.Info.Volumes["foo"].Structure[0].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[1].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[2].Update.Edition = 1
+
+	u.writeInterruptedJournal(c, rollbackDir)
+	j, err := gadget.ReadUpdateJournal(rollbackDir)
+	c.Assert(err, IsNil)
+	c.Check(j.Interrupted(), Equals, true)
+
+	var calls []string
+	u.mockJournaledUpdaters(c, &calls, "")
+
+	err = gadget.Update(uc16Model, oldData, newData, rollbackDir, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, []string{
+		"backup:first", "backup:second", "backup:third",
+		"update:first", "update:second", "update:third",
+	})
+
+	j, err = gadget.ReadUpdateJournal(rollbackDir)
+	c.Assert(err, IsNil)
+	c.Check(j.Status, Equals, gadget.UpdateDone)
+	c.Check(j.Attempts, Equals, 2)
+	c.Check(j.Started.Equal(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)), Equals, true)
+	for _, e := range j.Structures {
+		c.Check(e.State, Equals, gadget.StructureUpdated)
+	}
+}
+
+func (u *updateTestSuite) TestRollbackUpdateInterrupted(c *C) {
+	oldData, newData, rollbackDir := u.updateDataSet(c)
+	newData.Info.Volumes["foo"].Structure[0].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[1].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[2].Update.Edition = 1
+
+	u.writeInterruptedJournal(c, rollbackDir)
+
+	var calls []string
+	u.mockJournaledUpdaters(c, &calls, "")
+
+	muo := &mockUpdateProcessObserver{}
+	err := gadget.RollbackUpdate(uc16Model, oldData, newData, rollbackDir, nil, muo)
+	c.Assert(err, IsNil)
+	// only the structures that may have been written to are restored,
+	// last one first
+	c.Check(calls, DeepEquals, []string{"rollback:second", "rollback:first"})
+	c.Check(muo.canceledCalled, Equals, 1)
+
+	j, err := gadget.ReadUpdateJournal(rollbackDir)
+	c.Assert(err, IsNil)
+	c.Check(j.Status, Equals, gadget.UpdateRolledBack)
+	c.Check(j.Structures, DeepEquals, []*gadget.UpdateJournalEntry{
+		{Volume: "foo", Name: "first", YamlIndex: 0, State: gadget.StructureRolledBack},
+		{Volume: "foo", Name: "second", YamlIndex: 1, State: gadget.StructureRolledBack},
+		{Volume: "foo", Name: "third", YamlIndex: 2, State: gadget.StructureBackedUp},
+	})
+
+	// nothing more to roll back
+	err = gadget.RollbackUpdate(uc16Model, oldData, newData, rollbackDir, nil, muo)
+	c.Check(err, Equals, gadget.ErrNoUpdate)
+}
+
+func (u *updateTestSuite) TestRollbackUpdateRollbackFails(c *C) {
+	oldData, newData, rollbackDir := u.updateDataSet(c)
+	newData.Info.Volumes["foo"].Structure[0].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[1].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[2].Update.Edition = 1
+
+	u.writeInterruptedJournal(c, rollbackDir)
+
+	restore := gadget.MockUpdaterForStructure(func(loc gadget.StructureLocation, ps *gadget.LaidOutStructure, psRootDir, psRollbackDir string, observer gadget.ContentUpdateObserver) (gadget.Updater, error) {
+		return &mockUpdater{
+			rollbackCb: func() error {
+				if ps.Name() == "second" {
+					return errors.New("rollback failed")
+				}
+				return nil
+			},
+		}, nil
+	})
+	defer restore()
+
+	err := gadget.RollbackUpdate(uc16Model, oldData, newData, rollbackDir, nil, nil)
+	c.Assert(err, ErrorMatches, `cannot rollback volume structure #1 \("second"\) update on volume foo: rollback failed`)
+
+	// the update is still considered interrupted
+	j, err := gadget.ReadUpdateJournal(rollbackDir)
+	c.Assert(err, IsNil)
+	c.Check(j.Interrupted(), Equals, true)
+	c.Check(j.Structures[0].State, Equals, gadget.StructureRolledBack)
+	c.Check(j.Structures[1].State, Equals, gadget.StructureUpdating)
+}
+
+func (u *updateTestSuite) TestRollbackUpdateNoJournal(c *C) {
+	oldData, newData, rollbackDir := u.updateDataSet(c)
+
+	err := gadget.RollbackUpdate(uc16Model, oldData, newData, rollbackDir, nil, nil)
+	c.Check(err, Equals, gadget.ErrNoUpdate)
+}
+
+func (u *updateTestSuite) TestReadUpdateJournalBroken(c *C) {
+	rollbackDir := c.MkDir()
+	err := ioutil.WriteFile(gadget.UpdateJournalPath(rollbackDir), []byte(`{`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = gadget.ReadUpdateJournal(rollbackDir)
+	c.Check(err, ErrorMatches, `cannot decode gadget update journal ".*\.journal": unexpected end of JSON input`)
+}
@@ -0,0 +1,315 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gadget
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/osutil/mkfs"
+)
+
+var (
+	onDiskVolumeFromDevice = OnDiskVolumeFromDevice
+	mkfsMakeWithContent    = mkfs.MakeWithContent
+)
+
+// partitionUpdater implements support for changing the partition table of a
+// volume, creating a new partition for a structure added to the gadget, or
+// growing the partition of the last structure of the volume. The content of
+// a grown structure is then updated like for any other structure, within its
+// filesystem of the previous size. The filesystem is only grown once the
+// whole update is complete, as a mounted filesystem cannot be shrunk back
+// when rolling back.
+type partitionUpdater struct {
+	ps *LaidOutStructure
+	// oldSize is the size of a grown structure, 0 for a structure that
+	// is created
+	oldSize        quantity.Size
+	device         string
+	contentDir     string
+	backupDir      string
+	contentUpdater Updater
+}
+
+func newPartitionUpdater(loc StructureLocation, from, to *LaidOutStructure, contentDir, backupDir string, contentUpdater Updater) (*partitionUpdater, error) {
+	if backupDir == "" {
+		return nil, fmt.Errorf("internal error: backup directory cannot be unset")
+	}
+	if loc.Device == "" {
+		return nil, fmt.Errorf("internal error: structure %v has no disk device set", to)
+	}
+	pu := &partitionUpdater{
+		ps:             to,
+		device:         loc.Device,
+		contentDir:     contentDir,
+		backupDir:      backupDir,
+		contentUpdater: contentUpdater,
+	}
+	if from != nil {
+		pu.oldSize = from.VolumeStructure.Size
+	}
+	return pu, nil
+}
+
+func (p *partitionUpdater) partitionTableBackupPath() string {
+	return filepath.Join(p.backupDir, fmt.Sprintf("struct-%v.sfdisk", p.ps.YamlIndex))
+}
+
+// onDiskStructure returns the partition of the structure on the disk, if it
+// exists, along with the on disk volume.
+func (p *partitionUpdater) onDiskStructure() (*OnDiskStructure, *OnDiskVolume, error) {
+	dv, err := onDiskVolumeFromDevice(p.device)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read partitions of %s: %v", p.device, err)
+	}
+	for i := range dv.Structure {
+		if dv.Structure[i].StartOffset == p.ps.StartOffset {
+			return &dv.Structure[i], dv, nil
+		}
+	}
+	return nil, dv, nil
+}
+
+// Backup keeps a copy of the partition table as it was before the update,
+// along with the backup of the content of a grown structure.
+func (p *partitionUpdater) Backup() error {
+	backupPath := p.partitionTableBackupPath()
+	if !osutil.FileExists(backupPath) {
+		output, err := exec.Command("sfdisk", "--dump", p.device).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("cannot backup partition table: %v", osutil.OutputErr(output, err))
+		}
+		if err := osutil.AtomicWriteFile(backupPath, output, 0644, 0); err != nil {
+			return fmt.Errorf("cannot backup partition table: %v", err)
+		}
+	}
+	if p.contentUpdater != nil {
+		return p.contentUpdater.Backup()
+	}
+	return nil
+}
+
+// Update creates or grows the partition of the structure. A created
+// partition gets its filesystem and content, while the content of a grown one
+// is updated afterwards.
+func (p *partitionUpdater) Update() error {
+	ds, dv, err := p.onDiskStructure()
+	if err != nil {
+		return err
+	}
+	sectorSize := uint64(dv.SectorSize)
+	if sectorSize == 0 {
+		return fmt.Errorf("internal error: unknown sector size of %s", p.device)
+	}
+	start := uint64(p.ps.StartOffset) / sectorSize
+	size := uint64(p.ps.VolumeStructure.Size) / sectorSize
+
+	if p.oldSize == 0 {
+		if ds != nil {
+			// created already by an interrupted update, which
+			// may not have written the content
+			logger.Noticef("partition for structure %v already exists", p.ps)
+			return p.writeNewPartition()
+		}
+		if start+size > dv.UsableSectorsEnd {
+			return fmt.Errorf("cannot create partition for structure %v: not enough space on %s", p.ps, p.device)
+		}
+		input := fmt.Sprintf("start=%d, size=%d, type=%s, name=%q\n", start, size,
+			sfdiskPartitionType(dv.Schema, p.ps.Type()), p.ps.Name())
+		if err := runSfdisk(input, "--append", "--no-reread", p.device); err != nil {
+			return fmt.Errorf("cannot create partition for structure %v: %v", p.ps, err)
+		}
+		if err := reloadPartitions(p.device); err != nil {
+			return err
+		}
+		return p.writeNewPartition()
+	}
+
+	if ds == nil {
+		return fmt.Errorf("cannot find partition for structure %v on %s", p.ps, p.device)
+	}
+	grown := false
+	if ds.Size < p.ps.VolumeStructure.Size {
+		if start+size > dv.UsableSectorsEnd {
+			return fmt.Errorf("cannot grow partition for structure %v: not enough space on %s", p.ps, p.device)
+		}
+		input := fmt.Sprintf(", %d\n", size)
+		if err := runSfdisk(input, "--no-reread", "-N", fmt.Sprint(ds.DiskIndex), p.device); err != nil {
+			return fmt.Errorf("cannot grow partition for structure %v: %v", p.ps, err)
+		}
+		if err := reloadPartitions(p.device); err != nil {
+			return err
+		}
+		grown = true
+	}
+	if p.contentUpdater != nil {
+		err := p.contentUpdater.Update()
+		if err == ErrNoUpdate && grown {
+			err = nil
+		}
+		return err
+	}
+	if !grown {
+		return ErrNoUpdate
+	}
+	return nil
+}
+
+// writeNewPartition creates the filesystem of a newly created partition, or
+// writes the raw images of a structure with no filesystem.
+func (p *partitionUpdater) writeNewPartition() error {
+	ds, dv, err := p.onDiskStructure()
+	if err != nil {
+		return err
+	}
+	if ds == nil {
+		return fmt.Errorf("cannot find created partition for structure %v on %s", p.ps, p.device)
+	}
+
+	if !p.ps.HasFilesystem() {
+		if len(p.ps.LaidOutContent) == 0 {
+			return nil
+		}
+		rw, err := NewRawStructureWriter(p.contentDir, p.ps)
+		if err != nil {
+			return err
+		}
+		disk, err := os.OpenFile(p.device, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("cannot open device for writing: %v", err)
+		}
+		defer disk.Close()
+		return rw.Write(disk)
+	}
+
+	contentDir, err := ioutil.TempDir(p.backupDir, "content-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(contentDir)
+	// added structures have no role, so there are no boot assets to
+	// observe
+	fw, err := NewMountedFilesystemWriter(p.ps, nil)
+	if err != nil {
+		return err
+	}
+	if err := fw.Write(contentDir, nil); err != nil {
+		return fmt.Errorf("cannot prepare content of structure %v: %v", p.ps, err)
+	}
+	if err := mkfsMakeWithContent(p.ps.Filesystem(), ds.Node, p.ps.Label(), contentDir, p.ps.VolumeStructure.Size, dv.SectorSize); err != nil {
+		return fmt.Errorf("cannot create filesystem of structure %v: %v", p.ps, err)
+	}
+	return nil
+}
+
+// Finalize grows the filesystem of a grown structure to the size of its
+// partition.
+func (p *partitionUpdater) Finalize() error {
+	if p.oldSize == 0 || p.ps.Filesystem() != "ext4" {
+		return nil
+	}
+	ds, _, err := p.onDiskStructure()
+	if err != nil {
+		return err
+	}
+	if ds == nil {
+		return fmt.Errorf("cannot find partition for structure %v on %s", p.ps, p.device)
+	}
+	// growing a mounted filesystem is supported, and nothing is done
+	// when it is grown already
+	if output, err := exec.Command("resize2fs", ds.Node).CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot grow filesystem of structure %v: %v", p.ps, osutil.OutputErr(output, err))
+	}
+	return nil
+}
+
+// Rollback restores the content of a grown structure and then the partition
+// table from its backup, removing a created partition or shrinking a grown
+// one back to its previous size. Its filesystem is not grown yet at this
+// point.
+func (p *partitionUpdater) Rollback() error {
+	if p.contentUpdater != nil {
+		if err := p.contentUpdater.Rollback(); err != nil {
+			return err
+		}
+	}
+
+	ds, _, err := p.onDiskStructure()
+	if err != nil {
+		return err
+	}
+	if ds == nil {
+		// never created
+		return nil
+	}
+	if p.oldSize != 0 && ds.Size <= p.oldSize {
+		// not grown
+		return nil
+	}
+
+	backup, err := ioutil.ReadFile(p.partitionTableBackupPath())
+	if err != nil {
+		return fmt.Errorf("cannot restore partition table: %v", err)
+	}
+	if err := runSfdisk(string(backup), "--no-reread", p.device); err != nil {
+		return fmt.Errorf("cannot restore partition table of %s: %v", p.device, err)
+	}
+	return reloadPartitions(p.device)
+}
+
+func runSfdisk(input string, args ...string) error {
+	cmd := exec.Command("sfdisk", args...)
+	cmd.Stdin = bytes.NewBufferString(input)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return osutil.OutputErr(output, err)
+	}
+	return nil
+}
+
+// reloadPartitions makes the kernel aware of changes to the partition table
+// of the given disk, which can be mounted.
+func reloadPartitions(device string) error {
+	if output, err := exec.Command("partx", "-u", device).CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot reload partition table of %s: %v", device, osutil.OutputErr(output, err))
+	}
+	if output, err := exec.Command("udevadm", "settle", "--timeout=180").CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot wait for udev to settle after reloading partition table: %v", osutil.OutputErr(output, err))
+	}
+	return nil
+}
+
+// sfdiskPartitionType returns the partition type to use with the given
+// schema, out of a possibly hybrid <mbr>,<guid> type.
+func sfdiskPartitionType(schema, typ string) string {
+	t := strings.Split(typ, ",")
+	if len(t) == 2 && schema == schemaGPT {
+		return t[1]
+	}
+	return t[0]
+}
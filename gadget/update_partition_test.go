@@ -0,0 +1,764 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gadget_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type partitionUpdaterTestSuite struct {
+	testutil.BaseTest
+
+	dir         string
+	backup      string
+	disk        string
+	mockSfdisk  *testutil.MockCmd
+	mockPartx   *testutil.MockCmd
+	mockUdevadm *testutil.MockCmd
+	mockResize  *testutil.MockCmd
+
+	// partitions on the disk, as returned by the mocked
+	// OnDiskVolumeFromDevice
+	partitions []gadget.OnDiskStructure
+}
+
+var _ = Suite(&partitionUpdaterTestSuite{})
+
+func (s *partitionUpdaterTestSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	s.dir = c.MkDir()
+	s.backup = c.MkDir()
+	// a regular file stands in for the disk image
+	s.disk = filepath.Join(s.dir, "disk.img")
+	makeSizedFile(c, s.disk, 10*quantity.SizeMiB, nil)
+
+	s.mockSfdisk = testutil.MockCommand(c, "sfdisk", fmt.Sprintf(`
+if [ "$1" = "--dump" ]; then
+    echo "label: gpt"
+    exit 0
+fi
+cat >> %s/sfdisk.input
+`, s.dir))
+	s.AddCleanup(s.mockSfdisk.Restore)
+	s.mockPartx = testutil.MockCommand(c, "partx", "")
+	s.AddCleanup(s.mockPartx.Restore)
+	s.mockUdevadm = testutil.MockCommand(c, "udevadm", "")
+	s.AddCleanup(s.mockUdevadm.Restore)
+	s.mockResize = testutil.MockCommand(c, "resize2fs", "")
+	s.AddCleanup(s.mockResize.Restore)
+
+	s.partitions = []gadget.OnDiskStructure{{
+		Name:        "first",
+		Node:        "/dev/loop0p1",
+		DiskIndex:   1,
+		StartOffset: quantity.OffsetMiB,
+		Size:        quantity.SizeMiB,
+	}}
+	s.AddCleanup(gadget.MockOnDiskVolumeFromDevice(func(device string) (*gadget.OnDiskVolume, error) {
+		c.Check(device, Equals, s.disk)
+		return &gadget.OnDiskVolume{
+			Structure:        s.partitions,
+			Schema:           "gpt",
+			SectorSize:       512,
+			UsableSectorsEnd: uint64(10*quantity.SizeMiB/512) - 33,
+			Device:           device,
+		}, nil
+	}))
+	s.AddCleanup(gadget.MockMkfsMakeWithContent(func(typ, img, label, contentRootDir string, deviceSize, sectorSize quantity.Size) error {
+		c.Fatalf("unexpected call")
+		return nil
+	}))
+}
+
+func (s *partitionUpdaterTestSuite) addedStructure() *gadget.LaidOutStructure {
+	return &gadget.LaidOutStructure{
+		OnDiskStructure: gadget.OnDiskStructure{
+			StartOffset: 2 * quantity.OffsetMiB,
+			Size:        2 * quantity.SizeMiB,
+		},
+		VolumeStructure: &gadget.VolumeStructure{
+			VolumeName: "pc",
+			Name:       "extra",
+			Type:       "83,0FC63DAF-8483-4772-8E79-3D69D8477DE4",
+			Size:       2 * quantity.SizeMiB,
+			Filesystem: "ext4",
+			Label:      "extra",
+			Update:     gadget.VolumeUpdate{Edition: 1},
+		},
+		YamlIndex: 1,
+	}
+}
+
+func (s *partitionUpdaterTestSuite) grownStructure() (from, to *gadget.LaidOutStructure) {
+	from = &gadget.LaidOutStructure{
+		OnDiskStructure: gadget.OnDiskStructure{
+			StartOffset: quantity.OffsetMiB,
+			Size:        quantity.SizeMiB,
+		},
+		VolumeStructure: &gadget.VolumeStructure{
+			VolumeName: "pc",
+			Name:       "first",
+			Type:       "0FC63DAF-8483-4772-8E79-3D69D8477DE4",
+			Size:       quantity.SizeMiB,
+			Filesystem: "ext4",
+		},
+	}
+	to = &gadget.LaidOutStructure{
+		OnDiskStructure: gadget.OnDiskStructure{
+			StartOffset: quantity.OffsetMiB,
+			Size:        4 * quantity.SizeMiB,
+		},
+		VolumeStructure: &gadget.VolumeStructure{
+			VolumeName: "pc",
+			Name:       "first",
+			Type:       "0FC63DAF-8483-4772-8E79-3D69D8477DE4",
+			Size:       4 * quantity.SizeMiB,
+			Filesystem: "ext4",
+			Update:     gadget.VolumeUpdate{Edition: 1},
+		},
+	}
+	return from, to
+}
+
+func (s *partitionUpdaterTestSuite) TestNewPartitionUpdaterErrors(c *C) {
+	ps := s.addedStructure()
+
+	_, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, nil, ps, s.dir, "", nil)
+	c.Assert(err, ErrorMatches, "internal error: backup directory cannot be unset")
+
+	_, err = gadget.NewPartitionUpdater(gadget.StructureLocation{}, nil, ps, s.dir, s.backup, nil)
+	c.Assert(err, ErrorMatches, `internal error: structure #1 \("extra"\) has no disk device set`)
+}
+
+func (s *partitionUpdaterTestSuite) TestBackup(c *C) {
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, nil, s.addedStructure(), s.dir, s.backup, nil)
+	c.Assert(err, IsNil)
+
+	c.Assert(pu.Backup(), IsNil)
+	c.Check(filepath.Join(s.backup, "struct-1.sfdisk"), testutil.FileEquals, "label: gpt\n")
+	c.Check(s.mockSfdisk.Calls(), DeepEquals, [][]string{
+		{"sfdisk", "--dump", s.disk},
+	})
+
+	// the backup is kept across repeated attempts
+	c.Assert(pu.Backup(), IsNil)
+	c.Check(s.mockSfdisk.Calls(), HasLen, 1)
+}
+
+func (s *partitionUpdaterTestSuite) TestCreatePartitionWithFilesystem(c *C) {
+	ps := s.addedStructure()
+	contentDir := c.MkDir()
+	err := ioutil.WriteFile(filepath.Join(contentDir, "foo"), []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+	ps.VolumeStructure.Content = []gadget.VolumeContent{{UnresolvedSource: "foo", Target: "/"}}
+	ps.ResolvedContent = []gadget.ResolvedContent{{
+		VolumeContent:  &ps.VolumeStructure.Content[0],
+		ResolvedSource: filepath.Join(contentDir, "foo"),
+	}}
+
+	mkfsCalls := 0
+	s.AddCleanup(gadget.MockMkfsMakeWithContent(func(typ, img, label, contentRootDir string, deviceSize, sectorSize quantity.Size) error {
+		mkfsCalls++
+		c.Check(typ, Equals, "ext4")
+		c.Check(img, Equals, "/dev/loop0p2")
+		c.Check(label, Equals, "extra")
+		c.Check(deviceSize, Equals, 2*quantity.SizeMiB)
+		c.Check(sectorSize, Equals, quantity.Size(512))
+		c.Check(filepath.Join(contentRootDir, "foo"), testutil.FileEquals, "foo")
+		return nil
+	}))
+
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, nil, ps, contentDir, s.backup, nil)
+	c.Assert(err, IsNil)
+	c.Assert(pu.Backup(), IsNil)
+	s.mockSfdisk.ForgetCalls()
+
+	// the partition shows up once created
+	created := gadget.OnDiskStructure{
+		Name:        "extra",
+		Node:        "/dev/loop0p2",
+		DiskIndex:   2,
+		StartOffset: 2 * quantity.OffsetMiB,
+		Size:        2 * quantity.SizeMiB,
+	}
+	restore := gadget.MockOnDiskVolumeFromDevice(func(device string) (*gadget.OnDiskVolume, error) {
+		partitions := s.partitions
+		if len(s.mockSfdisk.Calls()) > 0 {
+			partitions = append(partitions, created)
+		}
+		return &gadget.OnDiskVolume{
+			Structure:        partitions,
+			Schema:           "gpt",
+			SectorSize:       512,
+			UsableSectorsEnd: uint64(10*quantity.SizeMiB/512) - 33,
+		}, nil
+	})
+	defer restore()
+
+	c.Assert(pu.Update(), IsNil)
+	c.Check(mkfsCalls, Equals, 1)
+	c.Check(s.mockSfdisk.Calls(), DeepEquals, [][]string{
+		{"sfdisk", "--append", "--no-reread", s.disk},
+	})
+	c.Check(filepath.Join(s.dir, "sfdisk.input"), testutil.FileEquals,
+		"start=4096, size=4096, type=0FC63DAF-8483-4772-8E79-3D69D8477DE4, name=\"extra\"\n")
+	c.Check(s.mockPartx.Calls(), DeepEquals, [][]string{
+		{"partx", "-u", s.disk},
+	})
+	c.Check(s.mockUdevadm.Calls(), DeepEquals, [][]string{
+		{"udevadm", "settle", "--timeout=180"},
+	})
+
+	// a repeated update of an interrupted one only writes the filesystem
+	s.mockSfdisk.ForgetCalls()
+	s.partitions = append(s.partitions, created)
+	c.Assert(pu.Update(), IsNil)
+	c.Check(mkfsCalls, Equals, 2)
+	c.Check(s.mockSfdisk.Calls(), HasLen, 0)
+}
+
+func (s *partitionUpdaterTestSuite) TestCreatePartitionRaw(c *C) {
+	ps := s.addedStructure()
+	ps.VolumeStructure.Filesystem = ""
+	ps.VolumeStructure.Label = ""
+	contentDir := c.MkDir()
+	makeSizedFile(c, filepath.Join(contentDir, "raw.img"), quantity.SizeKiB, []byte("raw content"))
+	ps.VolumeStructure.Content = []gadget.VolumeContent{{Image: "raw.img"}}
+	ps.LaidOutContent = []gadget.LaidOutContent{{
+		VolumeContent: &ps.VolumeStructure.Content[0],
+		StartOffset:   2 * quantity.OffsetMiB,
+		Size:          quantity.SizeKiB,
+	}}
+	s.partitions = append(s.partitions, gadget.OnDiskStructure{
+		Name:        "extra",
+		Node:        "/dev/loop0p2",
+		DiskIndex:   2,
+		StartOffset: 2 * quantity.OffsetMiB,
+		Size:        2 * quantity.SizeMiB,
+	})
+
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, nil, ps, contentDir, s.backup, nil)
+	c.Assert(err, IsNil)
+	c.Assert(pu.Update(), IsNil)
+
+	// the image is written at the start of the partition
+	f, err := os.Open(s.disk)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	buf := make([]byte, len("raw content"))
+	_, err = f.ReadAt(buf, int64(2*quantity.OffsetMiB))
+	c.Assert(err, IsNil)
+	c.Check(string(buf), Equals, "raw content")
+}
+
+func (s *partitionUpdaterTestSuite) TestCreatePartitionNoSpace(c *C) {
+	ps := s.addedStructure()
+	ps.StartOffset = 9 * quantity.OffsetMiB
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, nil, ps, s.dir, s.backup, nil)
+	c.Assert(err, IsNil)
+
+	err = pu.Update()
+	c.Assert(err, ErrorMatches, fmt.Sprintf(`cannot create partition for structure #1 \("extra"\): not enough space on %s`, s.disk))
+	c.Check(s.mockSfdisk.Calls(), HasLen, 0)
+}
+
+func (s *partitionUpdaterTestSuite) TestCreatePartitionSfdiskError(c *C) {
+	mockSfdisk := testutil.MockCommand(c, "sfdisk", "echo 'sfdisk failed'; exit 1")
+	defer mockSfdisk.Restore()
+
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, nil, s.addedStructure(), s.dir, s.backup, nil)
+	c.Assert(err, IsNil)
+
+	err = pu.Update()
+	c.Assert(err, ErrorMatches, `cannot create partition for structure #1 \("extra"\): sfdisk failed`)
+	c.Check(s.mockPartx.Calls(), HasLen, 0)
+}
+
+func (s *partitionUpdaterTestSuite) TestRollbackCreatedPartition(c *C) {
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, nil, s.addedStructure(), s.dir, s.backup, nil)
+	c.Assert(err, IsNil)
+	c.Assert(pu.Backup(), IsNil)
+	s.mockSfdisk.ForgetCalls()
+
+	// not created yet
+	c.Assert(pu.Rollback(), IsNil)
+	c.Check(s.mockSfdisk.Calls(), HasLen, 0)
+
+	s.partitions = append(s.partitions, gadget.OnDiskStructure{
+		Name:        "extra",
+		Node:        "/dev/loop0p2",
+		DiskIndex:   2,
+		StartOffset: 2 * quantity.OffsetMiB,
+		Size:        2 * quantity.SizeMiB,
+	})
+	c.Assert(pu.Rollback(), IsNil)
+	// the partition table is restored from the backup
+	c.Check(s.mockSfdisk.Calls(), DeepEquals, [][]string{
+		{"sfdisk", "--no-reread", s.disk},
+	})
+	c.Check(filepath.Join(s.dir, "sfdisk.input"), testutil.FileEquals, "label: gpt\n")
+	c.Check(s.mockPartx.Calls(), DeepEquals, [][]string{
+		{"partx", "-u", s.disk},
+	})
+}
+
+func (s *partitionUpdaterTestSuite) TestRollbackNoBackup(c *C) {
+	s.partitions = append(s.partitions, gadget.OnDiskStructure{
+		Name:        "extra",
+		Node:        "/dev/loop0p2",
+		DiskIndex:   2,
+		StartOffset: 2 * quantity.OffsetMiB,
+		Size:        2 * quantity.SizeMiB,
+	})
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, nil, s.addedStructure(), s.dir, s.backup, nil)
+	c.Assert(err, IsNil)
+
+	err = pu.Rollback()
+	c.Assert(err, ErrorMatches, "cannot restore partition table: open .*/struct-1.sfdisk: no such file or directory")
+	c.Check(s.mockSfdisk.Calls(), HasLen, 0)
+}
+
+func (s *partitionUpdaterTestSuite) TestGrowPartition(c *C) {
+	from, to := s.grownStructure()
+	var calls []string
+	content := &mockUpdater{
+		backupCb: func() error {
+			calls = append(calls, "backup")
+			return nil
+		},
+		updateCb: func() error {
+			calls = append(calls, "update")
+			return gadget.ErrNoUpdate
+		},
+	}
+
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, from, to, s.dir, s.backup, content)
+	c.Assert(err, IsNil)
+	c.Assert(pu.Backup(), IsNil)
+	c.Check(calls, DeepEquals, []string{"backup"})
+	s.mockSfdisk.ForgetCalls()
+
+	// no content change, but the partition was grown
+	c.Assert(pu.Update(), IsNil)
+	c.Check(calls, DeepEquals, []string{"backup", "update"})
+	c.Check(s.mockSfdisk.Calls(), DeepEquals, [][]string{
+		{"sfdisk", "--no-reread", "-N", "1", s.disk},
+	})
+	c.Check(filepath.Join(s.dir, "sfdisk.input"), testutil.FileEquals, ", 8192\n")
+	c.Check(s.mockPartx.Calls(), HasLen, 1)
+	// the filesystem is grown only once the update is complete
+	c.Check(s.mockResize.Calls(), HasLen, 0)
+
+	s.partitions[0].Size = 4 * quantity.SizeMiB
+	c.Assert(pu.Finalize(), IsNil)
+	c.Check(s.mockResize.Calls(), DeepEquals, [][]string{
+		{"resize2fs", "/dev/loop0p1"},
+	})
+}
+
+func (s *partitionUpdaterTestSuite) TestFinalizeNothingToGrow(c *C) {
+	// created partitions get a filesystem of the right size
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, nil, s.addedStructure(), s.dir, s.backup, nil)
+	c.Assert(err, IsNil)
+	c.Assert(pu.Finalize(), IsNil)
+
+	// grown partitions with no filesystem
+	from, to := s.grownStructure()
+	from.VolumeStructure.Filesystem = ""
+	to.VolumeStructure.Filesystem = ""
+	pu, err = gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, from, to, s.dir, s.backup, nil)
+	c.Assert(err, IsNil)
+	c.Assert(pu.Finalize(), IsNil)
+
+	c.Check(s.mockResize.Calls(), HasLen, 0)
+}
+
+func (s *partitionUpdaterTestSuite) TestGrowPartitionAlreadyGrown(c *C) {
+	from, to := s.grownStructure()
+	s.partitions[0].Size = 4 * quantity.SizeMiB
+
+	content := &mockUpdater{
+		updateCb: func() error { return gadget.ErrNoUpdate },
+	}
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, from, to, s.dir, s.backup, content)
+	c.Assert(err, IsNil)
+
+	c.Assert(pu.Update(), Equals, gadget.ErrNoUpdate)
+	c.Check(s.mockSfdisk.Calls(), HasLen, 0)
+	c.Check(s.mockResize.Calls(), HasLen, 0)
+}
+
+func (s *partitionUpdaterTestSuite) TestGrowPartitionNotFound(c *C) {
+	from, to := s.grownStructure()
+	s.partitions = nil
+
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, from, to, s.dir, s.backup, nil)
+	c.Assert(err, IsNil)
+
+	err = pu.Update()
+	c.Assert(err, ErrorMatches, fmt.Sprintf(`cannot find partition for structure #0 \("first"\) on %s`, s.disk))
+}
+
+func (s *partitionUpdaterTestSuite) TestGrowPartitionResizeError(c *C) {
+	from, to := s.grownStructure()
+	s.partitions[0].Size = 4 * quantity.SizeMiB
+	mockResize := testutil.MockCommand(c, "resize2fs", "echo 'resize failed'; exit 1")
+	defer mockResize.Restore()
+
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, from, to, s.dir, s.backup, &mockUpdater{})
+	c.Assert(err, IsNil)
+
+	err = pu.Finalize()
+	c.Assert(err, ErrorMatches, `cannot grow filesystem of structure #0 \("first"\): resize failed`)
+}
+
+func (s *partitionUpdaterTestSuite) TestRollbackGrownPartition(c *C) {
+	from, to := s.grownStructure()
+	s.partitions[0].Size = 4 * quantity.SizeMiB
+	var calls []string
+	content := &mockUpdater{
+		rollbackCb: func() error {
+			calls = append(calls, "rollback")
+			return nil
+		},
+	}
+
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, from, to, s.dir, s.backup, content)
+	c.Assert(err, IsNil)
+	c.Assert(pu.Backup(), IsNil)
+	s.mockSfdisk.ForgetCalls()
+
+	c.Assert(pu.Rollback(), IsNil)
+	c.Check(calls, DeepEquals, []string{"rollback"})
+	// the filesystem was not grown, only the partition table is restored
+	c.Check(s.mockResize.Calls(), HasLen, 0)
+	c.Check(s.mockSfdisk.Calls(), DeepEquals, [][]string{
+		{"sfdisk", "--no-reread", s.disk},
+	})
+	c.Check(filepath.Join(s.dir, "sfdisk.input"), testutil.FileEquals, "label: gpt\n")
+}
+
+func (s *partitionUpdaterTestSuite) TestRollbackContentError(c *C) {
+	from, to := s.grownStructure()
+	s.partitions[0].Size = 4 * quantity.SizeMiB
+	content := &mockUpdater{
+		rollbackCb: func() error { return errors.New("content rollback failed") },
+	}
+
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: s.disk}, from, to, s.dir, s.backup, content)
+	c.Assert(err, IsNil)
+
+	c.Assert(pu.Rollback(), ErrorMatches, "content rollback failed")
+	c.Check(s.mockSfdisk.Calls(), HasLen, 0)
+}
+
+// loopDeviceVolume returns the partitions of the disk attached to the given
+// loop device, as reported by sfdisk.
+func loopDeviceVolume(c *C, device string) *gadget.OnDiskVolume {
+	output, err := exec.Command("sfdisk", "--json", device).Output()
+	c.Assert(err, IsNil)
+	var dump struct {
+		PartitionTable struct {
+			LastLBA    uint64 `json:"lastlba"`
+			Partitions []struct {
+				Node  string `json:"node"`
+				Start uint64 `json:"start"`
+				Size  uint64 `json:"size"`
+			} `json:"partitions"`
+		} `json:"partitiontable"`
+	}
+	c.Assert(json.Unmarshal(output, &dump), IsNil)
+	dv := &gadget.OnDiskVolume{
+		Schema:           "gpt",
+		SectorSize:       512,
+		UsableSectorsEnd: dump.PartitionTable.LastLBA + 1,
+		Device:           device,
+	}
+	for i, p := range dump.PartitionTable.Partitions {
+		dv.Structure = append(dv.Structure, gadget.OnDiskStructure{
+			Node:        p.Node,
+			DiskIndex:   i + 1,
+			StartOffset: quantity.Offset(p.Start * 512),
+			Size:        quantity.Size(p.Size * 512),
+		})
+	}
+	return dv
+}
+
+func ext4Size(c *C, node string) quantity.Size {
+	output, err := exec.Command("dumpe2fs", "-h", node).Output()
+	c.Assert(err, IsNil)
+	var blockCount, blockSize uint64
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(fields[1])
+		switch fields[0] {
+		case "Block count":
+			blockCount, err = strconv.ParseUint(value, 10, 64)
+			c.Assert(err, IsNil)
+		case "Block size":
+			blockSize, err = strconv.ParseUint(value, 10, 64)
+			c.Assert(err, IsNil)
+		}
+	}
+	return quantity.Size(blockCount * blockSize)
+}
+
+func (s *partitionUpdaterTestSuite) TestGrowAndRollbackOnLoopDevice(c *C) {
+	if os.Geteuid() != 0 {
+		c.Skip("the test needs to be run by the root user")
+	}
+	// use the real tools, only waiting for udev is mocked
+	s.mockSfdisk.Restore()
+	for _, tool := range []string{"sfdisk", "losetup", "partx", "mkfs.ext4", "e2fsck", "resize2fs", "dumpe2fs"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			c.Skip(fmt.Sprintf("the test needs %s", tool))
+		}
+	}
+	mockUdevadm := testutil.MockCommand(c, "udevadm", "")
+	defer mockUdevadm.Restore()
+
+	cmd := exec.Command("sfdisk", s.disk)
+	cmd.Stdin = strings.NewReader("label: gpt\nstart=2048, size=2048, type=0FC63DAF-8483-4772-8E79-3D69D8477DE4, name=\"first\"\n")
+	output, err := cmd.CombinedOutput()
+	c.Assert(err, IsNil, Commentf("%s", output))
+	output, err = exec.Command("losetup", "--find", "--show", "--partscan", s.disk).CombinedOutput()
+	if err != nil {
+		c.Skip(fmt.Sprintf("cannot attach loop device: %s", output))
+	}
+	loop := strings.TrimSpace(string(output))
+	defer exec.Command("losetup", "-d", loop).Run()
+	part := loop + "p1"
+	if !osutil.FileExists(part) {
+		c.Skip("no device node for the loop device partition")
+	}
+	output, err = exec.Command("mkfs.ext4", "-q", part).CombinedOutput()
+	c.Assert(err, IsNil, Commentf("%s", output))
+	c.Assert(ext4Size(c, part), Equals, quantity.SizeMiB)
+
+	restore := gadget.MockOnDiskVolumeFromDevice(func(device string) (*gadget.OnDiskVolume, error) {
+		c.Check(device, Equals, loop)
+		return loopDeviceVolume(c, device), nil
+	})
+	defer restore()
+
+	from, to := s.grownStructure()
+	content := &mockUpdater{
+		updateCb: func() error { return gadget.ErrNoUpdate },
+	}
+	pu, err := gadget.NewPartitionUpdater(gadget.StructureLocation{Device: loop}, from, to, s.dir, s.backup, content)
+	c.Assert(err, IsNil)
+	c.Assert(pu.Backup(), IsNil)
+	before, err := exec.Command("sfdisk", "--dump", loop).Output()
+	c.Assert(err, IsNil)
+
+	c.Assert(pu.Update(), IsNil)
+	c.Check(loopDeviceVolume(c, loop).Structure[0].Size, Equals, 4*quantity.SizeMiB)
+	// the filesystem is not grown yet
+	c.Check(ext4Size(c, part), Equals, quantity.SizeMiB)
+
+	// rolling back restores the partition table
+	c.Assert(pu.Rollback(), IsNil)
+	c.Check(loopDeviceVolume(c, loop).Structure[0].Size, Equals, quantity.SizeMiB)
+	after, err := exec.Command("sfdisk", "--dump", loop).Output()
+	c.Assert(err, IsNil)
+	c.Check(string(after), Equals, string(before))
+	output, err = exec.Command("e2fsck", "-f", "-n", part).CombinedOutput()
+	c.Check(err, IsNil, Commentf("%s", output))
+
+	// once the update is complete the filesystem is grown
+	c.Assert(pu.Update(), IsNil)
+	c.Assert(pu.Finalize(), IsNil)
+	c.Check(ext4Size(c, part), Equals, 4*quantity.SizeMiB)
+}
+
+// mockPartitionUpdaters mocks updaters changing the partition table, recording
+// the calls made to them in calls.
+func (u *updateTestSuite) mockPartitionUpdaters(c *C, calls *[]string, rollbackDir string) {
+	restore := gadget.MockPartitionUpdaterForStructure(func(loc gadget.StructureLocation, from, to *gadget.LaidOutStructure, rootDir, psRollbackDir string, contentUpdater gadget.Updater) (gadget.Updater, error) {
+		c.Check(loc.Device, Equals, "/dev/foo")
+		c.Check(psRollbackDir, Equals, rollbackDir)
+		op := "create"
+		if from != nil {
+			op = "grow"
+			c.Check(from.VolumeStructure.Size < to.VolumeStructure.Size, Equals, true)
+			c.Check(contentUpdater, NotNil)
+		} else {
+			c.Check(contentUpdater, IsNil)
+		}
+		return &mockFinalizingUpdater{
+			finalizeCb: func() error {
+				*calls = append(*calls, fmt.Sprintf("%s-finalize:%s", op, to.Name()))
+				return nil
+			},
+			mockUpdater: mockUpdater{
+				backupCb: func() error {
+					*calls = append(*calls, fmt.Sprintf("%s-backup:%s", op, to.Name()))
+					if contentUpdater != nil {
+						return contentUpdater.Backup()
+					}
+					return nil
+				},
+				updateCb: func() error {
+					*calls = append(*calls, fmt.Sprintf("%s-update:%s", op, to.Name()))
+					if contentUpdater != nil {
+						return contentUpdater.Update()
+					}
+					return nil
+				},
+				rollbackCb: func() error {
+					*calls = append(*calls, fmt.Sprintf("%s-rollback:%s", op, to.Name()))
+					return nil
+				},
+			},
+		}, nil
+	})
+	u.AddCleanup(restore)
+}
+
+type mockFinalizingUpdater struct {
+	mockUpdater
+	finalizeCb func() error
+}
+
+func (m *mockFinalizingUpdater) Finalize() error {
+	return callOrNil(m.finalizeCb)
+}
+
+func (u *updateTestSuite) TestUpdateAddsStructure(c *C) {
+	oldData, newData, rollbackDir := u.updateDataSet(c)
+	newVol := newData.Info.Volumes["foo"]
+	newVol.Structure = append(newVol.Structure, gadget.VolumeStructure{
+		VolumeName: "foo",
+		Name:       "fourth",
+		Type:       "0FC63DAF-8483-4772-8E79-3D69D8477DE4",
+		Offset:     asOffsetPtr((1 + 5 + 10 + 5) * quantity.OffsetMiB),
+		Size:       5 * quantity.SizeMiB,
+		Filesystem: "ext4",
+		Update:     gadget.VolumeUpdate{Edition: 1},
+	})
+	r := gadget.MockVolumeStructureToLocationMap(func(_ gadget.GadgetData, _ gadget.Model, _ map[string]*gadget.LaidOutVolume) (map[string]map[int]gadget.StructureLocation, error) {
+		return map[string]map[int]gadget.StructureLocation{
+			"foo": {
+				0: {Device: "/dev/foo", Offset: quantity.OffsetMiB},
+				1: {RootMountPoint: "/foo"},
+				2: {RootMountPoint: "/foo"},
+				3: {Device: "/dev/foo", Offset: (1 + 5 + 10 + 5) * quantity.OffsetMiB},
+			},
+		}, nil
+	})
+	defer r()
+
+	var calls []string
+	u.mockJournaledUpdaters(c, &calls, "")
+	u.mockPartitionUpdaters(c, &calls, rollbackDir)
+
+	err := gadget.Update(uc16Model, oldData, newData, rollbackDir, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, []string{"create-backup:fourth", "create-update:fourth", "create-finalize:fourth"})
+}
+
+func (u *updateTestSuite) TestUpdateGrowsLastStructure(c *C) {
+	oldData, newData, rollbackDir := u.updateDataSet(c)
+	oldData.Info.Volumes["foo"].Structure[2].Filesystem = "ext4"
+	newData.Info.Volumes["foo"].Structure = append([]gadget.VolumeStructure(nil), oldData.Info.Volumes["foo"].Structure...)
+	newData.Info.Volumes["foo"].Structure[2].Size = 10 * quantity.SizeMiB
+	newData.Info.Volumes["foo"].Structure[2].Update.Edition = 1
+	r := gadget.MockVolumeStructureToLocationMap(func(_ gadget.GadgetData, _ gadget.Model, _ map[string]*gadget.LaidOutVolume) (map[string]map[int]gadget.StructureLocation, error) {
+		return map[string]map[int]gadget.StructureLocation{
+			"foo": {
+				0: {Device: "/dev/foo", Offset: quantity.OffsetMiB},
+				1: {RootMountPoint: "/foo"},
+				2: {Device: "/dev/foo", Offset: (1 + 5 + 10) * quantity.OffsetMiB, RootMountPoint: "/foo"},
+			},
+		}, nil
+	})
+	defer r()
+
+	var calls []string
+	u.mockJournaledUpdaters(c, &calls, "")
+	u.mockPartitionUpdaters(c, &calls, rollbackDir)
+
+	err := gadget.Update(uc16Model, oldData, newData, rollbackDir, nil, nil)
+	c.Assert(err, IsNil)
+	// the filesystem is grown once all structures are updated
+	c.Check(calls, DeepEquals, []string{"grow-backup:third", "backup:third", "grow-update:third", "update:third", "grow-finalize:third"})
+}
+
+func (u *updateTestSuite) TestUpdateGrowsNonLastStructureFails(c *C) {
+	oldData, newData, rollbackDir := u.updateDataSet(c)
+	newData.Info.Volumes["foo"].Structure[1].Size = 15 * quantity.SizeMiB
+	newData.Info.Volumes["foo"].Structure[1].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[2].Offset = asOffsetPtr((1 + 5 + 15) * quantity.OffsetMiB)
+
+	err := gadget.Update(uc16Model, oldData, newData, rollbackDir, nil, nil)
+	c.Assert(err, ErrorMatches, `cannot apply update to volume foo: cannot grow structure #1 \("second"\), only the last structure of a volume can be grown`)
+}
+
+func (u *updateTestSuite) TestCurrentLayout(c *C) {
+	oldData, newData, _ := u.updateDataSet(c)
+	oldVol := oldData.Info.Volumes["foo"]
+	newVol := newData.Info.Volumes["foo"]
+	newVol.Structure = append(append([]gadget.VolumeStructure(nil), oldVol.Structure...), gadget.VolumeStructure{
+		VolumeName: "foo",
+		Name:       "fourth",
+		Offset:     asOffsetPtr(30 * quantity.OffsetMiB),
+		Size:       5 * quantity.SizeMiB,
+		Filesystem: "ext4",
+	})
+	newVol.Structure[2].Size = 10 * quantity.SizeMiB
+
+	laidOut, err := gadget.LayoutVolume(newVol, &gadget.LayoutOptions{SkipResolveContent: true, GadgetRootDir: newData.RootDir})
+	c.Assert(err, IsNil)
+	c.Assert(laidOut.LaidOutStructure, HasLen, 4)
+
+	current := gadget.CurrentLayout(laidOut, oldVol)
+	c.Assert(current.LaidOutStructure, HasLen, 3)
+	c.Check(current.Volume.Structure, HasLen, 3)
+	c.Check(current.LaidOutStructure[2].VolumeStructure.Size, Equals, 5*quantity.SizeMiB)
+	c.Check(current.LaidOutStructure[2].Size, Equals, 5*quantity.SizeMiB)
+	// the new layout is left unchanged
+	c.Check(laidOut.LaidOutStructure[2].VolumeStructure.Size, Equals, 10*quantity.SizeMiB)
+	c.Check(newVol.Structure, HasLen, 4)
+
+	// no change in the layout
+	laidOut, err = gadget.LayoutVolume(oldVol, &gadget.LayoutOptions{SkipResolveContent: true, GadgetRootDir: oldData.RootDir})
+	c.Assert(err, IsNil)
+	c.Check(gadget.CurrentLayout(laidOut, oldVol), Equals, laidOut)
+}
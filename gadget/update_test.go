@@ -164,6 +164,69 @@ func (u *updateTestSuite) TestCanUpdateSize(c *C) {
 				VolumeStructure: &gadget.VolumeStructure{Size: 1 * quantity.SizeMiB},
 			},
 			err: "",
+		}, {
+			// growing a partition with a bumped edition
+			from: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 1 * quantity.SizeMiB, Filesystem: "ext4"},
+			},
+			to: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 2 * quantity.SizeMiB, Filesystem: "ext4", Update: gadget.VolumeUpdate{Edition: 1}},
+			},
+			err: "",
+		}, {
+			// growing without bumping the edition
+			from: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 1 * quantity.SizeMiB, Update: gadget.VolumeUpdate{Edition: 1}},
+			},
+			to: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 2 * quantity.SizeMiB, Update: gadget.VolumeUpdate{Edition: 1}},
+			},
+			err: "cannot change structure size from [0-9]+ to [0-9]+",
+		}, {
+			// shrinking
+			from: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 2 * quantity.SizeMiB},
+			},
+			to: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 1 * quantity.SizeMiB, Update: gadget.VolumeUpdate{Edition: 1}},
+			},
+			err: "cannot change structure size from [0-9]+ to [0-9]+",
+		}, {
+			// growing a bare structure
+			from: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 1 * quantity.SizeMiB, Type: "bare"},
+			},
+			to: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 2 * quantity.SizeMiB, Type: "bare", Update: gadget.VolumeUpdate{Edition: 1}},
+			},
+			err: "cannot change structure size from [0-9]+ to [0-9]+",
+		}, {
+			// growing a vfat filesystem
+			from: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 1 * quantity.SizeMiB, Filesystem: "vfat"},
+			},
+			to: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 2 * quantity.SizeMiB, Filesystem: "vfat", Update: gadget.VolumeUpdate{Edition: 1}},
+			},
+			err: "cannot change structure size from [0-9]+ to [0-9]+",
+		}, {
+			// growing system-data, which may be encrypted
+			from: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 1 * quantity.SizeMiB, Filesystem: "ext4", Role: gadget.SystemData},
+			},
+			to: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 2 * quantity.SizeMiB, Filesystem: "ext4", Role: gadget.SystemData, Update: gadget.VolumeUpdate{Edition: 1}},
+			},
+			err: "cannot change structure size from [0-9]+ to [0-9]+",
+		}, {
+			// growing system-save, which may be encrypted
+			from: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 1 * quantity.SizeMiB, Filesystem: "ext4", Role: gadget.SystemSave},
+			},
+			to: gadget.LaidOutStructure{
+				VolumeStructure: &gadget.VolumeStructure{Size: 2 * quantity.SizeMiB, Filesystem: "ext4", Role: gadget.SystemSave, Update: gadget.VolumeUpdate{Edition: 1}},
+			},
+			err: "cannot change structure size from [0-9]+ to [0-9]+",
 		},
 	}
 
@@ -589,16 +652,166 @@ func (u *updateTestSuite) TestCanUpdateVolume(c *C) {
 			from: gadget.PartiallyLaidOutVolume{
 				Volume: &gadget.Volume{Schema: "mbr"},
 				LaidOutStructure: []gadget.LaidOutStructure{
-					{}, {},
+					{VolumeStructure: &gadget.VolumeStructure{}},
+					{VolumeStructure: &gadget.VolumeStructure{}},
 				},
 			},
 			to: gadget.LaidOutVolume{
 				Volume: &gadget.Volume{Schema: "mbr"},
 				LaidOutStructure: []gadget.LaidOutStructure{
-					{}, {},
+					{VolumeStructure: &gadget.VolumeStructure{}},
+					{VolumeStructure: &gadget.VolumeStructure{}},
+				},
+			},
+			err: ``,
+		}, {
+			// growing the last structure is valid
+			from: gadget.PartiallyLaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt"},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+					{VolumeStructure: &gadget.VolumeStructure{Name: "b", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: 2 * quantity.OffsetMiB}},
+				},
+			},
+			to: gadget.LaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt"},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+					{VolumeStructure: &gadget.VolumeStructure{Name: "b", Size: 2 * quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: 2 * quantity.OffsetMiB}},
+				},
+			},
+			err: ``,
+		}, {
+			from: gadget.PartiallyLaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt"},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+					{VolumeStructure: &gadget.VolumeStructure{Name: "b", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: 2 * quantity.OffsetMiB}},
+				},
+			},
+			to: gadget.LaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt"},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: 2 * quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+					{VolumeStructure: &gadget.VolumeStructure{Name: "b", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: 3 * quantity.OffsetMiB}},
+				},
+			},
+			err: `cannot grow structure #0 \("a"\), only the last structure of a volume can be grown`,
+		}, {
+			// adding a structure after the existing ones is valid
+			from: gadget.PartiallyLaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt", Structure: make([]gadget.VolumeStructure, 1)},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+				},
+			},
+			to: gadget.LaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt"},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+					{
+						VolumeStructure: &gadget.VolumeStructure{Name: "b", Size: quantity.SizeMiB, Filesystem: "ext4", Update: gadget.VolumeUpdate{Edition: 1}},
+						OnDiskStructure: gadget.OnDiskStructure{StartOffset: 2 * quantity.OffsetMiB},
+						YamlIndex:       1,
+					},
 				},
 			},
 			err: ``,
+		}, {
+			from: gadget.PartiallyLaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt", Structure: make([]gadget.VolumeStructure, 1)},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+				},
+			},
+			to: gadget.LaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt"},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+					{
+						VolumeStructure: &gadget.VolumeStructure{Name: "b", Size: quantity.SizeMiB, Filesystem: "ext4"},
+						OnDiskStructure: gadget.OnDiskStructure{StartOffset: 2 * quantity.OffsetMiB},
+						YamlIndex:       1,
+					},
+				},
+			},
+			err: `cannot add structure #1 \("b"\) without an update edition`,
+		}, {
+			from: gadget.PartiallyLaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt", Structure: make([]gadget.VolumeStructure, 1)},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+				},
+			},
+			to: gadget.LaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt"},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: 2 * quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+					{
+						VolumeStructure: &gadget.VolumeStructure{Name: "b", Size: quantity.SizeMiB, Update: gadget.VolumeUpdate{Edition: 1}},
+						OnDiskStructure: gadget.OnDiskStructure{StartOffset: 2 * quantity.OffsetMiB},
+						YamlIndex:       1,
+					},
+				},
+			},
+			err: `cannot add structure #1 \("b"\), it overlaps with existing structures`,
+		}, {
+			from: gadget.PartiallyLaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt", Structure: make([]gadget.VolumeStructure, 1)},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+				},
+			},
+			to: gadget.LaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt"},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+					{
+						VolumeStructure: &gadget.VolumeStructure{Name: "b", Size: quantity.SizeMiB, Type: "bare", Update: gadget.VolumeUpdate{Edition: 1}},
+						OnDiskStructure: gadget.OnDiskStructure{StartOffset: 2 * quantity.OffsetMiB},
+						YamlIndex:       1,
+					},
+				},
+			},
+			err: `cannot add structure #1 \("b"\), only partitions can be added`,
+		}, {
+			from: gadget.PartiallyLaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt", Structure: make([]gadget.VolumeStructure, 1)},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+				},
+			},
+			to: gadget.LaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt"},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+					{
+						VolumeStructure: &gadget.VolumeStructure{Name: "b", Size: quantity.SizeMiB, Role: gadget.SystemData, Filesystem: "ext4", Update: gadget.VolumeUpdate{Edition: 1}},
+						OnDiskStructure: gadget.OnDiskStructure{StartOffset: 2 * quantity.OffsetMiB},
+						YamlIndex:       1,
+					},
+				},
+			},
+			err: `cannot add structure #1 \("b"\) with role "system-data"`,
+		}, {
+			from: gadget.PartiallyLaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt", Structure: make([]gadget.VolumeStructure, 1)},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+				},
+			},
+			to: gadget.LaidOutVolume{
+				Volume: &gadget.Volume{Schema: "gpt"},
+				LaidOutStructure: []gadget.LaidOutStructure{
+					{VolumeStructure: &gadget.VolumeStructure{Name: "a", Size: quantity.SizeMiB}, OnDiskStructure: gadget.OnDiskStructure{StartOffset: quantity.OffsetMiB}},
+					{
+						VolumeStructure: &gadget.VolumeStructure{Name: "b", Size: quantity.SizeMiB, Filesystem: "btrfs", Update: gadget.VolumeUpdate{Edition: 1}},
+						OnDiskStructure: gadget.OnDiskStructure{StartOffset: 2 * quantity.OffsetMiB},
+						YamlIndex:       1,
+					},
+				},
+			},
+			err: `cannot add structure #1 \("b"\) with filesystem "btrfs"`,
 		},
 	} {
 		c.Logf("tc: %v", idx)
@@ -2626,7 +2839,7 @@ func (u *updateTestSuite) TestUpdateApplyErrorIllegalVolumeUpdate(c *C) {
 			"foo": {
 				Bootloader: "grub",
 				Schema:     "gpt",
-				Structure:  []gadget.VolumeStructure{bareStruct},
+				Structure:  []gadget.VolumeStructure{bareStruct, bareStructUpdate},
 			},
 		},
 	}
@@ -2635,8 +2848,8 @@ func (u *updateTestSuite) TestUpdateApplyErrorIllegalVolumeUpdate(c *C) {
 			"foo": {
 				Bootloader: "grub",
 				Schema:     "gpt",
-				// more structures than old
-				Structure: []gadget.VolumeStructure{bareStruct, bareStructUpdate},
+				// fewer structures than old
+				Structure: []gadget.VolumeStructure{bareStruct},
 			},
 		},
 	}
@@ -2653,7 +2866,7 @@ func (u *updateTestSuite) TestUpdateApplyErrorIllegalVolumeUpdate(c *C) {
 	makeSizedFile(c, filepath.Join(newRootDir, "first.img"), 900*quantity.SizeKiB, nil)
 
 	err := gadget.Update(uc16Model, oldData, newData, rollbackDir, nil, nil)
-	c.Assert(err, ErrorMatches, `cannot apply update to volume foo: cannot change the number of structures within volume from 1 to 2`)
+	c.Assert(err, ErrorMatches, `cannot apply update to volume foo: cannot change the number of structures within volume from 2 to 1`)
 }
 
 func (u *updateTestSuite) TestUpdateApplyErrorIllegalStructureUpdate(c *C) {
@@ -4669,6 +4882,33 @@ func (s *updateTestSuite) TestBuildVolumeStructureToLocationUC20Encryption(c *C)
 	c.Assert(mockLogBuf.String(), testutil.Contains, "gadget asset update for assets on encrypted partition ubuntu-save unsupported")
 }
 
+func (s *updateTestSuite) TestBuildVolumeStructureToLocationUC20GrowEncryptedFails(c *C) {
+	traits := map[string]gadget.DiskVolumeDeviceTraits{
+		"pi": gadgettest.ExpectedLUKSEncryptedRaspiDiskVolumeDeviceTraits,
+	}
+	volMappings := map[string]*disks.MockDiskMapping{
+		"pi": gadgettest.ExpectedLUKSEncryptedRaspiMockDiskMapping,
+	}
+	restore := osutil.MockMountInfo("")
+	defer restore()
+
+	old, allLaidOutVolumes := s.setupForVolumeStructureToLocation(c, uc20Model,
+		gadgettest.RaspiSimplifiedYaml,
+		traits,
+		volMappings,
+		nil,
+	)
+	// ubuntu-data is grown by the new gadget
+	oldVol := *old.Info.Volumes["pi"]
+	oldVol.Structure = append([]gadget.VolumeStructure(nil), oldVol.Structure...)
+	c.Assert(oldVol.Structure[3].Name, Equals, "ubuntu-data")
+	oldVol.Structure[3].Size -= quantity.SizeMiB
+	old.Info.Volumes["pi"] = &oldVol
+
+	_, err := gadget.BuildVolumeStructureToLocation(uc20Model, old, allLaidOutVolumes, traits, false)
+	c.Assert(err, ErrorMatches, "cannot grow encrypted structure ubuntu-data on volume pi")
+}
+
 func (s *updateTestSuite) TestBuildVolumeStructureToLocationUC20MultiVolumeNonMountedPartition(c *C) {
 	traits := map[string]gadget.DiskVolumeDeviceTraits{
 		"pc":  gadgettest.VMSystemVolumeDeviceTraits,
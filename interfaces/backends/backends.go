@@ -24,13 +24,18 @@ import (
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/dbus"
 	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/landlock"
 	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/netns"
 	"github.com/snapcore/snapd/interfaces/polkit"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/interfaces/systemd"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/logger"
 	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
+	landlock_sandbox "github.com/snapcore/snapd/sandbox/landlock"
+	selinux_sandbox "github.com/snapcore/snapd/sandbox/selinux"
 )
 
 // All returns a set of all available security backends.
@@ -45,6 +50,7 @@ func All() []interfaces.SecurityBackend {
 		&mount.Backend{},
 		&kmod.Backend{},
 		&polkit.Backend{},
+		&netns.Backend{},
 	}
 
 	// TODO use something like:
@@ -67,6 +73,20 @@ func All() []interfaces.SecurityBackend {
 	switch apparmor_sandbox.ProbedLevel() {
 	case apparmor_sandbox.Partial, apparmor_sandbox.Full:
 		all = append(all, &apparmor.Backend{})
+	default:
+		// Without AppArmor, use landlock to at least restrict filesystem
+		// access of snaps, when the kernel supports it.
+		if landlock_sandbox.ProbedLevel() == landlock_sandbox.Supported {
+			logger.Noticef("Landlock status: %s", landlock_sandbox.Summary())
+			all = append(all, &landlock.Backend{})
+		}
+	}
+
+	// Enable the SELinux backend whenever SELinux is enabled, in both
+	// permissive and enforcing mode, so that the per-snap policy modules
+	// are in place when the system switches to enforcing.
+	if selinux_sandbox.ProbedLevel() != selinux_sandbox.Unsupported {
+		all = append(all, &selinux.Backend{})
 	}
 	return all
 }
@@ -18,12 +18,15 @@
 package backends_test
 
 import (
+	"errors"
 	"testing"
 
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/interfaces/backends"
 	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
+	landlock_sandbox "github.com/snapcore/snapd/sandbox/landlock"
+	selinux_sandbox "github.com/snapcore/snapd/sandbox/selinux"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -31,10 +34,22 @@ func Test(t *testing.T) {
 	TestingT(t)
 }
 
-type backendsSuite struct{}
+type backendsSuite struct {
+	testutil.BaseTest
+}
 
 var _ = Suite(&backendsSuite{})
 
+func (s *backendsSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.AddCleanup(selinux_sandbox.MockIsEnabled(func() (bool, error) { return false, nil }))
+	s.AddCleanup(landlock_sandbox.MockVersion(0, nil))
+}
+
+func (s *backendsSuite) TearDownTest(c *C) {
+	s.BaseTest.TearDownTest(c)
+}
+
 func (s *backendsSuite) TestIsAppArmorEnabled(c *C) {
 	for _, level := range []apparmor_sandbox.LevelType{apparmor_sandbox.Unsupported, apparmor_sandbox.Unusable, apparmor_sandbox.Partial, apparmor_sandbox.Full} {
 		restore := apparmor_sandbox.MockLevel(level)
@@ -55,6 +70,65 @@ func (s *backendsSuite) TestIsAppArmorEnabled(c *C) {
 	}
 }
 
+func (s *backendsSuite) TestIsSELinuxEnabled(c *C) {
+	for _, tc := range []struct {
+		enabled, enforcing bool
+		err                error
+		expected           bool
+	}{
+		{enabled: false, expected: false},
+		{enabled: true, err: errors.New("boom"), expected: false},
+		{enabled: true, enforcing: false, expected: true},
+		{enabled: true, enforcing: true, expected: true},
+	} {
+		restore := selinux_sandbox.MockIsEnabled(func() (bool, error) { return tc.enabled, nil })
+		defer restore()
+		restore = selinux_sandbox.MockIsEnforcing(func() (bool, error) { return tc.enforcing, tc.err })
+		defer restore()
+
+		all := backends.All()
+		names := make([]string, len(all))
+		for i, backend := range all {
+			names[i] = string(backend.Name())
+		}
+		if tc.expected {
+			c.Check(names, testutil.Contains, "selinux")
+		} else {
+			c.Check(names, Not(testutil.Contains), "selinux")
+		}
+	}
+}
+
+func (s *backendsSuite) TestIsLandlockEnabled(c *C) {
+	for _, tc := range []struct {
+		apparmor        apparmor_sandbox.LevelType
+		landlockVersion int
+		expected        bool
+	}{
+		{apparmor_sandbox.Unsupported, 0, false},
+		{apparmor_sandbox.Unsupported, 1, true},
+		{apparmor_sandbox.Unusable, 2, true},
+		{apparmor_sandbox.Partial, 2, false},
+		{apparmor_sandbox.Full, 2, false},
+	} {
+		restore := apparmor_sandbox.MockLevel(tc.apparmor)
+		defer restore()
+		restore = landlock_sandbox.MockVersion(tc.landlockVersion, nil)
+		defer restore()
+
+		all := backends.All()
+		names := make([]string, len(all))
+		for i, backend := range all {
+			names[i] = string(backend.Name())
+		}
+		if tc.expected {
+			c.Check(names, testutil.Contains, "landlock", Commentf("%v", tc))
+		} else {
+			c.Check(names, Not(testutil.Contains), "landlock", Commentf("%v", tc))
+		}
+	}
+}
+
 func (s *backendsSuite) TestEssentialOrdering(c *C) {
 	restore := apparmor_sandbox.MockLevel(apparmor_sandbox.Full)
 	defer restore()
@@ -32,9 +32,12 @@ import (
 	"github.com/snapcore/snapd/interfaces/dbus"
 	"github.com/snapcore/snapd/interfaces/ifacetest"
 	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/landlock"
 	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/netns"
 	"github.com/snapcore/snapd/interfaces/polkit"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/interfaces/systemd"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
@@ -89,6 +92,19 @@ type kmodDefiner4 interface {
 	KModPermanentSlot(spec *kmod.Specification, slot *snap.SlotInfo) error
 }
 
+type landlockDefiner1 interface {
+	LandlockConnectedPlug(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+}
+type landlockDefiner2 interface {
+	LandlockConnectedSlot(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+}
+type landlockDefiner3 interface {
+	LandlockPermanentPlug(spec *landlock.Specification, plug *snap.PlugInfo) error
+}
+type landlockDefiner4 interface {
+	LandlockPermanentSlot(spec *landlock.Specification, slot *snap.SlotInfo) error
+}
+
 type mountDefiner1 interface {
 	MountConnectedPlug(spec *mount.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
 }
@@ -102,6 +118,19 @@ type mountDefiner4 interface {
 	MountPermanentSlot(spec *mount.Specification, slot *snap.SlotInfo) error
 }
 
+type netnsDefiner1 interface {
+	NetNSConnectedPlug(spec *netns.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+}
+type netnsDefiner2 interface {
+	NetNSConnectedSlot(spec *netns.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+}
+type netnsDefiner3 interface {
+	NetNSPermanentPlug(spec *netns.Specification, plug *snap.PlugInfo) error
+}
+type netnsDefiner4 interface {
+	NetNSPermanentSlot(spec *netns.Specification, slot *snap.SlotInfo) error
+}
+
 type polkitDefiner1 interface {
 	PolkitConnectedPlug(spec *polkit.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
 }
@@ -115,6 +144,19 @@ type polkitDefiner4 interface {
 	PolkitPermanentSlot(spec *polkit.Specification, slot *snap.SlotInfo) error
 }
 
+type selinuxDefiner1 interface {
+	SELinuxConnectedPlug(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+}
+type selinuxDefiner2 interface {
+	SELinuxConnectedSlot(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+}
+type selinuxDefiner3 interface {
+	SELinuxPermanentPlug(spec *selinux.Specification, plug *snap.PlugInfo) error
+}
+type selinuxDefiner4 interface {
+	SELinuxPermanentSlot(spec *selinux.Specification, slot *snap.SlotInfo) error
+}
+
 type seccompDefiner1 interface {
 	SecCompConnectedPlug(spec *seccomp.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
 }
@@ -171,11 +213,21 @@ var allGoodDefiners = []reflect.Type{
 	reflect.TypeOf((*kmodDefiner2)(nil)).Elem(),
 	reflect.TypeOf((*kmodDefiner3)(nil)).Elem(),
 	reflect.TypeOf((*kmodDefiner4)(nil)).Elem(),
+	// landlock
+	reflect.TypeOf((*landlockDefiner1)(nil)).Elem(),
+	reflect.TypeOf((*landlockDefiner2)(nil)).Elem(),
+	reflect.TypeOf((*landlockDefiner3)(nil)).Elem(),
+	reflect.TypeOf((*landlockDefiner4)(nil)).Elem(),
 	// mount
 	reflect.TypeOf((*mountDefiner1)(nil)).Elem(),
 	reflect.TypeOf((*mountDefiner2)(nil)).Elem(),
 	reflect.TypeOf((*mountDefiner3)(nil)).Elem(),
 	reflect.TypeOf((*mountDefiner4)(nil)).Elem(),
+	// netns
+	reflect.TypeOf((*netnsDefiner1)(nil)).Elem(),
+	reflect.TypeOf((*netnsDefiner2)(nil)).Elem(),
+	reflect.TypeOf((*netnsDefiner3)(nil)).Elem(),
+	reflect.TypeOf((*netnsDefiner4)(nil)).Elem(),
 	// polkit
 	reflect.TypeOf((*polkitDefiner1)(nil)).Elem(),
 	reflect.TypeOf((*polkitDefiner2)(nil)).Elem(),
@@ -186,6 +238,11 @@ var allGoodDefiners = []reflect.Type{
 	reflect.TypeOf((*seccompDefiner2)(nil)).Elem(),
 	reflect.TypeOf((*seccompDefiner3)(nil)).Elem(),
 	reflect.TypeOf((*seccompDefiner4)(nil)).Elem(),
+	// selinux
+	reflect.TypeOf((*selinuxDefiner1)(nil)).Elem(),
+	reflect.TypeOf((*selinuxDefiner2)(nil)).Elem(),
+	reflect.TypeOf((*selinuxDefiner3)(nil)).Elem(),
+	reflect.TypeOf((*selinuxDefiner4)(nil)).Elem(),
 	// systemd
 	reflect.TypeOf((*systemdDefiner1)(nil)).Elem(),
 	reflect.TypeOf((*systemdDefiner2)(nil)).Elem(),
@@ -382,7 +439,7 @@ func (s *AllSuite) TestUnexpectedSpecSignatures(c *C) {
 	var sigs []funcSig
 
 	// All the valid signatures from all the specification definers from all the backends.
-	for _, backend := range []string{"AppArmor", "SecComp", "UDev", "DBus", "Systemd", "KMod", "Polkit"} {
+	for _, backend := range []string{"AppArmor", "SecComp", "UDev", "DBus", "Systemd", "KMod", "Polkit", "SELinux", "Landlock", "NetNS"} {
 		backendLower := strings.ToLower(backend)
 		sigs = append(sigs, []funcSig{{
 			name: fmt.Sprintf("%sPermanentPlug", backend),
@@ -26,8 +26,10 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/landlock"
 	"github.com/snapcore/snapd/interfaces/mount"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
@@ -56,6 +58,8 @@ type commonInterface struct {
 
 	connectedPlugAppArmor  string
 	connectedPlugSecComp   string
+	connectedPlugSELinux   string
+	connectedPlugLandlock  string
 	connectedPlugUDev      []string
 	rejectAutoConnectPairs bool
 
@@ -185,6 +189,20 @@ func (iface *commonInterface) SecCompConnectedPlug(spec *seccomp.Specification,
 	return nil
 }
 
+func (iface *commonInterface) SELinuxConnectedPlug(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if iface.connectedPlugSELinux != "" {
+		spec.AddSnippet(iface.connectedPlugSELinux)
+	}
+	return nil
+}
+
+func (iface *commonInterface) LandlockConnectedPlug(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if iface.connectedPlugLandlock != "" {
+		spec.AddSnippet(iface.connectedPlugLandlock)
+	}
+	return nil
+}
+
 func (iface *commonInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
 	// don't tag devices if the interface controls it's own device cgroup
 	if iface.controlsDeviceCgroup {
@@ -27,6 +27,7 @@ import (
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/landlock"
 	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
 	"github.com/snapcore/snapd/snap"
 )
@@ -165,3 +166,25 @@ func (iface *commonFilesInterface) AppArmorConnectedPlug(spec *apparmor.Specific
 
 	return nil
 }
+
+func (iface *commonFilesInterface) LandlockConnectedPlug(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	var reads, writes []interface{}
+	_ = plug.Attr("read", &reads)
+	_ = plug.Attr("write", &writes)
+
+	var buf bytes.Buffer
+	for _, paths := range []struct {
+		access string
+		paths  []interface{}
+	}{{"r", reads}, {"rw", writes}} {
+		for _, rawPath := range paths.paths {
+			p, ok := rawPath.(string)
+			if !ok {
+				return fmt.Errorf("cannot connect plug %s: %[2]v (%[2]T) is not a string", plug.Name(), rawPath)
+			}
+			fmt.Fprintf(&buf, "%s %s\n", paths.access, filepath.Clean(p))
+		}
+	}
+	spec.AddSnippet(buf.String())
+	return nil
+}
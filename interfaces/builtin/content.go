@@ -27,6 +27,7 @@ import (
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/landlock"
 	"github.com/snapcore/snapd/interfaces/mount"
 	"github.com/snapcore/snapd/osutil"
 	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
@@ -300,6 +301,22 @@ func (iface *contentInterface) AppArmorConnectedSlot(spec *apparmor.Specificatio
 	return nil
 }
 
+func (iface *contentInterface) LandlockConnectedPlug(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	// Grant access to the content mounted in the plug snap, the
+	// mount points can be outside of the writable areas of the snap.
+	var contentSnippet bytes.Buffer
+	for _, w := range iface.path(slot, "write") {
+		_, target := sourceTarget(plug, slot, w)
+		fmt.Fprintf(&contentSnippet, "rwx %s\n", target)
+	}
+	for _, r := range iface.path(slot, "read") {
+		_, target := sourceTarget(plug, slot, r)
+		fmt.Fprintf(&contentSnippet, "rx %s\n", target)
+	}
+	spec.AddSnippet(contentSnippet.String())
+	return nil
+}
+
 func (iface *contentInterface) AutoConnect(plug *snap.PlugInfo, slot *snap.SlotInfo) bool {
 	// allow what declarations allowed
 	return true
@@ -29,6 +29,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/landlock"
 	"github.com/snapcore/snapd/interfaces/mount"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
@@ -547,6 +548,40 @@ slots:
 	c.Assert(strings.Join(updateNS[:], ""), Equals, profile0)
 }
 
+func (s *ContentSuite) TestConnectedPlugLandlock(c *C) {
+	const consumerYaml = `name: consumer
+version: 0
+plugs:
+ content:
+  target: $SNAP/import
+apps:
+ app:
+  command: foo
+`
+	consumerInfo := snaptest.MockInfo(c, consumerYaml, &snap.SideInfo{Revision: snap.R(7)})
+	plug := interfaces.NewConnectedPlug(consumerInfo.Plugs["content"], nil, nil)
+	const producerYaml = `name: producer
+version: 0
+slots:
+ content:
+  source:
+   read:
+    - $SNAP/lib
+   write:
+    - $SNAP_DATA/export
+`
+	producerInfo := snaptest.MockInfo(c, producerYaml, &snap.SideInfo{Revision: snap.R(5)})
+	slot := interfaces.NewConnectedSlot(producerInfo.Slots["content"], nil, nil)
+
+	spec := &landlock.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, plug, slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Check(spec.SnippetForTag("snap.consumer.app"), Equals, `rwx /snap/consumer/7/import/export
+rx /snap/consumer/7/import/lib
+
+`)
+}
+
 // Check that sharing of writable common data is possible
 func (s *ContentSuite) TestConnectedPlugSnippetSharingSnapCommon(c *C) {
 	const consumerYaml = `name: consumer
@@ -39,8 +39,17 @@ var (
 	SlotAppLabelExpr            = slotAppLabelExpr
 	AareExclusivePatterns       = aareExclusivePatterns
 	GetDesktopFileRules         = getDesktopFileRules
+	SeccompRules                = seccompRules
+	SeccompSyscallNames         = seccompSyscallNames
 )
 
+func StaticConnectedPlugSecComp(iface interfaces.Interface) string {
+	if withRules, ok := iface.(interface{ staticConnectedPlugSecComp() string }); ok {
+		return withRules.staticConnectedPlugSecComp()
+	}
+	return ""
+}
+
 func MprisGetName(iface interfaces.Interface, attribs map[string]interface{}) (string, error) {
 	return iface.(*mprisInterface).getName(attribs)
 }
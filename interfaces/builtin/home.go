@@ -24,6 +24,7 @@ import (
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/landlock"
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -93,6 +94,17 @@ capability dac_read_search,
 @{HOME}/{s,sn,sna}{,/} r,
 `
 
+const homeConnectedPlugLandlock = `
+# Description: Can access the user's $HOME. Landlock cannot tell hidden files
+# apart so, unlike with AppArmor, they are accessible as well.
+rw $HOME
+`
+
+const homeConnectedPlugLandlockWithAllRead = `
+# Allow read access to the home directories of all users
+r /home
+`
+
 type homeInterface struct {
 	commonInterface
 }
@@ -121,6 +133,25 @@ func (iface *homeInterface) AppArmorConnectedPlug(spec *apparmor.Specification,
 	return nil
 }
 
+const homeConnectedPlugSELinux = `
+; Description: Can access non-hidden files in user's $HOME. SELinux does not
+; distinguish hidden files, those are protected by AppArmor where available.
+(allow ###DOMAIN### user_home_dir_t (dir (getattr search open read)))
+(allow ###DOMAIN### user_home_t (dir (getattr search open read write add_name remove_name create rmdir rename setattr)))
+(allow ###DOMAIN### user_home_t (file (getattr open read write append create unlink rename setattr lock ioctl map)))
+(allow ###DOMAIN### user_home_t (lnk_file (getattr read)))
+`
+
+func (iface *homeInterface) LandlockConnectedPlug(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	var read string
+	_ = plug.Attr("read", &read)
+	spec.AddSnippet(homeConnectedPlugLandlock)
+	if read == "all" {
+		spec.AddSnippet(homeConnectedPlugLandlockWithAllRead)
+	}
+	return nil
+}
+
 func init() {
 	registerIface(&homeInterface{commonInterface{
 		name:                 "home",
@@ -128,5 +159,6 @@ func init() {
 		implicitOnCore:       true,
 		implicitOnClassic:    true,
 		baseDeclarationSlots: homeBaseDeclarationSlots,
+		connectedPlugSELinux: homeConnectedPlugSELinux,
 	}})
 }
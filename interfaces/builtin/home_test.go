@@ -25,6 +25,8 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/landlock"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
@@ -149,6 +151,45 @@ func (s *HomeInterfaceSuite) TestConnectedPlugAppArmorWithoutAttrib(c *C) {
 	c.Check(apparmorSpec.SnippetForTag("snap.other.app"), Not(testutil.Contains), `# Allow non-owner read`)
 }
 
+func (s *HomeInterfaceSuite) TestConnectedPlugLandlock(c *C) {
+	landlockSpec := &landlock.Specification{}
+	err := landlockSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(landlockSpec.SecurityTags(), DeepEquals, []string{"snap.other.app"})
+	c.Check(landlockSpec.SnippetForTag("snap.other.app"), testutil.Contains, "\nrw $HOME\n")
+	c.Check(landlockSpec.SnippetForTag("snap.other.app"), Not(testutil.Contains), "r /home\n")
+}
+
+func (s *HomeInterfaceSuite) TestConnectedPlugLandlockWithAttribAll(c *C) {
+	const mockSnapYaml = `name: home-plug-snap
+version: 1.0
+plugs:
+ home:
+  read: all
+apps:
+ app2:
+  command: foo
+  plugs: [home]
+`
+	info := snaptest.MockInfo(c, mockSnapYaml, nil)
+	plug := interfaces.NewConnectedPlug(info.Plugs["home"], nil, nil)
+	landlockSpec := &landlock.Specification{}
+	err := landlockSpec.AddConnectedPlug(s.iface, plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(landlockSpec.SecurityTags(), DeepEquals, []string{"snap.home-plug-snap.app2"})
+	c.Check(landlockSpec.SnippetForTag("snap.home-plug-snap.app2"), testutil.Contains, "\nrw $HOME\n")
+	c.Check(landlockSpec.SnippetForTag("snap.home-plug-snap.app2"), testutil.Contains, "\nr /home\n")
+}
+
+func (s *HomeInterfaceSuite) TestConnectedPlugSELinux(c *C) {
+	selinuxSpec := &selinux.Specification{}
+	err := selinuxSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(selinuxSpec.SecurityTags(), DeepEquals, []string{"snap.other.app"})
+	c.Check(selinuxSpec.SnippetForTag("snap.other.app"), testutil.Contains, "(allow ###DOMAIN### user_home_dir_t (dir (getattr search open read)))\n")
+	c.Check(selinuxSpec.SnippetForTag("snap.other.app"), testutil.Contains, "(allow ###DOMAIN### user_home_t (file (")
+}
+
 func (s *HomeInterfaceSuite) TestConnectedPlugAppArmorWithAttribAll(c *C) {
 	const mockSnapYaml = `name: home-plug-snap
 version: 1.0
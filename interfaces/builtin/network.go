@@ -90,6 +90,17 @@ socket AF_NETLINK - NETLINK_ROUTE
 socket AF_CONN
 `
 
+const networkConnectedPlugSELinux = `
+; Description: Can access the network as a client.
+(allow ###DOMAIN### self (tcp_socket (create connect read write getattr setattr getopt setopt shutdown)))
+(allow ###DOMAIN### self (udp_socket (create connect read write getattr setattr getopt setopt shutdown)))
+(allow ###DOMAIN### self (netlink_route_socket (create bind read write getattr nlmsg_read)))
+(allow ###DOMAIN### port_type (tcp_socket (name_connect)))
+(allow ###DOMAIN### node_t (udp_socket (node_bind)))
+; name resolution
+(allow ###DOMAIN### net_conf_t (file (getattr open read)))
+`
+
 func init() {
 	registerIface(&commonInterface{
 		name:                  "network",
@@ -99,5 +110,6 @@ func init() {
 		baseDeclarationSlots:  networkBaseDeclarationSlots,
 		connectedPlugAppArmor: networkConnectedPlugAppArmor,
 		connectedPlugSecComp:  networkConnectedPlugSecComp,
+		connectedPlugSELinux:  networkConnectedPlugSELinux,
 	})
 }
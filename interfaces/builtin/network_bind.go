@@ -95,6 +95,16 @@ listen
 socket AF_NETLINK - NETLINK_ROUTE
 `
 
+const networkBindConnectedPlugSELinux = `
+; Description: Can access the network as a server.
+(allow ###DOMAIN### self (tcp_socket (create bind listen accept read write getattr setattr getopt setopt shutdown)))
+(allow ###DOMAIN### self (udp_socket (create bind read write getattr setattr getopt setopt shutdown)))
+(allow ###DOMAIN### port_type (tcp_socket (name_bind)))
+(allow ###DOMAIN### port_type (udp_socket (name_bind)))
+(allow ###DOMAIN### node_t (tcp_socket (node_bind)))
+(allow ###DOMAIN### node_t (udp_socket (node_bind)))
+`
+
 func init() {
 	registerIface(&commonInterface{
 		name:                  "network-bind",
@@ -104,5 +114,6 @@ func init() {
 		baseDeclarationSlots:  networkBindBaseDeclarationSlots,
 		connectedPlugAppArmor: networkBindConnectedPlugAppArmor,
 		connectedPlugSecComp:  networkBindConnectedPlugSecComp,
+		connectedPlugSELinux:  networkBindConnectedPlugSELinux,
 	})
 }
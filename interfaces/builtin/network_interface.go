@@ -0,0 +1,193 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/snap"
+)
+
+const networkInterfaceSummary = `allows configuration of a specific network interface`
+
+// network-interface grants access to the configuration of a particular
+// network interface. The interface is device-specific, so as with raw-volume
+// connecting it requires a snap declaration.
+const networkInterfaceBaseDeclarationSlots = `
+  network-interface:
+    allow-installation:
+      slot-snap-type:
+        - core
+        - gadget
+    deny-auto-connection: true
+`
+
+const networkInterfaceConnectedPlugAppArmor = `
+# Description: can observe and configure the network interface %[1]s through
+# sysfs and the per-interface kernel parameters.
+/sys/class/net/ r,
+/sys/class/net/%[1]s r,
+/sys/devices/**/net/%[1]s/ r,
+/sys/devices/**/net/%[1]s/** rw,
+@{PROC}/sys/net/ipv{4,6}/conf/%[1]s/* rw,
+@{PROC}/sys/net/ipv{4,6}/neigh/%[1]s/* rw,
+
+# Writing the sysfs and kernel parameters of the interface and configuring it
+# over rtnetlink, e.g. its addresses and routes, requires CAP_NET_ADMIN. The
+# capability is not specific to the interface, which is why connecting this
+# interface requires a snap declaration.
+capability net_admin,
+network netlink raw,
+`
+
+const networkInterfaceConnectedPlugSecComp = `
+# Description: can configure the network interface over rtnetlink.
+bind
+socket AF_NETLINK - NETLINK_ROUTE
+`
+
+type networkInterfaceInterface struct{}
+
+func (iface *networkInterfaceInterface) Name() string {
+	return "network-interface"
+}
+
+func (iface *networkInterfaceInterface) StaticInfo() interfaces.StaticInfo {
+	return interfaces.StaticInfo{
+		Summary:              networkInterfaceSummary,
+		BaseDeclarationSlots: networkInterfaceBaseDeclarationSlots,
+	}
+}
+
+func (iface *networkInterfaceInterface) String() string {
+	return iface.Name()
+}
+
+// Kernel network interface names are at most 15 characters long and cannot
+// contain slashes or whitespace. Be more restrictive than the kernel so that
+// the name is safe to use in AppArmor rules.
+var networkInterfaceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-][a-zA-Z0-9_.:-]{0,14}$`)
+
+// Mac address as found in the predictable network interface names based on
+// the mac address, e.g. enx001122334455.
+var networkInterfaceMacNamePattern = regexp.MustCompile(`^enx([0-9a-f]{12})$`)
+
+func (iface *networkInterfaceInterface) interfaceName(attrs interfaces.Attrer) (string, error) {
+	var name string
+	if err := attrs.Attr("ifname", &name); err != nil || name == "" {
+		return "", fmt.Errorf("%s slot must have an ifname attribute", iface.Name())
+	}
+	if !networkInterfaceNamePattern.MatchString(name) {
+		return "", fmt.Errorf("%s ifname attribute must be a valid network interface name", iface.Name())
+	}
+	return name, nil
+}
+
+func (iface *networkInterfaceInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	_, err := iface.interfaceName(slot)
+	return err
+}
+
+func (iface *networkInterfaceInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	name, err := iface.interfaceName(slot)
+	if err != nil {
+		return nil
+	}
+	spec.AddSnippet(fmt.Sprintf(networkInterfaceConnectedPlugAppArmor, name))
+	return nil
+}
+
+func (iface *networkInterfaceInterface) SecCompConnectedPlug(spec *seccomp.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	spec.AddSnippet(networkInterfaceConnectedPlugSecComp)
+	return nil
+}
+
+// HotplugDeviceDetected proposes a slot for physical network interfaces as
+// they appear. Virtual interfaces, like bridges or veth pairs, are ignored.
+func (iface *networkInterfaceInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	devPath, _ := di.Attribute("DEVPATH")
+	if di.Subsystem() != "net" || strings.HasPrefix(devPath, "/devices/virtual/") {
+		return nil, nil
+	}
+	name, _ := di.Attribute("INTERFACE")
+	if !networkInterfaceNamePattern.MatchString(name) {
+		return nil, nil
+	}
+	slot := &hotplug.ProposedSlot{
+		Attrs: map[string]interface{}{
+			"ifname": name,
+		},
+	}
+	if snap.ValidateSlotName(name) == nil {
+		slot.Name = name
+	}
+	if macName, ok := di.Attribute("ID_NET_NAME_MAC"); ok {
+		if m := networkInterfaceMacNamePattern.FindStringSubmatch(macName); m != nil {
+			var octets []string
+			for i := 0; i < len(m[1]); i += 2 {
+				octets = append(octets, m[1][i:i+2])
+			}
+			slot.Attrs["mac-address"] = strings.Join(octets, ":")
+		}
+	}
+	return slot, nil
+}
+
+// HotplugKey computes a key from the mac address of the network interface,
+// if known, so that the slot follows the network card across ports and
+// renames.
+func (iface *networkInterfaceInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	macName, ok := di.Attribute("ID_NET_NAME_MAC")
+	if !ok || macName == "" {
+		// fallback to the default key
+		return "", nil
+	}
+	key := sha256.New()
+	key.Write([]byte("ID_NET_NAME_MAC"))
+	key.Write([]byte{0})
+	key.Write([]byte(macName))
+	key.Write([]byte{0})
+	return snap.HotplugKey(fmt.Sprintf("0%x", key.Sum(nil))), nil
+}
+
+func (iface *networkInterfaceInterface) HandledByGadget(di *hotplug.HotplugDeviceInfo, slot *snap.SlotInfo) bool {
+	var name string
+	if err := slot.Attr("ifname", &name); err != nil {
+		return false
+	}
+	devName, _ := di.Attribute("INTERFACE")
+	return devName == name
+}
+
+func (iface *networkInterfaceInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bool {
+	// Allow what is allowed in the declarations
+	return true
+}
+
+func init() {
+	registerIface(&networkInterfaceInterface{})
+}
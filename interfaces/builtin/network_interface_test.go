@@ -0,0 +1,212 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type networkInterfaceInterfaceSuite struct {
+	testutil.BaseTest
+	iface interfaces.Interface
+
+	slotInfo    *snap.SlotInfo
+	slot        *interfaces.ConnectedSlot
+	badSlotInfo *snap.SlotInfo
+	plugInfo    *snap.PlugInfo
+	plug        *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&networkInterfaceInterfaceSuite{
+	iface: builtin.MustInterface("network-interface"),
+})
+
+func (s *networkInterfaceInterfaceSuite) SetUpTest(c *C) {
+	gadgetSnapInfo := snaptest.MockInfo(c, `
+name: some-device
+version: 0
+type: gadget
+slots:
+  eth0:
+    interface: network-interface
+    ifname: eth0
+  bad:
+    interface: network-interface
+`, nil)
+	s.slotInfo = gadgetSnapInfo.Slots["eth0"]
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+	s.badSlotInfo = gadgetSnapInfo.Slots["bad"]
+
+	consumingSnapInfo := snaptest.MockInfo(c, `
+name: client-snap
+version: 0
+plugs:
+  network-interface:
+apps:
+  app:
+    command: foo
+    plugs: [network-interface]
+`, nil)
+	s.plugInfo = consumingSnapInfo.Plugs["network-interface"]
+	s.plug = interfaces.NewConnectedPlug(s.plugInfo, nil, nil)
+}
+
+func (s *networkInterfaceInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "network-interface")
+}
+
+func (s *networkInterfaceInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.badSlotInfo), ErrorMatches,
+		`network-interface slot must have an ifname attribute`)
+
+	for _, name := range []string{"", "eth0/..", "a-very-long-interface-name", "eth 0", ".."} {
+		slot := &snap.SlotInfo{
+			Snap:      s.slotInfo.Snap,
+			Name:      "bad",
+			Interface: "network-interface",
+			Attrs:     map[string]interface{}{"ifname": name},
+		}
+		c.Check(interfaces.BeforePrepareSlot(s.iface, slot), NotNil, Commentf("%q", name))
+	}
+}
+
+func (s *networkInterfaceInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.client-snap.app"})
+	c.Check(spec.SnippetForTag("snap.client-snap.app"), testutil.Contains, `/sys/devices/**/net/eth0/** rw,`)
+	c.Check(spec.SnippetForTag("snap.client-snap.app"), testutil.Contains, `@{PROC}/sys/net/ipv{4,6}/conf/eth0/* rw,`)
+	// configuring the interface requires CAP_NET_ADMIN and rtnetlink
+	c.Check(spec.SnippetForTag("snap.client-snap.app"), testutil.Contains, "capability net_admin,\n")
+	c.Check(spec.SnippetForTag("snap.client-snap.app"), testutil.Contains, "network netlink raw,\n")
+}
+
+func (s *networkInterfaceInterfaceSuite) TestSecCompSpec(c *C) {
+	spec := &seccomp.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.client-snap.app"})
+	c.Check(spec.SnippetForTag("snap.client-snap.app"), testutil.Contains, "socket AF_NETLINK - NETLINK_ROUTE\n")
+	c.Check(spec.SnippetForTag("snap.client-snap.app"), testutil.Contains, "bind\n")
+}
+
+func (s *networkInterfaceInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-1/1-1:1.0/net/enx001122aabbcc", "INTERFACE": "enx001122aabbcc", "ID_NET_NAME_MAC": "enx001122aabbcc", "ACTION": "add", "SUBSYSTEM": "net"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{
+		Name: "enx001122aabbcc",
+		Attrs: map[string]interface{}{
+			"ifname":      "enx001122aabbcc",
+			"mac-address": "00:11:22:aa:bb:cc",
+		},
+	})
+
+	// interface names which are not valid slot names are left to be
+	// derived by the hotplug subsystem
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:1f.6/net/wlan_1", "INTERFACE": "wlan_1", "SUBSYSTEM": "net"})
+	c.Assert(err, IsNil)
+	proposedSlot, err = hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{
+		Attrs: map[string]interface{}{"ifname": "wlan_1"},
+	})
+}
+
+func (s *networkInterfaceInterfaceSuite) TestHotplugDeviceDetectedIgnored(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	for _, env := range []map[string]string{
+		// virtual interface
+		{"DEVPATH": "/devices/virtual/net/veth0", "INTERFACE": "veth0", "SUBSYSTEM": "net"},
+		// not a network interface
+		{"DEVPATH": "/devices/usb1/1-1/tty/ttyUSB0", "DEVNAME": "/dev/ttyUSB0", "SUBSYSTEM": "tty"},
+		// no interface name
+		{"DEVPATH": "/devices/pci0000:00/0000:00:1f.6/net/eth0", "SUBSYSTEM": "net"},
+	} {
+		di, err := hotplug.NewHotplugDeviceInfo(env)
+		c.Assert(err, IsNil)
+		proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+		c.Assert(err, IsNil)
+		c.Check(proposedSlot, IsNil, Commentf("%v", env))
+	}
+}
+
+func (s *networkInterfaceInterfaceSuite) TestHotplugKey(c *C) {
+	keyHandler := s.iface.(hotplug.HotplugKeyHandler)
+
+	env := map[string]string{"DEVPATH": "/devices/usb1/1-1/1-1:1.0/net/eth1", "INTERFACE": "eth1", "ID_NET_NAME_MAC": "enx001122aabbcc", "SUBSYSTEM": "net"}
+	di, err := hotplug.NewHotplugDeviceInfo(env)
+	c.Assert(err, IsNil)
+	key1, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key1, HasLen, 65)
+
+	// the same card in another port, renamed
+	env["DEVPATH"] = "/devices/usb2/2-1/2-1:1.0/net/eth2"
+	env["INTERFACE"] = "eth2"
+	di, err = hotplug.NewHotplugDeviceInfo(env)
+	c.Assert(err, IsNil)
+	key, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key, Equals, key1)
+
+	// without a mac address the default key is used
+	delete(env, "ID_NET_NAME_MAC")
+	di, err = hotplug.NewHotplugDeviceInfo(env)
+	c.Assert(err, IsNil)
+	key, err = keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key, Equals, snap.HotplugKey(""))
+}
+
+func (s *networkInterfaceInterfaceSuite) TestHotplugHandledByGadget(c *C) {
+	byGadgetPred := s.iface.(hotplug.HandledByGadgetPredicate)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:1f.6/net/eth0", "INTERFACE": "eth0", "SUBSYSTEM": "net"})
+	c.Assert(err, IsNil)
+	c.Check(byGadgetPred.HandledByGadget(di, s.slotInfo), Equals, true)
+	c.Check(byGadgetPred.HandledByGadget(di, s.badSlotInfo), Equals, false)
+}
+
+func (s *networkInterfaceInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, false)
+	c.Assert(si.ImplicitOnClassic, Equals, false)
+	c.Assert(si.Summary, Equals, `allows configuration of a specific network interface`)
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "network-interface")
+}
+
+func (s *networkInterfaceInterfaceSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(nil, nil), Equals, true)
+}
+
+func (s *networkInterfaceInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/netns"
+	"github.com/snapcore/snapd/snap"
+)
+
+const networkIsolationSummary = `allows isolating the snap from the network of the host`
+
+const networkIsolationBaseDeclarationSlots = `
+  network-isolation:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection:
+      plug-attributes:
+        mode: allowlist
+`
+
+// networkIsolationInterface places the snap in its own network namespace.
+//
+// By default the namespace only contains the loopback device. With
+// "mode: allowlist" the namespace is linked with the host and the snap may
+// connect to the destinations listed in the "allow" attribute, e.g.:
+//
+//	plugs:
+//	  isolated:
+//	    interface: network-isolation
+//	    mode: allowlist
+//	    allow:
+//	      - destination: 10.0.0.5/32
+//	        protocol: tcp
+//	        port: 1883
+//
+// The allowed destinations are chosen by the snap itself, so plugs in
+// allowlist mode are only auto-connected when the snap declaration grants
+// it, possibly constraining the "allow" attribute, otherwise the
+// connection must be made by the administrator.
+//
+// Creating sockets still requires the network interface to be connected.
+type networkIsolationInterface struct {
+	commonInterface
+}
+
+func (iface *networkIsolationInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	_, _, err := networkIsolationConfig(plug)
+	return err
+}
+
+func (iface *networkIsolationInterface) NetNSConnectedPlug(spec *netns.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	mode, destinations, err := networkIsolationConfig(plug)
+	if err != nil {
+		return err
+	}
+	spec.Isolate(mode)
+	for _, d := range destinations {
+		spec.AllowDestination(d)
+	}
+	return nil
+}
+
+// networkIsolationConfig returns the network namespace mode and the allowed
+// destinations requested by the given plug.
+func networkIsolationConfig(plug interfaces.Attrer) (netns.Mode, []netns.Destination, error) {
+	mode := netns.ModeLoopback
+	var modeAttr string
+	if err := plug.Attr("mode", &modeAttr); err == nil {
+		switch modeAttr {
+		case "loopback":
+		case "allowlist":
+			mode = netns.ModeAllowlist
+		default:
+			return mode, nil, fmt.Errorf(`network-isolation "mode" attribute must be "loopback" or "allowlist"`)
+		}
+	} else if !errors.Is(err, snap.AttributeNotFoundError{}) {
+		return mode, nil, fmt.Errorf(`network-isolation "mode" attribute must be a string`)
+	}
+
+	var allow []map[string]interface{}
+	if err := plug.Attr("allow", &allow); err != nil {
+		if errors.Is(err, snap.AttributeNotFoundError{}) {
+			return mode, nil, nil
+		}
+		return mode, nil, fmt.Errorf(`network-isolation "allow" attribute must be a list of dictionaries`)
+	}
+	if mode != netns.ModeAllowlist {
+		return mode, nil, fmt.Errorf(`network-isolation "allow" attribute requires "mode" to be "allowlist"`)
+	}
+	destinations := make([]netns.Destination, 0, len(allow))
+	for _, entry := range allow {
+		network, ok := entry["destination"].(string)
+		if !ok {
+			return mode, nil, fmt.Errorf(`network-isolation "destination" must be a string`)
+		}
+		var protocol string
+		if value, ok := entry["protocol"]; ok {
+			if protocol, ok = value.(string); !ok {
+				return mode, nil, fmt.Errorf(`network-isolation "protocol" must be a string`)
+			}
+		}
+		var port int64
+		if value, ok := entry["port"]; ok {
+			if port, ok = value.(int64); !ok {
+				return mode, nil, fmt.Errorf(`network-isolation "port" must be an integer`)
+			}
+		}
+		d, err := netns.NewDestination(network, protocol, int(port))
+		if err != nil {
+			return mode, nil, fmt.Errorf("network-isolation %v", err)
+		}
+		destinations = append(destinations, d)
+	}
+	return mode, destinations, nil
+}
+
+func init() {
+	registerIface(&networkIsolationInterface{
+		commonInterface: commonInterface{
+			name:                 "network-isolation",
+			summary:              networkIsolationSummary,
+			implicitOnCore:       true,
+			implicitOnClassic:    true,
+			baseDeclarationSlots: networkIsolationBaseDeclarationSlots,
+		},
+	})
+}
@@ -0,0 +1,152 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"fmt"
+	"regexp"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/netns"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type NetworkIsolationInterfaceSuite struct {
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&NetworkIsolationInterfaceSuite{
+	iface: builtin.MustInterface("network-isolation"),
+})
+
+const networkIsolationConsumerYaml = `name: consumer
+version: 0
+plugs:
+ isolated:
+  interface: network-isolation
+  mode: allowlist
+  allow:
+   - destination: 10.0.0.5/32
+     protocol: tcp
+     port: 1883
+   - destination: 192.168.1.0/24
+apps:
+ app:
+  plugs: [isolated]
+`
+
+const networkIsolationCoreYaml = `name: core
+version: 0
+type: os
+slots:
+ network-isolation:
+`
+
+func (s *NetworkIsolationInterfaceSuite) SetUpTest(c *C) {
+	s.plug, s.plugInfo = MockConnectedPlug(c, networkIsolationConsumerYaml, nil, "isolated")
+	s.slot, s.slotInfo = MockConnectedSlot(c, networkIsolationCoreYaml, nil, "network-isolation")
+}
+
+func (s *NetworkIsolationInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "network-isolation")
+}
+
+func (s *NetworkIsolationInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+}
+
+func (s *NetworkIsolationInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *NetworkIsolationInterfaceSuite) TestSanitizePlugErrors(c *C) {
+	const template = `name: consumer
+version: 0
+plugs:
+ isolated:
+  interface: network-isolation
+%s
+`
+	for _, tc := range []struct {
+		attrs    string
+		expected string
+	}{
+		{"  mode: foo", `network-isolation "mode" attribute must be "loopback" or "allowlist"`},
+		{"  mode: [allowlist]", `network-isolation "mode" attribute must be a string`},
+		{"  mode: allowlist\n  allow: 10.0.0.1/32", `network-isolation "allow" attribute must be a list of dictionaries`},
+		{"  allow:\n   - destination: 10.0.0.1/32", `network-isolation "allow" attribute requires "mode" to be "allowlist"`},
+		{"  mode: allowlist\n  allow:\n   - port: 80", `network-isolation "destination" must be a string`},
+		{"  mode: allowlist\n  allow:\n   - destination: 10.0.0.1", `network-isolation invalid destination network "10.0.0.1"`},
+		{"  mode: allowlist\n  allow:\n   - destination: fd00::/8", `network-isolation invalid destination network "fd00::/8": only IPv4 is supported`},
+		{"  mode: allowlist\n  allow:\n   - destination: 10.0.0.1/32\n     protocol: icmp", `network-isolation invalid destination protocol "icmp"`},
+		{"  mode: allowlist\n  allow:\n   - destination: 10.0.0.1/32\n     protocol: [tcp]", `network-isolation "protocol" must be a string`},
+		{"  mode: allowlist\n  allow:\n   - destination: 10.0.0.1/32\n     port: http", `network-isolation "port" must be an integer`},
+		{"  mode: allowlist\n  allow:\n   - destination: 10.0.0.1/32\n     port: 65536", `network-isolation invalid destination port 65536`},
+	} {
+		plugInfo := MockPlug(c, fmt.Sprintf(template, tc.attrs), nil, "isolated")
+		c.Check(interfaces.BeforePreparePlug(s.iface, plugInfo), ErrorMatches, regexp.QuoteMeta(tc.expected), Commentf("%s", tc.attrs))
+	}
+}
+
+func (s *NetworkIsolationInterfaceSuite) TestNetNSConnectedPlug(c *C) {
+	spec := &netns.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(spec.Mode(), Equals, netns.ModeAllowlist)
+	c.Check(spec.Destinations(), DeepEquals, []netns.Destination{
+		{Network: "10.0.0.5/32", Protocol: "tcp", Port: 1883},
+		{Network: "192.168.1.0/24"},
+	})
+}
+
+func (s *NetworkIsolationInterfaceSuite) TestNetNSConnectedPlugLoopback(c *C) {
+	const yaml = `name: consumer
+version: 0
+plugs:
+ isolated:
+  interface: network-isolation
+apps:
+ app:
+  plugs: [isolated]
+`
+	plug, _ := MockConnectedPlug(c, yaml, nil, "isolated")
+	spec := &netns.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, plug, s.slot), IsNil)
+	c.Check(spec.Mode(), Equals, netns.ModeLoopback)
+	c.Check(spec.Destinations(), HasLen, 0)
+}
+
+func (s *NetworkIsolationInterfaceSuite) TestNoAppArmor(c *C) {
+	apparmorSpec := &apparmor.Specification{}
+	c.Assert(apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(apparmorSpec.SecurityTags(), HasLen, 0)
+}
+
+func (s *NetworkIsolationInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
@@ -89,6 +90,13 @@ func (s *NetworkInterfaceSuite) TestUsedSecuritySystems(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(seccompSpec.SecurityTags(), DeepEquals, []string{"snap.other.app2"})
 	c.Check(seccompSpec.SnippetForTag("snap.other.app2"), testutil.Contains, "bind\n")
+
+	// connected plugs have a non-nil security snippet for selinux
+	selinuxSpec := &selinux.Specification{}
+	err = selinuxSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(selinuxSpec.SecurityTags(), DeepEquals, []string{"snap.other.app2"})
+	c.Check(selinuxSpec.SnippetForTag("snap.other.app2"), testutil.Contains, "(allow ###DOMAIN### port_type (tcp_socket (name_connect)))\n")
 }
 
 func (s *NetworkInterfaceSuite) TestInterfaces(c *C) {
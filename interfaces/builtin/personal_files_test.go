@@ -27,6 +27,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/landlock"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
@@ -87,6 +88,19 @@ owner "@{HOME}/.write-file{,/,/**}" rwkl,
 `)
 }
 
+func (s *personalFilesInterfaceSuite) TestConnectedPlugLandlock(c *C) {
+	landlockSpec := &landlock.Specification{}
+	err := landlockSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(landlockSpec.SecurityTags(), DeepEquals, []string{"snap.other.app"})
+	c.Check(landlockSpec.SnippetForTag("snap.other.app"), Equals, `r $HOME/.read-dir
+r $HOME/.read-file
+rw $HOME/.write-dir
+rw $HOME/.write-file
+
+`)
+}
+
 func (s *personalFilesInterfaceSuite) TestSanitizeSlot(c *C) {
 	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
 }
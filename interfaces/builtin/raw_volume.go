@@ -20,12 +20,14 @@
 package builtin
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 )
@@ -149,6 +151,55 @@ func (iface *rawVolumeInterface) UDevConnectedPlug(spec *udev.Specification, plu
 	return nil
 }
 
+// HotplugDeviceDetected proposes a slot for partitions of removable USB disks
+// as they are plugged in. The slot path is updated whenever the partition is
+// plugged in again under a different device node.
+func (iface *rawVolumeInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	bus, _ := di.Attribute("ID_BUS")
+	if di.Subsystem() != "block" || di.DeviceType() != "partition" || bus != "usb" {
+		return nil, nil
+	}
+	if !rawVolumePartitionPattern.MatchString(di.DeviceName()) {
+		return nil, nil
+	}
+	return &hotplug.ProposedSlot{
+		Attrs: map[string]interface{}{
+			"path": di.DeviceName(),
+		},
+	}, nil
+}
+
+// HotplugKey computes a key identifying a partition of a specific disk. The
+// default key is derived from the attributes of the disk alone and would be
+// the same for all its partitions.
+func (iface *rawVolumeInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	var attrs []string
+	if uuid, ok := di.Attribute("ID_PART_ENTRY_UUID"); ok && uuid != "" {
+		attrs = []string{"ID_PART_ENTRY_UUID", uuid}
+	} else {
+		serial, ok1 := di.Attribute("ID_SERIAL")
+		number, ok2 := di.Attribute("ID_PART_ENTRY_NUMBER")
+		if !ok1 || !ok2 || serial == "" || number == "" {
+			return "", fmt.Errorf("cannot identify partition %s", di.DeviceName())
+		}
+		attrs = []string{"ID_SERIAL", serial, "ID_PART_ENTRY_NUMBER", number}
+	}
+	key := sha256.New()
+	for _, attr := range attrs {
+		key.Write([]byte(attr))
+		key.Write([]byte{0})
+	}
+	return snap.HotplugKey(fmt.Sprintf("0%x", key.Sum(nil))), nil
+}
+
+func (iface *rawVolumeInterface) HandledByGadget(di *hotplug.HotplugDeviceInfo, slot *snap.SlotInfo) bool {
+	var path string
+	if err := slot.Attr("path", &path); err != nil {
+		return false
+	}
+	return di.DeviceName() == path
+}
+
 func (iface *rawVolumeInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bool {
 	// Allow what is allowed in the declarations
 	return true
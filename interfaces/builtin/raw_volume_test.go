@@ -29,6 +29,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
@@ -345,6 +346,87 @@ func (s *rawVolumeInterfaceSuite) TestAutoConnect(c *C) {
 	c.Check(s.iface.AutoConnect(nil, nil), Equals, true)
 }
 
+func (s *rawVolumeInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/usb1/1-1/block/sdb/sdb1", "DEVNAME": "/dev/sdb1", "DEVTYPE": "partition", "ACTION": "add", "SUBSYSTEM": "block", "ID_BUS": "usb"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/sdb1"}})
+}
+
+func (s *rawVolumeInterfaceSuite) TestHotplugDeviceDetectedIgnored(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	for _, env := range []map[string]string{
+		// whole disk
+		{"DEVPATH": "/devices/usb1/1-1/block/sdb", "DEVNAME": "/dev/sdb", "DEVTYPE": "disk", "SUBSYSTEM": "block", "ID_BUS": "usb"},
+		// internal disk
+		{"DEVPATH": "/devices/pci0000:00/block/sda/sda1", "DEVNAME": "/dev/sda1", "DEVTYPE": "partition", "SUBSYSTEM": "block", "ID_BUS": "ata"},
+		// not a disk partition
+		{"DEVPATH": "/devices/usb1/1-1/block/sr0", "DEVNAME": "/dev/sr0", "DEVTYPE": "partition", "SUBSYSTEM": "block", "ID_BUS": "usb"},
+		// not a block device
+		{"DEVPATH": "/devices/usb1/1-1/tty/ttyUSB0", "DEVNAME": "/dev/ttyUSB0", "SUBSYSTEM": "tty", "ID_BUS": "usb"},
+	} {
+		di, err := hotplug.NewHotplugDeviceInfo(env)
+		c.Assert(err, IsNil)
+		proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+		c.Assert(err, IsNil)
+		c.Check(proposedSlot, IsNil, Commentf("%v", env))
+	}
+}
+
+func (s *rawVolumeInterfaceSuite) TestHotplugKey(c *C) {
+	keyHandler := s.iface.(hotplug.HotplugKeyHandler)
+
+	env := map[string]string{"DEVPATH": "/devices/usb1/1-1/block/sdb/sdb1", "DEVNAME": "/dev/sdb1", "ID_SERIAL": "disk-1234", "ID_PART_ENTRY_NUMBER": "1", "ID_PART_ENTRY_UUID": "c0ffee00-0000-4000-8000-000000000001"}
+	di, err := hotplug.NewHotplugDeviceInfo(env)
+	c.Assert(err, IsNil)
+	key1, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key1, HasLen, 65)
+
+	// the partition is identified by its UUID, regardless of the device node
+	env["DEVNAME"] = "/dev/sdc1"
+	env["DEVPATH"] = "/devices/usb2/2-1/block/sdc/sdc1"
+	di, err = hotplug.NewHotplugDeviceInfo(env)
+	c.Assert(err, IsNil)
+	key, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key, Equals, key1)
+
+	// other partitions of the same disk have different keys
+	env["ID_PART_ENTRY_NUMBER"] = "2"
+	env["ID_PART_ENTRY_UUID"] = "c0ffee00-0000-4000-8000-000000000002"
+	di, err = hotplug.NewHotplugDeviceInfo(env)
+	c.Assert(err, IsNil)
+	key2, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key2, Not(Equals), key1)
+
+	// without a partition UUID the disk serial and partition number are used
+	delete(env, "ID_PART_ENTRY_UUID")
+	di, err = hotplug.NewHotplugDeviceInfo(env)
+	c.Assert(err, IsNil)
+	key, err = keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key, HasLen, 65)
+	c.Check(key, Not(Equals), key2)
+
+	delete(env, "ID_SERIAL")
+	di, err = hotplug.NewHotplugDeviceInfo(env)
+	c.Assert(err, IsNil)
+	_, err = keyHandler.HotplugKey(di)
+	c.Assert(err, ErrorMatches, "cannot identify partition /dev/sdc1")
+}
+
+func (s *rawVolumeInterfaceSuite) TestHotplugHandledByGadget(c *C) {
+	byGadgetPred := s.iface.(hotplug.HandledByGadgetPredicate)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/virtual/block/vda/vda1", "DEVNAME": "/dev/vda1", "DEVTYPE": "partition", "SUBSYSTEM": "block"})
+	c.Assert(err, IsNil)
+	c.Check(byGadgetPred.HandledByGadget(di, s.testUDev1Info), Equals, true)
+	c.Check(byGadgetPred.HandledByGadget(di, s.testUDev2Info), Equals, false)
+}
+
 func (s *rawVolumeInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
@@ -49,6 +49,26 @@ const removableMediaConnectedPlugAppArmor = `
 /mnt/** mrwklix,
 `
 
+const removableMediaConnectedPlugLandlock = `
+# Description: Can access removable storage mounted under /media, /run/media
+# and /mnt.
+rw /media
+rw /run/media
+rw /mnt
+`
+
+const removableMediaConnectedPlugSELinux = `
+; Description: Can access removable storage mounted under /media, /run/media
+; and /mnt.
+(allow ###DOMAIN### mnt_t (dir (getattr search open read)))
+(allow ###DOMAIN### removable_t (dir (getattr search open read write add_name remove_name create rmdir rename setattr)))
+(allow ###DOMAIN### removable_t (file (getattr open read write append create unlink rename setattr lock ioctl map)))
+(allow ###DOMAIN### removable_t (lnk_file (getattr read)))
+(allow ###DOMAIN### dosfs_t (filesystem (getattr)))
+(allow ###DOMAIN### dosfs_t (dir (getattr search open read write add_name remove_name create rmdir rename setattr)))
+(allow ###DOMAIN### dosfs_t (file (getattr open read write append create unlink rename setattr lock ioctl map)))
+`
+
 func init() {
 	registerIface(&commonInterface{
 		name:                  "removable-media",
@@ -57,5 +77,7 @@ func init() {
 		implicitOnClassic:     true,
 		baseDeclarationSlots:  removableMediaBaseDeclarationSlots,
 		connectedPlugAppArmor: removableMediaConnectedPlugAppArmor,
+		connectedPlugSELinux:  removableMediaConnectedPlugSELinux,
+		connectedPlugLandlock: removableMediaConnectedPlugLandlock,
 	})
 }
@@ -25,6 +25,8 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/landlock"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
@@ -81,6 +83,21 @@ func (s *RemovableMediaInterfaceSuite) TestUsedSecuritySystems(c *C) {
 	c.Assert(apparmorSpec.SecurityTags(), DeepEquals, []string{"snap.client-snap.other"})
 	c.Check(apparmorSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "/{,run/}media/*/ r")
 	c.Check(apparmorSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "/mnt/** mrwklix,")
+
+	// connected plugs have a non-nil security snippet for selinux
+	selinuxSpec := &selinux.Specification{}
+	err = selinuxSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(selinuxSpec.SecurityTags(), DeepEquals, []string{"snap.client-snap.other"})
+	c.Check(selinuxSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "(allow ###DOMAIN### removable_t (file (")
+	c.Check(selinuxSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "(allow ###DOMAIN### mnt_t (dir (getattr search open read)))\n")
+
+	// connected plugs have a non-nil security snippet for landlock
+	landlockSpec := &landlock.Specification{}
+	err = landlockSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(landlockSpec.SecurityTags(), DeepEquals, []string{"snap.client-snap.other"})
+	c.Check(landlockSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "\nrw /media\nrw /run/media\nrw /mnt\n")
 }
 
 func (s *RemovableMediaInterfaceSuite) TestInterfaces(c *C) {
@@ -0,0 +1,268 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/sandbox/audit"
+)
+
+// staticConnectedPlugAppArmor returns the AppArmor snippet the interface
+// always grants to a connected plug.
+func (iface *commonInterface) staticConnectedPlugAppArmor() string {
+	return iface.connectedPlugAppArmor
+}
+
+// staticConnectedPlugSecComp returns the seccomp snippet the interface
+// always grants to a connected plug.
+func (iface *commonInterface) staticConnectedPlugSecComp() string {
+	return iface.connectedPlugSecComp
+}
+
+// SuggestInterfaces returns the sorted names of the built-in interfaces whose
+// AppArmor or seccomp rules for connected plugs would allow the operation
+// denied to a snap. Only the rules common to all connections of an interface
+// are taken into account. The kernel records of seccomp denials carry neither
+// the name nor the arguments of the system call, the number is mapped to a
+// name for the architecture of the record and any rule for that system call
+// is considered to allow it.
+func SuggestInterfaces(denial *audit.Denial) []string {
+	switch denial.Kind {
+	case audit.AppArmorDenial:
+		return suggestAppArmorInterfaces(denial)
+	case audit.SeccompDenial:
+		return suggestSecCompInterfaces(denial)
+	}
+	return nil
+}
+
+func suggestAppArmorInterfaces(denial *audit.Denial) []string {
+	var allows func(rule []string) bool
+	switch {
+	case denial.Capability != "":
+		allows = func(rule []string) bool {
+			if rule[0] != "capability" {
+				return false
+			}
+			for _, capability := range rule[1:] {
+				if capability == denial.Capability {
+					return true
+				}
+			}
+			return false
+		}
+	case strings.HasPrefix(denial.Name, "/") && denial.DeniedMask != "":
+		allows = func(rule []string) bool {
+			if len(rule) < 2 || !isAppArmorPath(rule[0]) {
+				return false
+			}
+			return apparmorPermsAllow(rule[1], denial.DeniedMask) && apparmorPathMatches(rule[0], denial.Name)
+		}
+	default:
+		return nil
+	}
+
+	var names []string
+	for name, iface := range allInterfaces {
+		withRules, ok := iface.(interface{ staticConnectedPlugAppArmor() string })
+		if !ok {
+			continue
+		}
+		for _, rule := range apparmorRules(withRules.staticConnectedPlugAppArmor()) {
+			if allows(rule) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func suggestSecCompInterfaces(denial *audit.Denial) []string {
+	syscallName := seccompSyscallNames[denial.Arch][denial.Syscall]
+	if syscallName == "" {
+		return nil
+	}
+
+	var names []string
+	for name, iface := range allInterfaces {
+		withRules, ok := iface.(interface{ staticConnectedPlugSecComp() string })
+		if !ok {
+			continue
+		}
+		for _, rule := range seccompRules(withRules.staticConnectedPlugSecComp()) {
+			if rule == syscallName {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// seccompRules returns the names of the system calls allowed by a seccomp
+// snippet. Deny rules and special directives such as @unrestricted are left
+// out.
+func seccompRules(snippet string) []string {
+	var syscalls []string
+	for _, line := range strings.Split(snippet, "\n") {
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "~") || strings.HasPrefix(fields[0], "@") {
+			continue
+		}
+		syscalls = append(syscalls, fields[0])
+	}
+	return syscalls
+}
+
+// apparmorRules splits an AppArmor snippet into the fields of its allow
+// rules, with qualifiers dropped.
+func apparmorRules(snippet string) [][]string {
+	var rules [][]string
+	for _, line := range strings.Split(snippet, "\n") {
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+		line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+		fields := strings.Fields(line)
+		for len(fields) > 0 && (fields[0] == "audit" || fields[0] == "owner" || fields[0] == "allow") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 || fields[0] == "deny" {
+			continue
+		}
+		if len(fields) > 1 && fields[0] == "file" {
+			fields = fields[1:]
+		}
+		// rules with the permissions in front of the path
+		if len(fields) > 1 && !isAppArmorPath(fields[0]) && isAppArmorPath(fields[1]) {
+			fields[0], fields[1] = fields[1], fields[0]
+		}
+		for i := range fields {
+			fields[i] = strings.Trim(fields[i], `"`)
+		}
+		rules = append(rules, fields)
+	}
+	return rules
+}
+
+func isAppArmorPath(field string) bool {
+	field = strings.TrimPrefix(field, `"`)
+	return strings.HasPrefix(field, "/") || strings.HasPrefix(field, "@{")
+}
+
+// apparmorPermsAllow returns whether the permissions of a rule grant all of
+// the access in the denied mask of a kernel record.
+func apparmorPermsAllow(perms, deniedMask string) bool {
+	for _, m := range deniedMask {
+		switch m {
+		case ':':
+			// separates the owner and other permissions
+		case 'c', 'd', 'w':
+			if !strings.ContainsRune(perms, 'w') {
+				return false
+			}
+		case 'a':
+			if !strings.ContainsAny(perms, "aw") {
+				return false
+			}
+		case 'r', 'x', 'm', 'k', 'l':
+			if !strings.ContainsRune(perms, m) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// apparmorVariables are the regular expressions matching the values of the
+// AppArmor variables used in the interface snippets, those not listed match
+// a single path component.
+var apparmorVariables = map[string]string{
+	"PROC": "/proc",
+	"HOME": "(?:/home/[^/]+|/root)",
+	"pid":  "[0-9]+",
+	"pids": "[0-9]+",
+	"tid":  "[0-9]+",
+}
+
+// apparmorPathMatches returns whether the AppArmor path glob matches the
+// given path.
+func apparmorPathMatches(glob, path string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	depth := 0
+	for i := 0; i < len(glob); i++ {
+		switch ch := glob[i]; {
+		case ch == '@' && strings.HasPrefix(glob[i:], "@{"):
+			end := strings.IndexByte(glob[i:], '}')
+			if end == -1 {
+				return false
+			}
+			variable := glob[i+2 : i+end]
+			if value, ok := apparmorVariables[variable]; ok {
+				expr.WriteString(value)
+			} else {
+				expr.WriteString("[^/]+")
+			}
+			i += end
+		case ch == '*' && strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case ch == '*':
+			expr.WriteString("[^/]*")
+		case ch == '?':
+			expr.WriteString("[^/]")
+		case ch == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end == -1 {
+				return false
+			}
+			expr.WriteString(glob[i : i+end+1])
+			i += end
+		case ch == '{':
+			expr.WriteString("(?:")
+			depth++
+		case ch == '}' && depth > 0:
+			expr.WriteString(")")
+			depth--
+		case ch == ',' && depth > 0:
+			expr.WriteString("|")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return false
+	}
+	return re.MatchString(path)
+}
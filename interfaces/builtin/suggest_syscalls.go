@@ -0,0 +1,374 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+// seccompSyscallNames maps the system call numbers of each architecture, as
+// found in seccomp denial records, to their names. Only the system calls
+// granted by the static seccomp rules of the interfaces are listed, as no
+// other can lead to a suggestion. The numbers come from the kernel unistd
+// headers, system calls which do not exist on an architecture are missing.
+var seccompSyscallNames = map[string]map[int]string{
+	"i386": {
+		14:  "mknod",
+		16:  "lchown",
+		21:  "mount",
+		22:  "umount",
+		23:  "setuid",
+		34:  "nice",
+		52:  "umount2",
+		61:  "chroot",
+		74:  "sethostname",
+		79:  "settimeofday",
+		81:  "setgroups",
+		95:  "fchown",
+		97:  "setpriority",
+		101: "ioperm",
+		110: "iopl",
+		124: "adjtimex",
+		128: "init_module",
+		129: "delete_module",
+		131: "quotactl",
+		154: "sched_setparam",
+		156: "sched_setscheduler",
+		182: "chown",
+		185: "capset",
+		198: "lchown32",
+		206: "setgroups32",
+		207: "fchown32",
+		212: "chown32",
+		217: "pivot_root",
+		241: "sched_setaffinity",
+		264: "clock_settime",
+		286: "add_key",
+		287: "request_key",
+		288: "keyctl",
+		297: "mknodat",
+		298: "fchownat",
+		310: "unshare",
+		336: "perf_event_open",
+		343: "clock_adjtime",
+		345: "sendmmsg",
+		346: "setns",
+		350: "finit_module",
+		351: "sched_setattr",
+		357: "bpf",
+		359: "socket",
+		361: "bind",
+		363: "listen",
+		364: "accept4",
+		365: "getsockopt",
+		369: "sendto",
+		370: "sendmsg",
+		371: "recvfrom",
+		372: "recvmsg",
+		404: "clock_settime64",
+		405: "clock_adjtime64",
+	},
+	"amd64": {
+		41:  "socket",
+		43:  "accept",
+		44:  "sendto",
+		45:  "recvfrom",
+		46:  "sendmsg",
+		47:  "recvmsg",
+		49:  "bind",
+		50:  "listen",
+		55:  "getsockopt",
+		92:  "chown",
+		93:  "fchown",
+		94:  "lchown",
+		105: "setuid",
+		116: "setgroups",
+		126: "capset",
+		133: "mknod",
+		141: "setpriority",
+		142: "sched_setparam",
+		144: "sched_setscheduler",
+		155: "pivot_root",
+		159: "adjtimex",
+		161: "chroot",
+		164: "settimeofday",
+		165: "mount",
+		166: "umount2",
+		170: "sethostname",
+		172: "iopl",
+		173: "ioperm",
+		175: "init_module",
+		176: "delete_module",
+		179: "quotactl",
+		203: "sched_setaffinity",
+		227: "clock_settime",
+		248: "add_key",
+		249: "request_key",
+		250: "keyctl",
+		259: "mknodat",
+		260: "fchownat",
+		272: "unshare",
+		288: "accept4",
+		298: "perf_event_open",
+		305: "clock_adjtime",
+		307: "sendmmsg",
+		308: "setns",
+		313: "finit_module",
+		314: "sched_setattr",
+		321: "bpf",
+	},
+	"armhf": {
+		14:  "mknod",
+		16:  "lchown",
+		21:  "mount",
+		23:  "setuid",
+		34:  "nice",
+		52:  "umount2",
+		61:  "chroot",
+		74:  "sethostname",
+		79:  "settimeofday",
+		81:  "setgroups",
+		95:  "fchown",
+		97:  "setpriority",
+		124: "adjtimex",
+		128: "init_module",
+		129: "delete_module",
+		131: "quotactl",
+		154: "sched_setparam",
+		156: "sched_setscheduler",
+		182: "chown",
+		185: "capset",
+		198: "lchown32",
+		206: "setgroups32",
+		207: "fchown32",
+		212: "chown32",
+		218: "pivot_root",
+		241: "sched_setaffinity",
+		262: "clock_settime",
+		281: "socket",
+		282: "bind",
+		284: "listen",
+		285: "accept",
+		290: "sendto",
+		292: "recvfrom",
+		295: "getsockopt",
+		296: "sendmsg",
+		297: "recvmsg",
+		309: "add_key",
+		310: "request_key",
+		311: "keyctl",
+		324: "mknodat",
+		325: "fchownat",
+		337: "unshare",
+		364: "perf_event_open",
+		366: "accept4",
+		372: "clock_adjtime",
+		374: "sendmmsg",
+		375: "setns",
+		379: "finit_module",
+		380: "sched_setattr",
+		386: "bpf",
+		404: "clock_settime64",
+		405: "clock_adjtime64",
+	},
+	"arm64": {
+		33:  "mknodat",
+		39:  "umount2",
+		40:  "mount",
+		41:  "pivot_root",
+		51:  "chroot",
+		54:  "fchownat",
+		55:  "fchown",
+		60:  "quotactl",
+		91:  "capset",
+		97:  "unshare",
+		105: "init_module",
+		106: "delete_module",
+		112: "clock_settime",
+		118: "sched_setparam",
+		119: "sched_setscheduler",
+		122: "sched_setaffinity",
+		140: "setpriority",
+		146: "setuid",
+		159: "setgroups",
+		161: "sethostname",
+		170: "settimeofday",
+		171: "adjtimex",
+		198: "socket",
+		200: "bind",
+		201: "listen",
+		202: "accept",
+		206: "sendto",
+		207: "recvfrom",
+		209: "getsockopt",
+		211: "sendmsg",
+		212: "recvmsg",
+		217: "add_key",
+		218: "request_key",
+		219: "keyctl",
+		241: "perf_event_open",
+		242: "accept4",
+		266: "clock_adjtime",
+		268: "setns",
+		269: "sendmmsg",
+		273: "finit_module",
+		274: "sched_setattr",
+		280: "bpf",
+	},
+	"ppc64el": {
+		14:  "mknod",
+		16:  "lchown",
+		21:  "mount",
+		22:  "umount",
+		23:  "setuid",
+		34:  "nice",
+		52:  "umount2",
+		61:  "chroot",
+		74:  "sethostname",
+		79:  "settimeofday",
+		81:  "setgroups",
+		95:  "fchown",
+		97:  "setpriority",
+		101: "ioperm",
+		110: "iopl",
+		124: "adjtimex",
+		128: "init_module",
+		129: "delete_module",
+		131: "quotactl",
+		154: "sched_setparam",
+		156: "sched_setscheduler",
+		181: "chown",
+		184: "capset",
+		203: "pivot_root",
+		222: "sched_setaffinity",
+		245: "clock_settime",
+		269: "add_key",
+		270: "request_key",
+		271: "keyctl",
+		282: "unshare",
+		288: "mknodat",
+		289: "fchownat",
+		319: "perf_event_open",
+		326: "socket",
+		327: "bind",
+		329: "listen",
+		330: "accept",
+		335: "sendto",
+		337: "recvfrom",
+		340: "getsockopt",
+		341: "sendmsg",
+		342: "recvmsg",
+		344: "accept4",
+		347: "clock_adjtime",
+		349: "sendmmsg",
+		350: "setns",
+		353: "finit_module",
+		355: "sched_setattr",
+		361: "bpf",
+	},
+	"s390x": {
+		14:  "mknod",
+		21:  "mount",
+		22:  "umount",
+		34:  "nice",
+		52:  "umount2",
+		61:  "chroot",
+		74:  "sethostname",
+		79:  "settimeofday",
+		97:  "setpriority",
+		124: "adjtimex",
+		128: "init_module",
+		129: "delete_module",
+		131: "quotactl",
+		154: "sched_setparam",
+		156: "sched_setscheduler",
+		185: "capset",
+		198: "lchown",
+		206: "setgroups",
+		207: "fchown",
+		212: "chown",
+		213: "setuid",
+		217: "pivot_root",
+		239: "sched_setaffinity",
+		259: "clock_settime",
+		278: "add_key",
+		279: "request_key",
+		280: "keyctl",
+		290: "mknodat",
+		291: "fchownat",
+		303: "unshare",
+		331: "perf_event_open",
+		337: "clock_adjtime",
+		339: "setns",
+		344: "finit_module",
+		345: "sched_setattr",
+		351: "bpf",
+		358: "sendmmsg",
+		359: "socket",
+		361: "bind",
+		363: "listen",
+		364: "accept4",
+		365: "getsockopt",
+		369: "sendto",
+		370: "sendmsg",
+		371: "recvfrom",
+		372: "recvmsg",
+	},
+	"riscv64": {
+		33:  "mknodat",
+		39:  "umount2",
+		40:  "mount",
+		41:  "pivot_root",
+		51:  "chroot",
+		54:  "fchownat",
+		55:  "fchown",
+		60:  "quotactl",
+		91:  "capset",
+		97:  "unshare",
+		105: "init_module",
+		106: "delete_module",
+		112: "clock_settime",
+		118: "sched_setparam",
+		119: "sched_setscheduler",
+		122: "sched_setaffinity",
+		140: "setpriority",
+		146: "setuid",
+		159: "setgroups",
+		161: "sethostname",
+		170: "settimeofday",
+		171: "adjtimex",
+		198: "socket",
+		200: "bind",
+		201: "listen",
+		202: "accept",
+		206: "sendto",
+		207: "recvfrom",
+		209: "getsockopt",
+		211: "sendmsg",
+		212: "recvmsg",
+		217: "add_key",
+		218: "request_key",
+		219: "keyctl",
+		241: "perf_event_open",
+		242: "accept4",
+		266: "clock_adjtime",
+		268: "setns",
+		269: "sendmmsg",
+		273: "finit_module",
+		274: "sched_setattr",
+		280: "bpf",
+	},
+}
@@ -0,0 +1,158 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/sandbox/audit"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type suggestSuite struct{}
+
+var _ = Suite(&suggestSuite{})
+
+func (s *suggestSuite) TestSuggestInterfacesFile(c *C) {
+	suggested := builtin.SuggestInterfaces(&audit.Denial{
+		Kind:       audit.AppArmorDenial,
+		Operation:  "open",
+		Name:       "/dev/video0",
+		DeniedMask: "wr",
+	})
+	c.Check(suggested, testutil.Contains, "camera")
+	c.Check(suggested, Not(testutil.Contains), "mount-observe")
+
+	// variables are expanded
+	suggested = builtin.SuggestInterfaces(&audit.Denial{
+		Kind:       audit.AppArmorDenial,
+		Operation:  "open",
+		Name:       "/proc/1234/mountinfo",
+		DeniedMask: "r",
+	})
+	c.Check(suggested, testutil.Contains, "mount-observe")
+	c.Check(suggested, Not(testutil.Contains), "camera")
+
+	// the rules must grant all of the denied access
+	suggested = builtin.SuggestInterfaces(&audit.Denial{
+		Kind:       audit.AppArmorDenial,
+		Operation:  "open",
+		Name:       "/proc/1234/mountinfo",
+		DeniedMask: "w",
+	})
+	c.Check(suggested, Not(testutil.Contains), "mount-observe")
+}
+
+func (s *suggestSuite) TestSuggestInterfacesCapability(c *C) {
+	suggested := builtin.SuggestInterfaces(&audit.Denial{
+		Kind:       audit.AppArmorDenial,
+		Operation:  "capable",
+		Capability: "net_admin",
+	})
+	c.Check(suggested, testutil.Contains, "network-control")
+	c.Check(suggested, Not(testutil.Contains), "camera")
+}
+
+func (s *suggestSuite) TestSuggestInterfacesSyscall(c *C) {
+	// sethostname on amd64
+	suggested := builtin.SuggestInterfaces(&audit.Denial{
+		Kind:    audit.SeccompDenial,
+		Syscall: 170,
+		Arch:    "amd64",
+	})
+	c.Check(suggested, testutil.Contains, "hostname-control")
+	c.Check(suggested, Not(testutil.Contains), "mount-control")
+
+	// the system call numbers depend on the architecture, 170 is
+	// sethostname on amd64 and 161 is sethostname on arm64 but chroot on
+	// amd64
+	suggested = builtin.SuggestInterfaces(&audit.Denial{
+		Kind:    audit.SeccompDenial,
+		Syscall: 161,
+		Arch:    "arm64",
+	})
+	c.Check(suggested, testutil.Contains, "hostname-control")
+	suggested = builtin.SuggestInterfaces(&audit.Denial{
+		Kind:    audit.SeccompDenial,
+		Syscall: 161,
+		Arch:    "amd64",
+	})
+	c.Check(suggested, Not(testutil.Contains), "hostname-control")
+
+	// mount on amd64
+	suggested = builtin.SuggestInterfaces(&audit.Denial{
+		Kind:    audit.SeccompDenial,
+		Syscall: 165,
+		Arch:    "amd64",
+	})
+	c.Check(suggested, testutil.Contains, "mount-control")
+	c.Check(suggested, Not(testutil.Contains), "hostname-control")
+
+	// rules with argument filters match any call, the arguments are not
+	// recorded
+	suggested = builtin.SuggestInterfaces(&audit.Denial{
+		Kind:    audit.SeccompDenial,
+		Syscall: 359,
+		Arch:    "i386",
+	})
+	c.Check(suggested, testutil.Contains, "network-control")
+}
+
+func (s *suggestSuite) TestSeccompRules(c *C) {
+	c.Check(builtin.SeccompRules(`
+# comment
+mount
+socket AF_NETLINK - NETLINK_ROUTE # trailing comment
+~ioctl - TIOCSTI
+@unrestricted
+`), DeepEquals, []string{"mount", "socket"})
+}
+
+func (s *suggestSuite) TestSeccompSyscallNamesCoverStaticRules(c *C) {
+	known := make(map[string]bool)
+	for _, names := range builtin.SeccompSyscallNames {
+		for _, name := range names {
+			known[name] = true
+		}
+	}
+	for _, iface := range builtin.Interfaces() {
+		for _, syscall := range builtin.SeccompRules(builtin.StaticConnectedPlugSecComp(iface)) {
+			if syscall == "lchownat" {
+				// not a system call on any architecture
+				continue
+			}
+			c.Check(known[syscall], Equals, true, Commentf("system call %q of interface %q has no number", syscall, iface.Name()))
+		}
+	}
+}
+
+func (s *suggestSuite) TestSuggestInterfacesUnsupported(c *C) {
+	for _, denial := range []*audit.Denial{
+		// read on amd64, allowed to every snap
+		{Kind: audit.SeccompDenial, Syscall: 0, Arch: "amd64"},
+		{Kind: audit.SeccompDenial, Syscall: 165, Arch: "c0000999"},
+		{Kind: audit.AppArmorDenial, Operation: "dbus_method_call", Name: "org.freedesktop.NetworkManager"},
+		{Kind: audit.AppArmorDenial, Operation: "open", Name: "/dev/video0"},
+		{Kind: audit.AppArmorDenial, Operation: "open", Name: "/no/such/path", DeniedMask: "w"},
+	} {
+		c.Check(builtin.SuggestInterfaces(denial), HasLen, 0, Commentf("%+v", denial))
+	}
+}
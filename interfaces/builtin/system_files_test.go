@@ -27,6 +27,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/landlock"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
@@ -86,6 +87,19 @@ func (s *systemFilesInterfaceSuite) TestConnectedPlugAppArmor(c *C) {
 `)
 }
 
+func (s *systemFilesInterfaceSuite) TestConnectedPlugLandlock(c *C) {
+	landlockSpec := &landlock.Specification{}
+	err := landlockSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(landlockSpec.SecurityTags(), DeepEquals, []string{"snap.other.app"})
+	c.Check(landlockSpec.SnippetForTag("snap.other.app"), Equals, `r /etc/read-dir2
+r /etc/read-file2
+rw /etc/write-dir2
+rw /etc/write-file2
+
+`)
+}
+
 func (s *systemFilesInterfaceSuite) TestSanitizeSlot(c *C) {
 	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
 }
@@ -168,6 +182,10 @@ apps:
 	apparmorSpec := &apparmor.Specification{}
 	err := apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
 	c.Assert(err, ErrorMatches, `cannot connect plug system-files: 123 \(int64\) is not a string`)
+
+	landlockSpec := &landlock.Specification{}
+	err = landlockSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, ErrorMatches, `cannot connect plug system-files: 123 \(int64\) is not a string`)
 }
 
 func (s *systemFilesInterfaceSuite) TestInterfaces(c *C) {
@@ -27,6 +27,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/mount"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
@@ -166,6 +167,14 @@ socket AF_NETLINK - NETLINK_KOBJECT_UEVENT
 bind
 `
 
+const x11ConnectedPlugSELinux = `
+; Description: Can connect to the X server of the host.
+(allow ###DOMAIN### xdm_tmp_t (dir (getattr search)))
+(allow ###DOMAIN### xdm_tmp_t (sock_file (getattr write)))
+(allow ###DOMAIN### xserver_t (unix_stream_socket (connectto)))
+(allow ###DOMAIN### user_tmp_t (dir (getattr search)))
+`
+
 type x11Interface struct {
 	commonInterface
 }
@@ -253,6 +262,14 @@ func (iface *x11Interface) AppArmorPermanentSlot(spec *apparmor.Specification, s
 	return nil
 }
 
+func (iface *x11Interface) SELinuxConnectedPlug(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	// Only the X server of the host runs in a domain known to the policy.
+	if implicitSystemConnectedSlot(slot) {
+		spec.AddSnippet(x11ConnectedPlugSELinux)
+	}
+	return nil
+}
+
 func (iface *x11Interface) UDevPermanentSlot(spec *udev.Specification, slot *snap.SlotInfo) error {
 	if !implicitSystemPermanentSlot(slot) {
 		spec.TriggerSubsystem("input")
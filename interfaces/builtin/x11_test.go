@@ -30,6 +30,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/builtin"
 	"github.com/snapcore/snapd/interfaces/mount"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/release"
@@ -235,6 +236,30 @@ func (s *X11InterfaceSuite) TestSecCompOnCore(c *C) {
 	c.Assert(seccompSpec.SnippetForTag("snap.consumer.app"), testutil.Contains, "bind\n")
 }
 
+func (s *X11InterfaceSuite) TestSELinuxOnClassic(c *C) {
+	// on a classic system with x11 slot coming from the core snap.
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	selinuxSpec := &selinux.Specification{}
+	err := selinuxSpec.AddConnectedPlug(s.iface, s.plug, s.classicSlot)
+	c.Assert(err, IsNil)
+	c.Assert(selinuxSpec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Check(selinuxSpec.SnippetForTag("snap.consumer.app"), testutil.Contains, "(allow ###DOMAIN### xserver_t (unix_stream_socket (connectto)))\n")
+}
+
+func (s *X11InterfaceSuite) TestSELinuxOnCore(c *C) {
+	// on a core system with x11 slot coming from a snap.
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	selinuxSpec := &selinux.Specification{}
+	err := selinuxSpec.AddConnectedPlug(s.iface, s.plug, s.coreSlot)
+	c.Assert(err, IsNil)
+	// the X server is not running in a domain known to the policy
+	c.Assert(selinuxSpec.SecurityTags(), HasLen, 0)
+}
+
 func (s *X11InterfaceSuite) TestUDev(c *C) {
 	// on a core system with x11 slot coming from a regular app snap.
 	restore := release.MockOnClassic(false)
@@ -297,6 +297,12 @@ const (
 	SecuritySystemd SecuritySystem = "systemd"
 	// SecurityPolkit identifies the polkit security system.
 	SecurityPolkit SecuritySystem = "polkit"
+	// SecuritySELinux identifies the SELinux security system.
+	SecuritySELinux SecuritySystem = "selinux"
+	// SecurityLandlock identifies the landlock security system.
+	SecurityLandlock SecuritySystem = "landlock"
+	// SecurityNetNS identifies the network namespace security system.
+	SecurityNetNS SecuritySystem = "netns"
 )
 
 var isValidBusName = regexp.MustCompile(`^[a-zA-Z_-][a-zA-Z0-9_-]*(\.[a-zA-Z_-][a-zA-Z0-9_-]*)+$`).MatchString
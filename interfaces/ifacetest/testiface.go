@@ -25,9 +25,12 @@ import (
 	"github.com/snapcore/snapd/interfaces/dbus"
 	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/landlock"
 	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/netns"
 	"github.com/snapcore/snapd/interfaces/polkit"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/interfaces/systemd"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
@@ -111,6 +114,27 @@ type TestInterface struct {
 	PolkitConnectedSlotCallback func(spec *polkit.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
 	PolkitPermanentPlugCallback func(spec *polkit.Specification, plug *snap.PlugInfo) error
 	PolkitPermanentSlotCallback func(spec *polkit.Specification, slot *snap.SlotInfo) error
+
+	// Support for interacting with the SELinux backend.
+
+	SELinuxConnectedPlugCallback func(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	SELinuxConnectedSlotCallback func(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	SELinuxPermanentPlugCallback func(spec *selinux.Specification, plug *snap.PlugInfo) error
+	SELinuxPermanentSlotCallback func(spec *selinux.Specification, slot *snap.SlotInfo) error
+
+	// Support for interacting with the landlock backend.
+
+	LandlockConnectedPlugCallback func(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	LandlockConnectedSlotCallback func(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	LandlockPermanentPlugCallback func(spec *landlock.Specification, plug *snap.PlugInfo) error
+	LandlockPermanentSlotCallback func(spec *landlock.Specification, slot *snap.SlotInfo) error
+
+	// Support for interacting with the netns backend.
+
+	NetNSConnectedPlugCallback func(spec *netns.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	NetNSConnectedSlotCallback func(spec *netns.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	NetNSPermanentPlugCallback func(spec *netns.Specification, plug *snap.PlugInfo) error
+	NetNSPermanentSlotCallback func(spec *netns.Specification, slot *snap.SlotInfo) error
 }
 
 // TestHotplugInterface is an interface for various kinds of tests
@@ -450,6 +474,96 @@ func (t *TestInterface) PolkitPermanentPlug(spec *polkit.Specification, plug *sn
 	return nil
 }
 
+// Support for interacting with the SELinux backend.
+
+func (t *TestInterface) SELinuxConnectedPlug(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.SELinuxConnectedPlugCallback != nil {
+		return t.SELinuxConnectedPlugCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) SELinuxConnectedSlot(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.SELinuxConnectedSlotCallback != nil {
+		return t.SELinuxConnectedSlotCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) SELinuxPermanentSlot(spec *selinux.Specification, slot *snap.SlotInfo) error {
+	if t.SELinuxPermanentSlotCallback != nil {
+		return t.SELinuxPermanentSlotCallback(spec, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) SELinuxPermanentPlug(spec *selinux.Specification, plug *snap.PlugInfo) error {
+	if t.SELinuxPermanentPlugCallback != nil {
+		return t.SELinuxPermanentPlugCallback(spec, plug)
+	}
+	return nil
+}
+
+// Support for interacting with the landlock backend.
+
+func (t *TestInterface) LandlockConnectedPlug(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.LandlockConnectedPlugCallback != nil {
+		return t.LandlockConnectedPlugCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) LandlockConnectedSlot(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.LandlockConnectedSlotCallback != nil {
+		return t.LandlockConnectedSlotCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) LandlockPermanentSlot(spec *landlock.Specification, slot *snap.SlotInfo) error {
+	if t.LandlockPermanentSlotCallback != nil {
+		return t.LandlockPermanentSlotCallback(spec, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) LandlockPermanentPlug(spec *landlock.Specification, plug *snap.PlugInfo) error {
+	if t.LandlockPermanentPlugCallback != nil {
+		return t.LandlockPermanentPlugCallback(spec, plug)
+	}
+	return nil
+}
+
+// Support for interacting with the netns backend.
+
+func (t *TestInterface) NetNSConnectedPlug(spec *netns.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.NetNSConnectedPlugCallback != nil {
+		return t.NetNSConnectedPlugCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) NetNSConnectedSlot(spec *netns.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.NetNSConnectedSlotCallback != nil {
+		return t.NetNSConnectedSlotCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) NetNSPermanentSlot(spec *netns.Specification, slot *snap.SlotInfo) error {
+	if t.NetNSPermanentSlotCallback != nil {
+		return t.NetNSPermanentSlotCallback(spec, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) NetNSPermanentPlug(spec *netns.Specification, plug *snap.PlugInfo) error {
+	if t.NetNSPermanentPlugCallback != nil {
+		return t.NetNSPermanentPlugCallback(spec, plug)
+	}
+	return nil
+}
+
 // Support for interacting with hotplug subsystem.
 
 func (t *TestHotplugInterface) HotplugKey(deviceInfo *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
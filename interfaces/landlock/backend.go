@@ -0,0 +1,154 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package landlock implements integration between snapd and the landlock
+// LSM.
+//
+// The backend is used on systems where AppArmor is not available. It writes
+// one file with landlock rules for each application and hook of a snap to
+// /var/lib/snapd/landlock/<security-tag>.rules. The rules consist of a
+// default template and of the snippets contributed by interfaces which
+// affect filesystem access. The rules are applied by snap-exec right before
+// executing the application or hook.
+package landlock
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	landlock_sandbox "github.com/snapcore/snapd/sandbox/landlock"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timings"
+)
+
+// Backend is responsible for maintaining landlock rules for snap
+// applications and hooks.
+type Backend struct{}
+
+// Initialize does nothing.
+func (b *Backend) Initialize(*interfaces.SecurityBackendOptions) error {
+	return nil
+}
+
+// Name returns the name of the backend.
+func (b *Backend) Name() interfaces.SecuritySystem {
+	return interfaces.SecurityLandlock
+}
+
+// Setup writes the landlock rules of a given snap.
+//
+// Landlock has no complain mode, snaps in devmode and classic snaps get rules
+// marked as such and snap-exec does not apply them.
+func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
+	snapName := snapInfo.InstanceName()
+	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	if err != nil {
+		return fmt.Errorf("cannot obtain landlock specification for snap %q: %s", snapName, err)
+	}
+
+	content, err := deriveContent(spec.(*Specification), opts, snapInfo)
+	if err != nil {
+		return fmt.Errorf("cannot obtain expected landlock rules for snap %q: %s", snapName, err)
+	}
+
+	dir := dirs.SnapLandlockDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory for landlock rules %q: %s", dir, err)
+	}
+	glob := interfaces.SecurityTagGlob(snapName) + ".rules"
+	if _, _, err := osutil.EnsureDirState(dir, glob, content); err != nil {
+		return fmt.Errorf("cannot synchronize landlock rules for snap %q: %s", snapName, err)
+	}
+	return nil
+}
+
+// Remove removes the landlock rules of a given snap.
+func (b *Backend) Remove(snapName string) error {
+	glob := interfaces.SecurityTagGlob(snapName) + ".rules"
+	_, _, err := osutil.EnsureDirState(dirs.SnapLandlockDir, glob, nil)
+	if err != nil {
+		return fmt.Errorf("cannot synchronize landlock rules for snap %q: %s", snapName, err)
+	}
+	return nil
+}
+
+// deriveContent combines security snippets collected from all the interfaces
+// affecting a given snap into a content map applicable to EnsureDirState.
+func deriveContent(spec *Specification, opts interfaces.ConfinementOptions, snapInfo *snap.Info) (map[string]osutil.FileState, error) {
+	var securityTags []string
+	for _, hookInfo := range snapInfo.Hooks {
+		securityTags = append(securityTags, hookInfo.SecurityTag())
+	}
+	for _, appInfo := range snapInfo.Apps {
+		securityTags = append(securityTags, appInfo.SecurityTag())
+	}
+	if len(securityTags) == 0 {
+		return nil, nil
+	}
+
+	content := make(map[string]osutil.FileState, len(securityTags))
+	for _, securityTag := range securityTags {
+		rules := generateContent(opts, spec.SnippetForTag(securityTag))
+		// make sure that snap-exec will be able to parse the rules
+		if _, err := landlock_sandbox.ParseRuleset(bytes.NewReader(rules)); err != nil {
+			return nil, fmt.Errorf("invalid rules for %s: %v", securityTag, err)
+		}
+		content[securityTag+".rules"] = &osutil.MemoryFileState{
+			Content: rules,
+			Mode:    0644,
+		}
+	}
+	return content, nil
+}
+
+func generateContent(opts interfaces.ConfinementOptions, snippetForTag string) []byte {
+	var buffer bytes.Buffer
+
+	if opts.Classic && !opts.JailMode {
+		// NOTE: This is understood by snap-exec
+		buffer.WriteString("@unrestricted\n")
+	}
+	if opts.DevMode && !opts.JailMode {
+		// NOTE: This is understood by snap-exec
+		buffer.WriteString("@complain\n")
+	}
+
+	buffer.Write(defaultTemplate)
+	buffer.WriteString(snippetForTag)
+	return buffer.Bytes()
+}
+
+// NewSpecification returns an empty landlock specification.
+func (b *Backend) NewSpecification() interfaces.Specification {
+	return &Specification{}
+}
+
+// SandboxFeatures returns the list of landlock features supported by the
+// kernel.
+func (b *Backend) SandboxFeatures() []string {
+	version, err := landlock_sandbox.Version()
+	if err != nil || version == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("abi:%d", version)}
+}
@@ -0,0 +1,171 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/landlock"
+	landlock_sandbox "github.com/snapcore/snapd/sandbox/landlock"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type backendSuite struct {
+	ifacetest.BackendSuite
+}
+
+var _ = Suite(&backendSuite{})
+
+var testedConfinementOpts = []interfaces.ConfinementOptions{
+	{},
+	{DevMode: true},
+	{JailMode: true},
+	{Classic: true},
+}
+
+func (s *backendSuite) SetUpTest(c *C) {
+	s.Backend = &landlock.Backend{}
+	s.BackendSuite.SetUpTest(c)
+	c.Assert(s.Repo.AddBackend(s.Backend), IsNil)
+}
+
+func (s *backendSuite) TearDownTest(c *C) {
+	s.BackendSuite.TearDownTest(c)
+}
+
+func (s *backendSuite) TestName(c *C) {
+	c.Check(s.Backend.Name(), Equals, interfaces.SecurityLandlock)
+}
+
+func (s *backendSuite) TestInstallingSnapWritesRules(c *C) {
+	s.Iface.LandlockConnectedPlugCallback = func(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+		spec.AddSnippet("rw $HOME/foo")
+		return nil
+	}
+	s.Iface.LandlockPermanentSlotCallback = func(spec *landlock.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet("r /srv/samba")
+		return nil
+	}
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlWithHook, 0)
+	for _, tag := range []string{"snap.samba.smbd", "snap.samba.nmbd", "snap.samba.hook.configure"} {
+		rules := filepath.Join(dirs.SnapLandlockDir, tag+".rules")
+		c.Check(rules, testutil.FileContains, "\nrx /usr\n")
+		c.Check(rules, testutil.FileContains, "\nrw $SNAP_DATA\n")
+		c.Check(rules, Not(testutil.FileContains), "@")
+	}
+	// the slot is bound to all the apps
+	c.Check(filepath.Join(dirs.SnapLandlockDir, "snap.samba.smbd.rules"), testutil.FileContains, "r /srv/samba\n")
+	// the plug is bound to the hook, but not connected
+	c.Check(filepath.Join(dirs.SnapLandlockDir, "snap.samba.hook.configure.rules"), Not(testutil.FileContains), "rw $HOME/foo")
+	s.RemoveSnap(c, snapInfo)
+}
+
+func (s *backendSuite) TestInstallingSnapWritesRulesWithConfinementOptions(c *C) {
+	for _, tc := range []struct {
+		opts      interfaces.ConfinementOptions
+		directive string
+	}{
+		{opts: interfaces.ConfinementOptions{}},
+		{opts: interfaces.ConfinementOptions{JailMode: true}},
+		{opts: interfaces.ConfinementOptions{DevMode: true}, directive: "@complain\n"},
+		{opts: interfaces.ConfinementOptions{Classic: true}, directive: "@unrestricted\n"},
+		{opts: interfaces.ConfinementOptions{Classic: true, JailMode: true}},
+	} {
+		snapInfo := s.InstallSnap(c, tc.opts, "", ifacetest.SambaYamlV1, 0)
+		data, err := ioutil.ReadFile(filepath.Join(dirs.SnapLandlockDir, "snap.samba.smbd.rules"))
+		c.Assert(err, IsNil)
+		if tc.directive != "" {
+			c.Check(string(data), testutil.Contains, tc.directive)
+		} else {
+			c.Check(string(data), Not(testutil.Contains), "@")
+		}
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestRemovingSnapRemovesRules(c *C) {
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		s.RemoveSnap(c, snapInfo)
+		c.Check(filepath.Join(dirs.SnapLandlockDir, "snap.samba.smbd.rules"), testutil.FileAbsent)
+	}
+}
+
+func (s *backendSuite) TestUpdatingSnapToOneWithMoreApps(c *C) {
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		snapInfo = s.UpdateSnap(c, snapInfo, opts, ifacetest.SambaYamlV1WithNmbd, 0)
+		c.Check(filepath.Join(dirs.SnapLandlockDir, "snap.samba.nmbd.rules"), testutil.FilePresent)
+		snapInfo = s.UpdateSnap(c, snapInfo, opts, ifacetest.SambaYamlV1, 0)
+		c.Check(filepath.Join(dirs.SnapLandlockDir, "snap.samba.nmbd.rules"), testutil.FileAbsent)
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestInvalidSnippet(c *C) {
+	s.Iface.LandlockPermanentSlotCallback = func(spec *landlock.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet("rk /foo")
+		return nil
+	}
+	snapInfo := snaptest.MockInfo(c, ifacetest.SambaYamlV1, nil)
+	c.Assert(s.Repo.AddSnap(snapInfo), IsNil)
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, timings.New(nil))
+	c.Assert(err, ErrorMatches, `cannot obtain expected landlock rules for snap "samba": invalid rules for snap.samba.smbd: line .*: invalid access "rk"`)
+}
+
+func (s *backendSuite) TestUnexpectedRulesRemoved(c *C) {
+	c.Assert(os.MkdirAll(dirs.SnapLandlockDir, 0755), IsNil)
+	stale := filepath.Join(dirs.SnapLandlockDir, "snap.samba.gone.rules")
+	c.Assert(ioutil.WriteFile(stale, []byte("r /"), 0644), IsNil)
+
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(stale, testutil.FileAbsent)
+	s.RemoveSnap(c, snapInfo)
+}
+
+func (s *backendSuite) TestSandboxFeatures(c *C) {
+	restore := landlock_sandbox.MockVersion(2, nil)
+	defer restore()
+	c.Check(s.Backend.SandboxFeatures(), DeepEquals, []string{"abi:2"})
+
+	restore = landlock_sandbox.MockVersion(0, nil)
+	defer restore()
+	c.Check(s.Backend.SandboxFeatures(), HasLen, 0)
+
+	restore = landlock_sandbox.MockVersion(0, errors.New("boom"))
+	defer restore()
+	c.Check(s.Backend.SandboxFeatures(), HasLen, 0)
+}
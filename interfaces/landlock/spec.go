@@ -0,0 +1,140 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Specification keeps all the landlock rules.
+type Specification struct {
+	// Snippets are indexed by security tag.
+	snippets     map[string][]string
+	securityTags []string
+}
+
+// AddSnippet adds new landlock rules for all the security tags affected by
+// the current plug or slot.
+//
+// The snippet uses the format understood by sandbox/landlock, one
+// "<access> <path>" rule per line.
+func (spec *Specification) AddSnippet(snippet string) {
+	if len(spec.securityTags) == 0 {
+		return
+	}
+	if spec.snippets == nil {
+		spec.snippets = make(map[string][]string)
+	}
+	for _, tag := range spec.securityTags {
+		spec.snippets[tag] = append(spec.snippets[tag], snippet)
+	}
+}
+
+// Snippets returns a deep copy of all the added snippets.
+func (spec *Specification) Snippets() map[string][]string {
+	result := make(map[string][]string, len(spec.snippets))
+	for k, v := range spec.snippets {
+		vCopy := make([]string, 0, len(v))
+		vCopy = append(vCopy, v...)
+		result[k] = vCopy
+	}
+	return result
+}
+
+// SnippetForTag returns a combined snippet for given security tag with individual snippets
+// joined with newline character. Empty string is returned for non-existing security tag.
+// Landlock rules only ever grant access, so the order of the snippets does not matter.
+func (spec *Specification) SnippetForTag(tag string) string {
+	var buffer bytes.Buffer
+	sort.Strings(spec.snippets[tag])
+	for _, snippet := range spec.snippets[tag] {
+		buffer.WriteString(snippet)
+		buffer.WriteRune('\n')
+	}
+	return buffer.String()
+}
+
+// SecurityTags returns a list of security tags which have a snippet.
+func (spec *Specification) SecurityTags() []string {
+	var tags []string
+	for t := range spec.snippets {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Implementation of methods required by interfaces.Specification
+
+// AddConnectedPlug records landlock-specific side-effects of having a connected plug.
+func (spec *Specification) AddConnectedPlug(iface interfaces.Interface, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	type definer interface {
+		LandlockConnectedPlug(spec *Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	}
+	if iface, ok := iface.(definer); ok {
+		spec.securityTags = plug.SecurityTags()
+		defer func() { spec.securityTags = nil }()
+		return iface.LandlockConnectedPlug(spec, plug, slot)
+	}
+	return nil
+}
+
+// AddConnectedSlot records landlock-specific side-effects of having a connected slot.
+func (spec *Specification) AddConnectedSlot(iface interfaces.Interface, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	type definer interface {
+		LandlockConnectedSlot(spec *Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	}
+	if iface, ok := iface.(definer); ok {
+		spec.securityTags = slot.SecurityTags()
+		defer func() { spec.securityTags = nil }()
+		return iface.LandlockConnectedSlot(spec, plug, slot)
+	}
+	return nil
+}
+
+// AddPermanentPlug records landlock-specific side-effects of having a plug.
+func (spec *Specification) AddPermanentPlug(iface interfaces.Interface, plug *snap.PlugInfo) error {
+	type definer interface {
+		LandlockPermanentPlug(spec *Specification, plug *snap.PlugInfo) error
+	}
+	if iface, ok := iface.(definer); ok {
+		spec.securityTags = plug.SecurityTags()
+		defer func() { spec.securityTags = nil }()
+		return iface.LandlockPermanentPlug(spec, plug)
+	}
+	return nil
+}
+
+// AddPermanentSlot records landlock-specific side-effects of having a slot.
+func (spec *Specification) AddPermanentSlot(iface interfaces.Interface, slot *snap.SlotInfo) error {
+	type definer interface {
+		LandlockPermanentSlot(spec *Specification, slot *snap.SlotInfo) error
+	}
+	if iface, ok := iface.(definer); ok {
+		spec.securityTags = slot.SecurityTags()
+		defer func() { spec.securityTags = nil }()
+		return iface.LandlockPermanentSlot(spec, slot)
+	}
+	return nil
+}
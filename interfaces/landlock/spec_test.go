@@ -0,0 +1,145 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock_test
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/landlock"
+	landlock_sandbox "github.com/snapcore/snapd/sandbox/landlock"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+type specSuite struct {
+	iface *ifacetest.TestInterface
+	spec  *landlock.Specification
+	plug  *interfaces.ConnectedPlug
+	slot  *interfaces.ConnectedSlot
+}
+
+var _ = Suite(&specSuite{
+	iface: &ifacetest.TestInterface{
+		InterfaceName: "test",
+		LandlockConnectedPlugCallback: func(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("rw /media\nr /run/media")
+			return nil
+		},
+		LandlockConnectedSlotCallback: func(spec *landlock.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("rw /run/slot")
+			return nil
+		},
+		LandlockPermanentPlugCallback: func(spec *landlock.Specification, plug *snap.PlugInfo) error {
+			spec.AddSnippet("# Description: the user's home\nr $HOME")
+			return nil
+		},
+		LandlockPermanentSlotCallback: func(spec *landlock.Specification, slot *snap.SlotInfo) error {
+			spec.AddSnippet("rx /opt/slot")
+			return nil
+		},
+	},
+})
+
+const specPlugSnapYaml = `name: consumer
+version: 1
+apps:
+  app:
+    plugs: [test]
+hooks:
+  configure:
+    plugs: [test]
+plugs:
+  test:
+`
+
+const specSlotSnapYaml = `name: producer
+version: 1
+apps:
+  app:
+    slots: [test]
+slots:
+  test:
+`
+
+func (s *specSuite) SetUpTest(c *C) {
+	s.spec = &landlock.Specification{}
+	plugInfo := snaptest.MockInfo(c, specPlugSnapYaml, nil).Plugs["test"]
+	slotInfo := snaptest.MockInfo(c, specSlotSnapYaml, nil).Slots["test"]
+	s.plug = interfaces.NewConnectedPlug(plugInfo, nil, nil)
+	s.slot = interfaces.NewConnectedSlot(slotInfo, nil, nil)
+}
+
+func (s *specSuite) parseRules(c *C, tag string) []landlock_sandbox.Rule {
+	rs, err := landlock_sandbox.ParseRuleset(strings.NewReader(s.spec.SnippetForTag(tag)))
+	c.Assert(err, IsNil)
+	c.Check(rs.Enforced(), Equals, true)
+	return rs.Rules
+}
+
+// The spec.Specification can be used through the interfaces.Specification interface
+func (s *specSuite) TestSpecificationIface(c *C) {
+	var r interfaces.Specification = s.spec
+	c.Assert(r.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(r.AddConnectedSlot(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(r.AddPermanentPlug(s.iface, s.plug.Snap().Plugs["test"]), IsNil)
+	c.Assert(r.AddPermanentSlot(s.iface, s.slot.Snap().Slots["test"]), IsNil)
+
+	// the rules of a plug apply to the hooks as well
+	c.Check(s.spec.SecurityTags(), DeepEquals, []string{
+		"snap.consumer.app",
+		"snap.consumer.hook.configure",
+		"snap.producer.app",
+	})
+	for _, tag := range []string{"snap.consumer.app", "snap.consumer.hook.configure"} {
+		c.Check(s.parseRules(c, tag), DeepEquals, []landlock_sandbox.Rule{
+			{Access: landlock_sandbox.AccessRead, Path: "$HOME"},
+			{Access: landlock_sandbox.AccessRead | landlock_sandbox.AccessWrite, Path: "/media"},
+			{Access: landlock_sandbox.AccessRead, Path: "/run/media"},
+		}, Commentf("tag %s", tag))
+	}
+	c.Check(s.parseRules(c, "snap.producer.app"), DeepEquals, []landlock_sandbox.Rule{
+		{Access: landlock_sandbox.AccessRead | landlock_sandbox.AccessWrite, Path: "/run/slot"},
+		{Access: landlock_sandbox.AccessRead | landlock_sandbox.AccessExecute, Path: "/opt/slot"},
+	})
+
+	// snippets are kept as they were added
+	c.Check(s.spec.Snippets()["snap.producer.app"], DeepEquals, []string{"rw /run/slot", "rx /opt/slot"})
+}
+
+func (s *specSuite) TestSnippetsAreCopied(c *C) {
+	c.Assert(s.spec.AddConnectedSlot(s.iface, s.plug, s.slot), IsNil)
+	snippets := s.spec.Snippets()
+	snippets["snap.producer.app"][0] = "rwx /"
+	c.Check(s.parseRules(c, "snap.producer.app"), DeepEquals, []landlock_sandbox.Rule{
+		{Access: landlock_sandbox.AccessRead | landlock_sandbox.AccessWrite, Path: "/run/slot"},
+	})
+}
+
+func (s *specSuite) TestNoRules(c *C) {
+	// snippets added outside of a plug or slot do not apply to anything
+	s.spec.AddSnippet("rwx /")
+	c.Check(s.spec.SecurityTags(), HasLen, 0)
+	c.Check(s.spec.SnippetForTag("snap.consumer.app"), Equals, "")
+	c.Check(s.parseRules(c, "snap.consumer.app"), HasLen, 0)
+}
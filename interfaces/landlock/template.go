@@ -0,0 +1,65 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock
+
+// defaultTemplate contains the rules applied to all the applications and
+// hooks of strictly confined snaps. Landlock rules only ever grant access, so
+// the template lists the parts of the filesystem needed by all snaps while
+// leaving out user data, such as /home, /root, /media and /mnt, which is
+// made accessible by interfaces.
+//
+// Rules for paths which do not exist are ignored when applied, variables are
+// expanded by snap-exec from the environment of the snap, with $HOME being
+// the real home directory of the user.
+var defaultTemplate = []byte(`
+# Description: Allows access to the base snap and the snap itself, as well
+# as the private, per snap, writable areas.
+
+# base snap and system directories
+rx /bin
+rx /sbin
+rx /lib
+rx /lib32
+rx /lib64
+rx /libx32
+rx /usr
+rx /snap
+r /etc
+r /proc
+r /sys
+r /var/lib/snapd/desktop
+r /var/cache/fontconfig
+r /run/systemd/journal
+r /run/udev/data
+
+# devices, the device cgroup controls which ones can be used
+rw /dev
+
+# private temporary directories
+rw /tmp
+rw /var/tmp
+
+# writable areas of the snap
+rw $SNAP_DATA
+rw $SNAP_COMMON
+rw $SNAP_USER_DATA
+rw $SNAP_USER_COMMON
+rw $XDG_RUNTIME_DIR
+`)
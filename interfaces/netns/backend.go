@@ -0,0 +1,206 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package netns implements a backend which places snaps in private network
+// namespaces.
+//
+// Interfaces may request a snap to be isolated from the network of the host
+// with Specification.Isolate. In loopback mode the snap gets a network
+// namespace with just the loopback device. In allowlist mode the namespace is
+// linked with the host by a veth pair and an nftables ruleset loaded inside
+// the namespace drops all outgoing traffic except to the allowed
+// destinations. Services listening on the host are reachable directly,
+// reaching other destinations additionally requires IP forwarding and NAT
+// to be set up on the host by the administrator.
+//
+// The backend stores the description of the namespace of each isolated snap
+// in /var/lib/snapd/netns/snap.<name>.conf and maintains the namespace
+// itself with ip-netns(8), as /run/netns/snap.<name>. snap-confine joins
+// that namespace when starting applications and hooks of the snap, refusing
+// to run them if the description exists but the namespace does not.
+//
+// Isolation is not applied to snaps using devmode or classic confinement,
+// unless jailmode is requested.
+package netns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timings"
+)
+
+// Backend is responsible for maintaining network namespaces of snaps.
+type Backend struct {
+	preseed bool
+}
+
+// Initialize re-creates the network namespaces of isolated snaps, which do
+// not survive a reboot.
+func (b *Backend) Initialize(opts *interfaces.SecurityBackendOptions) error {
+	if opts != nil && opts.Preseed {
+		b.preseed = true
+		return nil
+	}
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapNetNSDir, "snap.*.conf"))
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		snapName := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), "snap."), ".conf")
+		// failing to restore the namespace of one snap should not
+		// affect snapd nor other snaps, snap-confine refuses to run
+		// the snap without its namespace anyway
+		if err := b.ensureNamespace(snapName); err != nil {
+			logger.Noticef("cannot restore network namespace of snap %q: %v", snapName, err)
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the backend.
+func (b *Backend) Name() interfaces.SecuritySystem {
+	return interfaces.SecurityNetNS
+}
+
+// Setup creates, updates or removes the network namespace of a given snap.
+//
+// The namespace is only re-created when its description changes. Processes
+// of the snap which are already running keep using the previous namespace.
+func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
+	snapName := snapInfo.InstanceName()
+	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	if err != nil {
+		return fmt.Errorf("cannot obtain network namespace specification for snap %q: %s", snapName, err)
+	}
+	old, err := readConfig(snapName)
+	if err != nil {
+		return err
+	}
+	content, err := deriveContent(spec.(*Specification), opts, snapName, old)
+	if err != nil {
+		return fmt.Errorf("cannot isolate network of snap %q: %s", snapName, err)
+	}
+
+	dir := dirs.SnapNetNSDir
+	if content != nil {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("cannot create directory for network namespaces %q: %s", dir, err)
+		}
+	}
+	changed, removed, err := osutil.EnsureDirState(dir, configFileName(snapName), content)
+	if err != nil {
+		return fmt.Errorf("cannot synchronize network namespaces for snap %q: %s", snapName, err)
+	}
+	if len(changed) > 0 || len(removed) > 0 {
+		if err := b.removeNamespace(snapName, old); err != nil {
+			return err
+		}
+	}
+	if content != nil {
+		return b.ensureNamespace(snapName)
+	}
+	return nil
+}
+
+// Remove removes the network namespace of a given snap.
+//
+// This method should be called after removing a snap.
+func (b *Backend) Remove(snapName string) error {
+	old, err := readConfig(snapName)
+	if err != nil {
+		// the namespace may be left behind but cannot be used anymore
+		logger.Noticef("%v", err)
+	}
+	_, _, err = osutil.EnsureDirState(dirs.SnapNetNSDir, configFileName(snapName), nil)
+	if err != nil {
+		return fmt.Errorf("cannot synchronize network namespaces for snap %q: %s", snapName, err)
+	}
+	return b.removeNamespace(snapName, old)
+}
+
+// deriveContent computes the description of the network namespace of a snap
+// into a content map applicable to EnsureDirState. The subnet allocated to
+// the previous description is kept, if any.
+func deriveContent(spec *Specification, opts interfaces.ConfinementOptions, snapName string, old *config) (map[string]osutil.FileState, error) {
+	if spec.Mode() == ModeHost || ((opts.DevMode || opts.Classic) && !opts.JailMode) {
+		return nil, nil
+	}
+	c := &config{mode: spec.Mode()}
+	if c.mode == ModeAllowlist {
+		if old != nil && old.mode == ModeAllowlist {
+			c.subnet = old.subnet
+		} else {
+			subnet, err := allocateSubnet(snapName)
+			if err != nil {
+				return nil, err
+			}
+			c.subnet = subnet
+		}
+		c.destinations = spec.Destinations()
+	}
+	return map[string]osutil.FileState{
+		configFileName(snapName): &osutil.MemoryFileState{
+			Content: c.content(),
+			Mode:    0644,
+		},
+	}, nil
+}
+
+// ensureNamespace creates the network namespace of a snap according to its
+// stored description, unless the namespace exists already.
+func (b *Backend) ensureNamespace(snapName string) error {
+	if b.preseed || osutil.FileExists(namespacePath(snapName)) {
+		return nil
+	}
+	c, err := readConfig(snapName)
+	if err != nil || c == nil {
+		return err
+	}
+	if err := createNamespace(snapName, c); err != nil {
+		return fmt.Errorf("cannot create network namespace of snap %q: %v", snapName, err)
+	}
+	return nil
+}
+
+func (b *Backend) removeNamespace(snapName string, c *config) error {
+	if b.preseed {
+		return nil
+	}
+	if err := removeNamespace(snapName, c); err != nil {
+		return fmt.Errorf("cannot remove network namespace of snap %q: %v", snapName, err)
+	}
+	return nil
+}
+
+func (b *Backend) NewSpecification() interfaces.Specification {
+	return &Specification{}
+}
+
+// SandboxFeatures returns the list of network namespace modes supported by snapd.
+func (b *Backend) SandboxFeatures() []string {
+	return []string{"mode:loopback", "mode:allowlist"}
+}
@@ -0,0 +1,292 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netns_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/netns"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type backendSuite struct {
+	ifacetest.BackendSuite
+
+	ipCmd *testutil.MockCmd
+}
+
+var _ = Suite(&backendSuite{})
+
+// mockIPScript pretends to manage network namespaces in the test root
+// directory and captures the nftables ruleset.
+const mockIPScript = `
+if [ "$1" = netns ] && [ "$2" = add ]; then mkdir -p %[1]s/netns && touch %[1]s/netns/"$3"; fi
+if [ "$1" = netns ] && [ "$2" = delete ]; then rm %[1]s/netns/"$3"; fi
+if [ "$1" = netns ] && [ "$2" = exec ]; then cat > %[1]s/ruleset; fi
+`
+
+func (s *backendSuite) SetUpTest(c *C) {
+	s.Backend = &netns.Backend{}
+	s.BackendSuite.SetUpTest(c)
+	c.Assert(s.Repo.AddBackend(s.Backend), IsNil)
+
+	s.ipCmd = testutil.MockCommand(c, "ip", fmt.Sprintf(mockIPScript, dirs.RunDir))
+	s.AddCleanup(s.ipCmd.Restore)
+}
+
+func (s *backendSuite) isolate(mode netns.Mode, destinations ...netns.Destination) {
+	s.Iface.NetNSPermanentSlotCallback = func(spec *netns.Specification, slot *snap.SlotInfo) error {
+		spec.Isolate(mode)
+		for _, d := range destinations {
+			spec.AllowDestination(d)
+		}
+		return nil
+	}
+}
+
+func (s *backendSuite) TestName(c *C) {
+	c.Check(s.Backend.Name(), Equals, interfaces.SecurityNetNS)
+}
+
+func (s *backendSuite) TestSandboxFeatures(c *C) {
+	c.Check(s.Backend.SandboxFeatures(), DeepEquals, []string{"mode:loopback", "mode:allowlist"})
+}
+
+func (s *backendSuite) TestInstallingSnapCreatesLoopbackNamespace(c *C) {
+	s.isolate(netns.ModeLoopback)
+	for _, opts := range []interfaces.ConfinementOptions{{}, {JailMode: true}, {DevMode: true, JailMode: true}} {
+		s.ipCmd.ForgetCalls()
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		c.Check(filepath.Join(dirs.SnapNetNSDir, "snap.samba.conf"), testutil.FileEquals, `# This file is automatically generated by snapd
+mode loopback
+`)
+		c.Check(filepath.Join(dirs.RunDir, "netns/snap.samba"), testutil.FilePresent)
+		c.Check(s.ipCmd.Calls(), DeepEquals, [][]string{
+			{"ip", "netns", "add", "snap.samba"},
+			{"ip", "-n", "snap.samba", "link", "set", "lo", "up"},
+		})
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestInstallingSnapCreatesAllowlistNamespace(c *C) {
+	s.isolate(netns.ModeAllowlist,
+		netns.Destination{Network: "192.168.0.0/16"},
+		netns.Destination{Network: "10.0.0.5/32", Protocol: "tcp", Port: 1883},
+		netns.Destination{Network: "10.0.0.6/32", Protocol: "udp"},
+		netns.Destination{Network: "10.0.0.7/32", Port: 53},
+	)
+	s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(filepath.Join(dirs.SnapNetNSDir, "snap.samba.conf"), testutil.FileEquals, `# This file is automatically generated by snapd
+mode allowlist
+subnet 0
+allow 10.0.0.5/32 tcp 1883
+allow 10.0.0.6/32 udp 0
+allow 10.0.0.7/32 any 53
+allow 192.168.0.0/16 any 0
+`)
+	c.Check(s.ipCmd.Calls(), DeepEquals, [][]string{
+		{"ip", "netns", "add", "snap.samba"},
+		{"ip", "-n", "snap.samba", "link", "set", "lo", "up"},
+		{"ip", "link", "add", "snapns0", "type", "veth", "peer", "name", "eth0", "netns", "snap.samba"},
+		{"ip", "addr", "add", "169.254.200.1/30", "dev", "snapns0"},
+		{"ip", "link", "set", "snapns0", "up"},
+		{"ip", "-n", "snap.samba", "addr", "add", "169.254.200.2/30", "dev", "eth0"},
+		{"ip", "-n", "snap.samba", "link", "set", "eth0", "up"},
+		{"ip", "-n", "snap.samba", "route", "add", "default", "via", "169.254.200.1"},
+		{"ip", "netns", "exec", "snap.samba", "nft", "-f", "-"},
+	})
+	c.Check(filepath.Join(dirs.RunDir, "ruleset"), testutil.FileEquals, `table inet snapd {
+	chain input {
+		type filter hook input priority 0; policy drop;
+		iif "lo" accept
+		ct state established,related accept
+	}
+	chain output {
+		type filter hook output priority 0; policy drop;
+		oif "lo" accept
+		ct state established,related accept
+		ip daddr 10.0.0.5/32 tcp dport 1883 accept
+		ip daddr 10.0.0.6/32 meta l4proto udp accept
+		ip daddr 10.0.0.7/32 meta l4proto { tcp, udp } th dport 53 accept
+		ip daddr 192.168.0.0/16 accept
+	}
+}
+`)
+}
+
+func (s *backendSuite) TestSubnetAllocation(c *C) {
+	s.isolate(netns.ModeAllowlist)
+	first := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	s.InstallSnap(c, interfaces.ConfinementOptions{}, "samba_foo", ifacetest.SambaYamlV1, 0)
+	c.Check(filepath.Join(dirs.SnapNetNSDir, "snap.samba_foo.conf"), testutil.FileContains, "subnet 1\n")
+
+	// the subnet is kept across updates
+	first = s.UpdateSnap(c, first, interfaces.ConfinementOptions{}, ifacetest.SambaYamlV1, 1)
+	c.Check(filepath.Join(dirs.SnapNetNSDir, "snap.samba.conf"), testutil.FileContains, "subnet 0\n")
+
+	// and reused once freed
+	s.RemoveSnap(c, first)
+	s.InstallSnap(c, interfaces.ConfinementOptions{}, "samba_bar", ifacetest.SambaYamlV1, 0)
+	c.Check(filepath.Join(dirs.SnapNetNSDir, "snap.samba_bar.conf"), testutil.FileContains, "subnet 0\n")
+}
+
+func (s *backendSuite) TestNotIsolatedWithoutConfinement(c *C) {
+	s.isolate(netns.ModeLoopback)
+	for _, opts := range []interfaces.ConfinementOptions{{DevMode: true}, {Classic: true}} {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		c.Check(filepath.Join(dirs.SnapNetNSDir, "snap.samba.conf"), testutil.FileAbsent)
+		s.RemoveSnap(c, snapInfo)
+	}
+	c.Check(s.ipCmd.Calls(), HasLen, 0)
+}
+
+func (s *backendSuite) TestNotIsolated(c *C) {
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	s.RemoveSnap(c, snapInfo)
+	c.Check(dirs.SnapNetNSDir, testutil.FileAbsent)
+	c.Check(s.ipCmd.Calls(), HasLen, 0)
+}
+
+func (s *backendSuite) TestRemovingSnapRemovesNamespace(c *C) {
+	s.isolate(netns.ModeAllowlist)
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Assert(os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/sys/class/net/snapns0"), 0755), IsNil)
+
+	s.ipCmd.ForgetCalls()
+	s.RemoveSnap(c, snapInfo)
+	c.Check(filepath.Join(dirs.SnapNetNSDir, "snap.samba.conf"), testutil.FileAbsent)
+	c.Check(filepath.Join(dirs.RunDir, "netns/snap.samba"), testutil.FileAbsent)
+	c.Check(s.ipCmd.Calls(), DeepEquals, [][]string{
+		{"ip", "link", "delete", "snapns0"},
+		{"ip", "netns", "delete", "snap.samba"},
+	})
+}
+
+func (s *backendSuite) TestUpdatingSnapRecreatesChangedNamespace(c *C) {
+	s.isolate(netns.ModeLoopback)
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+
+	// same description, nothing to do
+	s.ipCmd.ForgetCalls()
+	snapInfo = s.UpdateSnap(c, snapInfo, interfaces.ConfinementOptions{}, ifacetest.SambaYamlV1, 1)
+	c.Check(s.ipCmd.Calls(), HasLen, 0)
+
+	// a missing namespace is created again
+	c.Assert(os.Remove(filepath.Join(dirs.RunDir, "netns/snap.samba")), IsNil)
+	snapInfo = s.UpdateSnap(c, snapInfo, interfaces.ConfinementOptions{}, ifacetest.SambaYamlV1, 2)
+	c.Check(s.ipCmd.Calls(), DeepEquals, [][]string{
+		{"ip", "netns", "add", "snap.samba"},
+		{"ip", "-n", "snap.samba", "link", "set", "lo", "up"},
+	})
+
+	// changed description, the namespace is re-created
+	s.isolate(netns.ModeAllowlist)
+	s.ipCmd.ForgetCalls()
+	snapInfo = s.UpdateSnap(c, snapInfo, interfaces.ConfinementOptions{}, ifacetest.SambaYamlV1, 3)
+	calls := s.ipCmd.Calls()
+	c.Assert(len(calls) > 2, Equals, true)
+	c.Check(calls[:2], DeepEquals, [][]string{
+		{"ip", "netns", "delete", "snap.samba"},
+		{"ip", "netns", "add", "snap.samba"},
+	})
+
+	// no longer isolated
+	s.Iface.NetNSPermanentSlotCallback = nil
+	s.ipCmd.ForgetCalls()
+	s.UpdateSnap(c, snapInfo, interfaces.ConfinementOptions{}, ifacetest.SambaYamlV1, 4)
+	c.Check(filepath.Join(dirs.SnapNetNSDir, "snap.samba.conf"), testutil.FileAbsent)
+	c.Check(s.ipCmd.Calls(), DeepEquals, [][]string{
+		{"ip", "netns", "delete", "snap.samba"},
+	})
+}
+
+func (s *backendSuite) TestSetupCreateError(c *C) {
+	s.ipCmd = testutil.MockCommand(c, "ip", `if [ "$1" = "-n" ]; then echo failed; exit 1; fi`)
+	s.isolate(netns.ModeLoopback)
+	snapInfo := snaptest.MockInfo(c, ifacetest.SambaYamlV1, nil)
+	c.Assert(s.Repo.AddSnap(snapInfo), IsNil)
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, timings.New(nil))
+	c.Assert(err, ErrorMatches, `cannot create network namespace of snap "samba": cannot run "ip -n snap.samba link set lo up": failed`)
+}
+
+func (s *backendSuite) TestSetupCreateErrorCleansUp(c *C) {
+	s.ipCmd = testutil.MockCommand(c, "ip", fmt.Sprintf(mockIPScript, dirs.RunDir)+`
+if [ "$1" = "-n" ]; then echo failed; exit 1; fi`)
+	s.isolate(netns.ModeLoopback)
+	snapInfo := snaptest.MockInfo(c, ifacetest.SambaYamlV1, nil)
+	c.Assert(s.Repo.AddSnap(snapInfo), IsNil)
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, timings.New(nil))
+	c.Assert(err, NotNil)
+	c.Check(filepath.Join(dirs.RunDir, "netns/snap.samba"), testutil.FileAbsent)
+	c.Check(s.ipCmd.Calls(), DeepEquals, [][]string{
+		{"ip", "netns", "add", "snap.samba"},
+		{"ip", "-n", "snap.samba", "link", "set", "lo", "up"},
+		{"ip", "netns", "delete", "snap.samba"},
+	})
+}
+
+func (s *backendSuite) TestInitializeRestoresNamespaces(c *C) {
+	c.Assert(os.MkdirAll(dirs.SnapNetNSDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapNetNSDir, "snap.foo.conf"), []byte("mode loopback\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapNetNSDir, "snap.bar.conf"), []byte("mode bogus\n"), 0644), IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(dirs.RunDir, "netns"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.RunDir, "netns/snap.baz"), nil, 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapNetNSDir, "snap.baz.conf"), []byte("mode loopback\n"), 0644), IsNil)
+
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	c.Assert(s.Backend.Initialize(nil), IsNil)
+	c.Check(s.ipCmd.Calls(), DeepEquals, [][]string{
+		{"ip", "netns", "add", "snap.foo"},
+		{"ip", "-n", "snap.foo", "link", "set", "lo", "up"},
+	})
+	c.Check(logbuf.String(), Matches, `(?s).*cannot restore network namespace of snap "bar": cannot parse .*/snap.bar.conf: line 1: invalid mode "bogus".*`)
+}
+
+func (s *backendSuite) TestPreseedSkipsNamespaces(c *C) {
+	c.Assert(os.MkdirAll(dirs.SnapNetNSDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapNetNSDir, "snap.foo.conf"), []byte("mode loopback\n"), 0644), IsNil)
+	c.Assert(s.Backend.Initialize(&interfaces.SecurityBackendOptions{Preseed: true}), IsNil)
+
+	s.isolate(netns.ModeLoopback)
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(filepath.Join(dirs.SnapNetNSDir, "snap.samba.conf"), testutil.FilePresent)
+	s.RemoveSnap(c, snapInfo)
+	c.Check(s.ipCmd.Calls(), HasLen, 0)
+}
@@ -0,0 +1,243 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netns
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+)
+
+// maxSubnets is the number of /30 subnets available in the range used to
+// link network namespaces in allowlist mode with the host.
+const maxSubnets = 256
+
+// subnetBase is the first address of the 169.254.200.0/22 range.
+var subnetBase = net.IPv4(169, 254, 200, 0).To4()
+
+// config is the persistent description of the network namespace of a snap.
+//
+// It is stored in /var/lib/snapd/netns/snap.<name>.conf and contains enough
+// information to re-create the namespace, which does not survive a reboot.
+type config struct {
+	mode Mode
+	// subnet is the index of the subnet linking the namespace with the
+	// host, only meaningful in allowlist mode.
+	subnet       int
+	destinations []Destination
+}
+
+// namespaceName returns the name of the network namespace of a snap.
+func namespaceName(snapName string) string {
+	return "snap." + snapName
+}
+
+// namespacePath returns the path where ip-netns(8) keeps the network
+// namespace of a snap.
+func namespacePath(snapName string) string {
+	return filepath.Join(dirs.RunDir, "netns", namespaceName(snapName))
+}
+
+func configFileName(snapName string) string {
+	return namespaceName(snapName) + ".conf"
+}
+
+// hostInterface returns the name of the host side of the veth pair linking
+// the namespace with the host.
+func (c *config) hostInterface() string {
+	return fmt.Sprintf("snapns%d", c.subnet)
+}
+
+func (c *config) address(offset int) string {
+	ip := make(net.IP, len(subnetBase))
+	copy(ip, subnetBase)
+	n := c.subnet*4 + offset
+	ip[2] += byte(n / 256)
+	ip[3] += byte(n % 256)
+	return ip.String()
+}
+
+// hostAddress returns the address of the host in the subnet of the namespace.
+func (c *config) hostAddress() string {
+	return c.address(1)
+}
+
+// namespaceAddress returns the address of the namespace in its subnet.
+func (c *config) namespaceAddress() string {
+	return c.address(2)
+}
+
+func (c *config) content() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# This file is automatically generated by snapd\n")
+	fmt.Fprintf(&buf, "mode %s\n", c.mode)
+	if c.mode == ModeAllowlist {
+		fmt.Fprintf(&buf, "subnet %d\n", c.subnet)
+		for _, d := range c.destinations {
+			fmt.Fprintf(&buf, "allow %s\n", d)
+		}
+	}
+	return buf.Bytes()
+}
+
+// ruleset returns the nftables ruleset loaded into the namespace in
+// allowlist mode. Only the loopback device, replies and the allowed
+// destinations are permitted.
+func (c *config) ruleset() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("table inet snapd {\n")
+	buf.WriteString("\tchain input {\n")
+	buf.WriteString("\t\ttype filter hook input priority 0; policy drop;\n")
+	buf.WriteString("\t\tiif \"lo\" accept\n")
+	buf.WriteString("\t\tct state established,related accept\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tchain output {\n")
+	buf.WriteString("\t\ttype filter hook output priority 0; policy drop;\n")
+	buf.WriteString("\t\toif \"lo\" accept\n")
+	buf.WriteString("\t\tct state established,related accept\n")
+	for _, d := range c.destinations {
+		buf.WriteString("\t\tip daddr " + d.Network)
+		switch {
+		case d.Protocol != "" && d.Port != 0:
+			fmt.Fprintf(&buf, " %s dport %d", d.Protocol, d.Port)
+		case d.Protocol != "":
+			fmt.Fprintf(&buf, " meta l4proto %s", d.Protocol)
+		case d.Port != 0:
+			fmt.Fprintf(&buf, " meta l4proto { tcp, udp } th dport %d", d.Port)
+		}
+		buf.WriteString(" accept\n")
+	}
+	buf.WriteString("\t}\n")
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func parseMode(s string) (Mode, error) {
+	switch s {
+	case "allowlist":
+		return ModeAllowlist, nil
+	case "loopback":
+		return ModeLoopback, nil
+	}
+	return ModeHost, fmt.Errorf("invalid mode %q", s)
+}
+
+func parseConfig(r io.Reader) (*config, error) {
+	c := &config{}
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		var err error
+		switch {
+		case fields[0] == "mode" && len(fields) == 2:
+			c.mode, err = parseMode(fields[1])
+		case fields[0] == "subnet" && len(fields) == 2:
+			c.subnet, err = strconv.Atoi(fields[1])
+			if err == nil && (c.subnet < 0 || c.subnet >= maxSubnets) {
+				err = fmt.Errorf("subnet %d out of range", c.subnet)
+			}
+		case fields[0] == "allow" && len(fields) == 4:
+			var port int
+			var d Destination
+			protocol := fields[2]
+			if protocol == "any" {
+				protocol = ""
+			}
+			port, err = strconv.Atoi(fields[3])
+			if err == nil {
+				d, err = NewDestination(fields[1], protocol, port)
+				c.destinations = append(c.destinations, d)
+			}
+		default:
+			err = fmt.Errorf("cannot parse %q", line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if c.mode == ModeHost {
+		return nil, fmt.Errorf("mode not specified")
+	}
+	return c, nil
+}
+
+// readConfig reads the persistent configuration of the network namespace of
+// a snap. Nil is returned if the snap is not isolated.
+func readConfig(snapName string) (*config, error) {
+	f, err := os.Open(filepath.Join(dirs.SnapNetNSDir, configFileName(snapName)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := parseConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", f.Name(), err)
+	}
+	return c, nil
+}
+
+// allocateSubnet returns the lowest subnet not used by the network namespace
+// of any other snap.
+func allocateSubnet(snapName string) (int, error) {
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapNetNSDir, "snap.*.conf"))
+	if err != nil {
+		return 0, err
+	}
+	used := make(map[int]bool, len(matches))
+	for _, match := range matches {
+		if filepath.Base(match) == configFileName(snapName) {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), "snap."), ".conf")
+		c, err := readConfig(name)
+		if err != nil {
+			return 0, err
+		}
+		if c.mode == ModeAllowlist {
+			used[c.subnet] = true
+		}
+	}
+	for i := 0; i < maxSubnets; i++ {
+		if !used[i] {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no free subnets left for isolated network namespaces")
+}
@@ -0,0 +1,101 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netns
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+)
+
+func runCommand(stdin []byte, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot run %q: %v", name+" "+strings.Join(args, " "), osutil.OutputErr(output, err))
+	}
+	return nil
+}
+
+func hostInterfaceExists(name string) bool {
+	return osutil.FileExists(filepath.Join(dirs.GlobalRootDir, "/sys/class/net", name))
+}
+
+// createNamespace creates the network namespace of a snap as described by
+// the given configuration. A partially created namespace is removed again.
+func createNamespace(snapName string, c *config) (err error) {
+	ns := namespaceName(snapName)
+	if err := runCommand(nil, "ip", "netns", "add", ns); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			removeNamespace(snapName, c)
+		}
+	}()
+	if err := runCommand(nil, "ip", "-n", ns, "link", "set", "lo", "up"); err != nil {
+		return err
+	}
+	if c.mode != ModeAllowlist {
+		return nil
+	}
+
+	veth := c.hostInterface()
+	for _, args := range [][]string{
+		{"link", "add", veth, "type", "veth", "peer", "name", "eth0", "netns", ns},
+		{"addr", "add", c.hostAddress() + "/30", "dev", veth},
+		{"link", "set", veth, "up"},
+		{"-n", ns, "addr", "add", c.namespaceAddress() + "/30", "dev", "eth0"},
+		{"-n", ns, "link", "set", "eth0", "up"},
+		{"-n", ns, "route", "add", "default", "via", c.hostAddress()},
+	} {
+		if err := runCommand(nil, "ip", args...); err != nil {
+			return err
+		}
+	}
+	return runCommand(c.ruleset(), "ip", "netns", "exec", ns, "nft", "-f", "-")
+}
+
+// removeNamespace removes the network namespace of a snap, along with the
+// veth pair linking it with the host. The configuration may be nil when not
+// known.
+//
+// Processes of the snap which are still running keep using the namespace
+// until they terminate.
+func removeNamespace(snapName string, c *config) error {
+	var firstErr error
+	if c != nil && c.mode == ModeAllowlist && hostInterfaceExists(c.hostInterface()) {
+		// removing one end of the veth pair removes the other one too
+		firstErr = runCommand(nil, "ip", "link", "delete", c.hostInterface())
+	}
+	if osutil.FileExists(namespacePath(snapName)) {
+		if err := runCommand(nil, "ip", "netns", "delete", namespaceName(snapName)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
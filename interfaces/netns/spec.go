@@ -0,0 +1,189 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netns
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Mode describes the network namespace a snap is placed in.
+type Mode int
+
+const (
+	// ModeHost leaves the snap in the network namespace of the host.
+	ModeHost Mode = iota
+	// ModeAllowlist places the snap in a private network namespace
+	// connected to the host, where outgoing traffic is restricted to a
+	// list of allowed destinations.
+	ModeAllowlist
+	// ModeLoopback places the snap in a private network namespace with
+	// just the loopback device.
+	ModeLoopback
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeHost:
+		return "host"
+	case ModeAllowlist:
+		return "allowlist"
+	case ModeLoopback:
+		return "loopback"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// Destination describes network traffic allowed to leave an isolated
+// network namespace.
+type Destination struct {
+	// Network is the destination network in CIDR notation.
+	Network string
+	// Protocol is either "tcp" or "udp", empty means both.
+	Protocol string
+	// Port is the destination port, zero means any port.
+	Port int
+}
+
+// NewDestination returns a validated destination with the network in
+// canonical form.
+func NewDestination(network, protocol string, port int) (Destination, error) {
+	_, ipnet, err := net.ParseCIDR(network)
+	if err != nil {
+		return Destination{}, fmt.Errorf("invalid destination network %q", network)
+	}
+	// the namespace is only linked with the host using IPv4
+	if ipnet.IP.To4() == nil {
+		return Destination{}, fmt.Errorf("invalid destination network %q: only IPv4 is supported", network)
+	}
+	switch protocol {
+	case "", "tcp", "udp":
+	default:
+		return Destination{}, fmt.Errorf("invalid destination protocol %q", protocol)
+	}
+	if port < 0 || port > 65535 {
+		return Destination{}, fmt.Errorf("invalid destination port %d", port)
+	}
+	return Destination{Network: ipnet.String(), Protocol: protocol, Port: port}, nil
+}
+
+func (d Destination) String() string {
+	protocol := d.Protocol
+	if protocol == "" {
+		protocol = "any"
+	}
+	return fmt.Sprintf("%s %s %d", d.Network, protocol, d.Port)
+}
+
+// Specification assists in collecting the network isolation requirements
+// of a snap.
+//
+// Unlike most other specifications this one applies to the snap as a whole,
+// all applications and hooks of a snap share a single network namespace.
+type Specification struct {
+	mode         Mode
+	destinations map[Destination]bool
+}
+
+// Isolate requests the snap to be placed in a private network namespace.
+//
+// When isolation is requested more than once the most restrictive mode is
+// used, that is, loopback mode takes precedence over allowlist mode.
+func (spec *Specification) Isolate(mode Mode) {
+	if mode > spec.mode {
+		spec.mode = mode
+	}
+}
+
+// AllowDestination adds a destination which may be reached from the network
+// namespace in allowlist mode.
+func (spec *Specification) AllowDestination(d Destination) {
+	if spec.destinations == nil {
+		spec.destinations = make(map[Destination]bool)
+	}
+	spec.destinations[d] = true
+}
+
+// Mode returns the requested network namespace mode.
+func (spec *Specification) Mode() Mode {
+	return spec.mode
+}
+
+// Destinations returns the sorted list of allowed destinations.
+func (spec *Specification) Destinations() []Destination {
+	result := make([]Destination, 0, len(spec.destinations))
+	for d := range spec.destinations {
+		result = append(result, d)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].String() < result[j].String()
+	})
+	return result
+}
+
+// Implementation of methods required by interfaces.Specification
+
+// AddConnectedPlug records netns-specific side-effects of having a connected plug.
+func (spec *Specification) AddConnectedPlug(iface interfaces.Interface, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	type definer interface {
+		NetNSConnectedPlug(spec *Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	}
+	if iface, ok := iface.(definer); ok {
+		return iface.NetNSConnectedPlug(spec, plug, slot)
+	}
+	return nil
+}
+
+// AddConnectedSlot records netns-specific side-effects of having a connected slot.
+func (spec *Specification) AddConnectedSlot(iface interfaces.Interface, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	type definer interface {
+		NetNSConnectedSlot(spec *Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	}
+	if iface, ok := iface.(definer); ok {
+		return iface.NetNSConnectedSlot(spec, plug, slot)
+	}
+	return nil
+}
+
+// AddPermanentPlug records netns-specific side-effects of having a plug.
+func (spec *Specification) AddPermanentPlug(iface interfaces.Interface, plug *snap.PlugInfo) error {
+	type definer interface {
+		NetNSPermanentPlug(spec *Specification, plug *snap.PlugInfo) error
+	}
+	if iface, ok := iface.(definer); ok {
+		return iface.NetNSPermanentPlug(spec, plug)
+	}
+	return nil
+}
+
+// AddPermanentSlot records netns-specific side-effects of having a slot.
+func (spec *Specification) AddPermanentSlot(iface interfaces.Interface, slot *snap.SlotInfo) error {
+	type definer interface {
+		NetNSPermanentSlot(spec *Specification, slot *snap.SlotInfo) error
+	}
+	if iface, ok := iface.(definer); ok {
+		return iface.NetNSPermanentSlot(spec, slot)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netns_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/netns"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+type specSuite struct {
+	spec *netns.Specification
+}
+
+var _ = Suite(&specSuite{})
+
+func (s *specSuite) SetUpTest(c *C) {
+	s.spec = &netns.Specification{}
+}
+
+func (s *specSuite) TestIsolate(c *C) {
+	c.Check(s.spec.Mode(), Equals, netns.ModeHost)
+	s.spec.Isolate(netns.ModeAllowlist)
+	c.Check(s.spec.Mode(), Equals, netns.ModeAllowlist)
+	s.spec.Isolate(netns.ModeLoopback)
+	c.Check(s.spec.Mode(), Equals, netns.ModeLoopback)
+	// the most restrictive mode wins
+	s.spec.Isolate(netns.ModeAllowlist)
+	c.Check(s.spec.Mode(), Equals, netns.ModeLoopback)
+}
+
+func (s *specSuite) TestDestinations(c *C) {
+	c.Check(s.spec.Destinations(), HasLen, 0)
+	s.spec.AllowDestination(netns.Destination{Network: "10.0.0.2/32", Protocol: "tcp", Port: 80})
+	s.spec.AllowDestination(netns.Destination{Network: "10.0.0.1/32"})
+	s.spec.AllowDestination(netns.Destination{Network: "10.0.0.2/32", Protocol: "tcp", Port: 80})
+	c.Check(s.spec.Destinations(), DeepEquals, []netns.Destination{
+		{Network: "10.0.0.1/32"},
+		{Network: "10.0.0.2/32", Protocol: "tcp", Port: 80},
+	})
+}
+
+func (s *specSuite) TestNewDestination(c *C) {
+	d, err := netns.NewDestination("10.1.2.3/8", "udp", 53)
+	c.Assert(err, IsNil)
+	c.Check(d, Equals, netns.Destination{Network: "10.0.0.0/8", Protocol: "udp", Port: 53})
+	c.Check(d.String(), Equals, "10.0.0.0/8 udp 53")
+
+	d, err = netns.NewDestination("10.1.2.3/32", "", 0)
+	c.Assert(err, IsNil)
+	c.Check(d.String(), Equals, "10.1.2.3/32 any 0")
+
+	for _, tc := range []struct {
+		network, protocol string
+		port              int
+		err               string
+	}{
+		{"10.1.2.3", "", 0, `invalid destination network "10.1.2.3"`},
+		{"fd00::/8", "", 0, `invalid destination network "fd00::/8": only IPv4 is supported`},
+		{"10.0.0.0/8", "sctp", 0, `invalid destination protocol "sctp"`},
+		{"10.0.0.0/8", "tcp", -1, `invalid destination port -1`},
+		{"10.0.0.0/8", "tcp", 65536, `invalid destination port 65536`},
+	} {
+		_, err := netns.NewDestination(tc.network, tc.protocol, tc.port)
+		c.Check(err, ErrorMatches, tc.err)
+	}
+}
+
+func (s *specSuite) TestModeString(c *C) {
+	c.Check(netns.ModeHost.String(), Equals, "host")
+	c.Check(netns.ModeAllowlist.String(), Equals, "allowlist")
+	c.Check(netns.ModeLoopback.String(), Equals, "loopback")
+	c.Check(netns.Mode(42).String(), Equals, "Mode(42)")
+}
+
+func (s *specSuite) TestSpecificationIface(c *C) {
+	iface := &ifacetest.TestInterface{
+		InterfaceName: "test",
+		NetNSConnectedPlugCallback: func(spec *netns.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.Isolate(netns.ModeAllowlist)
+			spec.AllowDestination(netns.Destination{Network: "10.0.0.1/32"})
+			return nil
+		},
+		NetNSConnectedSlotCallback: func(spec *netns.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AllowDestination(netns.Destination{Network: "10.0.0.2/32"})
+			return nil
+		},
+		NetNSPermanentPlugCallback: func(spec *netns.Specification, plug *snap.PlugInfo) error {
+			spec.AllowDestination(netns.Destination{Network: "10.0.0.3/32"})
+			return nil
+		},
+		NetNSPermanentSlotCallback: func(spec *netns.Specification, slot *snap.SlotInfo) error {
+			spec.AllowDestination(netns.Destination{Network: "10.0.0.4/32"})
+			return nil
+		},
+	}
+	info := snaptest.MockInfo(c, `name: snap
+version: 0
+plugs:
+ plug:
+  interface: test
+slots:
+ slot:
+  interface: test
+`, nil)
+	plugInfo := info.Plugs["plug"]
+	slotInfo := info.Slots["slot"]
+	plug := interfaces.NewConnectedPlug(plugInfo, nil, nil)
+	slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+
+	c.Assert(s.spec.AddConnectedPlug(iface, plug, slot), IsNil)
+	c.Assert(s.spec.AddConnectedSlot(iface, plug, slot), IsNil)
+	c.Assert(s.spec.AddPermanentPlug(iface, plugInfo), IsNil)
+	c.Assert(s.spec.AddPermanentSlot(iface, slotInfo), IsNil)
+	c.Check(s.spec.Mode(), Equals, netns.ModeAllowlist)
+	c.Check(s.spec.Destinations(), DeepEquals, []netns.Destination{
+		{Network: "10.0.0.1/32"},
+		{Network: "10.0.0.2/32"},
+		{Network: "10.0.0.3/32"},
+		{Network: "10.0.0.4/32"},
+	})
+}
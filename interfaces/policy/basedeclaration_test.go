@@ -163,6 +163,7 @@ func (s *baseDeclSuite) TestAutoConnection(c *C) {
 		"mir":                     true,
 		"network":                 true,
 		"network-bind":            true,
+		"network-isolation":       true,
 		"network-status":          true,
 		"online-accounts-service": true,
 		"opengl":                  true,
@@ -802,6 +803,7 @@ var (
 		"mount-control":             {"core"},
 		"mpris":                     {"app"},
 		"netlink-driver":            {"core", "gadget"},
+		"network-interface":         {"core", "gadget"},
 		"network-manager":           {"app", "core"},
 		"network-manager-observe":   {"app", "core"},
 		"network-status":            {"core"},
@@ -1528,6 +1530,55 @@ plugs:
 	}
 }
 
+func (s *baseDeclSuite) TestNetworkIsolationAllowlist(c *C) {
+	const loopbackYaml = `name: plug-snap
+version: 0
+plugs:
+  network-isolation:
+    mode: loopback
+`
+	cand := s.connectCand(c, "network-isolation", "", loopbackYaml)
+	c.Check(cand.Check(), IsNil)
+	_, err := cand.CheckAutoConnect()
+	c.Check(err, IsNil)
+
+	// the snap picks its allowed destinations itself
+	const allowlistYaml = `name: plug-snap
+version: 0
+plugs:
+  network-isolation:
+    mode: allowlist
+    allow:
+      - destination: %s
+        protocol: tcp
+        port: 1883
+`
+	cand = s.connectCand(c, "network-isolation", "", fmt.Sprintf(allowlistYaml, "10.0.0.5/32"))
+	// the administrator can connect it
+	c.Check(cand.Check(), IsNil)
+	_, err = cand.CheckAutoConnect()
+	c.Check(err, ErrorMatches, `auto-connection denied by slot rule of interface "network-isolation"`)
+
+	// the snap declaration can allow auto-connection for given destinations
+	plugsSlots := `
+plugs:
+  network-isolation:
+    allow-auto-connection:
+      plug-attributes:
+        allow:
+          destination: 10\.0\.0\.5/32
+`
+	snapDecl := s.mockSnapDecl(c, "plug-snap", "J60k4JY0HppjwOjW8dZdYc8obXKxujRu", "canonical", plugsSlots)
+	cand.PlugSnapDeclaration = snapDecl
+	_, err = cand.CheckAutoConnect()
+	c.Check(err, IsNil)
+
+	cand = s.connectCand(c, "network-isolation", "", fmt.Sprintf(allowlistYaml, "0.0.0.0/0"))
+	cand.PlugSnapDeclaration = snapDecl
+	_, err = cand.CheckAutoConnect()
+	c.Check(err, ErrorMatches, `auto-connection not allowed by plug rule of interface "network-isolation" for "plug-snap" snap`)
+}
+
 func (s *baseDeclSuite) TestRawVolumeOverride(c *C) {
 	slotYaml := `name: slot-snap
 type: gadget
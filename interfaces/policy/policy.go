@@ -76,28 +76,40 @@ func (ic *InstallCandidate) checkPlugRule(plug *snap.PlugInfo, rule *asserts.Plu
 	return nil
 }
 
-func (ic *InstallCandidate) checkSlot(slot *snap.SlotInfo) error {
+// slotRule returns the rule deciding the installation of the slot, and
+// whether it comes from the snap declaration.
+func (ic *InstallCandidate) slotRule(slot *snap.SlotInfo) (rule *asserts.SlotRule, snapRule bool) {
 	iface := slot.Interface
 	if snapDecl := ic.SnapDeclaration; snapDecl != nil {
 		if rule := snapDecl.SlotRule(iface); rule != nil {
-			return ic.checkSlotRule(slot, rule, true)
+			return rule, true
 		}
 	}
-	if rule := ic.BaseDeclaration.SlotRule(iface); rule != nil {
-		return ic.checkSlotRule(slot, rule, false)
-	}
-	return nil
+	return ic.BaseDeclaration.SlotRule(iface), false
 }
 
-func (ic *InstallCandidate) checkPlug(plug *snap.PlugInfo) error {
+// plugRule returns the rule deciding the installation of the plug, and
+// whether it comes from the snap declaration.
+func (ic *InstallCandidate) plugRule(plug *snap.PlugInfo) (rule *asserts.PlugRule, snapRule bool) {
 	iface := plug.Interface
 	if snapDecl := ic.SnapDeclaration; snapDecl != nil {
 		if rule := snapDecl.PlugRule(iface); rule != nil {
-			return ic.checkPlugRule(plug, rule, true)
+			return rule, true
 		}
 	}
-	if rule := ic.BaseDeclaration.PlugRule(iface); rule != nil {
-		return ic.checkPlugRule(plug, rule, false)
+	return ic.BaseDeclaration.PlugRule(iface), false
+}
+
+func (ic *InstallCandidate) checkSlot(slot *snap.SlotInfo) error {
+	if rule, snapRule := ic.slotRule(slot); rule != nil {
+		return ic.checkSlotRule(slot, rule, snapRule)
+	}
+	return nil
+}
+
+func (ic *InstallCandidate) checkPlug(plug *snap.PlugInfo) error {
+	if rule, snapRule := ic.plugRule(plug); rule != nil {
+		return ic.checkPlugRule(plug, rule, snapRule)
 	}
 	return nil
 }
@@ -125,6 +137,41 @@ func (ic *InstallCandidate) Check() error {
 	return nil
 }
 
+// CheckSlot checks whether the installation of the given slot of the snap
+// is allowed. It returns a description of the deciding rule, if any.
+func (ic *InstallCandidate) CheckSlot(slot *snap.SlotInfo) (rule string, err error) {
+	if ic.BaseDeclaration == nil {
+		return "", fmt.Errorf("internal error: improperly initialized InstallCandidate")
+	}
+	slotRule, snapRule := ic.slotRule(slot)
+	if slotRule == nil {
+		return "", nil
+	}
+	return describeRule("slot", slot.Interface, ic.SnapDeclaration, snapRule), ic.checkSlotRule(slot, slotRule, snapRule)
+}
+
+// CheckPlug checks whether the installation of the given plug of the snap
+// is allowed. It returns a description of the deciding rule, if any.
+func (ic *InstallCandidate) CheckPlug(plug *snap.PlugInfo) (rule string, err error) {
+	if ic.BaseDeclaration == nil {
+		return "", fmt.Errorf("internal error: improperly initialized InstallCandidate")
+	}
+	plugRule, snapRule := ic.plugRule(plug)
+	if plugRule == nil {
+		return "", nil
+	}
+	return describeRule("plug", plug.Interface, ic.SnapDeclaration, snapRule), ic.checkPlugRule(plug, plugRule, snapRule)
+}
+
+// describeRule describes a plug or slot rule of an interface, coming either
+// from the given snap declaration or from the base declaration.
+func describeRule(side, iface string, snapDecl *asserts.SnapDeclaration, snapRule bool) string {
+	if snapRule {
+		return fmt.Sprintf("%s rule of interface %q in the snap-declaration of %q", side, iface, snapDecl.SnapName())
+	}
+	return fmt.Sprintf("%s rule of interface %q in the base-declaration", side, iface)
+}
+
 // ConnectCandidate represents a candidate connection.
 type ConnectCandidate struct {
 	Plug                *interfaces.ConnectedPlug
@@ -239,23 +286,56 @@ func (connc *ConnectCandidate) check(kind string) (interfaces.SideArity, error)
 		return nil, fmt.Errorf("cannot connect mismatched plug interface %q to slot interface %q", iface, connc.Slot.Interface())
 	}
 
+	plugRule, slotRule, snapRule := connc.rule()
+	if plugRule != nil {
+		return connc.checkPlugRule(kind, plugRule, snapRule)
+	}
+	if slotRule != nil {
+		return connc.checkSlotRule(kind, slotRule, snapRule)
+	}
+	return nil, nil
+}
+
+// rule returns the plug or slot rule deciding the connection, and whether it
+// comes from a snap declaration. Plug rules take precedence over slot rules,
+// and snap declarations over the base declaration.
+func (connc *ConnectCandidate) rule() (plugRule *asserts.PlugRule, slotRule *asserts.SlotRule, snapRule bool) {
+	iface := connc.Plug.Interface()
+
 	if plugDecl := connc.PlugSnapDeclaration; plugDecl != nil {
 		if rule := plugDecl.PlugRule(iface); rule != nil {
-			return connc.checkPlugRule(kind, rule, true)
+			return rule, nil, true
 		}
 	}
 	if slotDecl := connc.SlotSnapDeclaration; slotDecl != nil {
 		if rule := slotDecl.SlotRule(iface); rule != nil {
-			return connc.checkSlotRule(kind, rule, true)
+			return nil, rule, true
 		}
 	}
-	if rule := baseDecl.PlugRule(iface); rule != nil {
-		return connc.checkPlugRule(kind, rule, false)
+	if rule := connc.BaseDeclaration.PlugRule(iface); rule != nil {
+		return rule, nil, false
 	}
-	if rule := baseDecl.SlotRule(iface); rule != nil {
-		return connc.checkSlotRule(kind, rule, false)
+	if rule := connc.BaseDeclaration.SlotRule(iface); rule != nil {
+		return nil, rule, false
 	}
-	return nil, nil
+	return nil, nil, false
+}
+
+// DecidingRule returns a description of the rule deciding whether the
+// connection is allowed, or an empty string if there is no such rule.
+func (connc *ConnectCandidate) DecidingRule() string {
+	if connc.BaseDeclaration == nil {
+		return ""
+	}
+	iface := connc.Plug.Interface()
+	plugRule, slotRule, snapRule := connc.rule()
+	switch {
+	case plugRule != nil:
+		return describeRule("plug", iface, connc.PlugSnapDeclaration, snapRule)
+	case slotRule != nil:
+		return describeRule("slot", iface, connc.SlotSnapDeclaration, snapRule)
+	}
+	return ""
 }
 
 // Check checks whether the connection is allowed.
@@ -1085,6 +1085,34 @@ func (s *policySuite) TestSnapDeclAllowDenyAutoConnection(c *C) {
 	}
 }
 
+func (s *policySuite) TestConnectDecidingRule(c *C) {
+	tests := []struct {
+		iface    string
+		expected string
+	}{
+		{"random", ""},
+		{"base-plug-deny", `plug rule of interface "base-plug-deny" in the base-declaration`},
+		{"base-slot-deny", `slot rule of interface "base-slot-deny" in the base-declaration`},
+		{"snap-plug-deny", `plug rule of interface "snap-plug-deny" in the snap-declaration of "plug-snap"`},
+		{"snap-slot-deny", `slot rule of interface "snap-slot-deny" in the snap-declaration of "slot-snap"`},
+		// snap declarations take precedence
+		{"base-deny-snap-slot-allow", `slot rule of interface "base-deny-snap-slot-allow" in the snap-declaration of "slot-snap"`},
+		// and plug rules over slot rules
+		{"snap-slot-deny-snap-plug-allow", `plug rule of interface "snap-slot-deny-snap-plug-allow" in the snap-declaration of "plug-snap"`},
+	}
+
+	for _, t := range tests {
+		cand := policy.ConnectCandidate{
+			Plug:                interfaces.NewConnectedPlug(s.plugSnap.Plugs[t.iface], nil, nil),
+			Slot:                interfaces.NewConnectedSlot(s.slotSnap.Slots[t.iface], nil, nil),
+			PlugSnapDeclaration: s.plugDecl,
+			SlotSnapDeclaration: s.slotDecl,
+			BaseDeclaration:     s.baseDecl,
+		}
+		c.Check(cand.DecidingRule(), Equals, t.expected, Commentf(t.iface))
+	}
+}
+
 func (s *policySuite) TestSnapTypeCheckConnection(c *C) {
 	gadgetSnap := snaptest.MockInfo(c, `
 name: gadget
@@ -1496,6 +1524,39 @@ slots:
 	}
 }
 
+func (s *policySuite) TestInstallCandidateCheckPlugAndSlot(c *C) {
+	installSnap := snaptest.MockInfo(c, `name: install-snap
+version: 0
+slots:
+  innocuous:
+  install-slot-coreonly:
+plugs:
+  install-plug-attr-ok:
+    attr: ok
+`, nil)
+
+	cand := policy.InstallCandidate{
+		Snap:            installSnap,
+		BaseDeclaration: s.baseDecl,
+	}
+
+	rule, err := cand.CheckSlot(installSnap.Slots["innocuous"])
+	c.Check(err, IsNil)
+	c.Check(rule, Equals, "")
+
+	rule, err = cand.CheckSlot(installSnap.Slots["install-slot-coreonly"])
+	c.Check(err, ErrorMatches, `installation not allowed by "install-slot-coreonly" slot rule of interface "install-slot-coreonly"`)
+	c.Check(rule, Equals, `slot rule of interface "install-slot-coreonly" in the base-declaration`)
+
+	rule, err = cand.CheckPlug(installSnap.Plugs["install-plug-attr-ok"])
+	c.Check(err, IsNil)
+	c.Check(rule, Equals, `plug rule of interface "install-plug-attr-ok" in the base-declaration`)
+
+	cand.BaseDeclaration = nil
+	_, err = cand.CheckPlug(installSnap.Plugs["install-plug-attr-ok"])
+	c.Check(err, ErrorMatches, "internal error: improperly initialized InstallCandidate")
+}
+
 func (s *policySuite) TestSnapDeclAllowDenyInstallation(c *C) {
 
 	tests := []struct {
@@ -616,6 +616,57 @@ func (r *Repository) Connect(ref *ConnRef, plugStaticAttrs, plugDynamicAttrs, sl
 	return conn, nil
 }
 
+// UpdateConnectionAttrs replaces the dynamic attributes of the plug and slot of
+// an existing connection, keeping their static attributes. As with Connect, the
+// updated connection is validated by the interface and checked with the
+// given policy, the connection is left unchanged if either fails.
+func (r *Repository) UpdateConnectionAttrs(ref *ConnRef, plugDynamicAttrs, slotDynamicAttrs map[string]interface{}, policyCheck PolicyFunc) (*Connection, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	plug := r.plugs[ref.PlugRef.Snap][ref.PlugRef.Name]
+	slot := r.slots[ref.SlotRef.Snap][ref.SlotRef.Name]
+	conn, ok := r.slotPlugs[slot][plug]
+	if plug == nil || slot == nil || !ok {
+		return nil, &NotConnectedError{
+			message: fmt.Sprintf("no connection from %s:%s to %s:%s",
+				ref.PlugRef.Snap, ref.PlugRef.Name, ref.SlotRef.Snap, ref.SlotRef.Name)}
+	}
+
+	iface, ok := r.ifaces[plug.Interface]
+	if !ok {
+		return nil, fmt.Errorf("internal error: unknown interface %q", plug.Interface)
+	}
+
+	cplug := NewConnectedPlug(plug, conn.Plug.StaticAttrs(), plugDynamicAttrs)
+	cslot := NewConnectedSlot(slot, conn.Slot.StaticAttrs(), slotDynamicAttrs)
+
+	if i, ok := iface.(plugValidator); ok {
+		if err := i.BeforeConnectPlug(cplug); err != nil {
+			return nil, fmt.Errorf("cannot update plug %q of snap %q: %s", plug.Name, plug.Snap.InstanceName(), err)
+		}
+	}
+	if i, ok := iface.(slotValidator); ok {
+		if err := i.BeforeConnectSlot(cslot); err != nil {
+			return nil, fmt.Errorf("cannot update slot %q of snap %q: %s", slot.Name, slot.Snap.InstanceName(), err)
+		}
+	}
+	if policyCheck != nil {
+		ok, err := policyCheck(cplug, cslot)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("cannot update connection from %s:%s to %s:%s: not allowed by policy",
+				ref.PlugRef.Snap, ref.PlugRef.Name, ref.SlotRef.Snap, ref.SlotRef.Name)
+		}
+	}
+
+	conn.Plug = cplug
+	conn.Slot = cslot
+	return conn, nil
+}
+
 // NotConnectedError is returned by Disconnect() if the requested connection does
 // not exist.
 type NotConnectedError struct {
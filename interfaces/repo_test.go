@@ -1003,6 +1003,61 @@ func (s *RepositorySuite) TestConnectSucceeds(c *C) {
 	c.Assert(err, IsNil)
 }
 
+// Tests for Repository.UpdateConnectionAttrs()
+
+func (s *RepositorySuite) TestUpdateConnectionAttrs(c *C) {
+	c.Assert(s.testRepo.AddPlug(s.plug), IsNil)
+	c.Assert(s.testRepo.AddSlot(s.slot), IsNil)
+	connRef := NewConnRef(s.plug, s.slot)
+	_, err := s.testRepo.Connect(connRef, nil, map[string]interface{}{"a": "b"}, nil, map[string]interface{}{"c": "d"}, nil)
+	c.Assert(err, IsNil)
+
+	var checked bool
+	policyCheck := func(plug *ConnectedPlug, slot *ConnectedSlot) (bool, error) {
+		checked = true
+		c.Check(plug.DynamicAttrs(), DeepEquals, map[string]interface{}{"a": "b"})
+		c.Check(slot.DynamicAttrs(), DeepEquals, map[string]interface{}{"c": "e"})
+		return true, nil
+	}
+	conn, err := s.testRepo.UpdateConnectionAttrs(connRef, map[string]interface{}{"a": "b"}, map[string]interface{}{"c": "e"}, policyCheck)
+	c.Assert(err, IsNil)
+	c.Check(checked, Equals, true)
+	c.Check(conn.Slot.StaticAttrs(), DeepEquals, s.slot.Attrs)
+
+	conn, err = s.testRepo.Connection(connRef)
+	c.Assert(err, IsNil)
+	c.Check(conn.Plug.DynamicAttrs(), DeepEquals, map[string]interface{}{"a": "b"})
+	c.Check(conn.Slot.DynamicAttrs(), DeepEquals, map[string]interface{}{"c": "e"})
+}
+
+func (s *RepositorySuite) TestUpdateConnectionAttrsNotConnected(c *C) {
+	c.Assert(s.testRepo.AddPlug(s.plug), IsNil)
+	c.Assert(s.testRepo.AddSlot(s.slot), IsNil)
+	connRef := NewConnRef(s.plug, s.slot)
+	_, err := s.testRepo.UpdateConnectionAttrs(connRef, nil, nil, nil)
+	c.Assert(err, ErrorMatches, `no connection from consumer:plug to producer:slot`)
+	c.Check(err, FitsTypeOf, &NotConnectedError{})
+}
+
+func (s *RepositorySuite) TestUpdateConnectionAttrsDenied(c *C) {
+	c.Assert(s.testRepo.AddPlug(s.plug), IsNil)
+	c.Assert(s.testRepo.AddSlot(s.slot), IsNil)
+	connRef := NewConnRef(s.plug, s.slot)
+	_, err := s.testRepo.Connect(connRef, nil, nil, nil, map[string]interface{}{"c": "d"}, nil)
+	c.Assert(err, IsNil)
+
+	policyCheck := func(plug *ConnectedPlug, slot *ConnectedSlot) (bool, error) {
+		return false, nil
+	}
+	_, err = s.testRepo.UpdateConnectionAttrs(connRef, nil, map[string]interface{}{"c": "e"}, policyCheck)
+	c.Assert(err, ErrorMatches, `cannot update connection from consumer:plug to producer:slot: not allowed by policy`)
+
+	// the connection is left unchanged
+	conn, err := s.testRepo.Connection(connRef)
+	c.Assert(err, IsNil)
+	c.Check(conn.Slot.DynamicAttrs(), DeepEquals, map[string]interface{}{"c": "d"})
+}
+
 // Tests for Repository.Disconnect() and DisconnectAll()
 
 // Disconnect fails if any argument is empty
@@ -0,0 +1,182 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package selinux implements integration between snapd and SELinux.
+//
+// Interfaces contribute snippets written in the SELinux Common Intermediate
+// Language (CIL). The backend combines all the snippets affecting a given
+// snap into a single policy module, stored as
+// /var/lib/snapd/selinux/snap_<name>.cil, and loads it with semodule(8).
+// Each application and hook of the snap gets its own domain type which is
+// tagged with the snappy_snap_domain attribute defined by the snappy policy
+// module. Snippets refer to that type with the ###DOMAIN### placeholder.
+// snap-confine transitions into the domain of the application or hook it
+// runs, see DomainType.
+//
+// When the snap is removed the module is unloaded and its file removed.
+package selinux
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timings"
+)
+
+// identifierReplacer maps the characters allowed in security tags onto
+// characters valid in CIL identifiers. The mapping is unambiguous given the
+// naming rules of snaps, instance keys, applications and hooks.
+var identifierReplacer = strings.NewReplacer("_", "___", ".", "__", "-", "_")
+
+// ModuleName returns the name of the SELinux policy module of the given snap.
+func ModuleName(snapName string) string {
+	return "snap_" + identifierReplacer.Replace(snapName)
+}
+
+// DomainType returns the SELinux domain type of the given security tag.
+// The mapping is mirrored by snap-confine.
+func DomainType(securityTag string) string {
+	return identifierReplacer.Replace(securityTag) + "_t"
+}
+
+func moduleFileName(snapName string) string {
+	return ModuleName(snapName) + ".cil"
+}
+
+// Backend is responsible for maintaining SELinux policy modules for snaps.
+type Backend struct {
+	preseed bool
+}
+
+// Initialize does nothing beyond remembering if snapd is preseeding.
+func (b *Backend) Initialize(opts *interfaces.SecurityBackendOptions) error {
+	if opts != nil && opts.Preseed {
+		b.preseed = true
+	}
+	return nil
+}
+
+// Name returns the name of the backend.
+func (b *Backend) Name() interfaces.SecuritySystem {
+	return interfaces.SecuritySELinux
+}
+
+// Setup creates and loads the SELinux policy module of a given snap.
+//
+// The module is only reloaded when its content changes. The domains of snaps
+// are permissive, like the other snappy domains, until the baseline policy
+// covers what snap applications are expected to access.
+func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
+	snapName := snapInfo.InstanceName()
+	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	if err != nil {
+		return fmt.Errorf("cannot obtain SELinux specification for snap %q: %s", snapName, err)
+	}
+
+	content := deriveContent(spec.(*Specification), snapInfo)
+	dir := dirs.SnapSELinuxDir
+	if content != nil {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("cannot create directory for SELinux policy modules %q: %s", dir, err)
+		}
+	}
+	changed, removed, err := osutil.EnsureDirState(dir, moduleFileName(snapName), content)
+	if err != nil {
+		return fmt.Errorf("cannot synchronize SELinux policy modules for snap %q: %s", snapName, err)
+	}
+	for _, fname := range changed {
+		if err := b.loadModule(filepath.Join(dir, fname)); err != nil {
+			return err
+		}
+	}
+	if len(removed) > 0 {
+		return b.unloadModule(ModuleName(snapName))
+	}
+	return nil
+}
+
+// Remove unloads and removes the SELinux policy module of a given snap.
+//
+// This method should be called after removing a snap.
+func (b *Backend) Remove(snapName string) error {
+	_, removed, err := osutil.EnsureDirState(dirs.SnapSELinuxDir, moduleFileName(snapName), nil)
+	if err != nil {
+		return fmt.Errorf("cannot synchronize SELinux policy modules for snap %q: %s", snapName, err)
+	}
+	if len(removed) > 0 {
+		return b.unloadModule(ModuleName(snapName))
+	}
+	return nil
+}
+
+// deriveContent combines security snippets collected from all the interfaces
+// affecting a given snap into a content map applicable to EnsureDirState.
+//
+// A domain is declared for every application and hook of the snap, even
+// without snippets, as snap-confine transitions into it. The domains are
+// permissive: the baseline policy of snappy_snap_domain does not yet grant
+// everything the default AppArmor template does, e.g. access to the terminal
+// or to the host /etc, so enforcing it would break strict snaps.
+func deriveContent(spec *Specification, snapInfo *snap.Info) map[string]osutil.FileState {
+	tags := make([]string, 0, len(snapInfo.Apps)+len(snapInfo.Hooks))
+	for _, appInfo := range snapInfo.Apps {
+		tags = append(tags, appInfo.SecurityTag())
+	}
+	for _, hookInfo := range snapInfo.Hooks {
+		tags = append(tags, hookInfo.SecurityTag())
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	sort.Strings(tags)
+	var buffer bytes.Buffer
+	buffer.WriteString("; This file is automatically generated by snapd\n")
+	for _, tag := range tags {
+		domain := DomainType(tag)
+		fmt.Fprintf(&buffer, "\n; %s\n", tag)
+		fmt.Fprintf(&buffer, "(type %s)\n", domain)
+		fmt.Fprintf(&buffer, "(roletype system_r %s)\n", domain)
+		fmt.Fprintf(&buffer, "(typeattributeset snappy_snap_domain (%s))\n", domain)
+		fmt.Fprintf(&buffer, "(typepermissive %s)\n", domain)
+		buffer.WriteString(strings.Replace(spec.SnippetForTag(tag), "###DOMAIN###", domain, -1))
+	}
+	return map[string]osutil.FileState{
+		moduleFileName(snapInfo.InstanceName()): &osutil.MemoryFileState{
+			Content: buffer.Bytes(),
+			Mode:    0644,
+		},
+	}
+}
+
+func (b *Backend) NewSpecification() interfaces.Specification {
+	return &Specification{}
+}
+
+// SandboxFeatures returns list of features supported by snapd for SELinux policy.
+func (b *Backend) SandboxFeatures() []string {
+	return nil
+}
@@ -0,0 +1,222 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package selinux_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/selinux"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type backendSuite struct {
+	ifacetest.BackendSuite
+
+	semoduleCmd *testutil.MockCmd
+}
+
+var _ = Suite(&backendSuite{})
+
+var testedConfinementOpts = []interfaces.ConfinementOptions{
+	{},
+	{DevMode: true},
+	{JailMode: true},
+	{Classic: true},
+}
+
+func (s *backendSuite) SetUpTest(c *C) {
+	s.Backend = &selinux.Backend{}
+	s.BackendSuite.SetUpTest(c)
+	c.Assert(s.Repo.AddBackend(s.Backend), IsNil)
+
+	s.semoduleCmd = testutil.MockCommand(c, "semodule", "")
+}
+
+func (s *backendSuite) TearDownTest(c *C) {
+	s.semoduleCmd.Restore()
+	s.BackendSuite.TearDownTest(c)
+}
+
+func (s *backendSuite) TestName(c *C) {
+	c.Check(s.Backend.Name(), Equals, interfaces.SecuritySELinux)
+}
+
+func (s *backendSuite) TestDomainType(c *C) {
+	c.Check(selinux.DomainType("snap.foo.app"), Equals, "snap__foo__app_t")
+	c.Check(selinux.DomainType("snap.foo-bar_baz.hook.post-refresh"), Equals, "snap__foo_bar___baz__hook__post_refresh_t")
+	c.Check(selinux.ModuleName("foo-bar_baz"), Equals, "snap_foo_bar___baz")
+}
+
+func (s *backendSuite) TestInstallingSnapWritesAndLoadsModule(c *C) {
+	s.Iface.SELinuxPermanentSlotCallback = func(spec *selinux.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet("(allow ###DOMAIN### self (tcp_socket (create)))")
+		return nil
+	}
+	for _, opts := range testedConfinementOpts {
+		s.semoduleCmd.ForgetCalls()
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		module := filepath.Join(dirs.SnapSELinuxDir, "snap_samba.cil")
+		c.Check(module, testutil.FileEquals, `; This file is automatically generated by snapd
+
+; snap.samba.smbd
+(type snap__samba__smbd_t)
+(roletype system_r snap__samba__smbd_t)
+(typeattributeset snappy_snap_domain (snap__samba__smbd_t))
+(typepermissive snap__samba__smbd_t)
+(allow snap__samba__smbd_t self (tcp_socket (create)))
+`)
+		c.Check(s.semoduleCmd.Calls(), DeepEquals, [][]string{
+			{"semodule", "-i", module},
+		})
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestRemovingSnapRemovesAndUnloadsModule(c *C) {
+	s.Iface.SELinuxPermanentSlotCallback = func(spec *selinux.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet("(allow ###DOMAIN### self (tcp_socket (create)))")
+		return nil
+	}
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		s.semoduleCmd.ForgetCalls()
+		s.RemoveSnap(c, snapInfo)
+		c.Check(filepath.Join(dirs.SnapSELinuxDir, "snap_samba.cil"), testutil.FileAbsent)
+		c.Check(s.semoduleCmd.Calls(), DeepEquals, [][]string{
+			{"semodule", "-r", "snap_samba"},
+		})
+	}
+}
+
+func (s *backendSuite) TestUpdatingSnapReloadsOnlyChangedModule(c *C) {
+	snippet := "(allow ###DOMAIN### self (tcp_socket (create)))"
+	s.Iface.SELinuxPermanentSlotCallback = func(spec *selinux.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet(snippet)
+		return nil
+	}
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	module := filepath.Join(dirs.SnapSELinuxDir, "snap_samba.cil")
+
+	// same content, no reload
+	s.semoduleCmd.ForgetCalls()
+	snapInfo = s.UpdateSnap(c, snapInfo, interfaces.ConfinementOptions{}, ifacetest.SambaYamlV1, 1)
+	c.Check(s.semoduleCmd.Calls(), HasLen, 0)
+
+	// changed content is reloaded
+	snippet = "(allow ###DOMAIN### self (udp_socket (create)))"
+	snapInfo = s.UpdateSnap(c, snapInfo, interfaces.ConfinementOptions{}, ifacetest.SambaYamlV1, 2)
+	c.Check(module, testutil.FileContains, "(allow snap__samba__smbd_t self (udp_socket (create)))")
+	c.Check(s.semoduleCmd.Calls(), DeepEquals, [][]string{
+		{"semodule", "-i", module},
+	})
+
+	// no more snippets, the module only declares the domain
+	s.Iface.SELinuxPermanentSlotCallback = nil
+	s.semoduleCmd.ForgetCalls()
+	snapInfo = s.UpdateSnap(c, snapInfo, interfaces.ConfinementOptions{}, ifacetest.SambaYamlV1, 3)
+	c.Check(module, testutil.FileContains, "(type snap__samba__smbd_t)\n")
+	c.Check(module, Not(testutil.FileContains), "allow")
+	c.Check(s.semoduleCmd.Calls(), DeepEquals, [][]string{
+		{"semodule", "-i", module},
+	})
+	s.RemoveSnap(c, snapInfo)
+}
+
+func (s *backendSuite) TestModuleWithoutSnippets(c *C) {
+	for _, opts := range testedConfinementOpts {
+		s.semoduleCmd.ForgetCalls()
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlWithHook, 0)
+		module := filepath.Join(dirs.SnapSELinuxDir, "snap_samba.cil")
+		// all applications and hooks get a domain
+		for _, domain := range []string{"snap__samba__nmbd_t", "snap__samba__smbd_t", "snap__samba__hook__configure_t"} {
+			c.Check(module, testutil.FileContains, fmt.Sprintf("(type %[1]s)\n(roletype system_r %[1]s)\n(typeattributeset snappy_snap_domain (%[1]s))\n(typepermissive %[1]s)\n", domain))
+		}
+		c.Check(s.semoduleCmd.Calls(), DeepEquals, [][]string{
+			{"semodule", "-i", module},
+		})
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestNoModuleWithoutAppsOrHooks(c *C) {
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.PlugNoAppsYaml, 0)
+		s.RemoveSnap(c, snapInfo)
+	}
+	c.Check(dirs.SnapSELinuxDir, testutil.FileAbsent)
+	c.Check(s.semoduleCmd.Calls(), HasLen, 0)
+}
+
+func (s *backendSuite) TestSetupLoadError(c *C) {
+	s.semoduleCmd = testutil.MockCommand(c, "semodule", "echo failed; exit 1")
+	s.Iface.SELinuxPermanentSlotCallback = func(spec *selinux.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet("(allow ###DOMAIN### self (tcp_socket (create)))")
+		return nil
+	}
+	snapInfo := snaptest.MockInfo(c, ifacetest.SambaYamlV1, nil)
+	c.Assert(s.Repo.AddSnap(snapInfo), IsNil)
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, timings.New(nil))
+	c.Assert(err, ErrorMatches, `cannot load SELinux policy module ".*/snap_samba.cil": exit status 1\nsemodule output:\nfailed\n`)
+}
+
+func (s *backendSuite) TestPreseedSkipsSemodule(c *C) {
+	c.Assert(s.Backend.Initialize(&interfaces.SecurityBackendOptions{Preseed: true}), IsNil)
+	s.Iface.SELinuxPermanentSlotCallback = func(spec *selinux.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet("(allow ###DOMAIN### self (tcp_socket (create)))")
+		return nil
+	}
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(filepath.Join(dirs.SnapSELinuxDir, "snap_samba.cil"), testutil.FilePresent)
+	s.RemoveSnap(c, snapInfo)
+	c.Check(s.semoduleCmd.Calls(), HasLen, 0)
+}
+
+func (s *backendSuite) TestUnexpectedModuleReplaced(c *C) {
+	c.Assert(os.MkdirAll(dirs.SnapSELinuxDir, 0755), IsNil)
+	module := filepath.Join(dirs.SnapSELinuxDir, "snap_samba.cil")
+	c.Assert(ioutil.WriteFile(module, []byte("(type foo_t)"), 0644), IsNil)
+
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(module, testutil.FileContains, "(type snap__samba__smbd_t)\n")
+	c.Check(s.semoduleCmd.Calls(), DeepEquals, [][]string{
+		{"semodule", "-i", module},
+	})
+	s.RemoveSnap(c, snapInfo)
+}
+
+func (s *backendSuite) TestSandboxFeatures(c *C) {
+	c.Assert(s.Backend.SandboxFeatures(), HasLen, 0)
+}
@@ -0,0 +1,51 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package selinux
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// loadModule installs or upgrades the given CIL module in the system policy
+// using "semodule -i".
+func (b *Backend) loadModule(path string) error {
+	if b.preseed {
+		return nil
+	}
+	output, err := exec.Command("semodule", "-i", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot load SELinux policy module %q: %s\nsemodule output:\n%s", path, err, string(output))
+	}
+	return nil
+}
+
+// unloadModule removes the named module from the system policy using
+// "semodule -r".
+func (b *Backend) unloadModule(name string) error {
+	if b.preseed {
+		return nil
+	}
+	output, err := exec.Command("semodule", "-r", name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot unload SELinux policy module %q: %s\nsemodule output:\n%s", name, err, string(output))
+	}
+	return nil
+}
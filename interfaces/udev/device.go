@@ -0,0 +1,384 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package udev
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Device describes a device, along with its parent devices, as known to the
+// udev database.
+type Device struct {
+	// DevPath is the path of the device in sysfs, without the /sys prefix.
+	DevPath   string
+	Kernel    string
+	Subsystem string
+	Driver    string
+	// Attrs holds the sysfs attributes of the device.
+	Attrs map[string]string
+	// Properties holds the udev properties of the device, only known
+	// for the device itself and not for its parents.
+	Properties map[string]string
+	// Parent is the parent device, nil for the top-most device.
+	Parent *Device
+}
+
+var udevadmCommand = func(args ...string) ([]byte, error) {
+	output, err := exec.Command("udevadm", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("cannot run udevadm %s: %v", strings.Join(args, " "), osutil.OutputErr(output, err))
+	}
+	return output, nil
+}
+
+// QueryDevice obtains information about the device with the given device
+// node, or sysfs path, from the udev database.
+func QueryDevice(path string) (*Device, error) {
+	output, err := udevadmCommand("info", "--attribute-walk", "--name", path)
+	if err != nil {
+		return nil, err
+	}
+	dev, err := parseAttributeWalk(output)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse udev information of %s: %v", path, err)
+	}
+	output, err = udevadmCommand("info", "--query", "property", "--name", path)
+	if err != nil {
+		return nil, err
+	}
+	dev.Properties = make(map[string]string)
+	for _, line := range strings.Split(string(output), "\n") {
+		if kv := strings.SplitN(line, "=", 2); len(kv) == 2 {
+			dev.Properties[kv[0]] = kv[1]
+		}
+	}
+	return dev, nil
+}
+
+var (
+	walkDeviceRe = regexp.MustCompile(`^looking at (?:parent )?device '(.*)':$`)
+	walkKeyRe    = regexp.MustCompile(`^([A-Z]+)(?:\{([^}]+)\})?=="(.*)"$`)
+)
+
+// parseAttributeWalk parses the output of "udevadm info --attribute-walk".
+func parseAttributeWalk(output []byte) (*Device, error) {
+	var first, current *Device
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := walkDeviceRe.FindStringSubmatch(line); m != nil {
+			dev := &Device{DevPath: m[1], Attrs: make(map[string]string)}
+			if current == nil {
+				first = dev
+			} else {
+				current.Parent = dev
+			}
+			current = dev
+			continue
+		}
+		m := walkKeyRe.FindStringSubmatch(line)
+		if m == nil || current == nil {
+			continue
+		}
+		switch m[1] {
+		case "KERNEL", "KERNELS":
+			current.Kernel = m[3]
+		case "SUBSYSTEM", "SUBSYSTEMS":
+			current.Subsystem = m[3]
+		case "DRIVER", "DRIVERS":
+			current.Driver = m[3]
+		case "ATTR", "ATTRS":
+			current.Attrs[m[2]] = m[3]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if first == nil {
+		return nil, fmt.Errorf("no device found")
+	}
+	return first, nil
+}
+
+// ruleKey is a single match key of a udev rule.
+type ruleKey struct {
+	name    string
+	attr    string
+	negated bool
+	pattern string
+}
+
+var ruleKeyRe = regexp.MustCompile(`^\s*([A-Z_]+)(?:\{([^}]+)\})?\s*(==|!=|\+=|:=|-=|=)\s*"([^"]*)"\s*(?:,|$)`)
+
+// parseRule parses the match keys of a single line udev rule. Assignments
+// are ignored.
+func parseRule(rule string) ([]ruleKey, error) {
+	var keys []ruleKey
+	rest := strings.TrimSpace(rule)
+	for rest != "" {
+		m := ruleKeyRe.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, fmt.Errorf("cannot parse udev rule %q", rule)
+		}
+		rest = strings.TrimSpace(rest[len(m[0]):])
+		if m[3] != "==" && m[3] != "!=" {
+			continue
+		}
+		keys = append(keys, ruleKey{name: m[1], attr: m[2], negated: m[3] == "!=", pattern: m[4]})
+	}
+	return keys, nil
+}
+
+// globToRegexp converts a udev pattern, which is a shell glob possibly
+// combining alternatives with "|", into a regular expression.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var buf strings.Builder
+	buf.WriteString("^(?:")
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+			if c == '\\' {
+				buf.WriteString(`\\`)
+				continue
+			}
+			buf.WriteByte(c)
+		case c == '*':
+			buf.WriteString(".*")
+		case c == '?':
+			buf.WriteString(".")
+		case c == '|':
+			buf.WriteString("|")
+		case c == '[':
+			inClass = true
+			buf.WriteByte('[')
+			if i+1 < len(pattern) && pattern[i+1] == '!' {
+				buf.WriteByte('^')
+				i++
+			}
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	buf.WriteString(")$")
+	return regexp.Compile(buf.String())
+}
+
+func (k *ruleKey) matchValue(value string) (bool, error) {
+	re, err := globToRegexp(k.pattern)
+	if err != nil {
+		return false, fmt.Errorf("cannot use pattern %q: %v", k.pattern, err)
+	}
+	return re.MatchString(value) != k.negated, nil
+}
+
+// matchDevice matches keys applying to the device itself.
+func (k *ruleKey) matchDevice(dev *Device) (bool, error) {
+	switch k.name {
+	case "KERNEL":
+		return k.matchValue(dev.Kernel)
+	case "SUBSYSTEM":
+		return k.matchValue(dev.Subsystem)
+	case "DRIVER":
+		return k.matchValue(dev.Driver)
+	case "DEVPATH":
+		return k.matchValue(dev.DevPath)
+	case "ATTR":
+		return k.matchValue(dev.Attrs[k.attr])
+	case "ENV":
+		return k.matchValue(dev.Properties[k.attr])
+	case "ACTION":
+		// devices in the database have been added
+		return k.matchValue("add")
+	}
+	return false, fmt.Errorf("unsupported udev rule key %q", k.name)
+}
+
+// matchParent matches keys which apply to the device or any of its parents.
+func (k *ruleKey) matchParent(dev *Device) (bool, error) {
+	switch k.name {
+	case "KERNELS":
+		return k.matchValue(dev.Kernel)
+	case "SUBSYSTEMS":
+		return k.matchValue(dev.Subsystem)
+	case "DRIVERS":
+		return k.matchValue(dev.Driver)
+	case "ATTRS":
+		return k.matchValue(dev.Attrs[k.attr])
+	}
+	return false, fmt.Errorf("unsupported udev rule key %q", k.name)
+}
+
+func isParentKey(name string) bool {
+	switch name {
+	case "KERNELS", "SUBSYSTEMS", "DRIVERS", "ATTRS":
+		return true
+	}
+	return false
+}
+
+// Matches returns whether the rule matches the given device.
+//
+// Only the last line of multi-line rules is considered, earlier lines are
+// only used to import properties, which are known from the udev database
+// already. As with udev, keys looking at parent devices must all match the
+// same device, which may be the device itself.
+func (r *DeviceRule) Matches(dev *Device) (bool, error) {
+	lines := strings.Split(strings.TrimSpace(r.Rule), "\n")
+	keys, err := parseRule(lines[len(lines)-1])
+	if err != nil {
+		return false, err
+	}
+	var parentKeys []ruleKey
+	for _, k := range keys {
+		if isParentKey(k.name) {
+			parentKeys = append(parentKeys, k)
+			continue
+		}
+		ok, err := k.matchDevice(dev)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if len(parentKeys) == 0 {
+		return true, nil
+	}
+	for d := dev; d != nil; d = d.Parent {
+		matched := true
+		for _, k := range parentKeys {
+			ok, err := k.matchParent(d)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UnevaluatedDeviceRule is a device rule which could not be evaluated
+// against a device.
+type UnevaluatedDeviceRule struct {
+	DeviceRule
+	Err error
+}
+
+// UnrestrictedDevices describes an application or hook of a snap whose
+// access to devices is not restricted by a device cgroup.
+type UnrestrictedDevices struct {
+	SecurityTag string
+	// ControlsDeviceCgroup is set when the snap controls its own device
+	// cgroup, otherwise no devices are tagged for the application or hook.
+	ControlsDeviceCgroup bool
+}
+
+// DeviceAccess describes the access of the applications and hooks of snaps
+// to a device, as granted by the udev rules generated for their interfaces.
+type DeviceAccess struct {
+	// Rules are the device rules which match the device.
+	Rules []DeviceRule
+	// Unevaluated are the device rules which could not be evaluated, they
+	// may match the device.
+	Unevaluated []UnevaluatedDeviceRule
+	// Unrestricted are the applications and hooks which can access any
+	// device, including this one.
+	Unrestricted []UnrestrictedDevices
+}
+
+// AuditDeviceAccess returns how the applications and hooks of the given
+// snaps can access the given device, based on the interfaces of the snaps
+// in the repository.
+//
+// A device cgroup restricting access to the tagged devices is only set up for
+// applications and hooks which have devices tagged for them, and not at all
+// for snaps which control their own device cgroup, all of these are reported
+// as unrestricted.
+func AuditDeviceAccess(repo *interfaces.Repository, snaps []*snap.Info, dev *Device) (*DeviceAccess, error) {
+	sorted := make([]*snap.Info, len(snaps))
+	copy(sorted, snaps)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].InstanceName() < sorted[j].InstanceName()
+	})
+
+	access := &DeviceAccess{}
+	for _, info := range sorted {
+		s, err := repo.SnapSpecification(interfaces.SecurityUDev, info.InstanceName())
+		if err != nil {
+			return nil, err
+		}
+		spec := s.(*Specification)
+		controlsDeviceCgroup := spec.ControlsDeviceCgroup()
+		tagged := make(map[string]bool)
+		for _, rule := range spec.DeviceRules() {
+			tagged[rule.SecurityTag] = true
+			ok, err := rule.Matches(dev)
+			if err != nil {
+				access.Unevaluated = append(access.Unevaluated, UnevaluatedDeviceRule{DeviceRule: rule, Err: err})
+				continue
+			}
+			if ok {
+				access.Rules = append(access.Rules, rule)
+			}
+		}
+		for _, securityTag := range snapSecurityTags(info) {
+			if !tagged[securityTag] {
+				access.Unrestricted = append(access.Unrestricted, UnrestrictedDevices{
+					SecurityTag:          securityTag,
+					ControlsDeviceCgroup: controlsDeviceCgroup,
+				})
+			}
+		}
+	}
+	return access, nil
+}
+
+// snapSecurityTags returns the sorted security tags of the applications and
+// hooks of a snap.
+func snapSecurityTags(info *snap.Info) []string {
+	tags := make([]string, 0, len(info.Apps)+len(info.Hooks))
+	for _, app := range info.Apps {
+		tags = append(tags, app.SecurityTag())
+	}
+	for _, hook := range info.Hooks {
+		tags = append(tags, hook.SecurityTag())
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,308 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package udev_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type deviceSuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&deviceSuite{})
+
+const mockAttributeWalk = `
+Udevadm info starts with the device specified by the devpath and then
+walks up the chain of parent devices.
+
+  looking at device '/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0/ttyUSB0/tty/ttyUSB0':
+    KERNEL=="ttyUSB0"
+    SUBSYSTEM=="tty"
+    DRIVER==""
+    ATTR{power/control}=="auto"
+
+  looking at parent device '/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0/ttyUSB0':
+    KERNELS=="ttyUSB0"
+    SUBSYSTEMS=="usb-serial"
+    DRIVERS=="ftdi_sio"
+    ATTRS{port_number}=="0"
+
+  looking at parent device '/devices/pci0000:00/0000:00:14.0/usb1/1-2':
+    KERNELS=="1-2"
+    SUBSYSTEMS=="usb"
+    DRIVERS=="usb"
+    ATTRS{idProduct}=="6001"
+    ATTRS{idVendor}=="0403"
+`
+
+const mockProperties = `DEVPATH=/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0/ttyUSB0/tty/ttyUSB0
+DEVNAME=/dev/ttyUSB0
+SUBSYSTEM=tty
+ID_VENDOR_ID=0403
+ID_MODEL_ID=6001
+`
+
+func (s *deviceSuite) mockUdevadm(c *C) *testutil.MockCmd {
+	cmd := testutil.MockCommand(c, "udevadm", fmt.Sprintf(`
+if [ "$2" = "--attribute-walk" ]; then
+cat <<'EOF'
+%s
+EOF
+else
+cat <<'EOF'
+%s
+EOF
+fi`, mockAttributeWalk, mockProperties))
+	s.AddCleanup(cmd.Restore)
+	return cmd
+}
+
+func (s *deviceSuite) mockDevice(c *C) *udev.Device {
+	s.mockUdevadm(c)
+	dev, err := udev.QueryDevice("/dev/ttyUSB0")
+	c.Assert(err, IsNil)
+	return dev
+}
+
+func (s *deviceSuite) TestQueryDevice(c *C) {
+	cmd := s.mockUdevadm(c)
+	dev, err := udev.QueryDevice("/dev/ttyUSB0")
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), DeepEquals, [][]string{
+		{"udevadm", "info", "--attribute-walk", "--name", "/dev/ttyUSB0"},
+		{"udevadm", "info", "--query", "property", "--name", "/dev/ttyUSB0"},
+	})
+	c.Check(dev.DevPath, Equals, "/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0/ttyUSB0/tty/ttyUSB0")
+	c.Check(dev.Kernel, Equals, "ttyUSB0")
+	c.Check(dev.Subsystem, Equals, "tty")
+	c.Check(dev.Driver, Equals, "")
+	c.Check(dev.Attrs, DeepEquals, map[string]string{"power/control": "auto"})
+	c.Check(dev.Properties["ID_VENDOR_ID"], Equals, "0403")
+	c.Check(dev.Properties["DEVNAME"], Equals, "/dev/ttyUSB0")
+	c.Assert(dev.Parent, NotNil)
+	c.Check(dev.Parent.Subsystem, Equals, "usb-serial")
+	c.Check(dev.Parent.Driver, Equals, "ftdi_sio")
+	c.Assert(dev.Parent.Parent, NotNil)
+	c.Check(dev.Parent.Parent.Attrs, DeepEquals, map[string]string{"idProduct": "6001", "idVendor": "0403"})
+	c.Check(dev.Parent.Parent.Parent, IsNil)
+}
+
+func (s *deviceSuite) TestQueryDeviceError(c *C) {
+	cmd := testutil.MockCommand(c, "udevadm", "echo 'Unknown device'; exit 1")
+	defer cmd.Restore()
+	_, err := udev.QueryDevice("/dev/foo")
+	c.Check(err, ErrorMatches, `cannot run udevadm info --attribute-walk --name /dev/foo: Unknown device`)
+}
+
+func (s *deviceSuite) TestQueryDeviceNoDevice(c *C) {
+	cmd := testutil.MockCommand(c, "udevadm", "echo")
+	defer cmd.Restore()
+	_, err := udev.QueryDevice("/dev/foo")
+	c.Check(err, ErrorMatches, `cannot parse udev information of /dev/foo: no device found`)
+}
+
+func (s *deviceSuite) TestMatches(c *C) {
+	dev := s.mockDevice(c)
+
+	for _, tc := range []struct {
+		rule    string
+		matches bool
+	}{
+		{`KERNEL=="ttyUSB0"`, true},
+		{`KERNEL=="ttyUSB[0-9]*"`, true},
+		{`KERNEL=="ttyACM[0-9]*"`, false},
+		{`KERNEL=="rfcomm*|tty[a-zA-Z]*[0-9]*|cdc-wdm[0-9]*"`, true},
+		{`KERNEL!="ttyUSB0"`, false},
+		{`KERNEL=="tty?SB0"`, true},
+		{`KERNEL=="tty[!U]SB0"`, false},
+		{`SUBSYSTEM=="tty", KERNEL=="ttyUSB0"`, true},
+		{`SUBSYSTEM=="usb", KERNEL=="ttyUSB0"`, false},
+		{`SUBSYSTEMS=="usb", ATTRS{idVendor}=="0403", ATTRS{idProduct}=="6001"`, true},
+		{`SUBSYSTEMS=="usb", ATTRS{idVendor}=="0403", ATTRS{idProduct}=="6002"`, false},
+		// parent keys must match on the same device
+		{`SUBSYSTEMS=="usb-serial", ATTRS{idVendor}=="0403"`, false},
+		{`SUBSYSTEMS=="tty", KERNELS=="ttyUSB0"`, true},
+		{`DRIVERS=="ftdi_sio"`, true},
+		{`ENV{ID_VENDOR_ID}=="0403", ENV{ID_MODEL_ID}=="6001"`, true},
+		{`ENV{ID_VENDOR_ID}=="1234"`, false},
+		{`ATTR{power/control}=="auto"`, true},
+		{`ACTION!="remove", KERNEL=="ttyUSB0"`, true},
+		{`DEVPATH=="/devices/*/ttyUSB0"`, true},
+		{"IMPORT{builtin}=\"usb_id\"\nSUBSYSTEM==\"tty\", ENV{ID_VENDOR_ID}==\"0403\"", true},
+	} {
+		rule := udev.DeviceRule{Rule: tc.rule}
+		ok, err := rule.Matches(dev)
+		c.Check(err, IsNil, Commentf("%s", tc.rule))
+		c.Check(ok, Equals, tc.matches, Commentf("%s", tc.rule))
+	}
+}
+
+func (s *deviceSuite) TestMatchesErrors(c *C) {
+	dev := s.mockDevice(c)
+
+	for _, tc := range []struct {
+		rule string
+		err  string
+	}{
+		{`KERNEL=="tty`, `cannot parse udev rule "KERNEL==\\"tty"`},
+		{`PROGRAM=="foo"`, `unsupported udev rule key "PROGRAM"`},
+	} {
+		rule := udev.DeviceRule{Rule: tc.rule}
+		_, err := rule.Matches(dev)
+		c.Check(err, ErrorMatches, tc.err)
+	}
+}
+
+func (s *deviceSuite) TestAuditDeviceAccess(c *C) {
+	dev := s.mockDevice(c)
+
+	repo := interfaces.NewRepository()
+	c.Assert(repo.AddBackend(&udev.Backend{}), IsNil)
+	iface := &ifacetest.TestInterface{
+		InterfaceName: "serial",
+		UDevConnectedPlugCallback: func(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			var path string
+			slot.Attr("path", &path)
+			spec.TagDevice(fmt.Sprintf(`KERNEL=="%s"`, path))
+			return nil
+		},
+		UDevPermanentSlotCallback: func(spec *udev.Specification, slot *snap.SlotInfo) error {
+			spec.TagDevice(`SUBSYSTEM=="tty"`)
+			return nil
+		},
+	}
+	c.Assert(repo.AddInterface(iface), IsNil)
+	weird := &ifacetest.TestInterface{
+		InterfaceName: "weird",
+		UDevPermanentSlotCallback: func(spec *udev.Specification, slot *snap.SlotInfo) error {
+			spec.TagDevice(`PROGRAM=="/bin/true"`)
+			return nil
+		},
+	}
+	c.Assert(repo.AddInterface(weird), IsNil)
+	selfManaged := &ifacetest.TestInterface{
+		InterfaceName: "self-managed",
+		UDevConnectedPlugCallback: func(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.SetControlsDeviceCgroup()
+			return nil
+		},
+	}
+	c.Assert(repo.AddInterface(selfManaged), IsNil)
+
+	restore := snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {})
+	defer restore()
+	consumer := snaptest.MockInfo(c, `name: consumer
+version: 0
+plugs:
+ serial:
+apps:
+ app:
+  plugs: [serial]
+ other:
+`, nil)
+	gadget := snaptest.MockInfo(c, `name: gadget
+version: 0
+type: gadget
+slots:
+ usb0:
+  interface: serial
+  path: ttyUSB0
+ usb1:
+  interface: serial
+  path: ttyUSB1
+ self-managed:
+`, nil)
+	provider := snaptest.MockInfo(c, `name: provider
+version: 0
+slots:
+ serial:
+ weird:
+apps:
+ daemon:
+  slots: [serial, weird]
+`, nil)
+	manager := snaptest.MockInfo(c, `name: manager
+version: 0
+plugs:
+ self-managed:
+apps:
+ app:
+  plugs: [self-managed]
+`, nil)
+	// a snap without any interfaces
+	plain := snaptest.MockInfo(c, `name: plain
+version: 0
+apps:
+ app:
+`, nil)
+	for _, info := range []*snap.Info{consumer, gadget, provider, manager} {
+		c.Assert(repo.AddSnap(info), IsNil)
+	}
+	for _, slot := range []string{"usb0", "usb1"} {
+		_, err := repo.Connect(&interfaces.ConnRef{
+			PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "serial"},
+			SlotRef: interfaces.SlotRef{Snap: "gadget", Name: slot},
+		}, nil, nil, nil, nil, nil)
+		c.Assert(err, IsNil)
+	}
+	_, err := repo.Connect(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "manager", Name: "self-managed"},
+		SlotRef: interfaces.SlotRef{Snap: "gadget", Name: "self-managed"},
+	}, nil, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	access, err := udev.AuditDeviceAccess(repo, []*snap.Info{provider, plain, consumer, manager, gadget}, dev)
+	c.Assert(err, IsNil)
+	c.Check(access.Rules, DeepEquals, []udev.DeviceRule{{
+		SecurityTag: "snap.consumer.app",
+		Interface:   "serial",
+		Plug:        &interfaces.PlugRef{Snap: "consumer", Name: "serial"},
+		Slot:        &interfaces.SlotRef{Snap: "gadget", Name: "usb0"},
+		Rule:        `KERNEL=="ttyUSB0"`,
+	}, {
+		SecurityTag: "snap.provider.daemon",
+		Interface:   "serial",
+		Slot:        &interfaces.SlotRef{Snap: "provider", Name: "serial"},
+		Rule:        `SUBSYSTEM=="tty"`,
+	}})
+	c.Assert(access.Unevaluated, HasLen, 1)
+	c.Check(access.Unevaluated[0].DeviceRule, DeepEquals, udev.DeviceRule{
+		SecurityTag: "snap.provider.daemon",
+		Interface:   "weird",
+		Slot:        &interfaces.SlotRef{Snap: "provider", Name: "weird"},
+		Rule:        `PROGRAM=="/bin/true"`,
+	})
+	c.Check(access.Unevaluated[0].Err, ErrorMatches, `unsupported udev rule key "PROGRAM"`)
+	c.Check(access.Unrestricted, DeepEquals, []udev.UnrestrictedDevices{
+		{SecurityTag: "snap.consumer.other"},
+		{SecurityTag: "snap.manager.app", ControlsDeviceCgroup: true},
+		{SecurityTag: "snap.plain.app"},
+	})
+}
@@ -46,6 +46,29 @@ type Specification struct {
 	securityTags             []string
 	udevadmSubsystemTriggers []string
 	controlsDeviceCgroup     bool
+
+	// plug and slot being processed, used to describe the origin of
+	// device rules
+	plug        *interfaces.PlugRef
+	slot        *interfaces.SlotRef
+	deviceRules []DeviceRule
+}
+
+// DeviceRule describes a udev rule tagging devices for an application or
+// hook of a snap, along with the plug or slot requesting it.
+type DeviceRule struct {
+	// SecurityTag is the security tag of the application or hook.
+	SecurityTag string
+	// Interface is the name of the interface contributing the rule.
+	Interface string
+	// Plug is the plug contributing the rule or the connected plug of
+	// the slot contributing the rule, if any.
+	Plug *interfaces.PlugRef
+	// Slot is the slot contributing the rule or the connected slot of
+	// the plug contributing the rule, if any.
+	Slot *interfaces.SlotRef
+	// Rule is the udev rule matching the tagged devices.
+	Rule string
 }
 
 // SetControlsDeviceCgroup marks a specification as needing to control
@@ -96,9 +119,31 @@ func (spec *Specification) TagDevice(snippet string) {
 		spec.addEntry(fmt.Sprintf("# %s\n%s, TAG+=\"%s\"", spec.iface, snippet, tag), tag)
 		spec.addEntry(fmt.Sprintf("TAG==\"%s\", RUN+=\"%s/snap-device-helper $env{ACTION} %s $devpath $major:$minor\"",
 			tag, dirs.DistroLibExecDir, tag), tag)
+		spec.deviceRules = append(spec.deviceRules, DeviceRule{
+			SecurityTag: securityTag,
+			Interface:   spec.iface,
+			Plug:        spec.plug,
+			Slot:        spec.slot,
+			Rule:        snippet,
+		})
 	}
 }
 
+// DeviceRules returns the rules tagging devices for the applications and
+// hooks of the snap. No rules are returned if the snap controls its own
+// device cgroup, as no tagging rules are generated then. Note that the
+// applications and hooks without any tagged devices, as well as all of them
+// when the snap controls its own device cgroup, are not restricted to the
+// devices tagged for them, see AuditDeviceAccess.
+func (spec *Specification) DeviceRules() []DeviceRule {
+	if spec.ControlsDeviceCgroup() {
+		return nil
+	}
+	result := make([]DeviceRule, len(spec.deviceRules))
+	copy(result, spec.deviceRules)
+	return result
+}
+
 type byTagAndSnippet []entry
 
 func (c byTagAndSnippet) Len() int      { return len(c) }
@@ -131,6 +176,13 @@ func (spec *Specification) Snippets() (result []string) {
 	return result
 }
 
+func (spec *Specification) resetOrigin() {
+	spec.securityTags = nil
+	spec.iface = ""
+	spec.plug = nil
+	spec.slot = nil
+}
+
 // Implementation of methods required by interfaces.Specification
 
 // AddConnectedPlug records udev-specific side-effects of having a connected plug.
@@ -142,7 +194,8 @@ func (spec *Specification) AddConnectedPlug(iface interfaces.Interface, plug *in
 	if iface, ok := iface.(definer); ok {
 		spec.securityTags = plug.SecurityTags()
 		spec.iface = ifname
-		defer func() { spec.securityTags = nil; spec.iface = "" }()
+		spec.plug, spec.slot = plug.Ref(), slot.Ref()
+		defer spec.resetOrigin()
 		return iface.UDevConnectedPlug(spec, plug, slot)
 	}
 	return nil
@@ -157,7 +210,8 @@ func (spec *Specification) AddConnectedSlot(iface interfaces.Interface, plug *in
 	if iface, ok := iface.(definer); ok {
 		spec.securityTags = slot.SecurityTags()
 		spec.iface = ifname
-		defer func() { spec.securityTags = nil; spec.iface = "" }()
+		spec.plug, spec.slot = plug.Ref(), slot.Ref()
+		defer spec.resetOrigin()
 		return iface.UDevConnectedSlot(spec, plug, slot)
 	}
 	return nil
@@ -172,7 +226,8 @@ func (spec *Specification) AddPermanentPlug(iface interfaces.Interface, plug *sn
 	if iface, ok := iface.(definer); ok {
 		spec.securityTags = plug.SecurityTags()
 		spec.iface = ifname
-		defer func() { spec.securityTags = nil; spec.iface = "" }()
+		spec.plug = &interfaces.PlugRef{Snap: plug.Snap.InstanceName(), Name: plug.Name}
+		defer spec.resetOrigin()
 		return iface.UDevPermanentPlug(spec, plug)
 	}
 	return nil
@@ -187,7 +242,8 @@ func (spec *Specification) AddPermanentSlot(iface interfaces.Interface, slot *sn
 	if iface, ok := iface.(definer); ok {
 		spec.securityTags = slot.SecurityTags()
 		spec.iface = ifname
-		defer func() { spec.securityTags = nil; spec.iface = "" }()
+		spec.slot = &interfaces.SlotRef{Snap: slot.Snap.InstanceName(), Name: slot.Name}
+		defer spec.resetOrigin()
 		return iface.UDevPermanentSlot(spec, slot)
 	}
 	return nil
@@ -166,3 +166,29 @@ func (s *specSuite) TestControlsDeviceCgroup(c *C) {
 	s.spec.SetControlsDeviceCgroup()
 	c.Assert(s.spec.ControlsDeviceCgroup(), Equals, true)
 }
+
+func (s *specSuite) TestDeviceRules(c *C) {
+	iface := &ifacetest.TestInterface{
+		InterfaceName: "iface-1",
+		UDevConnectedPlugCallback: func(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.TagDevice(`KERNEL=="voodoo"`)
+			return nil
+		},
+		UDevPermanentSlotCallback: func(spec *udev.Specification, slot *snap.SlotInfo) error {
+			spec.TagDevice(`KERNEL=="hoodoo"`)
+			return nil
+		},
+	}
+	c.Assert(s.spec.AddConnectedPlug(iface, s.plug, s.slot), IsNil)
+	c.Assert(s.spec.AddPermanentSlot(iface, s.slotInfo), IsNil)
+	// slots without apps are not tagged
+	plugRef := &interfaces.PlugRef{Snap: "snap1", Name: "name"}
+	slotRef := &interfaces.SlotRef{Snap: "snap2", Name: "name"}
+	c.Check(s.spec.DeviceRules(), DeepEquals, []udev.DeviceRule{
+		{SecurityTag: "snap.snap1.foo", Interface: "iface-1", Plug: plugRef, Slot: slotRef, Rule: `KERNEL=="voodoo"`},
+		{SecurityTag: "snap.snap1.hook.configure", Interface: "iface-1", Plug: plugRef, Slot: slotRef, Rule: `KERNEL=="voodoo"`},
+	})
+
+	s.spec.SetControlsDeviceCgroup()
+	c.Check(s.spec.DeviceRules(), HasLen, 0)
+}
@@ -0,0 +1,84 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/sysconfig"
+)
+
+// optionKernelBootTries sets how many attempts the bootloader makes at booting
+// a new kernel before reverting to the previous one. Boot counting is only
+// implemented by grub with a snapd managed boot config, the option cannot be
+// set to a non-zero value on systems using u-boot or any other bootloader.
+const optionKernelBootTries = "system.boot.kernel-tries"
+
+var bootSetKernelBootTries = boot.SetKernelBootTries
+
+func init() {
+	supportedConfigurations["core."+optionKernelBootTries] = true
+}
+
+func parseKernelBootTries(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	tries, err := strconv.Atoi(v)
+	if err != nil || tries < 0 || tries > boot.MaxKernelBootTries {
+		return 0, fmt.Errorf("%s must be a number between 0 and %d, not %q", optionKernelBootTries, boot.MaxKernelBootTries, v)
+	}
+	return tries, nil
+}
+
+func validateKernelBootTries(tr ConfGetter) error {
+	v, err := coreCfg(tr, optionKernelBootTries)
+	if err != nil {
+		return err
+	}
+	_, err = parseKernelBootTries(v)
+	return err
+}
+
+func handleKernelBootTries(_ sysconfig.Device, tr ConfGetter, opts *fsOnlyContext) error {
+	if opts != nil {
+		// the modeenv does not exist yet when preparing the image, the
+		// option is applied on first boot
+		return nil
+	}
+	var pristine, v string
+	if err := tr.GetPristine("core", optionKernelBootTries, &pristine); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	if err := tr.Get("core", optionKernelBootTries, &v); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	if pristine == v {
+		return nil
+	}
+	tries, err := parseKernelBootTries(v)
+	if err != nil {
+		return err
+	}
+	return bootSetKernelBootTries(tries)
+}
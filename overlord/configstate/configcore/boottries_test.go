@@ -0,0 +1,136 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/bootloader/bootloadertest"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type bootTriesSuite struct {
+	configcoreSuite
+
+	tries []int
+}
+
+var _ = Suite(&bootTriesSuite{})
+
+func (s *bootTriesSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+
+	s.tries = nil
+	s.AddCleanup(configcore.MockBootSetKernelBootTries(func(tries int) error {
+		s.tries = append(s.tries, tries)
+		return nil
+	}))
+}
+
+func (s *bootTriesSuite) TestConfigureKernelBootTries(c *C) {
+	for _, tc := range []struct {
+		pristine, new string
+	}{
+		{"", "3"},
+		{"3", "0"},
+		{"3", ""},
+	} {
+		conf := map[string]interface{}{}
+		if tc.pristine != "" {
+			conf["system.boot.kernel-tries"] = tc.pristine
+		}
+		err := configcore.FilesystemOnlyRun(core20Dev, &mockConf{
+			state: s.state,
+			conf:  conf,
+			changes: map[string]interface{}{
+				"system.boot.kernel-tries": tc.new,
+			},
+		})
+		c.Assert(err, IsNil)
+	}
+	c.Check(s.tries, DeepEquals, []int{3, 0, 0})
+}
+
+func (s *bootTriesSuite) TestConfigureKernelBootTriesUnchanged(c *C) {
+	err := configcore.FilesystemOnlyRun(core20Dev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.boot.kernel-tries": "3",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.tries, HasLen, 0)
+}
+
+func (s *bootTriesSuite) TestConfigureKernelBootTriesInvalid(c *C) {
+	for _, v := range []string{"-1", "6", "many"} {
+		err := configcore.FilesystemOnlyRun(core20Dev, &mockConf{
+			state: s.state,
+			changes: map[string]interface{}{
+				"system.boot.kernel-tries": v,
+			},
+		})
+		c.Check(err, ErrorMatches, `system.boot.kernel-tries must be a number between 0 and 5, not ".*"`)
+	}
+	c.Check(s.tries, HasLen, 0)
+}
+
+func (s *bootTriesSuite) TestConfigureKernelBootTriesNoModeenv(c *C) {
+	err := configcore.FilesystemOnlyRun(coreDev, &mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"system.boot.kernel-tries": "3",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.tries, HasLen, 0)
+}
+
+func (s *bootTriesSuite) TestFilesystemOnlyApplyIgnored(c *C) {
+	err := configcore.FilesystemOnlyApply(core20Dev, c.MkDir(), map[string]interface{}{
+		"system.boot.kernel-tries": "3",
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.tries, HasLen, 0)
+}
+
+func (s *bootTriesSuite) TestConfigureKernelBootTriesUnsupportedBootloader(c *C) {
+	s.AddCleanup(configcore.MockBootSetKernelBootTries(boot.SetKernelBootTries))
+	m := &boot.Modeenv{
+		Mode: "run",
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+	bootloader.Force(bootloadertest.Mock("u-boot", c.MkDir()))
+	s.AddCleanup(func() { bootloader.Force(nil) })
+
+	err := configcore.FilesystemOnlyRun(core20Dev, &mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"system.boot.kernel-tries": "3",
+		},
+	})
+	c.Assert(err, ErrorMatches, `cannot use kernel boot tries, bootloader "u-boot" does not support boot counting \(only grub does\)`)
+
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.KernelBootTries, Equals, 0)
+}
@@ -89,3 +89,9 @@ func MockApparmorReloadAllSnapProfiles(f func() error) func() {
 	apparmorReloadAllSnapProfiles = f
 	return r
 }
+
+func MockBootSetKernelBootTries(f func(int) error) func() {
+	r := testutil.Backup(&bootSetKernelBootTries)
+	bootSetKernelBootTries = f
+	return r
+}
@@ -115,6 +115,9 @@ func init() {
 	// system.faillock
 	addFSOnlyHandler(validateFaillockSettings, handleFaillockConfiguration, coreOnly)
 
+	// system.boot.kernel-tries
+	addFSOnlyHandler(validateKernelBootTries, handleKernelBootTries, &flags{modeenvOnlyConfig: true})
+
 	sysconfig.ApplyFilesystemOnlyDefaultsImpl = filesystemOnlyApply
 }
 
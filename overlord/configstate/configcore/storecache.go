@@ -0,0 +1,51 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//go:build !nomanagers
+// +build !nomanagers
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/strutil"
+)
+
+func init() {
+	// maximum total size of the download cache
+	supportedConfigurations["core.store.cache.max-size"] = true
+	// free space to preserve on the filesystem of the download cache
+	supportedConfigurations["core.store.cache.min-free"] = true
+}
+
+func validateStoreCache(tr RunTransaction) error {
+	for _, key := range []string{"store.cache.max-size", "store.cache.min-free"} {
+		value, err := coreCfg(tr, key)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			continue
+		}
+		if _, err := strutil.ParseByteSize(value); err != nil {
+			return fmt.Errorf("cannot set %s: %v", key, err)
+		}
+	}
+	return nil
+}
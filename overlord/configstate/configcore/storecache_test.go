@@ -0,0 +1,61 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//go:build !nomanagers
+// +build !nomanagers
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type storeCacheSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&storeCacheSuite{})
+
+func (s *storeCacheSuite) TestConfigureStoreCacheHappy(c *C) {
+	err := configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"store.cache.max-size": "2GB",
+			"store.cache.min-free": "500MB",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *storeCacheSuite) TestConfigureStoreCacheRejected(c *C) {
+	for _, t := range []struct {
+		conf map[string]interface{}
+		err  string
+	}{
+		{map[string]interface{}{"store.cache.max-size": "2"}, `cannot set store.cache.max-size: cannot parse "2": need a number with a unit as input`},
+		{map[string]interface{}{"store.cache.min-free": "lots"}, `cannot set store.cache.min-free: cannot parse "lots": no numerical prefix`},
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf:  t.conf,
+		})
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
@@ -0,0 +1,60 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//go:build !nomanagers
+// +build !nomanagers
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+)
+
+func init() {
+	// URL of a store mirror to try before the store
+	supportedConfigurations["core.store.mirror"] = true
+	// address on which to serve the download cache as a store mirror,
+	// applied when snapd starts
+	supportedConfigurations["core.store.mirror-listen"] = true
+}
+
+func validateStoreMirror(tr RunTransaction) error {
+	mirror, err := coreCfg(tr, "store.mirror")
+	if err != nil {
+		return err
+	}
+	if mirror != "" {
+		u, err := url.Parse(mirror)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("store.mirror must be an http or https URL, not %q", mirror)
+		}
+	}
+
+	listen, err := coreCfg(tr, "store.mirror-listen")
+	if err != nil {
+		return err
+	}
+	if listen != "" {
+		if _, port, err := net.SplitHostPort(listen); err != nil || port == "" {
+			return fmt.Errorf("store.mirror-listen must be of the form [<host>]:<port>, not %q", listen)
+		}
+	}
+	return nil
+}
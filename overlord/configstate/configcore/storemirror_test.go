@@ -0,0 +1,63 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//go:build !nomanagers
+// +build !nomanagers
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type storeMirrorSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&storeMirrorSuite{})
+
+func (s *storeMirrorSuite) TestConfigureStoreMirrorHappy(c *C) {
+	err := configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"store.mirror":        "http://mirror.lan:8440",
+			"store.mirror-listen": ":8440",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *storeMirrorSuite) TestConfigureStoreMirrorRejected(c *C) {
+	for _, t := range []struct {
+		conf map[string]interface{}
+		err  string
+	}{
+		{map[string]interface{}{"store.mirror": "mirror.lan"}, `store.mirror must be an http or https URL, not "mirror.lan"`},
+		{map[string]interface{}{"store.mirror": "ftp://mirror.lan"}, `store.mirror must be an http or https URL, not "ftp://mirror.lan"`},
+		{map[string]interface{}{"store.mirror-listen": "8440"}, `store.mirror-listen must be of the form \[<host>\]:<port>, not "8440"`},
+		{map[string]interface{}{"store.mirror-listen": "0.0.0.0:"}, `store.mirror-listen must be of the form \[<host>\]:<port>, not "0.0.0.0:"`},
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf:  t.conf,
+		})
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -195,7 +196,9 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 	// we deem the new assets (be it bootloader or firmware) functional. The
 	// deployed boot assets must be backward compatible with reverted kernel
 	// or gadget snaps. There are no further changes to the boot assets,
-	// unless a new gadget update is deployed.
+	// unless a new gadget update is deployed. An update interrupted e.g. by
+	// a power loss is resumed when the task runs again, or rolled back if
+	// it cannot be completed.
 	runner.AddHandler("update-gadget-assets", m.doUpdateGadgetAssets, nil)
 	// There is no undo handler for successful boot config update. The
 	// config assets are assumed to be always backwards compatible.
@@ -206,6 +209,7 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 	runner.AddHandler("create-recovery-system", m.doCreateRecoverySystem, m.undoCreateRecoverySystem)
 	runner.AddHandler("finalize-recovery-system", m.doFinalizeTriedRecoverySystem, m.undoFinalizeTriedRecoverySystem)
 	runner.AddCleanup("finalize-recovery-system", m.cleanupRecoverySystem)
+	runner.AddHandler("remove-recovery-system", m.doRemoveRecoverySystem, nil)
 
 	// used from the install API
 	// TODO: use better task names that are close to our usual pattern
@@ -1946,6 +1950,9 @@ type System struct {
 	Current bool
 	// Label of the seed system
 	Label string
+	// DefaultRecoverySystem is true when the system was marked as the
+	// default recovery system
+	DefaultRecoverySystem bool
 	// Model assertion of the system
 	Model *asserts.Model
 	// Brand information
@@ -1969,6 +1976,14 @@ var recoverSystemActions = []SystemAction{
 	{Title: "Run normally", Mode: "run"},
 }
 
+// defaultRecoverySystemActions are the actions of the recovery system marked
+// as the default one, when it is not the current system
+var defaultRecoverySystemActions = []SystemAction{
+	{Title: "Reinstall", Mode: "install"},
+	{Title: "Recover", Mode: "recover"},
+	{Title: "Factory reset", Mode: "factory-reset"},
+}
+
 var ErrNoSystems = errors.New("no systems seeds")
 
 // Systems list the available recovery/seeding systems. Returns the list of
@@ -1977,6 +1992,12 @@ func (m *DeviceManager) Systems() ([]*System, error) {
 	// it's tough luck when we cannot determine the current system seed
 	systemMode := m.SystemMode(SysAny)
 	currentSys, _ := currentSystemForMode(m.state, systemMode)
+	m.state.Lock()
+	defaultSys, err := defaultRecoverySystem(m.state)
+	m.state.Unlock()
+	if err != nil {
+		return nil, err
+	}
 
 	systemLabels, err := filepath.Glob(filepath.Join(dirs.SnapSeedDir, "systems", "*"))
 	if err != nil && !os.IsNotExist(err) {
@@ -1997,11 +2018,23 @@ func (m *DeviceManager) Systems() ([]*System, error) {
 			logger.Noticef("cannot load system %q seed: %v", label, err)
 			continue
 		}
+		if defaultSys.sameAs(system) {
+			system.DefaultRecoverySystem = true
+			if !system.Current {
+				system.Actions = defaultRecoverySystemActions
+			}
+		}
 		systems = append(systems, system)
 	}
 	return systems, nil
 }
 
+// SystemsDiskUsage returns the disk usage of each of the available recovery
+// systems keyed by their label.
+func (m *DeviceManager) SystemsDiskUsage() (map[string]*SystemDiskUsage, error) {
+	return seedSystemsDiskUsage(dirs.SnapSeedDir)
+}
+
 // SystemAndGadgetAndEncryptionInfo return the system details
 // including the model assertion, gadget details and encryption info
 // for the given system label.
@@ -2101,11 +2134,12 @@ var ErrUnsupportedAction = errors.New("unsupported action")
 // When called without a systemLabel and without a mode it will just
 // trigger a regular reboot.
 //
-// When called without a systemLabel but with a mode it will use
-// the current system to enter the given mode.
+// When called without a systemLabel but with a mode it will use the
+// recovery system marked as the default one to enter the "install",
+// "recover" or "factory-reset" modes, and the current system otherwise.
 //
-// Note that "recover" and "run" modes are only available for the
-// current system.
+// Note that "recover" mode is only available for the current system and
+// the default recovery system, "run" mode only for the current system.
 func (m *DeviceManager) Reboot(systemLabel, mode string) error {
 	rebootCurrent := func() {
 		logger.Noticef("rebooting system")
@@ -2121,7 +2155,17 @@ func (m *DeviceManager) Reboot(systemLabel, mode string) error {
 		return nil
 	}
 
-	// no systemLabel means "current" so get the current system label
+	// no systemLabel means the default recovery system, if one was marked,
+	// for modes which boot into a recovery system
+	if systemLabel == "" && mode != "run" {
+		label, err := m.defaultRecoverySystemLabel()
+		if err != nil {
+			return err
+		}
+		systemLabel = label
+	}
+
+	// otherwise it means "current" so get the current system label
 	if systemLabel == "" {
 		systemMode := m.SystemMode(SysAny)
 		currentSys, err := currentSystemForMode(m.state, systemMode)
@@ -2140,6 +2184,34 @@ func (m *DeviceManager) Reboot(systemLabel, mode string) error {
 	return m.switchToSystemAndMode(systemLabel, mode, rebootCurrent, switched)
 }
 
+// defaultRecoverySystemLabel returns the label of the recovery system
+// marked as the default one, if it belongs to the model of the device and
+// is still present, or an empty label otherwise.
+func (m *DeviceManager) defaultRecoverySystemLabel() (string, error) {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	defaultSys, err := defaultRecoverySystem(m.state)
+	if err != nil || defaultSys == nil {
+		return "", err
+	}
+	model, err := findModel(m.state)
+	if err != nil {
+		if errors.Is(err, state.ErrNoState) {
+			return "", nil
+		}
+		return "", err
+	}
+	if defaultSys.Model != model.Model() || defaultSys.BrandID != model.BrandID() {
+		return "", nil
+	}
+	if !osutil.IsDirectory(filepath.Join(dirs.SnapSeedDir, "systems", defaultSys.System)) {
+		logger.Noticef("default recovery system %q is missing", defaultSys.System)
+		return "", nil
+	}
+	return defaultSys.System, nil
+}
+
 // RequestSystemAction requests the provided system to be run in a
 // given mode as specified by action.
 // A system reboot will be requested when the request can be
@@ -2184,6 +2256,17 @@ func (m *DeviceManager) switchToSystemAndMode(systemLabel, mode string, sameSyst
 	if err != nil {
 		return fmt.Errorf("cannot load seed system: %v", err)
 	}
+	if !system.Current {
+		m.state.Lock()
+		defaultSys, err := defaultRecoverySystem(m.state)
+		m.state.Unlock()
+		if err != nil {
+			return err
+		}
+		if defaultSys.sameAs(system) {
+			system.Actions = defaultRecoverySystemActions
+		}
+	}
 
 	var sysAction *SystemAction
 	for _, act := range system.Actions {
@@ -2230,6 +2313,15 @@ func (m *DeviceManager) switchToSystemAndMode(systemLabel, mode string, sameSyst
 	if err != nil {
 		return err
 	}
+	if mode == "factory-reset" {
+		if err := checkFactoryResetSealingMethod(); err != nil {
+			return err
+		}
+		// the data is gone once in factory-reset mode
+		if err := stageFactoryResetPreservedData(m.state, deviceCtx); err != nil {
+			return fmt.Errorf("cannot preserve data for factory reset: %v", err)
+		}
+	}
 	if err := boot.SetRecoveryBootSystemAndMode(deviceCtx, systemLabel, mode); err != nil {
 		return fmt.Errorf("cannot set device to boot into system %q in mode %q: %v", systemLabel, mode, err)
 	}
@@ -2429,6 +2521,10 @@ func (h fdeSetupHandler) Error(err error) (bool, error) {
 var (
 	secbootEnsureRecoveryKey  = secboot.EnsureRecoveryKey
 	secbootRemoveRecoveryKeys = secboot.RemoveRecoveryKeys
+
+	secbootAddNamedRecoveryKey    = secboot.AddNamedRecoveryKey
+	secbootRemoveNamedRecoveryKey = secboot.RemoveNamedRecoveryKey
+	secbootListRecoveryKeys       = secboot.ListRecoveryKeys
 )
 
 // EnsureRecoveryKeys makes sure appropriate recovery keys exist and
@@ -2543,6 +2639,119 @@ func (m *DeviceManager) RemoveRecoveryKeys() error {
 	return secbootRemoveRecoveryKeys(recoveryKeyDevices)
 }
 
+// runModeRecoveryKeyDevices returns the encrypted devices of a run mode
+// system on which named recovery keys are managed, together with the names of
+// their volumes.
+func (m *DeviceManager) runModeRecoveryKeyDevices(op string) ([]secboot.RecoveryKeyDevice, map[string]string, error) {
+	mode := m.SystemMode(SysAny)
+	if mode != "run" {
+		return nil, nil, fmt.Errorf("cannot %s recovery keys from system mode %q", op, mode)
+	}
+	if !device.HasEncryptedMarkerUnder(dirs.SnapFDEDir) {
+		return nil, nil, fmt.Errorf("system does not use disk encryption")
+	}
+	deviceCtx, err := DeviceCtx(m.state, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	model := deviceCtx.Model()
+
+	dataMountPoints, err := boot.HostUbuntuDataForMode(m.SystemMode(SysHasModeenv), model)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot determine ubuntu-data mount point: %v", err)
+	}
+	if len(dataMountPoints) == 0 {
+		// shouldn't happen as the marker file is under ubuntu-data
+		return nil, nil, fmt.Errorf("cannot %s recovery keys without any ubuntu-data mount points", op)
+	}
+	authKeyDir := dataMountPoints[0]
+	if !model.Classic() {
+		authKeyDir = filepath.Join(authKeyDir, "system-data")
+	}
+	recoveryKeyDevices := []secboot.RecoveryKeyDevice{
+		{
+			Mountpoint: dataMountPoints[0],
+			// authorization from keyring
+		},
+		{
+			Mountpoint:         boot.InitramfsUbuntuSaveDir,
+			AuthorizingKeyFile: device.SaveKeyUnder(dirs.SnapFDEDirUnder(authKeyDir)),
+		},
+	}
+	volumes := map[string]string{
+		dataMountPoints[0]:          "ubuntu-data",
+		boot.InitramfsUbuntuSaveDir: "ubuntu-save",
+	}
+	return recoveryKeyDevices, volumes, nil
+}
+
+// AddRecoveryKey adds a new recovery key with the given name to the
+// encrypted volumes and returns it. The key is not kept on disk once it has
+// been added.
+func (m *DeviceManager) AddRecoveryKey(name string) (*client.RecoveryKeyInfo, error) {
+	if err := keys.ValidateRecoveryKeyName(name); err != nil {
+		return nil, err
+	}
+	if name == keys.DefaultRecoveryKeyName {
+		return nil, fmt.Errorf("cannot add recovery key %q: name is reserved", name)
+	}
+	recoveryKeyDevices, volumes, err := m.runModeRecoveryKeyDevices("add")
+	if err != nil {
+		return nil, err
+	}
+	rkey, err := secbootAddNamedRecoveryKey(name, device.NamedRecoveryKeyUnder(dirs.SnapFDEDir, name), recoveryKeyDevices)
+	if err != nil {
+		return nil, err
+	}
+	info := &client.RecoveryKeyInfo{
+		Name:        name,
+		RecoveryKey: rkey.String(),
+	}
+	for _, dev := range recoveryKeyDevices {
+		info.Volumes = append(info.Volumes, volumes[dev.Mountpoint])
+	}
+	return info, nil
+}
+
+// RemoveRecoveryKey removes the recovery key with the given name from the
+// encrypted volumes. Removing the default recovery key is equivalent to
+// RemoveRecoveryKeys.
+func (m *DeviceManager) RemoveRecoveryKey(name string) error {
+	if name == keys.DefaultRecoveryKeyName {
+		return m.RemoveRecoveryKeys()
+	}
+	if err := keys.ValidateRecoveryKeyName(name); err != nil {
+		return err
+	}
+	recoveryKeyDevices, _, err := m.runModeRecoveryKeyDevices("remove")
+	if err != nil {
+		return err
+	}
+	return secbootRemoveNamedRecoveryKey(name, recoveryKeyDevices)
+}
+
+// ListRecoveryKeys returns the recovery keys of the encrypted volumes.
+func (m *DeviceManager) ListRecoveryKeys() ([]client.RecoveryKeyInfo, error) {
+	recoveryKeyDevices, volumes, err := m.runModeRecoveryKeyDevices("list")
+	if err != nil {
+		return nil, err
+	}
+	keyInfos, err := secbootListRecoveryKeys(recoveryKeyDevices)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]client.RecoveryKeyInfo, 0, len(keyInfos))
+	for _, keyInfo := range keyInfos {
+		info := client.RecoveryKeyInfo{Name: keyInfo.Name}
+		for _, mp := range keyInfo.Mountpoints {
+			info.Volumes = append(info.Volumes, volumes[mp])
+		}
+		sort.Strings(info.Volumes)
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 // EncryptionSupportInfo describes what encryption is available and needed
 // for the current device.
 type EncryptionSupportInfo struct {
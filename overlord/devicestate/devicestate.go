@@ -819,7 +819,7 @@ func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Mo
 		if err != nil {
 			return nil, fmt.Errorf("cannot select non-conflicting label for recovery system %q: %v", labelBase, err)
 		}
-		createRecoveryTasks, err := createRecoverySystemTasks(st, label, snapSetupTasks)
+		createRecoveryTasks, err := createRecoverySystemTasks(st, label, snapSetupTasks, CreateRecoverySystemOptions{})
 		if err != nil {
 			return nil, err
 		}
@@ -1023,6 +1023,35 @@ type recoverySystemSetup struct {
 	// SnapSetupTasks is a list of task IDs that carry snap setup
 	// information, relevant only during remodel, set when tasks are created
 	SnapSetupTasks []string `json:"snap-setup-tasks"`
+	// LocalSnaps is a list of snap files provided locally which are used
+	// instead of the currently installed revisions of the respective
+	// snaps, set when tasks are created
+	LocalSnaps []LocalSnap `json:"local-snaps,omitempty"`
+	// MarkDefault is set when the recovery system should become the
+	// default one once it has been successfully tested
+	MarkDefault bool `json:"mark-default,omitempty"`
+}
+
+// LocalSnap is a snap file provided locally, e.g. uploaded to snapd, to be
+// used when creating a recovery system.
+type LocalSnap struct {
+	// SideInfo of the snap, the revision and snap ID are set only when
+	// the snap is asserted
+	SideInfo *snap.SideInfo `json:"side-info"`
+	// Path to the snap file
+	Path string `json:"path"`
+}
+
+// CreateRecoverySystemOptions carries the options for creating a new
+// recovery system.
+type CreateRecoverySystemOptions struct {
+	// LocalSnaps is a list of snap files to use instead of the currently
+	// installed revisions of the respective snaps, the files are removed
+	// once the change is complete.
+	LocalSnaps []LocalSnap
+	// MarkDefault makes the recovery system the default one once it
+	// has been successfully tested.
+	MarkDefault bool
 }
 
 func pickRecoverySystemLabel(labelBase string) (string, error) {
@@ -1054,7 +1083,7 @@ func pickRecoverySystemLabel(labelBase string) (string, error) {
 	return fmt.Sprintf("%s-%d", labelBase, maxExistingNumber+1), nil
 }
 
-func createRecoverySystemTasks(st *state.State, label string, snapSetupTasks []string) (*state.TaskSet, error) {
+func createRecoverySystemTasks(st *state.State, label string, snapSetupTasks []string, opts CreateRecoverySystemOptions) (*state.TaskSet, error) {
 	// precondition check, the directory should not exist yet
 	systemDirectory := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label)
 	exists, _, err := osutil.DirExists(systemDirectory)
@@ -1072,6 +1101,8 @@ func createRecoverySystemTasks(st *state.State, label string, snapSetupTasks []s
 		Directory: systemDirectory,
 		// IDs of the tasks carrying snap-setup
 		SnapSetupTasks: snapSetupTasks,
+		LocalSnaps:     opts.LocalSnaps,
+		MarkDefault:    opts.MarkDefault,
 	})
 
 	finalize := st.NewTask("finalize-recovery-system", fmt.Sprintf("Finalize recovery system with label %q", label))
@@ -1081,7 +1112,12 @@ func createRecoverySystemTasks(st *state.State, label string, snapSetupTasks []s
 	return state.NewTaskSet(create, finalize), nil
 }
 
-func CreateRecoverySystem(st *state.State, label string) (*state.Change, error) {
+// CreateRecoverySystem creates a change that creates a new recovery system
+// with the given label for the current model, tries it by rebooting into it
+// and promotes it to the list of good recovery systems once it was
+// successfully tried. Snaps are taken from the current installed revisions,
+// unless local snap files are provided through options.
+func CreateRecoverySystem(st *state.State, label string, opts CreateRecoverySystemOptions) (*state.Change, error) {
 	var seeded bool
 	err := st.Get("seeded", &seeded)
 	if err != nil && !errors.Is(err, state.ErrNoState) {
@@ -1090,8 +1126,20 @@ func CreateRecoverySystem(st *state.State, label string) (*state.Change, error)
 	if !seeded {
 		return nil, fmt.Errorf("cannot create new recovery systems until fully seeded")
 	}
+	if err := checkRecoverySystemChangeConflict(st, fmt.Sprintf("cannot create recovery system %q", label)); err != nil {
+		return nil, err
+	}
+	if len(opts.LocalSnaps) > 0 {
+		model, err := findModel(st)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkLocalSnapsInModel(model, opts.LocalSnaps); err != nil {
+			return nil, err
+		}
+	}
 	chg := st.NewChange("create-recovery-system", fmt.Sprintf("Create new recovery system with label %q", label))
-	ts, err := createRecoverySystemTasks(st, label, nil)
+	ts, err := createRecoverySystemTasks(st, label, nil, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -1099,6 +1147,124 @@ func CreateRecoverySystem(st *state.State, label string) (*state.Change, error)
 	return chg, nil
 }
 
+// ErrNoRecoverySystem is returned when the recovery system requested for
+// removal does not exist.
+var ErrNoRecoverySystem = errors.New("recovery system does not exist")
+
+// RemoveRecoverySystem creates a change that removes the recovery system with
+// the given label from ubuntu-seed. The current recovery system, the default
+// recovery system and the last good recovery system cannot be removed. Seed
+// snaps which are no longer used by any of the remaining recovery systems are
+// removed too.
+func RemoveRecoverySystem(st *state.State, label string) (*state.Change, error) {
+	// the label is used to build a path under ubuntu-seed, make sure it
+	// cannot point outside of the systems directory
+	if err := asserts.IsValidSystemLabel(label); err != nil {
+		return nil, err
+	}
+
+	var seeded bool
+	err := st.Get("seeded", &seeded)
+	if err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+	if !seeded {
+		return nil, fmt.Errorf("cannot remove recovery systems until fully seeded")
+	}
+	modeenv, err := maybeReadModeenv()
+	if err != nil {
+		return nil, err
+	}
+	if modeenv == nil {
+		return nil, fmt.Errorf("cannot remove recovery systems on a system without modeenv")
+	}
+
+	systemDirectory := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label)
+	exists, _, err := osutil.DirExists(systemDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNoRecoverySystem
+	}
+
+	if label == modeenv.RecoverySystem {
+		return nil, fmt.Errorf("cannot remove the current recovery system %q", label)
+	}
+	var whatseeded []seededSystem
+	if err := st.Get("seeded-systems", &whatseeded); err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+	if len(whatseeded) > 0 && whatseeded[0].System == label {
+		return nil, fmt.Errorf("cannot remove the current recovery system %q", label)
+	}
+	defaultSys, err := defaultRecoverySystem(st)
+	if err != nil {
+		return nil, err
+	}
+	if defaultSys != nil && defaultSys.System == label {
+		return nil, fmt.Errorf("cannot remove the default recovery system %q", label)
+	}
+	if strutil.ListContains(modeenv.GoodRecoverySystems, label) && len(modeenv.GoodRecoverySystems) == 1 {
+		return nil, fmt.Errorf("cannot remove the last good recovery system %q", label)
+	}
+
+	if err := checkRecoverySystemChangeConflict(st, fmt.Sprintf("cannot remove recovery system %q", label)); err != nil {
+		return nil, err
+	}
+
+	chg := st.NewChange("remove-recovery-system", fmt.Sprintf("Remove recovery system with label %q", label))
+	remove := st.NewTask("remove-recovery-system", fmt.Sprintf("Remove recovery system with label %q", label))
+	remove.Set("recovery-system-label", label)
+	chg.AddTask(remove)
+	return chg, nil
+}
+
+// checkRecoverySystemChangeConflict checks for changes in progress which
+// create or remove recovery systems, a remodel creates one too.
+func checkRecoverySystemChangeConflict(st *state.State, what string) error {
+	for _, chg := range st.Changes() {
+		if chg.IsReady() {
+			continue
+		}
+		switch chg.Kind() {
+		case "create-recovery-system", "remove-recovery-system", "remodel":
+			return &snapstate.ChangeConflictError{
+				Message:    fmt.Sprintf("%s while a %s change is in progress", what, chg.Kind()),
+				ChangeKind: chg.Kind(),
+				ChangeID:   chg.ID(),
+			}
+		}
+	}
+	return nil
+}
+
+func checkLocalSnapsInModel(model *asserts.Model, localSnaps []LocalSnap) error {
+	// snapd is implicitly required
+	modelSnaps := []string{"snapd"}
+	for _, sn := range model.EssentialSnaps() {
+		modelSnaps = append(modelSnaps, sn.SnapName())
+	}
+	for _, sn := range model.SnapsWithoutEssential() {
+		modelSnaps = append(modelSnaps, sn.SnapName())
+	}
+	seen := make(map[string]bool, len(localSnaps))
+	for _, ls := range localSnaps {
+		if ls.SideInfo == nil || ls.Path == "" {
+			return fmt.Errorf("internal error: incomplete local snap information")
+		}
+		name := ls.SideInfo.RealName
+		if seen[name] {
+			return fmt.Errorf("cannot use snap %q provided more than once", name)
+		}
+		seen[name] = true
+		if !strutil.ListContains(modelSnaps, name) {
+			return fmt.Errorf("cannot use snap %q not listed in model %s/%s", name, model.BrandID(), model.Model())
+		}
+	}
+	return nil
+}
+
 // InstallFinish creates a change that will finish the install for the given
 // label and volumes. This includes writing missing volume content, seting
 // up the bootloader and installing the kernel.
@@ -1121,8 +1287,10 @@ func InstallFinish(st *state.State, label string, onVolumes map[string]*gadget.V
 
 // InstallSetupStorageEncryption creates a change that will setup the
 // storage encryption for the install of the given label and
-// volumes.
-func InstallSetupStorageEncryption(st *state.State, label string, onVolumes map[string]*gadget.Volume) (*state.Change, error) {
+// volumes. If a passphrase is provided, the encrypted partitions are
+// protected with it instead of keys sealed to the TPM, the passphrase is
+// only kept in memory.
+func InstallSetupStorageEncryption(st *state.State, label string, onVolumes map[string]*gadget.Volume, passphrase string) (*state.Change, error) {
 	if label == "" {
 		return nil, fmt.Errorf("cannot setup storage encryption with an empty system label")
 	}
@@ -1134,6 +1302,10 @@ func InstallSetupStorageEncryption(st *state.State, label string, onVolumes map[
 	setupStorageEncryptionTask := st.NewTask("install-setup-storage-encryption", fmt.Sprintf("Setup storage encryption for installing system %q", label))
 	setupStorageEncryptionTask.Set("system-label", label)
 	setupStorageEncryptionTask.Set("on-volumes", onVolumes)
+	if passphrase != "" {
+		setupStorageEncryptionTask.Set("use-passphrase", true)
+		st.Cache(installPassphraseKey{label}, passphrase)
+	}
 	chg.AddTask(setupStorageEncryptionTask)
 
 	return chg, nil
@@ -526,6 +526,120 @@ func (s *deviceMgrGadgetSuite) TestUpdateGadgetOnCoreUpdateFailed(c *C) {
 	c.Check(s.restartRequests, HasLen, 0)
 }
 
+func (s *deviceMgrGadgetSuite) mockInterruptedGadgetUpdate(c *C, rollbackDir string) {
+	err := os.MkdirAll(rollbackDir, 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(gadget.UpdateJournalPath(rollbackDir), []byte(`{"status":"in-progress","attempts":1}`), 0644)
+	c.Assert(err, IsNil)
+}
+
+func (s *deviceMgrGadgetSuite) TestUpdateGadgetOnCoreResumesInterrupted(c *C) {
+	rollbackDir := filepath.Join(dirs.SnapRollbackDir, "foo-gadget_34")
+	s.mockInterruptedGadgetUpdate(c, rollbackDir)
+
+	var updateCalled bool
+	restore := devicestate.MockGadgetUpdate(func(model gadget.Model, current, update gadget.GadgetData, path string, policy gadget.UpdatePolicyFunc, _ gadget.ContentUpdateObserver) error {
+		updateCalled = true
+		c.Check(path, Equals, rollbackDir)
+		return nil
+	})
+	defer restore()
+	restore = devicestate.MockGadgetRollback(func(model gadget.Model, current, update gadget.GadgetData, path string, policy gadget.UpdatePolicyFunc, _ gadget.ContentUpdateObserver) error {
+		return errors.New("unexpected call")
+	})
+	defer restore()
+
+	isClassic := false
+	chg, t := s.setupGadgetUpdate(c, "", gadgetYaml, "", isClassic)
+
+	s.state.Lock()
+	s.state.Set("seeded", true)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.IsReady(), Equals, true)
+	c.Check(chg.Err(), IsNil)
+	c.Check(t.Status(), Equals, state.DoneStatus)
+	c.Check(updateCalled, Equals, true)
+	c.Assert(t.Log(), Not(HasLen), 0)
+	c.Check(t.Log()[0], Matches, ".* INFO Resuming interrupted gadget assets update")
+	c.Check(s.restartRequests, DeepEquals, []restart.RestartType{restart.RestartSystem})
+	// the backups and the journal of the completed update are gone
+	c.Check(osutil.IsDirectory(rollbackDir), Equals, false)
+	c.Check(gadget.UpdateJournalPath(rollbackDir), testutil.FileAbsent)
+}
+
+func (s *deviceMgrGadgetSuite) TestUpdateGadgetOnCoreInterruptedUpdateFailedRollsBack(c *C) {
+	rollbackDir := filepath.Join(dirs.SnapRollbackDir, "foo-gadget_34")
+	s.mockInterruptedGadgetUpdate(c, rollbackDir)
+
+	restore := devicestate.MockGadgetUpdate(func(model gadget.Model, current, update gadget.GadgetData, path string, policy gadget.UpdatePolicyFunc, _ gadget.ContentUpdateObserver) error {
+		return errors.New("gadget exploded")
+	})
+	defer restore()
+	var rollbackCalled bool
+	restore = devicestate.MockGadgetRollback(func(model gadget.Model, current, update gadget.GadgetData, path string, policy gadget.UpdatePolicyFunc, _ gadget.ContentUpdateObserver) error {
+		rollbackCalled = true
+		c.Check(path, Equals, rollbackDir)
+		return nil
+	})
+	defer restore()
+
+	isClassic := false
+	chg, t := s.setupGadgetUpdate(c, "", gadgetYaml, "", isClassic)
+
+	s.state.Lock()
+	s.state.Set("seeded", true)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.IsReady(), Equals, true)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*update gadget \(gadget exploded\).*`)
+	c.Check(t.Status(), Equals, state.ErrorStatus)
+	c.Check(rollbackCalled, Equals, true)
+	c.Check(strings.Join(t.Log(), "\n"), Matches, `(?s).* INFO Rolled back interrupted gadget assets update.*`)
+	// the backups were restored
+	c.Check(osutil.IsDirectory(rollbackDir), Equals, false)
+	c.Check(s.restartRequests, HasLen, 0)
+}
+
+func (s *deviceMgrGadgetSuite) TestGadgetUpdateStatuses(c *C) {
+	statuses, err := devicestate.GadgetUpdateStatuses()
+	c.Assert(err, IsNil)
+	c.Check(statuses, HasLen, 0)
+
+	err = os.MkdirAll(dirs.SnapRollbackDir, 0755)
+	c.Assert(err, IsNil)
+	for name, content := range map[string]string{
+		"pc_34.journal":        `{"status":"in-progress","attempts":2,"started":"2023-02-01T00:00:00Z"}`,
+		"pc-kernel_12.journal": `{"status":"done","attempts":1,"started":"2023-01-01T00:00:00Z","structures":[{"volume":"pc","name":"mbr","yaml-index":0,"state":"updated"}]}`,
+		"pc_bad.journal":       `{"status":"done"}`,
+	} {
+		err := ioutil.WriteFile(filepath.Join(dirs.SnapRollbackDir, name), []byte(content), 0644)
+		c.Assert(err, IsNil)
+	}
+
+	statuses, err = devicestate.GadgetUpdateStatuses()
+	c.Assert(err, IsNil)
+	c.Assert(statuses, HasLen, 2)
+	c.Check(statuses[0].Snap, Equals, "pc-kernel")
+	c.Check(statuses[0].Revision, Equals, snap.R(12))
+	c.Check(statuses[0].Status, Equals, gadget.UpdateDone)
+	c.Check(statuses[0].Structures, DeepEquals, []*gadget.UpdateJournalEntry{
+		{Volume: "pc", Name: "mbr", YamlIndex: 0, State: gadget.StructureUpdated},
+	})
+	c.Check(statuses[1].Snap, Equals, "pc")
+	c.Check(statuses[1].Revision, Equals, snap.R(34))
+	c.Check(statuses[1].Interrupted(), Equals, true)
+	c.Check(statuses[1].Attempts, Equals, 2)
+}
+
 func (s *deviceMgrGadgetSuite) TestUpdateGadgetOnCoreNotDuringFirstboot(c *C) {
 	restore := devicestate.MockGadgetUpdate(func(model gadget.Model, current, update gadget.GadgetData, path string, policy gadget.UpdatePolicyFunc, _ gadget.ContentUpdateObserver) error {
 		return errors.New("unexpected call")
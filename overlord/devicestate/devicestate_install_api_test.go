@@ -424,7 +424,7 @@ func (s *deviceMgrInstallAPISuite) TestInstallFinishNoLabel(c *C) {
 - install API finish step \(cannot load assertions for label "classic": no seed assertions\)`)
 }
 
-func (s *deviceMgrInstallAPISuite) testInstallSetupStorageEncryption(c *C, hasTPM bool) {
+func (s *deviceMgrInstallAPISuite) testInstallSetupStorageEncryption(c *C, hasTPM bool, passphrase string) {
 	// Mock label
 	label := "classic"
 	isClassic := true
@@ -441,9 +441,10 @@ func (s *deviceMgrInstallAPISuite) testInstallSetupStorageEncryption(c *C, hasTP
 
 	// Mock encryption of partitions
 	encrytpPartCalls := 0
-	restore := devicestate.MockInstallEncryptPartitions(func(onVolumes map[string]*gadget.Volume, encryptionType secboot.EncryptionType, model *asserts.Model, gadgetRoot, kernelRoot string, perfTimings timings.Measurer) (*install.EncryptionSetupData, error) {
+	restore := devicestate.MockInstallEncryptPartitions(func(onVolumes map[string]*gadget.Volume, encryptionType secboot.EncryptionType, phrase string, model *asserts.Model, gadgetRoot, kernelRoot string, perfTimings timings.Measurer) (*install.EncryptionSetupData, error) {
 		encrytpPartCalls++
 		c.Check(encryptionType, Equals, secboot.EncryptionTypeLUKS)
+		c.Check(phrase, Equals, passphrase)
 		saveFound := false
 		dataFound := false
 		for _, strct := range onVolumes["pc"].Structure {
@@ -470,6 +471,10 @@ func (s *deviceMgrInstallAPISuite) testInstallSetupStorageEncryption(c *C, hasTP
 		"install API set-up encryption step")
 	encryptTask.Set("system-label", label)
 	encryptTask.Set("on-volumes", ginfo.Volumes)
+	if passphrase != "" {
+		encryptTask.Set("use-passphrase", true)
+		devicestate.MockInstallPassphraseInCache(s.state, label, passphrase)
+	}
 	chg.AddTask(encryptTask)
 
 	// now let the change run - some checks will happen in the mocked functions
@@ -486,8 +491,11 @@ func (s *deviceMgrInstallAPISuite) testInstallSetupStorageEncryption(c *C, hasTP
 	s.state.Lock()
 	defer s.state.Unlock()
 
+	// the passphrase never outlives the task
+	c.Check(devicestate.InstallPassphraseFromCache(s.state, label), IsNil)
+
 	// Checks now
-	if !hasTPM {
+	if !hasTPM && passphrase == "" {
 		c.Check(chg.Err(), ErrorMatches, `.*
 .*encryption unavailable on this device: not encrypting device storage as checking TPM gave: .*`)
 		return
@@ -513,11 +521,51 @@ func (s *deviceMgrInstallAPISuite) testInstallSetupStorageEncryption(c *C, hasTP
 }
 
 func (s *deviceMgrInstallAPISuite) TestInstallSetupStorageEncryptionHappy(c *C) {
-	s.testInstallSetupStorageEncryption(c, true)
+	s.testInstallSetupStorageEncryption(c, true, "")
 }
 
 func (s *deviceMgrInstallAPISuite) TestInstallSetupStorageEncryptionNoCrypto(c *C) {
-	s.testInstallSetupStorageEncryption(c, false)
+	s.testInstallSetupStorageEncryption(c, false, "")
+}
+
+func (s *deviceMgrInstallAPISuite) TestInstallSetupStorageEncryptionPassphraseHappy(c *C) {
+	s.testInstallSetupStorageEncryption(c, true, "s3cr3t")
+}
+
+func (s *deviceMgrInstallAPISuite) TestInstallSetupStorageEncryptionPassphraseNoTPM(c *C) {
+	s.testInstallSetupStorageEncryption(c, false, "s3cr3t")
+}
+
+func (s *deviceMgrInstallAPISuite) TestInstallSetupStorageEncryptionPassphraseMissing(c *C) {
+	label := "classic"
+	isClassic := true
+	_, _, ginfo, _ := s.mockSystemSeedWithLabel(c, label, isClassic)
+
+	restore := devicestate.MockInstallEncryptPartitions(func(onVolumes map[string]*gadget.Volume, encryptionType secboot.EncryptionType, phrase string, model *asserts.Model, gadgetRoot, kernelRoot string, perfTimings timings.Measurer) (*install.EncryptionSetupData, error) {
+		c.Fatalf("unexpected call")
+		return nil, nil
+	})
+	s.AddCleanup(restore)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("install-step-setup-storage-encryption",
+		"Setup storage encryption")
+	encryptTask := s.state.NewTask("install-setup-storage-encryption",
+		"install API set-up encryption step")
+	encryptTask.Set("system-label", label)
+	encryptTask.Set("on-volumes", ginfo.Volumes)
+	// e.g. snapd restarted and the passphrase got lost
+	encryptTask.Set("use-passphrase", true)
+	chg.AddTask(encryptTask)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Err(), ErrorMatches, `cannot perform the following tasks:
+- install API set-up encryption step \(cannot find the passphrase for installing system "classic", the step needs to be retried\)`)
 }
 
 func (s *deviceMgrInstallAPISuite) TestInstallSetupStorageEncryptionNoLabel(c *C) {
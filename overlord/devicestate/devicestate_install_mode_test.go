@@ -41,6 +41,7 @@ import (
 	"github.com/snapcore/snapd/bootloader/bootloadertest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/gadget/device"
 	"github.com/snapcore/snapd/gadget/install"
 	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/logger"
@@ -2934,6 +2935,27 @@ func (s *deviceMgrInstallModeSuite) TestFactoryResetPreviouslyEncrypted(c *C) {
 	c.Assert(err, ErrorMatches, `(?s).*cannot perform factory reset using different encryption, the original system was encrypted\)`)
 }
 
+func (s *deviceMgrInstallModeSuite) TestFactoryResetPassphraseEncrypted(c *C) {
+	s.state.Lock()
+	model := s.makeMockInstallModel(c, "dangerous")
+	s.state.Unlock()
+
+	// pretend snap-bootstrap mounted ubuntu-save and there is an encryption marker file
+	err := os.MkdirAll(filepath.Join(boot.InitramfsUbuntuSaveDir, "device/fde"), 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(boot.InitramfsUbuntuSaveDir, "device/fde/marker"), nil, 0644)
+	c.Assert(err, IsNil)
+	// and the system was encrypted with a passphrase
+	c.Assert(device.WritePassphraseMarker(boot.InitramfsSeedEncryptionKeyDir), IsNil)
+
+	err = s.doRunFactoryResetChange(c, model, resetTestCase{
+		tpm: true, encrypt: true,
+	})
+	c.Assert(err, ErrorMatches, `(?s).*cannot perform factory reset of a system encrypted with a passphrase, reinstall the system instead\)`)
+	// the save partition was not touched
+	c.Check(filepath.Join(boot.InitramfsUbuntuSaveDir, "device/fde/marker"), testutil.FilePresent)
+}
+
 func (s *deviceMgrInstallModeSuite) TestFactoryResetPreviouslyUnencrypted(c *C) {
 	s.state.Lock()
 	model := s.makeMockInstallModel(c, "dangerous")
@@ -3939,7 +3961,7 @@ func (s *installStepSuite) TestDeviceManagerInstallSetupStorageEncryptionEmptyLa
 	s.state.Lock()
 	defer s.state.Unlock()
 
-	chg, err := devicestate.InstallSetupStorageEncryption(s.state, "", mockOnVolumes)
+	chg, err := devicestate.InstallSetupStorageEncryption(s.state, "", mockOnVolumes, "")
 	c.Check(err, ErrorMatches, "cannot setup storage encryption with an empty system label")
 	c.Check(chg, IsNil)
 }
@@ -3948,7 +3970,7 @@ func (s *installStepSuite) TestDeviceManagerInstallSetupStorageEncryptionNoVolum
 	s.state.Lock()
 	defer s.state.Unlock()
 
-	chg, err := devicestate.InstallSetupStorageEncryption(s.state, "1234", nil)
+	chg, err := devicestate.InstallSetupStorageEncryption(s.state, "1234", nil, "")
 	c.Check(err, ErrorMatches, "cannot setup storage encryption without volumes data")
 	c.Check(chg, IsNil)
 }
@@ -3957,7 +3979,7 @@ func (s *installStepSuite) TestDeviceManagerInstallSetupStorageEncryptionTasksAn
 	s.state.Lock()
 	defer s.state.Unlock()
 
-	chg, err := devicestate.InstallSetupStorageEncryption(s.state, "1234", mockOnVolumes)
+	chg, err := devicestate.InstallSetupStorageEncryption(s.state, "1234", mockOnVolumes, "")
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	c.Check(chg.Summary(), Matches, `Setup storage encryption for installing system "1234"`)
@@ -3982,7 +4004,7 @@ func (s *installStepSuite) TestDeviceManagerInstallSetupStorageEncryptionRunthro
 	defer st.Unlock()
 
 	s.state.Set("seeded", true)
-	chg, err := devicestate.InstallSetupStorageEncryption(s.state, "1234", mockOnVolumes)
+	chg, err := devicestate.InstallSetupStorageEncryption(s.state, "1234", mockOnVolumes, "")
 	c.Assert(err, IsNil)
 
 	st.Unlock()
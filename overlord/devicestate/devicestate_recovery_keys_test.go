@@ -298,3 +298,115 @@ func (s *deviceMgrRecoveryKeysSuite) TestRemoveRecoveryKeysOtherModes(c *C) {
 		c.Check(err, ErrorMatches, fmt.Sprintf(`cannot remove recovery keys from system mode %q`, mode))
 	}
 }
+
+func (s *deviceMgrRecoveryKeysSuite) TestAddRecoveryKey(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := s.mgr.AddRecoveryKey("backup")
+	c.Check(err, ErrorMatches, `system does not use disk encryption`)
+
+	rkeystr, err := hex.DecodeString("e1f01302c5d43726a9b85b4a8d9c7f6e")
+	c.Assert(err, IsNil)
+	called := false
+	defer devicestate.MockSecbootAddNamedRecoveryKey(func(name, keyFile string, rkeyDevs []secboot.RecoveryKeyDevice) (keys.RecoveryKey, error) {
+		called = true
+		c.Check(name, Equals, "backup")
+		c.Check(keyFile, Equals, filepath.Join(dirs.SnapFDEDir, "recovery-backup.key"))
+		c.Check(rkeyDevs, DeepEquals, []secboot.RecoveryKeyDevice{
+			{Mountpoint: boot.InitramfsDataDir},
+			{
+				Mountpoint:         boot.InitramfsUbuntuSaveDir,
+				AuthorizingKeyFile: filepath.Join(boot.InitramfsDataDir, "system-data/var/lib/snapd/device/fde/ubuntu-save.key"),
+			},
+		})
+
+		var rkey keys.RecoveryKey
+		copy(rkey[:], []byte(rkeystr))
+		return rkey, nil
+	})()
+	mockSnapFDEFile(c, "marker", nil)
+
+	info, err := s.mgr.AddRecoveryKey("backup")
+	c.Assert(err, IsNil)
+	c.Check(called, Equals, true)
+	c.Check(info, DeepEquals, &client.RecoveryKeyInfo{
+		Name:        "backup",
+		RecoveryKey: "61665-00531-54469-09783-47273-19035-40077-28287",
+		Volumes:     []string{"ubuntu-data", "ubuntu-save"},
+	})
+}
+
+func (s *deviceMgrRecoveryKeysSuite) TestAddRecoveryKeyErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	mockSnapFDEFile(c, "marker", nil)
+	defer devicestate.MockSecbootAddNamedRecoveryKey(func(name, keyFile string, rkeyDevs []secboot.RecoveryKeyDevice) (keys.RecoveryKey, error) {
+		return keys.RecoveryKey{}, fmt.Errorf("boom")
+	})()
+
+	_, err := s.mgr.AddRecoveryKey("Not Valid")
+	c.Check(err, ErrorMatches, `invalid recovery key name "Not Valid"`)
+	_, err = s.mgr.AddRecoveryKey("default")
+	c.Check(err, ErrorMatches, `cannot add recovery key "default": name is reserved`)
+	_, err = s.mgr.AddRecoveryKey("backup")
+	c.Check(err, ErrorMatches, `boom`)
+
+	devicestate.SetSystemMode(s.mgr, "recover")
+	_, err = s.mgr.AddRecoveryKey("backup")
+	c.Check(err, ErrorMatches, `cannot add recovery keys from system mode "recover"`)
+}
+
+func (s *deviceMgrRecoveryKeysSuite) TestRemoveRecoveryKey(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	mockSnapFDEFile(c, "marker", nil)
+	removed := ""
+	defer devicestate.MockSecbootRemoveNamedRecoveryKey(func(name string, rkeyDevs []secboot.RecoveryKeyDevice) error {
+		removed = name
+		c.Check(rkeyDevs, HasLen, 2)
+		return nil
+	})()
+	removedDefault := false
+	defer devicestate.MockSecbootRemoveRecoveryKeys(func(r2k map[secboot.RecoveryKeyDevice]string) error {
+		removedDefault = true
+		return nil
+	})()
+
+	err := s.mgr.RemoveRecoveryKey("backup")
+	c.Assert(err, IsNil)
+	c.Check(removed, Equals, "backup")
+	c.Check(removedDefault, Equals, false)
+
+	removed = ""
+	err = s.mgr.RemoveRecoveryKey("default")
+	c.Assert(err, IsNil)
+	c.Check(removed, Equals, "")
+	c.Check(removedDefault, Equals, true)
+
+	err = s.mgr.RemoveRecoveryKey("-bad")
+	c.Check(err, ErrorMatches, `invalid recovery key name "-bad"`)
+}
+
+func (s *deviceMgrRecoveryKeysSuite) TestListRecoveryKeys(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	mockSnapFDEFile(c, "marker", nil)
+	defer devicestate.MockSecbootListRecoveryKeys(func(rkeyDevs []secboot.RecoveryKeyDevice) ([]secboot.RecoveryKeyInfo, error) {
+		c.Check(rkeyDevs, HasLen, 2)
+		return []secboot.RecoveryKeyInfo{
+			{Name: "backup", Mountpoints: []string{boot.InitramfsDataDir}},
+			{Name: "default", Mountpoints: []string{boot.InitramfsUbuntuSaveDir, boot.InitramfsDataDir}},
+		}, nil
+	})()
+
+	infos, err := s.mgr.ListRecoveryKeys()
+	c.Assert(err, IsNil)
+	c.Check(infos, DeepEquals, []client.RecoveryKeyInfo{
+		{Name: "backup", Volumes: []string{"ubuntu-data"}},
+		{Name: "default", Volumes: []string{"ubuntu-data", "ubuntu-save"}},
+	})
+}
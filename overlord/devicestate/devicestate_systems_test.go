@@ -38,7 +38,9 @@ import (
 	"github.com/snapcore/snapd/bootloader/bootloadertest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/gadget/device"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/assertstate/assertstatetest"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
@@ -749,6 +751,34 @@ func (s *deviceMgrSystemsSuite) TestRequestModeForNonCurrent(c *C) {
 	c.Check(s.logbuf.String(), Equals, "")
 }
 
+func (s *deviceMgrSystemsSuite) TestRequestFactoryResetPassphraseEncrypted(c *C) {
+	s.state.Lock()
+	s.state.Set("seeded-systems", []devicestate.SeededSystem{
+		{
+			System:  s.mockedSystemSeeds[0].label,
+			Model:   s.mockedSystemSeeds[0].model.Model(),
+			BrandID: s.mockedSystemSeeds[0].brand.AccountID(),
+		},
+	})
+	s.state.Unlock()
+	c.Assert(device.WritePassphraseMarker(boot.InitramfsSeedEncryptionKeyDir), IsNil)
+
+	err := s.mgr.RequestSystemAction(s.mockedSystemSeeds[0].label, devicestate.SystemAction{Mode: "factory-reset"})
+	c.Assert(err, ErrorMatches, "cannot perform factory reset of a system encrypted with a passphrase, reinstall the system instead")
+	c.Check(s.restartRequests, HasLen, 0)
+	m, err := s.bootloader.GetBootVars("snapd_recovery_mode", "snapd_recovery_system")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snapd_recovery_system": "",
+		"snapd_recovery_mode":   "",
+	})
+
+	// reinstalling is possible
+	err = s.mgr.RequestSystemAction(s.mockedSystemSeeds[0].label, devicestate.SystemAction{Mode: "install"})
+	c.Assert(err, IsNil)
+	c.Check(s.restartRequests, DeepEquals, []restart.RestartType{restart.RestartSystemNow})
+}
+
 func (s *deviceMgrSystemsSuite) TestRequestInstallForOther(c *C) {
 	devicestate.SetSystemMode(s.mgr, "run")
 	// non run modes use modeenv
@@ -878,6 +908,109 @@ func (s *deviceMgrSystemsSuite) TestRebootFromRunOnlyHappy(c *C) {
 	}
 }
 
+func (s *deviceMgrSystemsSuite) mockDefaultRecoverySystem(c *C, idx int) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded-systems", []devicestate.SeededSystem{
+		{
+			System:  s.mockedSystemSeeds[0].label,
+			Model:   s.mockedSystemSeeds[0].model.Model(),
+			BrandID: s.mockedSystemSeeds[0].brand.AccountID(),
+		},
+	})
+	assertstatetest.AddMany(s.state, s.brands.AccountsAndKeys("my-brand")...)
+	assertstatetest.AddMany(s.state, s.mockedSystemSeeds[1].model)
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: s.mockedSystemSeeds[1].brand.AccountID(),
+		Model: s.mockedSystemSeeds[1].model.Model(),
+	})
+	s.state.Set("default-recovery-system", &devicestate.DefaultRecoverySystem{
+		System:  s.mockedSystemSeeds[idx].label,
+		Model:   s.mockedSystemSeeds[idx].model.Model(),
+		BrandID: s.mockedSystemSeeds[idx].brand.AccountID(),
+	})
+}
+
+func (s *deviceMgrSystemsSuite) TestRebootFromRunDefaultRecoverySystem(c *C) {
+	// the default recovery system is of the model of the device
+	s.mockDefaultRecoverySystem(c, 1)
+
+	for _, mode := range []string{"recover", "install", "factory-reset"} {
+		s.restartRequests = nil
+		s.bootloader.BootVars = make(map[string]string)
+		s.logbuf.Reset()
+
+		err := s.mgr.Reboot("", mode)
+		c.Assert(err, IsNil)
+
+		m, err := s.bootloader.GetBootVars("snapd_recovery_mode", "snapd_recovery_system")
+		c.Assert(err, IsNil)
+		c.Check(m, DeepEquals, map[string]string{
+			"snapd_recovery_system": "20200318",
+			"snapd_recovery_mode":   mode,
+		})
+		c.Check(s.restartRequests, DeepEquals, []restart.RestartType{restart.RestartSystemNow})
+		c.Check(s.logbuf.String(), Matches, fmt.Sprintf(`.*: rebooting into system "20200318" in "%s" mode\n`, mode))
+	}
+
+	// the current system is still used for run mode
+	s.restartRequests = nil
+	s.bootloader.BootVars = make(map[string]string)
+	err := s.mgr.Reboot("", "run")
+	c.Assert(err, IsNil)
+	c.Check(s.restartRequests, DeepEquals, []restart.RestartType{restart.RestartSystemNow})
+	m, err := s.bootloader.GetBootVars("snapd_recovery_mode", "snapd_recovery_system")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snapd_recovery_system": "",
+		"snapd_recovery_mode":   "",
+	})
+
+	// and the default system lists the recovery actions
+	systems, err := s.mgr.Systems()
+	c.Assert(err, IsNil)
+	c.Assert(systems, HasLen, 3)
+	c.Check(systems[1].Label, Equals, "20200318")
+	c.Check(systems[1].DefaultRecoverySystem, Equals, true)
+	c.Check(systems[1].Actions, DeepEquals, []devicestate.SystemAction{
+		{Title: "Reinstall", Mode: "install"},
+		{Title: "Recover", Mode: "recover"},
+		{Title: "Factory reset", Mode: "factory-reset"},
+	})
+}
+
+func (s *deviceMgrSystemsSuite) TestRebootFromRunDefaultRecoverySystemOtherModel(c *C) {
+	// the default recovery system is not of the model of the device, so
+	// the current system is used
+	s.mockDefaultRecoverySystem(c, 2)
+
+	err := s.mgr.Reboot("", "recover")
+	c.Assert(err, IsNil)
+
+	m, err := s.bootloader.GetBootVars("snapd_recovery_mode", "snapd_recovery_system")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snapd_recovery_system": "20191119",
+		"snapd_recovery_mode":   "recover",
+	})
+}
+
+func (s *deviceMgrSystemsSuite) TestRebootFromRunDefaultRecoverySystemMissing(c *C) {
+	s.mockDefaultRecoverySystem(c, 1)
+	c.Assert(os.RemoveAll(filepath.Join(dirs.SnapSeedDir, "systems", "20200318")), IsNil)
+
+	err := s.mgr.Reboot("", "recover")
+	c.Assert(err, IsNil)
+
+	m, err := s.bootloader.GetBootVars("snapd_recovery_mode", "snapd_recovery_system")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snapd_recovery_system": "20191119",
+		"snapd_recovery_mode":   "recover",
+	})
+	c.Check(s.logbuf.String(), Matches, `(?s).*default recovery system "20200318" is missing\n.*`)
+}
+
 func (s *deviceMgrSystemsSuite) TestRebootFromRecoverToOther(c *C) {
 	modeenv := boot.Modeenv{
 		Mode:           "recover",
@@ -1319,7 +1452,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemTasks
 
 	s.state.Lock()
 	defer s.state.Unlock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -1343,6 +1476,21 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemTasks
 	c.Assert(otherTaskID, Equals, tskCreate.ID())
 }
 
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemConflict(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	other := s.state.NewChange("remove-recovery-system", "...")
+	other.AddTask(s.state.NewTask("remove-recovery-system", "..."))
+
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
+	c.Check(chg, IsNil)
+	c.Assert(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err, ErrorMatches, `cannot create recovery system "1234" while a remove-recovery-system change is in progress`)
+	c.Check(err.(*snapstate.ChangeConflictError).ChangeID, Equals, other.ID())
+}
+
 func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemTasksWhenDirExists(c *C) {
 	devicestate.SetBootOkRan(s.mgr, true)
 
@@ -1350,7 +1498,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemTasks
 
 	s.state.Lock()
 	defer s.state.Unlock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
 	c.Assert(err, ErrorMatches, `recovery system "1234" already exists`)
 	c.Check(chg, IsNil)
 }
@@ -1362,7 +1510,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemNotSe
 	defer s.state.Unlock()
 	s.state.Set("seeded", nil)
 
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
 	c.Assert(err, ErrorMatches, `cannot create new recovery systems until fully seeded`)
 	c.Check(chg, IsNil)
 }
@@ -1424,7 +1572,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemHappy
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	s.state.Lock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -1520,6 +1668,256 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemHappy
 	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", "1234", "snapd-new-file-log"), testutil.FileAbsent)
 }
 
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemLocalSnapsAndMarkDefault(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+	// a newer revision of the kernel provided locally
+	localKernel := snaptest.MakeTestSnapWithFiles(c, snapYamls["pc-kernel"], snapFiles["pc-kernel"])
+	s.setupSnapRevisionForFileAndID(c, localKernel, s.ss.AssertedSnapID("pc-kernel"), "canonical", snap.R(5))
+	s.state.Set("default-recovery-system", &devicestate.DefaultRecoverySystem{
+		System:  "othersystem",
+		Model:   s.model.Model(),
+		BrandID: s.model.BrandID(),
+	})
+
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{
+		LocalSnaps: []devicestate.LocalSnap{{
+			SideInfo: &snap.SideInfo{RealName: "pc-kernel", SnapID: s.ss.AssertedSnapID("pc-kernel"), Revision: snap.R(5)},
+			Path:     localKernel,
+		}},
+		MarkDefault: true,
+	})
+	c.Assert(err, IsNil)
+	tsks := chg.Tasks()
+	c.Assert(tsks, HasLen, 2)
+	tskCreate := tsks[0]
+	tskFinalize := tsks[1]
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(tskCreate.Status(), Equals, state.DoneStatus)
+	c.Assert(tskFinalize.Status(), Equals, state.DoingStatus)
+	c.Check(s.restartRequests, DeepEquals, []restart.RestartType{restart.RestartSystemNow})
+
+	validateCore20Seed(c, "1234", s.model, s.storeSigning.Trusted)
+	// the local kernel was used instead of the installed revision
+	expectedFilesLog := &bytes.Buffer{}
+	for _, fname := range []string{"snapd_4.snap", "pc-kernel_5.snap", "core20_3.snap", "pc_1.snap"} {
+		fmt.Fprintln(expectedFilesLog, filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", fname))
+	}
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", "1234", "snapd-new-file-log"),
+		testutil.FileEquals, expectedFilesLog.String())
+
+	// these things happen on snapd startup
+	restart.MockPending(s.state, restart.RestartUnset)
+	s.state.Set("tried-systems", []string{"1234"})
+	s.bootloader.SetBootVars(map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	})
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.IsReady(), Equals, true)
+	c.Assert(tskFinalize.Status(), Equals, state.DoneStatus)
+
+	var defaultSys devicestate.DefaultRecoverySystem
+	c.Assert(s.state.Get("default-recovery-system", &defaultSys), IsNil)
+	c.Check(defaultSys.System, Equals, "1234")
+	c.Check(defaultSys.Model, Equals, s.model.Model())
+	c.Check(defaultSys.BrandID, Equals, s.model.BrandID())
+	c.Check(defaultSys.TimeMadeDefault.IsZero(), Equals, false)
+	var previous devicestate.DefaultRecoverySystem
+	c.Assert(tskFinalize.Get("previous-default-recovery-system", &previous), IsNil)
+	c.Check(previous.System, Equals, "othersystem")
+	// the local snap file was removed once the change completed
+	c.Check(localKernel, testutil.FileAbsent)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemLocalSnapsErrors(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, tc := range []struct {
+		localSnaps []devicestate.LocalSnap
+		err        string
+	}{{
+		localSnaps: []devicestate.LocalSnap{{SideInfo: &snap.SideInfo{RealName: "foo"}, Path: "/foo.snap"}},
+		err:        `cannot use snap "foo" not listed in model canonical/pc-20`,
+	}, {
+		localSnaps: []devicestate.LocalSnap{
+			{SideInfo: &snap.SideInfo{RealName: "pc-kernel"}, Path: "/pc-kernel.snap"},
+			{SideInfo: &snap.SideInfo{RealName: "pc-kernel"}, Path: "/pc-kernel-other.snap"},
+		},
+		err: `cannot use snap "pc-kernel" provided more than once`,
+	}, {
+		localSnaps: []devicestate.LocalSnap{{Path: "/pc-kernel.snap"}},
+		err:        `internal error: incomplete local snap information`,
+	}} {
+		chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{
+			LocalSnaps: tc.localSnaps,
+		})
+		c.Check(err, ErrorMatches, tc.err)
+		c.Check(chg, IsNil)
+	}
+	// snapd is implicitly part of the model
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{
+		LocalSnaps: []devicestate.LocalSnap{{SideInfo: &snap.SideInfo{RealName: "snapd"}, Path: "/snapd.snap"}},
+	})
+	c.Assert(err, IsNil)
+	c.Check(chg, NotNil)
+}
+
+func (s *deviceMgrSystemsCreateSuite) mockRecoverySystems(c *C, current string, labels ...string) {
+	for _, label := range labels {
+		c.Assert(os.MkdirAll(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label), 0755), IsNil)
+	}
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	m.RecoverySystem = current
+	m.CurrentRecoverySystems = labels
+	m.GoodRecoverySystems = labels
+	c.Assert(m.Write(), IsNil)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerRemoveRecoverySystemTasksAndChange(c *C) {
+	s.mockRecoverySystems(c, "1234", "1234", "5678")
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chg, err := devicestate.RemoveRecoverySystem(s.state, "5678")
+	c.Assert(err, IsNil)
+	c.Assert(chg, NotNil)
+	c.Check(chg.Kind(), Equals, "remove-recovery-system")
+	tsks := chg.Tasks()
+	c.Assert(tsks, HasLen, 1)
+	c.Check(tsks[0].Kind(), Equals, "remove-recovery-system")
+	c.Check(tsks[0].Summary(), Equals, `Remove recovery system with label "5678"`)
+	var label string
+	c.Assert(tsks[0].Get("recovery-system-label", &label), IsNil)
+	c.Check(label, Equals, "5678")
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerRemoveRecoverySystemErrors(c *C) {
+	s.mockRecoverySystems(c, "1234", "1234", "5678", "default")
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("default-recovery-system", &devicestate.DefaultRecoverySystem{
+		System:  "default",
+		Model:   s.model.Model(),
+		BrandID: s.model.BrandID(),
+	})
+	s.state.Set("seeded-systems", []map[string]interface{}{{
+		"system": "5678", "model": s.model.Model(), "brand-id": s.model.BrandID(),
+	}})
+
+	for _, tc := range []struct {
+		label string
+		err   string
+	}{
+		{"missing", `recovery system does not exist`},
+		{"1234", `cannot remove the current recovery system "1234"`},
+		{"5678", `cannot remove the current recovery system "5678"`},
+		{"default", `cannot remove the default recovery system "default"`},
+	} {
+		chg, err := devicestate.RemoveRecoverySystem(s.state, tc.label)
+		c.Check(err, ErrorMatches, tc.err)
+		c.Check(chg, IsNil)
+	}
+	_, err := devicestate.RemoveRecoverySystem(s.state, "missing")
+	c.Check(err, Equals, devicestate.ErrNoRecoverySystem)
+
+	s.state.Set("seeded", nil)
+	_, err = devicestate.RemoveRecoverySystem(s.state, "1234")
+	c.Check(err, ErrorMatches, `cannot remove recovery systems until fully seeded`)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerRemoveRecoverySystemInvalidLabel(c *C) {
+	s.mockRecoverySystems(c, "1234", "1234", "5678")
+	// a directory outside of the systems directory that must be left alone
+	outside := filepath.Join(boot.InitramfsUbuntuSeedDir, "other")
+	c.Assert(os.MkdirAll(outside, 0755), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, label := range []string{"", "..", "../other", "5678/../../other", "../../../../etc", "/5678"} {
+		chg, err := devicestate.RemoveRecoverySystem(s.state, label)
+		c.Check(err, ErrorMatches, fmt.Sprintf(`invalid seed system label: %q`, label), Commentf("label %q", label))
+		c.Check(chg, IsNil)
+	}
+	c.Check(s.state.Changes(), HasLen, 0)
+	c.Check(outside, testutil.FilePresent)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerRemoveRecoverySystemLastGood(c *C) {
+	s.mockRecoverySystems(c, "", "1234")
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chg, err := devicestate.RemoveRecoverySystem(s.state, "1234")
+	c.Check(err, ErrorMatches, `cannot remove the last good recovery system "1234"`)
+	c.Check(chg, IsNil)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerRemoveRecoverySystemConflict(c *C) {
+	s.mockRecoverySystems(c, "1234", "1234", "5678")
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	other := s.state.NewChange("create-recovery-system", "...")
+	other.AddTask(s.state.NewTask("create-recovery-system", "..."))
+
+	chg, err := devicestate.RemoveRecoverySystem(s.state, "5678")
+	c.Check(chg, IsNil)
+	c.Assert(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err, ErrorMatches, `cannot remove recovery system "5678" while a create-recovery-system change is in progress`)
+	c.Check(err.(*snapstate.ChangeConflictError).ChangeID, Equals, other.ID())
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerRemoveRecoverySystemHappy(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+	s.mockRecoverySystems(c, "1234", "1234", "5678")
+	s.bootloader.SetBootVars(map[string]string{
+		"snapd_good_recovery_systems": "1234,5678",
+	})
+
+	s.state.Lock()
+	chg, err := devicestate.RemoveRecoverySystem(s.state, "5678")
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/5678"), testutil.FileAbsent)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FilePresent)
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.CurrentRecoverySystems, DeepEquals, []string{"1234"})
+	c.Check(m.GoodRecoverySystems, DeepEquals, []string{"1234"})
+	vars, err := s.bootloader.GetBootVars("snapd_good_recovery_systems")
+	c.Assert(err, IsNil)
+	c.Check(vars["snapd_good_recovery_systems"], Equals, "1234")
+}
+
 func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemodelDownloadingSnapsHappy(c *C) {
 	devicestate.SetBootOkRan(s.mgr, true)
 
@@ -1550,7 +1948,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemod
 	tSnapsup1.Set("snap-setup", snapsupFoo)
 	tSnapsup2.Set("snap-setup", snapsupBar)
 
-	tss, err := devicestate.CreateRecoverySystemTasks(s.state, "1234", []string{tSnapsup1.ID(), tSnapsup2.ID()})
+	tss, err := devicestate.CreateRecoverySystemTasks(s.state, "1234", []string{tSnapsup1.ID(), tSnapsup2.ID()}, devicestate.CreateRecoverySystemOptions{})
 	c.Assert(err, IsNil)
 	tsks := tss.Tasks()
 	c.Check(tsks, HasLen, 2)
@@ -1715,7 +2113,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemod
 	}
 	tSnapsup1.Set("snap-setup", snapsupFoo)
 
-	tss, err := devicestate.CreateRecoverySystemTasks(s.state, "1234missingdownload", []string{tSnapsup1.ID()})
+	tss, err := devicestate.CreateRecoverySystemTasks(s.state, "1234missingdownload", []string{tSnapsup1.ID()}, devicestate.CreateRecoverySystemOptions{})
 	c.Assert(err, IsNil)
 	tsks := tss.Tasks()
 	c.Check(tsks, HasLen, 2)
@@ -1794,7 +2192,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemUndo(
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	s.state.Lock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234undo")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234undo", devicestate.CreateRecoverySystemOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -1910,7 +2308,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemFinal
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	s.state.Lock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -2009,7 +2407,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemErrCl
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	s.state.Lock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234error")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234error", devicestate.CreateRecoverySystemOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -2080,7 +2478,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemReboo
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	s.state.Lock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234reboot")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234reboot", devicestate.CreateRecoverySystemOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -308,6 +308,14 @@ func MockGadgetUpdate(mock func(model gadget.Model, current, update gadget.Gadge
 	}
 }
 
+func MockGadgetRollback(mock func(model gadget.Model, current, update gadget.GadgetData, path string, policy gadget.UpdatePolicyFunc, observer gadget.ContentUpdateObserver) error) (restore func()) {
+	old := gadgetRollback
+	gadgetRollback = mock
+	return func() {
+		gadgetRollback = old
+	}
+}
+
 func MockGadgetIsCompatible(mock func(current, update *gadget.Info) error) (restore func()) {
 	old := gadgetIsCompatible
 	gadgetIsCompatible = mock
@@ -390,7 +398,7 @@ func MockInstallMountVolumes(f func(onVolumes map[string]*gadget.Volume, encSetu
 	}
 }
 
-func MockInstallEncryptPartitions(f func(onVolumes map[string]*gadget.Volume, encryptionType secboot.EncryptionType, model *asserts.Model, gadgetRoot, kernelRoot string, perfTimings timings.Measurer) (*install.EncryptionSetupData, error)) (restore func()) {
+func MockInstallEncryptPartitions(f func(onVolumes map[string]*gadget.Volume, encryptionType secboot.EncryptionType, passphrase string, model *asserts.Model, gadgetRoot, kernelRoot string, perfTimings timings.Measurer) (*install.EncryptionSetupData, error)) (restore func()) {
 	old := installEncryptPartitions
 	installEncryptPartitions = f
 	return func() {
@@ -486,6 +494,24 @@ func MockSecbootRemoveRecoveryKeys(f func(rkeyDevToKey map[secboot.RecoveryKeyDe
 	return restore
 }
 
+func MockSecbootAddNamedRecoveryKey(f func(name, keyFile string, rkeyDevs []secboot.RecoveryKeyDevice) (keys.RecoveryKey, error)) (restore func()) {
+	restore = testutil.Backup(&secbootAddNamedRecoveryKey)
+	secbootAddNamedRecoveryKey = f
+	return restore
+}
+
+func MockSecbootRemoveNamedRecoveryKey(f func(name string, rkeyDevs []secboot.RecoveryKeyDevice) error) (restore func()) {
+	restore = testutil.Backup(&secbootRemoveNamedRecoveryKey)
+	secbootRemoveNamedRecoveryKey = f
+	return restore
+}
+
+func MockSecbootListRecoveryKeys(f func(rkeyDevs []secboot.RecoveryKeyDevice) ([]secboot.RecoveryKeyInfo, error)) (restore func()) {
+	restore = testutil.Backup(&secbootListRecoveryKeys)
+	secbootListRecoveryKeys = f
+	return restore
+}
+
 func MockMarkFactoryResetComplete(f func(encrypted bool) error) (restore func()) {
 	restore = testutil.Backup(&bootMarkFactoryResetComplete)
 	bootMarkFactoryResetComplete = f
@@ -568,3 +594,20 @@ func CleanUpEncryptionSetupDataInCache(st *state.State, label string) {
 	key := encryptionSetupDataKey{label}
 	st.Cache(key, nil)
 }
+
+func MockInstallPassphraseInCache(st *state.State, label, passphrase string) {
+	st.Cache(installPassphraseKey{label}, passphrase)
+}
+
+func InstallPassphraseFromCache(st *state.State, label string) interface{} {
+	return st.Cached(installPassphraseKey{label})
+}
+
+type PreservedPath = preservedPath
+
+var (
+	StagePreservedPaths              = stagePreservedPaths
+	RestorePreservedPaths            = restorePreservedPaths
+	StageFactoryResetPreservedData   = stageFactoryResetPreservedData
+	RestoreFactoryResetPreservedData = restoreFactoryResetPreservedData
+)
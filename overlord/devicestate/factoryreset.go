@@ -0,0 +1,243 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// The states of a path preserved on factory reset.
+const (
+	preservedStaged   = "staged"
+	preservedMissing  = "missing"
+	preservedTooLarge = "too-large"
+	preservedRestored = "restored"
+	preservedFailed   = "failed"
+)
+
+// preservedPath is the status of a path declared by the gadget to be
+// preserved on factory reset.
+type preservedPath struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// factoryResetPreservedDir returns the directory on ubuntu-save where the data
+// preserved on factory reset is staged, ubuntu-save is mounted there in all
+// modes.
+func factoryResetPreservedDir() string {
+	return filepath.Join(boot.InstallHostDeviceSaveDir, "factory-reset")
+}
+
+func preservedManifest(stageDir string) string {
+	return filepath.Join(stageDir, "manifest.json")
+}
+
+func copyPreserved(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if output, err := exec.Command("cp", "-a", src, dst).CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot copy %q: %v", src, osutil.OutputErr(output, err))
+	}
+	return nil
+}
+
+// contentSize returns the size of the regular files at or under path.
+func contentSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// stagePreservedPaths copies the paths to preserve from fromDir, the root of
+// the system data, to stageDir along with a manifest of their status. Paths
+// that are missing or larger than allowed are not preserved.
+func stagePreservedPaths(preserve []gadget.FactoryResetPreservedPath, fromDir, stageDir string) ([]preservedPath, error) {
+	// drop anything left behind by a previous request
+	if err := os.RemoveAll(stageDir); err != nil {
+		return nil, err
+	}
+	if len(preserve) == 0 {
+		return nil, nil
+	}
+
+	paths := make([]preservedPath, 0, len(preserve))
+	for _, p := range preserve {
+		src := filepath.Join(fromDir, p.Path)
+		pp := preservedPath{Path: p.Path}
+		size, err := contentSize(src)
+		switch {
+		case os.IsNotExist(err):
+			pp.Status = preservedMissing
+		case err != nil:
+			return nil, fmt.Errorf("cannot preserve %q: %v", p.Path, err)
+		case size > int64(p.MaxSize):
+			pp.Size = size
+			pp.Status = preservedTooLarge
+			pp.Error = fmt.Sprintf("size %d exceeds the maximum of %d", size, p.MaxSize)
+		default:
+			if err := copyPreserved(src, filepath.Join(stageDir, "data", p.Path)); err != nil {
+				return nil, fmt.Errorf("cannot preserve %q: %v", p.Path, err)
+			}
+			pp.Size = size
+			pp.Status = preservedStaged
+		}
+		if pp.Status != preservedStaged {
+			logger.Noticef("not preserving %q on factory reset: %s %s", p.Path, pp.Status, pp.Error)
+		}
+		paths = append(paths, pp)
+	}
+
+	manifest, err := json.Marshal(paths)
+	if err != nil {
+		return nil, err
+	}
+	// nothing may have been staged if all paths are missing or too large
+	if err := os.MkdirAll(stageDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := osutil.AtomicWriteFile(preservedManifest(stageDir), manifest, 0600, 0); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// restorePreservedPaths copies the staged paths from stageDir back to toDir,
+// the root of the new system data, and removes the staged data. A path that
+// cannot be restored is reported as failed without failing the whole
+// operation.
+func restorePreservedPaths(stageDir, toDir string) ([]preservedPath, error) {
+	manifest, err := ioutil.ReadFile(preservedManifest(stageDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var paths []preservedPath
+	if err := json.Unmarshal(manifest, &paths); err != nil {
+		return nil, fmt.Errorf("cannot decode the manifest of preserved data: %v", err)
+	}
+	for i := range paths {
+		pp := &paths[i]
+		if pp.Status != preservedStaged {
+			continue
+		}
+		dst := filepath.Join(toDir, pp.Path)
+		err := os.RemoveAll(dst)
+		if err == nil {
+			err = copyPreserved(filepath.Join(stageDir, "data", pp.Path), dst)
+		}
+		if err != nil {
+			logger.Noticef("cannot restore preserved %q: %v", pp.Path, err)
+			pp.Status = preservedFailed
+			pp.Error = err.Error()
+			continue
+		}
+		pp.Status = preservedRestored
+	}
+	if err := os.RemoveAll(stageDir); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// stageFactoryResetPreservedData stages the data that the gadget declares to
+// be preserved on factory reset, it must be called before switching to
+// factory-reset mode from run or recover mode.
+func stageFactoryResetPreservedData(st *state.State, deviceCtx snapstate.DeviceContext) error {
+	gadgetInfo, err := snapstate.GadgetInfo(st, deviceCtx)
+	if errors.Is(err, state.ErrNoState) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ginfo, err := gadget.ReadInfo(gadgetInfo.MountDir(), deviceCtx.Model())
+	if err != nil {
+		return err
+	}
+	preserve := ginfo.FactoryReset.Preserve
+
+	stageDir := factoryResetPreservedDir()
+	if len(preserve) > 0 {
+		mounted, err := osutil.IsMounted(boot.InitramfsUbuntuSaveDir)
+		if err != nil {
+			return fmt.Errorf("cannot determine ubuntu-save mount state: %v", err)
+		}
+		if !mounted {
+			return fmt.Errorf("cannot preserve data without ubuntu-save")
+		}
+	} else if !osutil.IsDirectory(stageDir) {
+		return nil
+	}
+
+	// the data of the current system, ubuntu-data is mounted at the same
+	// location in both run and recover modes, while its layout depends on
+	// whether the model is a classic one
+	fromDir := boot.InitramfsHostWritableDir(deviceCtx.Model())
+	_, err = stagePreservedPaths(preserve, fromDir, stageDir)
+	return err
+}
+
+// restoreFactoryResetPreservedData restores the data staged before the factory
+// reset into the new system data and records the outcome in the change.
+func restoreFactoryResetPreservedData(t *state.Task, model gadget.Model) error {
+	paths, err := restorePreservedPaths(factoryResetPreservedDir(), boot.InstallHostWritableDir(model))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	for _, pp := range paths {
+		if pp.Error != "" {
+			t.Logf("Preserved path %q: %s (%s)", pp.Path, pp.Status, pp.Error)
+		} else {
+			t.Logf("Preserved path %q: %s", pp.Path, pp.Status)
+		}
+	}
+	t.Change().Set("preserved-data", paths)
+	return nil
+}
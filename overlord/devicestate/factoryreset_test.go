@@ -0,0 +1,284 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/boot/boottest"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type factoryResetPreserveSuite struct {
+	testutil.BaseTest
+
+	rootDir  string
+	stageDir string
+}
+
+var _ = Suite(&factoryResetPreserveSuite{})
+
+func (s *factoryResetPreserveSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.rootDir = c.MkDir()
+	dirs.SetRootDir(s.rootDir)
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+	s.stageDir = filepath.Join(boot.InstallHostDeviceSaveDir, "factory-reset")
+}
+
+func (s *factoryResetPreserveSuite) mockData(c *C, root string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		c.Assert(os.MkdirAll(filepath.Dir(p), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(p, []byte(content), 0644), IsNil)
+	}
+}
+
+func (s *factoryResetPreserveSuite) TestStageAndRestoreHappy(c *C) {
+	fromDir := filepath.Join(s.rootDir, "old-data")
+	s.mockData(c, fromDir, map[string]string{
+		"var/lib/kiosk/calibration/touch": "touch calibration",
+		"var/lib/kiosk/calibration/color": "color calibration",
+		"etc/kiosk.conf":                  "conf",
+		"var/lib/big/blob":                strings.Repeat("x", 2048),
+	})
+	preserve := []gadget.FactoryResetPreservedPath{
+		{Path: "var/lib/kiosk/calibration", MaxSize: quantity.SizeMiB},
+		{Path: "etc/kiosk.conf", MaxSize: quantity.SizeMiB},
+		{Path: "var/lib/missing", MaxSize: quantity.SizeMiB},
+		{Path: "var/lib/big", MaxSize: 1024},
+	}
+
+	paths, err := devicestate.StagePreservedPaths(preserve, fromDir, s.stageDir)
+	c.Assert(err, IsNil)
+	c.Check(paths, DeepEquals, []devicestate.PreservedPath{
+		{Path: "var/lib/kiosk/calibration", Size: 34, Status: "staged"},
+		{Path: "etc/kiosk.conf", Size: 4, Status: "staged"},
+		{Path: "var/lib/missing", Status: "missing"},
+		{Path: "var/lib/big", Size: 2048, Status: "too-large", Error: "size 2048 exceeds the maximum of 1024"},
+	})
+	c.Check(filepath.Join(s.stageDir, "data/var/lib/kiosk/calibration/touch"), testutil.FileEquals, "touch calibration")
+	c.Check(filepath.Join(s.stageDir, "data/var/lib/big"), testutil.FileAbsent)
+	c.Check(filepath.Join(s.stageDir, "manifest.json"), testutil.FilePresent)
+
+	toDir := filepath.Join(s.rootDir, "new-data")
+	// the new system may ship some of the content already
+	s.mockData(c, toDir, map[string]string{
+		"etc/kiosk.conf": "default conf",
+	})
+	paths, err = devicestate.RestorePreservedPaths(s.stageDir, toDir)
+	c.Assert(err, IsNil)
+	c.Check(paths, DeepEquals, []devicestate.PreservedPath{
+		{Path: "var/lib/kiosk/calibration", Size: 34, Status: "restored"},
+		{Path: "etc/kiosk.conf", Size: 4, Status: "restored"},
+		{Path: "var/lib/missing", Status: "missing"},
+		{Path: "var/lib/big", Size: 2048, Status: "too-large", Error: "size 2048 exceeds the maximum of 1024"},
+	})
+	c.Check(filepath.Join(toDir, "var/lib/kiosk/calibration/touch"), testutil.FileEquals, "touch calibration")
+	c.Check(filepath.Join(toDir, "var/lib/kiosk/calibration/color"), testutil.FileEquals, "color calibration")
+	c.Check(filepath.Join(toDir, "etc/kiosk.conf"), testutil.FileEquals, "conf")
+	c.Check(filepath.Join(toDir, "var/lib/big"), testutil.FileAbsent)
+	// staged data is gone
+	c.Check(s.stageDir, testutil.FileAbsent)
+}
+
+func (s *factoryResetPreserveSuite) TestStageDropsStale(c *C) {
+	s.mockData(c, s.stageDir, map[string]string{
+		"data/var/lib/stale": "stale",
+		"manifest.json":      `[{"path":"var/lib/stale","status":"staged"}]`,
+	})
+
+	paths, err := devicestate.StagePreservedPaths(nil, s.rootDir, s.stageDir)
+	c.Assert(err, IsNil)
+	c.Check(paths, HasLen, 0)
+	c.Check(s.stageDir, testutil.FileAbsent)
+}
+
+func (s *factoryResetPreserveSuite) TestStageAllMissing(c *C) {
+	preserve := []gadget.FactoryResetPreservedPath{
+		{Path: "var/lib/missing", MaxSize: quantity.SizeMiB},
+	}
+	paths, err := devicestate.StagePreservedPaths(preserve, s.rootDir, s.stageDir)
+	c.Assert(err, IsNil)
+	c.Check(paths, DeepEquals, []devicestate.PreservedPath{
+		{Path: "var/lib/missing", Status: "missing"},
+	})
+	// the outcome is still recorded for the restore
+	c.Check(filepath.Join(s.stageDir, "manifest.json"), testutil.FilePresent)
+}
+
+func (s *factoryResetPreserveSuite) TestRestoreNothingStaged(c *C) {
+	paths, err := devicestate.RestorePreservedPaths(s.stageDir, s.rootDir)
+	c.Assert(err, IsNil)
+	c.Check(paths, HasLen, 0)
+}
+
+func (s *factoryResetPreserveSuite) TestRestoreFailedPath(c *C) {
+	s.mockData(c, s.stageDir, map[string]string{
+		"data/var/lib/ok": "ok",
+		"manifest.json":   `[{"path":"var/lib/ok","status":"staged"},{"path":"var/lib/gone","status":"staged"}]`,
+	})
+	toDir := filepath.Join(s.rootDir, "new-data")
+
+	paths, err := devicestate.RestorePreservedPaths(s.stageDir, toDir)
+	c.Assert(err, IsNil)
+	c.Assert(paths, HasLen, 2)
+	c.Check(paths[0], DeepEquals, devicestate.PreservedPath{Path: "var/lib/ok", Status: "restored"})
+	c.Check(paths[1].Status, Equals, "failed")
+	c.Check(paths[1].Error, Matches, `cannot copy ".*/var/lib/gone": .*`)
+	c.Check(filepath.Join(toDir, "var/lib/ok"), testutil.FileEquals, "ok")
+}
+
+func (s *factoryResetPreserveSuite) mockGadget(c *C, st *state.State, gadgetYaml string) *snapstatetest.TrivialDeviceContext {
+	return s.mockGadgetForModel(c, st, gadgetYaml, boottest.MakeMockUC20Model())
+}
+
+func (s *factoryResetPreserveSuite) mockGadgetForModel(c *C, st *state.State, gadgetYaml string, model *asserts.Model) *snapstatetest.TrivialDeviceContext {
+	si := &snap.SideInfo{RealName: "pc", Revision: snap.R(1), SnapID: "pc-id"}
+	snaptest.MockSnapWithFiles(c, "name: pc\ntype: gadget\nversion: 1", si, [][]string{
+		{"meta/gadget.yaml", gadgetYaml},
+	})
+	snapstate.Set(st, "pc", &snapstate.SnapState{
+		SnapType: "gadget",
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+		Active:   true,
+	})
+	return &snapstatetest.TrivialDeviceContext{DeviceModel: model}
+}
+
+const preservingGadgetYaml = `
+volumes:
+  pc:
+    bootloader: grub
+factory-reset:
+  preserve:
+    - path: var/lib/kiosk
+`
+
+func (s *factoryResetPreserveSuite) TestStageFactoryResetPreservedDataHappy(c *C) {
+	s.AddCleanup(osutil.MockMountInfo(fmt.Sprintf(`26 27 8:3 / %s/run/mnt/ubuntu-save rw,relatime shared:7 - ext4 /dev/fakedevice0p1 rw,data=ordered`, s.rootDir)))
+	s.mockData(c, boot.InitramfsHostWritableDir(boottest.MakeMockUC20Model()), map[string]string{
+		"var/lib/kiosk/calibration": "calibration",
+	})
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+	deviceCtx := s.mockGadget(c, st, preservingGadgetYaml)
+
+	err := devicestate.StageFactoryResetPreservedData(st, deviceCtx)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(s.stageDir, "data/var/lib/kiosk/calibration"), testutil.FileEquals, "calibration")
+}
+
+func (s *factoryResetPreserveSuite) TestStageFactoryResetPreservedDataClassicModel(c *C) {
+	s.AddCleanup(osutil.MockMountInfo(fmt.Sprintf(`26 27 8:3 / %s/run/mnt/ubuntu-save rw,relatime shared:7 - ext4 /dev/fakedevice0p1 rw,data=ordered`, s.rootDir)))
+	model := boottest.MakeMockClassicWithModesModel()
+	// the data of classic models is not under system-data
+	s.mockData(c, filepath.Join(s.rootDir, "run/mnt/host/ubuntu-data"), map[string]string{
+		"var/lib/kiosk/calibration": "classic calibration",
+	})
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+	deviceCtx := s.mockGadgetForModel(c, st, preservingGadgetYaml, model)
+
+	err := devicestate.StageFactoryResetPreservedData(st, deviceCtx)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(s.stageDir, "data/var/lib/kiosk/calibration"), testutil.FileEquals, "classic calibration")
+}
+
+func (s *factoryResetPreserveSuite) TestStageFactoryResetPreservedDataNoSave(c *C) {
+	s.AddCleanup(osutil.MockMountInfo(``))
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+	deviceCtx := s.mockGadget(c, st, preservingGadgetYaml)
+
+	err := devicestate.StageFactoryResetPreservedData(st, deviceCtx)
+	c.Assert(err, ErrorMatches, "cannot preserve data without ubuntu-save")
+}
+
+func (s *factoryResetPreserveSuite) TestStageFactoryResetPreservedDataNothingDeclared(c *C) {
+	s.AddCleanup(osutil.MockMountInfo(``))
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+	deviceCtx := s.mockGadget(c, st, `
+volumes:
+  pc:
+    bootloader: grub
+`)
+
+	err := devicestate.StageFactoryResetPreservedData(st, deviceCtx)
+	c.Assert(err, IsNil)
+	c.Check(s.stageDir, testutil.FileAbsent)
+}
+
+func (s *factoryResetPreserveSuite) TestRestoreFactoryResetPreservedData(c *C) {
+	model := boottest.MakeMockUC20Model()
+	s.mockData(c, s.stageDir, map[string]string{
+		"data/var/lib/kiosk/calibration": "calibration",
+		"manifest.json":                  `[{"path":"var/lib/kiosk","size":11,"status":"staged"},{"path":"var/lib/big","size":2048,"status":"too-large","error":"size 2048 exceeds the maximum of 1024"}]`,
+	})
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+	chg := st.NewChange("factory-reset", "...")
+	t := st.NewTask("factory-reset-run-system", "...")
+	chg.AddTask(t)
+
+	err := devicestate.RestoreFactoryResetPreservedData(t, model)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(boot.InstallHostWritableDir(model), "var/lib/kiosk/calibration"), testutil.FileEquals, "calibration")
+
+	var paths []devicestate.PreservedPath
+	c.Assert(chg.Get("preserved-data", &paths), IsNil)
+	c.Check(paths, DeepEquals, []devicestate.PreservedPath{
+		{Path: "var/lib/kiosk", Size: 11, Status: "restored"},
+		{Path: "var/lib/big", Size: 2048, Status: "too-large", Error: "size 2048 exceeds the maximum of 1024"},
+	})
+	c.Assert(t.Log(), HasLen, 2)
+	c.Check(t.Log()[0], Matches, `.* INFO Preserved path "var/lib/kiosk": restored`)
+	c.Check(t.Log()[1], Matches, `.* INFO Preserved path "var/lib/big": too-large \(size 2048 exceeds the maximum of 1024\)`)
+}
@@ -23,6 +23,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"gopkg.in/tomb.v2"
 
@@ -39,16 +41,6 @@ import (
 	"github.com/snapcore/snapd/strutil"
 )
 
-func makeRollbackDir(name string) (string, error) {
-	rollbackDir := filepath.Join(dirs.SnapRollbackDir, name)
-
-	if err := os.MkdirAll(rollbackDir, 0750); err != nil {
-		return "", err
-	}
-
-	return rollbackDir, nil
-}
-
 // CurrentGadgetData returns the GadgetData for the currently active gadget.
 func CurrentGadgetData(st *state.State, curDeviceCtx snapstate.DeviceContext) (*gadget.GadgetData, error) {
 	currentInfo, err := snapstate.GadgetInfo(st, curDeviceCtx)
@@ -81,25 +73,37 @@ func pendingGadgetData(snapsup *snapstate.SnapSetup, pendingDeviceCtx snapstate.
 }
 
 var (
-	gadgetUpdate = gadget.Update
+	gadgetUpdate   = gadget.Update
+	gadgetRollback = gadget.RollbackUpdate
 )
 
-func (m *DeviceManager) doUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error {
+// gadgetAssetsUpdate carries the arguments of a gadget assets update.
+type gadgetAssetsUpdate struct {
+	model       *asserts.Model
+	current     *gadget.GadgetData
+	update      *gadget.GadgetData
+	rollbackDir string
+	policy      gadget.UpdatePolicyFunc
+	observer    gadget.ContentUpdateObserver
+}
+
+// prepareGadgetAssetsUpdate collects the arguments for the gadget assets
+// update of the given task. It returns nil if there is nothing to update
+// during first boot and seeding.
+func prepareGadgetAssetsUpdate(t *state.Task) (*gadgetAssetsUpdate, error) {
 	st := t.State()
-	st.Lock()
-	defer st.Unlock()
 
 	snapsup, err := snapstate.TaskSnapSetup(t)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	remodelCtx, err := DeviceCtx(st, t, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if remodelCtx.IsClassicBoot() {
-		return fmt.Errorf("cannot run update gadget assets task on a classic system")
+		return nil, fmt.Errorf("cannot run update gadget assets task on a classic system")
 	}
 	isRemodel := remodelCtx.ForRemodeling()
 	groundDeviceCtx := remodelCtx.GroundContext()
@@ -114,18 +118,18 @@ func (m *DeviceManager) doUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error
 	case snap.TypeGadget:
 		expectedGadgetSnap := model.Gadget()
 		if snapsup.InstanceName() != expectedGadgetSnap {
-			return fmt.Errorf("cannot apply gadget assets update from non-model gadget snap %q, expected %q snap",
+			return nil, fmt.Errorf("cannot apply gadget assets update from non-model gadget snap %q, expected %q snap",
 				snapsup.InstanceName(), expectedGadgetSnap)
 		}
 
 		updateData, err = pendingGadgetData(snapsup, remodelCtx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	case snap.TypeKernel:
 		expectedKernelSnap := model.Kernel()
 		if snapsup.InstanceName() != expectedKernelSnap {
-			return fmt.Errorf("cannot apply kernel assets update from non-model kernel snap %q, expected %q snap",
+			return nil, fmt.Errorf("cannot apply kernel assets update from non-model kernel snap %q, expected %q snap",
 				snapsup.InstanceName(), expectedKernelSnap)
 		}
 
@@ -133,19 +137,19 @@ func (m *DeviceManager) doUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error
 		// argumented from a different kernel
 		updateData, err = CurrentGadgetData(t.State(), groundDeviceCtx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	default:
-		return fmt.Errorf("internal errror: doUpdateGadgetAssets called with snap type %v", snapsup.Type)
+		return nil, fmt.Errorf("internal errror: doUpdateGadgetAssets called with snap type %v", snapsup.Type)
 	}
 
 	currentData, err := CurrentGadgetData(t.State(), groundDeviceCtx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if currentData == nil {
 		// no updates during first boot & seeding
-		return nil
+		return nil, nil
 	}
 
 	// add kernel directories
@@ -161,16 +165,11 @@ func (m *DeviceManager) doUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error
 	if snapsup.Type == snap.TypeKernel {
 		updateKernelInfo, err := snap.ReadInfo(snapsup.InstanceName(), snapsup.SideInfo)
 		if err != nil {
-			return fmt.Errorf("cannot read candidate kernel snap details: %v", err)
+			return nil, fmt.Errorf("cannot read candidate kernel snap details: %v", err)
 		}
 		updateData.KernelRootDir = updateKernelInfo.MountDir()
 	}
 
-	snapRollbackDir, err := makeRollbackDir(fmt.Sprintf("%v_%v", snapsup.InstanceName(), snapsup.SideInfo.Revision))
-	if err != nil {
-		return fmt.Errorf("cannot prepare update rollback directory: %v", err)
-	}
-
 	var updatePolicy gadget.UpdatePolicyFunc = nil
 
 	// Even with a remodel a kernel refresh only updates the kernel assets
@@ -185,27 +184,125 @@ func (m *DeviceManager) doUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error
 	var updateObserver gadget.ContentUpdateObserver
 	observeTrustedBootAssets, err := boot.TrustedAssetsUpdateObserverForModel(model, updateData.RootDir)
 	if err != nil && err != boot.ErrObserverNotApplicable {
-		return fmt.Errorf("cannot setup asset update observer: %v", err)
+		return nil, fmt.Errorf("cannot setup asset update observer: %v", err)
 	}
 	if err == nil {
 		updateObserver = observeTrustedBootAssets
 	}
+
+	return &gadgetAssetsUpdate{
+		model:       model,
+		current:     currentData,
+		update:      updateData,
+		rollbackDir: gadgetRollbackDir(snapsup),
+		policy:      updatePolicy,
+		observer:    updateObserver,
+	}, nil
+}
+
+// gadgetRollbackDir returns the directory keeping the backups of the
+// gadget assets update for the given snap.
+func gadgetRollbackDir(snapsup *snapstate.SnapSetup) string {
+	return filepath.Join(dirs.SnapRollbackDir, fmt.Sprintf("%v_%v", snapsup.InstanceName(), snapsup.SideInfo.Revision))
+}
+
+// GadgetUpdateStatus is the status of a gadget assets update as recorded in
+// its journal.
+type GadgetUpdateStatus struct {
+	Snap     string        `json:"snap"`
+	Revision snap.Revision `json:"revision"`
+	*gadget.UpdateJournal
+}
+
+// GadgetUpdateStatuses returns the status of the gadget assets updates that
+// have a journal, oldest first.
+func GadgetUpdateStatuses() ([]*GadgetUpdateStatus, error) {
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapRollbackDir, "*_*.journal"))
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]*GadgetUpdateStatus, 0, len(matches))
+	for _, match := range matches {
+		rollbackDir := strings.TrimSuffix(match, ".journal")
+		// see gadgetRollbackDir, instance names may contain an
+		// underscore too
+		base := filepath.Base(rollbackDir)
+		idx := strings.LastIndex(base, "_")
+		rev, err := snap.ParseRevision(base[idx+1:])
+		if err != nil {
+			logger.Noticef("cannot parse revision of gadget update journal %q: %v", match, err)
+			continue
+		}
+		journal, err := gadget.ReadUpdateJournal(rollbackDir)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, &GadgetUpdateStatus{
+			Snap:          base[:idx],
+			Revision:      rev,
+			UpdateJournal: journal,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Started.Before(statuses[j].Started)
+	})
+	return statuses, nil
+}
+
+func (m *DeviceManager) doUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	up, err := prepareGadgetAssetsUpdate(t)
+	if err != nil {
+		return err
+	}
+	if up == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(up.rollbackDir, 0750); err != nil {
+		return fmt.Errorf("cannot prepare update rollback directory: %v", err)
+	}
+
+	journal, err := gadget.ReadUpdateJournal(up.rollbackDir)
+	if err != nil {
+		return err
+	}
+	interrupted := journal.Interrupted()
+	if interrupted {
+		// the backups taken by the interrupted update are reused
+		t.Logf("Resuming interrupted gadget assets update")
+	}
+
 	// do not release the state lock, the update observer may attempt to
 	// modify modeenv inside, which implicitly is guarded by the state lock;
 	// on top of that we do not expect the update to be moving large amounts
 	// of data
-	err = gadgetUpdate(model, *currentData, *updateData, snapRollbackDir, updatePolicy, updateObserver)
+	err = gadgetUpdate(up.model, *up.current, *up.update, up.rollbackDir, up.policy, up.observer)
 	if err != nil {
 		if err == gadget.ErrNoUpdate {
 			// no update needed
 			t.Logf("No gadget assets update needed")
 			return nil
 		}
+		if interrupted {
+			// the update could not be resumed, do not leave a mix of
+			// old and new structures behind
+			if rerr := rollbackInterruptedGadgetUpdate(t, up); rerr != nil {
+				t.Errorf("cannot roll back interrupted gadget assets update: %v", rerr)
+			}
+		}
 		return err
 	}
 
-	if err := os.RemoveAll(snapRollbackDir); err != nil && !os.IsNotExist(err) {
-		logger.Noticef("failed to remove gadget update rollback directory %q: %v", snapRollbackDir, err)
+	if err := os.RemoveAll(up.rollbackDir); err != nil && !os.IsNotExist(err) {
+		logger.Noticef("failed to remove gadget update rollback directory %q: %v", up.rollbackDir, err)
+	}
+	// the update is complete, there is nothing left to resume or roll back
+	if err := os.Remove(gadget.UpdateJournalPath(up.rollbackDir)); err != nil && !os.IsNotExist(err) {
+		logger.Noticef("failed to remove gadget update journal %q: %v", gadget.UpdateJournalPath(up.rollbackDir), err)
 	}
 
 	// TODO: consider having the option to do this early via recovery in
@@ -213,6 +310,20 @@ func (m *DeviceManager) doUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error
 	return snapstate.FinishTaskWithRestart(t, state.DoneStatus, restart.RestartSystem, nil)
 }
 
+// rollbackInterruptedGadgetUpdate restores the structures an interrupted
+// gadget assets update may have modified, as recorded in its journal.
+func rollbackInterruptedGadgetUpdate(t *state.Task, up *gadgetAssetsUpdate) error {
+	err := gadgetRollback(up.model, *up.current, *up.update, up.rollbackDir, up.policy, up.observer)
+	if err != nil {
+		return err
+	}
+	t.Logf("Rolled back interrupted gadget assets update")
+	if err := os.RemoveAll(up.rollbackDir); err != nil && !os.IsNotExist(err) {
+		logger.Noticef("failed to remove gadget update rollback directory %q: %v", up.rollbackDir, err)
+	}
+	return nil
+}
+
 // fromSystemOption tells us if t was created when setting a system
 // option for the kernel command line.
 func fromSystemOption(t *state.Task) bool {
@@ -857,6 +857,17 @@ var maybeApplyPreseededData = func(st *state.State, ubuntuSeedDir, sysLabel, wri
 	return true, nil
 }
 
+// checkFactoryResetSealingMethod checks that the keys of the system can be
+// replaced by a factory reset. The passphrase of a system encrypted with a
+// passphrase is only known to the user and is not available to protect the
+// new keys, such systems need to be reinstalled instead.
+func checkFactoryResetSealingMethod() error {
+	if device.HasPassphraseMarkerUnder(boot.InitramfsSeedEncryptionKeyDir) {
+		return fmt.Errorf("cannot perform factory reset of a system encrypted with a passphrase, reinstall the system instead")
+	}
+	return nil
+}
+
 func (m *DeviceManager) doFactoryResetRunSystem(t *state.Task, _ *tomb.Tomb) error {
 	st := t.State()
 	st.Lock()
@@ -888,6 +899,9 @@ func (m *DeviceManager) doFactoryResetRunSystem(t *state.Task, _ *tomb.Tomb) err
 	if modeEnv == nil {
 		return fmt.Errorf("missing modeenv, cannot proceed")
 	}
+	if err := checkFactoryResetSealingMethod(); err != nil {
+		return err
+	}
 
 	// bootstrap
 	bopts := install.Options{
@@ -1009,6 +1023,9 @@ func (m *DeviceManager) doFactoryResetRunSystem(t *state.Task, _ *tomb.Tomb) err
 	if err := restoreDeviceFromSave(model); err != nil {
 		return fmt.Errorf("cannot restore data from save: %v", err)
 	}
+	if err := restoreFactoryResetPreservedData(t, model); err != nil {
+		return fmt.Errorf("cannot restore preserved data: %v", err)
+	}
 
 	// make it bootable
 	logger.Noticef("make system runnable")
@@ -1240,6 +1257,12 @@ type encryptionSetupDataKey struct {
 	systemLabel string
 }
 
+// installPassphraseKey is the key used to keep the passphrase provided for
+// the installation in memory only.
+type installPassphraseKey struct {
+	systemLabel string
+}
+
 func mountSeedSnap(seedSn *seed.Snap) (mountpoint string, unmount func() error, err error) {
 	mountpoint = filepath.Join(dirs.SnapRunDir, "snap-content", string(seedSn.EssentialType))
 	if err := os.MkdirAll(mountpoint, 0755); err != nil {
@@ -1395,6 +1418,9 @@ func (m *DeviceManager) doInstallFinish(t *state.Task, _ *tomb.Tomb) error {
 			if err := prepareEncryptedSystemData(sys.Model, install.KeysForRole(encryptSetupData), trustedInstallObserver); err != nil {
 				return err
 			}
+			if encryptSetupData.UsesPassphrase() {
+				trustedInstallObserver.ChosenPassphraseProtection()
+			}
 		}
 	}
 
@@ -1471,11 +1497,31 @@ func (m *DeviceManager) doInstallSetupStorageEncryption(t *state.Task, _ *tomb.T
 		return fmt.Errorf("reading gadget information: %v", err)
 	}
 
+	var usePassphrase bool
+	if err := t.Get("use-passphrase", &usePassphrase); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	var passphrase string
+	if usePassphrase {
+		// the passphrase is never stored in the state
+		cached, ok := st.Cached(installPassphraseKey{systemLabel}).(string)
+		if !ok || cached == "" {
+			return fmt.Errorf("cannot find the passphrase for installing system %q, the step needs to be retried", systemLabel)
+		}
+		passphrase = cached
+		defer st.Cache(installPassphraseKey{systemLabel}, nil)
+	}
+
 	encryptInfo, err := m.encryptionSupportInfo(sys.Model, secboot.TPMProvisionFull, snapInfos[snap.TypeKernel], gadgetInfo)
 	if err != nil {
 		return err
 	}
-	if !encryptInfo.Available {
+	switch {
+	case usePassphrase && encryptInfo.Disabled:
+		// a passphrase does not need a TPM, but encryption must
+		// not have been disabled explicitly
+		return fmt.Errorf("encryption unavailable on this device: encryption is disabled")
+	case !usePassphrase && !encryptInfo.Available:
 		var whyStr string
 		if encryptInfo.UnavailableErr != nil {
 			whyStr = encryptInfo.UnavailableErr.Error()
@@ -1487,7 +1533,7 @@ func (m *DeviceManager) doInstallSetupStorageEncryption(t *state.Task, _ *tomb.T
 
 	// TODO:ICE: support secboot.EncryptionTypeLUKSWithICE in the API
 	encType := secboot.EncryptionTypeLUKS
-	encryptionSetupData, err := installEncryptPartitions(onVolumes, encType, sys.Model, mntPtForType[snap.TypeGadget], mntPtForType[snap.TypeKernel], perfTimings)
+	encryptionSetupData, err := installEncryptPartitions(onVolumes, encType, passphrase, sys.Model, mntPtForType[snap.TypeGadget], mntPtForType[snap.TypeKernel], perfTimings)
 	if err != nil {
 		return err
 	}
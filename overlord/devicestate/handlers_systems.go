@@ -145,8 +145,30 @@ func (m *DeviceManager) doCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) (err
 	systemDirectory := setup.Directory
 
 	// get all infos
-	infoGetter := func(name string) (info *snap.Info, present bool, err error) {
-		// snaps are either being fetched or present in the system
+	infoGetter := func(name string) (info *snap.Info, path string, present bool, err error) {
+		// snaps are either provided locally, being fetched or present
+		// in the system
+
+		for _, localSnap := range setup.LocalSnaps {
+			if localSnap.SideInfo.RealName != name {
+				continue
+			}
+			logger.Debugf("requested info for local snap %q", name)
+			snapFile, err := snapfile.Open(localSnap.Path)
+			if err != nil {
+				return nil, "", false, err
+			}
+			info, err = snap.ReadInfoFromSnapFile(snapFile, localSnap.SideInfo)
+			if err != nil {
+				return nil, "", false, err
+			}
+			hash, _, err := asserts.SnapFileSHA3_384(localSnap.Path)
+			if err != nil {
+				return nil, "", true, fmt.Errorf("cannot compute SHA3 of snap file: %v", err)
+			}
+			info.Sha3_384 = hash
+			return info, localSnap.Path, true, nil
+		}
 
 		if isRemodel {
 			// in a remodel scenario, the snaps may need to be
@@ -159,7 +181,7 @@ func (m *DeviceManager) doCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) (err
 				taskWithSnapSetup := st.Task(tskID)
 				snapsup, err := snapstate.TaskSnapSetup(taskWithSnapSetup)
 				if err != nil {
-					return nil, false, err
+					return nil, "", false, err
 				}
 				if snapsup.SnapName() != name {
 					continue
@@ -168,14 +190,14 @@ func (m *DeviceManager) doCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) (err
 				// downloaded and validated
 				snapFile, err := snapfile.Open(snapsup.MountFile())
 				if err != nil {
-					return nil, false, err
+					return nil, "", false, err
 				}
 				info, err = snap.ReadInfoFromSnapFile(snapFile, snapsup.SideInfo)
 				if err != nil {
-					return nil, false, err
+					return nil, "", false, err
 				}
 
-				return info, true, nil
+				return info, "", true, nil
 			}
 		}
 
@@ -188,15 +210,15 @@ func (m *DeviceManager) doCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) (err
 		if err == nil {
 			hash, _, err := asserts.SnapFileSHA3_384(info.MountFile())
 			if err != nil {
-				return nil, true, fmt.Errorf("cannot compute SHA3 of snap file: %v", err)
+				return nil, "", true, fmt.Errorf("cannot compute SHA3 of snap file: %v", err)
 			}
 			info.Sha3_384 = hash
-			return info, true, nil
+			return info, "", true, nil
 		}
 		if _, ok := err.(*snap.NotInstalledError); !ok {
-			return nil, false, err
+			return nil, "", false, err
 		}
-		return nil, false, nil
+		return nil, "", false, nil
 	}
 
 	observeSnapFileWrite := func(recoverySystemDir, where string) error {
@@ -369,6 +391,12 @@ func (m *DeviceManager) doFinalizeTriedRecoverySystem(t *state.Task, _ *tomb.Tom
 		if err := boot.PromoteTriedRecoverySystem(remodelCtx, label, triedSystems); err != nil {
 			return fmt.Errorf("cannot promote recovery system %q: %v", label, err)
 		}
+		if setup.MarkDefault {
+			if err := markDefaultRecoverySystem(t, remodelCtx.Model(), label); err != nil {
+				return fmt.Errorf("cannot mark recovery system %q as the default one: %v", label, err)
+			}
+			t.Logf("marked recovery system %q as the default one", label)
+		}
 
 		// tried systems should be a one item list, we can clear it now
 		st.Set("tried-systems", nil)
@@ -396,6 +424,12 @@ func (m *DeviceManager) undoFinalizeTriedRecoverySystem(t *state.Task, _ *tomb.T
 	}
 	label := setup.Label
 
+	if setup.MarkDefault {
+		if err := unmarkDefaultRecoverySystem(t, label); err != nil {
+			return fmt.Errorf("cannot restore the default recovery system: %v", err)
+		}
+	}
+
 	if err := boot.DropRecoverySystem(remodelCtx, label); err != nil {
 		return fmt.Errorf("cannot drop a good recovery system %q: %v", label, err)
 	}
@@ -415,5 +449,67 @@ func (m *DeviceManager) cleanupRecoverySystem(t *state.Task, _ *tomb.Tomb) error
 	if os.Remove(filepath.Join(setup.Directory, "snapd-new-file-log")); err != nil && !os.IsNotExist(err) {
 		return err
 	}
+	// local snap files were handed over to the change
+	for _, localSnap := range setup.LocalSnaps {
+		if err := os.Remove(localSnap.Path); err != nil && !os.IsNotExist(err) {
+			logger.Noticef("cannot remove local snap file %q: %v", localSnap.Path, err)
+		}
+	}
+	return nil
+}
+
+func (m *DeviceManager) doRemoveRecoverySystem(t *state.Task, _ *tomb.Tomb) error {
+	if release.OnClassic {
+		// TODO: this may need to be lifted in the future
+		return fmt.Errorf("internal error: cannot remove recovery systems on a classic system")
+	}
+
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	deviceCtx, err := DeviceCtx(st, t, nil)
+	if err != nil {
+		return err
+	}
+
+	var label string
+	if err := t.Get("recovery-system-label", &label); err != nil {
+		return fmt.Errorf("internal error: cannot obtain recovery system label: %v", err)
+	}
+
+	// There is no undo, the steps are ordered such that the system is
+	// never offered while it cannot be booted anymore, and each of them
+	// does nothing when already done, so that after a failure the removal
+	// can be requested again.
+
+	// 1. drop the system from the list of systems known to the recovery
+	// bootloader, such that it is no longer offered
+	if err := boot.UnmarkRecoveryCapableSystem(label); err != nil {
+		return fmt.Errorf("cannot drop recovery system %q from the recovery bootloader: %v", label, err)
+	}
+	// 2. then from modeenv and reseal, such that it can no longer be
+	// booted into
+	if err := boot.DropRecoverySystem(deviceCtx, label); err != nil {
+		return fmt.Errorf("cannot drop recovery system %q: %v", label, err)
+	}
+	// 3. remove the system directory, along with any unasserted snaps it
+	// carries
+	systemDirectory := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label)
+	if err := os.RemoveAll(systemDirectory); err != nil {
+		return fmt.Errorf("cannot remove recovery system %q: %v", label, err)
+	}
+	t.Logf("removed recovery system directory %v", systemDirectory)
+
+	// 4. garbage collect seed snaps which are no longer used, the system is
+	// already gone so do not fail the task if that is not possible
+	removed, err := removeUnusedSeedSnaps(boot.InitramfsUbuntuSeedDir)
+	if err != nil {
+		t.Logf("cannot remove unused seed snaps: %v", err)
+	}
+	for _, fn := range removed {
+		t.Logf("removed unused seed snap %v", fn)
+	}
+
 	return nil
 }
@@ -22,8 +22,10 @@ package devicestate
 import (
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/boot"
@@ -36,6 +38,7 @@ import (
 	"github.com/snapcore/snapd/seed/seedwriter"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/timings"
 )
 
 func checkSystemRequestConflict(st *state.State, systemLabel string) error {
@@ -121,6 +124,84 @@ func (c *currentSystem) sameAs(other *System) bool {
 		c.BrandID == other.Brand.AccountID()
 }
 
+// DefaultRecoverySystem describes the recovery system which was marked as
+// the default one.
+type DefaultRecoverySystem struct {
+	// System is the label of the recovery system
+	System string `json:"system"`
+	// Model and BrandID identify the model of the recovery system
+	Model   string `json:"model"`
+	BrandID string `json:"brand-id"`
+	// TimeMadeDefault is when the system was marked as the default one
+	TimeMadeDefault time.Time `json:"time-made-default"`
+}
+
+func (d *DefaultRecoverySystem) sameAs(other *System) bool {
+	return d != nil &&
+		d.System == other.Label &&
+		d.Model == other.Model.Model() &&
+		d.BrandID == other.Brand.AccountID()
+}
+
+// defaultRecoverySystem returns the recovery system marked as the default
+// one, or nil if there is none. The state must be locked by the caller.
+func defaultRecoverySystem(st *state.State) (*DefaultRecoverySystem, error) {
+	var defaultSys DefaultRecoverySystem
+	if err := st.Get("default-recovery-system", &defaultSys); err != nil {
+		if errors.Is(err, state.ErrNoState) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &defaultSys, nil
+}
+
+// markDefaultRecoverySystem marks the recovery system with the given label
+// as the default one, the previous default is kept in the task so that it
+// can be restored on undo.
+func markDefaultRecoverySystem(t *state.Task, model *asserts.Model, label string) error {
+	st := t.State()
+	previous, err := defaultRecoverySystem(st)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		t.Set("previous-default-recovery-system", previous)
+	}
+	st.Set("default-recovery-system", &DefaultRecoverySystem{
+		System:          label,
+		Model:           model.Model(),
+		BrandID:         model.BrandID(),
+		TimeMadeDefault: timeNow(),
+	})
+	return nil
+}
+
+// unmarkDefaultRecoverySystem restores the default recovery system which was
+// recorded by the task when the system with the given label was marked as
+// the default one.
+func unmarkDefaultRecoverySystem(t *state.Task, label string) error {
+	st := t.State()
+	current, err := defaultRecoverySystem(st)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.System != label {
+		// the default was changed in the meantime
+		return nil
+	}
+	var previous DefaultRecoverySystem
+	if err := t.Get("previous-default-recovery-system", &previous); err != nil {
+		if !errors.Is(err, state.ErrNoState) {
+			return err
+		}
+		st.Set("default-recovery-system", nil)
+		return nil
+	}
+	st.Set("default-recovery-system", &previous)
+	return nil
+}
+
 func currentSystemForMode(st *state.State, mode string) (*currentSystem, error) {
 	var system *seededSystem
 	var actions []SystemAction
@@ -195,10 +276,12 @@ func seededSystemFromModeenv() (*seededSystem, error) {
 }
 
 // getInfoFunc is expected to return for a given snap name a snap.Info for that
-// snap and whether the snap is present is present. The second bit is relevant
-// for non-essential snaps mentioned in the model, which if present and having
-// an 'optional' presence in the model, will be added to the recovery system.
-type getSnapInfoFunc func(name string) (info *snap.Info, snapIsPresent bool, err error)
+// snap, the path to the snap file and whether the snap is present is present.
+// When the path is empty, the snap's mount file is used. The last bit is
+// relevant for non-essential snaps mentioned in the model, which if present
+// and having an 'optional' presence in the model, will be added to the
+// recovery system.
+type getSnapInfoFunc func(name string) (info *snap.Info, path string, snapIsPresent bool, err error)
 
 // snapWriteObserveFunc is called with the recovery system directory and the
 // path to a snap file being written. The snap file may be written to a location
@@ -248,7 +331,7 @@ func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string,
 				kind = fmt.Sprintf("non-essential but %v", nonEssentialPresence)
 			}
 		}
-		info, present, err := getInfo(name)
+		info, snapPath, present, err := getInfo(name)
 		if err != nil {
 			return fmt.Errorf("cannot obtain %v snap information: %v", kind, err)
 		}
@@ -262,7 +345,10 @@ func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string,
 		if !present {
 			return fmt.Errorf("internal error: %v snap %q not present", kind, name)
 		}
-		if _, ok := modelSnaps[info.MountFile()]; ok {
+		if snapPath == "" {
+			snapPath = info.MountFile()
+		}
+		if _, ok := modelSnaps[snapPath]; ok {
 			// we've already seen this snap
 			return nil
 		}
@@ -270,9 +356,9 @@ func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string,
 		// TODO: for grade dangerous we could have a channel here which is not
 		//       the model channel, handle that here
 		optsSnaps = append(optsSnaps, &seedwriter.OptionsSnap{
-			Path: info.MountFile(),
+			Path: snapPath,
 		})
-		modelSnaps[info.MountFile()] = info
+		modelSnaps[snapPath] = info
 		return nil
 	}
 
@@ -436,3 +522,150 @@ func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string,
 
 	return recoverySystemDir, nil
 }
+
+// trustedSnapRevisionHandler is a seed.SnapHandler which takes the digest and
+// size of the seed snaps from their snap revision assertions instead of
+// computing them, which is sufficient when only the location of the snap
+// files is relevant.
+type trustedSnapRevisionHandler struct{}
+
+func (trustedSnapRevisionHandler) HandleAndDigestAssertedSnap(name, path string, essentialType snap.Type, snapRev *asserts.SnapRevision, _ func(string, uint64) (snap.Revision, error), _ timings.Measurer) (string, string, uint64, error) {
+	if snapRev == nil {
+		return "", "", 0, fmt.Errorf("internal error: no snap revision for seed snap %q", name)
+	}
+	return path, snapRev.SnapSHA3_384(), snapRev.SnapSize(), nil
+}
+
+func (trustedSnapRevisionHandler) HandleUnassertedSnap(name, path string, _ timings.Measurer) (string, error) {
+	return path, nil
+}
+
+// seedSystemsSnaps returns the paths of the snap files used by each of the
+// recovery systems in the seed directory.
+func seedSystemsSnaps(seedDir string) (map[string][]string, error) {
+	systemDirs, err := filepath.Glob(filepath.Join(seedDir, "systems", "*"))
+	if err != nil {
+		return nil, err
+	}
+	systemsSnaps := make(map[string][]string, len(systemDirs))
+	for _, systemDir := range systemDirs {
+		label := filepath.Base(systemDir)
+		sd, err := seedOpen(seedDir, label)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open recovery system %q: %v", label, err)
+		}
+		if err := sd.LoadAssertions(nil, nil); err != nil {
+			return nil, fmt.Errorf("cannot load assertions of recovery system %q: %v", label, err)
+		}
+		if err := sd.LoadMeta(seed.AllModes, trustedSnapRevisionHandler{}, timings.New(nil)); err != nil {
+			return nil, fmt.Errorf("cannot load metadata of recovery system %q: %v", label, err)
+		}
+		var snaps []string
+		err = sd.Iter(func(sn *seed.Snap) error {
+			snaps = append(snaps, sn.Path)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		systemsSnaps[label] = snaps
+	}
+	return systemsSnaps, nil
+}
+
+// removeUnusedSeedSnaps removes the asserted snap files in the seed directory
+// which are not used by any of the recovery systems and returns the list of
+// removed files. Nothing is removed if any of the systems cannot be loaded.
+func removeUnusedSeedSnaps(seedDir string) (removed []string, err error) {
+	systemsSnaps, err := seedSystemsSnaps(seedDir)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	for _, snaps := range systemsSnaps {
+		for _, sn := range snaps {
+			used[sn] = true
+		}
+	}
+	seedSnaps, err := filepath.Glob(filepath.Join(seedDir, "snaps", "*.snap"))
+	if err != nil {
+		return nil, err
+	}
+	for _, sn := range seedSnaps {
+		if used[sn] {
+			continue
+		}
+		if err := os.Remove(sn); err != nil {
+			return removed, err
+		}
+		removed = append(removed, sn)
+	}
+	return removed, nil
+}
+
+// SystemDiskUsage holds the disk usage of a recovery system in the seed.
+type SystemDiskUsage struct {
+	// Size is the total size of the files used by the system, including
+	// snaps shared with other systems
+	Size int64
+	// Reclaimable is the size of the files which would be removed
+	// together with the system
+	Reclaimable int64
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// seedSystemsDiskUsage returns the disk usage of each recovery system in the
+// seed directory.
+func seedSystemsDiskUsage(seedDir string) (map[string]*SystemDiskUsage, error) {
+	systemsSnaps, err := seedSystemsSnaps(seedDir)
+	if err != nil {
+		return nil, err
+	}
+	// snaps in the common snaps directory can be shared, while the ones in
+	// the system directory are accounted for with the system directory
+	commonSnapsDir := filepath.Join(seedDir, "snaps") + "/"
+	users := make(map[string]int)
+	for _, snaps := range systemsSnaps {
+		for _, sn := range snaps {
+			if strings.HasPrefix(sn, commonSnapsDir) {
+				users[sn]++
+			}
+		}
+	}
+	usage := make(map[string]*SystemDiskUsage, len(systemsSnaps))
+	for label, snaps := range systemsSnaps {
+		size, err := dirSize(filepath.Join(seedDir, "systems", label))
+		if err != nil {
+			return nil, err
+		}
+		du := &SystemDiskUsage{Size: size, Reclaimable: size}
+		for _, sn := range snaps {
+			if !strings.HasPrefix(sn, commonSnapsDir) {
+				continue
+			}
+			fi, err := os.Stat(sn)
+			if err != nil {
+				return nil, err
+			}
+			du.Size += fi.Size()
+			if users[sn] == 1 {
+				du.Reclaimable += fi.Size()
+			}
+		}
+		usage[label] = du
+	}
+	return usage, nil
+}
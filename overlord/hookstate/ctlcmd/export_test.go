@@ -69,6 +69,12 @@ func MockServicestateControlFunc(f func(*state.State, []*snap.AppInfo, *services
 	return func() { servicestateControl = old }
 }
 
+func MockIfacestateUpdateAttrs(f func(st *state.State, snapName, plugOrSlot string, values map[string]interface{}, ignoreChangeID string) (*state.TaskSet, error)) (restore func()) {
+	r := testutil.Backup(&ifacestateUpdateAttrs)
+	ifacestateUpdateAttrs = f
+	return r
+}
+
 func MockDevicestateSystemModeInfoFromState(f func(*state.State) (*devicestate.SystemModeInfo, error)) (restore func()) {
 	old := devicestateSystemModeInfoFromState
 	devicestateSystemModeInfoFromState = f
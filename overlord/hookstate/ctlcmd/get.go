@@ -205,6 +205,8 @@ const (
 	connectSlotHook
 	disconnectPlugHook
 	disconnectSlotHook
+	interfaceChangedPlugHook
+	interfaceChangedSlotHook
 	unknownHook
 )
 
@@ -226,6 +228,10 @@ func interfaceHookType(hookName string) (ifaceHookType, error) {
 		return unprepareSlotHook, nil
 	case strings.HasPrefix(hookName, "unprepare-plug-"):
 		return unpreparePlugHook, nil
+	case strings.HasPrefix(hookName, "interface-changed-plug-"):
+		return interfaceChangedPlugHook, nil
+	case strings.HasPrefix(hookName, "interface-changed-slot-"):
+		return interfaceChangedSlotHook, nil
 	default:
 		return unknownHook, fmt.Errorf("unknown hook type")
 	}
@@ -294,7 +300,7 @@ func (c *getCommand) getInterfaceSetting(context *hookstate.Context, plugOrSlot
 		return fmt.Errorf("cannot use --plug and --slot together")
 	}
 
-	isPlugSide := (hookType == preparePlugHook || hookType == unpreparePlugHook || hookType == connectPlugHook || hookType == disconnectPlugHook)
+	isPlugSide := (hookType == preparePlugHook || hookType == unpreparePlugHook || hookType == connectPlugHook || hookType == disconnectPlugHook || hookType == interfaceChangedPlugHook)
 	if err = validatePlugOrSlot(attrsTask, isPlugSide, plugOrSlot); err != nil {
 		return err
 	}
@@ -23,12 +23,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/jsonutil"
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 )
 
 type setCommand struct {
@@ -63,6 +67,11 @@ Plug and slot attributes may be set in the respective prepare and connect hooks
 by naming the respective plug or slot:
 
     $ snapctl set :myplug path=/dev/ttyS0
+
+Outside of interface hooks, setting attributes of a plug or slot updates its
+existing connections in place, without disconnecting them. The security
+profiles of the connected snaps are updated and the interface-changed hook of
+the other side of each connection is run.
 `)
 
 func init() {
@@ -172,8 +181,14 @@ func setInterfaceAttribute(context *hookstate.Context, staticAttrs map[string]in
 }
 
 func (s *setCommand) setInterfaceSetting(context *hookstate.Context, plugOrSlot string) error {
-	// Make sure set :<plug|slot> is only supported during the execution of prepare-[plug|slot] hooks
+	// Outside of interface hooks (from apps or from other supported hooks)
+	// set :<plug|slot> updates the connections of the plug or slot in
+	// place, in interface hooks it is only supported during the execution
+	// of prepare-[plug|slot] hooks
 	hookType, _ := interfaceHookType(context.HookName())
+	if hookType == unknownHook && (context.IsEphemeral() || snap.IsHookSupported(context.HookName())) {
+		return s.updateInterfaceAttributes(context, plugOrSlot)
+	}
 	if hookType != preparePlugHook && hookType != prepareSlotHook {
 		return fmt.Errorf(i18n.G("interface attributes can only be set during the execution of prepare hooks"))
 	}
@@ -228,3 +243,56 @@ func (s *setCommand) setInterfaceSetting(context *hookstate.Context, plugOrSlot
 	attrsTask.Set(dynKey, dynamicAttrs)
 	return nil
 }
+
+var ifacestateUpdateAttrs = ifacestate.UpdateAttrs
+
+// updateInterfaceAttributes updates the dynamic attributes of the existing
+// connections of a plug or slot of the snap. Within a hook the update is
+// queued after the tasks of its change, otherwise it is performed right away.
+func (s *setCommand) updateInterfaceAttributes(context *hookstate.Context, plugOrSlot string) error {
+	values := make(map[string]interface{}, len(s.Positional.ConfValues))
+	for _, attrValue := range s.Positional.ConfValues {
+		parts := strings.SplitN(attrValue, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf(i18n.G("invalid parameter: %q (want key=value)"), attrValue)
+		}
+
+		var value interface{}
+		if err := jsonutil.DecodeWithNumber(strings.NewReader(parts[1]), &value); err != nil {
+			// Not valid JSON, save the string as-is
+			value = parts[1]
+		}
+		values[parts[0]] = value
+	}
+
+	st := context.State()
+	st.Lock()
+	var ignoreChangeID string
+	if task, ok := context.Task(); ok && task.Change() != nil {
+		ignoreChangeID = task.Change().ID()
+	}
+	ts, err := ifacestateUpdateAttrs(st, context.InstanceName(), plugOrSlot, values, ignoreChangeID)
+	st.Unlock()
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot set attribute: %v"), err)
+	}
+
+	if !context.IsEphemeral() {
+		return queueCommand(context, []*state.TaskSet{ts})
+	}
+
+	st.Lock()
+	chg := st.NewChange("update-interface-attrs", fmt.Sprintf("Update attributes of %q of snap %q", plugOrSlot, context.InstanceName()))
+	chg.AddAll(ts)
+	st.EnsureBefore(0)
+	st.Unlock()
+
+	select {
+	case <-chg.Ready():
+		st.Lock()
+		defer st.Unlock()
+		return chg.Err()
+	case <-time.After(configstate.ConfigureHookTimeout() / 2):
+		return fmt.Errorf("updating attributes of %q is taking too long", plugOrSlot)
+	}
+}
@@ -402,3 +402,76 @@ func (s *setAttrSuite) TestSetCommandFailsOutsideOfValidContext(c *C) {
 	c.Check(string(stdout), Equals, "")
 	c.Check(string(stderr), Equals, "")
 }
+
+func (s *setAttrSuite) TestSetCommandFailsInConnectHook(c *C) {
+	var err error
+	var mockContext *hookstate.Context
+
+	state := state.New(nil)
+	state.Lock()
+	defer state.Unlock()
+
+	task := state.NewTask("test-task", "my test task")
+	setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: "connect-plug-aplug"}
+	mockContext, err = hookstate.NewContext(task, task.State(), setup, s.mockHandler, "")
+	c.Assert(err, IsNil)
+
+	stdout, stderr, err := ctlcmd.Run(mockContext, []string{"set", ":aplug", "foo=bar"}, 0)
+	c.Check(err, ErrorMatches, `interface attributes can only be set during the execution of prepare hooks`)
+	c.Check(string(stdout), Equals, "")
+	c.Check(string(stderr), Equals, "")
+}
+
+func (s *setAttrSuite) TestSetUpdatesConnectionsOutsideOfInterfaceHooks(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	chg := st.NewChange("mychange", "mychange")
+	task := st.NewTask("run-hook", "my test task")
+	chg.AddTask(task)
+	st.Unlock()
+
+	setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: "configure"}
+	mockContext, err := hookstate.NewContext(task, st, setup, s.mockHandler, "")
+	c.Assert(err, IsNil)
+
+	var updateTask *state.Task
+	restore := ctlcmd.MockIfacestateUpdateAttrs(func(st *state.State, snapName, plugOrSlot string, values map[string]interface{}, ignoreChangeID string) (*state.TaskSet, error) {
+		c.Check(snapName, Equals, "test-snap")
+		c.Check(plugOrSlot, Equals, "aslot")
+		c.Check(values, DeepEquals, map[string]interface{}{"foo": "bar", "my.number": json.Number("1")})
+		c.Check(ignoreChangeID, Equals, chg.ID())
+		updateTask = st.NewTask("update-connection-attrs", "...")
+		return state.NewTaskSet(updateTask), nil
+	})
+	defer restore()
+
+	stdout, stderr, err := ctlcmd.Run(mockContext, []string{"set", ":aslot", "foo=bar", "my.number=1"}, 0)
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, "")
+	c.Check(string(stderr), Equals, "")
+
+	// the update is queued after the hook
+	st.Lock()
+	defer st.Unlock()
+	c.Assert(updateTask, NotNil)
+	c.Check(updateTask.Change(), Equals, chg)
+	c.Check(updateTask.WaitTasks(), DeepEquals, []*state.Task{task})
+}
+
+func (s *setAttrSuite) TestSetUpdatesConnectionsError(c *C) {
+	st := state.New(nil)
+	mockContext, err := hookstate.NewContext(nil, st, &hookstate.HookSetup{Snap: "test-snap"}, nil, "")
+	c.Assert(err, IsNil)
+
+	restore := ctlcmd.MockIfacestateUpdateAttrs(func(st *state.State, snapName, plugOrSlot string, values map[string]interface{}, ignoreChangeID string) (*state.TaskSet, error) {
+		c.Check(ignoreChangeID, Equals, "")
+		return nil, fmt.Errorf(`attribute "foo" cannot be overwritten`)
+	})
+	defer restore()
+
+	_, _, err = ctlcmd.Run(mockContext, []string{"set", ":aslot", "foo=bar"}, 0)
+	c.Check(err, ErrorMatches, `cannot set attribute: attribute "foo" cannot be overwritten`)
+
+	_, _, err = ctlcmd.Run(mockContext, []string{"set", ":aslot", "foo"}, 0)
+	c.Check(err, ErrorMatches, `invalid parameter: "foo" \(want key=value\)`)
+}
@@ -193,3 +193,15 @@ func (m *InterfaceManager) TransitionConnectionsCoreMigration(st *state.State, o
 func (m *InterfaceManager) SetupSecurityByBackend(task *state.Task, snaps []*snap.Info, opts []interfaces.ConfinementOptions, tm timings.Measurer) error {
 	return m.setupSecurityByBackend(task, snaps, opts, tm)
 }
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	restore = testutil.Backup(&timeNow)
+	timeNow = f
+	return restore
+}
+
+func MockOsutilBootID(f func() (string, error)) (restore func()) {
+	restore = testutil.Backup(&osutilBootID)
+	osutilBootID = f
+	return restore
+}
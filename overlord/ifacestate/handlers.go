@@ -481,6 +481,28 @@ func setDynamicHookAttributes(task *state.Task, plugAttrs, slotAttrs map[string]
 	task.Set("slot-dynamic", slotAttrs)
 }
 
+// connectionPolicyChecker returns the policy check for a connection: manual
+// connections and connections by the gadget obey the policy "connection"
+// rules, other auto-connections obey the "auto-connection" rules.
+func (m *InterfaceManager) connectionPolicyChecker(task *state.Task, deviceCtx snapstate.DeviceContext, autoConnect, byGadget bool) (interfaces.PolicyFunc, error) {
+	st := task.State()
+	if autoConnect && !byGadget {
+		autochecker, err := newAutoConnectChecker(st, task, m.repo, deviceCtx)
+		if err != nil {
+			return nil, err
+		}
+		return func(plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) (bool, error) {
+			ok, _, err := autochecker.check(plug, slot)
+			return ok, err
+		}, nil
+	}
+	policyCheck, err := newConnectChecker(st, deviceCtx)
+	if err != nil {
+		return nil, err
+	}
+	return policyCheck.check, nil
+}
+
 func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) (err error) {
 	st := task.State()
 	st.Lock()
@@ -506,6 +528,21 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) (err error)
 	if err := task.Get("delayed-setup-profiles", &delayedSetupProfiles); err != nil && !errors.Is(err, state.ErrNoState) {
 		return err
 	}
+	var expiry *time.Time
+	if err := task.Get("expiry", &expiry); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	var untilReboot bool
+	if err := task.Get("until-reboot", &untilReboot); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	var bootID string
+	if untilReboot {
+		bootID, err = osutilBootID()
+		if err != nil {
+			return fmt.Errorf("cannot get boot ID: %v", err)
+		}
+	}
 
 	deviceCtx, err := snapstate.DeviceCtx(st, task, nil)
 	if err != nil {
@@ -555,26 +592,9 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) (err error)
 		return fmt.Errorf("failed to get hook attributes: %s", err)
 	}
 
-	var policyChecker interfaces.PolicyFunc
-
-	// manual connections and connections by the gadget obey the
-	// policy "connection" rules, other auto-connections obey the
-	// "auto-connection" rules
-	if autoConnect && !byGadget {
-		autochecker, err := newAutoConnectChecker(st, task, m.repo, deviceCtx)
-		if err != nil {
-			return err
-		}
-		policyChecker = func(plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) (bool, error) {
-			ok, _, err := autochecker.check(plug, slot)
-			return ok, err
-		}
-	} else {
-		policyCheck, err := newConnectChecker(st, deviceCtx)
-		if err != nil {
-			return err
-		}
-		policyChecker = policyCheck.check
+	policyChecker, err := m.connectionPolicyChecker(task, deviceCtx, autoConnect, byGadget)
+	if err != nil {
+		return err
 	}
 
 	// static attributes of the plug and slot not provided, the ones from snap infos will be used
@@ -636,6 +656,8 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) (err error)
 		Auto:             autoConnect,
 		ByGadget:         byGadget,
 		HotplugKey:       slot.HotplugKey,
+		Expiry:           expiry,
+		BootID:           bootID,
 	}
 	setConns(st, conns)
 
@@ -645,6 +667,140 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) (err error)
 	return nil
 }
 
+func (m *InterfaceManager) doUpdateConnectionAttrs(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	perfTimings := state.TimingsForTask(task)
+	defer perfTimings.Save(st)
+
+	plugRef, slotRef, err := getPlugAndSlotRefs(task)
+	if err != nil {
+		return err
+	}
+	var ofPlug bool
+	if err := task.Get("attrs-of-plug", &ofPlug); err != nil {
+		return err
+	}
+	var values map[string]interface{}
+	if err := task.Get("attrs-values", &values); err != nil {
+		return err
+	}
+
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+	connRef := &interfaces.ConnRef{PlugRef: plugRef, SlotRef: slotRef}
+	cstate, ok := conns[connRef.ID()]
+	if !ok || cstate.Undesired || cstate.HotplugGone {
+		return fmt.Errorf("cannot update attributes of connection %s %s: not connected", plugRef, slotRef)
+	}
+
+	// apply the values to the current attributes, which may have been
+	// updated by other tasks since this one was created
+	plugDynamicAttrs, slotDynamicAttrs := cstate.DynamicPlugAttrs, cstate.DynamicSlotAttrs
+	if ofPlug {
+		plugDynamicAttrs, err = patchDynamicAttrs(plugRef.Snap, cstate.StaticPlugAttrs, cstate.DynamicPlugAttrs, values)
+	} else {
+		slotDynamicAttrs, err = patchDynamicAttrs(slotRef.Snap, cstate.StaticSlotAttrs, cstate.DynamicSlotAttrs, values)
+	}
+	if err != nil {
+		return err
+	}
+
+	deviceCtx, err := snapstate.DeviceCtx(st, task, nil)
+	if err != nil {
+		return err
+	}
+	policyChecker, err := m.connectionPolicyChecker(task, deviceCtx, cstate.Auto, cstate.ByGadget)
+	if err != nil {
+		return err
+	}
+
+	// for undo
+	task.Set("old-plug-dynamic", cstate.DynamicPlugAttrs)
+	task.Set("old-slot-dynamic", cstate.DynamicSlotAttrs)
+
+	conn, err := m.repo.UpdateConnectionAttrs(connRef, plugDynamicAttrs, slotDynamicAttrs, policyChecker)
+	if err != nil {
+		return err
+	}
+	cstate.DynamicPlugAttrs = conn.Plug.DynamicAttrs()
+	cstate.DynamicSlotAttrs = conn.Slot.DynamicAttrs()
+	setConns(st, conns)
+
+	// the interface-changed- hooks need to see the values as updated by
+	// the interface's BeforeConnectPlug/Slot code
+	setDynamicHookAttributes(task, conn.Plug.DynamicAttrs(), conn.Slot.DynamicAttrs())
+
+	return m.setupConnectedSnaps(task, conn, perfTimings)
+}
+
+func (m *InterfaceManager) undoUpdateConnectionAttrs(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	perfTimings := state.TimingsForTask(task)
+	defer perfTimings.Save(st)
+
+	plugRef, slotRef, err := getPlugAndSlotRefs(task)
+	if err != nil {
+		return err
+	}
+	var oldPlugDynamicAttrs, oldSlotDynamicAttrs map[string]interface{}
+	if err := task.Get("old-plug-dynamic", &oldPlugDynamicAttrs); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	if err := task.Get("old-slot-dynamic", &oldSlotDynamicAttrs); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+	connRef := &interfaces.ConnRef{PlugRef: plugRef, SlotRef: slotRef}
+	cstate, ok := conns[connRef.ID()]
+	if !ok {
+		// nothing to restore
+		return nil
+	}
+
+	// the previous attributes were already accepted
+	conn, err := m.repo.UpdateConnectionAttrs(connRef, oldPlugDynamicAttrs, oldSlotDynamicAttrs, nil)
+	if err != nil {
+		return err
+	}
+	cstate.DynamicPlugAttrs = oldPlugDynamicAttrs
+	cstate.DynamicSlotAttrs = oldSlotDynamicAttrs
+	setConns(st, conns)
+
+	return m.setupConnectedSnaps(task, conn, perfTimings)
+}
+
+// setupConnectedSnaps regenerates the security profiles of both sides of a
+// connection.
+func (m *InterfaceManager) setupConnectedSnaps(task *state.Task, conn *interfaces.Connection, tm timings.Measurer) error {
+	st := task.State()
+	for _, snapInfo := range []*snap.Info{conn.Slot.Snap(), conn.Plug.Snap()} {
+		var snapst snapstate.SnapState
+		if err := snapstate.Get(st, snapInfo.InstanceName(), &snapst); err != nil {
+			return err
+		}
+		opts, err := buildConfinementOptions(st, snapInfo, snapst.Flags)
+		if err != nil {
+			return err
+		}
+		if err := m.setupSnapSecurity(task, snapInfo, opts, tm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *InterfaceManager) doDisconnect(task *state.Task, _ *tomb.Tomb) error {
 	st := task.State()
 	st.Lock()
@@ -734,18 +890,28 @@ func (m *InterfaceManager) doDisconnect(task *state.Task, _ *tomb.Tomb) error {
 		return fmt.Errorf("internal error: cannot read 'by-hotplug' flag: %s", err)
 	}
 
+	// "undesired" flag indicates the connection must not be auto-connected
+	// again even if it was established manually, as for expired temporary
+	// connections
+	var undesired bool
+	if err := task.Get("undesired", &undesired); err != nil && !errors.Is(err, state.ErrNoState) {
+		return fmt.Errorf("internal error: cannot read 'undesired' flag: %s", err)
+	}
+
 	switch {
 	case forget:
 		delete(conns, cref.ID())
 	case byHotplug:
 		conn.HotplugGone = true
 		conns[cref.ID()] = conn
-	case conn.Auto && !autoDisconnect:
+	case (conn.Auto && !autoDisconnect) || undesired:
 		conn.Undesired = true
 		conn.DynamicPlugAttrs = nil
 		conn.DynamicSlotAttrs = nil
 		conn.StaticPlugAttrs = nil
 		conn.StaticSlotAttrs = nil
+		conn.Expiry = nil
+		conn.BootID = ""
 		conns[cref.ID()] = conn
 	default:
 		delete(conns, cref.ID())
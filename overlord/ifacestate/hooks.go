@@ -55,4 +55,6 @@ func setupHooks(hookMgr *hookstate.HookManager) {
 	hookMgr.Register(regexp.MustCompile("^connect-slot-[-a-z0-9]+$"), gen)
 	hookMgr.Register(regexp.MustCompile("^disconnect-plug-[-a-z0-9]+$"), gen)
 	hookMgr.Register(regexp.MustCompile("^disconnect-slot-[-a-z0-9]+$"), gen)
+	hookMgr.Register(regexp.MustCompile("^interface-changed-plug-[-a-z0-9]+$"), gen)
+	hookMgr.Register(regexp.MustCompile("^interface-changed-slot-[-a-z0-9]+$"), gen)
 }
@@ -97,6 +97,7 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 
 	addHandler("connect", m.doConnect, m.undoConnect)
 	addHandler("disconnect", m.doDisconnect, m.undoDisconnect)
+	addHandler("update-connection-attrs", m.doUpdateConnectionAttrs, m.undoUpdateConnectionAttrs)
 	addHandler("setup-profiles", m.doSetupProfiles, m.undoSetupProfiles)
 	addHandler("remove-profiles", m.doRemoveProfiles, m.doSetupProfiles)
 	addHandler("discard-conns", m.doDiscardConns, m.undoDiscardConns)
@@ -191,11 +192,17 @@ Run "systemctl enable --now snapd.apparmor" to correct this.`)
 
 // Ensure implements StateManager.Ensure.
 func (m *InterfaceManager) Ensure() error {
-	// do not worry about udev monitor in preseeding mode
+	// do not worry about udev monitor nor temporary connections in
+	// preseeding mode
 	if m.preseed {
 		return nil
 	}
 
+	if err := m.removeExpiredConnections(); err != nil {
+		// not a reason to skip the udev monitor handling below
+		logger.Noticef("cannot remove expired connections: %v", err)
+	}
+
 	if m.udevMonitorDisabled {
 		return nil
 	}
@@ -268,6 +275,11 @@ type ConnectionState struct {
 	StaticSlotAttrs  map[string]interface{}
 	DynamicSlotAttrs map[string]interface{}
 	HotplugGone      bool
+	// Expiry is when a temporary connection is automatically removed
+	Expiry time.Time
+	// UntilReboot indicates whether the connection is removed once the
+	// system has been rebooted
+	UntilReboot bool
 }
 
 // Active returns true if connection is not undesired and not removed by
@@ -289,7 +301,7 @@ func ConnectionStates(st *state.State) (connStateByRef map[string]ConnectionStat
 
 	connStateByRef = make(map[string]ConnectionState, len(states))
 	for cref, cstate := range states {
-		connStateByRef[cref] = ConnectionState{
+		connState := ConnectionState{
 			Auto:             cstate.Auto,
 			ByGadget:         cstate.ByGadget,
 			Interface:        cstate.Interface,
@@ -299,7 +311,12 @@ func ConnectionStates(st *state.State) (connStateByRef map[string]ConnectionStat
 			StaticSlotAttrs:  cstate.StaticSlotAttrs,
 			DynamicSlotAttrs: cstate.DynamicSlotAttrs,
 			HotplugGone:      cstate.HotplugGone,
+			UntilReboot:      cstate.BootID != "",
+		}
+		if cstate.Expiry != nil {
+			connState.Expiry = *cstate.Expiry
 		}
+		connStateByRef[cref] = connState
 	}
 	return connStateByRef, nil
 }
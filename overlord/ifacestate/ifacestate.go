@@ -22,8 +22,11 @@
 package ifacestate
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -31,7 +34,10 @@ import (
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/interfaces/utils"
+	"github.com/snapcore/snapd/jsonutil"
 	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -85,15 +91,36 @@ type connectOpts struct {
 	AutoConnect bool
 
 	DelayedSetupProfiles bool
+
+	Expiry      time.Time
+	UntilReboot bool
+}
+
+// ConnectOptions holds options for ConnectWithOptions.
+type ConnectOptions struct {
+	// Expiry, if set, is when the connection is automatically removed.
+	Expiry time.Time
+	// UntilReboot makes the connection be automatically removed once the
+	// system has been rebooted.
+	UntilReboot bool
 }
 
 // Connect returns a set of tasks for connecting an interface.
 func Connect(st *state.State, plugSnap, plugName, slotSnap, slotName string) (*state.TaskSet, error) {
+	return ConnectWithOptions(st, plugSnap, plugName, slotSnap, slotName, ConnectOptions{})
+}
+
+// ConnectWithOptions returns a set of tasks for connecting an interface,
+// possibly only temporarily.
+func ConnectWithOptions(st *state.State, plugSnap, plugName, slotSnap, slotName string, opts ConnectOptions) (*state.TaskSet, error) {
 	if err := snapstate.CheckChangeConflictMany(st, []string{plugSnap, slotSnap}, ""); err != nil {
 		return nil, err
 	}
 
-	return connect(st, plugSnap, plugName, slotSnap, slotName, connectOpts{})
+	return connect(st, plugSnap, plugName, slotSnap, slotName, connectOpts{
+		Expiry:      opts.Expiry,
+		UntilReboot: opts.UntilReboot,
+	})
 }
 
 func connect(st *state.State, plugSnap, plugName, slotSnap, slotName string, flags connectOpts) (*state.TaskSet, error) {
@@ -208,6 +235,12 @@ func connect(st *state.State, plugSnap, plugName, slotSnap, slotName string, fla
 	if flags.DelayedSetupProfiles {
 		connectInterface.Set("delayed-setup-profiles", true)
 	}
+	if !flags.Expiry.IsZero() {
+		connectInterface.Set("expiry", flags.Expiry)
+	}
+	if flags.UntilReboot {
+		connectInterface.Set("until-reboot", true)
+	}
 
 	// Expose a copy of all plug and slot attributes coming from yaml to interface hooks. The hooks will be able
 	// to modify them but all attributes will be checked against assertions after the hooks are run.
@@ -280,6 +313,154 @@ func connect(st *state.State, plugSnap, plugName, slotSnap, slotName string, fla
 	return tasks, nil
 }
 
+// UpdateAttrs returns a set of tasks updating in place the dynamic attributes
+// of all the connections of the given plug or slot of a snap. The values are
+// keyed by dotted attribute paths. The security profiles of the connected
+// snaps are regenerated without disconnecting, so that their services keep
+// running, and the interface-changed-[plug|slot]-<name> hook is run on the
+// other side of each connection.
+func UpdateAttrs(st *state.State, snapName, plugOrSlot string, values map[string]interface{}, ignoreChangeID string) (*state.TaskSet, error) {
+	var snapst snapstate.SnapState
+	if err := snapstate.Get(st, snapName, &snapst); err != nil {
+		return nil, err
+	}
+	snapInfo, err := snapst.CurrentInfo()
+	if err != nil {
+		return nil, err
+	}
+	_, isPlug := snapInfo.Plugs[plugOrSlot]
+	if _, isSlot := snapInfo.Slots[plugOrSlot]; !isPlug && !isSlot {
+		return nil, fmt.Errorf("snap %q has no plug or slot named %q", snapName, plugOrSlot)
+	}
+
+	conns, err := getConns(st)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(conns))
+	for id := range conns {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	tasks := state.NewTaskSet()
+	var prev *state.Task
+	for _, id := range ids {
+		cstate := conns[id]
+		if cstate.Undesired || cstate.HotplugGone {
+			continue
+		}
+		connRef, err := interfaces.ParseConnRef(id)
+		if err != nil {
+			return nil, err
+		}
+		if isPlug && (connRef.PlugRef.Snap != snapName || connRef.PlugRef.Name != plugOrSlot) {
+			continue
+		}
+		if !isPlug && (connRef.SlotRef.Snap != snapName || connRef.SlotRef.Name != plugOrSlot) {
+			continue
+		}
+
+		if err := snapstate.CheckChangeConflictMany(st, []string{connRef.PlugRef.Snap, connRef.SlotRef.Snap}, ignoreChangeID); err != nil {
+			return nil, err
+		}
+
+		// check the values early, they are applied to the attributes
+		// current at the time the task runs, so that updates queued
+		// meanwhile are not lost
+		staticAttrs, dynamicAttrs := cstate.StaticSlotAttrs, cstate.DynamicSlotAttrs
+		if isPlug {
+			staticAttrs, dynamicAttrs = cstate.StaticPlugAttrs, cstate.DynamicPlugAttrs
+		}
+		if _, err := patchDynamicAttrs(snapName, staticAttrs, dynamicAttrs, values); err != nil {
+			return nil, err
+		}
+
+		update := st.NewTask("update-connection-attrs", fmt.Sprintf(i18n.G("Update attributes of connection %s:%s to %s:%s"),
+			connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name))
+		update.Set("plug", connRef.PlugRef)
+		update.Set("slot", connRef.SlotRef)
+		update.Set("plug-static", cstate.StaticPlugAttrs)
+		update.Set("slot-static", cstate.StaticSlotAttrs)
+		update.Set("attrs-of-plug", isPlug)
+		update.Set("attrs-values", values)
+		if prev != nil {
+			update.WaitFor(prev)
+		}
+		tasks.AddTask(update)
+		prev = update
+
+		// notify the other side of the connection
+		otherSnap, hookName := connRef.SlotRef.Snap, "interface-changed-slot-"+connRef.SlotRef.Name
+		if !isPlug {
+			otherSnap, hookName = connRef.PlugRef.Snap, "interface-changed-plug-"+connRef.PlugRef.Name
+		}
+		var otherSnapst snapstate.SnapState
+		if err := snapstate.Get(st, otherSnap, &otherSnapst); err != nil {
+			return nil, err
+		}
+		otherSnapInfo, err := otherSnapst.CurrentInfo()
+		if err != nil {
+			return nil, err
+		}
+		if otherSnapInfo.Hooks[hookName] != nil {
+			hookSetup := &hookstate.HookSetup{
+				Snap:     otherSnap,
+				Hook:     hookName,
+				Optional: true,
+			}
+			summary := fmt.Sprintf(i18n.G("Run hook %s of snap %q"), hookSetup.Hook, hookSetup.Snap)
+			hookTask := hookstate.HookTask(st, summary, hookSetup, map[string]interface{}{"attrs-task": update.ID()})
+			hookTask.WaitFor(update)
+			tasks.AddTask(hookTask)
+			prev = hookTask
+		}
+	}
+	if prev == nil {
+		return nil, fmt.Errorf("cannot update attributes of %q of snap %q: not connected", plugOrSlot, snapName)
+	}
+	return tasks, nil
+}
+
+// patchDynamicAttrs returns a copy of the dynamic attributes with the given
+// values set. Static attributes cannot be overwritten.
+func patchDynamicAttrs(snapName string, staticAttrs, dynamicAttrs, values map[string]interface{}) (map[string]interface{}, error) {
+	var patched interface{} = utils.CopyAttributes(dynamicAttrs)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		subkeys, err := config.ParseKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := staticAttrs[subkeys[0]]; ok {
+			return nil, fmt.Errorf("attribute %q cannot be overwritten", key)
+		}
+		data, err := json.Marshal(values[key])
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal attribute %q: %v", key, err)
+		}
+		raw := json.RawMessage(data)
+		patched, err = config.PatchConfig(snapName, subkeys, 0, patched, &raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the patched values are raw JSON, decode them back
+	data, err := json.Marshal(patched)
+	if err != nil {
+		return nil, err
+	}
+	var attrs map[string]interface{}
+	if err := jsonutil.DecodeWithNumber(bytes.NewReader(data), &attrs); err != nil {
+		return nil, err
+	}
+	return utils.NormalizeInterfaceAttributes(attrs).(map[string]interface{}), nil
+}
+
 func initialConnectAttributes(st *state.State, plugSnapInfo *snap.Info, plugSnap string, plugName string, slotSnapInfo *snap.Info, slotSnap string, slotName string) (plugStatic, slotStatic map[string]interface{}, err error) {
 	var plugSnapst snapstate.SnapState
 
@@ -348,6 +529,9 @@ type disconnectOpts struct {
 	AutoDisconnect bool
 	ByHotplug      bool
 	Forget         bool
+	// Undesired marks the connection as undesired even if it was not
+	// auto-connected
+	Undesired bool
 }
 
 // forgetTasks creates a set of tasks for forgetting an inactive connection
@@ -397,6 +581,9 @@ func disconnectTasks(st *state.State, conn *interfaces.Connection, flags disconn
 	if flags.ByHotplug {
 		disconnectTask.Set("by-hotplug", true)
 	}
+	if flags.Undesired {
+		disconnectTask.Set("undesired", true)
+	}
 
 	ts := state.NewTaskSet()
 	var prev *state.Task
@@ -535,6 +722,7 @@ func delayedCrossMgrInit() {
 		// hook into conflict checks mechanisms
 		snapstate.RegisterAffectedSnapsByKind("connect", connectDisconnectAffectedSnaps)
 		snapstate.RegisterAffectedSnapsByKind("disconnect", connectDisconnectAffectedSnaps)
+		snapstate.RegisterAffectedSnapsByKind("update-connection-attrs", connectDisconnectAffectedSnaps)
 
 		// hook into snap linking/unlinking and activation state changes
 		snapstate.AddLinkSnapParticipant(snapstate.LinkSnapParticipantFunc(OnSnapLinkageChanged))
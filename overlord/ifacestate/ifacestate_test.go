@@ -1267,6 +1267,305 @@ func (s *interfaceManagerSuite) TestEnsureProcessesConnectTask(c *C) {
 		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"}}})
 }
 
+func (s *interfaceManagerSuite) TestConnectWithOptionsTemporary(c *C) {
+	s.MockModel(c, nil)
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	restore := ifacestate.MockOsutilBootID(func() (string, error) {
+		return "boot-id-1", nil
+	})
+	defer restore()
+	expiry := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+
+	s.state.Lock()
+	change := s.state.NewChange("kind", "summary")
+	ts, err := ifacestate.ConnectWithOptions(s.state, "consumer", "plug", "producer", "slot", ifacestate.ConnectOptions{
+		Expiry:      expiry,
+		UntilReboot: true,
+	})
+	c.Assert(err, IsNil)
+	task := ts.Tasks()[2]
+	c.Assert(task.Kind(), Equals, "connect")
+	var taskExpiry time.Time
+	c.Assert(task.Get("expiry", &taskExpiry), IsNil)
+	c.Check(taskExpiry.Equal(expiry), Equals, true)
+	var untilReboot bool
+	c.Assert(task.Get("until-reboot", &untilReboot), IsNil)
+	c.Check(untilReboot, Equals, true)
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(change.Err(), IsNil)
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns["consumer:plug producer:slot"], DeepEquals, map[string]interface{}{
+		"interface":   "test",
+		"plug-static": map[string]interface{}{"attr1": "value1"},
+		"slot-static": map[string]interface{}{"attr2": "value2"},
+		"expiry":      expiry.Format(time.RFC3339),
+		"boot-id":     "boot-id-1",
+	})
+
+	connStates, err := ifacestate.ConnectionStates(s.state)
+	c.Assert(err, IsNil)
+	connState := connStates["consumer:plug producer:slot"]
+	c.Check(connState.Expiry.Equal(expiry), Equals, true)
+	c.Check(connState.UntilReboot, Equals, true)
+}
+
+func (s *interfaceManagerSuite) TestEnsureRemovesExpiredConnections(c *C) {
+	s.MockModel(c, nil)
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	s.mockSnap(c, producer2Yaml)
+
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	restore := ifacestate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+	bootID := "boot-id-1"
+	restore = ifacestate.MockOsutilBootID(func() (string, error) { return bootID, nil })
+	defer restore()
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+			"expiry":    now.Add(time.Hour).Format(time.RFC3339),
+		},
+		"consumer:plug producer2:slot": map[string]interface{}{
+			"interface": "test",
+			"boot-id":   "boot-id-1",
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	repo := mgr.Repository()
+
+	// nothing expired yet
+	s.settle(c)
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 0)
+	s.state.Unlock()
+	c.Check(repo.Interfaces().Connections, HasLen, 2)
+
+	// the expiry was reached
+	now = now.Add(time.Hour)
+	s.settle(c)
+
+	s.state.Lock()
+	changes := s.state.Changes()
+	c.Assert(changes, HasLen, 1)
+	c.Check(changes[0].Kind(), Equals, "disconnect-snap")
+	c.Check(changes[0].Summary(), Equals, "Disconnect expired connection consumer:plug from producer:slot")
+	c.Check(changes[0].Status(), Equals, state.DoneStatus)
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, HasLen, 2)
+	c.Check(conns["consumer:plug producer2:slot"], NotNil)
+	// the test interface auto-connects, so the connection is kept as
+	// undesired to not be auto-connected again
+	c.Check(conns["consumer:plug producer:slot"], DeepEquals, map[string]interface{}{
+		"interface": "test",
+		"undesired": true,
+	})
+	s.state.Unlock()
+	c.Check(repo.Interfaces().Connections, DeepEquals, []*interfaces.ConnRef{{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer2", Name: "slot"},
+	}})
+
+	// the system was rebooted
+	bootID = "boot-id-2"
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 2)
+	conns = nil
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot":  map[string]interface{}{"interface": "test", "undesired": true},
+		"consumer:plug producer2:slot": map[string]interface{}{"interface": "test", "undesired": true},
+	})
+	c.Check(repo.Interfaces().Connections, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestEnsureRemovesExpiredConnectionsNotAutoConnectable(c *C) {
+	s.MockModel(c, nil)
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"})
+	r := assertstest.MockBuiltinBaseDeclaration([]byte(`
+type: base-declaration
+authority-id: canonical
+series: 16
+slots:
+  test:
+    deny-auto-connection: true
+`))
+	s.AddCleanup(r)
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+			"expiry":    time.Now().Add(-time.Minute).Format(time.RFC3339),
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 1)
+	// nothing to keep as the connection is not auto-connected
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, HasLen, 0)
+	c.Check(mgr.Repository().Interfaces().Connections, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestEnsureRemovesExpiredConnectionsErrorIsLogged(c *C) {
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	restore := ifacestate.MockOsutilBootID(func() (string, error) {
+		return "", fmt.Errorf("boom")
+	})
+	defer restore()
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+			"boot-id":   "boot-id-1",
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	// the rest of Ensure still happens
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(logbuf.String(), testutil.Contains, "cannot remove expired connections: cannot get boot ID: boom")
+}
+
+func (s *interfaceManagerSuite) TestEnsureRemovesExpiredConnectionsConflict(c *C) {
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+			"expiry":    time.Now().Add(-time.Minute).Format(time.RFC3339),
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+
+	// another change is in progress for the consumer snap
+	s.state.Lock()
+	chg := s.state.NewChange("other-change", "...")
+	t := s.state.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{SideInfo: &snap.SideInfo{RealName: "consumer"}})
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	c.Assert(mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 1)
+	c.Check(mgr.Repository().Interfaces().Connections, HasLen, 1)
+}
+
+func (s *interfaceManagerSuite) TestUpdateTemporaryConnection(c *C) {
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	s.mockSnap(c, producer2Yaml)
+	_ = s.manager(c)
+
+	restore := ifacestate.MockOsutilBootID(func() (string, error) {
+		return "boot-id-1", nil
+	})
+	defer restore()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+			"expiry":    now.Add(time.Hour).Format(time.RFC3339),
+		},
+		"consumer:plug producer2:slot": map[string]interface{}{
+			"interface": "test",
+		},
+	})
+
+	connRef := &interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+	err := ifacestate.UpdateTemporaryConnection(s.state, connRef, ifacestate.ConnectOptions{
+		Expiry:      now.Add(2 * time.Hour),
+		UntilReboot: true,
+	})
+	c.Assert(err, IsNil)
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns["consumer:plug producer:slot"], DeepEquals, map[string]interface{}{
+		"interface": "test",
+		"expiry":    now.Add(2 * time.Hour).Format(time.RFC3339),
+		"boot-id":   "boot-id-1",
+	})
+
+	// the new options replace the previous ones
+	err = ifacestate.UpdateTemporaryConnection(s.state, connRef, ifacestate.ConnectOptions{
+		UntilReboot: true,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns["consumer:plug producer:slot"], DeepEquals, map[string]interface{}{
+		"interface": "test",
+		"boot-id":   "boot-id-1",
+	})
+
+	// permanent connections are left alone
+	connRef.SlotRef.Snap = "producer2"
+	err = ifacestate.UpdateTemporaryConnection(s.state, connRef, ifacestate.ConnectOptions{
+		Expiry: now.Add(time.Hour),
+	})
+	c.Assert(err, ErrorMatches, `cannot make existing permanent connection "consumer:plug producer2:slot" temporary`)
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns["consumer:plug producer2:slot"], DeepEquals, map[string]interface{}{
+		"interface": "test",
+	})
+
+	connRef.PlugRef.Name = "otherplug"
+	err = ifacestate.UpdateTemporaryConnection(s.state, connRef, ifacestate.ConnectOptions{
+		UntilReboot: true,
+	})
+	c.Assert(err, ErrorMatches, `cannot update temporary connection "consumer:otherplug producer2:slot": not connected`)
+}
+
 func (s *interfaceManagerSuite) TestConnectTaskCheckInterfaceMismatch(c *C) {
 	s.MockModel(c, nil)
 
@@ -5021,6 +5320,143 @@ func (s *interfaceManagerSuite) TestCheckInterfacesConsidersImplicitSlots(c *C)
 	c.Check(snapInfo.Slots["home"], NotNil)
 }
 
+func (s *interfaceManagerSuite) mockPolicyCheckBaseDecl(c *C) {
+	restore := assertstest.MockBuiltinBaseDeclaration([]byte(`
+type: base-declaration
+authority-id: canonical
+series: 16
+plugs:
+  test2:
+    deny-installation: true
+slots:
+  test:
+    deny-auto-connection: true
+`))
+	s.AddCleanup(restore)
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+}
+
+func (s *interfaceManagerSuite) TestCheckPolicy(c *C) {
+	deviceCtx := s.TrivialDeviceContext(c, nil)
+	s.mockPolicyCheckBaseDecl(c)
+
+	s.MockSnapDecl(c, "producer", "producer-publisher", nil)
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	// the consumer snap is not installed
+	snapInfo := snaptest.MockInfo(c, consumerYaml, nil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	report, err := ifacestate.CheckPolicy(s.state, snapInfo, &ifacestate.PolicyCheckOptions{
+		Model: deviceCtx.Model(),
+	})
+	c.Assert(err, IsNil)
+	c.Check(report, DeepEquals, &ifacestate.PolicyCheckReport{
+		Snap: "consumer",
+		Installation: []*ifacestate.InstallPolicyCheck{{
+			Plug:      "otherplug",
+			Interface: "test2",
+			PolicyDecision: ifacestate.PolicyDecision{
+				Rule:   `plug rule of interface "test2" in the base-declaration`,
+				Reason: `installation denied by "otherplug" plug rule of interface "test2"`,
+			},
+		}, {
+			Plug:           "plug",
+			Interface:      "test",
+			PolicyDecision: ifacestate.PolicyDecision{Allowed: true},
+		}},
+		Connections: []*ifacestate.ConnectionPolicyCheck{{
+			Plug:      "consumer:plug",
+			Slot:      "producer:slot",
+			Interface: "test",
+			Connect: ifacestate.PolicyDecision{
+				Allowed: true,
+				Rule:    `slot rule of interface "test" in the base-declaration`,
+			},
+			AutoConnect: ifacestate.PolicyDecision{
+				Rule:   `slot rule of interface "test" in the base-declaration`,
+				Reason: `auto-connection denied by slot rule of interface "test"`,
+			},
+		}},
+	})
+}
+
+func (s *interfaceManagerSuite) TestCheckPolicyWithSnapDeclaration(c *C) {
+	deviceCtx := s.TrivialDeviceContext(c, nil)
+	s.mockPolicyCheckBaseDecl(c)
+
+	s.MockSnapDecl(c, "producer", "producer-publisher", nil)
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	s.MockSnapDecl(c, "consumer", "consumer-publisher", map[string]interface{}{
+		"format": "1",
+		"plugs": map[string]interface{}{
+			"test": map[string]interface{}{
+				"allow-auto-connection": "true",
+			},
+			"test2": map[string]interface{}{
+				"allow-installation": "true",
+			},
+		},
+	})
+	a, err := s.Db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "consumeridididididididididididid",
+	})
+	c.Assert(err, IsNil)
+	snapDecl := a.(*asserts.SnapDeclaration)
+
+	snapInfo := snaptest.MockInfo(c, consumerYaml, nil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	report, err := ifacestate.CheckPolicy(s.state, snapInfo, &ifacestate.PolicyCheckOptions{
+		SnapDeclaration: snapDecl,
+		Model:           deviceCtx.Model(),
+	})
+	c.Assert(err, IsNil)
+	c.Check(snapInfo.SnapID, Equals, "consumeridididididididididididid")
+	c.Check(report.Asserted, Equals, true)
+	c.Assert(report.Installation, HasLen, 2)
+	c.Check(report.Installation[0].Allowed, Equals, true)
+	c.Check(report.Installation[0].Rule, Equals, `plug rule of interface "test2" in the snap-declaration of "consumer"`)
+	c.Assert(report.Connections, HasLen, 1)
+	c.Check(report.Connections[0].AutoConnect, DeepEquals, ifacestate.PolicyDecision{
+		Allowed: true,
+		Rule:    `plug rule of interface "test" in the snap-declaration of "consumer"`,
+	})
+
+	// the declaration must be for the snap
+	otherInfo := snaptest.MockInfo(c, producerYaml, nil)
+	_, err = ifacestate.CheckPolicy(s.state, otherInfo, &ifacestate.PolicyCheckOptions{
+		SnapDeclaration: snapDecl,
+		Model:           deviceCtx.Model(),
+	})
+	c.Check(err, ErrorMatches, `cannot use snap-declaration for snap "consumer" with snap "producer"`)
+}
+
+func (s *interfaceManagerSuite) TestCheckPolicyRestrictedToSnaps(c *C) {
+	deviceCtx := s.TrivialDeviceContext(c, nil)
+	s.mockPolicyCheckBaseDecl(c)
+
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	snapInfo := snaptest.MockInfo(c, consumerYaml, nil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	report, err := ifacestate.CheckPolicy(s.state, snapInfo, &ifacestate.PolicyCheckOptions{
+		Model: deviceCtx.Model(),
+		Snaps: []string{"other"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(report.Connections, HasLen, 0)
+}
+
 // Test that setup-snap-security gets undone correctly when a snap is installed
 // but the installation fails (the security profiles are removed).
 func (s *interfaceManagerSuite) TestUndoSetupProfilesOnInstall(c *C) {
@@ -9305,3 +9741,258 @@ func (s *interfaceManagerSuite) TestOnSnapLinkageChanged(c *C) {
 		Sequence: []*snap.SideInfo{&info.SideInfo},
 	})
 }
+
+const consumerChangedHookYaml = `
+name: consumer
+version: 1
+plugs:
+ plug:
+  interface: test
+  static: plug-static-value
+hooks:
+ interface-changed-plug-plug:
+`
+
+const producerChangedHookYaml = `
+name: producer
+version: 1
+slots:
+ slot:
+  interface: test
+  static: slot-static-value
+`
+
+func (s *interfaceManagerSuite) mockConnectionForUpdate(c *C) {
+	s.mockSnap(c, consumerChangedHookYaml)
+	s.mockSnap(c, producerChangedHookYaml)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"plug-static":  map[string]interface{}{"static": "plug-static-value"},
+			"slot-static":  map[string]interface{}{"static": "slot-static-value"},
+			"slot-dynamic": map[string]interface{}{"dynamic": "old", "nested": map[string]interface{}{"a": "b"}},
+		},
+	})
+}
+
+func (s *interfaceManagerSuite) TestUpdateAttrsTasks(c *C) {
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockConnectionForUpdate(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	ts, err := ifacestate.UpdateAttrs(s.state, "producer", "slot", map[string]interface{}{"dynamic": "new", "nested.c": 1}, "")
+	c.Assert(err, IsNil)
+	tasks := ts.Tasks()
+	c.Assert(tasks, HasLen, 2)
+
+	update := tasks[0]
+	c.Check(update.Kind(), Equals, "update-connection-attrs")
+	var plugRef interfaces.PlugRef
+	c.Assert(update.Get("plug", &plugRef), IsNil)
+	c.Check(plugRef, Equals, interfaces.PlugRef{Snap: "consumer", Name: "plug"})
+	var slotRef interfaces.SlotRef
+	c.Assert(update.Get("slot", &slotRef), IsNil)
+	c.Check(slotRef, Equals, interfaces.SlotRef{Snap: "producer", Name: "slot"})
+	// only the requested values are stored, they are applied when the
+	// task runs
+	var ofPlug bool
+	c.Assert(update.Get("attrs-of-plug", &ofPlug), IsNil)
+	c.Check(ofPlug, Equals, false)
+	var values map[string]interface{}
+	c.Assert(update.Get("attrs-values", &values), IsNil)
+	c.Check(values, DeepEquals, map[string]interface{}{"dynamic": "new", "nested.c": 1.0})
+	var staticAttrs map[string]interface{}
+	c.Assert(update.Get("slot-static", &staticAttrs), IsNil)
+	c.Check(staticAttrs, DeepEquals, map[string]interface{}{"static": "slot-static-value"})
+
+	// the hook of the other side of the connection is run
+	hook := tasks[1]
+	c.Check(hook.Kind(), Equals, "run-hook")
+	c.Check(hook.WaitTasks(), DeepEquals, []*state.Task{update})
+	var hsup hookstate.HookSetup
+	c.Assert(hook.Get("hook-setup", &hsup), IsNil)
+	c.Check(hsup, Equals, hookstate.HookSetup{Snap: "consumer", Hook: "interface-changed-plug-plug", Optional: true})
+	var hookContext map[string]interface{}
+	c.Assert(hook.Get("hook-context", &hookContext), IsNil)
+	c.Check(hookContext, DeepEquals, map[string]interface{}{"attrs-task": update.ID()})
+}
+
+func (s *interfaceManagerSuite) TestUpdateAttrsErrors(c *C) {
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockConnectionForUpdate(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := ifacestate.UpdateAttrs(s.state, "producer", "slot", map[string]interface{}{"static": "new"}, "")
+	c.Check(err, ErrorMatches, `attribute "static" cannot be overwritten`)
+
+	_, err = ifacestate.UpdateAttrs(s.state, "producer", "unknown", map[string]interface{}{"a": "b"}, "")
+	c.Check(err, ErrorMatches, `snap "producer" has no plug or slot named "unknown"`)
+
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "undesired": true},
+	})
+	_, err = ifacestate.UpdateAttrs(s.state, "producer", "slot", map[string]interface{}{"a": "b"}, "")
+	c.Check(err, ErrorMatches, `cannot update attributes of "slot" of snap "producer": not connected`)
+}
+
+func (s *interfaceManagerSuite) TestUpdateAttrsConflict(c *C) {
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockConnectionForUpdate(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("other", "...")
+	t := s.state.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{SideInfo: &snap.SideInfo{RealName: "consumer"}})
+	chg.AddTask(t)
+
+	_, err := ifacestate.UpdateAttrs(s.state, "producer", "slot", map[string]interface{}{"a": "b"}, "")
+	c.Check(err, ErrorMatches, `snap "consumer" has "other" change in progress`)
+
+	// conflicts with the given change are ignored
+	_, err = ifacestate.UpdateAttrs(s.state, "producer", "slot", map[string]interface{}{"a": "b"}, chg.ID())
+	c.Check(err, IsNil)
+}
+
+func (s *interfaceManagerSuite) TestUpdateAttrsRun(c *C) {
+	s.MockModel(c, nil)
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockConnectionForUpdate(c)
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	ts, err := ifacestate.UpdateAttrs(s.state, "producer", "slot", map[string]interface{}{"dynamic": "new"}, "")
+	c.Assert(err, IsNil)
+	change := s.state.NewChange("update-attrs", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Err(), IsNil)
+	c.Check(change.Status(), Equals, state.DoneStatus)
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"plug-static":  map[string]interface{}{"static": "plug-static-value"},
+			"slot-static":  map[string]interface{}{"static": "slot-static-value"},
+			"slot-dynamic": map[string]interface{}{"dynamic": "new", "nested": map[string]interface{}{"a": "b"}},
+		},
+	})
+
+	conn, err := mgr.Repository().Connection(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(conn.Slot.DynamicAttrs(), DeepEquals, map[string]interface{}{"dynamic": "new", "nested": map[string]interface{}{"a": "b"}})
+
+	// the security profiles of both snaps are regenerated
+	c.Assert(s.secBackend.SetupCalls, HasLen, 2)
+	c.Assert(s.secBackend.RemoveCalls, HasLen, 0)
+	c.Check(s.secBackend.SetupCalls[0].SnapInfo.InstanceName(), Equals, "producer")
+	c.Check(s.secBackend.SetupCalls[1].SnapInfo.InstanceName(), Equals, "consumer")
+}
+
+func (s *interfaceManagerSuite) TestUpdateAttrsRunQueuedUpdates(c *C) {
+	s.MockModel(c, nil)
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockConnectionForUpdate(c)
+	mgr := s.manager(c)
+
+	// as with two snapctl set calls in the same hook, both updates are
+	// created before any of them runs
+	s.state.Lock()
+	ts1, err := ifacestate.UpdateAttrs(s.state, "producer", "slot", map[string]interface{}{"a": 1}, "")
+	c.Assert(err, IsNil)
+	ts2, err := ifacestate.UpdateAttrs(s.state, "producer", "slot", map[string]interface{}{"nested.b": 2}, "")
+	c.Assert(err, IsNil)
+	ts2.WaitAll(ts1)
+	change := s.state.NewChange("update-attrs", "")
+	change.AddAll(ts1)
+	change.AddAll(ts2)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Err(), IsNil)
+	expected := map[string]interface{}{
+		"dynamic": "old",
+		"a":       int64(1),
+		"nested":  map[string]interface{}{"a": "b", "b": int64(2)},
+	}
+	conn, err := mgr.Repository().Connection(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(conn.Slot.DynamicAttrs(), DeepEquals, expected)
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns["consumer:plug producer:slot"].(map[string]interface{})["slot-dynamic"], DeepEquals, map[string]interface{}{
+		"dynamic": "old",
+		"a":       1.0,
+		"nested":  map[string]interface{}{"a": "b", "b": 2.0},
+	})
+}
+
+func (s *interfaceManagerSuite) TestUpdateAttrsRunDenied(c *C) {
+	s.MockModel(c, nil)
+	s.mockIfaces(&ifacetest.TestInterface{
+		InterfaceName: "test",
+		BeforeConnectSlotCallback: func(slot *interfaces.ConnectedSlot) error {
+			var value string
+			if err := slot.Attr("dynamic", &value); err == nil && value == "bad" {
+				return fmt.Errorf("bad value")
+			}
+			return nil
+		},
+	})
+	s.mockConnectionForUpdate(c)
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	ts, err := ifacestate.UpdateAttrs(s.state, "producer", "slot", map[string]interface{}{"dynamic": "bad"}, "")
+	c.Assert(err, IsNil)
+	change := s.state.NewChange("update-attrs", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Err(), ErrorMatches, `(?s).*cannot update slot "slot" of snap "producer": bad value.*`)
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns["consumer:plug producer:slot"].(map[string]interface{})["slot-dynamic"], DeepEquals,
+		map[string]interface{}{"dynamic": "old", "nested": map[string]interface{}{"a": "b"}})
+
+	conn, err := mgr.Repository().Connection(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(conn.Slot.DynamicAttrs(), DeepEquals, map[string]interface{}{"dynamic": "old", "nested": map[string]interface{}{"a": "b"}})
+	c.Check(s.secBackend.SetupCalls, HasLen, 0)
+}
@@ -0,0 +1,275 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// PolicyCheckOptions holds options for CheckPolicy.
+type PolicyCheckOptions struct {
+	// SnapDeclaration is used as the snap declaration of the snap instead
+	// of the one in the system assertion database.
+	SnapDeclaration *asserts.SnapDeclaration
+	// Model is used instead of the model of the device.
+	Model *asserts.Model
+	// Snaps restricts the installed snaps whose plugs and slots are
+	// checked for connections, all of them are used if empty.
+	Snaps []string
+}
+
+// PolicyDecision is the outcome of a policy check.
+type PolicyDecision struct {
+	Allowed bool `json:"allowed"`
+	// Rule describes the rule which decided, if any.
+	Rule string `json:"rule,omitempty"`
+	// Reason is why the operation is not allowed.
+	Reason string `json:"reason,omitempty"`
+}
+
+func newPolicyDecision(rule string, err error) PolicyDecision {
+	decision := PolicyDecision{Allowed: err == nil, Rule: rule}
+	if err != nil {
+		decision.Reason = err.Error()
+	}
+	return decision
+}
+
+// InstallPolicyCheck is the outcome of the installation policy check for a
+// plug or slot of the snap.
+type InstallPolicyCheck struct {
+	Plug      string `json:"plug,omitempty"`
+	Slot      string `json:"slot,omitempty"`
+	Interface string `json:"interface"`
+	PolicyDecision
+}
+
+// ConnectionPolicyCheck is the outcome of the connection policy checks for
+// a plug and a slot.
+type ConnectionPolicyCheck struct {
+	Plug        string         `json:"plug"`
+	Slot        string         `json:"slot"`
+	Interface   string         `json:"interface"`
+	Connect     PolicyDecision `json:"connect"`
+	AutoConnect PolicyDecision `json:"auto-connect"`
+}
+
+// PolicyCheckReport is the outcome of the policy checks for a snap.
+type PolicyCheckReport struct {
+	Snap string `json:"snap"`
+	// Asserted is whether a snap declaration was available for the snap,
+	// otherwise only the base declaration is used.
+	Asserted     bool                     `json:"asserted"`
+	Installation []*InstallPolicyCheck    `json:"installation,omitempty"`
+	Connections  []*ConnectionPolicyCheck `json:"connections,omitempty"`
+}
+
+// CheckPolicy evaluates the base and snap declaration rules for installing
+// the given snap, and for connecting its plugs and slots with those of the
+// installed snaps, without changing the system.
+func CheckPolicy(st *state.State, info *snap.Info, opts *PolicyCheckOptions) (*PolicyCheckReport, error) {
+	if opts == nil {
+		opts = &PolicyCheckOptions{}
+	}
+
+	baseDecl, err := assertstate.BaseDeclaration(st)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot find base declaration: %v", err)
+	}
+
+	modelAs := opts.Model
+	if modelAs == nil {
+		deviceCtx, err := snapstate.DeviceCtxFromState(st, nil)
+		if err != nil {
+			return nil, fmt.Errorf("cannot check policy without a model: %v", err)
+		}
+		modelAs = deviceCtx.Model()
+	}
+	var storeAs *asserts.Store
+	if modelAs.Store() != "" {
+		storeAs, err = assertstate.Store(st, modelAs.Store())
+		if err != nil && !errors.Is(err, &asserts.NotFoundError{}) {
+			return nil, err
+		}
+	}
+
+	snapDecl := opts.SnapDeclaration
+	if snapDecl != nil {
+		if snapDecl.SnapName() != info.SnapName() {
+			return nil, fmt.Errorf("cannot use snap-declaration for snap %q with snap %q", snapDecl.SnapName(), info.SnapName())
+		}
+		info.SnapID = snapDecl.SnapID()
+	} else if info.SnapID != "" {
+		snapDecl, err = assertstate.SnapDeclaration(st, info.SnapID)
+		if err != nil && !errors.Is(err, &asserts.NotFoundError{}) {
+			return nil, err
+		}
+	}
+
+	if err := addImplicitSlots(st, info); err != nil {
+		return nil, err
+	}
+
+	report := &PolicyCheckReport{
+		Snap:     info.InstanceName(),
+		Asserted: snapDecl != nil,
+	}
+
+	ic := policy.InstallCandidate{
+		Snap:            info,
+		SnapDeclaration: snapDecl,
+		BaseDeclaration: baseDecl,
+		Model:           modelAs,
+		Store:           storeAs,
+	}
+	for _, slot := range sortedSlots(info.Slots) {
+		rule, err := ic.CheckSlot(slot)
+		report.Installation = append(report.Installation, &InstallPolicyCheck{
+			Slot:           slot.Name,
+			Interface:      slot.Interface,
+			PolicyDecision: newPolicyDecision(rule, err),
+		})
+	}
+	for _, plug := range sortedPlugs(info.Plugs) {
+		rule, err := ic.CheckPlug(plug)
+		report.Installation = append(report.Installation, &InstallPolicyCheck{
+			Plug:           plug.Name,
+			Interface:      plug.Interface,
+			PolicyDecision: newPolicyDecision(rule, err),
+		})
+	}
+
+	// the snap declarations of the installed snaps, nil for the unasserted ones
+	decls := map[string]*asserts.SnapDeclaration{info.InstanceName(): snapDecl}
+	declFor := func(other *snap.Info) (*asserts.SnapDeclaration, error) {
+		if decl, ok := decls[other.InstanceName()]; ok {
+			return decl, nil
+		}
+		var decl *asserts.SnapDeclaration
+		if other.SnapID != "" {
+			decl, err = assertstate.SnapDeclaration(st, other.SnapID)
+			if err != nil && !errors.Is(err, &asserts.NotFoundError{}) {
+				return nil, err
+			}
+		}
+		decls[other.InstanceName()] = decl
+		return decl, nil
+	}
+	// the plugs and slots of the installed snap with the same name are
+	// replaced by the ones of the snap
+	isCandidate := func(other *snap.Info) bool {
+		if other.InstanceName() == info.InstanceName() {
+			return false
+		}
+		return len(opts.Snaps) == 0 || strutil.ListContains(opts.Snaps, other.InstanceName())
+	}
+	check := func(plug *snap.PlugInfo, slot *snap.SlotInfo) error {
+		plugDecl, err := declFor(plug.Snap)
+		if err != nil {
+			return err
+		}
+		slotDecl, err := declFor(slot.Snap)
+		if err != nil {
+			return err
+		}
+		cc := policy.ConnectCandidate{
+			Plug:                interfaces.NewConnectedPlug(plug, nil, nil),
+			PlugSnapDeclaration: plugDecl,
+			Slot:                interfaces.NewConnectedSlot(slot, nil, nil),
+			SlotSnapDeclaration: slotDecl,
+			BaseDeclaration:     baseDecl,
+			Model:               modelAs,
+			Store:               storeAs,
+		}
+		rule := cc.DecidingRule()
+		_, autoErr := cc.CheckAutoConnect()
+		report.Connections = append(report.Connections, &ConnectionPolicyCheck{
+			Plug:        fmt.Sprintf("%s:%s", plug.Snap.InstanceName(), plug.Name),
+			Slot:        fmt.Sprintf("%s:%s", slot.Snap.InstanceName(), slot.Name),
+			Interface:   plug.Interface,
+			Connect:     newPolicyDecision(rule, cc.Check()),
+			AutoConnect: newPolicyDecision(rule, autoErr),
+		})
+		return nil
+	}
+
+	repo := ifacerepo.Get(st)
+	for _, plug := range sortedPlugs(info.Plugs) {
+		// the snap can connect to its own slots too
+		var slots []*snap.SlotInfo
+		for _, slot := range sortedSlots(info.Slots) {
+			if slot.Interface == plug.Interface {
+				slots = append(slots, slot)
+			}
+		}
+		for _, slot := range repo.AllSlots(plug.Interface) {
+			if isCandidate(slot.Snap) {
+				slots = append(slots, slot)
+			}
+		}
+		for _, slot := range slots {
+			if err := check(plug, slot); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, slot := range sortedSlots(info.Slots) {
+		for _, plug := range repo.AllPlugs(slot.Interface) {
+			if !isCandidate(plug.Snap) {
+				continue
+			}
+			if err := check(plug, slot); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+func sortedPlugs(plugs map[string]*snap.PlugInfo) []*snap.PlugInfo {
+	sorted := make([]*snap.PlugInfo, 0, len(plugs))
+	for _, plug := range plugs {
+		sorted = append(sorted, plug)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+func sortedSlots(slots map[string]*snap.SlotInfo) []*snap.SlotInfo {
+	sorted := make([]*snap.SlotInfo, 0, len(slots))
+	for _, slot := range slots {
+		sorted = append(sorted, slot)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
@@ -20,7 +20,11 @@
 // Package schema holds structs for reading and writing interface-related state data.
 package schema
 
-import "github.com/snapcore/snapd/snap"
+import (
+	"time"
+
+	"github.com/snapcore/snapd/snap"
+)
 
 // ConnState holds properties of an interface connection.
 type ConnState struct {
@@ -41,4 +45,9 @@ type ConnState struct {
 	// slots.
 	HotplugGone bool            `json:"hotplug-gone,omitempty" yaml:"hotplug-gone,omitempty"`
 	HotplugKey  snap.HotplugKey `json:"hotplug-key,omitempty" yaml:"hotplug-key,omitempty"`
+	// Temporary connections: Expiry is when the connection is
+	// automatically removed, BootID is set for connections lasting until
+	// the next reboot and is the ID of the boot they were established in.
+	Expiry *time.Time `json:"expiry,omitempty" yaml:"expiry,omitempty"`
+	BootID string     `json:"boot-id,omitempty" yaml:"boot-id,omitempty"`
 }
@@ -0,0 +1,182 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var (
+	timeNow      = time.Now
+	osutilBootID = osutil.BootID
+)
+
+// UpdateTemporaryConnection updates when an existing temporary connection is
+// automatically removed, as requested again with the given options. The
+// options replace the ones the connection was established with. Permanent
+// connections cannot be made temporary.
+func UpdateTemporaryConnection(st *state.State, connRef *interfaces.ConnRef, opts ConnectOptions) error {
+	if err := snapstate.CheckChangeConflictMany(st, []string{connRef.PlugRef.Snap, connRef.SlotRef.Snap}, ""); err != nil {
+		return err
+	}
+
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+	cstate, ok := conns[connRef.ID()]
+	if !ok || cstate.Undesired || cstate.HotplugGone {
+		return fmt.Errorf("cannot update temporary connection %q: not connected", connRef.ID())
+	}
+	if cstate.Expiry == nil && cstate.BootID == "" {
+		return fmt.Errorf("cannot make existing permanent connection %q temporary", connRef.ID())
+	}
+
+	cstate.Expiry = nil
+	if !opts.Expiry.IsZero() {
+		expiry := opts.Expiry
+		cstate.Expiry = &expiry
+	}
+	cstate.BootID = ""
+	if opts.UntilReboot {
+		cstate.BootID, err = osutilBootID()
+		if err != nil {
+			return fmt.Errorf("cannot get boot ID: %v", err)
+		}
+	}
+	setConns(st, conns)
+	// the expiry may now be earlier than the one removal was scheduled for
+	st.EnsureBefore(0)
+	return nil
+}
+
+// removeExpiredConnections creates changes removing the temporary
+// connections which expired, or which were established until a reboot that
+// happened since. Connections whose snaps are busy with other changes are
+// left for a later Ensure.
+//
+// Expired connections are disconnected as if by the user, so connections
+// which would be auto-connected are marked as undesired, restoring the
+// state from before they were connected temporarily.
+func (m *InterfaceManager) removeExpiredConnections() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	conns, err := getConns(m.state)
+	if err != nil {
+		return err
+	}
+
+	now := timeNow()
+	var bootID string
+	var autochecker *autoConnectChecker
+	var nextExpiry time.Time
+	for id, cstate := range conns {
+		if cstate.Undesired || cstate.HotplugGone {
+			continue
+		}
+		expired := false
+		if cstate.Expiry != nil {
+			if now.Before(*cstate.Expiry) {
+				if nextExpiry.IsZero() || cstate.Expiry.Before(nextExpiry) {
+					nextExpiry = *cstate.Expiry
+				}
+			} else {
+				expired = true
+			}
+		}
+		if !expired && cstate.BootID != "" {
+			if bootID == "" {
+				bootID, err = osutilBootID()
+				if err != nil {
+					return fmt.Errorf("cannot get boot ID: %v", err)
+				}
+			}
+			expired = cstate.BootID != bootID
+		}
+		if !expired {
+			continue
+		}
+
+		connRef, err := interfaces.ParseConnRef(id)
+		if err != nil {
+			return err
+		}
+		ts, err := m.disconnectExpired(connRef, &autochecker)
+		if err != nil {
+			var conflictErr *snapstate.ChangeConflictError
+			if errors.As(err, &conflictErr) {
+				logger.Debugf("cannot remove expired connection %s yet: %v", id, err)
+				continue
+			}
+			return err
+		}
+		summary := fmt.Sprintf(i18n.G("Disconnect expired connection %s:%s from %s:%s"),
+			connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name)
+		chg := m.state.NewChange("disconnect-snap", summary)
+		chg.AddAll(ts)
+		m.state.EnsureBefore(0)
+	}
+
+	if !nextExpiry.IsZero() {
+		m.state.EnsureBefore(nextExpiry.Sub(now))
+	}
+	return nil
+}
+
+// disconnectExpired returns the tasks disconnecting the given expired
+// connection. Connections that would be auto-connected are marked as
+// undesired, so that they are not auto-connected again, as they could only
+// have been connected temporarily after being disconnected by the user.
+func (m *InterfaceManager) disconnectExpired(connRef *interfaces.ConnRef, autochecker **autoConnectChecker) (*state.TaskSet, error) {
+	conn, err := m.repo.Connection(connRef)
+	if err != nil {
+		// the plug or slot is gone, just drop the connection
+		return Forget(m.state, m.repo, connRef)
+	}
+	if err := snapstate.CheckChangeConflictMany(m.state, []string{connRef.PlugRef.Snap, connRef.SlotRef.Snap}, ""); err != nil {
+		return nil, err
+	}
+
+	if *autochecker == nil {
+		deviceCtx, err := snapstate.DeviceCtx(m.state, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		*autochecker, err = newAutoConnectChecker(m.state, nil, m.repo, deviceCtx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	autoConnectable, _, err := (*autochecker).check(conn.Plug, conn.Slot)
+	if err != nil {
+		return nil, err
+	}
+	return disconnectTasks(m.state, conn, disconnectOpts{Undesired: autoConnectable})
+}
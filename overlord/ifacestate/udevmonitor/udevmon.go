@@ -95,6 +95,7 @@ func (m *Monitor) Connect() error {
 	// TODO: extend with other criteria based on the hotplug interfaces
 	filter = &netlink.RuleDefinitions{
 		Rules: []netlink.RuleDefinition{
+			{Env: map[string]string{"SUBSYSTEM": "block"}},
 			{Env: map[string]string{"SUBSYSTEM": "net"}},
 			{Env: map[string]string{"SUBSYSTEM": "tty"}},
 			{Env: map[string]string{"SUBSYSTEM": "usb"}},
@@ -207,6 +207,10 @@ func HoldRefresh(st *state.State, level HoldLevel, gatingSnap string, holdDurati
 	}
 
 	var durationMin time.Duration
+	// holds by gating snaps are renewed on every auto-refresh attempt, so
+	// only new holds and changes of their level are recorded in the
+	// history, while holds by the user are always recorded
+	changedHolds := make(map[string]bool)
 
 	now := timeNow()
 	for _, heldSnap := range affectingSnaps {
@@ -218,6 +222,9 @@ func HoldRefresh(st *state.State, level HoldLevel, gatingSnap string, holdDurati
 				FirstHeld: now,
 			}
 		}
+		if !ok || hold.Level != level || gatingSnap == "system" {
+			changedHolds[heldSnap] = true
+		}
 		hold.Level = level
 
 		if gatingSnap == "system" {
@@ -303,9 +310,33 @@ func HoldRefresh(st *state.State, level HoldLevel, gatingSnap string, holdDurati
 	if len(herr.SnapsInError) > 0 {
 		return 0, herr
 	}
+	for _, heldSnap := range affectingSnaps {
+		if !changedHolds[heldSnap] {
+			continue
+		}
+		holdUntil := gating[heldSnap][gatingSnap].HoldUntil
+		if err := addHistory(st, heldSnap, &HistoryEntry{
+			Action:    HistoryHold,
+			Trigger:   holdTrigger(gatingSnap),
+			Outcome:   HistoryOutcomeDone,
+			HeldBy:    gatingSnap,
+			HoldUntil: &holdUntil,
+		}); err != nil {
+			return 0, err
+		}
+	}
 	return durationMin, nil
 }
 
+// holdTrigger returns the history trigger of holds by the given gating
+// snap, holds by "system" are requested by the user.
+func holdTrigger(gatingSnap string) HistoryTrigger {
+	if gatingSnap == "system" {
+		return HistoryTriggerManual
+	}
+	return HistoryTriggerAuto
+}
+
 // ProceedWithRefresh unblocks a set of snaps held by gatingSnap for refresh.
 // If no snaps are specified, all snaps held by gatingSnap are unblocked. This
 // should be called for --proceed on the gatingSnap.
@@ -324,6 +355,14 @@ func ProceedWithRefresh(st *state.State, gatingSnap string, unholdSnaps []string
 		if _, ok := gatingSnaps[gatingSnap]; ok {
 			delete(gatingSnaps, gatingSnap)
 			changed = true
+			if err := addHistory(st, heldSnap, &HistoryEntry{
+				Action:  HistoryUnhold,
+				Trigger: holdTrigger(gatingSnap),
+				Outcome: HistoryOutcomeDone,
+				HeldBy:  gatingSnap,
+			}); err != nil {
+				return err
+			}
 		}
 		if len(gatingSnaps) == 0 {
 			delete(gating, heldSnap)
@@ -134,6 +134,15 @@ func checkChangeConflictExclusiveKinds(st *state.State, newExclusiveChangeKind,
 				ChangeKind: "create-recovery-system",
 				ChangeID:   chg.ID(),
 			}
+		case "remove-recovery-system":
+			if ignoreChangeID != "" && chg.ID() == ignoreChangeID {
+				continue
+			}
+			return &ChangeConflictError{
+				Message:    "removing recovery system in progress, no other changes allowed until this is done",
+				ChangeKind: "remove-recovery-system",
+				ChangeID:   chg.ID(),
+			}
 		default:
 			if newExclusiveChangeKind != "" {
 				// we want to run a new exclusive change, but other
@@ -471,3 +471,23 @@ func MockCgroupMonitorSnapEnded(f func(string, chan<- string) error) func() {
 		cgroupMonitorSnapEnded = old
 	}
 }
+
+func MockMaxHistoryEntries(n int) (restore func()) {
+	old := maxHistoryEntries
+	maxHistoryEntries = n
+	return func() { maxHistoryEntries = old }
+}
+
+func MockRemovedSnapHistoryRetention(d time.Duration) (restore func()) {
+	old := removedSnapHistoryRetention
+	removedSnapHistoryRetention = d
+	return func() { removedSnapHistoryRetention = old }
+}
+
+var AddHistory = addHistory
+
+func MockStoreApplyLocalDelta(f func(name, sourcePath, deltaPath, targetPath string) error) (restore func()) {
+	r := testutil.Backup(&storeApplyLocalDelta)
+	storeApplyLocalDelta = f
+	return r
+}
@@ -2067,8 +2067,8 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 
 	// Record the fact that the snap was refreshed successfully.
 	snapst.RefreshInhibitedTime = nil
+	now := timeNow()
 	if !snapsup.Revert {
-		now := timeNow()
 		snapst.LastRefreshTime = &now
 	}
 
@@ -2123,6 +2123,10 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 	// Do at the end so we only preserve the new state if it worked.
 	Set(st, snapsup.InstanceName(), snapst)
 
+	if err := addLinkHistory(t, snapsup, oldCurrent, snapst.TrackingChannel, now, HistoryOutcomeDone); err != nil {
+		return err
+	}
+
 	// Notify link snap participants about link changes.
 	notifyLinkParticipants(t, snapsup.InstanceName())
 
@@ -2536,6 +2540,10 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	// mark as inactive
 	Set(st, snapsup.InstanceName(), snapst)
 
+	if err := addLinkHistory(t, snapsup, oldCurrent, oldChannel, timeNow(), HistoryOutcomeUndone); err != nil {
+		return err
+	}
+
 	// Notify link snap participants about link changes.
 	notifyLinkParticipants(t, snapsup.InstanceName())
 
@@ -3203,6 +3211,11 @@ func (m *SnapManager) doDiscardSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err = SecurityProfilesRemoveLate(snapsup.InstanceName(), snapsup.Revision(), snapsup.Type); err != nil {
 		return err
 	}
+	if len(snapst.Sequence) == 0 {
+		if err := addRemoveHistory(t, snapsup); err != nil {
+			return err
+		}
+	}
 	Set(st, snapsup.InstanceName(), snapst)
 	return nil
 }
@@ -0,0 +1,221 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// HistoryAction is the kind of operation recorded in a snap's history.
+type HistoryAction string
+
+const (
+	HistoryInstall HistoryAction = "install"
+	HistoryRefresh HistoryAction = "refresh"
+	HistoryRevert  HistoryAction = "revert"
+	HistoryRemove  HistoryAction = "remove"
+	HistoryHold    HistoryAction = "hold"
+	HistoryUnhold  HistoryAction = "unhold"
+)
+
+// HistoryTrigger describes what initiated an operation recorded in a
+// snap's history.
+type HistoryTrigger string
+
+const (
+	HistoryTriggerManual        HistoryTrigger = "manual"
+	HistoryTriggerAuto          HistoryTrigger = "auto"
+	HistoryTriggerRemodel       HistoryTrigger = "remodel"
+	HistoryTriggerValidationSet HistoryTrigger = "validation-set"
+)
+
+// HistoryOutcome is the result of an operation recorded in a snap's
+// history.
+type HistoryOutcome string
+
+const (
+	HistoryOutcomeDone   HistoryOutcome = "done"
+	HistoryOutcomeUndone HistoryOutcome = "undone"
+)
+
+// HistoryEntry records a single install, refresh, revert, removal, hold
+// or unhold of a snap.
+type HistoryEntry struct {
+	Time         time.Time      `json:"time"`
+	Action       HistoryAction  `json:"action"`
+	FromRevision snap.Revision  `json:"from-revision"`
+	ToRevision   snap.Revision  `json:"to-revision"`
+	Channel      string         `json:"channel,omitempty"`
+	Trigger      HistoryTrigger `json:"trigger"`
+	ChangeID     string         `json:"change-id,omitempty"`
+	Outcome      HistoryOutcome `json:"outcome"`
+	// HeldBy and HoldUntil are only set for holds.
+	HeldBy    string     `json:"held-by,omitempty"`
+	HoldUntil *time.Time `json:"hold-until,omitempty"`
+}
+
+// maxHistoryEntries is the number of history entries kept for each snap,
+// older entries are dropped first.
+var maxHistoryEntries = 100
+
+// removedSnapHistoryRetention is for how long the history of a snap is kept
+// after its last entry, typically its removal, once the snap is not
+// installed anymore.
+var removedSnapHistoryRetention = 90 * 24 * time.Hour
+
+func snapsHistory(st *state.State) (map[string][]*HistoryEntry, error) {
+	var history map[string][]*HistoryEntry
+	err := st.Get("snaps-history", &history)
+	if err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, fmt.Errorf("internal error: cannot get snaps-history: %v", err)
+	}
+	if history == nil {
+		history = make(map[string][]*HistoryEntry)
+	}
+	return history, nil
+}
+
+// addHistory appends an entry to the history of the given snap, dropping
+// the oldest entries beyond the retention limit. The history is kept
+// after the snap is removed, so that it is still there if the snap is
+// installed again, until removedSnapHistoryRetention has passed.
+func addHistory(st *state.State, instanceName string, entry *HistoryEntry) error {
+	history, err := snapsHistory(st)
+	if err != nil {
+		return err
+	}
+	now := timeNow()
+	if entry.Time.IsZero() {
+		entry.Time = now
+	}
+
+	entries := append(history[instanceName], entry)
+	if len(entries) > maxHistoryEntries {
+		entries = entries[len(entries)-maxHistoryEntries:]
+	}
+	history[instanceName] = entries
+
+	if err := pruneRemovedSnapsHistory(st, history, instanceName, now); err != nil {
+		return err
+	}
+
+	st.Set("snaps-history", history)
+	return nil
+}
+
+// pruneRemovedSnapsHistory drops the history of snaps, other than the one
+// being updated, which are not installed anymore and have no entries more
+// recent than removedSnapHistoryRetention.
+func pruneRemovedSnapsHistory(st *state.State, history map[string][]*HistoryEntry, updated string, now time.Time) error {
+	for name, entries := range history {
+		if name == updated {
+			continue
+		}
+		if len(entries) > 0 && now.Sub(entries[len(entries)-1].Time) < removedSnapHistoryRetention {
+			continue
+		}
+		var snapst SnapState
+		err := Get(st, name, &snapst)
+		if err != nil && !errors.Is(err, state.ErrNoState) {
+			return err
+		}
+		if err == nil && snapst.IsInstalled() {
+			continue
+		}
+		delete(history, name)
+	}
+	return nil
+}
+
+// History returns the recorded history of the given snap, oldest first. The
+// history of a removed snap is available until removedSnapHistoryRetention
+// has passed since it was last updated.
+func History(st *state.State, instanceName string) ([]*HistoryEntry, error) {
+	history, err := snapsHistory(st)
+	if err != nil {
+		return nil, err
+	}
+	return history[instanceName], nil
+}
+
+// historyTrigger returns what initiated the change of the given task.
+func historyTrigger(t *state.Task, snapsup *SnapSetup) HistoryTrigger {
+	if snapsup.IsAutoRefresh {
+		return HistoryTriggerAuto
+	}
+	chg := t.Change()
+	if chg == nil {
+		return HistoryTriggerManual
+	}
+	if chg.Kind() == "remodel" {
+		return HistoryTriggerRemodel
+	}
+	for _, other := range chg.Tasks() {
+		if other.Kind() == "enforce-validation-sets" {
+			return HistoryTriggerValidationSet
+		}
+	}
+	return HistoryTriggerManual
+}
+
+// addLinkHistory records the link of a snap revision performed by the
+// given link-snap task at the given time.
+func addLinkHistory(t *state.Task, snapsup *SnapSetup, oldCurrent snap.Revision, channel string, when time.Time, outcome HistoryOutcome) error {
+	action := HistoryRefresh
+	switch {
+	case snapsup.Revert:
+		action = HistoryRevert
+	case oldCurrent.Unset():
+		action = HistoryInstall
+	}
+	entry := &HistoryEntry{
+		Time:         when,
+		Action:       action,
+		FromRevision: oldCurrent,
+		ToRevision:   snapsup.Revision(),
+		Channel:      channel,
+		Trigger:      historyTrigger(t, snapsup),
+		Outcome:      outcome,
+	}
+	if chg := t.Change(); chg != nil {
+		entry.ChangeID = chg.ID()
+	}
+	return addHistory(t.State(), snapsup.InstanceName(), entry)
+}
+
+// addRemoveHistory records the removal of the last revision of a snap
+// performed by the given discard-snap task.
+func addRemoveHistory(t *state.Task, snapsup *SnapSetup) error {
+	entry := &HistoryEntry{
+		Action:       HistoryRemove,
+		FromRevision: snapsup.Revision(),
+		Trigger:      historyTrigger(t, snapsup),
+		Outcome:      HistoryOutcomeDone,
+	}
+	if chg := t.Change(); chg != nil {
+		entry.ChangeID = chg.ID()
+	}
+	return addHistory(t.State(), snapsup.InstanceName(), entry)
+}
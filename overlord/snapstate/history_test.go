@@ -0,0 +1,311 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *linkSnapSuite) TestDoLinkSnapRecordsHistory(c *C) {
+	s.state.Lock()
+	si1 := &snap.SideInfo{RealName: "foo", Revision: snap.R(1), SnapID: "foo-id"}
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Sequence:        []*snap.SideInfo{si1},
+		Current:         si1.Revision,
+		TrackingChannel: "latest/stable",
+	})
+	t := s.state.NewTask("link-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "foo", Revision: snap.R(2), SnapID: "foo-id"},
+		Channel:  "beta",
+		Flags:    snapstate.Flags{IsAutoRefresh: true},
+	})
+	chg := s.state.NewChange("auto-refresh", "...")
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(t.Status(), Equals, state.DoneStatus)
+
+	history, err := snapstate.History(s.state, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	// the entry is recorded at the last refresh time
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "foo", &snapst), IsNil)
+	c.Assert(snapst.LastRefreshTime, NotNil)
+	c.Check(history[0].Time.Equal(*snapst.LastRefreshTime), Equals, true)
+	history[0].Time = time.Time{}
+	c.Check(history[0], DeepEquals, &snapstate.HistoryEntry{
+		Action:       snapstate.HistoryRefresh,
+		FromRevision: snap.R(1),
+		ToRevision:   snap.R(2),
+		Channel:      "latest/beta",
+		Trigger:      snapstate.HistoryTriggerAuto,
+		ChangeID:     chg.ID(),
+		Outcome:      snapstate.HistoryOutcomeDone,
+	})
+}
+
+func (s *linkSnapSuite) TestDoUndoLinkSnapRecordsHistory(c *C) {
+	s.state.Lock()
+	si1 := &snap.SideInfo{RealName: "foo", Revision: snap.R(1)}
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Sequence:        []*snap.SideInfo{si1},
+		Current:         si1.Revision,
+		TrackingChannel: "latest/stable",
+	})
+	t := s.state.NewTask("link-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "foo", Revision: snap.R(2)},
+	})
+	chg := s.state.NewChange("remodel", "...")
+	chg.AddTask(t)
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(t)
+	chg.AddTask(terr)
+	s.state.Unlock()
+
+	for i := 0; i < 6; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(t.Status(), Equals, state.UndoneStatus)
+
+	history, err := snapstate.History(s.state, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	for _, entry := range history {
+		c.Check(entry.Action, Equals, snapstate.HistoryRefresh)
+		c.Check(entry.Trigger, Equals, snapstate.HistoryTriggerRemodel)
+		c.Check(entry.FromRevision, Equals, snap.R(1))
+		c.Check(entry.ToRevision, Equals, snap.R(2))
+		c.Check(entry.ChangeID, Equals, chg.ID())
+	}
+	c.Check(history[0].Outcome, Equals, snapstate.HistoryOutcomeDone)
+	c.Check(history[1].Outcome, Equals, snapstate.HistoryOutcomeUndone)
+	c.Check(history[1].Channel, Equals, "latest/stable")
+}
+
+func (s *snapmgrTestSuite) TestHoldAndUnholdRecordHistory(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+
+	err := snapstate.HoldRefreshesBySystem(s.state, snapstate.HoldGeneral, "forever", []string{"some-snap"})
+	c.Assert(err, IsNil)
+	err = snapstate.ProceedWithRefresh(s.state, "system", []string{"some-snap"})
+	c.Assert(err, IsNil)
+
+	history, err := snapstate.History(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	c.Check(history[0].Action, Equals, snapstate.HistoryHold)
+	c.Check(history[0].Trigger, Equals, snapstate.HistoryTriggerManual)
+	c.Check(history[0].HeldBy, Equals, "system")
+	c.Assert(history[0].HoldUntil, NotNil)
+	c.Check(history[0].HoldUntil.After(time.Now().Add(24*time.Hour)), Equals, true)
+	c.Check(history[1].Action, Equals, snapstate.HistoryUnhold)
+	c.Check(history[1].Trigger, Equals, snapstate.HistoryTriggerManual)
+	c.Check(history[1].HoldUntil, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestHistoryRetention(c *C) {
+	defer snapstate.MockMaxHistoryEntries(3)()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+
+	// history of snaps which are not installed anymore is kept
+	err := snapstate.AddHistory(s.state, "gone-snap", &snapstate.HistoryEntry{Action: snapstate.HistoryInstall})
+	c.Assert(err, IsNil)
+
+	for i := 1; i <= 5; i++ {
+		err := snapstate.AddHistory(s.state, "some-snap", &snapstate.HistoryEntry{
+			Action:     snapstate.HistoryRefresh,
+			ToRevision: snap.R(i),
+		})
+		c.Assert(err, IsNil)
+	}
+
+	history, err := snapstate.History(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 3)
+	c.Check(history[0].ToRevision, Equals, snap.R(3))
+	c.Check(history[2].ToRevision, Equals, snap.R(5))
+
+	history, err = snapstate.History(s.state, "gone-snap")
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 1)
+}
+
+func (s *snapmgrTestSuite) TestHistoryOfRemovedSnapsPruned(c *C) {
+	defer snapstate.MockRemovedSnapHistoryRetention(24 * time.Hour)()
+	now := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+
+	for _, name := range []string{"some-snap", "gone-snap", "recently-gone-snap"} {
+		err := snapstate.AddHistory(s.state, name, &snapstate.HistoryEntry{
+			Time:   now.Add(-48 * time.Hour),
+			Action: snapstate.HistoryInstall,
+		})
+		c.Assert(err, IsNil)
+	}
+	err := snapstate.AddHistory(s.state, "recently-gone-snap", &snapstate.HistoryEntry{
+		Time:   now.Add(-time.Hour),
+		Action: snapstate.HistoryRemove,
+	})
+	c.Assert(err, IsNil)
+
+	// the history of installed snaps is kept regardless of its age, the
+	// history of removed snaps only until the retention has passed
+	for name, expected := range map[string]int{
+		"some-snap":          1,
+		"gone-snap":          0,
+		"recently-gone-snap": 2,
+	} {
+		history, err := snapstate.History(s.state, name)
+		c.Assert(err, IsNil)
+		c.Check(history, HasLen, expected, Commentf("snap %s", name))
+	}
+}
+
+func (s *snapmgrTestSuite) TestHistoryUsesTimeNow(c *C) {
+	now := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	err := snapstate.AddHistory(s.state, "some-snap", &snapstate.HistoryEntry{Action: snapstate.HistoryInstall})
+	c.Assert(err, IsNil)
+
+	history, err := snapstate.History(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Time.Equal(now), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestHoldByGatingSnapRecordsOnlyChanges(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, name := range []string{"some-snap", "gating-snap"} {
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active:   true,
+			Sequence: []*snap.SideInfo{{RealName: name, SnapID: name + "-id", Revision: snap.R(1)}},
+			Current:  snap.R(1),
+			SnapType: "app",
+		})
+	}
+	mockLastRefreshed(c, s.state, time.Now().UTC().Format(time.RFC3339), "some-snap")
+
+	// the hold is renewed on every auto-refresh attempt
+	for i := 0; i < 3; i++ {
+		_, err := snapstate.HoldRefresh(s.state, snapstate.HoldAutoRefresh, "gating-snap", time.Hour, "some-snap")
+		c.Assert(err, IsNil)
+	}
+	_, err := snapstate.HoldRefresh(s.state, snapstate.HoldGeneral, "gating-snap", time.Hour, "some-snap")
+	c.Assert(err, IsNil)
+
+	history, err := snapstate.History(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	c.Check(history[0].Action, Equals, snapstate.HistoryHold)
+	c.Check(history[0].HeldBy, Equals, "gating-snap")
+	c.Check(history[0].Trigger, Equals, snapstate.HistoryTriggerAuto)
+	c.Check(history[1].Action, Equals, snapstate.HistoryHold)
+}
+
+func (s *discardSnapSuite) TestDoDiscardSnapToEmptyRecordsHistory(c *C) {
+	s.state.Lock()
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(3)},
+		},
+		Current:  snap.R(3),
+		SnapType: "app",
+	})
+	t := s.state.NewTask("discard-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			Revision: snap.R(3),
+		},
+	})
+	chg := s.state.NewChange("remove-snap", "...")
+	chg.AddTask(t)
+
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(t.Status(), Equals, state.DoneStatus)
+
+	// the history outlives the snap
+	history, err := snapstate.History(s.state, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Action, Equals, snapstate.HistoryRemove)
+	c.Check(history[0].FromRevision, Equals, snap.R(3))
+	c.Check(history[0].Trigger, Equals, snapstate.HistoryTriggerManual)
+	c.Check(history[0].ChangeID, Equals, chg.ID())
+}
@@ -0,0 +1,62 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+)
+
+var storeApplyLocalDelta = store.ApplyLocalDelta
+
+// ApplyLocalDelta generates the snap at targetPath by applying the locally
+// provided xdelta3 delta at deltaPath to the given installed revision of
+// the snap. The source revision is linked (or copied) next to the target
+// first, so that removing the revision meanwhile does not affect the
+// delta. The state must be locked by the caller, it is unlocked while the
+// delta is applied.
+func ApplyLocalDelta(st *state.State, instanceName string, fromRev snap.Revision, deltaPath, targetPath string) error {
+	var snapst SnapState
+	if err := Get(st, instanceName, &snapst); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	if snapst.LastIndex(fromRev) < 0 {
+		return fmt.Errorf("revision %s of snap %q is not installed", fromRev, instanceName)
+	}
+
+	sourcePath := snap.MinimalPlaceInfo(instanceName, fromRev).MountFile()
+	sourceCopy := targetPath + ".source"
+	if err := os.Link(sourcePath, sourceCopy); err != nil {
+		if err := osutil.CopyFile(sourcePath, sourceCopy, osutil.CopyFlagDefault); err != nil {
+			return fmt.Errorf("cannot copy revision %s of snap %q: %v", fromRev, instanceName, err)
+		}
+	}
+	defer os.Remove(sourceCopy)
+
+	st.Unlock()
+	defer st.Lock()
+	return storeApplyLocalDelta(instanceName, sourceCopy, deltaPath, targetPath)
+}
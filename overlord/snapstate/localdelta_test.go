@@ -0,0 +1,83 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *snapmgrTestSuite) TestApplyLocalDelta(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "foo", Revision: snap.R("x1")}},
+		Current:  snap.R("x1"),
+	})
+	sourcePath := filepath.Join(dirs.SnapBlobDir, "foo_x1.snap")
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(sourcePath, []byte("source"), 0644), IsNil)
+	targetPath := filepath.Join(dirs.SnapBlobDir, "upload.snap")
+
+	restore := snapstate.MockStoreApplyLocalDelta(func(name, source, deltaPath, target string) error {
+		c.Check(name, Equals, "foo")
+		c.Check(deltaPath, Equals, "the.delta")
+		c.Check(target, Equals, targetPath)
+		// the source revision is protected from removal meanwhile
+		c.Check(source, Equals, targetPath+".source")
+		c.Assert(os.Remove(sourcePath), IsNil)
+		c.Check(source, testutil.FileEquals, "source")
+
+		// the state is unlocked while applying the delta
+		s.state.Lock()
+		s.state.Unlock()
+		return ioutil.WriteFile(target, []byte("target"), 0600)
+	})
+	defer restore()
+
+	err := snapstate.ApplyLocalDelta(s.state, "foo", snap.R("x1"), "the.delta", targetPath)
+	c.Assert(err, IsNil)
+	c.Check(targetPath, testutil.FileEquals, "target")
+	c.Check(targetPath+".source", testutil.FileAbsent)
+}
+
+func (s *snapmgrTestSuite) TestApplyLocalDeltaNotInstalled(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	restore := snapstate.MockStoreApplyLocalDelta(func(name, source, deltaPath, target string) error {
+		c.Fatal("unexpected call")
+		return nil
+	})
+	defer restore()
+
+	err := snapstate.ApplyLocalDelta(s.state, "foo", snap.R("x1"), "the.delta", "target.snap")
+	c.Assert(err, ErrorMatches, `revision x1 of snap "foo" is not installed`)
+}
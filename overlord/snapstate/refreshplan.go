@@ -0,0 +1,215 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// RefreshPlan describes what a refresh of many snaps would do, as
+// computed by UpdateManyPlan without creating any tasks.
+type RefreshPlan struct {
+	// Refresh holds the snaps that would be refreshed.
+	Refresh []*RefreshPlanSnap `json:"refresh,omitempty"`
+	// Held holds the snaps with an available update that is held back
+	// by the user or by gating snaps.
+	Held []*RefreshPlanHeldSnap `json:"held,omitempty"`
+	// Blocked holds the snaps with an available update that would not be
+	// refreshed because of validation sets constraints.
+	Blocked []*RefreshPlanBlockedSnap `json:"blocked,omitempty"`
+	// Prerequisites holds the bases and default content providers that
+	// are not installed and would be installed as part of the refresh.
+	Prerequisites []string `json:"prerequisites,omitempty"`
+	// RequiredSpace is the disk space, including the safety margin,
+	// needed to perform the refresh.
+	RequiredSpace uint64 `json:"required-space"`
+	// InsufficientSpace is set if the available disk space is below
+	// RequiredSpace.
+	InsufficientSpace bool `json:"insufficient-space,omitempty"`
+	// RebootRequired is set if refreshing any of the snaps requires a
+	// reboot of the system.
+	RebootRequired bool `json:"reboot-required,omitempty"`
+}
+
+// RefreshPlanSnap describes a single snap that would be refreshed.
+type RefreshPlanSnap struct {
+	InstanceName    string        `json:"name"`
+	Type            snap.Type     `json:"type"`
+	Version         string        `json:"version"`
+	CurrentRevision snap.Revision `json:"current-revision"`
+	Revision        snap.Revision `json:"revision"`
+	Channel         string        `json:"channel,omitempty"`
+	DownloadSize    int64         `json:"download-size"`
+	Prerequisites   []string      `json:"prerequisites,omitempty"`
+	RebootRequired  bool          `json:"reboot-required,omitempty"`
+}
+
+// RefreshPlanHeldSnap describes a snap whose update is held back.
+type RefreshPlanHeldSnap struct {
+	InstanceName string        `json:"name"`
+	Revision     snap.Revision `json:"revision"`
+	// HeldBy maps the holding snaps (or "system" for holds by the
+	// user) to the time until which they hold the refresh.
+	HeldBy map[string]time.Time `json:"held-by"`
+}
+
+// RefreshPlanBlockedSnap describes a snap whose update is not possible
+// because of validation sets constraints.
+type RefreshPlanBlockedSnap struct {
+	InstanceName string        `json:"name"`
+	Revision     snap.Revision `json:"revision"`
+	Reason       string        `json:"reason"`
+}
+
+// UpdateManyPlan computes what UpdateMany would do for the given list
+// of names (or for everything if the list is empty), accounting for
+// prerequisites, validation sets, refresh holds, disk space and
+// required reboots, without creating any tasks.
+// Note that the state must be locked by the caller.
+func UpdateManyPlan(ctx context.Context, st *state.State, names []string, userID int, flags *Flags) (*RefreshPlan, error) {
+	if flags == nil {
+		flags = &Flags{}
+	}
+	cands, err := validatedRefreshCandidates(ctx, st, names, nil, userID, nil, flags)
+	if err != nil {
+		return nil, err
+	}
+	names = cands.names
+	updates := cands.updates
+	stateByInstanceName := cands.stateByInstanceName
+	deviceCtx := cands.deviceCtx
+
+	plan := &RefreshPlan{}
+	for _, up := range cands.blocked {
+		plan.Blocked = append(plan.Blocked, &RefreshPlanBlockedSnap{
+			InstanceName: up.InstanceName(),
+			Revision:     up.Revision,
+			Reason:       cands.blockedErr.Error(),
+		})
+	}
+
+	// held snaps are only left out of a general refresh
+	var heldSnaps map[string]bool
+	if len(names) == 0 {
+		holdLevel := HoldGeneral
+		if flags.IsAutoRefresh {
+			holdLevel = HoldAutoRefresh
+		}
+		heldSnaps, err = HeldSnaps(st, holdLevel)
+		if err != nil {
+			return nil, err
+		}
+	}
+	gating, err := refreshGating(st)
+	if err != nil {
+		return nil, err
+	}
+
+	// prerequisites are only reported if they are not installed
+	installed, err := All(st)
+	if err != nil {
+		return nil, err
+	}
+
+	toUpdate := make([]minimalInstallInfo, 0, len(updates))
+	now := timeNow()
+	for _, up := range updates {
+		snapst := stateByInstanceName[up.InstanceName()]
+		if heldSnaps[up.InstanceName()] {
+			heldBy := make(map[string]time.Time)
+			for holdingSnap, hold := range gating[up.InstanceName()] {
+				if hold.HoldUntil.After(now) {
+					heldBy[holdingSnap] = hold.HoldUntil
+				}
+			}
+			plan.Held = append(plan.Held, &RefreshPlanHeldSnap{
+				InstanceName: up.InstanceName(),
+				Revision:     up.Revision,
+				HeldBy:       heldBy,
+			})
+			continue
+		}
+
+		inst := installSnapInfo{up}
+		toUpdate = append(toUpdate, inst)
+
+		planSnap := &RefreshPlanSnap{
+			InstanceName:    up.InstanceName(),
+			Type:            up.Type(),
+			Version:         up.Version,
+			CurrentRevision: snapst.Current,
+			Revision:        up.Revision,
+			Channel:         snapst.TrackingChannel,
+			DownloadSize:    up.Size,
+		}
+		if up.Type() == snap.TypeApp {
+			base := up.Base
+			if base == "" {
+				base = defaultCoreSnapName
+			}
+			if base != "none" && installed[base] == nil {
+				planSnap.Prerequisites = append(planSnap.Prerequisites, base)
+			}
+			for _, prereq := range inst.Prereq(st) {
+				if installed[prereq] == nil {
+					planSnap.Prerequisites = append(planSnap.Prerequisites, prereq)
+				}
+			}
+			sort.Strings(planSnap.Prerequisites)
+			for _, prereq := range planSnap.Prerequisites {
+				if !strutil.ListContains(plan.Prerequisites, prereq) {
+					plan.Prerequisites = append(plan.Prerequisites, prereq)
+				}
+			}
+		}
+		if !boot.Participant(up, up.Type(), deviceCtx).IsTrivial() {
+			planSnap.RebootRequired = true
+			plan.RebootRequired = true
+		}
+		plan.Refresh = append(plan.Refresh, planSnap)
+	}
+	sort.Strings(plan.Prerequisites)
+
+	if len(toUpdate) != 0 {
+		totalSize, err := installSize(st, toUpdate, userID)
+		if err != nil {
+			return nil, err
+		}
+		plan.RequiredSpace = safetyMarginDiskSpace(totalSize)
+		path := dirs.SnapdStateDir(dirs.GlobalRootDir)
+		if err := osutilCheckFreeSpace(path, plan.RequiredSpace); err != nil {
+			if _, ok := err.(*osutil.NotEnoughDiskSpaceError); !ok {
+				return nil, err
+			}
+			plan.InsufficientSpace = true
+		}
+	}
+
+	return plan, nil
+}
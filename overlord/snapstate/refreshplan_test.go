@@ -0,0 +1,195 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) setupRefreshPlanSnaps(c *C) {
+	lastRefresh := time.Now().Add(-time.Hour)
+	for _, name := range []string{"some-snap", "some-other-snap"} {
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active: true,
+			Sequence: []*snap.SideInfo{
+				{RealName: name, SnapID: name + "-id", Revision: snap.R(1)},
+			},
+			Current:         snap.R(1),
+			SnapType:        "app",
+			TrackingChannel: "latest/stable",
+			LastRefreshTime: &lastRefresh,
+		})
+	}
+	s.fakeStore.refreshRevnos = map[string]snap.Revision{
+		"some-other-snap-id": snap.R(5),
+	}
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyPlan(c *C) {
+	var requiredSpace uint64
+	restore := snapstate.MockOsutilCheckFreeSpace(func(path string, sz uint64) error {
+		requiredSpace = sz
+		return nil
+	})
+	defer restore()
+	restore = snapstate.MockInstallSize(func(st *state.State, snaps []snapstate.MinimalInstallInfo, userID int) (uint64, error) {
+		c.Check(snaps, HasLen, 2)
+		return 123, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRefreshPlanSnaps(c)
+
+	plan, err := snapstate.UpdateManyPlan(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Refresh, HasLen, 2)
+	byName := make(map[string]*snapstate.RefreshPlanSnap)
+	for _, planSnap := range plan.Refresh {
+		byName[planSnap.InstanceName] = planSnap
+	}
+	c.Check(byName["some-snap"], DeepEquals, &snapstate.RefreshPlanSnap{
+		InstanceName:    "some-snap",
+		Type:            snap.TypeApp,
+		Version:         "some-snap",
+		CurrentRevision: snap.R(1),
+		Revision:        snap.R(11),
+		Channel:         "latest/stable",
+	})
+	c.Check(byName["some-other-snap"].Revision, Equals, snap.R(5))
+	c.Check(plan.Prerequisites, HasLen, 0)
+	c.Check(plan.Held, HasLen, 0)
+	c.Check(plan.Blocked, HasLen, 0)
+	c.Check(plan.RequiredSpace, Equals, snapstate.SafetyMarginDiskSpace(123))
+	c.Check(requiredSpace, Equals, plan.RequiredSpace)
+	c.Check(plan.InsufficientSpace, Equals, false)
+	c.Check(plan.RebootRequired, Equals, false)
+
+	// no tasks were created
+	c.Check(s.state.TaskCount(), Equals, 0)
+	c.Check(s.state.Changes(), HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyPlanHeld(c *C) {
+	var sizeOf []string
+	restore := snapstate.MockInstallSize(func(st *state.State, snaps []snapstate.MinimalInstallInfo, userID int) (uint64, error) {
+		sizeOf = nil
+		for _, sn := range snaps {
+			sizeOf = append(sizeOf, sn.InstanceName())
+		}
+		return 123, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRefreshPlanSnaps(c)
+
+	err := snapstate.HoldRefreshesBySystem(s.state, snapstate.HoldGeneral, "forever", []string{"some-snap"})
+	c.Assert(err, IsNil)
+
+	plan, err := snapstate.UpdateManyPlan(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Refresh, HasLen, 1)
+	c.Check(plan.Refresh[0].InstanceName, Equals, "some-other-snap")
+	c.Assert(plan.Held, HasLen, 1)
+	c.Check(plan.Held[0].InstanceName, Equals, "some-snap")
+	c.Check(plan.Held[0].Revision, Equals, snap.R(11))
+	c.Check(plan.Held[0].HeldBy, HasLen, 1)
+	c.Check(plan.Held[0].HeldBy["system"].After(time.Now()), Equals, true)
+	c.Check(sizeOf, DeepEquals, []string{"some-other-snap"})
+
+	// holds do not apply when snaps are named explicitly
+	plan, err = snapstate.UpdateManyPlan(context.Background(), s.state, []string{"some-snap"}, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(plan.Held, HasLen, 0)
+	c.Assert(plan.Refresh, HasLen, 1)
+	c.Check(plan.Refresh[0].InstanceName, Equals, "some-snap")
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyPlanInsufficientSpace(c *C) {
+	restore := snapstate.MockOsutilCheckFreeSpace(func(path string, sz uint64) error {
+		return &osutil.NotEnoughDiskSpaceError{}
+	})
+	defer restore()
+	restore = snapstate.MockInstallSize(func(st *state.State, snaps []snapstate.MinimalInstallInfo, userID int) (uint64, error) {
+		return 123, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRefreshPlanSnaps(c)
+
+	plan, err := snapstate.UpdateManyPlan(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(plan.Refresh, HasLen, 2)
+	c.Check(plan.InsufficientSpace, Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyPlanValidationBlocked(c *C) {
+	restore := snapstate.MockInstallSize(func(st *state.State, snaps []snapstate.MinimalInstallInfo, userID int) (uint64, error) {
+		return 123, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRefreshPlanSnaps(c)
+
+	snapstate.ValidateRefreshes = func(st *state.State, refreshes []*snap.Info, ignoreValidation map[string]bool, userID int, deviceCtx snapstate.DeviceContext) ([]*snap.Info, error) {
+		var validated []*snap.Info
+		for _, info := range refreshes {
+			if info.InstanceName() != "some-snap" {
+				validated = append(validated, info)
+			}
+		}
+		return validated, fmt.Errorf("some-snap is gated")
+	}
+	defer func() { snapstate.ValidateRefreshes = nil }()
+
+	plan, err := snapstate.UpdateManyPlan(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Refresh, HasLen, 1)
+	c.Check(plan.Refresh[0].InstanceName, Equals, "some-other-snap")
+	c.Check(plan.Blocked, DeepEquals, []*snapstate.RefreshPlanBlockedSnap{
+		{InstanceName: "some-snap", Revision: snap.R(11), Reason: "some-snap is gated"},
+	})
+
+	// like UpdateMany, validation failures for explicit snaps are an error
+	_, err = snapstate.UpdateManyPlan(context.Background(), s.state, []string{"some-snap", "some-other-snap"}, 0, nil)
+	c.Check(err, ErrorMatches, "some-snap is gated")
+	_, _, err = snapstate.UpdateMany(context.Background(), s.state, []string{"some-snap", "some-other-snap"}, nil, 0, nil)
+	c.Check(err, ErrorMatches, "some-snap is gated")
+}
@@ -1645,46 +1645,19 @@ func updateManyFiltered(ctx context.Context, st *state.State, names []string, re
 	if flags == nil {
 		flags = &Flags{}
 	}
-	user, err := userFromUserID(st, userID)
-	if err != nil {
-		return nil, nil, err
-	}
 
-	// need to have a model set before trying to talk the store
-	deviceCtx, err := DevicePastSeeding(st, nil)
+	cands, err := validatedRefreshCandidates(ctx, st, names, revOpts, userID, filter, flags)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	names = strutil.Deduplicate(names)
-
-	refreshOpts := &store.RefreshOptions{IsAutoRefresh: flags.IsAutoRefresh}
-	updates, stateByInstanceName, ignoreValidation, err := refreshCandidates(ctx, st, names, revOpts, user, refreshOpts)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	if filter != nil {
-		actual := updates[:0]
-		for _, update := range updates {
-			if filter(update, stateByInstanceName[update.InstanceName()]) {
-				actual = append(actual, update)
-			}
-		}
-		updates = actual
-	}
-
-	if ValidateRefreshes != nil && len(updates) != 0 {
-		updates, err = ValidateRefreshes(st, updates, ignoreValidation, userID, deviceCtx)
-		if err != nil {
-			// not doing "refresh all" report the error
-			if len(names) != 0 {
-				return nil, nil, err
-			}
-			// doing "refresh all", log the problems
-			logger.Noticef("cannot refresh some snaps: %v", err)
-		}
+	if cands.blockedErr != nil {
+		// doing "refresh all", log the problems
+		logger.Noticef("cannot refresh some snaps: %v", cands.blockedErr)
 	}
+	names = cands.names
+	updates := cands.updates
+	stateByInstanceName := cands.stateByInstanceName
+	deviceCtx := cands.deviceCtx
 
 	params := func(update *snap.Info) (*RevisionOptions, Flags, *SnapState) {
 		snapst := stateByInstanceName[update.InstanceName()]
@@ -1726,6 +1699,85 @@ func updateManyFiltered(ctx context.Context, st *state.State, names []string, re
 	return updated, updateTss, nil
 }
 
+// refreshCandidateSet holds the refresh candidates for a multi-snap
+// refresh after filtering and validation.
+type refreshCandidateSet struct {
+	names               []string
+	updates             []*snap.Info
+	stateByInstanceName map[string]*SnapState
+	deviceCtx           DeviceContext
+	// blocked holds the candidates left out of a general refresh because
+	// of validation sets constraints, blockedErr the reason.
+	blocked    []*snap.Info
+	blockedErr error
+}
+
+// validatedRefreshCandidates returns the refresh candidates for the given
+// names (or for everything if the list is empty), filtered and validated
+// against validation sets. When explicit snaps were requested a failed
+// validation is an error, otherwise the snaps failing validation are left
+// out and reported as blocked.
+func validatedRefreshCandidates(ctx context.Context, st *state.State, names []string, revOpts []*RevisionOptions, userID int, filter updateFilter, flags *Flags) (*refreshCandidateSet, error) {
+	user, err := userFromUserID(st, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// need to have a model set before trying to talk the store
+	deviceCtx, err := DevicePastSeeding(st, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	names = strutil.Deduplicate(names)
+
+	refreshOpts := &store.RefreshOptions{IsAutoRefresh: flags.IsAutoRefresh}
+	updates, stateByInstanceName, ignoreValidation, err := refreshCandidates(ctx, st, names, revOpts, user, refreshOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter != nil {
+		actual := updates[:0]
+		for _, update := range updates {
+			if filter(update, stateByInstanceName[update.InstanceName()]) {
+				actual = append(actual, update)
+			}
+		}
+		updates = actual
+	}
+
+	cands := &refreshCandidateSet{
+		names:               names,
+		stateByInstanceName: stateByInstanceName,
+		deviceCtx:           deviceCtx,
+	}
+
+	if ValidateRefreshes != nil && len(updates) != 0 {
+		validated, err := ValidateRefreshes(st, updates, ignoreValidation, userID, deviceCtx)
+		if err != nil {
+			// not doing "refresh all" report the error
+			if len(names) != 0 {
+				return nil, err
+			}
+			valid := make(map[string]bool, len(validated))
+			for _, up := range validated {
+				valid[up.InstanceName()] = true
+			}
+			for _, up := range updates {
+				if !valid[up.InstanceName()] {
+					cands.blocked = append(cands.blocked, up)
+				}
+			}
+			cands.blockedErr = err
+		}
+		updates = validated
+	}
+	cands.updates = updates
+
+	return cands, nil
+}
+
 // filterHeldSnaps filters held snaps from being updated in a general refresh.
 func filterHeldSnaps(st *state.State, updates []minimalInstallInfo, flags *Flags) ([]minimalInstallInfo, error) {
 	holdLevel := HoldGeneral
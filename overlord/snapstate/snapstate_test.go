@@ -6440,6 +6440,25 @@ func (s *snapmgrTestSuite) TestConflictCreateRecovery(c *C) {
 	c.Check(err, ErrorMatches, `creating recovery system in progress, no other changes allowed until this is done`)
 }
 
+func (s *snapmgrTestSuite) TestConflictRemoveRecovery(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("remove-recovery-system", "...")
+	chg.SetStatus(state.DoingStatus)
+
+	err := snapstate.CheckChangeConflictMany(s.state, []string{"a-snap"}, "")
+	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err, ErrorMatches, `removing recovery system in progress, no other changes allowed until this is done`)
+
+	// remodeling conflicts with a change that removes a recovery system
+	err = snapstate.CheckChangeConflictRunExclusively(s.state, "remodel")
+	c.Check(err, ErrorMatches, `removing recovery system in progress, no other changes allowed until this is done`)
+	c.Assert(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err.(*snapstate.ChangeConflictError).ChangeKind, Equals, "remove-recovery-system")
+	c.Check(err.(*snapstate.ChangeConflictError).ChangeID, Equals, chg.ID())
+}
+
 func (s *snapmgrTestSuite) TestConflictExclusive(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -32,6 +32,7 @@ import (
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/strutil"
 )
 
 // A Backend exposes device information and device identity
@@ -265,3 +266,56 @@ func (sc *storeContext) CloudInfo() (*auth.CloudInfo, error) {
 
 	return nil, nil
 }
+
+// StoreMirrorURL returns the URL of the store mirror to try before the
+// store if one is set with the store.mirror option.
+func (sc *storeContext) StoreMirrorURL() (*url.URL, error) {
+	sc.state.Lock()
+	defer sc.state.Unlock()
+
+	tr := config.NewTransaction(sc.state)
+	var mirror string
+	err := tr.Get("core", "store.mirror", &mirror)
+	if err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	if mirror == "" {
+		return nil, nil
+	}
+
+	return url.Parse(mirror)
+}
+
+// DownloadCacheLimits returns the limits of the download cache set with
+// the store.cache.max-size and store.cache.min-free options.
+func (sc *storeContext) DownloadCacheLimits() (maxSize, minFree uint64, err error) {
+	sc.state.Lock()
+	defer sc.state.Unlock()
+
+	tr := config.NewTransaction(sc.state)
+	maxSize, err = byteSizeOption(tr, "store.cache.max-size")
+	if err != nil {
+		return 0, 0, err
+	}
+	minFree, err = byteSizeOption(tr, "store.cache.min-free")
+	if err != nil {
+		return 0, 0, err
+	}
+	return maxSize, minFree, nil
+}
+
+func byteSizeOption(tr *config.Transaction, key string) (uint64, error) {
+	var value string
+	err := tr.Get("core", key, &value)
+	if err != nil && !config.IsNoOption(err) {
+		return 0, err
+	}
+	if value == "" {
+		return 0, nil
+	}
+	size, err := strutil.ParseByteSize(value)
+	if err != nil {
+		return 0, fmt.Errorf("cannot use %s: %v", key, err)
+	}
+	return uint64(size), nil
+}
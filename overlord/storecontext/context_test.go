@@ -245,6 +245,45 @@ func (s *storeCtxSuite) TestCloudInfo(c *C) {
 	c.Check(cloud, DeepEquals, cloudInfo)
 }
 
+func (s *storeCtxSuite) TestStoreMirrorURL(c *C) {
+	storeCtx := storecontext.New(s.state, &testBackend{nothing: true})
+
+	mirror, err := storeCtx.StoreMirrorURL()
+	c.Assert(err, IsNil)
+	c.Check(mirror, IsNil)
+
+	s.state.Lock()
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "store.mirror", "http://mirror.lan:8440")
+	tr.Commit()
+	s.state.Unlock()
+
+	mirror, err = storeCtx.StoreMirrorURL()
+	c.Assert(err, IsNil)
+	c.Check(mirror.String(), Equals, "http://mirror.lan:8440")
+}
+
+func (s *storeCtxSuite) TestDownloadCacheLimits(c *C) {
+	storeCtx := storecontext.New(s.state, &testBackend{nothing: true})
+
+	maxSize, minFree, err := storeCtx.DownloadCacheLimits()
+	c.Assert(err, IsNil)
+	c.Check(maxSize, Equals, uint64(0))
+	c.Check(minFree, Equals, uint64(0))
+
+	s.state.Lock()
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "store.cache.max-size", "2GB")
+	tr.Set("core", "store.cache.min-free", "500MB")
+	tr.Commit()
+	s.state.Unlock()
+
+	maxSize, minFree, err = storeCtx.DownloadCacheLimits()
+	c.Assert(err, IsNil)
+	c.Check(maxSize, Equals, uint64(2000*1000*1000))
+	c.Check(minFree, Equals, uint64(500*1000*1000))
+}
+
 const (
 	exModel = `type: model
 authority-id: my-brand
@@ -0,0 +1,210 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package audit
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/sandbox/apparmor"
+)
+
+// DenialKind is the kind of sandbox which denied an operation.
+type DenialKind string
+
+const (
+	// AppArmorDenial is an operation denied by the AppArmor policy.
+	AppArmorDenial DenialKind = "apparmor"
+	// SeccompDenial is a system call denied by the seccomp filter.
+	SeccompDenial DenialKind = "seccomp"
+)
+
+// Denial is an operation of a snap denied by the sandbox, as recorded in the
+// kernel audit log.
+type Denial struct {
+	Kind DenialKind `json:"kind"`
+	// Label is the AppArmor label of the denied process.
+	Label string `json:"label"`
+	Snap  string `json:"snap"`
+	App   string `json:"app,omitempty"`
+	Hook  string `json:"hook,omitempty"`
+
+	Pid  int    `json:"pid,omitempty"`
+	Comm string `json:"comm,omitempty"`
+	Exe  string `json:"exe,omitempty"`
+
+	// AppArmor specific fields.
+	Operation     string `json:"operation,omitempty"`
+	Class         string `json:"class,omitempty"`
+	Name          string `json:"name,omitempty"`
+	RequestedMask string `json:"requested-mask,omitempty"`
+	DeniedMask    string `json:"denied-mask,omitempty"`
+	Capability    string `json:"capability,omitempty"`
+	Family        string `json:"family,omitempty"`
+	SockType      string `json:"sock-type,omitempty"`
+	Bus           string `json:"bus,omitempty"`
+	Path          string `json:"path,omitempty"`
+	Interface     string `json:"interface,omitempty"`
+	Member        string `json:"member,omitempty"`
+	PeerLabel     string `json:"peer-label,omitempty"`
+
+	// Seccomp specific fields.
+	Syscall int    `json:"syscall,omitempty"`
+	Arch    string `json:"arch,omitempty"`
+}
+
+// auditArchitectures maps the AUDIT_ARCH_* values to the architecture names
+// used by snapd.
+var auditArchitectures = map[string]string{
+	"40000003": "i386",
+	"c000003e": "amd64",
+	"40000028": "armhf",
+	"c00000b7": "arm64",
+	"c0000015": "ppc64el",
+	"80000016": "s390x",
+	"c00000f3": "riscv64",
+}
+
+// hexEncodedFields are the fields for which the kernel uses a hex encoding
+// of the value when it contains spaces or other special characters.
+var hexEncodedFields = map[string]bool{
+	"name":    true,
+	"comm":    true,
+	"exe":     true,
+	"profile": true,
+	"label":   true,
+}
+
+// ParseRecord splits an audit record into its key=value fields. Quoted
+// values are unquoted, a msg='...' field carrying a user space record, as
+// generated by dbus-daemon, is expanded in place.
+func ParseRecord(record string) map[string]string {
+	fields := make(map[string]string)
+	parseRecordInto(record, fields)
+	return fields
+}
+
+func parseRecordInto(record string, fields map[string]string) {
+	for len(record) > 0 {
+		record = strings.TrimLeft(record, " \t\n")
+		if record == "" {
+			break
+		}
+		eq := strings.IndexAny(record, "= \t\n")
+		if eq == -1 || record[eq] != '=' {
+			// a token without a value, eg. the record type or the
+			// AppArmor mode following the subject
+			if eq == -1 {
+				break
+			}
+			record = record[eq:]
+			continue
+		}
+		key := record[:eq]
+		record = record[eq+1:]
+		var value string
+		quoted := false
+		if len(record) > 0 && (record[0] == '"' || record[0] == '\'') {
+			quote := record[0]
+			end := strings.IndexByte(record[1:], quote)
+			if end == -1 {
+				value, record = record[1:], ""
+			} else {
+				value, record = record[1:end+1], record[end+2:]
+			}
+			quoted = true
+		} else {
+			end := strings.IndexAny(record, " \t\n")
+			if end == -1 {
+				value, record = record, ""
+			} else {
+				value, record = record[:end], record[end:]
+			}
+		}
+		if key == "msg" && quoted {
+			parseRecordInto(value, fields)
+			continue
+		}
+		if !quoted && hexEncodedFields[key] && len(value)%2 == 0 {
+			if decoded, err := hex.DecodeString(value); err == nil {
+				value = string(decoded)
+			}
+		}
+		fields[key] = value
+	}
+}
+
+// ParseDenial parses a kernel audit record and returns the sandbox denial it
+// describes. It returns nil if the record does not describe a denial of an
+// operation of a snap.
+func ParseDenial(record string) *Denial {
+	fields := ParseRecord(record)
+
+	var d Denial
+	switch {
+	case fields["apparmor"] == "DENIED":
+		d.Kind = AppArmorDenial
+		d.Label = fields["profile"]
+		if d.Label == "" {
+			// user space mediation, eg. D-Bus, uses label instead
+			d.Label = fields["label"]
+		}
+		d.Operation = fields["operation"]
+		d.Class = fields["class"]
+		d.Name = fields["name"]
+		d.RequestedMask = fields["requested_mask"]
+		d.DeniedMask = fields["denied_mask"]
+		d.Capability = fields["capname"]
+		d.Family = fields["family"]
+		d.SockType = fields["sock_type"]
+		d.Bus = fields["bus"]
+		d.Path = fields["path"]
+		d.Interface = fields["interface"]
+		d.Member = fields["member"]
+		d.PeerLabel = fields["peer_label"]
+	case fields["syscall"] != "" && fields["subj"] != "":
+		syscall, err := strconv.Atoi(fields["syscall"])
+		if err != nil {
+			return nil
+		}
+		d.Kind = SeccompDenial
+		d.Label = fields["subj"]
+		d.Syscall = syscall
+		d.Arch = auditArchitectures[fields["arch"]]
+		if d.Arch == "" {
+			d.Arch = fields["arch"]
+		}
+	default:
+		return nil
+	}
+
+	snapName, app, hook, err := apparmor.DecodeLabel(d.Label)
+	if err != nil {
+		return nil
+	}
+	d.Snap, d.App, d.Hook = snapName, app, hook
+	d.Comm = fields["comm"]
+	d.Exe = fields["exe"]
+	if pid, err := strconv.Atoi(fields["pid"]); err == nil {
+		d.Pid = pid
+	}
+	return &d
+}
@@ -0,0 +1,154 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package audit_test
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/sandbox/audit"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type auditSuite struct{}
+
+var _ = Suite(&auditSuite{})
+
+func (s *auditSuite) TestParseRecord(c *C) {
+	fields := audit.ParseRecord(`audit: type=1400 audit(1676541234.123:456): apparmor="DENIED" operation="open" profile="snap.foo.bar" name="/etc/some file" pid=1234 comm="bar" requested_mask="r" denied_mask="r" fsuid=1000 ouid=0`)
+	c.Check(fields, DeepEquals, map[string]string{
+		"type":           "1400",
+		"apparmor":       "DENIED",
+		"operation":      "open",
+		"profile":        "snap.foo.bar",
+		"name":           "/etc/some file",
+		"pid":            "1234",
+		"comm":           "bar",
+		"requested_mask": "r",
+		"denied_mask":    "r",
+		"fsuid":          "1000",
+		"ouid":           "0",
+	})
+
+	// hex encoded values
+	fields = audit.ParseRecord(`apparmor="DENIED" operation="open" profile="snap.foo.bar" name=2F746D702F6120622063 comm="bar"`)
+	c.Check(fields["name"], Equals, "/tmp/a b c")
+
+	// user space records
+	fields = audit.ParseRecord(`USER_AVC pid=1 uid=103 auid=4294967295 msg='apparmor="DENIED" operation="dbus_method_call" bus="system" label="snap.foo.bar"'`)
+	c.Check(fields["apparmor"], Equals, "DENIED")
+	c.Check(fields["label"], Equals, "snap.foo.bar")
+	c.Check(fields["pid"], Equals, "1")
+	c.Check(fields["msg"], Equals, "")
+
+	// garbage in, something sensible out
+	c.Check(audit.ParseRecord(""), HasLen, 0)
+	c.Check(audit.ParseRecord("foo bar"), HasLen, 0)
+	c.Check(audit.ParseRecord(`foo="bar`), DeepEquals, map[string]string{"foo": "bar"})
+}
+
+func (s *auditSuite) TestParseDenialAppArmorFile(c *C) {
+	d := audit.ParseDenial(`audit: type=1400 audit(1676541234.123:456): apparmor="DENIED" operation="open" class="file" profile="snap.foo.bar" name="/dev/video0" pid=1234 comm="bar" requested_mask="wr" denied_mask="wr" fsuid=1000 ouid=0`)
+	c.Check(d, DeepEquals, &audit.Denial{
+		Kind:          audit.AppArmorDenial,
+		Label:         "snap.foo.bar",
+		Snap:          "foo",
+		App:           "bar",
+		Pid:           1234,
+		Comm:          "bar",
+		Operation:     "open",
+		Class:         "file",
+		Name:          "/dev/video0",
+		RequestedMask: "wr",
+		DeniedMask:    "wr",
+	})
+}
+
+func (s *auditSuite) TestParseDenialAppArmorCapability(c *C) {
+	d := audit.ParseDenial(`AVC apparmor="DENIED" operation="capable" profile="snap.foo.hook.configure" pid=99 comm="configure" capability=21 capname="sys_admin"`)
+	c.Check(d, DeepEquals, &audit.Denial{
+		Kind:       audit.AppArmorDenial,
+		Label:      "snap.foo.hook.configure",
+		Snap:       "foo",
+		Hook:       "configure",
+		Pid:        99,
+		Comm:       "configure",
+		Operation:  "capable",
+		Capability: "sys_admin",
+	})
+}
+
+func (s *auditSuite) TestParseDenialAppArmorDBus(c *C) {
+	d := audit.ParseDenial(`USER_AVC pid=683 uid=103 auid=4294967295 ses=4294967295 msg='apparmor="DENIED" operation="dbus_method_call" bus="system" path="/org/freedesktop/NetworkManager" interface="org.freedesktop.DBus.Properties" member="GetAll" mask="send" name="org.freedesktop.NetworkManager" pid=2345 label="snap.foo.bar" peer_pid=700 peer_label="unconfined" exe="/usr/bin/dbus-daemon" sauid=103 hostname=? addr=? terminal=?'`)
+	c.Check(d, DeepEquals, &audit.Denial{
+		Kind:      audit.AppArmorDenial,
+		Label:     "snap.foo.bar",
+		Snap:      "foo",
+		App:       "bar",
+		Pid:       2345,
+		Exe:       "/usr/bin/dbus-daemon",
+		Operation: "dbus_method_call",
+		Name:      "org.freedesktop.NetworkManager",
+		Bus:       "system",
+		Path:      "/org/freedesktop/NetworkManager",
+		Interface: "org.freedesktop.DBus.Properties",
+		Member:    "GetAll",
+		PeerLabel: "unconfined",
+	})
+}
+
+func (s *auditSuite) TestParseDenialSeccomp(c *C) {
+	d := audit.ParseDenial(`audit: type=1326 audit(1676541234.123:457): auid=1000 uid=1000 gid=1000 ses=3 subj=snap.foo.bar (enforce) pid=1234 comm="bar" exe="/snap/foo/x1/bin/bar" sig=0 arch=c000003e syscall=165 compat=0 ip=0x7f0000000000 code=0x50000`)
+	c.Check(d, DeepEquals, &audit.Denial{
+		Kind:    audit.SeccompDenial,
+		Label:   "snap.foo.bar",
+		Snap:    "foo",
+		App:     "bar",
+		Pid:     1234,
+		Comm:    "bar",
+		Exe:     "/snap/foo/x1/bin/bar",
+		Syscall: 165,
+		Arch:    "amd64",
+	})
+
+	// unknown architectures are reported as is
+	d = audit.ParseDenial(`SECCOMP subj=snap.foo.bar arch=deadbeef syscall=1`)
+	c.Assert(d, NotNil)
+	c.Check(d.Arch, Equals, "deadbeef")
+}
+
+func (s *auditSuite) TestParseDenialNotSnapDenials(c *C) {
+	for _, record := range []string{
+		``,
+		`random kernel message`,
+		// not denied
+		`apparmor="ALLOWED" operation="open" profile="snap.foo.bar" name="/etc/shadow"`,
+		`apparmor="STATUS" operation="profile_load" profile="unconfined" name="snap.foo.bar"`,
+		// not a snap
+		`apparmor="DENIED" operation="open" profile="/usr/sbin/cupsd" name="/etc/shadow"`,
+		`subj=unconfined arch=c000003e syscall=165`,
+		// bogus syscall
+		`subj=snap.foo.bar arch=c000003e syscall=foo`,
+	} {
+		c.Check(audit.ParseDenial(record), IsNil, Commentf("%q", record))
+	}
+}
@@ -0,0 +1,218 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package landlock offers support for confining processes with the landlock
+// LSM, see https://docs.kernel.org/userspace-api/landlock.html
+//
+// Rules are stored in a simple line based format, one rule per line:
+//
+//	<access> <path>
+//
+// where access is a combination of the letters r (read), w (write) and x
+// (execute) and path is an absolute path, optionally starting with a
+// variable such as $HOME or $SNAP_DATA, which is expanded at the time the
+// rules are applied. Each rule grants access to the path and everything
+// beneath it. Lines starting with # are comments and lines starting with @
+// are directives, the following directives are understood:
+//
+//	@unrestricted - the rules are not applied (classic confinement)
+//	@complain     - the rules are not applied (devmode)
+package landlock
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LevelType encodes the state of landlock support found on this system.
+type LevelType int
+
+const (
+	// Landlock is not supported
+	Unsupported LevelType = iota
+	// Landlock is supported and enabled
+	Supported
+)
+
+// Access is a set of rights on a part of the filesystem.
+type Access int
+
+const (
+	// AccessRead allows reading files and listing directories.
+	AccessRead Access = 1 << iota
+	// AccessWrite allows writing files as well as creating and removing
+	// directory entries.
+	AccessWrite
+	// AccessExecute allows executing files.
+	AccessExecute
+)
+
+func (a Access) String() string {
+	var sb strings.Builder
+	if a&AccessRead != 0 {
+		sb.WriteRune('r')
+	}
+	if a&AccessWrite != 0 {
+		sb.WriteRune('w')
+	}
+	if a&AccessExecute != 0 {
+		sb.WriteRune('x')
+	}
+	return sb.String()
+}
+
+// ParseAccess parses a combination of the r, w and x letters.
+func ParseAccess(s string) (Access, error) {
+	var access Access
+	for _, r := range s {
+		var bit Access
+		switch r {
+		case 'r':
+			bit = AccessRead
+		case 'w':
+			bit = AccessWrite
+		case 'x':
+			bit = AccessExecute
+		default:
+			return 0, fmt.Errorf("invalid access %q", s)
+		}
+		if access&bit != 0 {
+			return 0, fmt.Errorf("invalid access %q: repeated %q", s, r)
+		}
+		access |= bit
+	}
+	if access == 0 {
+		return 0, fmt.Errorf("access cannot be empty")
+	}
+	return access, nil
+}
+
+// Rule grants access to a path and everything beneath it.
+type Rule struct {
+	Access Access
+	Path   string
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%s %s", r.Access, r.Path)
+}
+
+// Ruleset is a parsed set of landlock rules.
+type Ruleset struct {
+	// Unrestricted is set when the rules should not be applied
+	// because the snap uses classic confinement.
+	Unrestricted bool
+	// Complain is set when the rules should not be applied because
+	// the snap is in devmode.
+	Complain bool
+	Rules    []Rule
+}
+
+// Enforced returns whether the rules of the ruleset should be applied.
+func (rs *Ruleset) Enforced() bool {
+	return !rs.Unrestricted && !rs.Complain
+}
+
+// ParseRuleset parses rules in the format described in the package
+// documentation.
+func ParseRuleset(r io.Reader) (*Ruleset, error) {
+	rs := &Ruleset{}
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case line == "@unrestricted":
+			rs.Unrestricted = true
+			continue
+		case line == "@complain":
+			rs.Complain = true
+			continue
+		case strings.HasPrefix(line, "@"):
+			return nil, fmt.Errorf("line %d: unknown directive %q", lineno, line)
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected access and path, got %q", lineno, line)
+		}
+		access, err := ParseAccess(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		path := fields[1]
+		if !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "$") {
+			return nil, fmt.Errorf("line %d: path %q must be absolute or start with a variable", lineno, path)
+		}
+		rs.Rules = append(rs.Rules, Rule{Access: access, Path: path})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+var landlockVersion = probeVersion
+
+// Version returns the landlock ABI version supported by the kernel.
+//
+// A version of 0 is returned, without an error, when the kernel does not
+// support landlock or it is disabled.
+func Version() (int, error) {
+	return landlockVersion()
+}
+
+// ProbedLevel tells whether landlock can be used on this system.
+func ProbedLevel() LevelType {
+	level, _ := probeLandlock()
+	return level
+}
+
+// Summary describes landlock status.
+func Summary() string {
+	_, summary := probeLandlock()
+	return summary
+}
+
+func probeLandlock() (LevelType, string) {
+	version, err := landlockVersion()
+	if err != nil {
+		return Unsupported, fmt.Sprintf("cannot probe landlock: %v", err)
+	}
+	if version == 0 {
+		return Unsupported, "landlock is not supported or not enabled"
+	}
+	return Supported, fmt.Sprintf("landlock is enabled with ABI version %d", version)
+}
+
+// MockVersion makes the system believe the kernel supports the given
+// landlock ABI version.
+func MockVersion(version int, err error) (restore func()) {
+	old := landlockVersion
+	landlockVersion = func() (int, error) {
+		return version, err
+	}
+	return func() {
+		landlockVersion = old
+	}
+}
@@ -0,0 +1,162 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	accessFsRead  = unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+	accessFsWrite = unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+	accessFsExecute = unix.LANDLOCK_ACCESS_FS_EXECUTE
+
+	// rights which apply to files, all the other ones only make sense
+	// for directories
+	accessFsFile = unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_EXECUTE
+
+	// all the rights known in ABI version 1, creation of block and
+	// character devices is handled but never granted
+	accessFsV1 = accessFsRead | accessFsWrite | accessFsExecute |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR
+)
+
+func probeVersion() (int, error) {
+	version, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	switch errno {
+	case 0:
+		return int(version), nil
+	case unix.ENOSYS, unix.EOPNOTSUPP:
+		return 0, nil
+	}
+	return 0, errno
+}
+
+// handledAccess returns the set of rights restricted by a ruleset for the
+// given ABI version.
+func handledAccess(version int) uint64 {
+	handled := uint64(accessFsV1)
+	if version >= 2 {
+		// linking and renaming across directories
+		handled |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	return handled
+}
+
+// ruleAccess returns the rights granted by a rule on a file or directory for
+// the given ABI version.
+func ruleAccess(access Access, isDir bool, version int) uint64 {
+	var allowed uint64
+	if access&AccessRead != 0 {
+		allowed |= accessFsRead
+	}
+	if access&AccessWrite != 0 {
+		allowed |= accessFsWrite
+		if version >= 2 {
+			allowed |= unix.LANDLOCK_ACCESS_FS_REFER
+		}
+	}
+	if access&AccessExecute != 0 {
+		allowed |= accessFsExecute
+	}
+	if !isDir {
+		allowed &= accessFsFile
+	}
+	return allowed
+}
+
+// RestrictSelf confines the calling process, and all its future children,
+// so that only the filesystem access granted by the given rules is
+// possible. Rules for paths which do not exist are ignored. The no_new_privs
+// flag is set on the process as required by landlock.
+func RestrictSelf(rules []Rule) error {
+	version, err := probeVersion()
+	if err != nil {
+		return fmt.Errorf("cannot probe landlock: %v", err)
+	}
+	if version == 0 {
+		return fmt.Errorf("landlock is not supported")
+	}
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handledAccess(version)}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("cannot create landlock ruleset: %v", errno)
+	}
+	rulesetFd := int(fd)
+	defer unix.Close(rulesetFd)
+
+	for _, rule := range rules {
+		if err := addPathRule(rulesetFd, rule, version); err != nil {
+			return err
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("cannot set no_new_privs: %v", err)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(rulesetFd), 0, 0); errno != 0 {
+		return fmt.Errorf("cannot apply landlock ruleset: %v", errno)
+	}
+	return nil
+}
+
+func addPathRule(rulesetFd int, rule Rule, version int) error {
+	pathFd, err := unix.Open(rule.Path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("cannot open %q for landlock rule: %v", rule.Path, err)
+	}
+	defer unix.Close(pathFd)
+
+	var st unix.Stat_t
+	if err := unix.Fstat(pathFd, &st); err != nil {
+		return fmt.Errorf("cannot stat %q for landlock rule: %v", rule.Path, err)
+	}
+	isDir := st.Mode&unix.S_IFMT == unix.S_IFDIR
+	attr := unix.LandlockPathBeneathAttr{
+		Allowed_access: ruleAccess(rule.Access, isDir, version),
+		Parent_fd:      int32(pathFd),
+	}
+	if attr.Allowed_access == 0 {
+		return nil
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFd), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("cannot add landlock rule %q: %v", rule, errno)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock_test
+
+import (
+	"golang.org/x/sys/unix"
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/sandbox/landlock"
+)
+
+func (s *landlockSuite) TestHandledAccess(c *C) {
+	c.Check(landlock.HandledAccess(1), Equals, uint64(0x1fff))
+	c.Check(landlock.HandledAccess(2), Equals, uint64(0x3fff))
+}
+
+func (s *landlockSuite) TestRuleAccess(c *C) {
+	rw := landlock.AccessRead | landlock.AccessWrite
+	c.Check(landlock.RuleAccess(landlock.AccessRead, true, 1), Equals,
+		uint64(unix.LANDLOCK_ACCESS_FS_READ_FILE|unix.LANDLOCK_ACCESS_FS_READ_DIR))
+	c.Check(landlock.RuleAccess(landlock.AccessRead, false, 1), Equals,
+		uint64(unix.LANDLOCK_ACCESS_FS_READ_FILE))
+	c.Check(landlock.RuleAccess(rw, false, 2), Equals,
+		uint64(unix.LANDLOCK_ACCESS_FS_READ_FILE|unix.LANDLOCK_ACCESS_FS_WRITE_FILE))
+	c.Check(landlock.RuleAccess(landlock.AccessExecute, false, 1), Equals,
+		uint64(unix.LANDLOCK_ACCESS_FS_EXECUTE))
+
+	// creating devices is never granted
+	c.Check(landlock.RuleAccess(rw|landlock.AccessExecute, true, 1)&unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK, Equals, uint64(0))
+	c.Check(landlock.RuleAccess(rw|landlock.AccessExecute, true, 1)&unix.LANDLOCK_ACCESS_FS_MAKE_CHAR, Equals, uint64(0))
+	// refer is only added with ABI version 2
+	c.Check(landlock.RuleAccess(rw, true, 1)&unix.LANDLOCK_ACCESS_FS_REFER, Equals, uint64(0))
+	c.Check(landlock.RuleAccess(rw, true, 2)&unix.LANDLOCK_ACCESS_FS_REFER, Equals, uint64(unix.LANDLOCK_ACCESS_FS_REFER))
+}
@@ -0,0 +1,141 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/sandbox/landlock"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type landlockSuite struct{}
+
+var _ = Suite(&landlockSuite{})
+
+func (s *landlockSuite) TestProbeUnsupported(c *C) {
+	restore := landlock.MockVersion(0, nil)
+	defer restore()
+
+	level, summary := landlock.ProbeLandlock()
+	c.Check(level, Equals, landlock.Unsupported)
+	c.Check(summary, Equals, "landlock is not supported or not enabled")
+	c.Check(landlock.ProbedLevel(), Equals, level)
+	c.Check(landlock.Summary(), Equals, summary)
+}
+
+func (s *landlockSuite) TestProbeSupported(c *C) {
+	restore := landlock.MockVersion(2, nil)
+	defer restore()
+
+	level, summary := landlock.ProbeLandlock()
+	c.Check(level, Equals, landlock.Supported)
+	c.Check(summary, Equals, "landlock is enabled with ABI version 2")
+	v, err := landlock.Version()
+	c.Check(err, IsNil)
+	c.Check(v, Equals, 2)
+}
+
+func (s *landlockSuite) TestProbeError(c *C) {
+	restore := landlock.MockVersion(0, errors.New("boom"))
+	defer restore()
+
+	level, summary := landlock.ProbeLandlock()
+	c.Check(level, Equals, landlock.Unsupported)
+	c.Check(summary, Equals, "cannot probe landlock: boom")
+}
+
+func (s *landlockSuite) TestAccess(c *C) {
+	for _, tc := range []struct {
+		in  string
+		out landlock.Access
+		err string
+	}{
+		{in: "r", out: landlock.AccessRead},
+		{in: "rw", out: landlock.AccessRead | landlock.AccessWrite},
+		{in: "xr", out: landlock.AccessRead | landlock.AccessExecute},
+		{in: "rwx", out: landlock.AccessRead | landlock.AccessWrite | landlock.AccessExecute},
+		{in: "", err: "access cannot be empty"},
+		{in: "rr", err: `invalid access "rr": repeated 'r'`},
+		{in: "rk", err: `invalid access "rk"`},
+	} {
+		access, err := landlock.ParseAccess(tc.in)
+		if tc.err != "" {
+			c.Check(err, ErrorMatches, tc.err, Commentf("%q", tc.in))
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("%q", tc.in))
+		c.Check(access, Equals, tc.out)
+	}
+	c.Check(landlock.Access(landlock.AccessExecute|landlock.AccessRead).String(), Equals, "rx")
+}
+
+func (s *landlockSuite) TestParseRuleset(c *C) {
+	rs, err := landlock.ParseRuleset(strings.NewReader(`
+# comment
+@complain
+rx /usr
+  rw   $SNAP_DATA
+r $HOME/.config/foo
+`))
+	c.Assert(err, IsNil)
+	c.Check(rs, DeepEquals, &landlock.Ruleset{
+		Complain: true,
+		Rules: []landlock.Rule{
+			{Access: landlock.AccessRead | landlock.AccessExecute, Path: "/usr"},
+			{Access: landlock.AccessRead | landlock.AccessWrite, Path: "$SNAP_DATA"},
+			{Access: landlock.AccessRead, Path: "$HOME/.config/foo"},
+		},
+	})
+	c.Check(rs.Enforced(), Equals, false)
+	c.Check(rs.Rules[0].String(), Equals, "rx /usr")
+
+	rs, err = landlock.ParseRuleset(strings.NewReader("@unrestricted\n"))
+	c.Assert(err, IsNil)
+	c.Check(rs.Unrestricted, Equals, true)
+	c.Check(rs.Enforced(), Equals, false)
+
+	rs, err = landlock.ParseRuleset(strings.NewReader("r /etc\n"))
+	c.Assert(err, IsNil)
+	c.Check(rs.Enforced(), Equals, true)
+}
+
+func (s *landlockSuite) TestParseRulesetErrors(c *C) {
+	for _, tc := range []struct {
+		in  string
+		err string
+	}{
+		{"@foo", `line 1: unknown directive "@foo"`},
+		{"\nr", `line 2: expected access and path, got "r"`},
+		{"r /foo bar", `line 1: expected access and path, got "r /foo bar"`},
+		{"q /foo", `line 1: invalid access "q"`},
+		{"r foo", `line 1: path "foo" must be absolute or start with a variable`},
+	} {
+		_, err := landlock.ParseRuleset(strings.NewReader(tc.in))
+		c.Check(err, ErrorMatches, tc.err, Commentf("%q", tc.in))
+	}
+}
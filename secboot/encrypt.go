@@ -44,3 +44,11 @@ type RecoveryKeyDevice struct {
 	// present in the user session keyring
 	AuthorizingKeyFile string
 }
+
+// RecoveryKeyInfo describes a recovery key of the encrypted devices.
+type RecoveryKeyInfo struct {
+	// Name of the recovery key
+	Name string
+	// Mountpoints of the devices the key can unlock
+	Mountpoints []string
+}
@@ -33,6 +33,18 @@ func RemoveRecoveryKeys(map[RecoveryKeyDevice]string) error {
 	return errBuildWithoutSecboot
 }
 
+func AddNamedRecoveryKey(string, string, []RecoveryKeyDevice) (keys.RecoveryKey, error) {
+	return keys.RecoveryKey{}, errBuildWithoutSecboot
+}
+
+func RemoveNamedRecoveryKey(string, []RecoveryKeyDevice) error {
+	return errBuildWithoutSecboot
+}
+
+func ListRecoveryKeys([]RecoveryKeyDevice) ([]RecoveryKeyInfo, error) {
+	return nil, errBuildWithoutSecboot
+}
+
 func StageEncryptionKeyChange(node string, key keys.EncryptionKey) error {
 	return errBuildWithoutSecboot
 }
@@ -26,7 +26,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
+	"sort"
 
 	sb "github.com/snapcore/secboot"
 
@@ -82,10 +84,29 @@ func AddRecoveryKey(key keys.EncryptionKey, rkey keys.RecoveryKey, node string)
 	return keymgr.AddRecoveryKeyToLUKSDeviceUsingKey(rkey, key, node)
 }
 
+// AddPassphrase adds a user passphrase that can be used to unlock the existing
+// encrypted volume created with FormatEncryptedDevice on the block device given
+// by node. The existing key to the encrypted volume is provided in the key
+// argument.
+func AddPassphrase(key keys.EncryptionKey, passphrase string, node string) error {
+	return keymgr.AddPassphraseToLUKSDeviceUsingKey(passphrase, key, node)
+}
+
 func runSnapFDEKeymgr(args []string, stdin io.Reader) error {
+	_, err := runSnapFDEKeymgrWithOptions(args, &systemd.RunOptions{Stdin: stdin})
+	return err
+}
+
+// runSnapFDEKeymgrWithOutput runs the keymgr tool and returns its standard
+// output.
+func runSnapFDEKeymgrWithOutput(args []string, stdin io.Reader) ([]byte, error) {
+	return runSnapFDEKeymgrWithOptions(args, &systemd.RunOptions{Stdin: stdin, StdoutOnly: true})
+}
+
+func runSnapFDEKeymgrWithOptions(args []string, opts *systemd.RunOptions) ([]byte, error) {
 	toolPath, err := snapdtool.InternalToolPath("snap-fde-keymgr")
 	if err != nil {
-		return fmt.Errorf("cannot find keymgr tool: %v", err)
+		return nil, fmt.Errorf("cannot find keymgr tool: %v", err)
 	}
 
 	sysd := systemd.New(systemd.SystemMode, nil)
@@ -94,11 +115,27 @@ func runSnapFDEKeymgr(args []string, stdin io.Reader) error {
 		toolPath,
 	}
 	command = append(command, args...)
-	_, err = sysd.Run(command, &systemd.RunOptions{
-		KeyringMode: systemd.KeyringModeInherit,
-		Stdin:       stdin,
-	})
-	return err
+	opts.KeyringMode = systemd.KeyringModeInherit
+	return sysd.Run(command, opts)
+}
+
+func recoveryKeyDevicesArgs(rkeyDevs []RecoveryKeyDevice) ([]string, error) {
+	var args []string
+	for _, rkeyDev := range rkeyDevs {
+		dev, err := devByPartUUIDFromMount(rkeyDev.Mountpoint)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find matching device for: %v", err)
+		}
+		authzMethod := "keyring"
+		if rkeyDev.AuthorizingKeyFile != "" {
+			authzMethod = "file:" + rkeyDev.AuthorizingKeyFile
+		}
+		args = append(args, []string{
+			"--devices", dev,
+			"--authorizations", authzMethod,
+		}...)
+	}
+	return args, nil
 }
 
 // EnsureRecoveryKey makes sure the encrypted block devices have a recovery key.
@@ -136,6 +173,106 @@ func EnsureRecoveryKey(keyFile string, rkeyDevs []RecoveryKeyDevice) (keys.Recov
 	return *rk, nil
 }
 
+// AddNamedRecoveryKey adds a new recovery key with the given name to the
+// encrypted block devices. The key is generated into keyFile, which is removed
+// once the key has been added, named recovery keys are not kept on disk.
+func AddNamedRecoveryKey(name, keyFile string, rkeyDevs []RecoveryKeyDevice) (keys.RecoveryKey, error) {
+	command := []string{
+		"add-recovery-key",
+		"--name", name,
+		"--key-file", keyFile,
+	}
+	devArgs, err := recoveryKeyDevicesArgs(rkeyDevs)
+	if err != nil {
+		return keys.RecoveryKey{}, err
+	}
+	command = append(command, devArgs...)
+
+	// the key file may have been written even if the tool failed later
+	defer os.Remove(keyFile)
+	if err := runSnapFDEKeymgr(command, nil); err != nil {
+		return keys.RecoveryKey{}, fmt.Errorf("cannot run keymgr tool: %v", err)
+	}
+
+	rk, err := keys.RecoveryKeyFromFile(keyFile)
+	if err != nil {
+		return keys.RecoveryKey{}, fmt.Errorf("cannot read recovery key: %v", err)
+	}
+	return *rk, nil
+}
+
+// RemoveNamedRecoveryKey removes the recovery key with the given name from
+// the encrypted block devices.
+func RemoveNamedRecoveryKey(name string, rkeyDevs []RecoveryKeyDevice) error {
+	command := []string{
+		"remove-recovery-key",
+		"--name", name,
+	}
+	devArgs, err := recoveryKeyDevicesArgs(rkeyDevs)
+	if err != nil {
+		return err
+	}
+	command = append(command, devArgs...)
+
+	if err := runSnapFDEKeymgr(command, nil); err != nil {
+		return fmt.Errorf("cannot run keymgr tool: %v", err)
+	}
+	return nil
+}
+
+// ListRecoveryKeys returns the recovery keys of the encrypted block devices,
+// sorted by name. Only the mount points of the devices are used.
+func ListRecoveryKeys(rkeyDevs []RecoveryKeyDevice) ([]RecoveryKeyInfo, error) {
+	command := []string{
+		"list-recovery-keys",
+	}
+	devToMountpoint := make(map[string]string, len(rkeyDevs))
+	for _, rkeyDev := range rkeyDevs {
+		dev, err := devByPartUUIDFromMount(rkeyDev.Mountpoint)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find matching device for: %v", err)
+		}
+		devToMountpoint[dev] = rkeyDev.Mountpoint
+		command = append(command, "--devices", dev)
+	}
+
+	output, err := runSnapFDEKeymgrWithOutput(command, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot run keymgr tool: %v", err)
+	}
+	var devKeys map[string][]struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(output, &devKeys); err != nil {
+		return nil, fmt.Errorf("cannot decode recovery keys: %v", err)
+	}
+
+	byName := make(map[string]*RecoveryKeyInfo)
+	var infos []*RecoveryKeyInfo
+	for dev, rkeys := range devKeys {
+		mountpoint, ok := devToMountpoint[dev]
+		if !ok {
+			return nil, fmt.Errorf("internal error: unexpected device %v", dev)
+		}
+		for _, rkey := range rkeys {
+			info := byName[rkey.Name]
+			if info == nil {
+				info = &RecoveryKeyInfo{Name: rkey.Name}
+				byName[rkey.Name] = info
+				infos = append(infos, info)
+			}
+			info.Mountpoints = append(info.Mountpoints, mountpoint)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	res := make([]RecoveryKeyInfo, len(infos))
+	for i, info := range infos {
+		sort.Strings(info.Mountpoints)
+		res[i] = *info
+	}
+	return res, nil
+}
+
 func devByPartUUIDFromMount(mp string) (string, error) {
 	partUUID, err := disks.PartitionUUIDFromMountPoint(mp, &disks.Options{
 		IsDecryptedDevice: true,
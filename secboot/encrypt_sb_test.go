@@ -134,6 +134,13 @@ if [ "$1" = "add-recovery-key" ]; then
         esac
     done
 fi
+if [ "$1" = "list-recovery-keys" ]; then
+    # diagnostics must not be mistaken for the output
+    echo "some warning" >&2
+    echo '{"/dev/disk/by-partuuid/foo-uuid":[{"name":"default","keyslot":1},{"name":"usb-stick","keyslot":3}],'
+    echo '"/dev/disk/by-partuuid/bar-uuid":[{"name":"usb-stick","keyslot":4}]}'
+    exit 0
+fi
 if [ "$1" = "remove-recovery-key" ]; then
     while [ "$#" -gt 1 ]; do
         case "$1" in
@@ -445,3 +452,94 @@ func (s *keymgrSuite) TestRemoveRecoveryKey(c *C) {
 	c.Check(s.systemdRunCmd.Calls(), DeepEquals, expectedSystemdRunCalls)
 	c.Check(s.keymgrCmd.Calls(), DeepEquals, expectedKeymgrCalls)
 }
+
+func (s *keymgrSuite) TestAddNamedRecoveryKey(c *C) {
+	s.mocksForDeviceMounts(c)
+
+	keyFile := filepath.Join(s.d, "recovery-usb-stick.key")
+	rkey, err := secboot.AddNamedRecoveryKey("usb-stick", keyFile, []secboot.RecoveryKeyDevice{
+		{Mountpoint: "/foo"},
+		{Mountpoint: "/bar", AuthorizingKeyFile: "/authz/key.file"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.keymgrCmd.Calls(), DeepEquals, [][]string{
+		{
+			"snap-fde-keymgr", "add-recovery-key",
+			"--name", "usb-stick",
+			"--key-file", keyFile,
+			"--devices", "/dev/disk/by-partuuid/foo-uuid", "--authorizations", "keyring",
+			"--devices", "/dev/disk/by-partuuid/bar-uuid", "--authorizations", "file:/authz/key.file",
+		},
+	})
+	c.Check(rkey, DeepEquals, keys.RecoveryKey{'r', 'e', 'c', 'o', 'v', 'e', 'r', 'y', '1', '1', '1', '1', '1', '1', '1', '1'})
+	// named keys are not kept
+	c.Check(keyFile, testutil.FileAbsent)
+}
+
+func (s *keymgrSuite) TestAddNamedRecoveryKeyErrorRemovesKeyFile(c *C) {
+	s.mocksForDeviceMounts(c)
+	keymgrCmd := testutil.MockCommand(c, "snap-fde-keymgr", `
+while true; do
+    case "$1" in
+        --key-file)
+            shift
+            printf "recovery11111111" > "$1"
+            echo "cannot import token" >&2
+            exit 1
+            ;;
+        *) shift ;;
+    esac
+done
+`)
+	defer keymgrCmd.Restore()
+	restore := snapdtool.MockOsReadlink(func(string) (string, error) {
+		return filepath.Join(filepath.Dir(keymgrCmd.Exe()), "snapd"), nil
+	})
+	defer restore()
+
+	keyFile := filepath.Join(s.d, "recovery-usb-stick.key")
+	_, err := secboot.AddNamedRecoveryKey("usb-stick", keyFile, []secboot.RecoveryKeyDevice{
+		{Mountpoint: "/foo"},
+	})
+	c.Assert(err, ErrorMatches, "cannot run keymgr tool: .*cannot import token")
+	c.Check(keyFile, testutil.FileAbsent)
+}
+
+func (s *keymgrSuite) TestRemoveNamedRecoveryKey(c *C) {
+	s.mocksForDeviceMounts(c)
+
+	err := secboot.RemoveNamedRecoveryKey("usb-stick", []secboot.RecoveryKeyDevice{
+		{Mountpoint: "/foo"},
+		{Mountpoint: "/bar", AuthorizingKeyFile: "/authz/key.file"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.keymgrCmd.Calls(), DeepEquals, [][]string{
+		{
+			"snap-fde-keymgr", "remove-recovery-key",
+			"--name", "usb-stick",
+			"--devices", "/dev/disk/by-partuuid/foo-uuid", "--authorizations", "keyring",
+			"--devices", "/dev/disk/by-partuuid/bar-uuid", "--authorizations", "file:/authz/key.file",
+		},
+	})
+}
+
+func (s *keymgrSuite) TestListRecoveryKeys(c *C) {
+	s.mocksForDeviceMounts(c)
+
+	infos, err := secboot.ListRecoveryKeys([]secboot.RecoveryKeyDevice{
+		{Mountpoint: "/foo"},
+		{Mountpoint: "/bar", AuthorizingKeyFile: "/authz/key.file"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(infos, DeepEquals, []secboot.RecoveryKeyInfo{
+		{Name: "default", Mountpoints: []string{"/foo"}},
+		{Name: "usb-stick", Mountpoints: []string{"/bar", "/foo"}},
+	})
+	c.Check(s.keymgrCmd.Calls(), DeepEquals, [][]string{
+		{
+			"snap-fde-keymgr", "list-recovery-keys",
+			"--devices", "/dev/disk/by-partuuid/foo-uuid",
+			"--devices", "/dev/disk/by-partuuid/bar-uuid",
+		},
+	})
+}
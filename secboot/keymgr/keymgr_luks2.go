@@ -20,8 +20,12 @@
 package keymgr
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"time"
 
 	sb "github.com/snapcore/secboot"
@@ -38,6 +42,17 @@ const (
 	recoveryKeySlot = 1
 	// temporary key slot used when changing the encryption key
 	tempKeySlot = recoveryKeySlot + 1
+	// first key slot used by named recovery keys
+	firstNamedRecoveryKeySlot = tempKeySlot + 1
+	// LUKS2 supports up to 32 key slots
+	lastKeySlot = 31
+	// key slot used by the user passphrase
+	passphraseKeySlot = lastKeySlot
+	// last key slot used by named recovery keys
+	lastNamedRecoveryKeySlot = passphraseKeySlot - 1
+
+	// type of the LUKS2 tokens recording the names of recovery keys
+	recoveryKeyTokenType = "ubuntu-recovery-key"
 )
 
 var (
@@ -99,6 +114,48 @@ func recoveryKDF() (*luks2.KDFOptions, error) {
 	}, nil
 }
 
+// passphraseTargetDuration is the target time for benchmarking the cost of
+// the KDF used by the passphrase key slot.
+const passphraseTargetDuration = 2 * time.Second
+
+func passphraseKDF() (*luks2.KDFOptions, error) {
+	opts, err := recoveryKDF()
+	if err != nil {
+		return nil, err
+	}
+	// unlike the recovery key, a passphrase is likely to have low entropy,
+	// so let cryptsetup benchmark the time cost, using at most the same
+	// amount of memory as the recovery key
+	return &luks2.KDFOptions{
+		MemoryKiB:      opts.MemoryKiB,
+		TargetDuration: passphraseTargetDuration,
+	}, nil
+}
+
+// AddPassphraseToLUKSDeviceUsingKey adds a user passphrase to the existing
+// LUKS encrypted volume on the block device given by dev. The existing key to
+// the encrypted volume is provided in the currKey argument and used to
+// authorize the operation. The passphrase is added to the last key slot.
+func AddPassphraseToLUKSDeviceUsingKey(passphrase string, currKey keys.EncryptionKey, dev string) error {
+	if passphrase == "" {
+		return fmt.Errorf("cannot add an empty passphrase")
+	}
+	opts, err := passphraseKDF()
+	if err != nil {
+		return err
+	}
+
+	options := luks2.AddKeyOptions{
+		KDFOptions: *opts,
+		Slot:       passphraseKeySlot,
+	}
+	if err := luks2.AddKey(dev, currKey, []byte(passphrase), &options); err != nil {
+		return fmt.Errorf("cannot add passphrase: %v", err)
+	}
+
+	return nil
+}
+
 // AddRecoveryKeyToLUKSDevice adds a recovery key to a LUKS2 device. It the
 // devuce unlock key from the user keyring to authorize the change. The
 // recoveyry key is added to keyslot 1.
@@ -275,3 +332,219 @@ func TransitionLUKSDeviceEncryptionKeyChange(newKey keys.EncryptionKey, dev stri
 	}
 	return nil
 }
+
+// NamedRecoveryKey describes a recovery key of a LUKS2 device.
+type NamedRecoveryKey struct {
+	Name    string `json:"name"`
+	Keyslot int    `json:"keyslot"`
+}
+
+// recoveryKeyToken is a LUKS2 token recording the name of the recovery key in
+// the referenced key slot.
+type recoveryKeyToken struct {
+	Type     string   `json:"type"`
+	Keyslots []string `json:"keyslots"`
+	Name     string   `json:"ubuntu-recovery-key-name"`
+}
+
+// namedRecoveryKeyToken is a recovery key token along with its number in the
+// LUKS2 metadata.
+type namedRecoveryKeyToken struct {
+	id      int
+	keyslot int
+	name    string
+}
+
+func recoveryKeyTokens(md *luks2.Metadata) ([]namedRecoveryKeyToken, error) {
+	var tokens []namedRecoveryKeyToken
+	for idStr, raw := range md.Tokens {
+		var token recoveryKeyToken
+		if err := json.Unmarshal(raw, &token); err != nil {
+			return nil, fmt.Errorf("cannot decode token %v: %v", idStr, err)
+		}
+		if token.Type != recoveryKeyTokenType {
+			continue
+		}
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token number %q", idStr)
+		}
+		if len(token.Keyslots) != 1 {
+			return nil, fmt.Errorf("invalid recovery key token %v with %v key slots", id, len(token.Keyslots))
+		}
+		slot, err := strconv.Atoi(token.Keyslots[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid key slot %q of recovery key token %v", token.Keyslots[0], id)
+		}
+		tokens = append(tokens, namedRecoveryKeyToken{id: id, keyslot: slot, name: token.Name})
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].keyslot < tokens[j].keyslot })
+	return tokens, nil
+}
+
+func findRecoveryKeyToken(md *luks2.Metadata, name string) (*namedRecoveryKeyToken, error) {
+	tokens, err := recoveryKeyTokens(md)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tokens {
+		if tokens[i].name == name {
+			return &tokens[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ListRecoveryKeysOnLUKSDevice returns the recovery keys of a LUKS2 device,
+// sorted by their key slot. The recovery key in key slot 1 is listed under
+// the default name.
+func ListRecoveryKeysOnLUKSDevice(dev string) ([]NamedRecoveryKey, error) {
+	md, err := luks2.ReadMetadata(dev)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read LUKS2 metadata: %v", err)
+	}
+	var rkeys []NamedRecoveryKey
+	if _, ok := md.Keyslots[strconv.Itoa(recoveryKeySlot)]; ok {
+		rkeys = append(rkeys, NamedRecoveryKey{Name: keys.DefaultRecoveryKeyName, Keyslot: recoveryKeySlot})
+	}
+	tokens, err := recoveryKeyTokens(md)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		if _, ok := md.Keyslots[strconv.Itoa(token.keyslot)]; !ok {
+			// the key slot was killed, but removing the token
+			// got interrupted
+			continue
+		}
+		rkeys = append(rkeys, NamedRecoveryKey{Name: token.name, Keyslot: token.keyslot})
+	}
+	return rkeys, nil
+}
+
+// ErrRecoveryKeyExists is returned when adding a named recovery key to a
+// device which already has a key with the same name.
+var ErrRecoveryKeyExists = errors.New("recovery key already exists")
+
+// AddNamedRecoveryKeyToLUKSDevice adds a named recovery key to a LUKS2
+// device. The device unlock key from the user keyring is used to authorize
+// the change.
+func AddNamedRecoveryKeyToLUKSDevice(name string, recoveryKey keys.RecoveryKey, dev string) error {
+	currKey, err := getEncryptionKeyFromUserKeyring(dev)
+	if err != nil {
+		return err
+	}
+	return AddNamedRecoveryKeyToLUKSDeviceUsingKey(name, recoveryKey, currKey, dev)
+}
+
+// AddNamedRecoveryKeyToLUKSDeviceUsingKey adds a named recovery key to a
+// LUKS2 device, using the provided key to authorize the operation. The
+// recovery key is added to the first free key slot after the ones reserved by
+// snapd, and a token records its name. ErrRecoveryKeyExists is returned if
+// the device has a recovery key with the same name already.
+func AddNamedRecoveryKeyToLUKSDeviceUsingKey(name string, recoveryKey keys.RecoveryKey, currKey keys.EncryptionKey, dev string) error {
+	if err := keys.ValidateRecoveryKeyName(name); err != nil {
+		return err
+	}
+	if name == keys.DefaultRecoveryKeyName {
+		return fmt.Errorf("cannot add a named recovery key with the reserved name %q", name)
+	}
+	md, err := luks2.ReadMetadata(dev)
+	if err != nil {
+		return fmt.Errorf("cannot read LUKS2 metadata: %v", err)
+	}
+	existing, err := findRecoveryKeyToken(md, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if _, ok := md.Keyslots[strconv.Itoa(existing.keyslot)]; ok {
+			return ErrRecoveryKeyExists
+		}
+		// stale token of a removed key
+		if err := luks2.RemoveToken(dev, existing.id); err != nil {
+			return fmt.Errorf("cannot remove stale recovery key token: %v", err)
+		}
+	}
+
+	slot := -1
+	for i := firstNamedRecoveryKeySlot; i <= lastNamedRecoveryKeySlot; i++ {
+		if _, ok := md.Keyslots[strconv.Itoa(i)]; !ok {
+			slot = i
+			break
+		}
+	}
+	if slot == -1 {
+		return fmt.Errorf("cannot add recovery key: no free key slots")
+	}
+
+	opts, err := recoveryKDF()
+	if err != nil {
+		return err
+	}
+	options := luks2.AddKeyOptions{
+		KDFOptions: *opts,
+		Slot:       slot,
+	}
+	if err := luks2.AddKey(dev, currKey, recoveryKey[:], &options); err != nil {
+		return fmt.Errorf("cannot add key: %v", err)
+	}
+
+	token, err := json.Marshal(recoveryKeyToken{
+		Type:     recoveryKeyTokenType,
+		Keyslots: []string{strconv.Itoa(slot)},
+		Name:     name,
+	})
+	if err != nil {
+		return err
+	}
+	if err := luks2.ImportToken(dev, token); err != nil {
+		// do not leave behind a key slot without a name
+		if kerr := luks2.KillSlot(dev, slot, currKey); kerr != nil {
+			return fmt.Errorf("cannot add recovery key token: %v (and cannot kill key slot %v: %v)", err, slot, kerr)
+		}
+		return fmt.Errorf("cannot add recovery key token: %v", err)
+	}
+	return nil
+}
+
+// RemoveNamedRecoveryKeyFromLUKSDevice removes a named recovery key from a
+// LUKS2 device. The device unlock key from the user keyring is used to
+// authorize the change.
+func RemoveNamedRecoveryKeyFromLUKSDevice(name string, dev string) error {
+	currKey, err := getEncryptionKeyFromUserKeyring(dev)
+	if err != nil {
+		return err
+	}
+	return RemoveNamedRecoveryKeyFromLUKSDeviceUsingKey(name, currKey, dev)
+}
+
+// RemoveNamedRecoveryKeyFromLUKSDeviceUsingKey removes a named recovery key
+// from a LUKS2 device, using the provided key to authorize the operation. The
+// default recovery key is removed from key slot 1. Removing a key which does
+// not exist is not an error.
+func RemoveNamedRecoveryKeyFromLUKSDeviceUsingKey(name string, currKey keys.EncryptionKey, dev string) error {
+	if name == keys.DefaultRecoveryKeyName {
+		return RemoveRecoveryKeyFromLUKSDeviceUsingKey(currKey, dev)
+	}
+	md, err := luks2.ReadMetadata(dev)
+	if err != nil {
+		return fmt.Errorf("cannot read LUKS2 metadata: %v", err)
+	}
+	token, err := findRecoveryKeyToken(md, name)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if err := luks2.KillSlot(dev, token.keyslot, currKey); err != nil {
+		if !isKeyslotNotActive(err) {
+			return fmt.Errorf("cannot kill recovery key slot: %v", err)
+		}
+	}
+	if err := luks2.RemoveToken(dev, token.id); err != nil {
+		return fmt.Errorf("cannot remove recovery key token: %v", err)
+	}
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	sb "github.com/snapcore/secboot"
@@ -902,3 +903,262 @@ CmaTotal:         131072 kB
 		ForceIterations: 4,
 	})
 }
+
+const mockLUKS2Metadata = `{
+  "keyslots": {"0": {"type": "luks2"}, "1": {"type": "luks2"}, "3": {"type": "luks2"}},
+  "tokens": {
+    "0": {"type": "ubuntu-recovery-key", "keyslots": ["3"], "ubuntu-recovery-key-name": "usb-stick"},
+    "1": {"type": "other", "keyslots": ["0"]},
+    "2": {"type": "ubuntu-recovery-key", "keyslots": ["5"], "ubuntu-recovery-key-name": "stale"}
+  }
+}`
+
+// mockCryptsetupWithMetadata mocks cryptsetup reporting the given LUKS2
+// metadata, the key added with luksAddKey and the imported token are saved in
+// new.key and token.json respectively.
+func (s *keymgrSuite) mockCryptsetupWithMetadata(c *C, metadata string) *testutil.MockCmd {
+	mdFile := filepath.Join(s.rootDir, "metadata.json")
+	c.Assert(ioutil.WriteFile(mdFile, []byte(metadata), 0644), IsNil)
+	cmd := testutil.MockCommand(c, "cryptsetup", fmt.Sprintf(`
+case "$1" in
+  luksDump)
+    cat %[1]s
+    ;;
+  luksAddKey)
+    # the unlock key is passed through a FIFO
+    cat "$5" > /dev/null
+    cat > %[2]s/new.key
+    ;;
+  token)
+    if [ "$2" = "import" ]; then
+      cat > %[2]s/token.json
+    fi
+    ;;
+esac
+`, mdFile, s.rootDir))
+	s.AddCleanup(cmd.Restore)
+	return cmd
+}
+
+func (s *keymgrSuite) TestListRecoveryKeysOnDevice(c *C) {
+	cmd := s.mockCryptsetupWithMetadata(c, mockLUKS2Metadata)
+
+	rkeys, err := keymgr.ListRecoveryKeysOnLUKSDevice("/dev/foobar")
+	c.Assert(err, IsNil)
+	c.Check(rkeys, DeepEquals, []keymgr.NamedRecoveryKey{
+		{Name: "default", Keyslot: 1},
+		// the key of the stale token is gone
+		{Name: "usb-stick", Keyslot: 3},
+	})
+	c.Check(cmd.Calls(), DeepEquals, [][]string{
+		{"cryptsetup", "luksDump", "--dump-json-metadata", "/dev/foobar"},
+	})
+}
+
+func (s *keymgrSuite) TestListRecoveryKeysOnDeviceBadToken(c *C) {
+	s.mockCryptsetupWithMetadata(c, `{"keyslots": {}, "tokens": {"0": {"type": "ubuntu-recovery-key", "keyslots": ["3", "4"]}}}`)
+
+	_, err := keymgr.ListRecoveryKeysOnLUKSDevice("/dev/foobar")
+	c.Assert(err, ErrorMatches, "invalid recovery key token 0 with 2 key slots")
+}
+
+func (s *keymgrSuite) TestListRecoveryKeysOnDeviceError(c *C) {
+	cmd := testutil.MockCommand(c, "cryptsetup", `echo "Device /dev/foobar is not a valid LUKS device." >&2; exit 1`)
+	defer cmd.Restore()
+
+	_, err := keymgr.ListRecoveryKeysOnLUKSDevice("/dev/foobar")
+	c.Assert(err, ErrorMatches, "cannot read LUKS2 metadata: cryptsetup failed with: Device /dev/foobar is not a valid LUKS device.")
+}
+
+func (s *keymgrSuite) TestAddNamedRecoveryKeyToDevice(c *C) {
+	unlockKey := "1234abcd"
+	restore := keymgr.MockGetDiskUnlockKeyFromKernel(func(prefix, devicePath string, remove bool) (sb.DiskUnlockKey, error) {
+		c.Check(devicePath, Equals, "/dev/foobar")
+		return []byte(unlockKey), nil
+	})
+	defer restore()
+	cmd := s.mockCryptsetupWithMetadata(c, mockLUKS2Metadata)
+
+	err := keymgr.AddNamedRecoveryKeyToLUKSDevice("backup", mockRecoveryKey, "/dev/foobar")
+	c.Assert(err, IsNil)
+	calls := cmd.Calls()
+	c.Assert(calls, HasLen, 3)
+	c.Check(calls[0], DeepEquals, []string{"cryptsetup", "luksDump", "--dump-json-metadata", "/dev/foobar"})
+	c.Assert(calls[1], HasLen, 16)
+	calls[1][5] = "<fifo>"
+	c.Check(calls[1], DeepEquals, []string{
+		"cryptsetup", "luksAddKey", "--type", "luks2",
+		"--key-file", "<fifo>",
+		"--pbkdf", "argon2i",
+		"--pbkdf-force-iterations", "4",
+		"--pbkdf-memory", "202834",
+		// first free key slot after the reserved ones
+		"--key-slot", "4",
+		"/dev/foobar", "-",
+	})
+	c.Check(calls[2], DeepEquals, []string{"cryptsetup", "token", "import", "/dev/foobar"})
+	c.Check(filepath.Join(s.rootDir, "new.key"), testutil.FileEquals, mockRecoveryKey[:])
+	c.Check(filepath.Join(s.rootDir, "token.json"), testutil.FileEquals,
+		`{"type":"ubuntu-recovery-key","keyslots":["4"],"ubuntu-recovery-key-name":"backup"}`)
+}
+
+func (s *keymgrSuite) TestAddNamedRecoveryKeyToDeviceReplacesStaleToken(c *C) {
+	cmd := s.mockCryptsetupWithMetadata(c, mockLUKS2Metadata)
+
+	err := keymgr.AddNamedRecoveryKeyToLUKSDeviceUsingKey("stale", mockRecoveryKey, []byte("1234abcd"), "/dev/foobar")
+	c.Assert(err, IsNil)
+	calls := cmd.Calls()
+	c.Assert(calls, HasLen, 4)
+	c.Check(calls[1], DeepEquals, []string{"cryptsetup", "token", "remove", "--token-id", "2", "/dev/foobar"})
+	c.Check(calls[3], DeepEquals, []string{"cryptsetup", "token", "import", "/dev/foobar"})
+}
+
+func (s *keymgrSuite) TestAddNamedRecoveryKeyToDeviceErrors(c *C) {
+	cmd := s.mockCryptsetupWithMetadata(c, mockLUKS2Metadata)
+
+	err := keymgr.AddNamedRecoveryKeyToLUKSDeviceUsingKey("usb-stick", mockRecoveryKey, []byte("1234abcd"), "/dev/foobar")
+	c.Assert(err, Equals, keymgr.ErrRecoveryKeyExists)
+
+	err = keymgr.AddNamedRecoveryKeyToLUKSDeviceUsingKey("default", mockRecoveryKey, []byte("1234abcd"), "/dev/foobar")
+	c.Assert(err, ErrorMatches, `cannot add a named recovery key with the reserved name "default"`)
+
+	err = keymgr.AddNamedRecoveryKeyToLUKSDeviceUsingKey("Bad Name", mockRecoveryKey, []byte("1234abcd"), "/dev/foobar")
+	c.Assert(err, ErrorMatches, `invalid recovery key name "Bad Name"`)
+	// only the metadata was read
+	c.Check(cmd.Calls(), HasLen, 1)
+
+	var slots []string
+	for i := 0; i < 32; i++ {
+		slots = append(slots, fmt.Sprintf(`"%d": {}`, i))
+	}
+	s.mockCryptsetupWithMetadata(c, fmt.Sprintf(`{"keyslots": {%s}}`, strings.Join(slots, ", ")))
+	err = keymgr.AddNamedRecoveryKeyToLUKSDeviceUsingKey("backup", mockRecoveryKey, []byte("1234abcd"), "/dev/foobar")
+	c.Assert(err, ErrorMatches, "cannot add recovery key: no free key slots")
+}
+
+func (s *keymgrSuite) TestAddNamedRecoveryKeyToDeviceTokenImportFails(c *C) {
+	mdFile := filepath.Join(s.rootDir, "metadata.json")
+	c.Assert(ioutil.WriteFile(mdFile, []byte(mockLUKS2Metadata), 0644), IsNil)
+	cmd := testutil.MockCommand(c, "cryptsetup", fmt.Sprintf(`
+case "$1" in
+  luksDump)
+    cat %s
+    ;;
+  luksAddKey)
+    # the unlock key is passed through a FIFO
+    cat "$5" > /dev/null
+    cat > /dev/null
+    ;;
+  luksKillSlot)
+    cat > /dev/null
+    ;;
+  token)
+    echo "token import failed" >&2
+    exit 1
+    ;;
+esac
+`, mdFile))
+	defer cmd.Restore()
+
+	err := keymgr.AddNamedRecoveryKeyToLUKSDeviceUsingKey("backup", mockRecoveryKey, []byte("1234abcd"), "/dev/foobar")
+	c.Assert(err, ErrorMatches, "cannot add recovery key token: cryptsetup failed with: token import failed")
+	calls := cmd.Calls()
+	c.Assert(calls, HasLen, 4)
+	c.Check(calls[1][1], Equals, "luksAddKey")
+	c.Check(calls[2], DeepEquals, []string{"cryptsetup", "token", "import", "/dev/foobar"})
+	// the key slot added for the recovery key is killed
+	c.Check(calls[3], DeepEquals, []string{"cryptsetup", "luksKillSlot", "--type", "luks2", "--key-file", "-", "/dev/foobar", "4"})
+}
+
+func (s *keymgrSuite) TestRemoveNamedRecoveryKeyFromDevice(c *C) {
+	restore := keymgr.MockGetDiskUnlockKeyFromKernel(func(prefix, devicePath string, remove bool) (sb.DiskUnlockKey, error) {
+		return []byte("1234abcd"), nil
+	})
+	defer restore()
+	cmd := s.mockCryptsetupWithMetadata(c, mockLUKS2Metadata)
+
+	err := keymgr.RemoveNamedRecoveryKeyFromLUKSDevice("usb-stick", "/dev/foobar")
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), DeepEquals, [][]string{
+		{"cryptsetup", "luksDump", "--dump-json-metadata", "/dev/foobar"},
+		{"cryptsetup", "luksKillSlot", "--type", "luks2", "--key-file", "-", "/dev/foobar", "3"},
+		{"cryptsetup", "token", "remove", "--token-id", "0", "/dev/foobar"},
+	})
+	cmd.ForgetCalls()
+
+	// not an error if the key is gone already
+	err = keymgr.RemoveNamedRecoveryKeyFromLUKSDevice("missing", "/dev/foobar")
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), HasLen, 1)
+	cmd.ForgetCalls()
+
+	// the default key is in its own key slot
+	err = keymgr.RemoveNamedRecoveryKeyFromLUKSDevice("default", "/dev/foobar")
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), DeepEquals, [][]string{
+		{"cryptsetup", "luksKillSlot", "--type", "luks2", "--key-file", "-", "/dev/foobar", "1"},
+	})
+}
+
+func (s *keymgrSuite) TestRemoveNamedRecoveryKeyFromDeviceInactiveSlot(c *C) {
+	cmd := testutil.MockCommand(c, "cryptsetup", fmt.Sprintf(`
+case "$1" in
+  luksDump)
+    echo '%s'
+    ;;
+  luksKillSlot)
+    echo "Keyslot 5 is not active." >&2
+    exit 1
+    ;;
+esac
+`, strings.Replace(mockLUKS2Metadata, "\n", "", -1)))
+	defer cmd.Restore()
+
+	err := keymgr.RemoveNamedRecoveryKeyFromLUKSDeviceUsingKey("stale", []byte("1234abcd"), "/dev/foobar")
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), DeepEquals, [][]string{
+		{"cryptsetup", "luksDump", "--dump-json-metadata", "/dev/foobar"},
+		{"cryptsetup", "luksKillSlot", "--type", "luks2", "--key-file", "-", "/dev/foobar", "5"},
+		{"cryptsetup", "token", "remove", "--token-id", "2", "/dev/foobar"},
+	})
+}
+
+func (s *keymgrSuite) TestAddPassphraseToDeviceUsingKey(c *C) {
+	cmd := s.mockCryptsetupForAddKey(c)
+	defer cmd.Restore()
+	key := bytes.Repeat([]byte{1}, 32)
+	err := keymgr.AddPassphraseToLUKSDeviceUsingKey("my secret", keys.EncryptionKey(key), "/dev/foobar")
+	c.Assert(err, IsNil)
+
+	calls := cmd.Calls()
+	c.Assert(calls, HasLen, 1)
+	c.Assert(calls[0], HasLen, 16)
+	c.Assert(calls[0][5], testutil.Contains, s.rootDir)
+	calls[0][5] = "<fifo>"
+	c.Check(calls[0], DeepEquals, []string{
+		"cryptsetup", "luksAddKey", "--type", "luks2",
+		"--key-file", "<fifo>",
+		"--pbkdf", "argon2i",
+		"--iter-time", "2000",
+		"--pbkdf-memory", "202834",
+		"--key-slot", "31",
+		"/dev/foobar", "-",
+	})
+	c.Check(filepath.Join(s.rootDir, "unlock.key"), testutil.FileEquals, key)
+	c.Check(filepath.Join(s.rootDir, "new.key"), testutil.FileEquals, "my secret")
+}
+
+func (s *keymgrSuite) TestAddPassphraseToDeviceErrors(c *C) {
+	key := bytes.Repeat([]byte{1}, 32)
+	err := keymgr.AddPassphraseToLUKSDeviceUsingKey("", keys.EncryptionKey(key), "/dev/foobar")
+	c.Assert(err, ErrorMatches, "cannot add an empty passphrase")
+
+	cmd := testutil.MockCommand(c, "cryptsetup", `
+cat "$5" > /dev/null
+echo "Key slot 31 is full, please select another one." >&2
+exit 1
+`)
+	defer cmd.Restore()
+	err = keymgr.AddPassphraseToLUKSDeviceUsingKey("my secret", keys.EncryptionKey(key), "/dev/foobar")
+	c.Assert(err, ErrorMatches, "cannot add passphrase: cryptsetup failed with: Key slot 31 is full, please select another one.")
+}
@@ -25,6 +25,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/snapcore/snapd/osutil"
 )
@@ -106,6 +107,21 @@ func RecoveryKeyFromFile(recoveryKeyFile string) (*RecoveryKey, error) {
 	return &rkey, nil
 }
 
+// DefaultRecoveryKeyName is the name of the recovery key created by snapd
+// for the system, other recovery keys are named by the user.
+const DefaultRecoveryKeyName = "default"
+
+var validRecoveryKeyName = regexp.MustCompile(`^[a-z0-9](?:-?[a-z0-9])*$`)
+
+// ValidateRecoveryKeyName checks that the name of a recovery key is made of
+// lowercase letters, digits and dashes, and is at most 40 characters long.
+func ValidateRecoveryKeyName(name string) error {
+	if len(name) > 40 || !validRecoveryKeyName.MatchString(name) {
+		return fmt.Errorf("invalid recovery key name %q", name)
+	}
+	return nil
+}
+
 // AuxKey is the key to bind models to keys.
 type AuxKey [AuxKeySize]byte
 
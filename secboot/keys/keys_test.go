@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	. "gopkg.in/check.v1"
@@ -114,3 +115,12 @@ func (s *keysSuite) TestNewAuxKeySad(c *C) {
 	_, err := keys.NewAuxKey()
 	c.Check(err, ErrorMatches, "fail")
 }
+
+func (s *keysSuite) TestValidateRecoveryKeyName(c *C) {
+	for _, name := range []string{"default", "a", "backup-1", "0", "my-usb-stick"} {
+		c.Check(keys.ValidateRecoveryKeyName(name), IsNil, Commentf("%q", name))
+	}
+	for _, name := range []string{"", "-a", "a-", "a--b", "A", "a_b", "a b", strings.Repeat("a", 41)} {
+		c.Check(keys.ValidateRecoveryKeyName(name), ErrorMatches, `invalid recovery key name ".*"`, Commentf("%q", name))
+	}
+}
@@ -21,6 +21,7 @@ package luks2
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -217,3 +218,39 @@ func KillSlot(devicePath string, slot int, key []byte) error {
 func SetSlotPriority(devicePath string, slot int, priority SlotPriority) error {
 	return cryptsetupCmd(nil, nil, "config", "--priority", priority.String(), "--key-slot", strconv.Itoa(slot), devicePath)
 }
+
+// Metadata is the part of the JSON metadata of a LUKS2 container used by
+// snapd, describing the keyslots and tokens in use. Both are indexed by their
+// number, as a string.
+type Metadata struct {
+	Keyslots map[string]json.RawMessage `json:"keyslots"`
+	Tokens   map[string]json.RawMessage `json:"tokens"`
+}
+
+// ReadMetadata reads the JSON metadata of the specified LUKS2 container.
+func ReadMetadata(devicePath string) (*Metadata, error) {
+	cmd := exec.Command("cryptsetup", "luksDump", "--dump-json-metadata", devicePath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cryptsetup failed with: %v", osutil.OutputErr(stderr.Bytes(), err))
+	}
+	var md Metadata
+	if err := json.Unmarshal(output, &md); err != nil {
+		return nil, fmt.Errorf("cannot decode LUKS2 metadata: %v", err)
+	}
+	return &md, nil
+}
+
+// ImportToken imports the supplied JSON encoded token in to the specified
+// LUKS2 container. The token is assigned the first free token number.
+func ImportToken(devicePath string, token []byte) error {
+	return cryptsetupCmd(bytes.NewReader(token), nil, "token", "import", devicePath)
+}
+
+// RemoveToken removes the token with the supplied number from the specified
+// LUKS2 container.
+func RemoveToken(devicePath string, tokenID int) error {
+	return cryptsetupCmd(nil, nil, "token", "remove", "--token-id", strconv.Itoa(tokenID), devicePath)
+}
@@ -153,6 +153,10 @@ type UnlockVolumeUsingSealedKeyOptions struct {
 	// WhichModel if invoked should return the device model
 	// assertion for which the disk is being unlocked.
 	WhichModel func() (*asserts.Model, error)
+	// UsePassphrase when true indicates that the volume is protected with
+	// a user passphrase rather than a sealed key, the user is prompted for
+	// the passphrase and the sealed key is not used.
+	UsePassphrase bool
 }
 
 // UnlockMethod is the method that was used to unlock a volume.
@@ -173,6 +177,9 @@ const (
 	UnlockedWithKey
 	// UnlockStatusUnknown indicates that the unlock status of the device is not clear.
 	UnlockStatusUnknown
+	// UnlockedWithPassphrase indicates that the device was unlocked by the
+	// user providing the passphrase at the prompt.
+	UnlockedWithPassphrase
 )
 
 // UnlockResult is the result of trying to unlock a volume.
@@ -192,6 +199,7 @@ type UnlockResult struct {
 	// - UnlockedWithRecoveryKey
 	// - UnlockedWithSealedKey
 	// - UnlockedWithKey
+	// - UnlockedWithPassphrase
 	UnlockMethod UnlockMethod
 }
 
@@ -22,14 +22,18 @@
 package secboot
 
 import (
+	"bytes"
 	"fmt"
+	"os/exec"
 	"path/filepath"
+	"strings"
 
 	sb "github.com/snapcore/secboot"
 	"golang.org/x/xerrors"
 
 	"github.com/snapcore/snapd/kernel/fde"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/osutil/disks"
 )
 
@@ -106,6 +110,10 @@ func UnlockVolumeUsingSealedKeyIfEncrypted(disk disks.Disk, name string, sealedE
 	sourceDevice := partDevice
 	targetDevice := filepath.Join("/dev/mapper", mapperName)
 
+	if opts.UsePassphrase {
+		return unlockVolumeUsingPassphrase(name, sourceDevice, targetDevice, mapperName, opts)
+	}
+
 	if fdeHasRevealKey() {
 		return unlockVolumeUsingSealedKeyFDERevealKey(sealedEncryptionKeyFile, sourceDevice, targetDevice, mapperName, opts)
 	} else {
@@ -154,6 +162,63 @@ func unlockEncryptedPartitionWithKey(name, device string, key []byte) error {
 	return err
 }
 
+// passphraseTries is the number of attempts the user has to enter the
+// passphrase before falling back to the recovery key
+const passphraseTries = 3
+
+func askPassphraseImpl(name, sourceDevice string) (string, error) {
+	cmd := exec.Command("systemd-ask-password",
+		"--icon", "drive-harddisk",
+		"--id", "snapd:"+sourceDevice,
+		fmt.Sprintf("Please enter the passphrase for %s (%s):", name, sourceDevice))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot obtain passphrase: %v", osutil.OutputErr(stderr.Bytes(), err))
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+var askPassphrase = askPassphraseImpl
+
+// unlockVolumeUsingPassphrase prompts for the passphrase of an encrypted
+// device and uses it to open it. If activation with the passphrase fails, the
+// recovery key is requested instead if allowed by the options.
+func unlockVolumeUsingPassphrase(name, sourceDevice, targetDevice, mapperName string, opts *UnlockVolumeUsingSealedKeyOptions) (UnlockResult, error) {
+	res := UnlockResult{IsEncrypted: true, PartDevice: sourceDevice}
+
+	options := sb.ActivateVolumeOptions{
+		KeyringPrefix: keyringPrefix,
+	}
+	var err error
+	for i := 0; i < passphraseTries; i++ {
+		var passphrase string
+		passphrase, err = askPassphrase(name, sourceDevice)
+		if err != nil {
+			break
+		}
+		err = sbActivateVolumeWithKey(mapperName, sourceDevice, []byte(passphrase), &options)
+		if err == nil {
+			logger.Noticef("successfully activated encrypted device %q using a passphrase", sourceDevice)
+			res.FsDevice = targetDevice
+			res.UnlockMethod = UnlockedWithPassphrase
+			return res, nil
+		}
+	}
+	if !opts.AllowRecoveryKey {
+		return res, fmt.Errorf("cannot unlock encrypted device %q with passphrase: %v", name, err)
+	}
+	logger.Noticef("cannot unlock encrypted device %q with passphrase: %v", name, err)
+
+	if err := UnlockEncryptedVolumeWithRecoveryKey(mapperName, sourceDevice); err != nil {
+		return res, err
+	}
+	res.FsDevice = targetDevice
+	res.UnlockMethod = UnlockedWithRecoveryKey
+	return res, nil
+}
+
 // UnlockEncryptedVolumeWithRecoveryKey prompts for the recovery key and uses it
 // to open an encrypted device.
 func UnlockEncryptedVolumeWithRecoveryKey(name, device string) error {
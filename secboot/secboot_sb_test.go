@@ -2176,3 +2176,152 @@ func (s *secbootSuite) testMarkSuccessfulEncrypted(c *C, sealingMethod device.Se
 
 	c.Check(daLockResetCalls, Equals, expectedDaLockResetCalls)
 }
+
+func (s *secbootSuite) TestUnlockVolumeUsingSealedKeyIfEncryptedWithPassphrase(c *C) {
+	disk := &disks.MockDiskMapping{
+		Structure: []disks.Partition{
+			{
+				FilesystemLabel: "ubuntu-data-enc",
+				PartitionUUID:   "123-123-123",
+			},
+		},
+	}
+	restore := secboot.MockRandomKernelUUID(func() string {
+		return "random-uuid-123-123"
+	})
+	defer restore()
+
+	// the first attempt is wrong
+	askPassword := testutil.MockCommand(c, "systemd-ask-password", fmt.Sprintf(`
+if [ -e %[1]s ]; then
+    echo "good passphrase"
+else
+    touch %[1]s
+    echo "bad passphrase"
+fi
+`, filepath.Join(c.MkDir(), "asked")))
+	defer askPassword.Restore()
+
+	activations := 0
+	restore = secboot.MockSbActivateVolumeWithKey(func(volumeName, sourceDevicePath string, key []byte,
+		options *sb.ActivateVolumeOptions) error {
+		activations++
+		c.Check(volumeName, Equals, "ubuntu-data-random-uuid-123-123")
+		c.Check(sourceDevicePath, Equals, "/dev/disk/by-partuuid/123-123-123")
+		c.Check(options, DeepEquals, &sb.ActivateVolumeOptions{KeyringPrefix: "ubuntu-fde"})
+		if string(key) != "good passphrase" {
+			return fmt.Errorf("bad key")
+		}
+		return nil
+	})
+	defer restore()
+	restore = secboot.MockSbActivateVolumeWithKeyData(func(volumeName, sourceDevicePath string, key *sb.KeyData, options *sb.ActivateVolumeOptions) (sb.SnapModelChecker, error) {
+		c.Fatalf("unexpected call")
+		return nil, nil
+	})
+	defer restore()
+
+	opts := &secboot.UnlockVolumeUsingSealedKeyOptions{UsePassphrase: true}
+	unlockRes, err := secboot.UnlockVolumeUsingSealedKeyIfEncrypted(disk, "ubuntu-data", "some-sealed-key-file", opts)
+	c.Assert(err, IsNil)
+	c.Check(unlockRes, DeepEquals, secboot.UnlockResult{
+		PartDevice:   "/dev/disk/by-partuuid/123-123-123",
+		FsDevice:     "/dev/mapper/ubuntu-data-random-uuid-123-123",
+		IsEncrypted:  true,
+		UnlockMethod: secboot.UnlockedWithPassphrase,
+	})
+	c.Check(activations, Equals, 2)
+	c.Check(askPassword.Calls(), DeepEquals, [][]string{
+		{"systemd-ask-password", "--icon", "drive-harddisk", "--id", "snapd:/dev/disk/by-partuuid/123-123-123",
+			"Please enter the passphrase for ubuntu-data (/dev/disk/by-partuuid/123-123-123):"},
+		{"systemd-ask-password", "--icon", "drive-harddisk", "--id", "snapd:/dev/disk/by-partuuid/123-123-123",
+			"Please enter the passphrase for ubuntu-data (/dev/disk/by-partuuid/123-123-123):"},
+	})
+}
+
+func (s *secbootSuite) TestUnlockVolumeUsingSealedKeyIfEncryptedWithPassphraseFallback(c *C) {
+	disk := &disks.MockDiskMapping{
+		Structure: []disks.Partition{
+			{
+				FilesystemLabel: "ubuntu-data-enc",
+				PartitionUUID:   "123-123-123",
+			},
+		},
+	}
+	restore := secboot.MockRandomKernelUUID(func() string {
+		return "random-uuid-123-123"
+	})
+	defer restore()
+
+	askPassword := testutil.MockCommand(c, "systemd-ask-password", `echo "bad passphrase"`)
+	defer askPassword.Restore()
+
+	restore = secboot.MockSbActivateVolumeWithKey(func(volumeName, sourceDevicePath string, key []byte,
+		options *sb.ActivateVolumeOptions) error {
+		return fmt.Errorf("bad key")
+	})
+	defer restore()
+	recoveryKeyCalls := 0
+	restore = secboot.MockSbActivateVolumeWithRecoveryKey(func(volumeName, sourceDevicePath string,
+		keyReader io.Reader, options *sb.ActivateVolumeOptions) error {
+		recoveryKeyCalls++
+		c.Check(volumeName, Equals, "ubuntu-data-random-uuid-123-123")
+		return nil
+	})
+	defer restore()
+
+	// without the recovery key
+	opts := &secboot.UnlockVolumeUsingSealedKeyOptions{UsePassphrase: true}
+	unlockRes, err := secboot.UnlockVolumeUsingSealedKeyIfEncrypted(disk, "ubuntu-data", "", opts)
+	c.Assert(err, ErrorMatches, `cannot unlock encrypted device "ubuntu-data" with passphrase: bad key`)
+	c.Check(unlockRes, DeepEquals, secboot.UnlockResult{
+		PartDevice:  "/dev/disk/by-partuuid/123-123-123",
+		IsEncrypted: true,
+	})
+	c.Check(askPassword.Calls(), HasLen, 3)
+	c.Check(recoveryKeyCalls, Equals, 0)
+
+	// with the recovery key
+	askPassword.ForgetCalls()
+	opts.AllowRecoveryKey = true
+	unlockRes, err = secboot.UnlockVolumeUsingSealedKeyIfEncrypted(disk, "ubuntu-data", "", opts)
+	c.Assert(err, IsNil)
+	c.Check(unlockRes, DeepEquals, secboot.UnlockResult{
+		PartDevice:   "/dev/disk/by-partuuid/123-123-123",
+		FsDevice:     "/dev/mapper/ubuntu-data-random-uuid-123-123",
+		IsEncrypted:  true,
+		UnlockMethod: secboot.UnlockedWithRecoveryKey,
+	})
+	c.Check(askPassword.Calls(), HasLen, 3)
+	c.Check(recoveryKeyCalls, Equals, 1)
+}
+
+func (s *secbootSuite) TestUnlockVolumeUsingSealedKeyIfEncryptedWithPassphraseAskError(c *C) {
+	disk := &disks.MockDiskMapping{
+		Structure: []disks.Partition{
+			{
+				FilesystemLabel: "ubuntu-data-enc",
+				PartitionUUID:   "123-123-123",
+			},
+		},
+	}
+	askPassword := testutil.MockCommand(c, "systemd-ask-password", `echo "no console" >&2; exit 1`)
+	defer askPassword.Restore()
+
+	opts := &secboot.UnlockVolumeUsingSealedKeyOptions{UsePassphrase: true}
+	_, err := secboot.UnlockVolumeUsingSealedKeyIfEncrypted(disk, "ubuntu-data", "", opts)
+	c.Assert(err, ErrorMatches, `cannot unlock encrypted device "ubuntu-data" with passphrase: cannot obtain passphrase: no console`)
+	c.Check(askPassword.Calls(), HasLen, 1)
+}
+
+func (s *secbootSuite) TestAddPassphrase(c *C) {
+	cmd := testutil.MockCommand(c, "cryptsetup", `cat "$5" > /dev/null; cat > /dev/null`)
+	defer cmd.Restore()
+	key := keys.EncryptionKey(bytes.Repeat([]byte{1}, 32))
+	err := secboot.AddPassphrase(key, "secret", "/dev/foo")
+	c.Assert(err, IsNil)
+	calls := cmd.Calls()
+	c.Assert(calls, HasLen, 1)
+	c.Check(calls[0][1], Equals, "luksAddKey")
+	c.Check(calls[0][len(calls[0])-4:], DeepEquals, []string{"--key-slot", "31", "/dev/foo", "-"})
+}
@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"sync"
 	"syscall"
 	"time"
 
@@ -34,7 +35,10 @@ import (
 )
 
 // overridden in the unit tests
-var osRemove = os.Remove
+var (
+	osRemove      = os.Remove
+	syscallStatfs = syscall.Statfs
+)
 
 // downloadCache is the interface that a store download cache must provide
 type downloadCache interface {
@@ -65,10 +69,25 @@ func (s changesByMtime) Len() int           { return len(s) }
 func (s changesByMtime) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s changesByMtime) Less(i, j int) bool { return s[i].ModTime().Before(s[j].ModTime()) }
 
+// CachePolicy controls when entries are evicted from the download cache.
+// Zero values mean no limit.
+type CachePolicy struct {
+	// MaxItems is the maximum number of entries in the cache.
+	MaxItems int
+	// MaxSize is the maximum total size in bytes of the entries in
+	// the cache.
+	MaxSize uint64
+	// MinFree is the free space in bytes to preserve on the
+	// filesystem of the cache.
+	MinFree uint64
+}
+
 // cacheManager implements a downloadCache via content based hard linking
 type CacheManager struct {
 	cacheDir string
-	maxItems int
+
+	mu     sync.Mutex
+	policy CachePolicy
 }
 
 // NewCacheManager returns a new CacheManager with the given cacheDir
@@ -80,16 +99,106 @@ type CacheManager struct {
 //     return success
 //  3. If not found, download the snap
 //  4. On success, hardlink into $cacheDir/<digest>
-//  5. If cache dir has more than maxItems entries, remove oldest mtimes
-//     until it has maxItems
+//  5. If cache dir is over the limits of its policy, remove oldest
+//     mtimes until it is within them
+//
+// Entries are keyed by the sha3-384 digest of the snaps, so a cached
+// snap is found whatever the name it is downloaded under. Entries that
+// are also linked from elsewhere, as the revisions of installed snaps
+// are, are pinned: they take no extra space and are never evicted.
 //
 // The caching part is done here, the downloading happens in the store.go
 // code.
 func NewCacheManager(cacheDir string, maxItems int) *CacheManager {
 	return &CacheManager{
 		cacheDir: cacheDir,
-		maxItems: maxItems,
+		policy:   CachePolicy{MaxItems: maxItems},
+	}
+}
+
+// SetPolicy sets the policy used to evict entries from the cache.
+func (cm *CacheManager) SetPolicy(policy CachePolicy) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.policy = policy
+}
+
+// Policy returns the policy used to evict entries from the cache.
+func (cm *CacheManager) Policy() CachePolicy {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.policy
+}
+
+// CacheEntry describes an entry of the download cache.
+type CacheEntry struct {
+	// Digest is the sha3-384 digest of the cached snap.
+	Digest  string
+	Size    int64
+	ModTime time.Time
+	// Pinned is set if the snap is also linked from outside the
+	// cache, e.g. because it is an installed revision.
+	Pinned bool
+}
+
+// Entries returns the entries in the cache, oldest first.
+func (cm *CacheManager) Entries() ([]CacheEntry, error) {
+	fil, err := ioutil.ReadDir(cm.cacheDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(changesByMtime(fil))
+
+	entries := make([]CacheEntry, 0, len(fil))
+	for _, fi := range fil {
+		n, err := hardLinkCount(fi)
+		if err != nil {
+			logger.Noticef("cannot inspect cache: %s", err)
+		}
+		entries = append(entries, CacheEntry{
+			Digest:  fi.Name(),
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
+			Pinned:  n > 1,
+		})
 	}
+	return entries, nil
+}
+
+// Remove removes the entry with the given cacheKey from the cache, even
+// if it is pinned.
+func (cm *CacheManager) Remove(cacheKey string) error {
+	return osRemove(cm.path(cacheKey))
+}
+
+// Purge removes all the entries that are not pinned from the cache and
+// returns the removed entries. Entries for which inUse, if given,
+// returns true are kept as well, as the hardlink count alone does not
+// tell whether a cached snap is still used.
+func (cm *CacheManager) Purge(inUse func(cacheKey string) bool) ([]CacheEntry, error) {
+	entries, err := cm.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var removed []CacheEntry
+	var lastErr error
+	for _, entry := range entries {
+		if entry.Pinned || (inUse != nil && inUse(entry.Digest)) {
+			continue
+		}
+		if err := cm.Remove(entry.Digest); err != nil {
+			if !os.IsNotExist(err) {
+				logger.Noticef("cannot purge cache: %s", err)
+				lastErr = err
+			}
+			continue
+		}
+		removed = append(removed, entry)
+	}
+	return removed, lastErr
 }
 
 // GetPath returns the full path of the given content in the cache
@@ -159,17 +268,30 @@ func (cm *CacheManager) path(cacheKey string) string {
 	return filepath.Join(cm.cacheDir, cacheKey)
 }
 
-// cleanup ensures that only maxItems are stored in the cache
+// diskFree returns the space available to unprivileged users on the
+// filesystem of the given path
+func diskFree(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscallStatfs(path, &st); err != nil {
+		return 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), nil
+}
+
+// cleanup ensures that the cache is within the limits of its policy
 func (cm *CacheManager) cleanup() error {
+	policy := cm.Policy()
+
 	fil, err := ioutil.ReadDir(cm.cacheDir)
 	if err != nil {
 		return err
 	}
-	if len(fil) <= cm.maxItems {
+	if policy.MaxItems > 0 && len(fil) <= policy.MaxItems && policy.MaxSize == 0 && policy.MinFree == 0 {
 		return nil
 	}
 
 	numOwned := 0
+	var ownedSize uint64
 	for _, fi := range fil {
 		n, err := hardLinkCount(fi)
 		if err != nil {
@@ -178,16 +300,31 @@ func (cm *CacheManager) cleanup() error {
 		// Only count the file if it is not referenced elsewhere in the filesystem
 		if n <= 1 {
 			numOwned++
+			ownedSize += uint64(fi.Size())
+		}
+	}
+
+	var free uint64
+	checkFree := policy.MinFree > 0
+	if checkFree {
+		free, err = diskFree(cm.cacheDir)
+		if err != nil {
+			logger.Noticef("cannot inspect free space of cache: %s", err)
+			checkFree = false
 		}
 	}
 
-	if numOwned <= cm.maxItems {
+	overLimits := func() bool {
+		return (policy.MaxItems > 0 && numOwned > policy.MaxItems) ||
+			(policy.MaxSize > 0 && ownedSize > policy.MaxSize) ||
+			(checkFree && free < policy.MinFree)
+	}
+	if !overLimits() {
 		return nil
 	}
 
 	var lastErr error
 	sort.Sort(changesByMtime(fil))
-	deleted := 0
 	for _, fi := range fil {
 		path := cm.path(fi.Name())
 		n, err := hardLinkCount(fi)
@@ -207,8 +344,10 @@ func (cm *CacheManager) cleanup() error {
 			}
 			continue
 		}
-		deleted++
-		if numOwned-deleted <= cm.maxItems {
+		numOwned--
+		ownedSize -= uint64(fi.Size())
+		free += uint64(fi.Size())
+		if !overLimits() {
 			break
 		}
 	}
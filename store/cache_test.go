@@ -25,6 +25,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"syscall"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -228,3 +229,97 @@ func (s *cacheSuite) TestCacheHitOnErrExist(c *C) {
 	cacheHit := s.cm.Get("foo", targetPath)
 	c.Assert(cacheHit, Equals, true)
 }
+
+func (s *cacheSuite) TestCleanupMaxSize(c *C) {
+	s.cm.SetPolicy(store.CachePolicy{MaxSize: 3})
+	cacheKeys, testFiles := s.makeTestFiles(c, 5)
+	for _, p := range testFiles {
+		c.Assert(os.Remove(p), IsNil)
+	}
+	c.Assert(s.cm.Cleanup(), IsNil)
+
+	// each entry is one byte, only the newest three are kept
+	c.Check(s.cm.Count(), Equals, 3)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[1])), Equals, false)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[2])), Equals, true)
+}
+
+func (s *cacheSuite) TestCleanupMinFree(c *C) {
+	cacheKeys, testFiles := s.makeTestFiles(c, 4)
+	for _, p := range testFiles {
+		c.Assert(os.Remove(p), IsNil)
+	}
+
+	restore := store.MockSyscallStatfs(func(path string, st *syscall.Statfs_t) error {
+		c.Check(path, Equals, s.cm.CacheDir())
+		st.Bsize = 1
+		st.Bavail = 8
+		return nil
+	})
+	defer restore()
+	s.cm.SetPolicy(store.CachePolicy{MinFree: 10})
+	c.Assert(s.cm.Cleanup(), IsNil)
+
+	// removing the two oldest one byte entries frees enough space
+	c.Check(s.cm.Count(), Equals, 2)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[1])), Equals, false)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[2])), Equals, true)
+}
+
+func (s *cacheSuite) TestCleanupKeepsPinned(c *C) {
+	s.cm.SetPolicy(store.CachePolicy{MaxSize: 1})
+	cacheKeys, testFiles := s.makeTestFiles(c, 3)
+	// the oldest entry is still linked from elsewhere, as installed
+	// snaps are
+	for _, p := range testFiles[1:] {
+		c.Assert(os.Remove(p), IsNil)
+	}
+	c.Assert(s.cm.Cleanup(), IsNil)
+
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[0])), Equals, true)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[1])), Equals, false)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[2])), Equals, true)
+}
+
+func (s *cacheSuite) TestEntriesAndPurge(c *C) {
+	s.cm.SetPolicy(store.CachePolicy{})
+	cacheKeys, testFiles := s.makeTestFiles(c, 3)
+	c.Assert(os.Remove(testFiles[0]), IsNil)
+	c.Assert(os.Remove(testFiles[2]), IsNil)
+
+	entries, err := s.cm.Entries()
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 3)
+	for i, entry := range entries {
+		c.Check(entry.Digest, Equals, cacheKeys[i])
+		c.Check(entry.Size, Equals, int64(1))
+		c.Check(entry.Pinned, Equals, i == 1)
+	}
+
+	// entries in use are kept even if they are not linked from elsewhere
+	inUse := func(cacheKey string) bool { return cacheKey == cacheKeys[2] }
+	removed, err := s.cm.Purge(inUse)
+	c.Assert(err, IsNil)
+	c.Assert(removed, HasLen, 1)
+	c.Check(removed[0].Digest, Equals, cacheKeys[0])
+
+	removed, err = s.cm.Purge(nil)
+	c.Assert(err, IsNil)
+	c.Assert(removed, HasLen, 1)
+	c.Check(removed[0].Digest, Equals, cacheKeys[2])
+
+	entries, err = s.cm.Entries()
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+	c.Check(entries[0].Digest, Equals, cacheKeys[1])
+
+	c.Assert(s.cm.Remove(cacheKeys[1]), IsNil)
+	c.Check(s.cm.Count(), Equals, 0)
+}
+
+func (s *cacheSuite) TestEntriesNoCacheDir(c *C) {
+	cm := store.NewCacheManager(filepath.Join(s.tmp, "missing"), 5)
+	entries, err := cm.Entries()
+	c.Assert(err, IsNil)
+	c.Check(entries, HasLen, 0)
+}
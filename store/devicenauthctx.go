@@ -41,6 +41,15 @@ type DeviceAndAuthContext interface {
 	ProxyStoreParams(defaultURL *url.URL) (proxyStoreID string, proxySroreURL *url.URL, err error)
 
 	CloudInfo() (*auth.CloudInfo, error)
+
+	// StoreMirrorURL returns the URL of a store mirror to try before
+	// the store, or nil if none is set.
+	StoreMirrorURL() (*url.URL, error)
+
+	// DownloadCacheLimits returns the maximum total size of the
+	// download cache and the free space to preserve on its
+	// filesystem, in bytes, with zero meaning no limit.
+	DownloadCacheLimits() (maxSize, minFree uint64, err error)
 }
 
 // DeviceSessionRequestParams gathers the assertions and information to be sent to request a device session.
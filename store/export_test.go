@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"net/url"
 	"os/exec"
+	"syscall"
 	"time"
 
 	"github.com/juju/ratelimit"
@@ -130,6 +131,22 @@ func (cm *CacheManager) Count() int {
 	return cm.count()
 }
 
+func MockChunkedDownload(minSize, chunkSize int64, workers int) (restore func()) {
+	oldMinSize, oldChunkSize, oldWorkers := chunkedDownloadMinSize, downloadChunkSize, downloadChunkWorkers
+	chunkedDownloadMinSize, downloadChunkSize, downloadChunkWorkers = minSize, chunkSize, workers
+	return func() {
+		chunkedDownloadMinSize, downloadChunkSize, downloadChunkWorkers = oldMinSize, oldChunkSize, oldWorkers
+	}
+}
+
+func MockSyscallStatfs(f func(path string, st *syscall.Statfs_t) error) func() {
+	old := syscallStatfs
+	syscallStatfs = f
+	return func() {
+		syscallStatfs = old
+	}
+}
+
 func MockOsRemove(f func(name string) error) func() {
 	oldOsRemove := osRemove
 	osRemove = f
@@ -0,0 +1,263 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Paths under which a store mirror serves the snaps from its download
+// cache and its assertions.
+const (
+	mirrorSnapsPath      = "v2/mirror/snaps"
+	mirrorAssertionsPath = "v2/mirror/assertions"
+)
+
+var validSHA3_384 = regexp.MustCompile(`^[0-9a-f]{96}$`).MatchString
+
+// MirrorAssertionFinder finds the assertions served by a store mirror.
+type MirrorAssertionFinder func(assertType *asserts.AssertionType, headers map[string]string) (asserts.Assertion, error)
+
+type mirrorHandler struct {
+	cache         downloadCache
+	findAssertion MirrorAssertionFinder
+}
+
+// NewMirrorHandler returns an http.Handler serving to LAN peers the snaps
+// in the given download cache, by their sha3-384 digest, and the
+// assertions found with findAssertion. No authentication is performed,
+// peers verify the digest of the snaps and the signatures of the
+// assertions they get.
+func NewMirrorHandler(cache *CacheManager, findAssertion MirrorAssertionFinder) http.Handler {
+	return &mirrorHandler{
+		cache:         cache,
+		findAssertion: findAssertion,
+	}
+}
+
+func (h *mirrorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case strings.HasPrefix(p, mirrorSnapsPath+"/"):
+		h.serveSnap(w, r, strings.TrimPrefix(p, mirrorSnapsPath+"/"))
+	case strings.HasPrefix(p, mirrorAssertionsPath+"/"):
+		h.serveAssertion(w, r, strings.TrimPrefix(p, mirrorAssertionsPath+"/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *mirrorHandler) serveSnap(w http.ResponseWriter, r *http.Request, digest string) {
+	if !validSHA3_384(digest) {
+		http.NotFound(w, r)
+		return
+	}
+	cachePath := h.cache.GetPath(digest)
+	if cachePath == "" {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := os.Open(cachePath)
+	if err != nil {
+		// the snap may have been evicted from the cache meanwhile
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, "", fi.ModTime(), f)
+}
+
+func (h *mirrorHandler) serveAssertion(w http.ResponseWriter, r *http.Request, p string) {
+	parts := strings.Split(p, "/")
+	assertType := asserts.Type(parts[0])
+	if assertType == nil {
+		http.NotFound(w, r)
+		return
+	}
+	headers, err := asserts.HeadersFromPrimaryKey(assertType, parts[1:])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.findAssertion(assertType, headers)
+	if errors.Is(err, &asserts.NotFoundError{}) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", asserts.MediaType)
+	w.Write(asserts.Encode(a))
+}
+
+// mirrorURL returns the URL of the store mirror to try before the store,
+// if one is set.
+func (s *Store) mirrorURL() *url.URL {
+	if s.dauthCtx == nil {
+		return nil
+	}
+	u, err := s.dauthCtx.StoreMirrorURL()
+	if err != nil {
+		logger.Noticef("cannot get store mirror: %v", err)
+		return nil
+	}
+	return u
+}
+
+// mirrorGet performs a request to the store mirror. Unlike requests to
+// the store no credentials are sent.
+func (s *Store) mirrorGet(ctx context.Context, u *url.URL) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", s.userAgent)
+
+	resp, err := s.newHTTPClient(nil).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, &DownloadError{Code: resp.StatusCode, URL: resp.Request.URL}
+	}
+	return resp, nil
+}
+
+// downloadFromMirror downloads the snap with the given download info
+// from the store mirror to targetPath, checking its digest.
+func (s *Store) downloadFromMirror(ctx context.Context, mirror *url.URL, name, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter) (err error) {
+	if !validSHA3_384(downloadInfo.Sha3_384) {
+		return fmt.Errorf("cannot download snap %q from store mirror without a valid digest", name)
+	}
+	if downloadInfo.Size <= 0 {
+		return fmt.Errorf("cannot download snap %q from store mirror without a known size", name)
+	}
+	resp, err := s.mirrorGet(ctx, endpointURL(mirror, path.Join(mirrorSnapsPath, downloadInfo.Sha3_384), nil))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	partialPath := targetPath + ".mirror.partial"
+	w, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(partialPath)
+		}
+	}()
+
+	if pbar == nil {
+		pbar = progress.Null
+	}
+	h := crypto.SHA3_384.New()
+	pbar.Start(name, float64(downloadInfo.Size))
+	// never read more than the expected size, a misbehaving mirror
+	// could otherwise fill the disk
+	n, err := io.Copy(io.MultiWriter(w, h, pbar), io.LimitReader(resp.Body, downloadInfo.Size+1))
+	pbar.Finished()
+	if err != nil {
+		return err
+	}
+	if n != downloadInfo.Size {
+		return fmt.Errorf("store mirror returned %d bytes for snap %q instead of %d", n, name, downloadInfo.Size)
+	}
+
+	actualSha3 := fmt.Sprintf("%x", h.Sum(nil))
+	if actualSha3 != downloadInfo.Sha3_384 {
+		return HashError{name, actualSha3, downloadInfo.Sha3_384}
+	}
+	if err := w.Sync(); err != nil {
+		return err
+	}
+	return os.Rename(partialPath, targetPath)
+}
+
+// mirrorAssertion fetches the assertion with the given type and primary
+// key from the store mirror, refusing formats above maxFormat. The caller
+// is expected to verify its signature, as usual when adding it to the
+// assertion database.
+func (s *Store) mirrorAssertion(mirror *url.URL, assertType *asserts.AssertionType, primaryKey []string, maxFormat int) (asserts.Assertion, error) {
+	headers, err := asserts.HeadersFromPrimaryKey(assertType, primaryKey)
+	if err != nil {
+		return nil, err
+	}
+	p := path.Join(mirrorAssertionsPath, assertType.Name, path.Join(asserts.ReducePrimaryKey(assertType, primaryKey)...))
+	resp, err := s.mirrorGet(context.TODO(), endpointURL(mirror, p, nil))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	a, err := asserts.NewDecoder(resp.Body).Decode()
+	if err != nil {
+		return nil, err
+	}
+	if a.Type() != assertType {
+		return nil, fmt.Errorf("store mirror returned %q assertion instead of %q", a.Type().Name, assertType.Name)
+	}
+	if a.Format() > maxFormat {
+		return nil, fmt.Errorf("store mirror returned %q assertion with unsupported format %d", assertType.Name, a.Format())
+	}
+	for k, v := range headers {
+		if a.HeaderString(k) != v {
+			return nil, fmt.Errorf("store mirror returned %q assertion with unexpected %q header", assertType.Name, k)
+		}
+	}
+	return a, nil
+}
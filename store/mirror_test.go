@@ -0,0 +1,318 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type storeMirrorSuite struct {
+	baseStoreSuite
+}
+
+var _ = Suite(&storeMirrorSuite{})
+
+func sha3_384(content []byte) string {
+	return fmt.Sprintf("%x", sha3.Sum384(content))
+}
+
+func (s *storeMirrorSuite) mirrorServer(c *C, findAssertion store.MirrorAssertionFinder) (*httptest.Server, *store.CacheManager) {
+	cm := store.NewCacheManager(c.MkDir(), 5)
+	srv := httptest.NewServer(store.NewMirrorHandler(cm, findAssertion))
+	s.AddCleanup(srv.Close)
+	return srv, cm
+}
+
+func mirrorGet(c *C, u string) (int, string, string) {
+	resp, err := http.Get(u)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	return resp.StatusCode, resp.Header.Get("Content-Type"), string(body)
+}
+
+func (s *storeMirrorSuite) TestMirrorHandlerServesCachedSnaps(c *C) {
+	srv, cm := s.mirrorServer(c, nil)
+
+	content := []byte("snap content")
+	digest := sha3_384(content)
+	snapPath := filepath.Join(c.MkDir(), "foo.snap")
+	c.Assert(ioutil.WriteFile(snapPath, content, 0644), IsNil)
+	c.Assert(cm.Put(digest, snapPath), IsNil)
+
+	status, contentType, body := mirrorGet(c, srv.URL+"/v2/mirror/snaps/"+digest)
+	c.Check(status, Equals, 200)
+	c.Check(contentType, Equals, "application/octet-stream")
+	c.Check(body, Equals, "snap content")
+
+	for _, p := range []string{
+		"/v2/mirror/snaps/" + sha3_384([]byte("other")),
+		"/v2/mirror/snaps/..%2f..%2fetc%2fpasswd",
+		"/v2/mirror/snaps/",
+		"/v2/snaps/info/foo",
+	} {
+		status, _, _ := mirrorGet(c, srv.URL+p)
+		c.Check(status, Equals, 404, Commentf(p))
+	}
+
+	resp, err := http.Post(srv.URL+"/v2/mirror/snaps/"+digest, "text/plain", nil)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Check(resp.StatusCode, Equals, 405)
+}
+
+func (s *storeMirrorSuite) TestMirrorHandlerServesAssertions(c *C) {
+	a, err := asserts.Decode([]byte(testAssertion))
+	c.Assert(err, IsNil)
+	srv, _ := s.mirrorServer(c, func(assertType *asserts.AssertionType, headers map[string]string) (asserts.Assertion, error) {
+		c.Check(assertType, Equals, asserts.SnapDeclarationType)
+		if headers["snap-id"] != "snapidfoo" {
+			return nil, &asserts.NotFoundError{Type: assertType, Headers: headers}
+		}
+		c.Check(headers, DeepEquals, map[string]string{"series": "16", "snap-id": "snapidfoo"})
+		return a, nil
+	})
+
+	status, contentType, body := mirrorGet(c, srv.URL+"/v2/mirror/assertions/snap-declaration/16/snapidfoo")
+	c.Check(status, Equals, 200)
+	c.Check(contentType, Equals, asserts.MediaType)
+	c.Check(body, Equals, string(asserts.Encode(a)))
+
+	status, _, _ = mirrorGet(c, srv.URL+"/v2/mirror/assertions/snap-declaration/16/snapidbar")
+	c.Check(status, Equals, 404)
+	status, _, _ = mirrorGet(c, srv.URL+"/v2/mirror/assertions/no-such-type/16")
+	c.Check(status, Equals, 404)
+	status, _, _ = mirrorGet(c, srv.URL+"/v2/mirror/assertions/snap-declaration/16")
+	c.Check(status, Equals, 400)
+}
+
+func (s *storeMirrorSuite) TestDownloadFromMirror(c *C) {
+	content := []byte("snap content")
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/mirror/snaps/"+sha3_384(content))
+		// no credentials are sent to the mirror
+		c.Check(r.Header.Get("Authorization"), Equals, "")
+		c.Check(r.Header.Get("X-Device-Authorization"), Equals, "")
+		w.Write(content)
+	}))
+	defer mirror.Close()
+	mirrorURL, err := url.Parse(mirror.URL)
+	c.Assert(err, IsNil)
+
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Fatalf("unexpected download from the store")
+		return nil
+	})
+	defer restore()
+
+	sto := store.New(&store.Config{}, &testDauthContext{c: c, device: s.device, mirrorURL: mirrorURL})
+	info := &snap.DownloadInfo{DownloadURL: "URL", Sha3_384: sha3_384(content), Size: int64(len(content))}
+	targetPath := filepath.Join(c.MkDir(), "foo.snap")
+	err = sto.Download(s.ctx, "foo", targetPath, info, nil, s.user, nil)
+	c.Assert(err, IsNil)
+	c.Check(targetPath, testutil.FileEquals, content)
+	c.Check(osutil.FileExists(targetPath+".mirror.partial"), Equals, false)
+}
+
+func (s *storeMirrorSuite) TestDownloadFromMirrorFallsBackToStore(c *C) {
+	content := []byte("snap content")
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("snap CONTENT"))
+	}))
+	defer mirror.Close()
+	mirrorURL, err := url.Parse(mirror.URL)
+	c.Assert(err, IsNil)
+
+	downloads := 0
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Check(url, Equals, "URL")
+		downloads++
+		w.Write(content)
+		return nil
+	})
+	defer restore()
+
+	sto := store.New(&store.Config{}, &testDauthContext{c: c, device: s.device, mirrorURL: mirrorURL})
+	info := &snap.DownloadInfo{DownloadURL: "URL", Sha3_384: sha3_384(content), Size: int64(len(content))}
+	targetPath := filepath.Join(c.MkDir(), "foo.snap")
+	err = sto.Download(s.ctx, "foo", targetPath, info, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(downloads, Equals, 1)
+	c.Check(targetPath, testutil.FileEquals, content)
+	c.Check(osutil.FileExists(targetPath+".mirror.partial"), Equals, false)
+	c.Check(s.logbuf.String(), Matches, `(?s).*Cannot download foo from store mirror: sha3-384 mismatch.*`)
+}
+
+func (s *storeMirrorSuite) TestDownloadFromMirrorOversize(c *C) {
+	content := []byte("snap content")
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+		// more data than expected
+		w.Write(make([]byte, 1024))
+	}))
+	defer mirror.Close()
+	mirrorURL, err := url.Parse(mirror.URL)
+	c.Assert(err, IsNil)
+
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		w.Write(content)
+		return nil
+	})
+	defer restore()
+
+	sto := store.New(&store.Config{}, &testDauthContext{c: c, device: s.device, mirrorURL: mirrorURL})
+	info := &snap.DownloadInfo{DownloadURL: "URL", Sha3_384: sha3_384(content), Size: int64(len(content))}
+	targetPath := filepath.Join(c.MkDir(), "foo.snap")
+	err = sto.Download(s.ctx, "foo", targetPath, info, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(targetPath, testutil.FileEquals, content)
+	c.Check(osutil.FileExists(targetPath+".mirror.partial"), Equals, false)
+	c.Check(s.logbuf.String(), Matches, `(?s).*Cannot download foo from store mirror: store mirror returned 13 bytes for snap "foo" instead of 12.*`)
+}
+
+func (s *storeMirrorSuite) TestAssertionFromMirror(c *C) {
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/mirror/assertions/snap-declaration/16/snapidfoo")
+		c.Check(r.Header.Get("X-Device-Authorization"), Equals, "")
+		io.WriteString(w, testAssertion)
+	}))
+	defer mirror.Close()
+	mirrorURL, err := url.Parse(mirror.URL)
+	c.Assert(err, IsNil)
+	nowhereURL, err := url.Parse("http://nowhere.invalid")
+	c.Assert(err, IsNil)
+
+	// the store cannot be reached
+	sto := store.New(&store.Config{StoreBaseURL: nowhereURL}, &testDauthContext{c: c, device: s.device, mirrorURL: mirrorURL})
+	a, err := sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(a.HeaderString("snap-id"), Equals, "snapidfoo")
+}
+
+func (s *storeMirrorSuite) TestAssertionFromStoreBeforeMirror(c *C) {
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to the store mirror")
+	}))
+	defer mirror.Close()
+	mirrorURL, err := url.Parse(mirror.URL)
+	c.Assert(err, IsNil)
+
+	storeHits := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequest(c, r, "GET", "/v2/assertions/.*")
+		c.Check(r.URL.Query().Get("max-format"), Equals, "88")
+		storeHits++
+		io.WriteString(w, testAssertion)
+	}))
+	defer mockServer.Close()
+	mockServerURL, err := url.Parse(mockServer.URL)
+	c.Assert(err, IsNil)
+
+	cfg := store.Config{
+		StoreBaseURL:        mockServerURL,
+		AssertionMaxFormats: map[string]int{"snap-declaration": 88},
+	}
+	sto := store.New(&cfg, &testDauthContext{c: c, device: s.device, mirrorURL: mirrorURL})
+	a, err := sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(a.HeaderString("snap-id"), Equals, "snapidfoo")
+	c.Check(storeHits, Equals, 1)
+}
+
+func (s *storeMirrorSuite) TestAssertionNotFoundInStoreSkipsMirror(c *C) {
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to the store mirror")
+	}))
+	defer mirror.Close()
+	mirrorURL, err := url.Parse(mirror.URL)
+	c.Assert(err, IsNil)
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequest(c, r, "GET", "/v2/assertions/.*")
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(404)
+		io.WriteString(w, `{"error-list":[{"code":"not-found","message":"not found"}]}`)
+	}))
+	defer mockServer.Close()
+	mockServerURL, err := url.Parse(mockServer.URL)
+	c.Assert(err, IsNil)
+
+	sto := store.New(&store.Config{StoreBaseURL: mockServerURL}, &testDauthContext{c: c, device: s.device, mirrorURL: mirrorURL})
+	_, err = sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+	c.Check(err, testutil.ErrorIs, &asserts.NotFoundError{})
+}
+
+func (s *storeMirrorSuite) TestAssertionFromMirrorMismatch(c *C) {
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// not the requested assertion
+		io.WriteString(w, strings.Replace(testAssertion, "snap-id: snapidfoo", "snap-id: snapidbar", 1))
+	}))
+	defer mirror.Close()
+	mirrorURL, err := url.Parse(mirror.URL)
+	c.Assert(err, IsNil)
+	nowhereURL, err := url.Parse("http://nowhere.invalid")
+	c.Assert(err, IsNil)
+
+	sto := store.New(&store.Config{StoreBaseURL: nowhereURL}, &testDauthContext{c: c, device: s.device, mirrorURL: mirrorURL})
+	_, err = sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+	// the store error is reported
+	c.Check(err, ErrorMatches, `.*nowhere.invalid.*`)
+	c.Check(s.logbuf.String(), Matches, `(?s).*cannot fetch assertion from store mirror: store mirror returned "snap-declaration" assertion with unexpected "snap-id" header.*`)
+}
+
+func (s *storeMirrorSuite) TestAssertionFromMirrorUnsupportedFormat(c *C) {
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, testAssertion)
+	}))
+	defer mirror.Close()
+	mirrorURL, err := url.Parse(mirror.URL)
+	c.Assert(err, IsNil)
+	nowhereURL, err := url.Parse("http://nowhere.invalid")
+	c.Assert(err, IsNil)
+
+	cfg := store.Config{
+		StoreBaseURL:        nowhereURL,
+		AssertionMaxFormats: map[string]int{"snap-declaration": -1},
+	}
+	sto := store.New(&cfg, &testDauthContext{c: c, device: s.device, mirrorURL: mirrorURL})
+	_, err = sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+	c.Check(err, ErrorMatches, `.*nowhere.invalid.*`)
+	c.Check(s.logbuf.String(), Matches, `(?s).*store mirror returned "snap-declaration" assertion with unsupported format 0.*`)
+}
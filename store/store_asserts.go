@@ -23,6 +23,7 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -32,6 +33,7 @@ import (
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/httputil"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/auth"
 )
 
@@ -74,18 +76,35 @@ func (e *assertionSvcError) toError() error {
 	return fmt.Errorf("assertion service error: [%s] %q", e.Title, e.Detail)
 }
 
-func (s *Store) setMaxFormat(v url.Values, assertType *asserts.AssertionType) {
-	var maxFormat int
+func (s *Store) maxFormat(assertType *asserts.AssertionType) int {
 	if s.cfg.AssertionMaxFormats == nil {
-		maxFormat = assertType.MaxSupportedFormat()
-	} else {
-		maxFormat = s.cfg.AssertionMaxFormats[assertType.Name]
+		return assertType.MaxSupportedFormat()
 	}
-	v.Set("max-format", strconv.Itoa(maxFormat))
+	return s.cfg.AssertionMaxFormats[assertType.Name]
+}
+
+func (s *Store) setMaxFormat(v url.Values, assertType *asserts.AssertionType) {
+	v.Set("max-format", strconv.Itoa(s.maxFormat(assertType)))
 }
 
 // Assertion retrieves the assertion for the given type and primary key.
+// If the store cannot be reached a store mirror, if set, is tried instead.
 func (s *Store) Assertion(assertType *asserts.AssertionType, primaryKey []string, user *auth.UserState) (asserts.Assertion, error) {
+	asrt, err := s.storeAssertion(assertType, primaryKey, user)
+	if err == nil || errors.Is(err, &asserts.NotFoundError{}) {
+		return asrt, err
+	}
+	if mirror := s.mirrorURL(); mirror != nil {
+		masrt, merr := s.mirrorAssertion(mirror, assertType, primaryKey, s.maxFormat(assertType))
+		if merr == nil {
+			return masrt, nil
+		}
+		logger.Debugf("cannot fetch assertion from store mirror: %v", merr)
+	}
+	return nil, err
+}
+
+func (s *Store) storeAssertion(assertType *asserts.AssertionType, primaryKey []string, user *auth.UserState) (asserts.Assertion, error) {
 	v := url.Values{}
 	s.setMaxFormat(v, assertType)
 	u := s.assertionsEndpointURL(path.Join(assertType.Name, path.Join(asserts.ReducePrimaryKey(assertType, primaryKey)...)), v)
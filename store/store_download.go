@@ -104,7 +104,14 @@ func (s *Store) useDeltas() (use bool) {
 
 	// TODO: have a per-format checker instead, we currently only support
 	// xdelta3 as a format for deltas
+	s.xdelta3CmdFunc = xdelta3Command()
+	return s.xdelta3CmdFunc != nil
+}
 
+// xdelta3Command returns a function building xdelta3 commands, using the
+// xdelta3 from the system snap if it works and the one from the host
+// system otherwise, or nil if no working xdelta3 is available.
+func xdelta3Command() func(args ...string) *exec.Cmd {
 	// check if the xdelta3 config command works from the system snap
 	cmd, err := commandFromSystemSnap("/usr/bin/xdelta3", "config")
 	if err == nil {
@@ -120,7 +127,7 @@ func (s *Store) useDeltas() (use bool) {
 			args := cmd.Args[:len(cmd.Args)-1]
 			env := cmd.Env
 			dir := cmd.Dir
-			s.xdelta3CmdFunc = func(xDelta3args ...string) *exec.Cmd {
+			return func(xDelta3args ...string) *exec.Cmd {
 				return &exec.Cmd{
 					Path: exe,
 					Args: append(args, xDelta3args...),
@@ -128,7 +135,6 @@ func (s *Store) useDeltas() (use bool) {
 					Dir:  dir,
 				}
 			}
-			return true
 		} else {
 			logger.Noticef("unable to use system snap provided xdelta3, running config command failed: %v", runErr)
 		}
@@ -140,20 +146,19 @@ func (s *Store) useDeltas() (use bool) {
 	if err != nil {
 		// no xdelta3 in the env, so no deltas
 		logger.Noticef("no host system xdelta3 available to use deltas")
-		return false
+		return nil
 	}
 
 	if err := exec.Command(loc, "config").Run(); err != nil {
 		// xdelta3 in the env failed to run, so no deltas
 		logger.Noticef("unable to use host system xdelta3, running config command failed: %v", err)
-		return false
+		return nil
 	}
 
 	// the xdelta3 in the env worked, so use that one
-	s.xdelta3CmdFunc = func(args ...string) *exec.Cmd {
+	return func(args ...string) *exec.Cmd {
 		return exec.Command(loc, args...)
 	}
-	return true
 }
 
 func (s *Store) cdnHeader() (string, error) {
@@ -201,6 +206,15 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		return nil
 	}
 
+	if mirror := s.mirrorURL(); mirror != nil {
+		err := s.downloadFromMirror(ctx, mirror, name, targetPath, downloadInfo, pbar)
+		if err == nil {
+			return s.putInCache(downloadInfo.Sha3_384, targetPath)
+		}
+		// We revert to downloading from the store if there is any error.
+		logger.Noticef("Cannot download %s from store mirror: %v", name, err)
+	}
+
 	if s.useDeltas() {
 		logger.Debugf("Available deltas returned by store: %v", downloadInfo.Deltas)
 
@@ -233,6 +247,7 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		}
 		if dlOpts == nil || !dlOpts.LeavePartialOnError || fi == nil || fi.Size() == 0 {
 			os.Remove(w.Name())
+			os.Remove(chunksStatePath(w.Name()))
 		}
 	}()
 	if resume > 0 {
@@ -242,7 +257,26 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 	}
 
 	url := downloadInfo.DownloadURL
-	if downloadInfo.Size == 0 || resume < downloadInfo.Size {
+	// large snaps are fetched in parallel chunks, unless a plain
+	// download of them is already underway
+	chunked := downloadInfo.Size >= chunkedDownloadMinSize &&
+		(resume == 0 || osutil.FileExists(chunksStatePath(partialPath)))
+	if chunked {
+		err = downloadChunked(ctx, name, downloadInfo.Sha3_384, url, downloadInfo.Size, user, s, w, pbar, dlOpts)
+		if errors.Is(err, errRangeNotSupported) {
+			logger.Debugf("Cannot download %q in chunks: %v", url, err)
+			if err = w.Truncate(0); err != nil {
+				return err
+			}
+			if _, err = w.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, 0, pbar, dlOpts)
+		}
+		if err != nil {
+			logger.Debugf("download of %q failed: %#v", url, err)
+		}
+	} else if downloadInfo.Size == 0 || resume < downloadInfo.Size {
 		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, resume, pbar, dlOpts)
 		if err != nil {
 			logger.Debugf("download of %q failed: %#v", url, err)
@@ -291,7 +325,7 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		return err
 	}
 
-	return s.cacher.Put(downloadInfo.Sha3_384, targetPath)
+	return s.putInCache(downloadInfo.Sha3_384, targetPath)
 }
 
 func downloadReqOpts(storeURL *url.URL, cdnHeader string, opts *DownloadOptions) *requestOptions {
@@ -657,18 +691,40 @@ func (s *Store) applyDeltaImpl(name string, deltaPath string, deltaInfo *snap.De
 		return fmt.Errorf("cannot apply unsupported delta format %q (only xdelta3 currently)", deltaInfo.Format)
 	}
 
-	partialTargetPath := targetPath + ".partial"
-
-	xdelta3Args := []string{"-d", "-s", snapPath, deltaPath, partialTargetPath}
-
 	// validity check that deltas are available and that the path for the xdelta3
 	// command is set
 	if ok := s.useDeltas(); !ok {
 		return fmt.Errorf("internal error: applyDelta used when deltas are not available")
 	}
 
+	return applyXdelta3(s.xdelta3CmdFunc, name, snapPath, deltaPath, targetPath, targetSha3_384)
+}
+
+// ApplyLocalDelta generates the target snap at targetPath from the snap
+// at sourcePath and a locally provided xdelta3 delta, as opposed to one
+// downloaded from the store. The caller is in charge of verifying the
+// resulting snap.
+func ApplyLocalDelta(name, sourcePath, deltaPath, targetPath string) error {
+	if !osutil.FileExists(sourcePath) {
+		return fmt.Errorf("cannot find source snap %q for delta at %s", name, sourcePath)
+	}
+	xdelta3Cmd := xdelta3Command()
+	if xdelta3Cmd == nil {
+		return fmt.Errorf("cannot apply delta for snap %q: xdelta3 is not available on this system", name)
+	}
+
+	return applyXdelta3(xdelta3Cmd, name, sourcePath, deltaPath, targetPath, "")
+}
+
+// applyXdelta3 generates the target snap from the given snap and xdelta3
+// delta, checking its digest if targetSha3_384 is set.
+func applyXdelta3(xdelta3Cmd func(args ...string) *exec.Cmd, name, snapPath, deltaPath, targetPath, targetSha3_384 string) error {
+	partialTargetPath := targetPath + ".partial"
+
+	xdelta3Args := []string{"-d", "-s", snapPath, deltaPath, partialTargetPath}
+
 	// run the xdelta3 command, cleaning up if we fail and logging about it
-	if runErr := s.xdelta3CmdFunc(xdelta3Args...).Run(); runErr != nil {
+	if runErr := xdelta3Cmd(xdelta3Args...).Run(); runErr != nil {
 		logger.Noticef("encountered error applying delta: %v", runErr)
 		if err := os.Remove(partialTargetPath); err != nil {
 			logger.Noticef("error cleaning up partial delta target %q: %s", partialTargetPath, err)
@@ -731,6 +787,24 @@ func (s *Store) downloadAndApplyDelta(name, targetPath string, downloadInfo *sna
 	return nil
 }
 
+// putInCache adds the downloaded snap at path to the download cache,
+// applying the current limits of the cache first.
+func (s *Store) putInCache(cacheKey, path string) error {
+	if cm, ok := s.cacher.(*CacheManager); ok && s.dauthCtx != nil {
+		maxSize, minFree, err := s.dauthCtx.DownloadCacheLimits()
+		if err != nil {
+			logger.Noticef("cannot get download cache limits: %v", err)
+		} else {
+			cm.SetPolicy(CachePolicy{
+				MaxItems: s.cfg.CacheDownloads,
+				MaxSize:  maxSize,
+				MinFree:  minFree,
+			})
+		}
+	}
+	return s.cacher.Put(cacheKey, path)
+}
+
 func (s *Store) CacheDownloads() int {
 	return s.cfg.CacheDownloads
 }
@@ -0,0 +1,363 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/juju/ratelimit"
+	"gopkg.in/retry.v1"
+
+	"github.com/snapcore/snapd/httputil"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/progress"
+)
+
+var (
+	// snaps of at least this size are downloaded in chunks
+	chunkedDownloadMinSize = int64(64 * 1024 * 1024)
+	// size of the chunks of a chunked download
+	downloadChunkSize = int64(16 * 1024 * 1024)
+	// number of chunks of a chunked download fetched in parallel
+	downloadChunkWorkers = 4
+)
+
+var downloadChunked = downloadChunkedImpl
+
+// errRangeNotSupported is returned by downloadChunked when the server
+// does not honour range requests, or answers them with the wrong range.
+var errRangeNotSupported = errors.New("server does not support range requests")
+
+// chunksState is persisted next to the partial download of a chunked
+// download, recording which chunks were already fetched so that an
+// interrupted download resumes all of them.
+type chunksState struct {
+	Sha3_384  string `json:"sha3-384"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk-size"`
+	// Done is a bitmap of the fetched chunks.
+	Done []byte `json:"done"`
+}
+
+func chunksStatePath(partialPath string) string {
+	return partialPath + ".chunks"
+}
+
+func newChunksState(sha3_384 string, size int64) *chunksState {
+	n := (size + downloadChunkSize - 1) / downloadChunkSize
+	return &chunksState{
+		Sha3_384:  sha3_384,
+		Size:      size,
+		ChunkSize: downloadChunkSize,
+		Done:      make([]byte, (n+7)/8),
+	}
+}
+
+// readChunksState reads the chunks state of the partial download at the
+// given path. It returns nil if there is none, or if it is for a
+// different download.
+func readChunksState(partialPath, sha3_384 string, size int64) *chunksState {
+	data, err := ioutil.ReadFile(chunksStatePath(partialPath))
+	if err != nil {
+		return nil
+	}
+	var cs chunksState
+	if err := json.Unmarshal(data, &cs); err != nil {
+		logger.Noticef("cannot read state of chunked download %q: %v", partialPath, err)
+		return nil
+	}
+	if cs.Sha3_384 != sha3_384 || cs.Size != size || cs.ChunkSize <= 0 ||
+		int64(len(cs.Done)) != ((size+cs.ChunkSize-1)/cs.ChunkSize+7)/8 {
+		return nil
+	}
+	return &cs
+}
+
+func (cs *chunksState) write(partialPath string) error {
+	data, err := json.Marshal(cs)
+	if err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(chunksStatePath(partialPath), data, 0600, 0)
+}
+
+func (cs *chunksState) numChunks() int {
+	return int((cs.Size + cs.ChunkSize - 1) / cs.ChunkSize)
+}
+
+func (cs *chunksState) isDone(i int) bool {
+	return cs.Done[i/8]&(1<<uint(i%8)) != 0
+}
+
+func (cs *chunksState) markDone(i int) {
+	cs.Done[i/8] |= 1 << uint(i%8)
+}
+
+// bounds returns the offset and the length of the given chunk.
+func (cs *chunksState) bounds(i int) (offset, length int64) {
+	offset = int64(i) * cs.ChunkSize
+	length = cs.ChunkSize
+	if offset+length > cs.Size {
+		length = cs.Size - offset
+	}
+	return offset, length
+}
+
+// offsetWriter writes sequentially to w starting at the given offset.
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+func (ow *offsetWriter) Write(p []byte) (int, error) {
+	n, err := ow.w.WriteAt(p, ow.offset)
+	ow.offset += int64(n)
+	return n, err
+}
+
+// lockedMeter serializes the updates of a progress.Meter shared by the
+// connections of a chunked download.
+type lockedMeter struct {
+	mu sync.Mutex
+	progress.Meter
+}
+
+func (m *lockedMeter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Meter.Write(p)
+}
+
+// downloadChunkedImpl downloads the snap of the given size to w, the
+// partial download at partialPath, fetching chunks of it in parallel
+// with range requests. Each chunk is retried on its own and has its own
+// transfer speed monitoring. The fetched chunks are recorded next to the
+// partial download so that the download can be resumed.
+func downloadChunkedImpl(ctx context.Context, name, sha3_384, downloadURL string, size int64, user *auth.UserState, s *Store, w *os.File, pbar progress.Meter, dlOpts *DownloadOptions) error {
+	if dlOpts == nil {
+		dlOpts = &DownloadOptions{}
+	}
+	storeURL, err := url.Parse(downloadURL)
+	if err != nil {
+		return err
+	}
+	cdnHeader, err := s.cdnHeader()
+	if err != nil {
+		return err
+	}
+
+	partialPath := w.Name()
+	cs := readChunksState(partialPath, sha3_384, size)
+	if cs == nil {
+		cs = newChunksState(sha3_384, size)
+		if err := w.Truncate(0); err != nil {
+			return err
+		}
+	} else {
+		logger.Debugf("Resuming chunked download of %q.", partialPath)
+	}
+	if err := w.Truncate(size); err != nil {
+		return err
+	}
+	if err := cs.write(partialPath); err != nil {
+		return err
+	}
+
+	if pbar == nil {
+		pbar = progress.Null
+	}
+	meter := &lockedMeter{Meter: pbar}
+	pbar.Start(name, float64(size))
+	defer pbar.Finished()
+
+	var pending []int
+	var doneSize int64
+	for i := 0; i < cs.numChunks(); i++ {
+		if cs.isDone(i) {
+			_, length := cs.bounds(i)
+			doneSize += length
+			continue
+		}
+		pending = append(pending, i)
+	}
+	pbar.Set(float64(doneSize))
+
+	var bucket *ratelimit.Bucket
+	if limit := dlOpts.RateLimit; limit > 0 {
+		bucket = ratelimit.NewBucketWithRate(float64(limit), 2*limit)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	chunks := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < downloadChunkWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				err := downloadChunk(ctx, storeURL, cdnHeader, user, s, w, cs, chunk, meter, bucket, dlOpts)
+				mu.Lock()
+				if err == nil {
+					cs.markDone(chunk)
+					err = cs.write(partialPath)
+				}
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+dispatch:
+	for _, chunk := range pending {
+		select {
+		case chunks <- chunk:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	if firstErr != nil {
+		if errors.Is(firstErr, errRangeNotSupported) {
+			// the download starts over from scratch, so must
+			// its progress
+			os.Remove(chunksStatePath(partialPath))
+			pbar.Set(0)
+		}
+		return firstErr
+	}
+	if cancelled(ctx) {
+		return fmt.Errorf("the download has been cancelled: %s", ctx.Err())
+	}
+
+	h := crypto.SHA3_384.New()
+	if _, err := io.Copy(h, io.NewSectionReader(w, 0, size)); err != nil {
+		return err
+	}
+	// the chunks are complete, a mismatch can only be fixed by starting
+	// from scratch
+	os.Remove(chunksStatePath(partialPath))
+	actualSha3 := fmt.Sprintf("%x", h.Sum(nil))
+	if sha3_384 != "" && sha3_384 != actualSha3 {
+		return HashError{name, actualSha3, sha3_384}
+	}
+	if _, err := w.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	return nil
+}
+
+// downloadChunk fetches the given chunk with a range request, retrying
+// on errors.
+func downloadChunk(ctx context.Context, storeURL *url.URL, cdnHeader string, user *auth.UserState, s *Store, w io.WriterAt, cs *chunksState, chunk int, pbar io.Writer, bucket *ratelimit.Bucket, dlOpts *DownloadOptions) error {
+	offset, length := cs.bounds(chunk)
+	end := offset + length
+
+	var finalErr error
+	for attempt := retry.Start(downloadRetryStrategy, nil); attempt.Next(); {
+		reqOptions := downloadReqOpts(storeURL, cdnHeader, dlOpts)
+		reqOptions.ExtraHeaders["Range"] = fmt.Sprintf("bytes=%d-%d", offset, end-1)
+
+		tc, chunkCtx := NewTransferSpeedMonitoringWriterAndContext(ctx, downloadSpeedMeasureWindow, downloadSpeedMin)
+		var resp *http.Response
+		resp, finalErr = s.doRequest(chunkCtx, s.newHTTPClient(nil), reqOptions, user)
+		if cancelled(chunkCtx) {
+			return fmt.Errorf("the download has been cancelled: %s", chunkCtx.Err())
+		}
+		if finalErr != nil {
+			if httputil.ShouldRetryAttempt(attempt, finalErr) {
+				continue
+			}
+			return finalErr
+		}
+		if httputil.ShouldRetryHttpResponse(attempt, resp) {
+			resp.Body.Close()
+			continue
+		}
+
+		switch resp.StatusCode {
+		case 206: // Partial Content
+			var start, last int64
+			contentRange := resp.Header.Get("Content-Range")
+			if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/", &start, &last); err != nil || start != offset || last < start {
+				resp.Body.Close()
+				return fmt.Errorf("%w: got range %q when asking for bytes %d-%d", errRangeNotSupported, contentRange, offset, end-1)
+			}
+		case 200:
+			resp.Body.Close()
+			return errRangeNotSupported
+		default:
+			resp.Body.Close()
+			return &DownloadError{Code: resp.StatusCode, URL: resp.Request.URL}
+		}
+
+		var body io.Reader = io.LimitReader(resp.Body, end-offset)
+		if bucket != nil {
+			body = ratelimitReader(body, bucket)
+		}
+		dst := &offsetWriter{w: w, offset: offset}
+		stopMonitorCh := tc.Monitor()
+		_, finalErr = io.Copy(io.MultiWriter(dst, pbar, tc), body)
+		close(stopMonitorCh)
+		resp.Body.Close()
+
+		if err := tc.Err(); err != nil {
+			return err
+		}
+		if cancelled(chunkCtx) {
+			return fmt.Errorf("the download has been cancelled: %s", chunkCtx.Err())
+		}
+		// resume the chunk from where it stopped on retries
+		offset = dst.offset
+		if finalErr == nil && offset < end {
+			finalErr = io.ErrUnexpectedEOF
+		}
+		if finalErr != nil {
+			if httputil.ShouldRetryAttempt(attempt, finalErr) {
+				continue
+			}
+			return finalErr
+		}
+		return nil
+	}
+	if finalErr == nil {
+		finalErr = fmt.Errorf("cannot download chunk %d: retries exhausted", chunk)
+	}
+	return finalErr
+}
@@ -0,0 +1,262 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+	"gopkg.in/retry.v1"
+
+	"github.com/snapcore/snapd/progress/progresstest"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type chunkedDownloadSuite struct {
+	testutil.BaseTest
+
+	content []byte
+	info    *snap.DownloadInfo
+
+	mu     sync.Mutex
+	ranges []string
+}
+
+var _ = Suite(&chunkedDownloadSuite{})
+
+func (s *chunkedDownloadSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	store.MockDownloadRetryStrategy(&s.BaseTest, retry.LimitCount(5, retry.Exponential{
+		Initial: time.Millisecond,
+		Factor:  2.5,
+	}))
+	// 10 chunks of 10 bytes, 3 at a time
+	s.AddCleanup(store.MockChunkedDownload(50, 10, 3))
+
+	s.content = bytes.Repeat([]byte("0123456789"), 10)
+	s.content[42] = 'x'
+	s.info = &snap.DownloadInfo{
+		DownloadURL: "URL",
+		Sha3_384:    sha3_384(s.content),
+		Size:        int64(len(s.content)),
+	}
+	s.ranges = nil
+}
+
+// server serves the content supporting range requests, letting fail
+// decide on how to fail requests.
+func (s *chunkedDownloadSuite) server(c *C, fail func(w http.ResponseWriter, r *http.Request) bool) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		s.mu.Unlock()
+		if fail != nil && fail(w, r) {
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.content))
+	}))
+	s.AddCleanup(srv.Close)
+	s.info.DownloadURL = srv.URL
+	return srv
+}
+
+func (s *chunkedDownloadSuite) download(c *C, dlOpts *store.DownloadOptions) (string, error) {
+	return s.downloadWithProgress(c, nil, dlOpts)
+}
+
+func (s *chunkedDownloadSuite) downloadWithProgress(c *C, pbar *currentMeter, dlOpts *store.DownloadOptions) (string, error) {
+	sto := store.New(&store.Config{}, nil)
+	targetPath := filepath.Join(c.MkDir(), "foo.snap")
+	if pbar == nil {
+		return targetPath, sto.Download(context.TODO(), "foo", targetPath, s.info, nil, nil, dlOpts)
+	}
+	return targetPath, sto.Download(context.TODO(), "foo", targetPath, s.info, pbar, nil, dlOpts)
+}
+
+// currentMeter tracks the current progress like the real meters do.
+type currentMeter struct {
+	progresstest.Meter
+
+	mu      sync.Mutex
+	current float64
+}
+
+func (m *currentMeter) Set(value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.current = value
+}
+
+func (m *currentMeter) Write(bs []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.current += float64(len(bs))
+	return len(bs), nil
+}
+
+func (s *chunkedDownloadSuite) TestDownloadInChunks(c *C) {
+	s.server(c, nil)
+
+	targetPath, err := s.download(c, nil)
+	c.Assert(err, IsNil)
+	c.Check(targetPath, testutil.FileEquals, s.content)
+	c.Check(targetPath+".partial.chunks", testutil.FileAbsent)
+
+	c.Check(s.ranges, HasLen, 10)
+	for i := 0; i < 10; i++ {
+		c.Check(s.ranges, testutil.Contains, fmt.Sprintf("bytes=%d-%d", i*10, i*10+9))
+	}
+}
+
+func (s *chunkedDownloadSuite) TestDownloadInChunksRetriesChunk(c *C) {
+	failed := false
+	s.server(c, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Range") != "bytes=40-49" || failed {
+			return false
+		}
+		failed = true
+		// send part of the chunk and drop the connection
+		w.Header().Set("Content-Range", "bytes 40-49/100")
+		w.Header().Set("Content-Length", "10")
+		w.WriteHeader(206)
+		w.Write(s.content[40:44])
+		w.(http.Flusher).Flush()
+		hj, ok := w.(http.Hijacker)
+		c.Assert(ok, Equals, true)
+		conn, _, err := hj.Hijack()
+		c.Assert(err, IsNil)
+		conn.Close()
+		return true
+	})
+
+	pbar := &currentMeter{}
+	targetPath, err := s.downloadWithProgress(c, pbar, nil)
+	c.Assert(err, IsNil)
+	c.Check(targetPath, testutil.FileEquals, s.content)
+	// the bytes of the chunk are counted once
+	c.Check(pbar.current, Equals, float64(len(s.content)))
+	// the chunk is resumed from where it stopped
+	c.Check(s.ranges, HasLen, 11)
+	c.Check(s.ranges, testutil.Contains, "bytes=44-49")
+}
+
+func (s *chunkedDownloadSuite) TestDownloadInChunksResumes(c *C) {
+	// fetch the chunks in order
+	restore := store.MockChunkedDownload(50, 10, 1)
+	defer restore()
+
+	broken := true
+	s.server(c, func(w http.ResponseWriter, r *http.Request) bool {
+		if broken && r.Header.Get("Range") == "bytes=70-79" {
+			w.WriteHeader(404)
+			return true
+		}
+		return false
+	})
+
+	sto := store.New(&store.Config{}, nil)
+	targetPath := filepath.Join(c.MkDir(), "foo.snap")
+	dlOpts := &store.DownloadOptions{LeavePartialOnError: true}
+	err := sto.Download(context.TODO(), "foo", targetPath, s.info, nil, nil, dlOpts)
+	c.Assert(err, ErrorMatches, `received an unexpected http response code \(404\) when trying to download .*`)
+	c.Check(targetPath+".partial", testutil.FilePresent)
+	c.Check(targetPath+".partial.chunks", testutil.FilePresent)
+
+	// only the chunks that were not fetched are downloaded again
+	broken = false
+	s.ranges = nil
+	err = sto.Download(context.TODO(), "foo", targetPath, s.info, nil, nil, dlOpts)
+	c.Assert(err, IsNil)
+	c.Check(targetPath, testutil.FileEquals, s.content)
+	c.Check(targetPath+".partial.chunks", testutil.FileAbsent)
+	c.Check(s.ranges, DeepEquals, []string{"bytes=70-79", "bytes=80-89", "bytes=90-99"})
+}
+
+func (s *chunkedDownloadSuite) TestDownloadInChunksErrorRemovesPartial(c *C) {
+	s.server(c, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Range") == "bytes=70-79" {
+			w.WriteHeader(404)
+			return true
+		}
+		return false
+	})
+
+	targetPath, err := s.download(c, nil)
+	c.Assert(err, NotNil)
+	c.Check(targetPath+".partial", testutil.FileAbsent)
+	c.Check(targetPath+".partial.chunks", testutil.FileAbsent)
+}
+
+func (s *chunkedDownloadSuite) TestDownloadInChunksNoRangeSupport(c *C) {
+	s.server(c, func(w http.ResponseWriter, r *http.Request) bool {
+		w.Write(s.content)
+		return true
+	})
+
+	targetPath, err := s.download(c, nil)
+	c.Assert(err, IsNil)
+	c.Check(targetPath, testutil.FileEquals, s.content)
+	c.Check(targetPath+".partial.chunks", testutil.FileAbsent)
+	// the last request is the plain download
+	c.Check(s.ranges[len(s.ranges)-1], Equals, "")
+}
+
+func (s *chunkedDownloadSuite) TestDownloadInChunksWrongRange(c *C) {
+	s.server(c, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Range") != "bytes=90-99" {
+			return false
+		}
+		w.Header().Set("Content-Range", "bytes 0-9/100")
+		w.Header().Set("Content-Length", "10")
+		w.WriteHeader(206)
+		w.Write(s.content[0:10])
+		return true
+	})
+
+	pbar := &currentMeter{}
+	targetPath, err := s.downloadWithProgress(c, pbar, nil)
+	c.Assert(err, IsNil)
+	// the snap is downloaded again without ranges
+	c.Check(targetPath, testutil.FileEquals, s.content)
+	c.Check(targetPath+".partial.chunks", testutil.FileAbsent)
+	c.Check(s.ranges[len(s.ranges)-1], Equals, "")
+	// and its progress starts over
+	c.Check(pbar.current, Equals, float64(len(s.content)))
+}
+
+func (s *chunkedDownloadSuite) TestDownloadInChunksHashMismatch(c *C) {
+	s.info.Sha3_384 = sha3_384([]byte("other content"))
+	s.server(c, nil)
+
+	targetPath, err := s.download(c, nil)
+	c.Assert(err, ErrorMatches, `sha3-384 mismatch for "foo": got .* but expected .*`)
+	c.Check(targetPath+".partial", testutil.FileAbsent)
+	c.Check(targetPath+".partial.chunks", testutil.FileAbsent)
+}
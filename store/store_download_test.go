@@ -692,6 +692,46 @@ func (s *storeDownloadSuite) TestApplyDelta(c *C) {
 	}
 }
 
+func (s *storeDownloadSuite) TestApplyLocalDelta(c *C) {
+	sourcePath := filepath.Join(dirs.SnapBlobDir, "foo_x1.snap")
+	targetPath := filepath.Join(c.MkDir(), "foo.snap")
+	deltaPath := filepath.Join(c.MkDir(), "foo.xdelta3")
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(sourcePath, nil, 0644), IsNil)
+	c.Assert(ioutil.WriteFile(deltaPath, nil, 0644), IsNil)
+	// simulate the .partial produced by xdelta3
+	c.Assert(ioutil.WriteFile(targetPath+".partial", []byte("target"), 0644), IsNil)
+
+	err := store.ApplyLocalDelta("foo", sourcePath, deltaPath, targetPath)
+	c.Assert(err, IsNil)
+	c.Check(s.mockXDelta.Calls(), DeepEquals, [][]string{
+		{"xdelta3", "config"},
+		{"xdelta3", "-d", "-s", sourcePath, deltaPath, targetPath + ".partial"},
+	})
+	c.Check(targetPath, testutil.FileEquals, "target")
+	c.Check(osutil.FileExists(targetPath+".partial"), Equals, false)
+}
+
+func (s *storeDownloadSuite) TestApplyLocalDeltaMissingSource(c *C) {
+	err := store.ApplyLocalDelta("foo", filepath.Join(dirs.SnapBlobDir, "foo_x1.snap"), "the.delta", "target.snap")
+	c.Assert(err, ErrorMatches, `cannot find source snap "foo" for delta at .*/foo_x1.snap`)
+	c.Check(s.mockXDelta.Calls(), HasLen, 0)
+}
+
+func (s *storeDownloadSuite) TestApplyLocalDeltaNoXdelta3(c *C) {
+	// no working xdelta3 from the system snap nor from the host
+	mockXDelta := testutil.MockCommand(c, "xdelta3", "exit 1")
+	defer mockXDelta.Restore()
+
+	sourcePath := filepath.Join(dirs.SnapBlobDir, "foo_x1.snap")
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(sourcePath, nil, 0644), IsNil)
+
+	err := store.ApplyLocalDelta("foo", sourcePath, "the.delta", "target.snap")
+	c.Assert(err, ErrorMatches, `cannot apply delta for snap "foo": xdelta3 is not available on this system`)
+	c.Check(mockXDelta.Calls(), DeepEquals, [][]string{{"xdelta3", "config"}})
+}
+
 type cacheObserver struct {
 	inCache map[string]bool
 
@@ -759,6 +799,28 @@ func (s *storeDownloadSuite) TestDownloadCacheMiss(c *C) {
 	c.Check(obs.puts, DeepEquals, []string{fmt.Sprintf("the-snaps-sha3_384:%s", path)})
 }
 
+func (s *storeDownloadSuite) TestDownloadCacheAppliesLimits(c *C) {
+	dauthCtx := &testDauthContext{c: c, cacheMaxSize: 2000, cacheMinFree: 1000}
+	sto := store.New(&store.Config{CacheDownloads: 3}, dauthCtx)
+	cm := store.NewCacheManager(c.MkDir(), 3)
+	defer sto.MockCacher(cm)()
+
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		return nil
+	})
+	defer restore()
+
+	snap := &snap.Info{}
+	snap.Sha3_384 = "the-snaps-sha3_384"
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(s.ctx, "foo", path, &snap.DownloadInfo, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	c.Check(cm.Policy(), Equals, store.CachePolicy{MaxItems: 3, MaxSize: 2000, MinFree: 1000})
+	c.Check(cm.GetPath("the-snaps-sha3_384"), Not(Equals), "")
+}
+
 func (s *storeDownloadSuite) TestDownloadStreamOK(c *C) {
 	expectedContent := []byte("I was downloaded")
 	restore := store.MockDoDownloadReq(func(ctx context.Context, url *url.URL, cdnHeader string, resume int64, s *store.Store, user *auth.UserState) (*http.Response, error) {
@@ -244,6 +244,11 @@ type testDauthContext struct {
 	storeID string
 
 	cloudInfo *auth.CloudInfo
+
+	mirrorURL *url.URL
+
+	cacheMaxSize uint64
+	cacheMinFree uint64
 }
 
 func (dac *testDauthContext) Device() (*auth.DeviceState, error) {
@@ -317,6 +322,14 @@ func (dac *testDauthContext) CloudInfo() (*auth.CloudInfo, error) {
 	return dac.cloudInfo, nil
 }
 
+func (dac *testDauthContext) StoreMirrorURL() (*url.URL, error) {
+	return dac.mirrorURL, nil
+}
+
+func (dac *testDauthContext) DownloadCacheLimits() (maxSize, minFree uint64, err error) {
+	return dac.cacheMaxSize, dac.cacheMinFree, nil
+}
+
 func makeTestMacaroon() (*macaroon.Macaroon, error) {
 	m, err := macaroon.New([]byte("secret"), "some-id", "location")
 	if err != nil {
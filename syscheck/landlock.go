@@ -0,0 +1,46 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package syscheck
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/sandbox/apparmor"
+	"github.com/snapcore/snapd/sandbox/landlock"
+)
+
+func init() {
+	checks = append(checks, checkLandlock)
+}
+
+func checkLandlock() error {
+	// Landlock is only used to confine snaps when AppArmor is not
+	// available.
+	switch apparmor.ProbedLevel() {
+	case apparmor.Partial, apparmor.Full:
+		return nil
+	}
+	// A kernel without landlock support is fine, but landlock being
+	// reported in a state we cannot make sense of is not.
+	if _, err := landlock.Version(); err != nil {
+		return fmt.Errorf("landlock detected but cannot be probed: %v", err)
+	}
+	return nil
+}
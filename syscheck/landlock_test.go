@@ -0,0 +1,59 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package syscheck_test
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/sandbox/apparmor"
+	"github.com/snapcore/snapd/sandbox/landlock"
+	"github.com/snapcore/snapd/syscheck"
+)
+
+func (s *syscheckSuite) TestCheckLandlock(c *C) {
+	for _, tc := range []struct {
+		apparmor apparmor.LevelType
+		version  int
+		err      error
+		expected string
+	}{
+		{apparmor.Unsupported, 0, nil, ""},
+		{apparmor.Unsupported, 2, nil, ""},
+		{apparmor.Unsupported, 0, errors.New("boom"), "landlock detected but cannot be probed: boom"},
+		{apparmor.Unusable, 0, errors.New("boom"), "landlock detected but cannot be probed: boom"},
+		// landlock is not relevant with AppArmor
+		{apparmor.Full, 0, errors.New("boom"), ""},
+		{apparmor.Partial, 0, errors.New("boom"), ""},
+	} {
+		restore := apparmor.MockLevel(tc.apparmor)
+		defer restore()
+		restore = landlock.MockVersion(tc.version, tc.err)
+		defer restore()
+
+		err := syscheck.CheckLandlock()
+		if tc.expected == "" {
+			c.Check(err, IsNil)
+		} else {
+			c.Check(err, ErrorMatches, tc.expected)
+		}
+	}
+}
@@ -302,6 +302,35 @@ func MockJournalctl(f func(svcs []string, n int, follow, namespaces bool) (io.Re
 	}
 }
 
+// jctlAudit calls journalctl to get the JSON kernel audit records.
+var jctlAudit = func(n int) (io.ReadCloser, error) {
+	args := []string{"-o", "json", "--no-pager"}
+	if n < 0 {
+		args = append(args, "--no-tail")
+	} else {
+		args = append(args, "-n", strconv.Itoa(n))
+	}
+	// audit records are forwarded by journald under the audit transport,
+	// or show up under the kernel one when there is no audit support
+	args = append(args, "_TRANSPORT=audit", "_TRANSPORT=kernel")
+
+	return osutilStreamCommand("journalctl", args...)
+}
+
+// AuditLogReader returns a reader for the JSON log of the last n kernel and
+// audit records in the system journal, or all of them if n is negative.
+func AuditLogReader(n int) (io.ReadCloser, error) {
+	return jctlAudit(n)
+}
+
+func MockJournalctlAudit(f func(n int) (io.ReadCloser, error)) func() {
+	oldJctlAudit := jctlAudit
+	jctlAudit = f
+	return func() {
+		jctlAudit = oldJctlAudit
+	}
+}
+
 type MountUnitOptions struct {
 	// Whether the unit is transient or persistent across reboots
 	Lifetime UnitLifetime
@@ -430,6 +459,9 @@ type RunOptions struct {
 	//      and let the caller do the keyring setup but feels a bit loose
 	KeyringMode KeyringMode
 	Stdin       io.Reader
+	// StdoutOnly makes Run return only the standard output of the
+	// command, its standard error is then only used in errors.
+	StdoutOnly bool
 }
 
 // A Log is a single entry in the systemd journal.
@@ -1695,6 +1727,16 @@ func (s *systemd) Run(command []string, opts *RunOptions) ([]byte, error) {
 	cmd := exec.Command("systemd-run", runArgs...)
 	cmd.Stdin = opts.Stdin
 
+	if opts.StdoutOnly {
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("cannot run %q: %v", command, osutil.OutputErr(stderr.Bytes(), err))
+		}
+		return output, nil
+	}
+
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("cannot run %q: %v", command, osutil.OutputErr(output, err))
@@ -1547,6 +1547,23 @@ func (s *SystemdTestSuite) TestJctl(c *C) {
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "--no-tail", "--namespace=*", "-u", "foo", "-u", "bar"})
 }
 
+func (s *SystemdTestSuite) TestAuditLogReader(c *C) {
+	var args []string
+	restore := MockOsutilStreamCommand(func(name string, myargs ...string) (io.ReadCloser, error) {
+		c.Check(name, Equals, "journalctl")
+		args = myargs
+		return nil, nil
+	})
+	defer restore()
+
+	_, err := AuditLogReader(10)
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "10", "_TRANSPORT=audit", "_TRANSPORT=kernel"})
+	_, err = AuditLogReader(-1)
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "--no-tail", "_TRANSPORT=audit", "_TRANSPORT=kernel"})
+}
+
 func (s *SystemdTestSuite) TestIsActiveUnderRoot(c *C) {
 	sysErr := &Error{}
 	// manpage states that systemctl returns exit code 3 for inactive
@@ -2369,6 +2386,26 @@ func (s *SystemdTestSuite) TestSystemdRunHappyWithStdin(c *C) {
 	})
 }
 
+func (s *SystemdTestSuite) TestSystemdRunStdoutOnly(c *C) {
+	sr := testutil.MockCommand(c, "systemd-run", `echo "happy output" && >&2 echo "to stderr"`)
+	defer sr.Restore()
+
+	sysd := New(SystemMode, s.rep)
+	output, err := sysd.Run([]string{"happy-cmd", "arg1"}, &RunOptions{StdoutOnly: true})
+	c.Check(string(output), Equals, "happy output\n")
+	c.Check(err, IsNil)
+}
+
+func (s *SystemdTestSuite) TestSystemdRunStdoutOnlyError(c *C) {
+	sr := testutil.MockCommand(c, "systemd-run", `echo "some output" && >&2 echo "fail"; exit 11`)
+	defer sr.Restore()
+
+	sysd := New(SystemMode, s.rep)
+	output, err := sysd.Run([]string{"bad-cmd", "arg1"}, &RunOptions{StdoutOnly: true})
+	c.Check(output, IsNil)
+	c.Assert(err, ErrorMatches, `cannot run \["bad-cmd" "arg1"\]: fail`)
+}
+
 func (s *SystemdTestSuite) TestSystemdRunKeyringMode(c *C) {
 	sr := testutil.MockCommand(c, "systemd-run", `echo "happy output"`)
 	defer sr.Restore()